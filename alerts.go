@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zephyrtronium/robot/channel"
+	"github.com/zephyrtronium/robot/jobs"
+)
+
+// alertKinds is the kinds of alerts, in the order they're reported.
+var alertKinds = []string{"growth", "rejection", "filter"}
+
+// countSnapshot is the counts of a channel at one time.
+type countSnapshot struct {
+	seen, filtered, learned, generated, rejected int64
+}
+
+func snapshotCounts(c *channel.Counts) countSnapshot {
+	return countSnapshot{
+		seen:      c.Seen.Load(),
+		filtered:  c.Filtered.Load(),
+		learned:   c.Learned.Load(),
+		generated: c.Generated.Load(),
+		rejected:  c.Rejected.Load(),
+	}
+}
+
+// alertWatch tracks channel rates for alerts.
+type alertWatch struct {
+	cfg   Alerts
+	every time.Duration
+
+	mu sync.Mutex
+	// prev is each channel's counts at the last check.
+	prev map[string]countSnapshot
+	// firing is the alerts firing in each channel with their values.
+	firing map[string]map[string]float64
+}
+
+// SetAlerts sets up alerts for channel growth and filter rates.
+func (robo *Robot) SetAlerts(cfg Alerts) {
+	if cfg.Growth <= 0 && cfg.Rejection <= 0 && cfg.Filter <= 0 {
+		robo.alerts = nil
+		return
+	}
+	if cfg.Min <= 0 {
+		cfg.Min = 10
+	}
+	every := fseconds(cfg.Every)
+	if every <= 0 {
+		every = 5 * time.Minute
+	}
+	robo.alerts = &alertWatch{
+		cfg:    cfg,
+		every:  every,
+		prev:   make(map[string]countSnapshot),
+		firing: make(map[string]map[string]float64),
+	}
+}
+
+// addAlertsJob adds a job to check channel rates against alert thresholds,
+// if any are configured.
+func (robo *Robot) addAlertsJob() {
+	if robo.alerts == nil {
+		return
+	}
+	robo.jobs.Add(jobs.Job{
+		Name:  "alerts",
+		Every: robo.alerts.every,
+		Fn: func(ctx context.Context, _ string) (string, error) {
+			robo.checkAlerts(ctx)
+			return "", nil
+		},
+	})
+}
+
+// alertValues gives the value of each kind of alert which exceeds its
+// threshold between two snapshots taken elapsed apart. Rates of messages
+// generated or seen count only once there are at least cfg.Min of them.
+func alertValues(cfg *Alerts, prev, cur countSnapshot, elapsed time.Duration) map[string]float64 {
+	r := make(map[string]float64)
+	if m := elapsed.Minutes(); cfg.Growth > 0 && m > 0 {
+		if v := float64(cur.learned-prev.learned) / m; v > cfg.Growth {
+			r["growth"] = v
+		}
+	}
+	if n := cur.generated - prev.generated; cfg.Rejection > 0 && n >= cfg.Min {
+		if v := float64(cur.rejected-prev.rejected) / float64(n); v > cfg.Rejection {
+			r["rejection"] = v
+		}
+	}
+	if n := cur.seen - prev.seen; cfg.Filter > 0 && n >= cfg.Min {
+		if v := float64(cur.filtered-prev.filtered) / float64(n); v > cfg.Filter {
+			r["filter"] = v
+		}
+	}
+	return r
+}
+
+// checkAlerts compares each channel's rates since the last check against the
+// alert thresholds, reporting alerts as they start and stop firing.
+func (robo *Robot) checkAlerts(ctx context.Context) {
+	a := robo.alerts
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for name, ch := range robo.channels.All() {
+		cur := snapshotCounts(&ch.Counts)
+		prev, ok := a.prev[name]
+		a.prev[name] = cur
+		if !ok {
+			continue
+		}
+		now := alertValues(&a.cfg, prev, cur, a.every)
+		was := a.firing[name]
+		for _, k := range alertKinds {
+			v, on := now[k]
+			_, wason := was[k]
+			switch {
+			case on && !wason:
+				text := alertText(name, k, v, &a.cfg)
+				if a.cfg.Notify {
+					robo.notifyOwner(ctx, text)
+				} else {
+					slog.WarnContext(ctx, "alert", slog.String("in", name), slog.String("alert", k), slog.Float64("value", v), slog.String("text", text))
+				}
+			case !on && wason:
+				slog.InfoContext(ctx, "alert resolved", slog.String("in", name), slog.String("alert", k))
+			}
+		}
+		a.firing[name] = now
+	}
+}
+
+// alertText describes an alert for the owner.
+func alertText(channel, kind string, v float64, cfg *Alerts) string {
+	switch kind {
+	case "growth":
+		return fmt.Sprintf("%s is learning %.1f messages per minute, over the alert threshold of %g. Someone may be flooding it to poison what I learn.", channel, v, cfg.Growth)
+	case "rejection":
+		return fmt.Sprintf("%.0f%% of the messages I generated in %s were filtered, over the alert threshold of %.0f%%. What I've learned there may be poisoned.", v*100, channel, cfg.Rejection*100)
+	case "filter":
+		return fmt.Sprintf("%.0f%% of messages in %s tripped filters, over the alert threshold of %.0f%%. Someone may be trying to teach me things I shouldn't say.", v*100, channel, cfg.Filter*100)
+	}
+	return fmt.Sprintf("%s alert in %s: %g", kind, channel, v)
+}
+
+// promLabel escapes a Prometheus label value.
+var promLabel = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// metricsHTTP serves channel counts and firing alerts in the Prometheus text
+// exposition format.
+func (robo *Robot) metricsHTTP(w http.ResponseWriter, r *http.Request) {
+	counts := make(map[string]countSnapshot)
+	for name, ch := range robo.channels.All() {
+		counts[name] = snapshotCounts(&ch.Counts)
+	}
+	names := slices.Sorted(maps.Keys(counts))
+	var b strings.Builder
+	metric := func(name, help string, v func(countSnapshot) int64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+		for _, nm := range names {
+			fmt.Fprintf(&b, "%s{channel=\"%s\"} %d\n", name, promLabel.Replace(nm), v(counts[nm]))
+		}
+	}
+	metric("robot_messages_seen_total", "Messages considered for learning.", func(c countSnapshot) int64 { return c.seen })
+	metric("robot_messages_filtered_total", "Messages kept from learning by filters.", func(c countSnapshot) int64 { return c.filtered })
+	metric("robot_messages_learned_total", "Messages learned.", func(c countSnapshot) int64 { return c.learned })
+	metric("robot_messages_generated_total", "Messages generated to send.", func(c countSnapshot) int64 { return c.generated })
+	metric("robot_messages_rejected_total", "Generated messages kept from sending by filters.", func(c countSnapshot) int64 { return c.rejected })
+	if a := robo.alerts; a != nil {
+		b.WriteString("# HELP robot_alert_firing Whether an alert is firing in a channel.\n# TYPE robot_alert_firing gauge\n")
+		a.mu.Lock()
+		for _, nm := range names {
+			for _, k := range alertKinds {
+				v := 0
+				if _, ok := a.firing[nm][k]; ok {
+					v = 1
+				}
+				fmt.Fprintf(&b, "robot_alert_firing{channel=\"%s\",alert=\"%s\"} %d\n", promLabel.Replace(nm), k, v)
+			}
+		}
+		a.mu.Unlock()
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write([]byte(b.String()))
+}
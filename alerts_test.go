@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/channel"
+)
+
+func TestAlertValues(t *testing.T) {
+	cfg := Alerts{Growth: 60, Rejection: 0.5, Filter: 0.25, Min: 10}
+	cases := []struct {
+		name string
+		prev countSnapshot
+		cur  countSnapshot
+		want []string
+	}{
+		{
+			name: "quiet",
+			cur:  countSnapshot{seen: 100, filtered: 5, learned: 95, generated: 20, rejected: 2},
+		},
+		{
+			name: "growth",
+			prev: countSnapshot{learned: 100},
+			cur:  countSnapshot{learned: 800},
+			want: []string{"growth"},
+		},
+		{
+			name: "rejection",
+			cur:  countSnapshot{generated: 20, rejected: 15},
+			want: []string{"rejection"},
+		},
+		{
+			name: "filter",
+			cur:  countSnapshot{seen: 100, filtered: 40, learned: 60},
+			want: []string{"filter"},
+		},
+		{
+			name: "few",
+			cur:  countSnapshot{seen: 5, filtered: 5, generated: 5, rejected: 5},
+		},
+		{
+			name: "all",
+			cur:  countSnapshot{seen: 2000, filtered: 1000, learned: 1000, generated: 10, rejected: 10},
+			want: []string{"growth", "rejection", "filter"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := alertValues(&cfg, c.prev, c.cur, 10*time.Minute)
+			if len(got) != len(c.want) {
+				t.Errorf("wrong alerts: want %v, got %v", c.want, got)
+			}
+			for _, k := range c.want {
+				if _, ok := got[k]; !ok {
+					t.Errorf("missing %s alert: got %v", k, got)
+				}
+			}
+		})
+	}
+}
+
+func TestMetricsHTTP(t *testing.T) {
+	robo := New(1)
+	robo.SetAlerts(Alerts{Growth: 1})
+	ch := &channel.Channel{Name: `#"kessoku"`}
+	ch.Counts.Seen.Add(3)
+	ch.Counts.Learned.Add(2)
+	ch.Counts.Filtered.Add(1)
+	robo.channels.Store(ch.Name, ch)
+	robo.alerts.firing[ch.Name] = map[string]float64{"growth": 2}
+	rec := httptest.NewRecorder()
+	robo.metricsHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	want := []string{
+		`robot_messages_seen_total{channel="#\"kessoku\""} 3`,
+		`robot_messages_learned_total{channel="#\"kessoku\""} 2`,
+		`robot_messages_filtered_total{channel="#\"kessoku\""} 1`,
+		`robot_messages_generated_total{channel="#\"kessoku\""} 0`,
+		`robot_alert_firing{channel="#\"kessoku\"",alert="growth"} 1`,
+		`robot_alert_firing{channel="#\"kessoku\"",alert="filter"} 0`,
+		"# TYPE robot_messages_seen_total counter",
+	}
+	for _, w := range want {
+		if !strings.Contains(body, w) {
+			t.Errorf("missing %s in:\n%s", w, body)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// Package apikey manages keys for the bot's HTTP API.
+//
+// Keys are bearer tokens shown once when created. The store keeps only a hash
+// of each token keyed with the bot's secret, so a copy of the database alone
+// can neither recover tokens nor check guesses against them.
+package apikey
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Scope is the set of endpoints a key may use.
+type Scope string
+
+const (
+	// Speak allows generating messages.
+	Speak Scope = "speak"
+	// Stats allows reading metrics and explanations.
+	Stats Scope = "stats"
+	// Admin allows everything.
+	Admin Scope = "admin"
+)
+
+// ParseScope parses a scope name.
+func ParseScope(s string) (Scope, bool) {
+	switch sc := Scope(strings.ToLower(s)); sc {
+	case Speak, Stats, Admin:
+		return sc, true
+	default:
+		return "", false
+	}
+}
+
+// Allows reports whether a key with scope s may use an endpoint needing
+// scope need.
+func (s Scope) Allows(need Scope) bool {
+	return s == Admin || s == need
+}
+
+// Key is the description of an API key, without its token.
+type Key struct {
+	// ID identifies the key. It is also part of the key's token.
+	ID string `json:"id"`
+	// Name is a label for the key.
+	Name string `json:"name"`
+	// Scope is the endpoints the key may use.
+	Scope Scope `json:"scope"`
+	// Every and Burst are the key's rate limit: a request every Every with
+	// bursts of up to Burst. If Every is zero, the key is not rate limited.
+	Every time.Duration `json:"every"`
+	Burst int           `json:"burst"`
+	// Created is the time the key was created.
+	Created time.Time `json:"created"`
+}
+
+// Store is a set of API keys backed by an SQL database.
+type Store struct {
+	db *sqlitex.Pool
+	// secret keys the hashes of tokens.
+	secret []byte
+}
+
+const schemaSQL = `CREATE TABLE IF NOT EXISTS apikeys (
+	id TEXT PRIMARY KEY,
+	hash BLOB NOT NULL UNIQUE,
+	name TEXT NOT NULL,
+	scope TEXT NOT NULL,
+	every INTEGER NOT NULL,
+	burst INTEGER NOT NULL,
+	created INTEGER NOT NULL
+) STRICT`
+
+// Open opens a key store in an SQL database, creating its table if needed.
+// secret keys the hashes of tokens; keys created with one secret can't be
+// checked with another.
+func Open(ctx context.Context, db *sqlitex.Pool, secret []byte) (*Store, error) {
+	conn, err := db.Take(ctx)
+	defer db.Put(conn)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get connection from pool: %w", err)
+	}
+	if err := sqlitex.ExecuteTransient(conn, schemaSQL, nil); err != nil {
+		return nil, fmt.Errorf("couldn't run migration: %w", err)
+	}
+	return &Store{db: db, secret: secret}, nil
+}
+
+// hash computes the stored hash of a token.
+func (s *Store) hash(tok string) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(tok))
+	return h.Sum(nil)
+}
+
+// Create creates a new key and returns its token.
+// The token cannot be recovered later.
+func (s *Store) Create(ctx context.Context, name string, scope Scope, every time.Duration, burst int, now time.Time) (string, Key, error) {
+	var b [4 + 24]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", Key{}, fmt.Errorf("couldn't generate key: %w", err)
+	}
+	k := Key{
+		ID:      hex.EncodeToString(b[:4]),
+		Name:    name,
+		Scope:   scope,
+		Every:   every,
+		Burst:   max(burst, 1),
+		Created: now,
+	}
+	tok := "robot_" + k.ID + "_" + base64.RawURLEncoding.EncodeToString(b[4:])
+	conn, err := s.db.Take(ctx)
+	defer s.db.Put(conn)
+	if err != nil {
+		return "", Key{}, fmt.Errorf("couldn't get connection to create key: %w", err)
+	}
+	opts := sqlitex.ExecOptions{
+		Args: []any{k.ID, s.hash(tok), k.Name, string(k.Scope), k.Every.Nanoseconds(), k.Burst, k.Created.UnixNano()},
+	}
+	const insert = `INSERT INTO apikeys (id, hash, name, scope, every, burst, created) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	if err := sqlitex.Execute(conn, insert, &opts); err != nil {
+		return "", Key{}, fmt.Errorf("couldn't create key: %w", err)
+	}
+	return tok, k, nil
+}
+
+// Revoke deletes a key. It reports whether the key existed.
+func (s *Store) Revoke(ctx context.Context, id string) (bool, error) {
+	conn, err := s.db.Take(ctx)
+	defer s.db.Put(conn)
+	if err != nil {
+		return false, fmt.Errorf("couldn't get connection to revoke key: %w", err)
+	}
+	opts := sqlitex.ExecOptions{Args: []any{id}}
+	if err := sqlitex.Execute(conn, `DELETE FROM apikeys WHERE id=?`, &opts); err != nil {
+		return false, fmt.Errorf("couldn't revoke key: %w", err)
+	}
+	return conn.Changes() > 0, nil
+}
+
+// List lists all keys, oldest first.
+func (s *Store) List(ctx context.Context) ([]Key, error) {
+	conn, err := s.db.Take(ctx)
+	defer s.db.Put(conn)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get connection to list keys: %w", err)
+	}
+	var r []Key
+	opts := sqlitex.ExecOptions{
+		ResultFunc: func(st *sqlite.Stmt) error {
+			r = append(r, scan(st))
+			return nil
+		},
+	}
+	if err := sqlitex.Execute(conn, `SELECT id, name, scope, every, burst, created FROM apikeys ORDER BY created`, &opts); err != nil {
+		return nil, fmt.Errorf("couldn't list keys: %w", err)
+	}
+	return r, nil
+}
+
+// Count returns the number of keys.
+func (s *Store) Count(ctx context.Context) (int, error) {
+	conn, err := s.db.Take(ctx)
+	defer s.db.Put(conn)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't get connection to count keys: %w", err)
+	}
+	n, err := sqlitex.ResultInt(conn.Prep(`SELECT COUNT(*) FROM apikeys`))
+	if err != nil {
+		return 0, fmt.Errorf("couldn't count keys: %w", err)
+	}
+	return n, nil
+}
+
+// Check finds the key for a token. The second result is false if the token
+// is not a current key.
+func (s *Store) Check(ctx context.Context, tok string) (Key, bool, error) {
+	conn, err := s.db.Take(ctx)
+	defer s.db.Put(conn)
+	if err != nil {
+		return Key{}, false, fmt.Errorf("couldn't get connection to check key: %w", err)
+	}
+	var (
+		k  Key
+		ok bool
+	)
+	opts := sqlitex.ExecOptions{
+		Args: []any{s.hash(tok)},
+		ResultFunc: func(st *sqlite.Stmt) error {
+			k, ok = scan(st), true
+			return nil
+		},
+	}
+	if err := sqlitex.Execute(conn, `SELECT id, name, scope, every, burst, created FROM apikeys WHERE hash=?`, &opts); err != nil {
+		return Key{}, false, fmt.Errorf("couldn't check key: %w", err)
+	}
+	return k, ok, nil
+}
+
+func scan(st *sqlite.Stmt) Key {
+	return Key{
+		ID:      st.ColumnText(0),
+		Name:    st.ColumnText(1),
+		Scope:   Scope(st.ColumnText(2)),
+		Every:   time.Duration(st.ColumnInt64(3)),
+		Burst:   st.ColumnInt(4),
+		Created: time.Unix(0, st.ColumnInt64(5)),
+	}
+}
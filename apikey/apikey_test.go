@@ -0,0 +1,104 @@
+package apikey_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/zephyrtronium/robot/apikey"
+)
+
+var dbcount atomic.Uint64
+
+func testConn() *sqlitex.Pool {
+	k := dbcount.Add(1)
+	pool, err := sqlitex.NewPool(fmt.Sprintf("file:%d.db?mode=memory&cache=shared", k), sqlitex.PoolOptions{Flags: sqlite.OpenReadWrite | sqlite.OpenCreate | sqlite.OpenMemory | sqlite.OpenSharedCache | sqlite.OpenURI})
+	if err != nil {
+		panic(err)
+	}
+	return pool
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	db := testConn()
+	s, err := apikey.Open(ctx, db, []byte("bocchi"))
+	if err != nil {
+		t.Fatalf("couldn't open store: %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	speak, sk, err := s.Create(ctx, "overlay", apikey.Speak, time.Second, 5, now)
+	if err != nil {
+		t.Fatalf("couldn't create key: %v", err)
+	}
+	admin, ak, err := s.Create(ctx, "ops", apikey.Admin, 0, 0, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("couldn't create key: %v", err)
+	}
+	if speak == admin || sk.ID == ak.ID {
+		t.Fatalf("keys collided: %q/%q, %q/%q", speak, admin, sk.ID, ak.ID)
+	}
+	k, ok, err := s.Check(ctx, speak)
+	if err != nil || !ok {
+		t.Fatalf("couldn't check speak key: %v %v", ok, err)
+	}
+	if k != sk {
+		t.Errorf("wrong key: want %+v, got %+v", sk, k)
+	}
+	if _, ok, _ := s.Check(ctx, speak+"x"); ok {
+		t.Errorf("wrong token checked ok")
+	}
+	other, err := apikey.Open(ctx, db, []byte("ryo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := other.Check(ctx, speak); ok {
+		t.Errorf("token checked ok with a different secret")
+	}
+	if n, err := s.Count(ctx); n != 2 || err != nil {
+		t.Errorf("wrong count: want 2, got %d (%v)", n, err)
+	}
+	l, err := s.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 2 || l[0] != sk || l[1] != ak {
+		t.Errorf("wrong list: %+v", l)
+	}
+	ok, err = s.Revoke(ctx, sk.ID)
+	if err != nil || !ok {
+		t.Errorf("couldn't revoke: %v %v", ok, err)
+	}
+	if ok, _ := s.Revoke(ctx, sk.ID); ok {
+		t.Errorf("revoked twice")
+	}
+	if _, ok, _ := s.Check(ctx, speak); ok {
+		t.Errorf("revoked key checked ok")
+	}
+	if _, ok, _ := s.Check(ctx, admin); !ok {
+		t.Errorf("other key revoked")
+	}
+}
+
+func TestScope(t *testing.T) {
+	cases := []struct {
+		have, need apikey.Scope
+		want       bool
+	}{
+		{apikey.Speak, apikey.Speak, true},
+		{apikey.Speak, apikey.Stats, false},
+		{apikey.Stats, apikey.Admin, false},
+		{apikey.Admin, apikey.Speak, true},
+		{apikey.Admin, apikey.Admin, true},
+	}
+	for _, c := range cases {
+		if got := c.have.Allows(c.need); got != c.want {
+			t.Errorf("%s allows %s: want %t, got %t", c.have, c.need, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/zephyrtronium/robot/audit"
+)
+
+// record adds an entry to the audit log.
+// Failures are logged rather than stopping the action.
+func (robo *Robot) record(ctx context.Context, e audit.Entry) {
+	if robo.audit == nil {
+		return
+	}
+	if err := robo.audit.Record(ctx, e); err != nil {
+		slog.ErrorContext(ctx, "couldn't record audit entry",
+			slog.Any("err", err),
+			slog.String("actor", e.Actor),
+			slog.String("action", e.Action),
+		)
+	}
+}
@@ -0,0 +1,135 @@
+// Package audit provides a durable record of privileged actions taken on the
+// bot, such as moderator and owner commands and admin API requests.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Log is an audit log backed by an SQL database.
+type Log struct {
+	db *sqlitex.Pool
+}
+
+// Entry is one privileged action.
+type Entry struct {
+	// Time is the time at which the action was taken.
+	Time time.Time `json:"time"`
+	// Actor identifies who took the action, e.g. a user ID or an API role.
+	Actor string `json:"actor"`
+	// Channel is the channel in which the action was taken.
+	// It is empty for actions outside any channel.
+	Channel string `json:"channel,omitempty"`
+	// Action names the action, e.g. a command name.
+	Action string `json:"action"`
+	// Params is the parameters of the action.
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// Query selects entries from an audit log. Empty fields match everything.
+type Query struct {
+	// Since excludes entries from before it.
+	Since time.Time
+	// Actor, Channel, and Action select entries with exactly those values.
+	Actor, Channel, Action string
+	// Limit is the maximum number of entries to return.
+	// If it is not positive, all matching entries are returned.
+	Limit int
+}
+
+const schemaSQL = `
+CREATE TABLE IF NOT EXISTS audit (
+	time INTEGER NOT NULL,
+	actor TEXT NOT NULL,
+	channel TEXT NOT NULL,
+	action TEXT NOT NULL,
+	params TEXT NOT NULL
+) STRICT;
+CREATE INDEX IF NOT EXISTS audit_time ON audit (time);
+`
+
+// Open opens an audit log in an SQL database, creating its table if needed.
+func Open(ctx context.Context, db *sqlitex.Pool) (*Log, error) {
+	conn, err := db.Take(ctx)
+	defer db.Put(conn)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get connection from pool: %w", err)
+	}
+	if err := sqlitex.ExecuteScript(conn, schemaSQL, nil); err != nil {
+		return nil, fmt.Errorf("couldn't run migration: %w", err)
+	}
+	return &Log{db: db}, nil
+}
+
+// Record adds an entry to the log.
+func (l *Log) Record(ctx context.Context, e Entry) error {
+	conn, err := l.db.Take(ctx)
+	defer l.db.Put(conn)
+	if err != nil {
+		return fmt.Errorf("couldn't get connection to record audit entry: %w", err)
+	}
+	p, err := json.Marshal(e.Params)
+	if err != nil {
+		// Should be impossible.
+		go panic(fmt.Errorf("audit: couldn't marshal params %#v: %w", e.Params, err))
+	}
+	opts := sqlitex.ExecOptions{Args: []any{e.Time.UnixNano(), e.Actor, e.Channel, e.Action, string(p)}}
+	const insert = `INSERT INTO audit (time, actor, channel, action, params) VALUES (?, ?, ?, ?, ?)`
+	if err := sqlitex.Execute(conn, insert, &opts); err != nil {
+		return fmt.Errorf("couldn't record audit entry: %w", err)
+	}
+	return nil
+}
+
+// List gets the entries matching a query, newest first.
+func (l *Log) List(ctx context.Context, q Query) ([]Entry, error) {
+	conn, err := l.db.Take(ctx)
+	defer l.db.Put(conn)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get connection to list audit entries: %w", err)
+	}
+	lim := q.Limit
+	if lim <= 0 {
+		lim = -1
+	}
+	var r []Entry
+	opts := sqlitex.ExecOptions{
+		Named: map[string]any{
+			":since":   q.Since.UnixNano(),
+			":actor":   q.Actor,
+			":channel": q.Channel,
+			":action":  q.Action,
+			":limit":   lim,
+		},
+		ResultFunc: func(st *sqlite.Stmt) error {
+			e := Entry{
+				Time:    time.Unix(0, st.ColumnInt64(0)),
+				Actor:   st.ColumnText(1),
+				Channel: st.ColumnText(2),
+				Action:  st.ColumnText(3),
+			}
+			if err := json.Unmarshal([]byte(st.ColumnText(4)), &e.Params); err != nil {
+				return fmt.Errorf("couldn't decode params: %w", err)
+			}
+			r = append(r, e)
+			return nil
+		},
+	}
+	const sel = `SELECT time, actor, channel, action, params FROM audit
+		WHERE time >= :since
+			AND (:actor = '' OR actor = :actor)
+			AND (:channel = '' OR channel = :channel)
+			AND (:action = '' OR action = :action)
+		ORDER BY time DESC
+		LIMIT :limit`
+	if err := sqlitex.Execute(conn, sel, &opts); err != nil {
+		return nil, fmt.Errorf("couldn't list audit entries: %w", err)
+	}
+	return r, nil
+}
@@ -0,0 +1,70 @@
+package audit_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/zephyrtronium/robot/audit"
+)
+
+var dbcount atomic.Uint64
+
+func testConn() *sqlitex.Pool {
+	k := dbcount.Add(1)
+	pool, err := sqlitex.NewPool(fmt.Sprintf("file:%d.db?mode=memory&cache=shared", k), sqlitex.PoolOptions{Flags: sqlite.OpenReadWrite | sqlite.OpenCreate | sqlite.OpenMemory | sqlite.OpenSharedCache | sqlite.OpenURI})
+	if err != nil {
+		panic(err)
+	}
+	return pool
+}
+
+func TestLog(t *testing.T) {
+	ctx := context.Background()
+	l, err := audit.Open(ctx, testConn())
+	if err != nil {
+		t.Fatalf("couldn't open log: %v", err)
+	}
+	base := time.Unix(1700000000, 0)
+	entries := []audit.Entry{
+		{Time: base, Actor: "bocchi", Channel: "#kessoku", Action: "forget", Params: map[string]string{"term": "guitar"}},
+		{Time: base.Add(time.Minute), Actor: "ryo", Channel: "#kessoku", Action: "add-emote", Params: map[string]string{"emote": "Kappa", "weight": "1"}},
+		{Time: base.Add(2 * time.Minute), Actor: "bocchi", Channel: "#starry", Action: "resume", Params: map[string]string{"in": "#kessoku"}},
+		{Time: base.Add(3 * time.Minute), Actor: "operator", Action: "poster-review"},
+	}
+	for _, e := range entries {
+		if err := l.Record(ctx, e); err != nil {
+			t.Fatalf("couldn't record %+v: %v", e, err)
+		}
+	}
+	cases := []struct {
+		name string
+		q    audit.Query
+		want []audit.Entry
+	}{
+		{"all", audit.Query{}, []audit.Entry{entries[3], entries[2], entries[1], entries[0]}},
+		{"actor", audit.Query{Actor: "bocchi"}, []audit.Entry{entries[2], entries[0]}},
+		{"channel", audit.Query{Channel: "#kessoku"}, []audit.Entry{entries[1], entries[0]}},
+		{"action", audit.Query{Action: "resume"}, []audit.Entry{entries[2]}},
+		{"since", audit.Query{Since: base.Add(90 * time.Second)}, []audit.Entry{entries[3], entries[2]}},
+		{"limit", audit.Query{Limit: 1}, []audit.Entry{entries[3]}},
+		{"none", audit.Query{Actor: "kita"}, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := l.List(ctx, c.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("wrong entries (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -21,6 +21,15 @@ type TokenSource interface {
 	Refresh(ctx context.Context, old *oauth2.Token) (*oauth2.Token, error)
 }
 
+// Reauthorizer is a TokenSource which can obtain a new token through its
+// interactive flow regardless of whether it has a usable one, e.g. so that
+// the user can grant scopes the current token lacks.
+type Reauthorizer interface {
+	TokenSource
+	// Reauthorize runs the interactive flow and stores the resulting token.
+	Reauthorize(ctx context.Context) (*oauth2.Token, error)
+}
+
 // Equal compares two OAuth2 tokens by access token, refresh token, token type,
 // and expiry.
 func Equal(a, b *oauth2.Token) bool {
@@ -25,6 +25,8 @@ type dcf struct {
 	prompt DeviceCodePrompt
 }
 
+var _ Reauthorizer = (*dcf)(nil)
+
 type DeviceCodePrompt func(userCode, verURI, verURIComplete string)
 
 // DeviceCodeFlow creates a TokenSource which retrieves tokens through the
@@ -91,6 +93,12 @@ func (s *dcf) Refresh(ctx context.Context, old *oauth2.Token) (*oauth2.Token, er
 	return s.flowLocked(ctx)
 }
 
+func (s *dcf) Reauthorize(ctx context.Context) (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flowLocked(ctx)
+}
+
 func (s *dcf) refreshLocked(ctx context.Context, rt string) (*oauth2.Token, error) {
 	// x/oauth2 doesn't expose anything to do token refresh, so we implement
 	// that manually here.
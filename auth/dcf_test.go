@@ -157,6 +157,26 @@ func TestDCFRefresh(t *testing.T) {
 	}
 }
 
+func TestDCFReauthorize(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	prompts, src := dcfFixture(t)
+	src.st.Store(ctx, &oauth2.Token{AccessToken: "ryou", RefreshToken: "bocchi", Expiry: time.Now().Add(time.Hour)})
+	tok, err := src.Reauthorize(ctx)
+	if err != nil {
+		t.Fatalf("couldn't reauthorize: %v", err)
+	}
+	if tok.AccessToken != "nijika" {
+		t.Errorf("wrong access token: want %q, got %q", "nijika", tok.AccessToken)
+	}
+	if *prompts != 1 {
+		t.Errorf("wrong number of prompts: want 1, got %d", *prompts)
+	}
+	if cur, _ := src.Token(ctx); !Equal(cur, tok) {
+		t.Errorf("new token not stored: got %+v", cur)
+	}
+}
+
 func TestDCFToken(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
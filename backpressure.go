@@ -0,0 +1,91 @@
+package main
+
+import (
+	"unicode"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/channel"
+	"github.com/zephyrtronium/robot/message"
+)
+
+// SetBackpressure sets the number of pending works beyond which the robot
+// drops low-value messages rather than learning them, and the number of
+// tokens below which a message is low-value.
+// If cfg.Pending is not positive, the robot learns everything.
+func (robo *Robot) SetBackpressure(cfg Backpressure) {
+	robo.backlog = int64(cfg.Pending)
+	robo.short = cfg.Short
+}
+
+// defaultQueue is the number of works which may wait for each channel when
+// the configuration doesn't set it.
+const defaultQueue = 1000
+
+// SetWorkQueue sets the number of works which may wait for each channel. Once a
+// channel's queue is full, the oldest waiting chat work is dropped to make
+// room. Moderation works are never dropped.
+// If n is not positive, the default is used.
+func (robo *Robot) SetWorkQueue(n int) {
+	if n <= 0 {
+		n = defaultQueue
+	}
+	robo.works.SetDepth(n)
+}
+
+// dropWork accounts for a work dropped from the worker pool without running.
+func (robo *Robot) dropWork(key string) {
+	robo.pending.Add(-1)
+	robo.queueDrops.Add(key, 1)
+}
+
+// dropReason returns the reason not to learn a message because the robot has
+// more pending work than it can handle while learning everything, or the
+// empty string if the message should be learned.
+func (robo *Robot) dropReason(ch *channel.Channel, m *message.Incoming) string {
+	if robo.backlog <= 0 || robo.pending.Load() <= robo.backlog {
+		return ""
+	}
+	return lowValue(ch.History, m.ID, m.Text, m.Emotes, robo.short)
+}
+
+// lowValue returns the reason a message is not worth learning when the robot
+// is overloaded, or the empty string if it is worth learning.
+// The message must already be in the history.
+// emotes is the spans of the text which are emotes.
+func lowValue(h *channel.History, id, text string, emotes []message.Span, short int) string {
+	if len(brain.Tokens(nil, text)) < short {
+		return "short"
+	}
+	if emoteOnly(text, emotes) {
+		return "emote-only"
+	}
+	for m := range h.All() {
+		if m.ID != id && m.Text == text {
+			return "duplicate"
+		}
+	}
+	return ""
+}
+
+// emoteOnly reports whether every non-space character in a message is part of
+// an emote.
+func emoteOnly(text string, emotes []message.Span) bool {
+	if len(emotes) == 0 {
+		return false
+	}
+	covered := make([]bool, len(text))
+	for _, e := range emotes {
+		if e.Start < 0 || e.End < e.Start || e.End > len(text) {
+			return false
+		}
+		for k := e.Start; k < e.End; k++ {
+			covered[k] = true
+		}
+	}
+	for i, c := range text {
+		if !covered[i] && !unicode.IsSpace(c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/channel"
+	"github.com/zephyrtronium/robot/message"
+)
+
+func TestEmoteOnly(t *testing.T) {
+	cases := []struct {
+		name   string
+		text   string
+		emotes []message.Span
+		want   bool
+	}{
+		{"none", "hello, world!", nil, false},
+		{"one", "Kappa", []message.Span{{Start: 0, End: 5, ID: "25"}}, true},
+		{"spaced", "Kappa Kappa", []message.Span{{Start: 0, End: 5, ID: "25"}, {Start: 6, End: 11, ID: "25"}}, true},
+		{"several", "Kappa PogChamp", []message.Span{{Start: 0, End: 5, ID: "25"}, {Start: 6, End: 14, ID: "305954156"}}, true},
+		{"text", "hello Kappa", []message.Span{{Start: 6, End: 11, ID: "25"}}, false},
+		{"unicode", "😂 Kappa", []message.Span{{Start: 5, End: 10, ID: "25"}}, false},
+		{"bad", "Kappa", []message.Span{{Start: 0, End: 9, ID: "25"}}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := emoteOnly(c.text, c.emotes); got != c.want {
+				t.Errorf("wrong result for %q with %v: want %t, got %t", c.text, c.emotes, c.want, got)
+			}
+		})
+	}
+}
+
+func TestLowValue(t *testing.T) {
+	var h channel.History
+	now := time.Now()
+	h.Add(now, "1", "bocchi", "the rock is rolling")
+	h.Add(now, "2", "ryou", "the rock is rolling")
+	h.Add(now, "3", "nijika", "drums are the best instrument")
+	cases := []struct {
+		name   string
+		id     string
+		text   string
+		emotes []message.Span
+		want   string
+	}{
+		{"short", "4", "hi", nil, "short"},
+		{"emote-only", "4", "Kappa Kappa Kappa", []message.Span{{Start: 0, End: 5, ID: "25"}, {Start: 6, End: 11, ID: "25"}, {Start: 12, End: 17, ID: "25"}}, "emote-only"},
+		{"duplicate", "2", "the rock is rolling", nil, "duplicate"},
+		{"unique", "3", "drums are the best instrument", nil, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := lowValue(&h, c.id, c.text, c.emotes, 3); got != c.want {
+				t.Errorf("wrong reason for %q: want %q, got %q", c.text, c.want, got)
+			}
+		})
+	}
+}
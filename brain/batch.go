@@ -0,0 +1,75 @@
+package brain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// Message is a message to learn as part of a batch.
+type Message struct {
+	// Tag is the tag under which to learn the message.
+	Tag string
+	// ID is the message ID.
+	ID string
+	// User is the hashed sender of the message.
+	User userhash.Hash
+	// Time is the time at which the message was sent.
+	Time time.Time
+	// Tuples is the tuples to learn from the message.
+	Tuples []Tuple
+}
+
+// Batcher is a learner which can learn a batch of messages atomically.
+type Batcher interface {
+	// LearnBatch records the tuples of each message. Either every message is
+	// learned, or none are.
+	LearnBatch(ctx context.Context, msgs []Message) error
+}
+
+// LearnBatch learns a batch of tokenized messages, all or nothing.
+// Each element of toks is the tokens of the corresponding message in msgs;
+// the messages' tuples are ignored. Messages with no tokens are skipped.
+//
+// The batch goes directly to the first [Batcher] in the chain of wrappers
+// starting at br, bypassing any wrappers before it.
+// If there is none, messages are learned one at a time, and
+// those already learned are forgotten if any fails. Forgetting may prevent
+// them from being learned again later.
+func LearnBatch(ctx context.Context, br Brain, msgs []Message, toks [][]string) error {
+	if len(msgs) != len(toks) {
+		panic(fmt.Errorf("brain.LearnBatch: %d messages but %d token lists", len(msgs), len(toks)))
+	}
+	b := make([]Message, 0, len(msgs))
+	for i, m := range msgs {
+		if len(toks[i]) == 0 {
+			continue
+		}
+		m.Tuples = tupleToks(make([]Tuple, 0, len(toks[i])+1), slices.Clone(toks[i]))
+		b = append(b, m)
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	if l, ok := As[Batcher](br); ok {
+		return l.LearnBatch(ctx, b)
+	}
+	for i, m := range b {
+		err := br.Learn(ctx, m.Tag, m.ID, m.User, m.Time, m.Tuples)
+		if err == nil {
+			continue
+		}
+		err = fmt.Errorf("couldn't learn message %v: %w", m.ID, err)
+		for _, m := range b[:i] {
+			if rerr := br.ForgetMessage(ctx, m.Tag, m.ID); rerr != nil {
+				err = errors.Join(err, fmt.Errorf("couldn't roll back message %v: %w", m.ID, rerr))
+			}
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package brain_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// failing is a brain which fails to learn a particular message and records
+// the messages it learns and forgets.
+type failing struct {
+	plain
+	fail            string
+	learned, forgot []string
+}
+
+func (f *failing) Learn(ctx context.Context, tag, id string, user userhash.Hash, t time.Time, tuples []brain.Tuple) error {
+	if id == f.fail {
+		return errors.New("failed")
+	}
+	f.learned = append(f.learned, id)
+	return nil
+}
+
+func (f *failing) ForgetMessage(ctx context.Context, tag, id string) error {
+	f.forgot = append(f.forgot, id)
+	return nil
+}
+
+// batcher is a brain which learns batches.
+type batcher struct {
+	plain
+	batch []brain.Message
+}
+
+func (b *batcher) LearnBatch(ctx context.Context, msgs []brain.Message) error {
+	b.batch = msgs
+	return nil
+}
+
+func TestLearnBatch(t *testing.T) {
+	ctx := context.Background()
+	msgs := []brain.Message{{Tag: "kessoku", ID: "1"}, {Tag: "kessoku", ID: "2"}, {Tag: "kessoku", ID: "3"}, {Tag: "kessoku", ID: "4"}}
+	toks := [][]string{strings.Fields("bocchi the rock"), nil, strings.Fields("kita"), strings.Fields("ryo")}
+	t.Run("batcher", func(t *testing.T) {
+		var b batcher
+		if err := brain.LearnBatch(ctx, wrapper{br: &b}, msgs, toks); err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]string, 0, len(b.batch))
+		for _, m := range b.batch {
+			ids = append(ids, m.ID)
+		}
+		if want := []string{"1", "3", "4"}; !slices.Equal(ids, want) {
+			t.Errorf("wrong messages in batch: want %q, got %q", want, ids)
+		}
+		if len(b.batch) > 0 && len(b.batch[0].Tuples) != 4 {
+			t.Errorf("wrong tuples for first message: %q", b.batch[0].Tuples)
+		}
+		if toks[0][0] != "bocchi" {
+			t.Errorf("tokens were modified: %q", toks[0])
+		}
+	})
+	t.Run("fallback", func(t *testing.T) {
+		f := failing{fail: "4"}
+		if err := brain.LearnBatch(ctx, &f, msgs, toks); err == nil {
+			t.Error("batch succeeded despite failure")
+		}
+		if want := []string{"1", "3"}; !slices.Equal(f.learned, want) {
+			t.Errorf("wrong messages learned: want %q, got %q", want, f.learned)
+		}
+		if want := []string{"1", "3"}; !slices.Equal(f.forgot, want) {
+			t.Errorf("wrong messages rolled back: want %q, got %q", want, f.forgot)
+		}
+	})
+}
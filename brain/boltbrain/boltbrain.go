@@ -0,0 +1,81 @@
+// Package boltbrain implements a brain in a single bbolt database file.
+//
+// It is meant for small deployments where Badger's background compaction and
+// directory of files are more than needed. Everything about a message,
+// including what is needed to forget it, is stored in the file, so nothing
+// is lost across restarts.
+package boltbrain
+
+import (
+	"errors"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/zephyrtronium/robot/brain"
+)
+
+/*
+Layout:
+Each tag has a top-level bucket named "tag:" followed by the tag, holding
+four buckets.
+- knowledge maps tuples to suffixes. Keys are the prefix terms in reverse
+	order, each followed by \xff, then \xff, then the message ID. As in kvbrain,
+	the start of a message has an empty prefix, so its key is \xff followed by
+	the ID.
+- messages maps message IDs to the time, userhash, and knowledge keys of the
+	message, so that it can be forgotten.
+- times has keys of the message time followed by its ID, for forgetting
+	spans of time.
+- users has keys of the userhash followed by a message ID, for forgetting
+	users.
+*/
+
+var (
+	bucketKnowledge = []byte("knowledge")
+	bucketMessages  = []byte("messages")
+	bucketTimes     = []byte("times")
+	bucketUsers     = []byte("users")
+)
+
+// tagPrefix starts the names of tag buckets.
+const tagPrefix = "tag:"
+
+// Brain is a brain backed by a bbolt database.
+type Brain struct {
+	db *bbolt.DB
+}
+
+var (
+	_ brain.Brain   = (*Brain)(nil)
+	_ brain.Batcher = (*Brain)(nil)
+)
+
+// New creates a brain using a bbolt database.
+func New(db *bbolt.DB) *Brain {
+	return &Brain{db: db}
+}
+
+// tagBucket returns the bucket for a tag, or nil if nothing has been learned
+// under it.
+func tagBucket(tx *bbolt.Tx, tag string) *bbolt.Bucket {
+	return tx.Bucket([]byte(tagPrefix + tag))
+}
+
+// createTag returns the bucket for a tag, creating it and its contents if
+// needed.
+func createTag(tx *bbolt.Tx, tag string) (*bbolt.Bucket, error) {
+	b, err := tx.CreateBucketIfNotExists([]byte(tagPrefix + tag))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create bucket for tag %q: %w", tag, err)
+	}
+	for _, name := range [][]byte{bucketKnowledge, bucketMessages, bucketTimes, bucketUsers} {
+		if _, err := b.CreateBucketIfNotExists(name); err != nil {
+			return nil, fmt.Errorf("couldn't create %s bucket for tag %q: %w", name, tag, err)
+		}
+	}
+	return b, nil
+}
+
+// errCorrupt is returned when a message record can't be decoded.
+var errCorrupt = errors.New("corrupt message record")
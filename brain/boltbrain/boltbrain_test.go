@@ -0,0 +1,91 @@
+package boltbrain_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/boltbrain"
+	"github.com/zephyrtronium/robot/brain/braintest"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+func testDB(t testing.TB) *bbolt.DB {
+	t.Helper()
+	db, err := bbolt.Open(filepath.Join(t.TempDir(), "brain.db"), 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestBrain(t *testing.T) {
+	braintest.Test(context.Background(), t, func(ctx context.Context) brain.Brain {
+		return boltbrain.New(testDB(t))
+	})
+}
+
+func TestReopen(t *testing.T) {
+	ctx := context.Background()
+	file := filepath.Join(t.TempDir(), "brain.db")
+	db, err := bbolt.Open(file, 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	br := boltbrain.New(db)
+	if err := brain.Learn(ctx, br, "kessoku", "1", userhash.Hash{1}, time.Unix(1, 0), brain.Tokens(nil, "bocchi plays guitar")); err != nil {
+		t.Fatalf("couldn't learn: %v", err)
+	}
+	if err := brain.Learn(ctx, br, "kessoku", "2", userhash.Hash{2}, time.Unix(2, 0), brain.Tokens(nil, "nijika plays drums")); err != nil {
+		t.Fatalf("couldn't learn: %v", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Forgetting must still work after reopening, since everything needed
+	// is in the file.
+	db, err = bbolt.Open(file, 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	br = boltbrain.New(db)
+	if err := br.ForgetUser(ctx, &userhash.Hash{1}); err != nil {
+		t.Fatalf("couldn't forget user: %v", err)
+	}
+	for range 32 {
+		s, trace, err := brain.Speak(ctx, br, "kessoku", "")
+		if err != nil {
+			t.Fatalf("couldn't speak: %v", err)
+		}
+		if s != "nijika plays drums" || len(trace) != 1 || trace[0] != "2" {
+			t.Errorf("wrong speech after forgetting user: %q from %q", s, trace)
+		}
+	}
+	st, err := br.Stats(ctx, "kessoku")
+	if err != nil {
+		t.Fatalf("couldn't get stats: %v", err)
+	}
+	if want := (brain.Stats{Tuples: 4, Messages: 1}); *st != want {
+		t.Errorf("wrong stats: want %+v, got %+v", want, *st)
+	}
+}
+
+func BenchmarkLearn(b *testing.B) {
+	new := func(ctx context.Context, b *testing.B) brain.Learner {
+		return boltbrain.New(testDB(b))
+	}
+	braintest.BenchLearn(context.Background(), b, new, nil)
+}
+
+func BenchmarkSpeak(b *testing.B) {
+	new := func(ctx context.Context, b *testing.B) brain.Brain {
+		return boltbrain.New(testDB(b))
+	}
+	braintest.BenchSpeak(context.Background(), b, new, nil)
+}
@@ -0,0 +1,117 @@
+package boltbrain
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// ForgetMessage forgets everything learned from a single given message.
+// If nothing has been learned from the message, it is ignored.
+func (br *Brain) ForgetMessage(ctx context.Context, tag, id string) error {
+	err := br.db.Update(func(tx *bbolt.Tx) error {
+		b := tagBucket(tx, tag)
+		if b == nil {
+			return nil
+		}
+		return forget(b, []byte(id))
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't commit deleting message %v: %w", id, err)
+	}
+	return nil
+}
+
+// ForgetDuring forgets all messages learned in the given time span.
+func (br *Brain) ForgetDuring(ctx context.Context, tag string, since, before time.Time) error {
+	err := br.db.Update(func(tx *bbolt.Tx) error {
+		b := tagBucket(tx, tag)
+		if b == nil {
+			return nil
+		}
+		lo, hi := appendTime(nil, since), appendTime(nil, before)
+		var ids [][]byte
+		c := b.Bucket(bucketTimes).Cursor()
+		for k, _ := c.Seek(lo); k != nil && bytes.Compare(k[:8], hi) <= 0; k, _ = c.Next() {
+			ids = append(ids, bytes.Clone(k[8:]))
+		}
+		return forgetAll(ctx, b, ids)
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't commit deleting between times %v and %v: %w", since, before, err)
+	}
+	return nil
+}
+
+// ForgetUser forgets all messages associated with a userhash.
+func (br *Brain) ForgetUser(ctx context.Context, user *userhash.Hash) error {
+	err := br.db.Update(func(tx *bbolt.Tx) error {
+		c := tx.Cursor()
+		for name, _ := c.Seek([]byte(tagPrefix)); name != nil && bytes.HasPrefix(name, []byte(tagPrefix)); name, _ = c.Next() {
+			b := tx.Bucket(name)
+			var ids [][]byte
+			uc := b.Bucket(bucketUsers).Cursor()
+			for k, _ := uc.Seek(user[:]); k != nil && bytes.HasPrefix(k, user[:]); k, _ = uc.Next() {
+				ids = append(ids, bytes.Clone(k[len(user):]))
+			}
+			if err := forgetAll(ctx, b, ids); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't commit deleting messages by user: %w", err)
+	}
+	return nil
+}
+
+// forgetAll forgets a list of messages in a tag bucket.
+func forgetAll(ctx context.Context, b *bbolt.Bucket, ids [][]byte) error {
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := forget(b, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forget deletes a message and everything learned from it from a tag bucket.
+func forget(b *bbolt.Bucket, id []byte) error {
+	msgs := b.Bucket(bucketMessages)
+	rec := bytes.Clone(msgs.Get(id))
+	if rec == nil {
+		return nil
+	}
+	if len(rec) < 8+len(userhash.Hash{}) {
+		return fmt.Errorf("%w for message %s", errCorrupt, id)
+	}
+	tm, user, keys := rec[:8], rec[8:8+len(userhash.Hash{})], rec[8+len(userhash.Hash{}):]
+	know := b.Bucket(bucketKnowledge)
+	for len(keys) > 0 {
+		n, k := binary.Uvarint(keys)
+		if k <= 0 || uint64(len(keys)-k) < n {
+			return fmt.Errorf("%w for message %s", errCorrupt, id)
+		}
+		if err := know.Delete(keys[k : k+int(n)]); err != nil {
+			return err
+		}
+		keys = keys[k+int(n):]
+	}
+	if err := b.Bucket(bucketTimes).Delete(append(tm[:8:8], id...)); err != nil {
+		return err
+	}
+	if err := b.Bucket(bucketUsers).Delete(append(user[:len(user):len(user)], id...)); err != nil {
+		return err
+	}
+	return msgs.Delete(id)
+}
@@ -0,0 +1,106 @@
+package boltbrain
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// Learn records a set of tuples.
+// If a message with the same tag and ID is already known, Learn does nothing.
+func (br *Brain) Learn(ctx context.Context, tag, id string, user userhash.Hash, t time.Time, tuples []brain.Tuple) error {
+	if len(tuples) == 0 {
+		return errors.New("no tuples to learn")
+	}
+	err := br.db.Update(func(tx *bbolt.Tx) error {
+		return learn(tx, tag, id, user, t, tuples)
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't commit learned knowledge: %w", err)
+	}
+	return nil
+}
+
+// LearnBatch records the tuples of each message in a single transaction.
+// Messages already known are skipped.
+func (br *Brain) LearnBatch(ctx context.Context, msgs []brain.Message) error {
+	err := br.db.Update(func(tx *bbolt.Tx) error {
+		for _, m := range msgs {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if len(m.Tuples) == 0 {
+				return fmt.Errorf("no tuples to learn from message %v", m.ID)
+			}
+			if err := learn(tx, m.Tag, m.ID, m.User, m.Time, m.Tuples); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't commit learned knowledge: %w", err)
+	}
+	return nil
+}
+
+// learn records a message within a transaction.
+func learn(tx *bbolt.Tx, tag, id string, user userhash.Hash, t time.Time, tuples []brain.Tuple) error {
+	b, err := createTag(tx, tag)
+	if err != nil {
+		return err
+	}
+	msgs := b.Bucket(bucketMessages)
+	if msgs.Get([]byte(id)) != nil {
+		return nil
+	}
+	know := b.Bucket(bucketKnowledge)
+	// Keys and values passed to Put must stay valid for the whole
+	// transaction, so each gets its own allocation.
+	rec := make([]byte, 0, 8+len(user)+len(tuples)*32)
+	rec = appendTime(rec, t)
+	rec = append(rec, user[:]...)
+	for _, tup := range tuples {
+		key := append(appendPrefix(nil, tup.Prefix), '\xff')
+		key = append(key, id...)
+		if err := know.Put(key, []byte(tup.Suffix)); err != nil {
+			return fmt.Errorf("couldn't record tuple: %w", err)
+		}
+		rec = binary.AppendUvarint(rec, uint64(len(key)))
+		rec = append(rec, key...)
+	}
+	if err := msgs.Put([]byte(id), rec); err != nil {
+		return fmt.Errorf("couldn't record message: %w", err)
+	}
+	tk := append(appendTime(nil, t), id...)
+	if err := b.Bucket(bucketTimes).Put(tk, []byte{}); err != nil {
+		return fmt.Errorf("couldn't record message time: %w", err)
+	}
+	uk := append(user[:len(user):len(user)], id...)
+	if err := b.Bucket(bucketUsers).Put(uk, []byte{}); err != nil {
+		return fmt.Errorf("couldn't record message sender: %w", err)
+	}
+	return nil
+}
+
+// appendTime appends a time to b such that byte order is time order.
+func appendTime(b []byte, t time.Time) []byte {
+	return binary.BigEndian.AppendUint64(b, uint64(t.UnixNano())^(1<<63))
+}
+
+// appendPrefix appends the prefix components for a knowledge key to b,
+// not including the sentinel marking the end of the prefix.
+func appendPrefix(b []byte, prefix []string) []byte {
+	for _, w := range prefix {
+		b = append(b, w...)
+		b = append(b, '\xff')
+	}
+	return b
+}
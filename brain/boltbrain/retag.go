@@ -0,0 +1,51 @@
+package boltbrain
+
+import (
+	"context"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/zephyrtronium/robot/brain"
+)
+
+var _ brain.Retagger = (*Brain)(nil)
+
+// Retag moves everything learned under one tag to another.
+// Buckets can't be renamed, so this copies every record.
+func (br *Brain) Retag(ctx context.Context, from, to string) (int64, error) {
+	var n int64
+	err := br.db.Update(func(tx *bbolt.Tx) error {
+		src := tagBucket(tx, from)
+		if src == nil {
+			return nil
+		}
+		dst, err := createTag(tx, to)
+		if err != nil {
+			return err
+		}
+		for _, name := range [][]byte{bucketKnowledge, bucketMessages, bucketTimes, bucketUsers} {
+			s, d := src.Bucket(name), dst.Bucket(name)
+			c := s.Cursor()
+			for k, v := c.First(); k != nil; k, v = c.Next() {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if string(name) == string(bucketMessages) {
+					if d.Get(k) != nil {
+						return fmt.Errorf("message %s is learned under both tags", k)
+					}
+					n++
+				}
+				if err := d.Put(k, v); err != nil {
+					return err
+				}
+			}
+		}
+		return tx.DeleteBucket([]byte(tagPrefix + from))
+	})
+	if err != nil {
+		return 0, fmt.Errorf("couldn't move knowledge from %q to %q: %w", from, to, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,98 @@
+package boltbrain
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand/v2"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/deque"
+	"github.com/zephyrtronium/robot/tpool"
+)
+
+var prependerPool tpool.Pool[deque.Deque[string]]
+
+// Speak generates a full message and appends it to w.
+// The prompt is in reverse order and has entropy reduction applied.
+func (br *Brain) Speak(ctx context.Context, tag string, prompt []string, w *brain.Builder) error {
+	search := prependerPool.Get().Prepend(prompt...)
+	defer func() { prependerPool.Put(search.Reset()) }()
+
+	// Generate the whole message from one snapshot, so that concurrent
+	// forgetting can't remove a term between choosing and reading it.
+	err := br.db.View(func(tx *bbolt.Tx) error {
+		b := tagBucket(tx, tag)
+		if b == nil {
+			return nil
+		}
+		know := b.Bucket(bucketKnowledge)
+		buf := make([]byte, 0, 128)
+		for range 1024 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			term, id, l := next(know, buf, search.Slice())
+			if len(term) == 0 {
+				break
+			}
+			w.Append(string(id), term)
+			search = search.DropEnd(search.Len() - l - 1).Prepend(brain.ReduceEntropy(string(term)))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't read knowledge: %w", err)
+	}
+	return nil
+}
+
+// next finds a single term to continue a prompt.
+// The returned values are, in order, the new term, the ID of the message used
+// for the term, and the number of terms of the prompt which matched to
+// produce the new term. The term and ID are only valid during the
+// transaction. If the term is empty, generation should end.
+func next(know *bbolt.Bucket, b []byte, prompt []string) ([]byte, []byte, int) {
+	var (
+		key, val []byte
+		skip     brain.Skip
+		seen     int
+		n        uint64
+	)
+	b = appendPrefix(b[:0], prompt)
+	if len(prompt) == 0 {
+		// If we have no prompt, then we want to make sure we select only
+		// options that start a message.
+		b = append(b, '\xff')
+	}
+	for {
+		c := know.Cursor()
+		for k, v := c.Seek(b); k != nil && bytes.HasPrefix(k, b); k, v = c.Next() {
+			seen++
+			if n == 0 {
+				key, val = k, v
+				n = skip.N(rand.Uint64(), rand.Uint64())
+			}
+			n--
+		}
+		if seen < 3 && len(prompt) > 3 {
+			// We haven't seen enough options, and we have context we could
+			// lose. Do so and try again from the beginning.
+			prompt = prompt[:len(prompt)-1]
+			b = appendPrefix(b[:0], prompt)
+			continue
+		}
+		if key == nil {
+			return nil, nil, len(prompt)
+		}
+		// The ID is everything after the \xff ending an empty prefix, or
+		// after the first \xff\xff otherwise.
+		id := key[1:]
+		if len(prompt) > 0 {
+			_, id, _ = bytes.Cut(key, []byte{0xff, 0xff})
+		}
+		return val, id, len(prompt)
+	}
+}
@@ -0,0 +1,95 @@
+package boltbrain
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/zephyrtronium/robot/brain"
+)
+
+var (
+	_ brain.Stater    = (*Brain)(nil)
+	_ brain.Backuper  = (*Brain)(nil)
+	_ brain.Continuer = (*Brain)(nil)
+)
+
+// Stats counts the tuples and messages learned under a tag.
+func (br *Brain) Stats(ctx context.Context, tag string) (*brain.Stats, error) {
+	var r brain.Stats
+	err := br.db.View(func(tx *bbolt.Tx) error {
+		b := tagBucket(tx, tag)
+		if b == nil {
+			return nil
+		}
+		var err error
+		if r.Tuples, err = count(ctx, b.Bucket(bucketKnowledge)); err != nil {
+			return err
+		}
+		r.Messages, err = count(ctx, b.Bucket(bucketMessages))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't count knowledge: %w", err)
+	}
+	return &r, nil
+}
+
+// count counts the keys in a bucket.
+func count(ctx context.Context, b *bbolt.Bucket) (int64, error) {
+	var n int64
+	c := b.Cursor()
+	for k, _ := c.First(); k != nil; k, _ = c.Next() {
+		if n%4096 == 0 {
+			if err := ctx.Err(); err != nil {
+				return n, err
+			}
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Backup writes a consistent copy of the brain's database file to w.
+// The copy can be opened directly with bbolt.
+func (br *Brain) Backup(ctx context.Context, w io.Writer) error {
+	err := br.db.View(func(tx *bbolt.Tx) error {
+		_, err := tx.WriteTo(w)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't back up knowledge: %w", err)
+	}
+	return nil
+}
+
+// Continuations counts the terms learned to follow a prompt.
+func (br *Brain) Continuations(ctx context.Context, tag string, prompt []string) (map[string]int, error) {
+	p := appendPrefix(nil, prompt)
+	if len(prompt) == 0 {
+		// Only count terms which start messages.
+		p = append(p, '\xff')
+	}
+	r := make(map[string]int)
+	err := br.db.View(func(tx *bbolt.Tx) error {
+		b := tagBucket(tx, tag)
+		if b == nil {
+			return nil
+		}
+		c := b.Bucket(bucketKnowledge).Cursor()
+		for k, v := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, v = c.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			r[string(v)]++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read continuations: %w", err)
+	}
+	return r, nil
+}
@@ -0,0 +1,136 @@
+package boltbrain_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/boltbrain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	br := boltbrain.New(testDB(t))
+	msgs := []struct {
+		tag, id, text string
+	}{
+		{"kessoku", "1", "bocchi the rock"},
+		{"kessoku", "2", "kita"},
+		{"sickhack", "3", "kikuri"},
+	}
+	for _, m := range msgs {
+		err := brain.Learn(ctx, br, m.tag, m.id, userhash.Hash{}, time.Unix(0, 0), strings.Fields(m.text))
+		if err != nil {
+			t.Fatalf("couldn't learn %q: %v", m.text, err)
+		}
+	}
+	if err := br.ForgetMessage(ctx, "kessoku", "2"); err != nil {
+		t.Fatalf("couldn't forget: %v", err)
+	}
+	st, err := br.Stats(ctx, "kessoku")
+	if err != nil {
+		t.Fatalf("couldn't get stats: %v", err)
+	}
+	if want := (brain.Stats{Tuples: 4, Messages: 1}); *st != want {
+		t.Errorf("wrong stats: want %+v, got %+v", want, *st)
+	}
+	st, err = br.Stats(ctx, "nothing")
+	if err != nil {
+		t.Fatalf("couldn't get stats for unknown tag: %v", err)
+	}
+	if *st != (brain.Stats{}) {
+		t.Errorf("wrong stats for unknown tag: %+v", *st)
+	}
+	c, err := br.Continuations(ctx, "kessoku", []string{"bocchi"})
+	if err != nil {
+		t.Fatalf("couldn't get continuations: %v", err)
+	}
+	if len(c) != 1 || c["the"] != 1 {
+		t.Errorf("wrong continuations of bocchi: %v", c)
+	}
+	c, err = br.Continuations(ctx, "kessoku", nil)
+	if err != nil {
+		t.Fatalf("couldn't get continuations: %v", err)
+	}
+	if len(c) != 1 || c["bocchi"] != 1 {
+		t.Errorf("wrong starting continuations: %v", c)
+	}
+}
+
+func TestBackup(t *testing.T) {
+	ctx := context.Background()
+	br := boltbrain.New(testDB(t))
+	if err := brain.Learn(ctx, br, "sickhack", "1", userhash.Hash{}, time.Unix(0, 0), brain.Tokens(nil, "kikuri drinks")); err != nil {
+		t.Fatalf("couldn't learn: %v", err)
+	}
+	var b bytes.Buffer
+	if err := br.Backup(ctx, &b); err != nil {
+		t.Fatalf("couldn't back up: %v", err)
+	}
+	// The backup is itself a database.
+	file := filepath.Join(t.TempDir(), "backup.db")
+	if err := os.WriteFile(file, b.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	db, err := bbolt.Open(file, 0o600, nil)
+	if err != nil {
+		t.Fatalf("couldn't open backup: %v", err)
+	}
+	defer db.Close()
+	s, _, err := brain.Speak(ctx, boltbrain.New(db), "sickhack", "")
+	if err != nil {
+		t.Fatalf("couldn't speak from backup: %v", err)
+	}
+	if s != "kikuri drinks" {
+		t.Errorf("wrong speech from backup: %q", s)
+	}
+}
+
+func TestRetag(t *testing.T) {
+	ctx := context.Background()
+	br := boltbrain.New(testDB(t))
+	msgs := []struct {
+		tag, id, text string
+	}{
+		{"kessoku", "1", "bocchi the rock"},
+		{"kessoku", "2", "kita"},
+		{"sickhack", "3", "kikuri"},
+	}
+	for _, m := range msgs {
+		err := brain.Learn(ctx, br, m.tag, m.id, userhash.Hash{1}, time.Unix(1, 0), strings.Fields(m.text))
+		if err != nil {
+			t.Fatalf("couldn't learn %q: %v", m.text, err)
+		}
+	}
+	n, err := br.Retag(ctx, "kessoku", "twitch/kessoku")
+	if err != nil {
+		t.Fatalf("couldn't retag: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("wrong number of messages moved: want 2, got %d", n)
+	}
+	if st, err := br.Stats(ctx, "kessoku"); err != nil || *st != (brain.Stats{}) {
+		t.Errorf("knowledge left under old tag: %+v, %v", st, err)
+	}
+	if st, err := br.Stats(ctx, "twitch/kessoku"); err != nil || *st != (brain.Stats{Tuples: 6, Messages: 2}) {
+		t.Errorf("wrong knowledge under new tag: %+v, %v", st, err)
+	}
+	// Forgetting still works after moving.
+	if err := br.ForgetUser(ctx, &userhash.Hash{1}); err != nil {
+		t.Fatalf("couldn't forget user: %v", err)
+	}
+	if st, err := br.Stats(ctx, "twitch/kessoku"); err != nil || *st != (brain.Stats{}) {
+		t.Errorf("knowledge left after forgetting: %+v, %v", st, err)
+	}
+	if n, err := br.Retag(ctx, "nothing", "twitch/nothing"); err != nil || n != 0 {
+		t.Errorf("wrong result retagging unknown tag: %d, %v", n, err)
+	}
+}
@@ -1,3 +1,11 @@
+// Package brain defines the Markov chain engine used by Robot: tokenization,
+// learning, and generation, independent of any particular storage.
+//
+// The package and its in-memory implementation in [membrain] have no
+// dependencies on chat platforms or databases and build for js/wasm, so they
+// can be embedded in other programs.
+//
+// [membrain]: https://pkg.go.dev/github.com/zephyrtronium/robot/brain/membrain
 package brain
 
 // Brain is a combined [Learner] and [Speaker].
@@ -1,8 +1,11 @@
 // Package braintest provides integration testing facilities for brains.
+// Implementations of [brain.Brain] outside this module can run [Test] to
+// verify that they behave as the rest of the bot expects.
 package braintest
 
 import (
 	"context"
+	"runtime"
 	"slices"
 	"strings"
 	"testing"
@@ -14,6 +17,10 @@ import (
 )
 
 // Test runs the integration test suite against brains produced by new.
+// Besides fixed examples, the suite checks properties over random messages:
+// forgetting undoes learning, the order of tuples doesn't matter, repeated
+// learns and forgets have no further effect, concurrent use is safe, and
+// traces name the messages that were spoken.
 //
 // If a brain cannot be created without error, new should call t.Fatal.
 func Test(ctx context.Context, t *testing.T, new func(context.Context) brain.Brain) {
@@ -21,6 +28,12 @@ func Test(ctx context.Context, t *testing.T, new func(context.Context) brain.Bra
 	t.Run("forgetMessage", testForgetMessage(ctx, new(ctx)))
 	t.Run("forgetDuring", testForgetDuring(ctx, new(ctx)))
 	t.Run("combinatoric", testCombinatoric(ctx, new(ctx)))
+	t.Run("relearn", testRelearn(ctx, new(ctx)))
+	t.Run("forgetSymmetry", testForgetSymmetry(ctx, new(ctx)))
+	t.Run("tupleOrder", testTupleOrder(ctx, new(ctx)))
+	t.Run("idempotent", testIdempotent(ctx, new(ctx)))
+	t.Run("concurrent", testConcurrent(ctx, new(ctx)))
+	t.Run("trace", testTrace(ctx, new(ctx)))
 }
 
 func these(s ...string) func() []string {
@@ -233,6 +246,24 @@ func testForgetMessage(ctx context.Context, br brain.Brain) func(t *testing.T) {
 	}
 }
 
+// testRelearn tests that a brain ignores messages with IDs it already knows.
+func testRelearn(ctx context.Context, br brain.Brain) func(t *testing.T) {
+	return func(t *testing.T) {
+		learn(ctx, t, br)
+		m := messages[len(messages)-1]
+		if err := brain.Learn(ctx, br, m.Tag, m.ID, m.User, m.Time, []string{"manager ", "kikuri "}); err != nil {
+			t.Errorf("couldn't relearn message %v: %v", m.ID, err)
+		}
+		got := speak(ctx, t, br, "sickhack", "manager", 32)
+		want := map[string]struct{}{
+			"9#manager seika": {},
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("wrong prompted messages after relearning (+got/-want):\n%s", diff)
+		}
+	}
+}
+
 // testForgetDuring tests that a brain can forget messages in a time range.
 func testForgetDuring(ctx context.Context, br brain.Brain) func(t *testing.T) {
 	return func(t *testing.T) {
@@ -276,8 +307,6 @@ func testForgetDuring(ctx context.Context, br brain.Brain) func(t *testing.T) {
 	}
 }
 
-// TODO(zeph): testForgetUser
-
 // testCombinatoric tests that chains can generate even with substantial
 // overlap in learned material.
 func testCombinatoric(ctx context.Context, br brain.Brain) func(t *testing.T) {
@@ -306,12 +335,20 @@ func testCombinatoric(ctx context.Context, br brain.Brain) func(t *testing.T) {
 				}
 			}
 		}
-		allocs := testing.AllocsPerRun(10, func() {
+		// testing.AllocsPerRun refuses to run in parallel tests, and brains'
+		// tests are often parallel, so count allocations directly. Other
+		// tests running at the same time inflate the count, so it's only a
+		// rough guide.
+		const runs = 10
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		for range runs {
 			_, _, err := brain.Speak(ctx, br, "bocchi", "")
 			if err != nil {
 				t.Errorf("couldn't speak: %v", err)
 			}
-		})
-		t.Logf("speaking cost %v allocs per run", allocs)
+		}
+		runtime.ReadMemStats(&after)
+		t.Logf("speaking cost about %d allocs per run", (after.Mallocs-before.Mallocs)/runs)
 	}
 }
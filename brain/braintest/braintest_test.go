@@ -4,7 +4,6 @@ import (
 	"context"
 	"math/rand/v2"
 	"slices"
-	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -37,17 +36,30 @@ func (m *membrain) Learn(ctx context.Context, tag, id string, user userhash.Hash
 		m.tups[tag] = make(map[string][][2]string)
 		m.tms[tag] = make(map[int64][]string)
 	}
+	for _, v := range m.tups[tag][""] {
+		if v[0] == id {
+			return nil
+		}
+	}
 	m.users[user] = append(m.users[user], [2]string{tag, id})
 	tms := m.tms[tag]
 	tms[t.UnixNano()] = append(tms[t.UnixNano()], id)
 	r := m.tups[tag]
 	for _, tup := range tuples {
-		p := strings.Join(tup.Prefix, "\xff")
+		p := last(tup.Prefix)
 		r[p] = append(r[p], [2]string{id, tup.Suffix})
 	}
 	return nil
 }
 
+// last returns the most recent term of a prefix.
+func last(prefix []string) string {
+	if len(prefix) == 0 {
+		return ""
+	}
+	return prefix[0]
+}
+
 func (m *membrain) forgetIDLocked(tag, id string) {
 	for p, u := range m.tups[tag] {
 		for len(u) > 0 {
@@ -70,7 +82,7 @@ func (m *membrain) Forget(ctx context.Context, tag string, tuples []brain.Tuple)
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	for _, tup := range tuples {
-		p := strings.Join(tup.Prefix, "\xff")
+		p := last(tup.Prefix)
 		u := m.tups[tag][p]
 		k := slices.IndexFunc(u, func(v [2]string) bool { return v[1] == tup.Suffix })
 		if k < 0 {
@@ -0,0 +1,344 @@
+package braintest
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// sample is a randomly generated message. No two samples share a word, so
+// anything a brain says from samples must be exactly one of them.
+type sample struct {
+	ID   string
+	User userhash.Hash
+	Time time.Time
+	Text string
+}
+
+func (s *sample) tokens() []string {
+	return brain.Tokens(nil, s.Text)
+}
+
+// samples generates n samples with distinct words. Senders are chosen from
+// users distinct userhashes, and times are one second apart from the epoch.
+func samples(rng *rand.Rand, n, users int) []sample {
+	r := make([]sample, n)
+	for i := range r {
+		w := make([]string, 1+rng.IntN(8))
+		for j := range w {
+			// Lowercase only so that entropy reduction keeps words distinct.
+			w[j] = word(i*16+j) + "_" + word(rng.IntN(1<<20))
+		}
+		r[i] = sample{
+			ID:   "s" + strconv.Itoa(i),
+			User: userhash.Hash{byte(1 + rng.IntN(users))},
+			Time: time.Unix(int64(i), 0),
+			Text: strings.Join(w, " "),
+		}
+	}
+	return r
+}
+
+// word spells a number in letters.
+func word(k int) string {
+	var b []byte
+	for {
+		b = append(b, byte('a'+k%26))
+		k /= 26
+		if k == 0 {
+			return string(b)
+		}
+	}
+}
+
+// newRand creates a random source for a property test and logs its seed so
+// that failures can be reproduced.
+func newRand(t *testing.T) *rand.Rand {
+	t.Helper()
+	seed := rand.Uint64()
+	t.Logf("seed %#x", seed)
+	return rand.New(rand.NewPCG(seed, seed))
+}
+
+func learnSamples(ctx context.Context, t *testing.T, br brain.Learner, tag string, s []sample) {
+	t.Helper()
+	for _, m := range s {
+		if err := brain.Learn(ctx, br, tag, m.ID, m.User, m.Time, m.tokens()); err != nil {
+			t.Fatalf("couldn't learn sample %v: %v", m.ID, err)
+		}
+	}
+}
+
+// checkSpoken speaks repeatedly and verifies that everything said is exactly
+// the text of an allowed sample with a trace of only that sample's ID.
+// It returns the IDs of the samples spoken.
+func checkSpoken(ctx context.Context, t *testing.T, br brain.Speaker, tag string, allowed []sample, iters int) map[string]bool {
+	t.Helper()
+	byID := make(map[string]*sample, len(allowed))
+	for i := range allowed {
+		byID[allowed[i].ID] = &allowed[i]
+	}
+	got := make(map[string]bool)
+	for range iters {
+		s, trace, err := brain.Speak(ctx, br, tag, "")
+		if err != nil {
+			t.Fatalf("couldn't speak: %v", err)
+		}
+		if s == "" && len(trace) == 0 {
+			if len(allowed) != 0 {
+				t.Errorf("spoke nothing with %d samples known", len(allowed))
+			}
+			continue
+		}
+		if len(trace) != 1 {
+			t.Errorf("wrong trace for %q: %q", s, trace)
+			continue
+		}
+		m := byID[trace[0]]
+		switch {
+		case m == nil:
+			t.Errorf("spoke %q traced to %q, which isn't allowed", s, trace[0])
+		case s != m.Text:
+			t.Errorf("spoke %q traced to %q, which is %q", s, trace[0], m.Text)
+		default:
+			got[m.ID] = true
+		}
+	}
+	return got
+}
+
+// testForgetSymmetry tests that forgetting messages by ID, time, and user
+// removes exactly what learning them added.
+func testForgetSymmetry(ctx context.Context, br brain.Brain) func(t *testing.T) {
+	return func(t *testing.T) {
+		rng := newRand(t)
+		s := samples(rng, 32, 4)
+		learnSamples(ctx, t, br, "symmetry", s)
+		learnSamples(ctx, t, br, "other", s)
+		other := slices.Clone(s)
+		checkSpoken(ctx, t, br, "symmetry", s, 256)
+		// Forget a random selection of messages by ID.
+		rng.Shuffle(len(s), func(i, j int) { s[i], s[j] = s[j], s[i] })
+		for _, m := range s[:8] {
+			if err := br.ForgetMessage(ctx, "symmetry", m.ID); err != nil {
+				t.Fatalf("couldn't forget message %v: %v", m.ID, err)
+			}
+		}
+		s = s[8:]
+		checkSpoken(ctx, t, br, "symmetry", s, 256)
+		// Forget a span of time.
+		slices.SortFunc(s, func(a, b sample) int { return a.Time.Compare(b.Time) })
+		since, before := s[4].Time.Add(-time.Millisecond), s[11].Time.Add(time.Millisecond)
+		if err := br.ForgetDuring(ctx, "symmetry", since, before); err != nil {
+			t.Fatalf("couldn't forget during: %v", err)
+		}
+		s = slices.Delete(s, 4, 12)
+		checkSpoken(ctx, t, br, "symmetry", s, 256)
+		// Forget a user.
+		u := s[0].User
+		if err := br.ForgetUser(ctx, &u); err != nil {
+			t.Fatalf("couldn't forget user: %v", err)
+		}
+		s = slices.DeleteFunc(s, func(m sample) bool { return m.User == u })
+		checkSpoken(ctx, t, br, "symmetry", s, 256)
+		// Forget everything left.
+		for _, m := range s {
+			if err := br.ForgetMessage(ctx, "symmetry", m.ID); err != nil {
+				t.Fatalf("couldn't forget message %v: %v", m.ID, err)
+			}
+		}
+		checkSpoken(ctx, t, br, "symmetry", nil, 32)
+		// The other tag is untouched except by forgetting the user.
+		other = slices.DeleteFunc(other, func(m sample) bool { return m.User == u })
+		checkSpoken(ctx, t, br, "other", other, 256)
+	}
+}
+
+// testTupleOrder tests that a brain doesn't depend on the order of tuples
+// passed to Learn.
+func testTupleOrder(ctx context.Context, br brain.Brain) func(t *testing.T) {
+	return func(t *testing.T) {
+		rng := newRand(t)
+		s := samples(rng, 16, 4)
+		for _, m := range s {
+			var tt []brain.Tuple
+			brain.Learn(ctx, learnerFunc(func(tuples []brain.Tuple) {
+				tt = make([]brain.Tuple, len(tuples))
+				for i, v := range tuples {
+					tt[i] = brain.Tuple{Prefix: slices.Clone(v.Prefix), Suffix: v.Suffix}
+				}
+			}), "order", m.ID, m.User, m.Time, m.tokens())
+			rng.Shuffle(len(tt), func(i, j int) { tt[i], tt[j] = tt[j], tt[i] })
+			if err := br.Learn(ctx, "order", m.ID, m.User, m.Time, tt); err != nil {
+				t.Fatalf("couldn't learn shuffled sample %v: %v", m.ID, err)
+			}
+		}
+		checkSpoken(ctx, t, br, "order", s, 256)
+	}
+}
+
+// learnerFunc is a Learner that captures the tuples it would learn.
+type learnerFunc func(tuples []brain.Tuple)
+
+func (f learnerFunc) Learn(ctx context.Context, tag, id string, user userhash.Hash, t time.Time, tuples []brain.Tuple) error {
+	f(tuples)
+	return nil
+}
+
+func (learnerFunc) ForgetMessage(ctx context.Context, tag, id string) error { return nil }
+
+func (learnerFunc) ForgetDuring(ctx context.Context, tag string, since, before time.Time) error {
+	return nil
+}
+
+func (learnerFunc) ForgetUser(ctx context.Context, user *userhash.Hash) error { return nil }
+
+// testIdempotent tests that repeating learns and forgets has no further
+// effect and that forgetting what was never learned is not an error.
+func testIdempotent(ctx context.Context, br brain.Brain) func(t *testing.T) {
+	return func(t *testing.T) {
+		rng := newRand(t)
+		s := samples(rng, 8, 2)
+		learnSamples(ctx, t, br, "idempotent", s)
+		learnSamples(ctx, t, br, "idempotent", s)
+		checkSpoken(ctx, t, br, "idempotent", s, 128)
+		for _, m := range s[:4] {
+			for range 2 {
+				if err := br.ForgetMessage(ctx, "idempotent", m.ID); err != nil {
+					t.Fatalf("couldn't forget message %v: %v", m.ID, err)
+				}
+			}
+		}
+		if err := br.ForgetMessage(ctx, "idempotent", "never"); err != nil {
+			t.Errorf("couldn't forget unknown message: %v", err)
+		}
+		if err := br.ForgetMessage(ctx, "nothing", s[4].ID); err != nil {
+			t.Errorf("couldn't forget message in unknown tag: %v", err)
+		}
+		if err := br.ForgetDuring(ctx, "idempotent", time.Unix(1e6, 0), time.Unix(1e6+1, 0)); err != nil {
+			t.Errorf("couldn't forget empty span: %v", err)
+		}
+		if err := br.ForgetUser(ctx, &userhash.Hash{0xff}); err != nil {
+			t.Errorf("couldn't forget unknown user: %v", err)
+		}
+		checkSpoken(ctx, t, br, "idempotent", s[4:], 128)
+		got, trace, err := brain.Speak(ctx, br, "nothing", "")
+		if err != nil {
+			t.Errorf("couldn't speak in unknown tag: %v", err)
+		}
+		if got != "" || len(trace) != 0 {
+			t.Errorf("spoke %q (trace %q) in unknown tag", got, trace)
+		}
+	}
+}
+
+// testConcurrent tests that a brain can learn, speak, and forget from many
+// goroutines at once. It is most useful with the race detector.
+func testConcurrent(ctx context.Context, br brain.Brain) func(t *testing.T) {
+	return func(t *testing.T) {
+		rng := newRand(t)
+		const workers = 8
+		s := samples(rng, 8*workers, 4)
+		var wg sync.WaitGroup
+		errs := make(chan error, 3*len(s))
+		for w := range workers {
+			part := s[w*8 : (w+1)*8]
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i, m := range part {
+					if err := brain.Learn(ctx, br, "concurrent", m.ID, m.User, m.Time, m.tokens()); err != nil {
+						errs <- fmt.Errorf("couldn't learn %v: %w", m.ID, err)
+					}
+					if _, _, err := brain.Speak(ctx, br, "concurrent", ""); err != nil {
+						errs <- fmt.Errorf("couldn't speak: %w", err)
+					}
+					if i%4 == 3 {
+						if err := br.ForgetMessage(ctx, "concurrent", m.ID); err != nil {
+							errs <- fmt.Errorf("couldn't forget %v: %w", m.ID, err)
+						}
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Error(err)
+		}
+		kept := make([]sample, 0, len(s))
+		for i, m := range s {
+			if i%4 != 3 {
+				kept = append(kept, m)
+			}
+		}
+		checkSpoken(ctx, t, br, "concurrent", kept, 256)
+	}
+}
+
+// testTrace tests that traces name the messages that contributed to what
+// was spoken, including when speaking from a prompt.
+func testTrace(ctx context.Context, br brain.Brain) func(t *testing.T) {
+	return func(t *testing.T) {
+		rng := newRand(t)
+		s := samples(rng, 16, 4)
+		learnSamples(ctx, t, br, "trace", s)
+		for _, m := range s {
+			toks := strings.Fields(m.Text)
+			got, trace, err := brain.Speak(ctx, br, "trace", toks[0])
+			if err != nil {
+				t.Fatalf("couldn't speak: %v", err)
+			}
+			if len(toks) == 1 {
+				// The prompt is the whole message, so there may be
+				// nothing more to say.
+				if len(trace) != 0 && (len(trace) != 1 || trace[0] != m.ID) {
+					t.Errorf("wrong trace for prompt %q: want [%v] or nothing, got %q", toks[0], m.ID, trace)
+				}
+				continue
+			}
+			if got != m.Text || !slices.Equal(trace, []string{m.ID}) {
+				t.Errorf("wrong speech for prompt %q: want %q with trace [%v], got %q with %q", toks[0], m.Text, m.ID, got, trace)
+			}
+		}
+		// Two messages joined at a shared word trace to both.
+		a := []string{"kita ", "ikuyo ", "plays ", "guitar "}
+		b := []string{"seika ", "plays ", "drums "}
+		if err := brain.Learn(ctx, br, "joined", "a", userhash.Hash{1}, time.Unix(0, 0), a); err != nil {
+			t.Fatalf("couldn't learn: %v", err)
+		}
+		if err := brain.Learn(ctx, br, "joined", "b", userhash.Hash{1}, time.Unix(0, 0), b); err != nil {
+			t.Fatalf("couldn't learn: %v", err)
+		}
+		want := map[string][]string{
+			"kita ikuyo plays guitar": {"a"},
+			"kita ikuyo plays drums":  {"a", "b"},
+			"seika plays guitar":      {"a", "b"},
+			"seika plays drums":       {"b"},
+		}
+		for range 256 {
+			got, trace, err := brain.Speak(ctx, br, "joined", "")
+			if err != nil {
+				t.Fatalf("couldn't speak: %v", err)
+			}
+			w, ok := want[got]
+			if !ok {
+				t.Errorf("spoke %q, which can't be made from what was learned", got)
+				continue
+			}
+			trace = slices.Compact(slices.Sorted(slices.Values(trace)))
+			if !slices.Equal(trace, w) {
+				t.Errorf("wrong trace for %q: want %q, got %q", got, w, trace)
+			}
+		}
+	}
+}
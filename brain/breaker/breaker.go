@@ -0,0 +1,218 @@
+// Package breaker provides a circuit breaker for brains.
+package breaker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/fault"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// ErrOpen is the error returned when the breaker is refusing operations.
+// It must be checked using [errors.Is].
+// It wraps [fault.ErrBrainUnavailable].
+var ErrOpen = fmt.Errorf("%w: circuit breaker is open", fault.ErrBrainUnavailable)
+
+// Config is the configuration for a breaker.
+type Config struct {
+	// Failures is the number of failed operations within Window which trips
+	// the breaker.
+	Failures int
+	// Window is the time span over which failures are counted.
+	Window time.Duration
+	// Slow is the duration beyond which a successful operation counts as a
+	// failure. If it is zero, operations are never too slow.
+	Slow time.Duration
+	// Cooldown is the time the breaker stays open before allowing an
+	// operation through to probe whether the brain has recovered.
+	Cooldown time.Duration
+}
+
+// Brain is a brain which stops using another brain for learning and speaking
+// while it is failing.
+//
+// When the breaker is closed, all operations go through to the underlying
+// brain. Once enough operations fail or are too slow, the breaker opens, and
+// Learn and Speak return [ErrOpen] without using the underlying brain.
+// After a cooldown, the breaker is half-open and allows a single operation
+// through as a probe. If the probe succeeds, the breaker closes; otherwise,
+// it opens for another cooldown.
+//
+// Forget operations always go through to the underlying brain, because
+// moderation should not be lost.
+type Brain struct {
+	br  brain.Brain
+	cfg Config
+
+	// mu guards the breaker state.
+	mu sync.Mutex
+	// fails is the times of recent failures, oldest first.
+	fails []time.Time
+	// until is the time at which the breaker becomes half-open.
+	// If it is zero, the breaker is closed.
+	until time.Time
+	// probing indicates that a probe is in progress while half-open.
+	probing bool
+
+	calls    atomic.Int64
+	failures atomic.Int64
+	slow     atomic.Int64
+	rejected atomic.Int64
+	trips    atomic.Int64
+}
+
+var (
+	_ brain.Brain   = (*Brain)(nil)
+	_ brain.Wrapper = (*Brain)(nil)
+)
+
+// New wraps a brain with a circuit breaker.
+func New(br brain.Brain, cfg Config) *Brain {
+	return &Brain{br: br, cfg: cfg}
+}
+
+// Unwrap returns the underlying brain.
+func (b *Brain) Unwrap() brain.Brain {
+	return b.br
+}
+
+// Stats is a snapshot of a breaker's state and counters.
+type Stats struct {
+	// State is one of "closed", "open", or "half-open".
+	State string `json:"state"`
+	// Calls is the number of operations passed to the underlying brain.
+	Calls int64 `json:"calls"`
+	// Failures is the number of operations which returned errors.
+	Failures int64 `json:"failures"`
+	// Slow is the number of operations which succeeded but took too long.
+	Slow int64 `json:"slow"`
+	// Rejected is the number of operations refused while open.
+	Rejected int64 `json:"rejected"`
+	// Trips is the number of times the breaker has opened.
+	Trips int64 `json:"trips"`
+}
+
+// Stats returns a snapshot of the breaker's state and counters.
+func (b *Brain) Stats() Stats {
+	b.mu.Lock()
+	state := "closed"
+	switch {
+	case b.until.IsZero(): // do nothing
+	case time.Now().Before(b.until):
+		state = "open"
+	default:
+		state = "half-open"
+	}
+	b.mu.Unlock()
+	return Stats{
+		State:    state,
+		Calls:    b.calls.Load(),
+		Failures: b.failures.Load(),
+		Slow:     b.slow.Load(),
+		Rejected: b.rejected.Load(),
+		Trips:    b.trips.Load(),
+	}
+}
+
+// allow reports whether an operation may proceed at the given time.
+// If the result is true, the caller must call done when the operation
+// finishes.
+func (b *Brain) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.until.IsZero():
+		return true
+	case now.Before(b.until), b.probing:
+		b.rejected.Add(1)
+		return false
+	default:
+		b.probing = true
+		return true
+	}
+}
+
+// done records the result of an operation which began at start.
+func (b *Brain) done(start time.Time, err error) {
+	now := time.Now()
+	b.calls.Add(1)
+	bad := false
+	switch {
+	case err != nil:
+		b.failures.Add(1)
+		bad = true
+	case b.cfg.Slow > 0 && now.Sub(start) > b.cfg.Slow:
+		b.slow.Add(1)
+		bad = true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.probing {
+		b.probing = false
+		if bad {
+			b.until = now.Add(b.cfg.Cooldown)
+			return
+		}
+		b.until = time.Time{}
+		b.fails = b.fails[:0]
+		return
+	}
+	if !bad {
+		return
+	}
+	k := 0
+	for k < len(b.fails) && now.Sub(b.fails[k]) >= b.cfg.Window {
+		k++
+	}
+	b.fails = append(b.fails[:0], b.fails[k:]...)
+	b.fails = append(b.fails, now)
+	if len(b.fails) >= b.cfg.Failures && b.until.IsZero() {
+		b.until = now.Add(b.cfg.Cooldown)
+		b.trips.Add(1)
+	}
+}
+
+// Learn records a set of tuples if the breaker allows it.
+func (b *Brain) Learn(ctx context.Context, tag, id string, user userhash.Hash, t time.Time, tuples []brain.Tuple) error {
+	start := time.Now()
+	if !b.allow(start) {
+		return ErrOpen
+	}
+	err := b.br.Learn(ctx, tag, id, user, t, tuples)
+	b.done(start, err)
+	return err
+}
+
+// Speak generates a message if the breaker allows it.
+func (b *Brain) Speak(ctx context.Context, tag string, prompt []string, w *brain.Builder) error {
+	start := time.Now()
+	if !b.allow(start) {
+		return ErrOpen
+	}
+	err := b.br.Speak(ctx, tag, prompt, w)
+	b.done(start, err)
+	return err
+}
+
+// ForgetMessage forgets everything learned from a single given message.
+// It is never refused.
+func (b *Brain) ForgetMessage(ctx context.Context, tag, id string) error {
+	return b.br.ForgetMessage(ctx, tag, id)
+}
+
+// ForgetDuring forgets all messages learned in the given time span.
+// It is never refused.
+func (b *Brain) ForgetDuring(ctx context.Context, tag string, since, before time.Time) error {
+	return b.br.ForgetDuring(ctx, tag, since, before)
+}
+
+// ForgetUser forgets all messages associated with a userhash.
+// It is never refused.
+func (b *Brain) ForgetUser(ctx context.Context, user *userhash.Hash) error {
+	return b.br.ForgetUser(ctx, user)
+}
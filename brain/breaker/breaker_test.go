@@ -0,0 +1,92 @@
+package breaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/breaker"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// flaky is a brain whose operations fail while err is set.
+type flaky struct {
+	err   error
+	calls int
+}
+
+func (f *flaky) Learn(ctx context.Context, tag, id string, user userhash.Hash, t time.Time, tuples []brain.Tuple) error {
+	f.calls++
+	return f.err
+}
+
+func (f *flaky) Speak(ctx context.Context, tag string, prompt []string, w *brain.Builder) error {
+	f.calls++
+	return f.err
+}
+
+func (f *flaky) ForgetMessage(ctx context.Context, tag, id string) error {
+	f.calls++
+	return f.err
+}
+
+func (f *flaky) ForgetDuring(ctx context.Context, tag string, since, before time.Time) error {
+	f.calls++
+	return f.err
+}
+
+func (f *flaky) ForgetUser(ctx context.Context, user *userhash.Hash) error {
+	f.calls++
+	return f.err
+}
+
+func TestBreaker(t *testing.T) {
+	ctx := context.Background()
+	f := &flaky{err: errors.New("bocchi")}
+	b := breaker.New(f, breaker.Config{Failures: 2, Window: time.Hour, Cooldown: 50 * time.Millisecond})
+	learn := func() error {
+		return b.Learn(ctx, "kessoku", "1", userhash.Hash{}, time.Now(), nil)
+	}
+	for range 2 {
+		if err := learn(); !errors.Is(err, f.err) {
+			t.Errorf("wrong error while closed: want %v, got %v", f.err, err)
+		}
+	}
+	if got := b.Stats().State; got != "open" {
+		t.Errorf("wrong state after failures: want open, got %s", got)
+	}
+	if err := learn(); !errors.Is(err, breaker.ErrOpen) {
+		t.Errorf("wrong error while open: want %v, got %v", breaker.ErrOpen, err)
+	}
+	if err := b.ForgetMessage(ctx, "kessoku", "1"); !errors.Is(err, f.err) {
+		t.Errorf("forget was refused while open: %v", err)
+	}
+	if f.calls != 3 {
+		t.Errorf("wrong number of calls through breaker: want 3, got %d", f.calls)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if got := b.Stats().State; got != "half-open" {
+		t.Errorf("wrong state after cooldown: want half-open, got %s", got)
+	}
+	// Failing probe reopens.
+	if err := learn(); !errors.Is(err, f.err) {
+		t.Errorf("wrong error from probe: want %v, got %v", f.err, err)
+	}
+	if err := learn(); !errors.Is(err, breaker.ErrOpen) {
+		t.Errorf("wrong error after failed probe: want %v, got %v", breaker.ErrOpen, err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	f.err = nil
+	if err := learn(); err != nil {
+		t.Errorf("probe failed: %v", err)
+	}
+	if got := b.Stats().State; got != "closed" {
+		t.Errorf("wrong state after successful probe: want closed, got %s", got)
+	}
+	st := b.Stats()
+	if st.Trips != 1 || st.Rejected != 2 || st.Failures != 3 {
+		t.Errorf("wrong stats: %+v", st)
+	}
+}
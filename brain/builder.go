@@ -6,15 +6,45 @@ import "slices"
 type Builder struct {
 	w  []byte
 	id []string
+
+	// emit, if not nil, receives each term as it is appended.
+	emit func(term string) error
+	// err is the first error returned by emit.
+	err error
+	// stop cancels generation when emit fails.
+	stop func()
+	// relay, if not nil, receives each term with its message ID.
+	relay func(id string, term []byte)
+}
+
+// NewRelayBuilder returns a builder which also passes each term and the ID of
+// the message it came from to f as it is appended, e.g. to forward generation
+// over a network. The term is only valid during the call. Reset removes f.
+func NewRelayBuilder(f func(id string, term []byte)) *Builder {
+	return &Builder{relay: f}
 }
 
 // Append adds a term to the builder.
+// If the builder is streaming and the receiver of terms has failed, the term
+// is discarded.
 func (b *Builder) Append(id string, term []byte) {
+	if b.err != nil {
+		return
+	}
 	b.w = append(b.w, term...)
 	k, ok := slices.BinarySearch(b.id, id)
 	if !ok {
 		b.id = slices.Insert(b.id, k, id)
 	}
+	if b.relay != nil {
+		b.relay(id, term)
+	}
+	if b.emit != nil {
+		if err := b.emit(string(term)); err != nil {
+			b.err = err
+			b.stop()
+		}
+	}
 }
 
 // prompt adds a term without an ID.
@@ -47,4 +77,5 @@ func (b *Builder) Reset() {
 	b.w = b.w[:0]
 	clear(b.id) // allow held strings to release
 	b.id = b.id[:0]
+	b.emit, b.err, b.stop, b.relay = nil, nil, nil, nil
 }
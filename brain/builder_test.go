@@ -95,6 +95,28 @@ func TestBuilder(t *testing.T) {
 	}
 }
 
+func TestRelayBuilder(t *testing.T) {
+	var got [][2]string
+	b := brain.NewRelayBuilder(func(id string, term []byte) {
+		got = append(got, [2]string{id, string(term)})
+	})
+	b.Append("nijika", []byte("ryo "))
+	b.Append("bocchi", []byte("kita "))
+	b.Append("nijika", []byte("seika"))
+	want := [][2]string{{"nijika", "ryo "}, {"bocchi", "kita "}, {"nijika", "seika"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("wrong relayed terms: want %q, got %q", want, got)
+	}
+	if s := b.String(); s != "ryo kita seika" {
+		t.Errorf("wrong string: %q", s)
+	}
+	b.Reset()
+	b.Append("ryo", []byte("bocchi"))
+	if len(got) != len(want) {
+		t.Errorf("relayed after reset: %q", got)
+	}
+}
+
 func BenchmarkBuilder(b *testing.B) {
 	var ids [256]string
 	var words [256][]byte
@@ -0,0 +1,71 @@
+// Package busy retries brain operations which fail because an SQLite database
+// is busy.
+package busy
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/fault"
+	"github.com/zephyrtronium/robot/sqlpool"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// Brain is a brain which retries learning and forgetting in another brain
+// when they fail because its database is locked.
+// Speaking is not retried, since readers don't wait on writers.
+type Brain struct {
+	br brain.Brain
+	r  *sqlpool.Retrier
+}
+
+var (
+	_ brain.Brain   = (*Brain)(nil)
+	_ brain.Wrapper = (*Brain)(nil)
+)
+
+// New wraps a brain to retry operations using r.
+func New(br brain.Brain, r *sqlpool.Retrier) *Brain {
+	return &Brain{br: br, r: r}
+}
+
+// Unwrap returns the underlying brain.
+func (b *Brain) Unwrap() brain.Brain {
+	return b.br
+}
+
+// Learn records a set of tuples.
+func (b *Brain) Learn(ctx context.Context, tag, id string, user userhash.Hash, t time.Time, tuples []brain.Tuple) error {
+	return unavailable(b.r.Do(ctx, func() error { return b.br.Learn(ctx, tag, id, user, t, tuples) }))
+}
+
+// Speak generates a message.
+func (b *Brain) Speak(ctx context.Context, tag string, prompt []string, w *brain.Builder) error {
+	return b.br.Speak(ctx, tag, prompt, w)
+}
+
+// ForgetMessage forgets everything learned from a single given message.
+func (b *Brain) ForgetMessage(ctx context.Context, tag, id string) error {
+	return unavailable(b.r.Do(ctx, func() error { return b.br.ForgetMessage(ctx, tag, id) }))
+}
+
+// ForgetDuring forgets all messages learned in the given time span.
+func (b *Brain) ForgetDuring(ctx context.Context, tag string, since, before time.Time) error {
+	return unavailable(b.r.Do(ctx, func() error { return b.br.ForgetDuring(ctx, tag, since, before) }))
+}
+
+// ForgetUser forgets all messages associated with a userhash.
+func (b *Brain) ForgetUser(ctx context.Context, user *userhash.Hash) error {
+	return unavailable(b.r.Do(ctx, func() error { return b.br.ForgetUser(ctx, user) }))
+}
+
+// unavailable wraps an error from a database which stayed busy through every
+// retry with [fault.ErrBrainUnavailable].
+func unavailable(err error) error {
+	if sqlpool.Busy(err) {
+		return fmt.Errorf("%w: %w", fault.ErrBrainUnavailable, err)
+	}
+	return err
+}
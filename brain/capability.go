@@ -0,0 +1,224 @@
+package brain
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"time"
+)
+
+// Stats is a summary of a brain's knowledge under a tag.
+type Stats struct {
+	// Tuples is the number of tuples learned and not forgotten.
+	Tuples int64
+	// Messages is the number of messages learned and not forgotten.
+	// It is negative if the brain does not track messages.
+	Messages int64
+}
+
+// Stater is a brain which can summarize its knowledge.
+type Stater interface {
+	// Stats summarizes the knowledge under a tag.
+	Stats(ctx context.Context, tag string) (*Stats, error)
+}
+
+// Backuper is a brain which can back up its knowledge.
+type Backuper interface {
+	// Backup writes a copy of all knowledge to w in a format that is
+	// specific to the implementation.
+	Backup(ctx context.Context, w io.Writer) error
+}
+
+// Windower is a brain which can restrict generation to knowledge learned in a
+// time span.
+type Windower interface {
+	// During returns a brain which speaks only from messages learned at or
+	// after since and before before.
+	During(since, before time.Time) Brain
+}
+
+// Continuer is a brain which can list the terms that follow a prompt.
+type Continuer interface {
+	// Continuations counts the terms learned to follow a prompt.
+	// The prompt is in reverse order and has entropy reduction applied.
+	// The empty string as a term denotes the end of a message.
+	Continuations(ctx context.Context, tag string, prompt []string) (map[string]int, error)
+}
+
+// Recaller is a brain which can reconstruct the text of learned messages.
+type Recaller interface {
+	// Recall returns the text of a learned message. If nothing has been
+	// learned from the message or it has been forgotten, the result is the
+	// empty string.
+	Recall(ctx context.Context, tag, id string) (string, error)
+}
+
+// Quota is a limit on the knowledge kept under a tag.
+// Zero fields are unlimited.
+type Quota struct {
+	// Tuples is the most tuples to keep.
+	Tuples int64
+	// Bytes is the most bytes of tuple text to keep.
+	Bytes int64
+}
+
+// Evicter is a brain which can discard its oldest knowledge to stay within a
+// quota.
+type Evicter interface {
+	// Evict permanently forgets the oldest messages learned under a tag
+	// until what remains is within the quota. Forgotten messages which still
+	// occupy storage count against the quota. It returns the number of
+	// messages evicted.
+	Evict(ctx context.Context, tag string, quota Quota) (int, error)
+}
+
+// TermSpan describes the messages that used a term.
+type TermSpan struct {
+	// Term is the term itself.
+	Term string
+	// First and Last are the times of the earliest and latest messages that
+	// used the term. Each is the zero time if no such message has a time.
+	First, Last time.Time
+	// Uses is the number of tuples with the term as their suffix.
+	Uses int64
+}
+
+// Diff is the change in the terms known under a tag since a point in time.
+type Diff struct {
+	// Appeared lists terms known now which no message before the point in
+	// time used.
+	Appeared []TermSpan
+	// Disappeared lists terms which messages before the point in time used
+	// and which are no longer known, e.g. because those messages were
+	// forgotten.
+	Disappeared []TermSpan
+}
+
+// Differ is a brain which can report how its vocabulary has changed.
+type Differ interface {
+	// Diff compares the terms known under a tag now to those learned before
+	// since. Knowledge which the brain has removed entirely, e.g. to stay
+	// within a quota, can't be reported.
+	Diff(ctx context.Context, tag string, since time.Time) (*Diff, error)
+}
+
+// Undeleter is a brain which keeps forgotten knowledge for a while so that
+// mistaken forgetting can be reversed.
+type Undeleter interface {
+	// Undelete restores everything forgotten by an operation, as attached to
+	// the context with [WithOp], which hasn't yet been purged. It returns the
+	// number of messages restored.
+	Undelete(ctx context.Context, op string) (int, error)
+	// Forgotten counts the messages forgotten by an operation which can
+	// still be restored.
+	Forgotten(ctx context.Context, op string) (int, error)
+	// Purge permanently removes knowledge forgotten before a time.
+	// It returns the number of messages purged.
+	Purge(ctx context.Context, before time.Time) (int, error)
+}
+
+// View is a tag composed at query time from the knowledge of other tags.
+type View struct {
+	// Tags is the tags whose knowledge the view combines.
+	Tags []string
+	// Block matches terms which the view leaves out. Terms include trailing
+	// spaces. The end of a message is never blocked. If Block is nil, the
+	// view includes every term.
+	Block *regexp.Regexp
+}
+
+// Viewer is a brain which can speak from views.
+type Viewer interface {
+	// SpeakView generates a full message from a view and appends it to w.
+	// The prompt is in reverse order and has entropy reduction applied.
+	SpeakView(ctx context.Context, v *View, prompt []string, w *Builder) error
+}
+
+// Preloader is a brain which can read knowledge ahead of time, so that
+// speaking from it doesn't wait on slow storage.
+type Preloader interface {
+	// Preload reads the knowledge under a tag into memory. If top is
+	// positive, only tuples with the top most frequent prefixes are read;
+	// otherwise, the entire tag is. If progress is not nil, it is called
+	// periodically with the number of tuples read so far.
+	// Preload returns the total number of tuples read.
+	Preload(ctx context.Context, tag string, top int, progress func(n int64)) (int64, error)
+}
+
+// Retagger is a brain which can move knowledge from one tag to another.
+type Retagger interface {
+	// Retag moves everything learned under one tag to another, including
+	// forgotten knowledge which can still be restored. It is an error for
+	// the destination to already have knowledge of the same message.
+	// Retag returns the number of messages moved.
+	Retag(ctx context.Context, from, to string) (int64, error)
+}
+
+// Wrapper is a brain which wraps another brain, e.g. to add behavior.
+// Optional capabilities of the wrapped brain are found through Unwrap.
+type Wrapper interface {
+	// Unwrap returns the wrapped brain.
+	Unwrap() Brain
+}
+
+// As finds the first brain in the chain of wrappers starting at br which
+// implements T.
+func As[T any](br Brain) (T, bool) {
+	for br != nil {
+		if r, ok := br.(T); ok {
+			return r, true
+		}
+		w, ok := br.(Wrapper)
+		if !ok {
+			break
+		}
+		br = w.Unwrap()
+	}
+	var zero T
+	return zero, false
+}
+
+// Capabilities lists the optional capabilities that a brain supports.
+func Capabilities(br Brain) []string {
+	var r []string
+	if _, ok := As[Stater](br); ok {
+		r = append(r, "stats")
+	}
+	if _, ok := As[Backuper](br); ok {
+		r = append(r, "backup")
+	}
+	if _, ok := As[Windower](br); ok {
+		r = append(r, "window")
+	}
+	if _, ok := As[Continuer](br); ok {
+		r = append(r, "continuations")
+	}
+	if _, ok := As[Recaller](br); ok {
+		r = append(r, "recall")
+	}
+	if _, ok := As[Batcher](br); ok {
+		r = append(r, "batch")
+	}
+	if _, ok := As[Evicter](br); ok {
+		r = append(r, "evict")
+	}
+	if _, ok := As[Differ](br); ok {
+		r = append(r, "diff")
+	}
+	if _, ok := As[Viewer](br); ok {
+		r = append(r, "view")
+	}
+	if _, ok := As[Undeleter](br); ok {
+		r = append(r, "undelete")
+	}
+	if _, ok := As[Exporter](br); ok {
+		r = append(r, "export")
+	}
+	if _, ok := As[Preloader](br); ok {
+		r = append(r, "preload")
+	}
+	if _, ok := As[Retagger](br); ok {
+		r = append(r, "retag")
+	}
+	return r
+}
@@ -0,0 +1,67 @@
+package brain_test
+
+import (
+	"context"
+	"io"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+type plain struct{}
+
+func (plain) Learn(ctx context.Context, tag, id string, user userhash.Hash, t time.Time, tuples []brain.Tuple) error {
+	return nil
+}
+func (plain) ForgetMessage(ctx context.Context, tag, id string) error { return nil }
+func (plain) ForgetDuring(ctx context.Context, tag string, since, before time.Time) error {
+	return nil
+}
+func (plain) ForgetUser(ctx context.Context, user *userhash.Hash) error { return nil }
+func (plain) Speak(ctx context.Context, tag string, prompt []string, w *brain.Builder) error {
+	return nil
+}
+
+type stater struct{ plain }
+
+func (stater) Stats(ctx context.Context, tag string) (*brain.Stats, error) {
+	return &brain.Stats{Tuples: 1, Messages: 1}, nil
+}
+
+type backuper struct{ stater }
+
+func (backuper) Backup(ctx context.Context, w io.Writer) error { return nil }
+
+type wrapper struct {
+	plain
+	br brain.Brain
+}
+
+func (w wrapper) Unwrap() brain.Brain { return w.br }
+
+func TestCapabilities(t *testing.T) {
+	cases := []struct {
+		name string
+		br   brain.Brain
+		want []string
+	}{
+		{"plain", plain{}, nil},
+		{"stats", stater{}, []string{"stats"}},
+		{"both", backuper{}, []string{"stats", "backup"}},
+		{"wrapped", wrapper{br: backuper{}}, []string{"stats", "backup"}},
+		{"wrapped-plain", wrapper{br: plain{}}, nil},
+		{"nested", wrapper{br: wrapper{br: stater{}}}, []string{"stats"}},
+		{"batch", wrapper{br: &batcher{}}, []string{"batch"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := brain.Capabilities(c.br)
+			if !slices.Equal(got, c.want) {
+				t.Errorf("wrong capabilities: want %q, got %q", c.want, got)
+			}
+		})
+	}
+}
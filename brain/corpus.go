@@ -0,0 +1,135 @@
+package brain
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// Counted is a tuple with the number of times it has been learned.
+// An anonymized corpus is a list of them, with nothing about who sent the
+// messages they came from or when.
+type Counted struct {
+	// Prefix is the entropy-reduced prefix in reverse order.
+	Prefix []string `json:"prefix"`
+	// Suffix is the full-entropy term following the prefix.
+	Suffix string `json:"suffix"`
+	// Count is the number of times the tuple has been learned.
+	Count int64 `json:"count"`
+}
+
+// Exporter is a brain which can list its knowledge without attribution.
+type Exporter interface {
+	// Export calls f with each distinct tuple learned and not forgotten
+	// under a tag and the number of times it was learned. If f returns an
+	// error, Export stops and returns it.
+	Export(ctx context.Context, tag string, f func(Counted) error) error
+}
+
+// WriteCorpus writes the knowledge under a tag to w as an anonymized corpus
+// of JSON lines, one per distinct tuple.
+func WriteCorpus(ctx context.Context, e Exporter, tag string, w io.Writer) (int, error) {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	n := 0
+	err := e.Export(ctx, tag, func(c Counted) error {
+		n++
+		return enc.Encode(c)
+	})
+	if err != nil {
+		return n, fmt.Errorf("couldn't export corpus: %w", err)
+	}
+	if err := bw.Flush(); err != nil {
+		return n, fmt.Errorf("couldn't write corpus: %w", err)
+	}
+	return n, nil
+}
+
+// CorpusPrefix starts the IDs of messages imported from an anonymized corpus.
+const CorpusPrefix = "corpus:"
+
+// ImportCorpus learns an anonymized corpus written by [WriteCorpus] under a
+// tag. It returns the number of tuples imported.
+//
+// Imported knowledge is unattributable: each tuple is learned as its own
+// message with the zero userhash and a time at the UNIX epoch, with an ID
+// starting with [CorpusPrefix] derived from the tuple. So, forgetting a user
+// never removes it, forgetting recent chat never reaches it, and it is the
+// first knowledge evicted under a quota. Brains which skip replayed messages
+// learn nothing new from importing the same corpus twice.
+func ImportCorpus(ctx context.Context, br Brain, tag string, r io.Reader) (int, error) {
+	dec := json.NewDecoder(r)
+	batch := make([]Message, 0, 256)
+	n := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		var err error
+		if l, ok := As[Batcher](br); ok {
+			err = l.LearnBatch(ctx, batch)
+		} else {
+			for _, m := range batch {
+				if err = br.Learn(ctx, m.Tag, m.ID, m.User, m.Time, m.Tuples); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("couldn't learn corpus: %w", err)
+		}
+		n += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		var c Counted
+		err := dec.Decode(&c)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return n, fmt.Errorf("couldn't read corpus: %w", err)
+		}
+		if c.Count <= 0 {
+			continue
+		}
+		t := Tuple{Prefix: c.Prefix, Suffix: c.Suffix}
+		batch = append(batch, Message{
+			Tag:    tag,
+			ID:     corpusID(t),
+			User:   userhash.Hash{},
+			Time:   time.Unix(0, 0),
+			Tuples: slices.Repeat([]Tuple{t}, int(c.Count)),
+		})
+		if len(batch) == cap(batch) {
+			if err := flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, flush()
+}
+
+// corpusID derives the message ID of an imported tuple.
+func corpusID(t Tuple) string {
+	h := sha256.New()
+	for _, w := range t.Prefix {
+		io.WriteString(h, strconv.Quote(w))
+	}
+	io.WriteString(h, "\x00")
+	io.WriteString(h, t.Suffix)
+	return CorpusPrefix + hex.EncodeToString(h.Sum(nil)[:16])
+}
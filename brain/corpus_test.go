@@ -0,0 +1,74 @@
+package brain_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/membrain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+func TestCorpus(t *testing.T) {
+	ctx := context.Background()
+	src := membrain.New()
+	for i, s := range []string{"bocchi rock", "bocchi rock", "kita"} {
+		id := string(rune('1' + i))
+		if err := brain.Learn(ctx, src, "kessoku", id, userhash.Hash{1}, time.Unix(1e9, 0), brain.Tokens(nil, s)); err != nil {
+			t.Fatalf("couldn't learn %q: %v", s, err)
+		}
+	}
+	var b bytes.Buffer
+	n, err := brain.WriteCorpus(ctx, src, "kessoku", &b)
+	if err != nil {
+		t.Fatalf("couldn't write corpus: %v", err)
+	}
+	if n != 5 {
+		t.Errorf("wrong number of tuples written: want 5, got %d", n)
+	}
+	if s := b.String(); strings.Contains(s, "user") || strings.Contains(s, "time") || strings.Contains(s, `"1"`) {
+		t.Errorf("corpus has attribution:\n%s", s)
+	}
+	corpus := b.String()
+
+	dst := membrain.New()
+	n, err = brain.ImportCorpus(ctx, dst, "shared", strings.NewReader(corpus))
+	if err != nil {
+		t.Fatalf("couldn't import corpus: %v", err)
+	}
+	if n != 5 {
+		t.Errorf("wrong number of tuples imported: want 5, got %d", n)
+	}
+	// Importing again learns nothing new.
+	if _, err := brain.ImportCorpus(ctx, dst, "shared", strings.NewReader(corpus)); err != nil {
+		t.Fatalf("couldn't import corpus again: %v", err)
+	}
+	// Forgetting users and recent chat doesn't touch imported knowledge.
+	if err := dst.ForgetUser(ctx, &userhash.Hash{1}); err != nil {
+		t.Fatalf("couldn't forget user: %v", err)
+	}
+	if err := dst.ForgetDuring(ctx, "shared", time.Unix(1, 0), time.Now()); err != nil {
+		t.Fatalf("couldn't forget recent: %v", err)
+	}
+	var want, got []brain.Counted
+	collect := func(r *[]brain.Counted) func(brain.Counted) error {
+		return func(c brain.Counted) error {
+			*r = append(*r, c)
+			return nil
+		}
+	}
+	if err := src.Export(ctx, "kessoku", collect(&want)); err != nil {
+		t.Fatal(err)
+	}
+	if err := dst.Export(ctx, "shared", collect(&got)); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("imported knowledge differs (-want/+got):\n%s", diff)
+	}
+}
@@ -0,0 +1,197 @@
+// Package deadline bounds the time brain operations may take.
+package deadline
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/fault"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// Config is the configuration for deadlines.
+// Zero durations mean no deadline.
+type Config struct {
+	// Learn is the longest learning may take.
+	Learn time.Duration
+	// Speak is the longest speaking may take.
+	Speak time.Duration
+	// Forget is the longest forgetting may take.
+	Forget time.Duration
+	// MaxStalled is the number of operations still running past their
+	// deadlines at which new operations fail immediately. If it is not
+	// positive, operations are never refused.
+	MaxStalled int64
+}
+
+// Brain is a brain which gives up on operations in another brain that take
+// too long.
+//
+// Operations are canceled at their deadlines, but some brains don't notice
+// cancellation promptly, e.g. while waiting on a database compaction.
+// So, operations run separately from their callers, and callers stop
+// waiting at the deadline even if the operation doesn't. Operations which
+// time out return errors wrapping both [fault.ErrBrainUnavailable] and
+// [context.DeadlineExceeded]. If the caller's own context ends first, its
+// error is returned instead.
+type Brain struct {
+	br  brain.Brain
+	cfg Config
+
+	learnTimeouts  atomic.Int64
+	speakTimeouts  atomic.Int64
+	forgetTimeouts atomic.Int64
+	refused        atomic.Int64
+	stalled        atomic.Int64
+}
+
+var (
+	_ brain.Brain   = (*Brain)(nil)
+	_ brain.Wrapper = (*Brain)(nil)
+)
+
+// New wraps a brain with deadlines.
+func New(br brain.Brain, cfg Config) *Brain {
+	return &Brain{br: br, cfg: cfg}
+}
+
+// Unwrap returns the underlying brain.
+func (b *Brain) Unwrap() brain.Brain {
+	return b.br
+}
+
+// Stats is a snapshot of timeout counters.
+type Stats struct {
+	// LearnTimeouts is the number of learn operations which timed out.
+	LearnTimeouts int64 `json:"learn_timeouts"`
+	// SpeakTimeouts is the number of speak operations which timed out.
+	SpeakTimeouts int64 `json:"speak_timeouts"`
+	// ForgetTimeouts is the number of forget operations which timed out.
+	ForgetTimeouts int64 `json:"forget_timeouts"`
+	// Refused is the number of operations refused because too many were
+	// stalled.
+	Refused int64 `json:"refused"`
+	// Stalled is the number of operations still running past their
+	// deadlines.
+	Stalled int64 `json:"stalled"`
+}
+
+// Stats returns a snapshot of the counters.
+func (b *Brain) Stats() Stats {
+	return Stats{
+		LearnTimeouts:  b.learnTimeouts.Load(),
+		SpeakTimeouts:  b.speakTimeouts.Load(),
+		ForgetTimeouts: b.forgetTimeouts.Load(),
+		Refused:        b.refused.Load(),
+		Stalled:        b.stalled.Load(),
+	}
+}
+
+// Learn records a set of tuples.
+func (b *Brain) Learn(ctx context.Context, tag, id string, user userhash.Hash, t time.Time, tuples []brain.Tuple) error {
+	if b.cfg.Learn <= 0 {
+		return b.br.Learn(ctx, tag, id, user, t, tuples)
+	}
+	// Callers may reuse the tuples once we return, which can be before the
+	// operation finishes.
+	tt := make([]brain.Tuple, len(tuples))
+	for i, v := range tuples {
+		tt[i] = brain.Tuple{Prefix: slices.Clone(v.Prefix), Suffix: v.Suffix}
+	}
+	return b.do(ctx, "learn", b.cfg.Learn, &b.learnTimeouts, func(ctx context.Context) error {
+		return b.br.Learn(ctx, tag, id, user, t, tt)
+	})
+}
+
+// Speak generates a message.
+func (b *Brain) Speak(ctx context.Context, tag string, prompt []string, w *brain.Builder) error {
+	if b.cfg.Speak <= 0 {
+		return b.br.Speak(ctx, tag, prompt, w)
+	}
+	prompt = slices.Clone(prompt)
+	// Pass terms through to w as they come so that streaming still works,
+	// but stop once we've given up so that nothing touches w after we return.
+	var mu sync.Mutex
+	abandoned := false
+	rw := brain.NewRelayBuilder(func(id string, term []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !abandoned {
+			w.Append(id, term)
+		}
+	})
+	err := b.do(ctx, "speak", b.cfg.Speak, &b.speakTimeouts, func(ctx context.Context) error {
+		return b.br.Speak(ctx, tag, prompt, rw)
+	})
+	mu.Lock()
+	abandoned = true
+	mu.Unlock()
+	return err
+}
+
+// ForgetMessage forgets everything learned from a single given message.
+func (b *Brain) ForgetMessage(ctx context.Context, tag, id string) error {
+	return b.do(ctx, "forget", b.cfg.Forget, &b.forgetTimeouts, func(ctx context.Context) error {
+		return b.br.ForgetMessage(ctx, tag, id)
+	})
+}
+
+// ForgetDuring forgets all messages learned in the given time span.
+func (b *Brain) ForgetDuring(ctx context.Context, tag string, since, before time.Time) error {
+	return b.do(ctx, "forget", b.cfg.Forget, &b.forgetTimeouts, func(ctx context.Context) error {
+		return b.br.ForgetDuring(ctx, tag, since, before)
+	})
+}
+
+// ForgetUser forgets all messages associated with a userhash.
+func (b *Brain) ForgetUser(ctx context.Context, user *userhash.Hash) error {
+	u := *user
+	return b.do(ctx, "forget", b.cfg.Forget, &b.forgetTimeouts, func(ctx context.Context) error {
+		return b.br.ForgetUser(ctx, &u)
+	})
+}
+
+// do runs f with a deadline of d, waiting no longer than that for it.
+func (b *Brain) do(ctx context.Context, op string, d time.Duration, timeouts *atomic.Int64, f func(ctx context.Context) error) error {
+	if d <= 0 {
+		return f(ctx)
+	}
+	if b.cfg.MaxStalled > 0 {
+		if n := b.stalled.Load(); n >= b.cfg.MaxStalled {
+			b.refused.Add(1)
+			return fmt.Errorf("%w: %d operations stalled past their deadlines", fault.ErrBrainUnavailable, n)
+		}
+	}
+	opctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	errc := make(chan error, 1)
+	go func() { errc <- f(opctx) }()
+	select {
+	case err := <-errc:
+		return err
+	case <-opctx.Done():
+	}
+	// The operation may have finished just as time ran out.
+	select {
+	case err := <-errc:
+		return err
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		// The caller gave up, not us. The operation will still finish
+		// eventually, but that isn't a stall.
+		return err
+	}
+	timeouts.Add(1)
+	b.stalled.Add(1)
+	go func() {
+		<-errc
+		b.stalled.Add(-1)
+	}()
+	return fmt.Errorf("%w: %s took longer than %v: %w", fault.ErrBrainUnavailable, op, d, context.DeadlineExceeded)
+}
@@ -0,0 +1,92 @@
+package deadline_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/braintest"
+	"github.com/zephyrtronium/robot/brain/deadline"
+	"github.com/zephyrtronium/robot/brain/membrain"
+	"github.com/zephyrtronium/robot/fault"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+func TestBrain(t *testing.T) {
+	braintest.Test(context.Background(), t, func(ctx context.Context) brain.Brain {
+		return deadline.New(membrain.New(), deadline.Config{Learn: time.Minute, Speak: time.Minute, Forget: time.Minute})
+	})
+}
+
+// stuck is a brain whose operations wait on a channel, ignoring cancellation
+// like a database in the middle of a compaction.
+type stuck struct {
+	*membrain.Brain
+	release chan struct{}
+}
+
+func (s *stuck) Learn(ctx context.Context, tag, id string, user userhash.Hash, t time.Time, tuples []brain.Tuple) error {
+	<-s.release
+	return s.Brain.Learn(ctx, tag, id, user, t, tuples)
+}
+
+func (s *stuck) Speak(ctx context.Context, tag string, prompt []string, w *brain.Builder) error {
+	w.Append("1", []byte("bocchi "))
+	<-s.release
+	w.Append("2", []byte("ryo "))
+	return nil
+}
+
+func TestTimeout(t *testing.T) {
+	ctx := context.Background()
+	s := &stuck{Brain: membrain.New(), release: make(chan struct{})}
+	br := deadline.New(s, deadline.Config{Learn: time.Millisecond, Speak: time.Millisecond, Forget: time.Second, MaxStalled: 2})
+	err := brain.Learn(ctx, br, "kessoku", "1", userhash.Hash{1}, time.Now(), brain.Tokens(nil, "bocchi plays guitar"))
+	if !errors.Is(err, fault.ErrBrainUnavailable) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wrong learn error: %v", err)
+	}
+	_, _, err = brain.Speak(ctx, br, "kessoku", "")
+	if !errors.Is(err, fault.ErrBrainUnavailable) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wrong speak error: %v", err)
+	}
+	if st := br.Stats(); st.LearnTimeouts != 1 || st.SpeakTimeouts != 1 || st.Stalled != 2 {
+		t.Errorf("wrong stats after timeouts: %+v", st)
+	}
+	// With too many stalled, operations fail without waiting.
+	err = br.ForgetMessage(ctx, "kessoku", "1")
+	if !errors.Is(err, fault.ErrBrainUnavailable) {
+		t.Errorf("wrong error while stalled: %v", err)
+	}
+	if st := br.Stats(); st.Refused != 1 {
+		t.Errorf("wrong stats after refusing: %+v", st)
+	}
+	// Once the stalled operations finish, they stop counting. The learn still
+	// happens, even though its caller gave up on it.
+	close(s.release)
+	for br.Stats().Stalled != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if m, err := s.Recall(ctx, "kessoku", "1"); err != nil || m != "bocchi plays guitar" {
+		t.Errorf("wrong message after stall: %q, %v", m, err)
+	}
+	if err := br.ForgetMessage(ctx, "kessoku", "1"); err != nil {
+		t.Errorf("couldn't forget after recovering: %v", err)
+	}
+}
+
+func TestCallerCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &stuck{Brain: membrain.New(), release: make(chan struct{})}
+	defer close(s.release)
+	br := deadline.New(s, deadline.Config{Learn: time.Minute})
+	time.AfterFunc(time.Millisecond, cancel)
+	err := brain.Learn(ctx, br, "kessoku", "1", userhash.Hash{1}, time.Now(), brain.Tokens(nil, "bocchi"))
+	if !errors.Is(err, context.Canceled) || errors.Is(err, fault.ErrBrainUnavailable) {
+		t.Errorf("wrong error when caller canceled: %v", err)
+	}
+	if st := br.Stats(); st.LearnTimeouts != 0 || st.Stalled != 0 {
+		t.Errorf("caller cancellation counted as timeout: %+v", st)
+	}
+}
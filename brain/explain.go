@@ -0,0 +1,92 @@
+package brain
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Segment is a span of a generated message along with the learned messages
+// which contain it.
+type Segment struct {
+	// Text is the text of the span.
+	Text string `json:"text"`
+	// Sources are the IDs of learned messages which contain the entire span.
+	// It is empty if no message in the trace contains the span, e.g. because
+	// the span came from a prompt.
+	Sources []string `json:"sources"`
+}
+
+// Explain attributes the terms of a generated message to learned messages.
+// sources maps message IDs in the message's trace to their texts.
+// Each segment of the result is the longest run of terms, starting where the
+// previous one ended, which appears consecutively in some learned message.
+func Explain(text string, sources map[string]string) []Segment {
+	out := reduced(Tokens(nil, text))
+	toks := make(map[string][]string, len(sources))
+	for id, s := range sources {
+		toks[id] = reduced(Tokens(nil, s))
+	}
+	orig := Tokens(nil, text)
+	var r []Segment
+	for i := 0; i < len(out); {
+		best := 0
+		var ids []string
+		for id, src := range toks {
+			n := longestRun(out[i:], src)
+			switch {
+			case n > best:
+				best = n
+				ids = append(ids[:0], id)
+			case n == best && n > 0:
+				ids = append(ids, id)
+			}
+		}
+		if best == 0 {
+			best = 1
+		}
+		slices.Sort(ids)
+		r = append(r, Segment{Text: strings.TrimSpace(strings.Join(orig[i:i+best], "")), Sources: ids})
+		i += best
+	}
+	return r
+}
+
+// Recall collects the texts of the messages in a trace.
+// Messages which the brain cannot recall are omitted.
+func Recall(ctx context.Context, r Recaller, tag string, trace []string) (map[string]string, error) {
+	m := make(map[string]string, len(trace))
+	for _, id := range trace {
+		s, err := r.Recall(ctx, tag, id)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't recall %s: %w", id, err)
+		}
+		if s != "" {
+			m[id] = s
+		}
+	}
+	return m, nil
+}
+
+// reduced applies entropy reduction to each of a list of tokens in place.
+func reduced(toks []string) []string {
+	for i, t := range toks {
+		toks[i] = ReduceEntropy(t)
+	}
+	return toks
+}
+
+// longestRun returns the length of the longest prefix of out which appears
+// consecutively anywhere in src.
+func longestRun(out, src []string) int {
+	best := 0
+	for j := range src {
+		n := 0
+		for n < len(out) && j+n < len(src) && out[n] == src[j+n] {
+			n++
+		}
+		best = max(best, n)
+	}
+	return best
+}
@@ -0,0 +1,63 @@
+package brain_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/zephyrtronium/robot/brain"
+)
+
+func TestExplain(t *testing.T) {
+	sources := map[string]string{
+		"1": "bocchi the rock is a great show",
+		"2": "the rock is rolling down the hill",
+		"3": "kita is a great guitarist",
+	}
+	cases := []struct {
+		name string
+		text string
+		want []brain.Segment
+	}{
+		{
+			name: "single",
+			text: "bocchi the rock",
+			want: []brain.Segment{{Text: "bocchi the rock", Sources: []string{"1"}}},
+		},
+		{
+			name: "spliced",
+			text: "bocchi the rock is rolling down",
+			want: []brain.Segment{
+				{Text: "bocchi the rock is", Sources: []string{"1"}},
+				{Text: "rolling down", Sources: []string{"2"}},
+			},
+		},
+		{
+			name: "later",
+			text: "is a great show",
+			want: []brain.Segment{{Text: "is a great show", Sources: []string{"1"}}},
+		},
+		{
+			name: "tie",
+			text: "a great",
+			want: []brain.Segment{{Text: "a great", Sources: []string{"1", "3"}}},
+		},
+		{
+			name: "unknown",
+			text: "Nijika the rock",
+			want: []brain.Segment{
+				{Text: "Nijika", Sources: nil},
+				{Text: "the rock", Sources: []string{"1", "2"}},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := brain.Explain(c.text, sources)
+			if !slices.EqualFunc(got, c.want, func(a, b brain.Segment) bool {
+				return a.Text == b.Text && slices.Equal(a.Sources, b.Sources)
+			}) {
+				t.Errorf("wrong explanation:\nwant %q\ngot  %q", c.want, got)
+			}
+		})
+	}
+}
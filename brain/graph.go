@@ -0,0 +1,126 @@
+package brain
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Graph is a neighborhood of a Markov chain.
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a term in a chain neighborhood.
+// The same term may appear in multiple nodes when it is reached with
+// different context.
+type GraphNode struct {
+	ID int `json:"id"`
+	// Term is the term, or the prompt for the root node.
+	// The empty string denotes the end of a message.
+	Term string `json:"term"`
+}
+
+// GraphEdge is a continuation from one node to another.
+type GraphEdge struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+	// Count is the number of times the continuation was learned.
+	Count int `json:"count"`
+}
+
+// Neighborhood explores the terms which follow a prompt, up to depth terms
+// deep and keeping up to width of the most common continuations of each term.
+// Node 0 is the prompt.
+func Neighborhood(ctx context.Context, c Continuer, tag, prompt string, depth, width int) (*Graph, error) {
+	toks := Tokens(nil, prompt)
+	for i, t := range toks {
+		toks[i] = ReduceEntropy(t)
+	}
+	slices.Reverse(toks)
+	g := Graph{Nodes: []GraphNode{{ID: 0, Term: prompt}}}
+	type frontier struct {
+		id     int
+		prompt []string
+	}
+	cur := []frontier{{id: 0, prompt: toks}}
+	for range depth {
+		var next []frontier
+		for _, f := range cur {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			terms, err := continuations(ctx, c, tag, f.prompt)
+			if err != nil {
+				return nil, err
+			}
+			type tc struct {
+				term  string
+				count int
+			}
+			l := make([]tc, 0, len(terms))
+			for t, n := range terms {
+				l = append(l, tc{t, n})
+			}
+			slices.SortFunc(l, func(a, b tc) int {
+				return cmp.Or(cmp.Compare(b.count, a.count), strings.Compare(a.term, b.term))
+			})
+			for _, v := range l[:min(len(l), width)] {
+				id := len(g.Nodes)
+				g.Nodes = append(g.Nodes, GraphNode{ID: id, Term: strings.TrimSpace(v.term)})
+				g.Edges = append(g.Edges, GraphEdge{From: f.id, To: id, Count: v.count})
+				if v.term == "" {
+					continue
+				}
+				p := make([]string, 0, len(f.prompt)+1)
+				p = append(p, ReduceEntropy(v.term))
+				p = append(p, f.prompt...)
+				next = append(next, frontier{id: id, prompt: p})
+			}
+		}
+		cur = next
+	}
+	return &g, nil
+}
+
+// continuations finds continuations of a prompt, dropping the oldest terms
+// while there are few options, as in generation.
+func continuations(ctx context.Context, c Continuer, tag string, prompt []string) (map[string]int, error) {
+	for {
+		terms, err := c.Continuations(ctx, tag, prompt)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't find continuations: %w", err)
+		}
+		if len(terms) >= 3 || len(prompt) <= 3 {
+			return terms, nil
+		}
+		prompt = prompt[:len(prompt)-1]
+	}
+}
+
+// WriteDOT writes the graph in Graphviz DOT format.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph chain {\n")
+	for _, n := range g.Nodes {
+		label := n.Term
+		if label == "" {
+			label = "<end>"
+			if n.ID == 0 {
+				label = "<start>"
+			}
+		}
+		fmt.Fprintf(&b, "\tn%d [label=%s];\n", n.ID, strconv.Quote(label))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "\tn%d -> n%d [label=\"%d\"];\n", e.From, e.To, e.Count)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
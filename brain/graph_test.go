@@ -0,0 +1,60 @@
+package brain_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/zephyrtronium/robot/brain"
+)
+
+// chain is a Continuer keyed by the most recent term of the prompt.
+type chain map[string]map[string]int
+
+func (c chain) Continuations(ctx context.Context, tag string, prompt []string) (map[string]int, error) {
+	k := ""
+	if len(prompt) > 0 {
+		k = prompt[0]
+	}
+	return c[k], nil
+}
+
+func TestNeighborhood(t *testing.T) {
+	c := chain{
+		"":        {"bocchi ": 3, "kita ": 1},
+		"bocchi ": {"the ": 2, "": 1},
+		"kita ":   {"aura ": 1},
+		"the ":    {"rock ": 2},
+	}
+	g, err := brain.Neighborhood(context.Background(), c, "kessoku", "", 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []brain.GraphNode{{0, ""}, {1, "bocchi"}, {2, "the"}}
+	if len(g.Nodes) != len(want) {
+		t.Fatalf("wrong nodes: want %v, got %v", want, g.Nodes)
+	}
+	for i, n := range want {
+		if g.Nodes[i] != n {
+			t.Errorf("wrong node %d: want %v, got %v", i, n, g.Nodes[i])
+		}
+	}
+	wantEdges := []brain.GraphEdge{{From: 0, To: 1, Count: 3}, {From: 1, To: 2, Count: 2}}
+	if len(g.Edges) != len(wantEdges) {
+		t.Fatalf("wrong edges: want %v, got %v", wantEdges, g.Edges)
+	}
+	for i, e := range wantEdges {
+		if g.Edges[i] != e {
+			t.Errorf("wrong edge %d: want %v, got %v", i, e, g.Edges[i])
+		}
+	}
+	var b strings.Builder
+	if err := g.WriteDOT(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`n0 [label="<start>"]`, `n1 [label="bocchi"]`, `n1 -> n2 [label="2"]`} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("DOT output missing %q:\n%s", s, b.String())
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package kvbrain
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"github.com/zephyrtronium/robot/brain"
+)
+
+var _ brain.Evicter = (*Brain)(nil)
+
+// usage is the storage used by one message's knowledge.
+type usage struct {
+	// version is the earliest Badger version among the message's keys,
+	// which orders messages by when they were learned.
+	version uint64
+	tuples  int64
+	bytes   int64
+}
+
+// Evict deletes the knowledge of the oldest messages learned under a tag
+// until the rest are within the quota.
+// The brain doesn't keep message timestamps, so messages are ordered by when
+// they were learned rather than when they were sent.
+func (br *Brain) Evict(ctx context.Context, tag string, quota brain.Quota) (int, error) {
+	if quota.Tuples <= 0 && quota.Bytes <= 0 {
+		return 0, nil
+	}
+	msgs := make(map[string]*usage)
+	var tuples, size int64
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	opts.Prefix = hashTag(nil, tag)
+	err := br.knowledge.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			item := it.Item()
+			id, n := keyUsage(item.Key())
+			n += item.ValueSize()
+			u := msgs[string(id)]
+			if u == nil {
+				u = &usage{version: item.Version()}
+				msgs[string(id)] = u
+			}
+			u.version = min(u.version, item.Version())
+			u.tuples++
+			u.bytes += n
+			tuples++
+			size += n
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("couldn't measure usage: %w", err)
+	}
+	over := func() bool {
+		return quota.Tuples > 0 && tuples > quota.Tuples || quota.Bytes > 0 && size > quota.Bytes
+	}
+	if !over() {
+		return 0, nil
+	}
+
+	ids := make([]string, 0, len(msgs))
+	for id := range msgs {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b string) int { return cmp.Compare(msgs[a].version, msgs[b].version) })
+	evict := make(map[string]bool)
+	for _, id := range ids {
+		if !over() {
+			break
+		}
+		evict[id] = true
+		tuples -= msgs[id].tuples
+		size -= msgs[id].bytes
+	}
+
+	batch := br.knowledge.NewWriteBatch()
+	defer batch.Cancel()
+	err = br.knowledge.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			key := it.Item().Key()
+			if id, _ := keyUsage(key); !evict[string(id)] {
+				continue
+			}
+			if err := batch.Delete(it.Item().KeyCopy(nil)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("couldn't find knowledge to evict: %w", err)
+	}
+	if err := batch.Flush(); err != nil {
+		return 0, fmt.Errorf("couldn't commit eviction: %w", err)
+	}
+	return len(evict), nil
+}
+
+// keyUsage extracts the message ID from a knowledge key and counts the bytes
+// of text in its prefix.
+func keyUsage(key []byte) (id []byte, n int64) {
+	b := key[tagHashLen:]
+	k := bytes.LastIndexByte(b, '\xff')
+	// Each prefix term is followed by a sentinel, and the prefix is ended by
+	// another.
+	return b[k+1:], int64(k + 1 - bytes.Count(b[:k+1], []byte{'\xff'}))
+}
@@ -0,0 +1,81 @@
+package kvbrain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+func TestEvict(t *testing.T) {
+	ctx := context.Background()
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	br := New(db)
+	// Messages are evicted in the order they're learned, regardless of time.
+	learn := []struct {
+		tag, id, msg string
+		t            int64
+	}{
+		{"kessoku", "1", "bocchi plays guitar", 3},
+		{"kessoku", "2", "nijika plays drums", 2},
+		{"kessoku", "3", "ryo plays bass", 1},
+		{"sickhack", "4", "kikuri plays bass", 0},
+	}
+	for _, l := range learn {
+		if err := brain.Learn(ctx, br, l.tag, l.id, userhash.Hash{1}, time.Unix(l.t, 0), brain.Tokens(nil, l.msg)); err != nil {
+			t.Fatalf("couldn't learn %s/%s: %v", l.tag, l.id, err)
+		}
+	}
+	has := func(tag, id string) bool {
+		t.Helper()
+		var ok bool
+		err := db.View(func(txn *badger.Txn) error {
+			var err error
+			ok, err = known(txn, tag, id)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+	n, err := br.Evict(ctx, "kessoku", brain.Quota{Tuples: 8})
+	if err != nil {
+		t.Errorf("couldn't evict: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("wrong number of messages evicted for tuples: want 1, got %d", n)
+	}
+	if has("kessoku", "1") {
+		t.Errorf("oldest message still known after eviction")
+	}
+	// The tuples of "ryo plays bass" have 44 bytes of text.
+	n, err = br.Evict(ctx, "kessoku", brain.Quota{Bytes: 50})
+	if err != nil {
+		t.Errorf("couldn't evict: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("wrong number of messages evicted for bytes: want 1, got %d", n)
+	}
+	if !has("kessoku", "3") {
+		t.Errorf("newest message evicted")
+	}
+	if !has("sickhack", "4") {
+		t.Errorf("message in other tag evicted")
+	}
+	s, err := br.Stats(ctx, "kessoku")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Tuples != 4 {
+		t.Errorf("wrong tuples remaining: want 4, got %d", s.Tuples)
+	}
+}
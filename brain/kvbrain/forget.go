@@ -157,7 +157,7 @@ func (br *Brain) ForgetUser(ctx context.Context, user *userhash.Hash) error {
 			rangeErr = fmt.Errorf("couldn't commit deleting messages by user: %w", err)
 			return false
 		}
-		return false
+		return true
 	})
 	return rangeErr
 }
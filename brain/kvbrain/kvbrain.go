@@ -40,7 +40,10 @@ type Brain struct {
 	past      sync2.Map[string, *past]
 }
 
-var _ brain.Learner = (*Brain)(nil)
+var (
+	_ brain.Learner = (*Brain)(nil)
+	_ brain.Batcher = (*Brain)(nil)
+)
 
 func New(knowledge *badger.DB) *Brain {
 	return &Brain{
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/dgraph-io/badger/v4"
+
 	"github.com/zephyrtronium/robot/brain"
 	"github.com/zephyrtronium/robot/userhash"
 )
@@ -16,16 +18,91 @@ import (
 // denote the start of the message and end with one empty suffix to denote
 // the end; all other tokens are non-empty. Each tuple's prefix has entropy
 // reduction transformations applied.
+// If a message with the same tag and ID is already known, Learn does nothing.
 func (br *Brain) Learn(ctx context.Context, tag, id string, user userhash.Hash, t time.Time, tuples []brain.Tuple) error {
 	if len(tuples) == 0 {
 		return errors.New("no tuples to learn")
 	}
-	// Construct the keys and values we will use.
+	keys, vals := knowledge(tag, id, tuples)
+	var seen bool
+	err := br.knowledge.View(func(txn *badger.Txn) error {
+		var err error
+		seen, err = known(txn, tag, id)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't check for message: %w", err)
+	}
+	if seen {
+		return nil
+	}
+	br.record(tag, id, user, t, keys)
+
+	batch := br.knowledge.NewWriteBatch()
+	defer batch.Cancel()
+	for i, key := range keys {
+		err := batch.Set(key, vals[i])
+		if err != nil {
+			return err
+		}
+	}
+	err = batch.Flush()
+	if err != nil {
+		return fmt.Errorf("couldn't commit learned knowledge: %w", err)
+	}
+	return nil
+}
+
+// LearnBatch records the tuples of each message in a single transaction.
+// It fails without learning anything if the batch is too large for one
+// transaction. Messages already known are skipped.
+func (br *Brain) LearnBatch(ctx context.Context, msgs []brain.Message) error {
+	keys := make([][][]byte, len(msgs))
+	err := br.knowledge.Update(func(txn *badger.Txn) error {
+		for i, m := range msgs {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if len(m.Tuples) == 0 {
+				return fmt.Errorf("no tuples to learn from message %v", m.ID)
+			}
+			k, v := knowledge(m.Tag, m.ID, m.Tuples)
+			seen, err := known(txn, m.Tag, m.ID)
+			if err != nil {
+				return err
+			}
+			if seen {
+				continue
+			}
+			for j, key := range k {
+				if err := txn.Set(key, v[j]); err != nil {
+					return err
+				}
+			}
+			keys[i] = k
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't commit learned knowledge: %w", err)
+	}
+	// Only record messages once they're committed, so that we don't try to
+	// forget keys that don't exist.
+	for i, m := range msgs {
+		if keys[i] != nil {
+			br.record(m.Tag, m.ID, m.User, m.Time, keys[i])
+		}
+	}
+	return nil
+}
+
+// knowledge constructs the knowledge keys and values for a message.
+func knowledge(tag, id string, tuples []brain.Tuple) (keys, vals [][]byte) {
 	// There are probably things we could do to control allocations since we're
 	// using many overlapping tuples for keys, but it's tremendously easier to
 	// just fill up a buffer for each.
-	keys := make([][]byte, len(tuples))
-	vals := make([][]byte, len(tuples)) // TODO(zeph): could do one call to make
+	keys = make([][]byte, len(tuples))
+	vals = make([][]byte, len(tuples)) // TODO(zeph): could do one call to make
 	var b []byte
 	for i, t := range tuples {
 		b = hashTag(b[:0], tag)
@@ -35,7 +112,28 @@ func (br *Brain) Learn(ctx context.Context, tag, id string, user userhash.Hash,
 		keys[i] = bytes.Clone(b)
 		vals[i] = []byte(t.Suffix)
 	}
+	return keys, vals
+}
 
+// known reports whether a message has already been learned.
+// Every message has exactly one tuple with the empty prefix, so we check for
+// that key.
+func known(txn *badger.Txn, tag, id string) (bool, error) {
+	key := append(hashTag(nil, tag), '\xff')
+	key = append(key, id...)
+	_, err := txn.Get(key)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, badger.ErrKeyNotFound):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// record records the knowledge keys of a message so that it can be forgotten.
+func (br *Brain) record(tag, id string, user userhash.Hash, t time.Time, keys [][]byte) {
 	p, _ := br.past.Load(tag)
 	if p == nil {
 		// We might race with others also creating this past. Ensure we don't
@@ -43,20 +141,6 @@ func (br *Brain) Learn(ctx context.Context, tag, id string, user userhash.Hash,
 		p, _ = br.past.LoadOrStore(tag, new(past))
 	}
 	p.record(id, user, t.UnixNano(), keys)
-
-	batch := br.knowledge.NewWriteBatch()
-	defer batch.Cancel()
-	for i, key := range keys {
-		err := batch.Set(key, vals[i])
-		if err != nil {
-			return err
-		}
-	}
-	err := batch.Flush()
-	if err != nil {
-		return fmt.Errorf("couldn't commit learned knowledge: %w", err)
-	}
-	return nil
 }
 
 // appendPrefix appends the prefix components for a knowledge key to b,
@@ -136,6 +136,49 @@ func TestLearn(t *testing.T) {
 	}
 }
 
+func TestLearnBatch(t *testing.T) {
+	ctx := context.Background()
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	br := New(db)
+	msgs := []brain.Message{
+		{Tag: "kessoku", ID: "1", Time: time.Unix(0, 1), Tuples: []brain.Tuple{{Prefix: nil, Suffix: "bocchi"}, {Prefix: []string{"bocchi"}, Suffix: ""}}},
+		{Tag: "kessoku", ID: "2", Time: time.Unix(0, 2), Tuples: []brain.Tuple{{Prefix: nil, Suffix: "ryo"}, {Prefix: []string{"ryo"}, Suffix: ""}}},
+		{Tag: "sickhack", ID: "3", Time: time.Unix(0, 3), Tuples: []brain.Tuple{{Prefix: nil, Suffix: "kikuri"}, {Prefix: []string{"kikuri"}, Suffix: ""}}},
+	}
+	if err := br.LearnBatch(ctx, msgs); err != nil {
+		t.Fatalf("couldn't learn batch: %v", err)
+	}
+	want := map[string]string{
+		mkey("kessoku", "\xff", "1"):            "bocchi",
+		mkey("kessoku", "bocchi\xff\xff", "1"):  "",
+		mkey("kessoku", "\xff", "2"):            "ryo",
+		mkey("kessoku", "ryo\xff\xff", "2"):     "",
+		mkey("sickhack", "\xff", "3"):           "kikuri",
+		mkey("sickhack", "kikuri\xff\xff", "3"): "",
+	}
+	dbcheck(t, db, want)
+	// Messages learned in a batch can be forgotten.
+	if err := br.ForgetMessage(ctx, "kessoku", "1"); err != nil {
+		t.Fatalf("couldn't forget: %v", err)
+	}
+	delete(want, mkey("kessoku", "\xff", "1"))
+	delete(want, mkey("kessoku", "bocchi\xff\xff", "1"))
+	dbcheck(t, db, want)
+	// A batch with an empty message fails entirely.
+	bad := []brain.Message{
+		{Tag: "kessoku", ID: "4", Time: time.Unix(0, 4), Tuples: []brain.Tuple{{Prefix: nil, Suffix: "kita"}, {Prefix: []string{"kita"}, Suffix: ""}}},
+		{Tag: "kessoku", ID: "5", Time: time.Unix(0, 5)},
+	}
+	if err := br.LearnBatch(ctx, bad); err == nil {
+		t.Errorf("learning an empty message in a batch succeeded")
+	}
+	dbcheck(t, db, want)
+}
+
 func BenchmarkLearn(b *testing.B) {
 	new := func(ctx context.Context, b *testing.B) brain.Learner {
 		db, err := badger.Open(badger.DefaultOptions(b.TempDir()).WithLogger(nil))
@@ -0,0 +1,67 @@
+package kvbrain
+
+import (
+	"expvar"
+)
+
+// Metrics summarizes the state of the Badger database underlying a brain.
+type Metrics struct {
+	// LSM is the size in bytes of the LSM tree.
+	LSM int64 `json:"lsm_bytes"`
+	// VLog is the size in bytes of the value log.
+	VLog int64 `json:"vlog_bytes"`
+	// Pending is the number of writes waiting to be applied to memtables.
+	Pending int64 `json:"pending_writes"`
+	// Compacting is the number of tables currently being compacted.
+	// Badger counts this across all databases in the process.
+	Compacting int64 `json:"compacting_tables"`
+	// Levels describes each level of the LSM tree.
+	Levels []Level `json:"levels"`
+}
+
+// Level describes one level of the LSM tree.
+type Level struct {
+	// Level is the level number, where 0 is the newest.
+	Level int `json:"level"`
+	// Tables is the number of tables in the level.
+	Tables int `json:"tables"`
+	// Size is the size of the level in bytes.
+	Size int64 `json:"bytes"`
+	// Target is the size in bytes the level aims to stay under.
+	Target int64 `json:"target_bytes"`
+	// Stale is the size in bytes of data in the level that compaction can
+	// discard.
+	Stale int64 `json:"stale_bytes"`
+	// Score is the level's compaction priority. Levels with scores at least 1
+	// need compaction.
+	Score float64 `json:"score"`
+}
+
+// Metrics returns a snapshot of the database's metrics.
+// Sizes are updated by Badger periodically rather than on every write.
+// Size and pending write counts are zero if the database was opened with
+// metrics disabled.
+func (br *Brain) Metrics() Metrics {
+	opts := br.knowledge.Opts()
+	var m Metrics
+	m.LSM, m.VLog = br.knowledge.Size()
+	if v, ok := expvar.Get("badger_write_pending_num_memtable").(*expvar.Map); ok {
+		if n, ok := v.Get(opts.Dir).(*expvar.Int); ok {
+			m.Pending = n.Value()
+		}
+	}
+	if v, ok := expvar.Get("badger_compaction_current_num_lsm").(*expvar.Int); ok {
+		m.Compacting = v.Value()
+	}
+	for _, l := range br.knowledge.Levels() {
+		m.Levels = append(m.Levels, Level{
+			Level:  l.Level,
+			Tables: l.NumTables,
+			Size:   l.Size,
+			Target: l.TargetSize,
+			Stale:  l.StaleDatSize,
+			Score:  l.Score,
+		})
+	}
+	return m
+}
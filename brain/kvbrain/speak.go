@@ -30,6 +30,9 @@ func (br *Brain) Speak(ctx context.Context, tag string, prompt []string, w *brai
 	opts.PrefetchValues = false
 	opts.Prefix = hashTag(nil, tag)
 	for range 1024 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		var err error
 		var l int
 		b = append(b[:0], tb...)
@@ -68,10 +71,11 @@ func (br *Brain) next(b []byte, prompt []string, opts badger.IteratorOptions) ([
 		// options that start a message.
 		b = append(b, '\xff')
 	}
-	for {
-		err := br.knowledge.View(func(txn *badger.Txn) error {
+	// Select the key and read its value in one transaction, so that a
+	// concurrent forget can't delete the key in between.
+	err := br.knowledge.View(func(txn *badger.Txn) error {
+		for {
 			it := txn.NewIterator(opts)
-			defer it.Close()
 			it.Seek(b)
 			for it.ValidForPrefix(b) {
 				if n == 0 {
@@ -84,24 +88,20 @@ func (br *Brain) next(b []byte, prompt []string, opts badger.IteratorOptions) ([
 				it.Next()
 				n--
 			}
-			return nil
-		})
-		if err != nil {
-			return nil, "", len(prompt), fmt.Errorf("couldn't read knowledge: %w", err)
-		}
-		if picked < 3 && len(prompt) > 3 {
-			// We haven't seen enough options, and we have context we could
-			// lose. Do so and try again from the beginning.
-			prompt = prompt[:len(prompt)-1]
-			b = appendPrefix(b[:tagHashLen], prompt)
-			continue
-		}
-		if key == nil {
-			// We never saw any options. Since we always select the first, this
-			// means there were no options. Don't look for nothing in the DB.
-			return b[:0], "", len(prompt), nil
-		}
-		err = br.knowledge.View(func(txn *badger.Txn) error {
+			it.Close()
+			if picked < 3 && len(prompt) > 3 {
+				// We haven't seen enough options, and we have context we could
+				// lose. Do so and try again from the beginning.
+				prompt = prompt[:len(prompt)-1]
+				b = appendPrefix(b[:tagHashLen], prompt)
+				continue
+			}
+			if key == nil {
+				// We never saw any options. Since we always select the first, this
+				// means there were no options. Don't look for nothing in the DB.
+				b = b[:0]
+				return nil
+			}
 			item, err := txn.Get(key)
 			if err != nil {
 				return fmt.Errorf("couldn't get item for key %q: %w", key, err)
@@ -111,13 +111,19 @@ func (br *Brain) next(b []byte, prompt []string, opts badger.IteratorOptions) ([
 				return fmt.Errorf("couldn't get value for key %q: %w", key, err)
 			}
 			return nil
-		})
-		// The id is everything after the first byte following the hash for
-		// empty prefixes, and everything after the first \xff\xff otherwise.
-		id := key[tagHashLen+1:]
-		if len(prompt) > 0 {
-			_, id, _ = bytes.Cut(key, []byte{0xff, 0xff})
 		}
-		return b, string(id), len(prompt), err
+	})
+	if err != nil {
+		return nil, "", len(prompt), fmt.Errorf("couldn't read knowledge: %w", err)
+	}
+	if key == nil {
+		return b, "", len(prompt), nil
+	}
+	// The id is everything after the first byte following the hash for
+	// empty prefixes, and everything after the first \xff\xff otherwise.
+	id := key[tagHashLen+1:]
+	if len(prompt) > 0 {
+		_, id, _ = bytes.Cut(key, []byte{0xff, 0xff})
 	}
+	return b, string(id), len(prompt), nil
 }
@@ -0,0 +1,83 @@
+package kvbrain
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"github.com/zephyrtronium/robot/brain"
+)
+
+var (
+	_ brain.Stater    = (*Brain)(nil)
+	_ brain.Backuper  = (*Brain)(nil)
+	_ brain.Continuer = (*Brain)(nil)
+)
+
+// Stats counts the tuples learned under a tag.
+// The brain does not track messages, so the message count is always -1.
+func (br *Brain) Stats(ctx context.Context, tag string) (*brain.Stats, error) {
+	r := brain.Stats{Messages: -1}
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	opts.Prefix = hashTag(nil, tag)
+	err := br.knowledge.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			r.Tuples++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't count tuples: %w", err)
+	}
+	return &r, nil
+}
+
+// Backup writes a full Badger backup of the brain's knowledge to w.
+// The backup can be restored with [badger.DB.Load].
+func (br *Brain) Backup(ctx context.Context, w io.Writer) error {
+	if _, err := br.knowledge.Backup(w, 0); err != nil {
+		return fmt.Errorf("couldn't back up knowledge: %w", err)
+	}
+	return nil
+}
+
+// Continuations counts the terms learned to follow a prompt.
+func (br *Brain) Continuations(ctx context.Context, tag string, prompt []string) (map[string]int, error) {
+	b := appendPrefix(hashTag(nil, tag), prompt)
+	if len(prompt) == 0 {
+		// Only count terms which start messages.
+		b = append(b, '\xff')
+	}
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = b
+	r := make(map[string]int)
+	err := br.knowledge.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			err := it.Item().Value(func(val []byte) error {
+				r[string(val)]++
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read continuations: %w", err)
+	}
+	return r, nil
+}
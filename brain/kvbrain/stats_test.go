@@ -0,0 +1,96 @@
+package kvbrain_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/kvbrain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	db, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	br := kvbrain.New(db)
+	msgs := []struct {
+		tag, id, text string
+	}{
+		{"kessoku", "1", "bocchi the rock"},
+		{"kessoku", "2", "kita"},
+		{"sickhack", "3", "kikuri"},
+	}
+	for _, m := range msgs {
+		err := brain.Learn(ctx, br, m.tag, m.id, userhash.Hash{}, time.Unix(0, 0), strings.Fields(m.text))
+		if err != nil {
+			t.Fatalf("couldn't learn %q: %v", m.text, err)
+		}
+	}
+	if err := br.ForgetMessage(ctx, "kessoku", "2"); err != nil {
+		t.Fatalf("couldn't forget: %v", err)
+	}
+	st, err := br.Stats(ctx, "kessoku")
+	if err != nil {
+		t.Fatalf("couldn't get stats: %v", err)
+	}
+	if want := (brain.Stats{Tuples: 4, Messages: -1}); *st != want {
+		t.Errorf("wrong stats: want %+v, got %+v", want, *st)
+	}
+	c, err := br.Continuations(ctx, "kessoku", []string{"bocchi"})
+	if err != nil {
+		t.Fatalf("couldn't get continuations: %v", err)
+	}
+	if len(c) != 1 || c["the"] != 1 {
+		t.Errorf("wrong continuations of bocchi: %v", c)
+	}
+	c, err = br.Continuations(ctx, "kessoku", nil)
+	if err != nil {
+		t.Fatalf("couldn't get continuations: %v", err)
+	}
+	if len(c) != 1 || c["bocchi"] != 1 {
+		t.Errorf("wrong starting continuations: %v", c)
+	}
+	var b bytes.Buffer
+	if err := br.Backup(ctx, &b); err != nil {
+		t.Fatalf("couldn't back up: %v", err)
+	}
+	if b.Len() == 0 {
+		t.Errorf("empty backup")
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	opts := badger.DefaultOptions("").WithInMemory(true).WithLogger(nil)
+	db, err := badger.Open(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	br := kvbrain.New(db)
+	err = brain.Learn(ctx, br, "kessoku", "1", userhash.Hash{}, time.Unix(0, 0), strings.Fields("bocchi the rock"))
+	if err != nil {
+		t.Fatalf("couldn't learn: %v", err)
+	}
+	m := br.Metrics()
+	if len(m.Levels) != opts.MaxLevels {
+		t.Fatalf("wrong number of levels: want %d, got %+v", opts.MaxLevels, m.Levels)
+	}
+	for i, l := range m.Levels {
+		if l.Level != i {
+			t.Errorf("level %d has wrong number %d", i, l.Level)
+		}
+	}
+	if m.LSM < 0 || m.VLog < 0 || m.Pending < 0 || m.Compacting < 0 {
+		t.Errorf("negative metrics: %+v", m)
+	}
+}
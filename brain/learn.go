@@ -26,6 +26,8 @@ type Learner interface {
 	// of the message. The positions of each in the argument are not guaranteed.
 	// Each tuple's prefix has entropy reduction transformations applied.
 	// Tuples in the argument may share storage for prefixes.
+	// If a message with the same tag and ID has already been learned, Learn
+	// should do nothing, so that replayed messages aren't weighted twice.
 	Learn(ctx context.Context, tag, id string, user userhash.Hash, t time.Time, tuples []Tuple) error
 	// ForgetMessage forgets everything learned from a single given message.
 	// If nothing has been learned from the message, it should be ignored.
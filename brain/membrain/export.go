@@ -0,0 +1,111 @@
+package membrain
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/zephyrtronium/robot/brain"
+)
+
+var (
+	_ brain.Backuper = (*Brain)(nil)
+	_ brain.Exporter = (*Brain)(nil)
+)
+
+// exported is a single line of an export.
+type exported struct {
+	Tag string `json:"tag"`
+	ID  string `json:"id"`
+	record
+}
+
+// Backup writes all knowledge to w as JSON lines, one per learned message.
+// The result can be loaded with [Brain.Restore], including by programs
+// embedding the brain elsewhere.
+func (br *Brain) Backup(ctx context.Context, w io.Writer) error {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, tag := range sorted(br.tags) {
+		k := br.tags[tag]
+		for _, id := range sorted(k.msgs) {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := enc.Encode(exported{Tag: tag, ID: id, record: *k.msgs[id]}); err != nil {
+				return fmt.Errorf("couldn't write message %s/%s: %w", tag, id, err)
+			}
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("couldn't write backup: %w", err)
+	}
+	return nil
+}
+
+// Restore learns all messages in an export written by [Brain.Backup].
+// Messages already learned under the same tag and ID are replaced.
+func (br *Brain) Restore(ctx context.Context, r io.Reader) error {
+	dec := json.NewDecoder(r)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var m exported
+		err := dec.Decode(&m)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("couldn't read backup: %w", err)
+		}
+		br.mu.Lock()
+		br.learnLocked(m.Tag, m.ID, &m.record)
+		br.mu.Unlock()
+	}
+}
+
+// Export calls f with each distinct tuple learned under a tag and the number
+// of times it was learned, in order of prefix and suffix.
+func (br *Brain) Export(ctx context.Context, tag string, f func(brain.Counted) error) error {
+	br.mu.Lock()
+	counts := make(map[string]*brain.Counted)
+	if k := br.tags[tag]; k != nil {
+		for _, m := range k.msgs {
+			for _, tup := range m.Tuples {
+				key := strings.Join(tup.Prefix, "\x00") + "\x01" + tup.Suffix
+				c := counts[key]
+				if c == nil {
+					c = &brain.Counted{Prefix: slices.Clone(tup.Prefix), Suffix: tup.Suffix}
+					counts[key] = c
+				}
+				c.Count++
+			}
+		}
+	}
+	br.mu.Unlock()
+	for _, key := range sorted(counts) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := f(*counts[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sorted[V any](m map[string]V) []string {
+	r := make([]string, 0, len(m))
+	for k := range m {
+		r = append(r, k)
+	}
+	slices.Sort(r)
+	return r
+}
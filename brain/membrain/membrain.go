@@ -0,0 +1,309 @@
+// Package membrain implements a brain held entirely in memory.
+//
+// The package depends only on the standard library and the brain package, so
+// it builds for any target including js/wasm and wasip1/wasm. It is suitable
+// for embedding the same Markov engine as Robot in other programs, such as
+// web playgrounds, or for exploring brain exports without a database.
+package membrain
+
+import (
+	"cmp"
+	"context"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// Brain is a brain held in memory.
+// The zero value is an empty brain ready to use.
+type Brain struct {
+	mu   sync.Mutex
+	tags map[string]*tagged
+}
+
+// tagged is the knowledge under a single tag.
+type tagged struct {
+	// msgs maps message IDs to the records learned from them.
+	msgs map[string]*record
+	// tups maps the latest term of each prefix to the tuples containing it.
+	// The empty prefix is under the empty string.
+	tups map[string][]entry
+}
+
+// record is a single learned message.
+type record struct {
+	User   userhash.Hash `json:"user"`
+	Time   int64         `json:"time"`
+	Tuples []brain.Tuple `json:"tuples"`
+}
+
+// entry is a single tuple in the index.
+type entry struct {
+	id     string
+	prefix []string
+	suffix string
+}
+
+var (
+	_ brain.Brain    = (*Brain)(nil)
+	_ brain.Stater   = (*Brain)(nil)
+	_ brain.Recaller = (*Brain)(nil)
+	_ brain.Evicter  = (*Brain)(nil)
+)
+
+// New returns a new empty brain.
+func New() *Brain {
+	return new(Brain)
+}
+
+// Learn records a set of tuples.
+// If a message with the same tag and ID is already known, Learn does nothing.
+func (br *Brain) Learn(ctx context.Context, tag, id string, user userhash.Hash, t time.Time, tuples []brain.Tuple) error {
+	// Tuples may share storage, so copy them before keeping them.
+	r := record{User: user, Time: t.UnixNano(), Tuples: make([]brain.Tuple, len(tuples))}
+	for i, tup := range tuples {
+		r.Tuples[i] = brain.Tuple{Prefix: slices.Clone(tup.Prefix), Suffix: tup.Suffix}
+	}
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	if k := br.tags[tag]; k != nil && k.msgs[id] != nil {
+		return nil
+	}
+	br.learnLocked(tag, id, &r)
+	return nil
+}
+
+func (br *Brain) learnLocked(tag, id string, r *record) {
+	if br.tags == nil {
+		br.tags = make(map[string]*tagged)
+	}
+	k := br.tags[tag]
+	if k == nil {
+		k = &tagged{msgs: make(map[string]*record), tups: make(map[string][]entry)}
+		br.tags[tag] = k
+	}
+	if k.msgs[id] != nil {
+		k.forget(id)
+	}
+	k.msgs[id] = r
+	for _, tup := range r.Tuples {
+		key := latest(tup.Prefix)
+		k.tups[key] = append(k.tups[key], entry{id: id, prefix: tup.Prefix, suffix: tup.Suffix})
+	}
+}
+
+// latest returns the index key for a prefix.
+func latest(prefix []string) string {
+	if len(prefix) == 0 {
+		return ""
+	}
+	return prefix[0]
+}
+
+// forget removes a message from the index.
+func (k *tagged) forget(id string) {
+	r := k.msgs[id]
+	if r == nil {
+		return
+	}
+	delete(k.msgs, id)
+	for _, tup := range r.Tuples {
+		key := latest(tup.Prefix)
+		u := slices.DeleteFunc(k.tups[key], func(e entry) bool { return e.id == id })
+		if len(u) == 0 {
+			delete(k.tups, key)
+		} else {
+			k.tups[key] = u
+		}
+	}
+}
+
+// ForgetMessage forgets everything learned from a single given message.
+func (br *Brain) ForgetMessage(ctx context.Context, tag, id string) error {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	if k := br.tags[tag]; k != nil {
+		k.forget(id)
+	}
+	return nil
+}
+
+// ForgetDuring forgets all messages learned in the given time span.
+func (br *Brain) ForgetDuring(ctx context.Context, tag string, since, before time.Time) error {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	k := br.tags[tag]
+	if k == nil {
+		return nil
+	}
+	s, b := since.UnixNano(), before.UnixNano()
+	for id, r := range k.msgs {
+		if r.Time >= s && r.Time <= b {
+			k.forget(id)
+		}
+	}
+	return nil
+}
+
+// ForgetUser forgets all messages associated with a userhash.
+func (br *Brain) ForgetUser(ctx context.Context, user *userhash.Hash) error {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	for _, k := range br.tags {
+		for id, r := range k.msgs {
+			if r.User == *user {
+				k.forget(id)
+			}
+		}
+	}
+	return nil
+}
+
+// Evict forgets the oldest messages learned under a tag until the rest are
+// within the quota.
+func (br *Brain) Evict(ctx context.Context, tag string, quota brain.Quota) (int, error) {
+	if quota.Tuples <= 0 && quota.Bytes <= 0 {
+		return 0, nil
+	}
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	k := br.tags[tag]
+	if k == nil {
+		return 0, nil
+	}
+	var tuples, bytes int64
+	ids := make([]string, 0, len(k.msgs))
+	for id, r := range k.msgs {
+		tuples += int64(len(r.Tuples))
+		bytes += r.size()
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b string) int { return cmp.Compare(k.msgs[a].Time, k.msgs[b].Time) })
+	n := 0
+	for _, id := range ids {
+		if (quota.Tuples <= 0 || tuples <= quota.Tuples) && (quota.Bytes <= 0 || bytes <= quota.Bytes) {
+			break
+		}
+		r := k.msgs[id]
+		tuples -= int64(len(r.Tuples))
+		bytes -= r.size()
+		k.forget(id)
+		n++
+	}
+	return n, nil
+}
+
+// size is the number of bytes of text in the record's tuples.
+func (r *record) size() int64 {
+	var n int64
+	for _, t := range r.Tuples {
+		for _, w := range t.Prefix {
+			n += int64(len(w))
+		}
+		n += int64(len(t.Suffix))
+	}
+	return n
+}
+
+// Speak generates a full message and appends it to w.
+// The prompt is in reverse order and has entropy reduction applied.
+func (br *Brain) Speak(ctx context.Context, tag string, prompt []string, w *brain.Builder) error {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	k := br.tags[tag]
+	if k == nil {
+		return nil
+	}
+	search := slices.Clone(prompt)
+	for range 1024 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		e, n := k.next(search)
+		if e == nil || e.suffix == "" {
+			break
+		}
+		w.Append(e.id, []byte(e.suffix))
+		search = slices.Insert(search[:n], 0, brain.ReduceEntropy(e.suffix))
+	}
+	return nil
+}
+
+// next selects a tuple to continue a prompt, along with the number of terms
+// of the prompt that it matched. The result is nil if there are no options.
+func (k *tagged) next(prompt []string) (*entry, int) {
+	for {
+		var (
+			e      *entry
+			skip   brain.Skip
+			picked int
+			n      uint64
+		)
+		for i, c := range k.tups[latest(prompt)] {
+			if !matches(c.prefix, prompt) {
+				continue
+			}
+			picked++
+			if n == 0 {
+				e = &k.tups[latest(prompt)][i]
+				n = skip.N(rand.Uint64(), rand.Uint64())
+				continue
+			}
+			n--
+		}
+		if picked < 3 && len(prompt) > 3 {
+			// We haven't seen enough options, and we have context we could
+			// lose. Do so and try again.
+			prompt = prompt[:len(prompt)-1]
+			continue
+		}
+		return e, len(prompt)
+	}
+}
+
+// matches returns whether a learned prefix continues a prompt.
+func matches(prefix, prompt []string) bool {
+	if len(prompt) == 0 {
+		// Only select options which start messages.
+		return len(prefix) == 0
+	}
+	return len(prefix) >= len(prompt) && slices.Equal(prefix[:len(prompt)], prompt)
+}
+
+// Stats counts the tuples and messages learned under a tag.
+func (br *Brain) Stats(ctx context.Context, tag string) (*brain.Stats, error) {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	var r brain.Stats
+	if k := br.tags[tag]; k != nil {
+		for _, m := range k.msgs {
+			r.Tuples += int64(len(m.Tuples))
+		}
+		r.Messages = int64(len(k.msgs))
+	}
+	return &r, nil
+}
+
+// Recall reconstructs the text of a learned message from its tuples.
+func (br *Brain) Recall(ctx context.Context, tag, id string) (string, error) {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	k := br.tags[tag]
+	if k == nil || k.msgs[id] == nil {
+		return "", nil
+	}
+	// Each tuple's suffix follows its prefix, so ordering by prefix length
+	// puts the suffixes in message order.
+	tups := slices.Clone(k.msgs[id].Tuples)
+	slices.SortFunc(tups, func(a, b brain.Tuple) int { return len(a.Prefix) - len(b.Prefix) })
+	var b strings.Builder
+	for _, tup := range tups {
+		b.WriteString(tup.Suffix)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
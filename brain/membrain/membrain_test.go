@@ -0,0 +1,136 @@
+package membrain_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/braintest"
+	"github.com/zephyrtronium/robot/brain/membrain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+func TestBrain(t *testing.T) {
+	braintest.Test(context.Background(), t, func(ctx context.Context) brain.Brain {
+		return membrain.New()
+	})
+}
+
+func TestRecall(t *testing.T) {
+	ctx := context.Background()
+	br := membrain.New()
+	msg := "Kikuri drinks sake, always"
+	if err := brain.Learn(ctx, br, "sickhack", "4", userhash.Hash{1}, time.Unix(1, 0), brain.Tokens(nil, msg)); err != nil {
+		t.Fatalf("couldn't learn: %v", err)
+	}
+	got, err := br.Recall(ctx, "sickhack", "4")
+	if err != nil {
+		t.Errorf("couldn't recall: %v", err)
+	}
+	if got != msg {
+		t.Errorf("wrong recall: want %q, got %q", msg, got)
+	}
+	if err := br.ForgetMessage(ctx, "sickhack", "4"); err != nil {
+		t.Fatalf("couldn't forget: %v", err)
+	}
+	got, err = br.Recall(ctx, "sickhack", "4")
+	if err != nil {
+		t.Errorf("couldn't recall forgotten: %v", err)
+	}
+	if got != "" {
+		t.Errorf("forgotten message recalled as %q", got)
+	}
+}
+
+func TestBackupRestore(t *testing.T) {
+	ctx := context.Background()
+	br := membrain.New()
+	learn := []struct {
+		tag, id, msg string
+	}{
+		{"kessoku", "1", "bocchi plays guitar"},
+		{"kessoku", "2", "nijika plays drums"},
+		{"sickhack", "3", "kikuri plays bass"},
+	}
+	for _, l := range learn {
+		if err := brain.Learn(ctx, br, l.tag, l.id, userhash.Hash{1}, time.Unix(1, 0), brain.Tokens(nil, l.msg)); err != nil {
+			t.Fatalf("couldn't learn %s/%s: %v", l.tag, l.id, err)
+		}
+	}
+	var b bytes.Buffer
+	if err := br.Backup(ctx, &b); err != nil {
+		t.Fatalf("couldn't back up: %v", err)
+	}
+	r := membrain.New()
+	if err := r.Restore(ctx, &b); err != nil {
+		t.Fatalf("couldn't restore: %v", err)
+	}
+	for _, l := range learn {
+		got, err := r.Recall(ctx, l.tag, l.id)
+		if err != nil {
+			t.Errorf("couldn't recall %s/%s: %v", l.tag, l.id, err)
+		}
+		if got != l.msg {
+			t.Errorf("wrong message for %s/%s: want %q, got %q", l.tag, l.id, l.msg, got)
+		}
+	}
+	st, err := r.Stats(ctx, "kessoku")
+	if err != nil {
+		t.Fatalf("couldn't get stats: %v", err)
+	}
+	if st.Messages != 2 || st.Tuples != 8 {
+		t.Errorf("wrong stats after restore: want 2 messages and 8 tuples, got %+v", st)
+	}
+	// Forgetting by user must still work after restoring.
+	if err := r.ForgetUser(ctx, &userhash.Hash{1}); err != nil {
+		t.Fatalf("couldn't forget user: %v", err)
+	}
+	if got, _ := r.Recall(ctx, "sickhack", "3"); got != "" {
+		t.Errorf("message from forgotten user recalled as %q", got)
+	}
+}
+
+func TestEvict(t *testing.T) {
+	ctx := context.Background()
+	br := membrain.New()
+	learn := []struct {
+		tag, id, msg string
+		t            int64
+	}{
+		{"kessoku", "2", "nijika plays drums", 2},
+		{"kessoku", "1", "bocchi plays guitar", 1},
+		{"kessoku", "3", "ryo plays bass", 3},
+		{"sickhack", "4", "kikuri plays bass", 0},
+	}
+	for _, l := range learn {
+		if err := brain.Learn(ctx, br, l.tag, l.id, userhash.Hash{1}, time.Unix(l.t, 0), brain.Tokens(nil, l.msg)); err != nil {
+			t.Fatalf("couldn't learn %s/%s: %v", l.tag, l.id, err)
+		}
+	}
+	n, err := br.Evict(ctx, "kessoku", brain.Quota{Tuples: 8})
+	if err != nil {
+		t.Errorf("couldn't evict: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("wrong number of messages evicted for tuples: want 1, got %d", n)
+	}
+	if s, _ := br.Recall(ctx, "kessoku", "1"); s != "" {
+		t.Errorf("oldest message still known after eviction: %q", s)
+	}
+	// The tuples of "ryo plays bass" have 44 bytes of text.
+	n, err = br.Evict(ctx, "kessoku", brain.Quota{Bytes: 50})
+	if err != nil {
+		t.Errorf("couldn't evict: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("wrong number of messages evicted for bytes: want 1, got %d", n)
+	}
+	if s, _ := br.Recall(ctx, "kessoku", "3"); s == "" {
+		t.Errorf("newest message evicted")
+	}
+	if s, _ := br.Recall(ctx, "sickhack", "4"); s == "" {
+		t.Errorf("message in other tag evicted")
+	}
+}
@@ -0,0 +1,29 @@
+package brain
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+)
+
+// opKey is the context key for operation IDs.
+type opKey struct{}
+
+// WithOp returns a context which attributes forgetting through it to an
+// operation, so that brains which keep forgotten knowledge for a while can
+// restore everything the operation forgot.
+func WithOp(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, opKey{}, id)
+}
+
+// Op returns the ID of the operation attached to ctx by [WithOp], or the empty
+// string if there is none.
+func Op(ctx context.Context) string {
+	id, _ := ctx.Value(opKey{}).(string)
+	return id
+}
+
+// NewOp creates a random operation ID.
+func NewOp() string {
+	return fmt.Sprintf("%016x", rand.Uint64())
+}
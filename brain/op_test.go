@@ -0,0 +1,22 @@
+package brain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zephyrtronium/robot/brain"
+)
+
+func TestOp(t *testing.T) {
+	ctx := context.Background()
+	if id := brain.Op(ctx); id != "" {
+		t.Errorf("operation without one attached: %q", id)
+	}
+	id := brain.NewOp()
+	if len(id) != 16 {
+		t.Errorf("wrong length of operation ID %q", id)
+	}
+	if got := brain.Op(brain.WithOp(ctx, id)); got != id {
+		t.Errorf("wrong operation: want %q, got %q", id, got)
+	}
+}
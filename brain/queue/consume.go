@@ -0,0 +1,74 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/zephyrtronium/robot/brain"
+)
+
+// Consumer writes operations from a queue to a brain.
+type Consumer struct {
+	br brain.Brain
+	t  Transport
+
+	applied  atomic.Int64
+	failures atomic.Int64
+	dropped  atomic.Int64
+}
+
+// NewConsumer creates a consumer which applies operations from t to br.
+func NewConsumer(br brain.Brain, t Transport) *Consumer {
+	return &Consumer{br: br, t: t}
+}
+
+// Stats is a snapshot of a consumer's counters.
+type Stats struct {
+	// Applied is the number of operations written to the brain.
+	Applied int64 `json:"applied"`
+	// Failures is the number of attempts to write an operation which failed
+	// and will be retried.
+	Failures int64 `json:"failures"`
+	// Dropped is the number of operations which couldn't be decoded.
+	Dropped int64 `json:"dropped"`
+}
+
+// Stats returns a snapshot of the consumer's counters.
+func (c *Consumer) Stats() Stats {
+	return Stats{
+		Applied:  c.applied.Load(),
+		Failures: c.failures.Load(),
+		Dropped:  c.dropped.Load(),
+	}
+}
+
+// Run applies operations until ctx is canceled.
+func (c *Consumer) Run(ctx context.Context) error {
+	return c.t.Consume(ctx, c.handle)
+}
+
+func (c *Consumer) handle(ctx context.Context, data []byte) error {
+	var o op
+	err := json.Unmarshal(data, &o)
+	if err == nil {
+		err = o.apply(ctx, c.br)
+	}
+	var syn *json.SyntaxError
+	if errors.Is(err, errMalformed) || errors.As(err, &syn) {
+		// Retrying won't fix it. Drop it so it doesn't hold up everything
+		// after it.
+		c.dropped.Add(1)
+		slog.ErrorContext(ctx, "dropping malformed queued operation", slog.Any("err", err))
+		return nil
+	}
+	if err != nil {
+		c.failures.Add(1)
+		slog.WarnContext(ctx, "couldn't apply queued operation", slog.String("op", o.Kind), slog.String("tag", o.Tag), slog.Any("err", err))
+		return err
+	}
+	c.applied.Add(1)
+	return nil
+}
@@ -0,0 +1,106 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// NATS is a transport using a NATS JetStream stream.
+// Operations stay in the stream until the consumer has applied them, so
+// neither publishers nor the consumer need to be running at the same time.
+type NATS struct {
+	nc      *nats.Conn
+	js      jetstream.JetStream
+	stream  string
+	subject string
+}
+
+var _ Transport = (*NATS)(nil)
+
+// DialNATS connects to a NATS server and ensures that a stream exists to hold
+// operations published to subject.
+func DialNATS(ctx context.Context, url, stream, subject string) (*NATS, error) {
+	nc, err := nats.Connect(url, nats.Name("robot"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't connect to NATS: %w", err)
+	}
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("couldn't use JetStream: %w", err)
+	}
+	cfg := jetstream.StreamConfig{
+		Name:     stream,
+		Subjects: []string{subject},
+		Storage:  jetstream.FileStorage,
+		// Work queue retention removes operations once they're applied.
+		Retention: jetstream.WorkQueuePolicy,
+	}
+	if _, err := js.CreateOrUpdateStream(ctx, cfg); err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("couldn't create stream %s: %w", stream, err)
+	}
+	return &NATS{nc: nc, js: js, stream: stream, subject: subject}, nil
+}
+
+// Close closes the connection to the NATS server.
+func (n *NATS) Close() error {
+	return n.nc.Drain()
+}
+
+// Publish publishes an operation and waits for the server to store it.
+func (n *NATS) Publish(ctx context.Context, data []byte) error {
+	if _, err := n.js.Publish(ctx, n.subject, data); err != nil {
+		return fmt.Errorf("couldn't publish to %s: %w", n.subject, err)
+	}
+	return nil
+}
+
+// Consume calls f with each operation in the stream until ctx is canceled.
+// Operations for which f fails are redelivered after a delay.
+func (n *NATS) Consume(ctx context.Context, f func(ctx context.Context, data []byte) error) error {
+	cfg := jetstream.ConsumerConfig{
+		Durable:   "learner",
+		AckPolicy: jetstream.AckExplicitPolicy,
+		// Applying one at a time keeps learning and forgetting in order.
+		MaxAckPending: 1,
+	}
+	c, err := n.js.CreateOrUpdateConsumer(ctx, n.stream, cfg)
+	if err != nil {
+		return fmt.Errorf("couldn't create consumer on %s: %w", n.stream, err)
+	}
+	it, err := c.Messages()
+	if err != nil {
+		return fmt.Errorf("couldn't consume from %s: %w", n.stream, err)
+	}
+	stop := context.AfterFunc(ctx, it.Stop)
+	defer stop()
+	for {
+		msg, err := it.Next()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, jetstream.ErrMsgIteratorClosed) {
+				return err
+			}
+			// Anything else is transient, e.g. a missed heartbeat while
+			// reconnecting.
+			continue
+		}
+		if err := f(ctx, msg.Data()); err != nil {
+			msg.NakWithDelay(5 * time.Second)
+			continue
+		}
+		if err := msg.Ack(); err != nil {
+			// The operation will be redelivered and applied again. Learning
+			// and forgetting are both idempotent, so that's harmless.
+			continue
+		}
+	}
+}
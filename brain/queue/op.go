@@ -0,0 +1,99 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// Kinds of operations.
+const (
+	opLearn         = "learn"
+	opForgetMessage = "forget_message"
+	opForgetDuring  = "forget_during"
+	opForgetUser    = "forget_user"
+)
+
+// errMalformed is the error for operations which can never be applied.
+var errMalformed = errors.New("malformed operation")
+
+// op is an operation as sent through a queue.
+type op struct {
+	Kind string `json:"op"`
+	// Op is the ID of the operation to which forgetting is attributed.
+	Op     string  `json:"op_id,omitempty"`
+	Tag    string  `json:"tag,omitempty"`
+	ID     string  `json:"id,omitempty"`
+	User   []byte  `json:"user,omitempty"`
+	Time   int64   `json:"time,omitempty"`
+	Since  int64   `json:"since,omitempty"`
+	Before int64   `json:"before,omitempty"`
+	Tuples []tuple `json:"tuples,omitempty"`
+}
+
+// tuple is a tuple as sent through a queue.
+type tuple struct {
+	Prefix []string `json:"p"`
+	Suffix string   `json:"s"`
+}
+
+func wireTuples(tuples []brain.Tuple) []tuple {
+	r := make([]tuple, len(tuples))
+	for i, t := range tuples {
+		r[i] = tuple{Prefix: t.Prefix, Suffix: t.Suffix}
+	}
+	return r
+}
+
+func (o *op) encode() ([]byte, error) {
+	b, err := json.Marshal(o)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't encode %s operation: %w", o.Kind, err)
+	}
+	return b, nil
+}
+
+// apply performs an operation on a brain.
+func (o *op) apply(ctx context.Context, br brain.Brain) error {
+	if o.Op != "" {
+		ctx = brain.WithOp(ctx, o.Op)
+	}
+	switch o.Kind {
+	case opLearn:
+		user, err := o.user()
+		if err != nil {
+			return err
+		}
+		tuples := make([]brain.Tuple, len(o.Tuples))
+		for i, t := range o.Tuples {
+			tuples[i] = brain.Tuple{Prefix: t.Prefix, Suffix: t.Suffix}
+		}
+		return br.Learn(ctx, o.Tag, o.ID, user, time.Unix(0, o.Time), tuples)
+	case opForgetMessage:
+		return br.ForgetMessage(ctx, o.Tag, o.ID)
+	case opForgetDuring:
+		return br.ForgetDuring(ctx, o.Tag, time.Unix(0, o.Since), time.Unix(0, o.Before))
+	case opForgetUser:
+		user, err := o.user()
+		if err != nil {
+			return err
+		}
+		return br.ForgetUser(ctx, &user)
+	default:
+		return fmt.Errorf("%w: unknown operation %q", errMalformed, o.Kind)
+	}
+}
+
+func (o *op) user() (userhash.Hash, error) {
+	var u userhash.Hash
+	if len(o.User) != len(u) {
+		return u, fmt.Errorf("%w: %s operation has %d byte userhash", errMalformed, o.Kind, len(o.User))
+	}
+	copy(u[:], o.User)
+	return u, nil
+}
@@ -0,0 +1,90 @@
+// Package queue decouples learning from storage by sending learned messages
+// and forget requests through a message queue to a separate consumer which
+// writes them to the brain.
+//
+// Any number of chat frontends can publish to the same queue, each speaking
+// directly from the shared brain database, while a single consumer owns
+// writes to it.
+package queue
+
+import (
+	"context"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// Transport carries encoded operations from publishers to a consumer.
+type Transport interface {
+	// Publish sends an encoded operation. It returns once the transport has
+	// accepted responsibility for delivering it.
+	Publish(ctx context.Context, data []byte) error
+	// Consume calls f with each encoded operation, in the order they were
+	// published, until ctx is canceled. If f returns an error, the operation
+	// is delivered again later.
+	Consume(ctx context.Context, f func(ctx context.Context, data []byte) error) error
+}
+
+// Brain is a brain which publishes learning and forgetting to a queue
+// instead of applying them directly. Speaking goes to the wrapped brain, so
+// it reflects what was learned only once the consumer has written it.
+type Brain struct {
+	br brain.Brain
+	t  Transport
+}
+
+var (
+	_ brain.Brain   = (*Brain)(nil)
+	_ brain.Wrapper = (*Brain)(nil)
+)
+
+// New wraps a brain to learn and forget through a queue.
+func New(br brain.Brain, t Transport) *Brain {
+	return &Brain{br: br, t: t}
+}
+
+// Unwrap returns the underlying brain.
+func (b *Brain) Unwrap() brain.Brain {
+	return b.br
+}
+
+// Learn publishes a set of tuples to learn.
+func (b *Brain) Learn(ctx context.Context, tag, id string, user userhash.Hash, t time.Time, tuples []brain.Tuple) error {
+	o := op{Kind: opLearn, Tag: tag, ID: id, User: user[:], Time: t.UnixNano(), Tuples: wireTuples(tuples)}
+	return b.publish(ctx, &o)
+}
+
+// Speak generates a message from the underlying brain.
+func (b *Brain) Speak(ctx context.Context, tag string, prompt []string, w *brain.Builder) error {
+	return b.br.Speak(ctx, tag, prompt, w)
+}
+
+// ForgetMessage publishes a request to forget a single message.
+func (b *Brain) ForgetMessage(ctx context.Context, tag, id string) error {
+	o := op{Kind: opForgetMessage, Tag: tag, ID: id}
+	return b.publish(ctx, &o)
+}
+
+// ForgetDuring publishes a request to forget messages in a time span.
+func (b *Brain) ForgetDuring(ctx context.Context, tag string, since, before time.Time) error {
+	o := op{Kind: opForgetDuring, Tag: tag, Since: since.UnixNano(), Before: before.UnixNano()}
+	return b.publish(ctx, &o)
+}
+
+// ForgetUser publishes a request to forget all messages from a user.
+func (b *Brain) ForgetUser(ctx context.Context, user *userhash.Hash) error {
+	o := op{Kind: opForgetUser, User: user[:]}
+	return b.publish(ctx, &o)
+}
+
+func (b *Brain) publish(ctx context.Context, o *op) error {
+	// Carry the operation ID so that the consumer attributes forgetting the
+	// same way we would have.
+	o.Op = brain.Op(ctx)
+	data, err := o.encode()
+	if err != nil {
+		return err
+	}
+	return b.t.Publish(ctx, data)
+}
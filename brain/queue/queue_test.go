@@ -0,0 +1,134 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/membrain"
+	"github.com/zephyrtronium/robot/brain/queue"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// pipe is an in-memory transport.
+type pipe struct {
+	c chan []byte
+}
+
+func (p *pipe) Publish(ctx context.Context, data []byte) error {
+	p.c <- data
+	return nil
+}
+
+func (p *pipe) Consume(ctx context.Context, f func(ctx context.Context, data []byte) error) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case data := <-p.c:
+			for f(ctx, data) != nil {
+				// Redeliver until it works.
+			}
+		}
+	}
+}
+
+// flaky is a brain which fails to learn a fixed number of times.
+type flaky struct {
+	*membrain.Brain
+	fails int
+}
+
+func (f *flaky) Learn(ctx context.Context, tag, id string, user userhash.Hash, t time.Time, tuples []brain.Tuple) error {
+	if f.fails > 0 {
+		f.fails--
+		return errors.New("flaky")
+	}
+	return f.Brain.Learn(ctx, tag, id, user, t, tuples)
+}
+
+func wait(t *testing.T, c *queue.Consumer, n int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for c.Stats().Applied+c.Stats().Dropped < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("operations not applied: %+v", c.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestQueue(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := &pipe{c: make(chan []byte, 16)}
+	store := &flaky{Brain: membrain.New(), fails: 2}
+	br := queue.New(store, p)
+	c := queue.NewConsumer(store, p)
+	go c.Run(ctx)
+
+	learn := func(id, text string, user userhash.Hash) {
+		t.Helper()
+		if err := brain.Learn(ctx, br, "kessoku", id, user, time.Unix(1, 0), brain.Tokens(nil, text)); err != nil {
+			t.Fatalf("couldn't learn %q: %v", text, err)
+		}
+	}
+	learn("1", "bocchi plays guitar", userhash.Hash{1})
+	learn("2", "nijika plays drums", userhash.Hash{2})
+	learn("3", "ryo plays bass", userhash.Hash{3})
+	// Publishing doesn't write to the brain directly.
+	wait(t, c, 3)
+	if st := c.Stats(); st.Failures != 2 {
+		t.Errorf("wrong failure count: %+v", st)
+	}
+	for id, want := range map[string]string{"1": "bocchi plays guitar", "2": "nijika plays drums", "3": "ryo plays bass"} {
+		s, err := store.Recall(ctx, "kessoku", id)
+		if err != nil {
+			t.Fatalf("couldn't recall %s: %v", id, err)
+		}
+		if s != want {
+			t.Errorf("wrong message %s: want %q, got %q", id, want, s)
+		}
+	}
+	// Speaking goes directly to the brain.
+	s, _, err := brain.Speak(ctx, br, "kessoku", "bocchi")
+	if err != nil {
+		t.Fatalf("couldn't speak: %v", err)
+	}
+	if s != "bocchi plays guitar" {
+		t.Errorf("wrong speech: %q", s)
+	}
+
+	if err := br.ForgetMessage(ctx, "kessoku", "1"); err != nil {
+		t.Fatalf("couldn't forget message: %v", err)
+	}
+	if err := br.ForgetUser(ctx, &userhash.Hash{2}); err != nil {
+		t.Fatalf("couldn't forget user: %v", err)
+	}
+	if err := br.ForgetDuring(ctx, "kessoku", time.Unix(0, 0), time.Unix(2, 0)); err != nil {
+		t.Fatalf("couldn't forget during: %v", err)
+	}
+	wait(t, c, 6)
+	for _, id := range []string{"1", "2", "3"} {
+		if s, _ := store.Recall(ctx, "kessoku", id); s != "" {
+			t.Errorf("message %s not forgotten: %q", id, s)
+		}
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := &pipe{c: make(chan []byte, 16)}
+	c := queue.NewConsumer(membrain.New(), p)
+	go c.Run(ctx)
+	p.c <- []byte(`{"op":"learn","tag":"kessoku","id":"1","user":"AAAA"}`)
+	p.c <- []byte(`{"op":"remember"}`)
+	p.c <- []byte(`not json`)
+	wait(t, c, 3)
+	if st := c.Stats(); st.Dropped != 3 || st.Applied != 0 {
+		t.Errorf("wrong stats: %+v", st)
+	}
+}
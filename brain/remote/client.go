@@ -0,0 +1,161 @@
+package remote
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/fault"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// Brain is a brain served by a remote [Server].
+type Brain struct {
+	cc *grpc.ClientConn
+}
+
+var _ brain.Brain = (*Brain)(nil)
+
+// DialConfig configures the connection to a remote brain.
+type DialConfig struct {
+	// Token is presented with every request if it is not empty.
+	Token string
+	// TLS configures TLS for the connection. If it is nil, the connection is
+	// unencrypted.
+	TLS *tls.Config
+	// Insecure allows presenting the token over an unencrypted connection to
+	// a target that isn't on the loopback interface.
+	Insecure bool
+}
+
+// Dial creates a brain which speaks to the server at target, in gRPC name
+// syntax. Without TLS, the connection is unencrypted, so it belongs on a
+// private network, and Dial refuses to present a token over it unless the
+// target is loopback or cfg.Insecure is set.
+// Connecting happens lazily, so Dial only fails if target is malformed or the
+// token would be exposed.
+func Dial(target string, cfg DialConfig, opts ...grpc.DialOption) (*Brain, error) {
+	creds := insecure.NewCredentials()
+	if cfg.TLS != nil {
+		creds = credentials.NewTLS(cfg.TLS)
+	}
+	if cfg.Token != "" && cfg.TLS == nil && !cfg.Insecure && !loopback(target) {
+		return nil, fmt.Errorf("refusing to send token to brain at %s without TLS", target)
+	}
+	opts = append([]grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(codec{})),
+	}, opts...)
+	if cfg.Token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(bearer(cfg.Token)))
+	}
+	cc, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create client for brain at %s: %w", target, err)
+	}
+	return &Brain{cc: cc}, nil
+}
+
+// loopback reports whether a gRPC target names a Unix socket or a host on the
+// loopback interface.
+func loopback(target string) bool {
+	if strings.HasPrefix(target, "unix:") || strings.HasPrefix(target, "unix-abstract:") {
+		return true
+	}
+	// Strip the scheme and authority from URL-style targets like
+	// dns:///localhost:50051.
+	if _, rest, ok := strings.Cut(target, "://"); ok {
+		_, target, _ = strings.Cut(rest, "/")
+	}
+	host, _, err := net.SplitHostPort(target)
+	if err != nil {
+		host = target
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Close closes the connection to the server.
+func (b *Brain) Close() error {
+	return b.cc.Close()
+}
+
+// Learn records a set of tuples.
+func (b *Brain) Learn(ctx context.Context, tag, id string, user userhash.Hash, t time.Time, tuples []brain.Tuple) error {
+	req := learnRequest{tag: tag, id: id, user: user[:], time: t.UnixNano(), tuples: make([]tuple, len(tuples))}
+	for i, v := range tuples {
+		req.tuples[i] = tuple{prefix: v.Prefix, suffix: v.Suffix}
+	}
+	return b.invoke(ctx, "Learn", &req, new(empty))
+}
+
+// Speak generates a full message and appends it to w.
+func (b *Brain) Speak(ctx context.Context, tag string, prompt []string, w *brain.Builder) error {
+	var r speakResponse
+	if err := b.invoke(ctx, "Speak", &speakRequest{tag: tag, prompt: prompt}, &r); err != nil {
+		return err
+	}
+	for _, t := range r.terms {
+		w.Append(t.id, t.text)
+	}
+	return nil
+}
+
+// ForgetMessage forgets everything learned from a single given message.
+func (b *Brain) ForgetMessage(ctx context.Context, tag, id string) error {
+	return b.invoke(ctx, "ForgetMessage", &forgetMessageRequest{tag: tag, id: id}, new(empty))
+}
+
+// ForgetDuring forgets all messages learned in the given time span.
+func (b *Brain) ForgetDuring(ctx context.Context, tag string, since, before time.Time) error {
+	req := forgetDuringRequest{tag: tag, since: since.UnixNano(), before: before.UnixNano()}
+	return b.invoke(ctx, "ForgetDuring", &req, new(empty))
+}
+
+// ForgetUser forgets all messages associated with a userhash.
+func (b *Brain) ForgetUser(ctx context.Context, user *userhash.Hash) error {
+	return b.invoke(ctx, "ForgetUser", &forgetUserRequest{user: user[:]}, new(empty))
+}
+
+func (b *Brain) invoke(ctx context.Context, method string, req, resp message) error {
+	if op := brain.Op(ctx); op != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, opHeader, op)
+	}
+	err := b.cc.Invoke(ctx, "/"+service+"/"+method, req, resp)
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if status.Code(err) == codes.Unavailable {
+		// Either the server's brain is unavailable or the server is.
+		return fmt.Errorf("%w: %w", fault.ErrBrainUnavailable, err)
+	}
+	return fmt.Errorf("remote %s failed: %w", method, err)
+}
+
+// bearer is a token presented with each request.
+type bearer string
+
+func (t bearer) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (t bearer) RequireTransportSecurity() bool {
+	return false
+}
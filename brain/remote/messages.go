@@ -0,0 +1,324 @@
+package remote
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// message is a protobuf message of the brain service. The messages are
+// encoded by hand following brain.proto.
+type message interface {
+	marshal(b []byte) []byte
+	unmarshal(b []byte) error
+}
+
+type empty struct{}
+
+func (*empty) marshal(b []byte) []byte { return b }
+
+func (*empty) unmarshal(b []byte) error {
+	return fields(b, func(protowire.Number, protowire.Type, []byte) (int, error) { return -1, nil })
+}
+
+type tuple struct {
+	prefix []string
+	suffix string
+}
+
+func (m *tuple) marshal(b []byte) []byte {
+	for _, p := range m.prefix {
+		b = appendElem(b, 1, p)
+	}
+	return appendString(b, 2, m.suffix)
+}
+
+func (m *tuple) unmarshal(b []byte) error {
+	return fields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			m.prefix = append(m.prefix, v)
+			return n, nil
+		case num == 2 && typ == protowire.BytesType:
+			var n int
+			m.suffix, n = protowire.ConsumeString(b)
+			return n, nil
+		}
+		return -1, nil
+	})
+}
+
+type learnRequest struct {
+	tag, id string
+	user    []byte
+	time    int64
+	tuples  []tuple
+}
+
+func (m *learnRequest) marshal(b []byte) []byte {
+	b = appendString(b, 1, m.tag)
+	b = appendString(b, 2, m.id)
+	b = appendBytes(b, 3, m.user)
+	b = appendInt(b, 4, m.time)
+	for i := range m.tuples {
+		b = appendMessage(b, 5, &m.tuples[i])
+	}
+	return b
+}
+
+func (m *learnRequest) unmarshal(b []byte) error {
+	return fields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			var n int
+			m.tag, n = protowire.ConsumeString(b)
+			return n, nil
+		case num == 2 && typ == protowire.BytesType:
+			var n int
+			m.id, n = protowire.ConsumeString(b)
+			return n, nil
+		case num == 3 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			m.user = append(m.user[:0], v...)
+			return n, nil
+		case num == 4 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			m.time = int64(v)
+			return n, nil
+		case num == 5 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			var t tuple
+			if err := t.unmarshal(v); err != nil {
+				return 0, err
+			}
+			m.tuples = append(m.tuples, t)
+			return n, nil
+		}
+		return -1, nil
+	})
+}
+
+type speakRequest struct {
+	tag    string
+	prompt []string
+}
+
+func (m *speakRequest) marshal(b []byte) []byte {
+	b = appendString(b, 1, m.tag)
+	for _, p := range m.prompt {
+		b = appendElem(b, 2, p)
+	}
+	return b
+}
+
+func (m *speakRequest) unmarshal(b []byte) error {
+	return fields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			var n int
+			m.tag, n = protowire.ConsumeString(b)
+			return n, nil
+		case num == 2 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			m.prompt = append(m.prompt, v)
+			return n, nil
+		}
+		return -1, nil
+	})
+}
+
+type term struct {
+	id   string
+	text []byte
+}
+
+func (m *term) marshal(b []byte) []byte {
+	b = appendString(b, 1, m.id)
+	return appendBytes(b, 2, m.text)
+}
+
+func (m *term) unmarshal(b []byte) error {
+	return fields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			var n int
+			m.id, n = protowire.ConsumeString(b)
+			return n, nil
+		case num == 2 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			m.text = append(m.text[:0], v...)
+			return n, nil
+		}
+		return -1, nil
+	})
+}
+
+type speakResponse struct {
+	terms []term
+}
+
+func (m *speakResponse) marshal(b []byte) []byte {
+	for i := range m.terms {
+		b = appendMessage(b, 1, &m.terms[i])
+	}
+	return b
+}
+
+func (m *speakResponse) unmarshal(b []byte) error {
+	return fields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			var t term
+			if err := t.unmarshal(v); err != nil {
+				return 0, err
+			}
+			m.terms = append(m.terms, t)
+			return n, nil
+		}
+		return -1, nil
+	})
+}
+
+type forgetMessageRequest struct {
+	tag, id string
+}
+
+func (m *forgetMessageRequest) marshal(b []byte) []byte {
+	b = appendString(b, 1, m.tag)
+	return appendString(b, 2, m.id)
+}
+
+func (m *forgetMessageRequest) unmarshal(b []byte) error {
+	return fields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			var n int
+			m.tag, n = protowire.ConsumeString(b)
+			return n, nil
+		case num == 2 && typ == protowire.BytesType:
+			var n int
+			m.id, n = protowire.ConsumeString(b)
+			return n, nil
+		}
+		return -1, nil
+	})
+}
+
+type forgetDuringRequest struct {
+	tag           string
+	since, before int64
+}
+
+func (m *forgetDuringRequest) marshal(b []byte) []byte {
+	b = appendString(b, 1, m.tag)
+	b = appendInt(b, 2, m.since)
+	return appendInt(b, 3, m.before)
+}
+
+func (m *forgetDuringRequest) unmarshal(b []byte) error {
+	return fields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			var n int
+			m.tag, n = protowire.ConsumeString(b)
+			return n, nil
+		case num == 2 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			m.since = int64(v)
+			return n, nil
+		case num == 3 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			m.before = int64(v)
+			return n, nil
+		}
+		return -1, nil
+	})
+}
+
+type forgetUserRequest struct {
+	user []byte
+}
+
+func (m *forgetUserRequest) marshal(b []byte) []byte {
+	return appendBytes(b, 1, m.user)
+}
+
+func (m *forgetUserRequest) unmarshal(b []byte) error {
+	return fields(b, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		if num == 1 && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			m.user = append(m.user[:0], v...)
+			return n, nil
+		}
+		return -1, nil
+	})
+}
+
+// fields calls f with each field in an encoded message. f returns the length
+// of the field's value it consumed, negative for a protowire error, or -1 if
+// it doesn't know the field, in which case the field is skipped.
+func fields(b []byte, f func(num protowire.Number, typ protowire.Type, b []byte) (int, error)) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return fmt.Errorf("malformed message: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+		n, err := f(num, typ, b)
+		if err != nil {
+			return err
+		}
+		if n == -1 {
+			// Unknown field. Skip it for compatibility with newer peers.
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return fmt.Errorf("malformed field %d: %w", num, protowire.ParseError(n))
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+// appendElem appends an element of a repeated string field. Unlike singular
+// fields, elements are encoded even when empty.
+func appendElem(b []byte, num protowire.Number, v string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendInt(b []byte, num protowire.Number, v int64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(v))
+}
+
+func appendMessage(b []byte, num protowire.Number, m message) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m.marshal(nil))
+}
@@ -0,0 +1,80 @@
+// Package remote serves a brain over gRPC and provides a brain which speaks
+// to one, so that several bots can share a single brain service.
+//
+// The service is described by brain.proto. Messages are encoded by hand
+// rather than generated, so the package needs no protobuf toolchain.
+package remote
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+)
+
+// service is the full name of the brain service.
+const service = "robot.brain.v1.Brain"
+
+// opHeader is the request metadata key carrying operation IDs.
+const opHeader = "robot-op"
+
+// codec encodes the service's messages.
+type codec struct{}
+
+func (codec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(message)
+	if !ok {
+		return nil, fmt.Errorf("remote: can't encode %T", v)
+	}
+	return m.marshal(nil), nil
+}
+
+func (codec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(message)
+	if !ok {
+		return fmt.Errorf("remote: can't decode %T", v)
+	}
+	return m.unmarshal(data)
+}
+
+// Name returns the name of the standard protobuf codec, since the encoding
+// is the same.
+func (codec) Name() string {
+	return "proto"
+}
+
+// handler creates the gRPC handler for a unary method.
+func handler[Req any, PReq interface {
+	*Req
+	message
+}, Resp message](method string, f func(s *Server, ctx context.Context, req PReq) (Resp, error)) grpc.MethodDesc {
+	h := func(srv any, ctx context.Context, dec func(any) error, intercept grpc.UnaryServerInterceptor) (any, error) {
+		req := PReq(new(Req))
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		s := srv.(*Server)
+		if intercept == nil {
+			return f(s, ctx, req)
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + service + "/" + method}
+		return intercept(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+			return f(s, ctx, req.(PReq))
+		})
+	}
+	return grpc.MethodDesc{MethodName: method, Handler: h}
+}
+
+// serviceDesc describes the brain service to gRPC.
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: service,
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{
+		handler("Learn", (*Server).learn),
+		handler("Speak", (*Server).speak),
+		handler("ForgetMessage", (*Server).forgetMessage),
+		handler("ForgetDuring", (*Server).forgetDuring),
+		handler("ForgetUser", (*Server).forgetUser),
+	},
+	Metadata: "brain.proto",
+}
@@ -0,0 +1,172 @@
+package remote_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/braintest"
+	"github.com/zephyrtronium/robot/brain/membrain"
+	"github.com/zephyrtronium/robot/brain/remote"
+	"github.com/zephyrtronium/robot/fault"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// serve serves br and dials it, both over an in-memory connection.
+func serve(t *testing.T, br brain.Brain, token, use string) *remote.Brain {
+	t.Helper()
+	return serveWith(t, br, token, remote.DialConfig{Token: use, Insecure: true})
+}
+
+// serveWith serves br with opts and dials it with cfg, both over an in-memory
+// connection.
+func serveWith(t *testing.T, br brain.Brain, token string, cfg remote.DialConfig, opts ...grpc.ServerOption) *remote.Brain {
+	t.Helper()
+	l := bufconn.Listen(1 << 20)
+	s := remote.NewServer(br, token, opts...)
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+	dial := func(ctx context.Context, _ string) (net.Conn, error) { return l.DialContext(ctx) }
+	c, err := remote.Dial("passthrough:///bufconn", cfg, grpc.WithContextDialer(dial))
+	if err != nil {
+		t.Fatalf("couldn't dial: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestIntegrated(t *testing.T) {
+	braintest.Test(context.Background(), t, func(ctx context.Context) brain.Brain {
+		return serve(t, membrain.New(), "", "")
+	})
+}
+
+// oprec records the operation IDs attached to forgetting.
+type oprec struct {
+	*membrain.Brain
+	ops []string
+}
+
+func (o *oprec) ForgetMessage(ctx context.Context, tag, id string) error {
+	o.ops = append(o.ops, brain.Op(ctx))
+	return o.Brain.ForgetMessage(ctx, tag, id)
+}
+
+func TestOp(t *testing.T) {
+	ctx := context.Background()
+	rec := &oprec{Brain: membrain.New()}
+	br := serve(t, rec, "", "")
+	if err := br.ForgetMessage(brain.WithOp(ctx, "bocchi"), "kessoku", "1"); err != nil {
+		t.Fatalf("couldn't forget: %v", err)
+	}
+	if err := br.ForgetMessage(ctx, "kessoku", "2"); err != nil {
+		t.Fatalf("couldn't forget: %v", err)
+	}
+	if len(rec.ops) != 2 || rec.ops[0] != "bocchi" || rec.ops[1] != "" {
+		t.Errorf("wrong operation IDs: %q", rec.ops)
+	}
+}
+
+func TestToken(t *testing.T) {
+	ctx := context.Background()
+	ok := serve(t, membrain.New(), "kita", "kita")
+	if err := brain.Learn(ctx, ok, "kessoku", "1", userhash.Hash{1}, time.Now(), brain.Tokens(nil, "bocchi")); err != nil {
+		t.Errorf("couldn't learn with token: %v", err)
+	}
+	bad := serve(t, membrain.New(), "kita", "ikuyo")
+	if err := brain.Learn(ctx, bad, "kessoku", "1", userhash.Hash{1}, time.Now(), brain.Tokens(nil, "bocchi")); err == nil {
+		t.Errorf("learned with wrong token")
+	}
+}
+
+// down is a brain which is always unavailable.
+type down struct {
+	*membrain.Brain
+}
+
+func (down) Speak(ctx context.Context, tag string, prompt []string, w *brain.Builder) error {
+	return fault.ErrBrainUnavailable
+}
+
+func TestUnavailable(t *testing.T) {
+	br := serve(t, down{membrain.New()}, "", "")
+	_, _, err := brain.Speak(context.Background(), br, "kessoku", "")
+	if !errors.Is(err, fault.ErrBrainUnavailable) {
+		t.Errorf("wrong error: %v", err)
+	}
+}
+
+func TestTLS(t *testing.T) {
+	ctx := context.Background()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{"bufconn"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	creds := credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}})
+	br := serveWith(t, membrain.New(), "kita", remote.DialConfig{Token: "kita", TLS: &tls.Config{RootCAs: roots}}, grpc.Creds(creds))
+	if err := brain.Learn(ctx, br, "kessoku", "1", userhash.Hash{1}, time.Now(), brain.Tokens(nil, "bocchi")); err != nil {
+		t.Errorf("couldn't learn over TLS: %v", err)
+	}
+	untrusted := serveWith(t, membrain.New(), "kita", remote.DialConfig{Token: "kita", TLS: &tls.Config{}}, grpc.Creds(creds))
+	if err := brain.Learn(ctx, untrusted, "kessoku", "1", userhash.Hash{1}, time.Now(), brain.Tokens(nil, "bocchi")); err == nil {
+		t.Errorf("learned from untrusted server")
+	}
+}
+
+func TestDialInsecureToken(t *testing.T) {
+	cases := []struct {
+		name   string
+		target string
+		cfg    remote.DialConfig
+		ok     bool
+	}{
+		{"no-token", "dns:///brain.internal:50051", remote.DialConfig{}, true},
+		{"remote", "dns:///brain.internal:50051", remote.DialConfig{Token: "kita"}, false},
+		{"override", "dns:///brain.internal:50051", remote.DialConfig{Token: "kita", Insecure: true}, true},
+		{"tls", "dns:///brain.internal:50051", remote.DialConfig{Token: "kita", TLS: &tls.Config{}}, true},
+		{"localhost", "dns:///localhost:50051", remote.DialConfig{Token: "kita"}, true},
+		{"ipv4", "127.0.0.1:50051", remote.DialConfig{Token: "kita"}, true},
+		{"ipv6", "[::1]:50051", remote.DialConfig{Token: "kita"}, true},
+		{"unix", "unix:///run/robot/brain.sock", remote.DialConfig{Token: "kita"}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			br, err := remote.Dial(c.target, c.cfg)
+			if (err == nil) != c.ok {
+				t.Errorf("wrong result: want ok %t, got error %v", c.ok, err)
+			}
+			if br != nil {
+				br.Close()
+			}
+		})
+	}
+}
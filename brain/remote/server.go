@@ -0,0 +1,116 @@
+package remote
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/fault"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// Server serves a brain.
+type Server struct {
+	br    brain.Brain
+	token string
+}
+
+// NewServer creates a gRPC server serving br. If token is not empty, clients
+// must present it to make any request. opts can add options such as TLS
+// credentials with [grpc.Creds].
+func NewServer(br brain.Brain, token string, opts ...grpc.ServerOption) *grpc.Server {
+	s := &Server{br: br, token: token}
+	opts = append([]grpc.ServerOption{grpc.ForceServerCodec(codec{}), grpc.UnaryInterceptor(s.authorize)}, opts...)
+	g := grpc.NewServer(opts...)
+	g.RegisterService(&serviceDesc, s)
+	return g
+}
+
+// authorize checks the token of each request and attaches its operation ID.
+func (s *Server) authorize(ctx context.Context, req any, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if s.token != "" {
+		var got string
+		if v := md.Get("authorization"); len(v) != 0 {
+			got = v[0]
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte("Bearer "+s.token)) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "bad token")
+		}
+	}
+	if v := md.Get(opHeader); len(v) != 0 {
+		ctx = brain.WithOp(ctx, v[0])
+	}
+	return next(ctx, req)
+}
+
+func (s *Server) learn(ctx context.Context, req *learnRequest) (*empty, error) {
+	user, err := hash(req.user)
+	if err != nil {
+		return nil, err
+	}
+	tuples := make([]brain.Tuple, len(req.tuples))
+	for i, t := range req.tuples {
+		tuples[i] = brain.Tuple{Prefix: t.prefix, Suffix: t.suffix}
+	}
+	return new(empty), code(s.br.Learn(ctx, req.tag, req.id, user, time.Unix(0, req.time), tuples))
+}
+
+func (s *Server) speak(ctx context.Context, req *speakRequest) (*speakResponse, error) {
+	var r speakResponse
+	w := brain.NewRelayBuilder(func(id string, b []byte) {
+		r.terms = append(r.terms, term{id: id, text: append([]byte(nil), b...)})
+	})
+	if err := s.br.Speak(ctx, req.tag, req.prompt, w); err != nil {
+		return nil, code(err)
+	}
+	return &r, nil
+}
+
+func (s *Server) forgetMessage(ctx context.Context, req *forgetMessageRequest) (*empty, error) {
+	return new(empty), code(s.br.ForgetMessage(ctx, req.tag, req.id))
+}
+
+func (s *Server) forgetDuring(ctx context.Context, req *forgetDuringRequest) (*empty, error) {
+	return new(empty), code(s.br.ForgetDuring(ctx, req.tag, time.Unix(0, req.since), time.Unix(0, req.before)))
+}
+
+func (s *Server) forgetUser(ctx context.Context, req *forgetUserRequest) (*empty, error) {
+	user, err := hash(req.user)
+	if err != nil {
+		return nil, err
+	}
+	return new(empty), code(s.br.ForgetUser(ctx, &user))
+}
+
+func hash(b []byte) (userhash.Hash, error) {
+	var u userhash.Hash
+	if len(b) != len(u) {
+		return u, status.Errorf(codes.InvalidArgument, "userhash has %d bytes, not %d", len(b), len(u))
+	}
+	copy(u[:], b)
+	return u, nil
+}
+
+// code converts a brain error to a gRPC status, so that clients can tell
+// which failures are worth retrying.
+func code(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	case errors.Is(err, fault.ErrBrainUnavailable):
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		return status.Error(codes.Internal, fmt.Sprint(err))
+	}
+}
@@ -48,3 +48,38 @@ func Speak(ctx context.Context, s Speaker, tag, prompt string) (string, []string
 	}
 	return strings.TrimSpace(w.String()), slices.Clone(w.Trace()), nil
 }
+
+// SpeakFunc is like [Speak], but it calls f with each generated term as soon
+// as the speaker produces it, so that callers can begin using a long message
+// before generation completes. Terms passed to f do not include the prompt.
+// If f returns an error, generation is canceled and SpeakFunc returns that
+// error along with the terms generated so far.
+func SpeakFunc(ctx context.Context, s Speaker, tag, prompt string, f func(term string) error) (string, []string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	w := builderPool.Get()
+	toks := Tokens(tokensPool.Get(), prompt)
+	defer func() {
+		w.Reset()
+		builderPool.Put(w)
+		tokensPool.Put(toks[:0])
+	}()
+	w.grow(len(prompt) + 1)
+	for i, t := range toks {
+		w.prompt(t)
+		toks[i] = ReduceEntropy(t)
+	}
+	slices.Reverse(toks)
+	w.emit, w.stop = f, cancel
+	err := s.Speak(ctx, tag, toks, w)
+	if w.err != nil {
+		return strings.TrimSpace(w.String()), slices.Clone(w.Trace()), w.err
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("couldn't speak: %w", err)
+	}
+	if len(w.Trace()) == 0 {
+		return "", nil, nil
+	}
+	return strings.TrimSpace(w.String()), slices.Clone(w.Trace()), nil
+}
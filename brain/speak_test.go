@@ -2,6 +2,8 @@ package brain_test
 
 import (
 	"context"
+	"errors"
+	"strconv"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -97,3 +99,82 @@ func TestSpeak(t *testing.T) {
 		})
 	}
 }
+
+// countSpeaker appends numbered terms until it is canceled or reaches n.
+type countSpeaker struct {
+	n int
+}
+
+func (c *countSpeaker) Speak(ctx context.Context, tag string, prompt []string, w *brain.Builder) error {
+	for i := range c.n {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		w.Append(strconv.Itoa(i), []byte(strconv.Itoa(i)+" "))
+	}
+	return nil
+}
+
+func TestSpeakFunc(t *testing.T) {
+	stop := errors.New("stop")
+	cases := []struct {
+		name  string
+		n     int
+		limit int
+		terms []string
+		say   string
+		trace []string
+		err   error
+	}{
+		{
+			name:  "empty",
+			n:     0,
+			limit: 5,
+			terms: nil,
+			say:   "",
+			trace: nil,
+		},
+		{
+			name:  "full",
+			n:     3,
+			limit: 5,
+			terms: []string{"0 ", "1 ", "2 "},
+			say:   "bocchi 0 1 2",
+			trace: []string{"0", "1", "2"},
+		},
+		{
+			name:  "stopped",
+			n:     1000,
+			limit: 2,
+			terms: []string{"0 ", "1 "},
+			say:   "bocchi 0 1",
+			trace: []string{"0", "1"},
+			err:   stop,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var terms []string
+			f := func(term string) error {
+				terms = append(terms, term)
+				if len(terms) >= c.limit {
+					return stop
+				}
+				return nil
+			}
+			r, trace, err := brain.SpeakFunc(context.Background(), &countSpeaker{n: c.n}, "", "bocchi", f)
+			if !errors.Is(err, c.err) {
+				t.Errorf("wrong error: want %v, got %v", c.err, err)
+			}
+			if diff := cmp.Diff(c.terms, terms); diff != "" {
+				t.Errorf("wrong terms:\n%s", diff)
+			}
+			if r != c.say {
+				t.Errorf("wrong result: want %q, got %q", c.say, r)
+			}
+			if diff := cmp.Diff(c.trace, trace, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("wrong trace:\n%s", diff)
+			}
+		})
+	}
+}
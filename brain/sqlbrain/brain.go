@@ -4,14 +4,39 @@ import (
 	"context"
 	_ "embed"
 	"fmt"
+	"regexp"
+	"time"
 
 	"zombiezen.com/go/sqlite"
 	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/zephyrtronium/robot/brain"
+)
+
+var (
+	_ brain.Windower = (*Brain)(nil)
+	_ brain.Batcher  = (*Brain)(nil)
 )
 
 // Brain is an implementation of knowledge using an SQLite database.
 type Brain struct {
 	db *sqlitex.Pool
+	// since and before are the bounds in nanoseconds from the UNIX epoch of
+	// the times of messages from which the brain speaks.
+	// If before is zero, the brain speaks from all messages.
+	since, before int64
+	// tags is a JSON array of the tags from which the brain speaks in place
+	// of the one it is given. If it is empty, the brain speaks from the given
+	// tag.
+	tags string
+	// block matches terms which the brain doesn't speak. It may be nil.
+	block *regexp.Regexp
+	// replica is a read replica of db from which the brain speaks.
+	// It may be nil.
+	replica *sqlitex.Pool
+	// replicaStats counts speaking from the replica. It is nil if and only if
+	// replica is.
+	replicaStats *replicaStats
 }
 
 // Open returns a brain within the given database.
@@ -25,13 +50,21 @@ func Open(ctx context.Context, db *sqlitex.Pool) (*Brain, error) {
 	if err := sqlitex.ExecuteScript(conn, schemaSQL, nil); err != nil {
 		return nil, fmt.Errorf("couldn't run migration: %w", err)
 	}
-	br := Brain{db}
+	br := Brain{db: db}
 	return &br, nil
 }
 
 //go:embed schema.sql
 var schemaSQL string
 
+// During returns a view of the brain which speaks only from messages learned
+// at or after since and before before. Messages without timestamps, such as
+// imported ones, are excluded. Learning and forgetting through the view are
+// the same as through br.
+func (br *Brain) During(since, before time.Time) brain.Brain {
+	return &Brain{db: br.db, since: since.UnixNano(), before: before.UnixNano(), replica: br.replica, replicaStats: br.replicaStats}
+}
+
 // Close closes the underlying database.
 func (br *Brain) Close() error {
 	return br.db.Close()
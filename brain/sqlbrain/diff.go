@@ -0,0 +1,79 @@
+package sqlbrain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/zephyrtronium/robot/brain"
+)
+
+var _ brain.Differ = (*Brain)(nil)
+
+// Diff compares the terms known under a tag now to those used by messages
+// learned before since. Messages without timestamps count as learned before
+// since. Tuples evicted to stay within a quota are removed entirely, so their
+// terms can't be reported.
+func (br *Brain) Diff(ctx context.Context, tag string, since time.Time) (*brain.Diff, error) {
+	conn, err := br.db.Take(ctx)
+	defer br.db.Put(conn)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get connection for diff: %w", err)
+	}
+	const appeared = `
+		SELECT suffix, MIN(messages.time), MAX(messages.time), COUNT(*)
+		FROM knowledge JOIN messages USING (tag, id)
+		WHERE knowledge.tag = :tag AND knowledge.deleted IS NULL AND length(suffix) > 0
+		GROUP BY suffix
+		HAVING suffix NOT IN (
+			SELECT suffix FROM knowledge JOIN messages USING (tag, id)
+			WHERE knowledge.tag = :tag AND (messages.time < :since OR messages.time IS NULL)
+		)
+		ORDER BY MIN(messages.time), suffix
+	`
+	const disappeared = `
+		SELECT suffix, MIN(messages.time), MAX(messages.time), COUNT(*)
+		FROM knowledge JOIN messages USING (tag, id)
+		WHERE knowledge.tag = :tag AND (messages.time < :since OR messages.time IS NULL) AND length(suffix) > 0
+		GROUP BY suffix
+		HAVING suffix NOT IN (
+			SELECT suffix FROM knowledge WHERE tag = :tag AND deleted IS NULL
+		)
+		ORDER BY MAX(messages.time), suffix
+	`
+	var r brain.Diff
+	opts := func(s *[]brain.TermSpan) *sqlitex.ExecOptions {
+		return &sqlitex.ExecOptions{
+			Named: map[string]any{":tag": tag, ":since": since.UnixNano()},
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				*s = append(*s, brain.TermSpan{
+					Term:  strings.TrimSpace(stmt.ColumnText(0)),
+					First: nanotime(stmt, 1),
+					Last:  nanotime(stmt, 2),
+					Uses:  stmt.ColumnInt64(3),
+				})
+				return nil
+			},
+		}
+	}
+	if err := sqlitex.Execute(conn, appeared, opts(&r.Appeared)); err != nil {
+		return nil, fmt.Errorf("couldn't find appeared terms: %w", err)
+	}
+	if err := sqlitex.Execute(conn, disappeared, opts(&r.Disappeared)); err != nil {
+		return nil, fmt.Errorf("couldn't find disappeared terms: %w", err)
+	}
+	return &r, nil
+}
+
+// nanotime gets a time stored as nanoseconds from the UNIX epoch from a result
+// column. It returns the zero time if the column is null.
+func nanotime(stmt *sqlite.Stmt, col int) time.Time {
+	if stmt.ColumnType(col) == sqlite.TypeNull {
+		return time.Time{}
+	}
+	return time.Unix(0, stmt.ColumnInt64(col))
+}
@@ -0,0 +1,59 @@
+package sqlbrain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/sqlbrain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+func TestDiff(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(ctx)
+	br, err := sqlbrain.Open(ctx, db)
+	if err != nil {
+		t.Fatalf("couldn't open brain: %v", err)
+	}
+	msgs := []struct {
+		tag, id, text string
+		t             int64
+	}{
+		{"kessoku", "1", "bocchi rock", 1},
+		{"kessoku", "2", "kita guitar", 2},
+		{"kessoku", "3", "bocchi guitar", 5},
+		{"kessoku", "4", "ryo bass", 6},
+		{"kessoku", "5", "ryo bass", 7},
+		{"sickhack", "6", "kikuri sake", 8},
+	}
+	for _, m := range msgs {
+		err := brain.Learn(ctx, br, m.tag, m.id, userhash.Hash{}, time.Unix(m.t, 0), brain.Tokens(nil, m.text))
+		if err != nil {
+			t.Fatalf("couldn't learn %q: %v", m.text, err)
+		}
+	}
+	if err := br.ForgetMessage(ctx, "kessoku", "2"); err != nil {
+		t.Fatalf("couldn't forget: %v", err)
+	}
+	d, err := br.Diff(ctx, "kessoku", time.Unix(4, 0))
+	if err != nil {
+		t.Fatalf("couldn't diff: %v", err)
+	}
+	want := brain.Diff{
+		Appeared: []brain.TermSpan{
+			// Guitar was used before, even though that message was forgotten.
+			{Term: "bass", First: time.Unix(6, 0), Last: time.Unix(7, 0), Uses: 2},
+			{Term: "ryo", First: time.Unix(6, 0), Last: time.Unix(7, 0), Uses: 2},
+		},
+		Disappeared: []brain.TermSpan{
+			{Term: "kita", First: time.Unix(2, 0), Last: time.Unix(2, 0), Uses: 1},
+		},
+	}
+	if diff := cmp.Diff(want, *d); diff != "" {
+		t.Errorf("wrong diff (-want +got):\n%s", diff)
+	}
+}
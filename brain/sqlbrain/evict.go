@@ -0,0 +1,102 @@
+package sqlbrain
+
+import (
+	"context"
+	"fmt"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/zephyrtronium/robot/brain"
+)
+
+var _ brain.Evicter = (*Brain)(nil)
+
+// Evict deletes the tuples of the oldest messages learned under a tag until
+// the rest are within the quota. Messages without timestamps are evicted
+// first. The messages themselves are kept, marked deleted, so that they can't
+// be learned again.
+func (br *Brain) Evict(ctx context.Context, tag string, quota brain.Quota) (n int, err error) {
+	if quota.Tuples <= 0 && quota.Bytes <= 0 {
+		return 0, nil
+	}
+	conn, err := br.db.Take(ctx)
+	defer br.db.Put(conn)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't get connection to evict: %w", err)
+	}
+	defer sqlitex.Transaction(conn)(&err)
+
+	const usage = `SELECT COUNT(*), COALESCE(SUM(length(prefix) + length(suffix)), 0) FROM knowledge WHERE tag = :tag`
+	var tuples, bytes int64
+	err = sqlitex.Execute(conn, usage, &sqlitex.ExecOptions{
+		Named: map[string]any{":tag": tag},
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			tuples, bytes = stmt.ColumnInt64(0), stmt.ColumnInt64(1)
+			return nil
+		},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("couldn't measure usage: %w", err)
+	}
+	over := func() bool {
+		return quota.Tuples > 0 && tuples > quota.Tuples || quota.Bytes > 0 && bytes > quota.Bytes
+	}
+	if !over() {
+		return 0, nil
+	}
+
+	// Find the oldest messages which free enough space.
+	const oldest = `
+		SELECT messages.id, COUNT(*), SUM(length(prefix) + length(suffix))
+		FROM messages JOIN knowledge USING (tag, id)
+		WHERE messages.tag = :tag
+		GROUP BY messages.id
+		ORDER BY messages.time NULLS FIRST
+	`
+	var ids []string
+	err = sqlitex.Execute(conn, oldest, &sqlitex.ExecOptions{
+		Named: map[string]any{":tag": tag},
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			if !over() {
+				return nil
+			}
+			ids = append(ids, stmt.ColumnText(0))
+			tuples -= stmt.ColumnInt64(1)
+			bytes -= stmt.ColumnInt64(2)
+			return nil
+		},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("couldn't find oldest messages: %w", err)
+	}
+
+	const del = `DELETE FROM knowledge WHERE tag = :tag AND id = :id`
+	const mark = `UPDATE messages SET deleted = 'QUOTA' WHERE tag = :tag AND id = :id AND deleted IS NULL`
+	sd, err := conn.Prepare(del)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't prepare eviction: %w", err)
+	}
+	sm, err := conn.Prepare(mark)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't prepare marking evicted messages: %w", err)
+	}
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		sd.SetText(":tag", tag)
+		sd.SetText(":id", id)
+		if err := allsteps(sd); err != nil {
+			return 0, fmt.Errorf("couldn't evict message %v: %w", id, err)
+		}
+		sd.Reset()
+		sm.SetText(":tag", tag)
+		sm.SetText(":id", id)
+		if err := allsteps(sm); err != nil {
+			return 0, fmt.Errorf("couldn't mark message %v evicted: %w", id, err)
+		}
+		sm.Reset()
+	}
+	return len(ids), nil
+}
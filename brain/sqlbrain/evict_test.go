@@ -0,0 +1,80 @@
+package sqlbrain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/sqlbrain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+func TestEvict(t *testing.T) {
+	ctx := context.Background()
+	br, err := sqlbrain.Open(ctx, testDB(ctx))
+	if err != nil {
+		t.Fatalf("couldn't open brain: %v", err)
+	}
+	learn := []struct {
+		tag, id, msg string
+		t            int64
+	}{
+		{"kessoku", "2", "nijika plays drums", 2},
+		{"kessoku", "1", "bocchi plays guitar", 1},
+		{"kessoku", "3", "ryo plays bass", 3},
+		{"sickhack", "4", "kikuri plays bass", 0},
+	}
+	for _, l := range learn {
+		if err := brain.Learn(ctx, br, l.tag, l.id, userhash.Hash{1}, time.Unix(l.t, 0), brain.Tokens(nil, l.msg)); err != nil {
+			t.Fatalf("couldn't learn %s/%s: %v", l.tag, l.id, err)
+		}
+	}
+	// Within quota does nothing.
+	n, err := br.Evict(ctx, "kessoku", brain.Quota{Tuples: 12})
+	if err != nil {
+		t.Errorf("couldn't evict within quota: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("evicted %d messages within quota", n)
+	}
+	n, err = br.Evict(ctx, "kessoku", brain.Quota{Tuples: 8})
+	if err != nil {
+		t.Errorf("couldn't evict: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("wrong number of messages evicted for tuples: want 1, got %d", n)
+	}
+	if s, _ := br.Recall(ctx, "kessoku", "1"); s != "" {
+		t.Errorf("oldest message still known after eviction: %q", s)
+	}
+	if s, _ := br.Recall(ctx, "kessoku", "2"); s == "" {
+		t.Errorf("newer message evicted")
+	}
+	// The tuples of "ryo plays bass" take 54 bytes as stored.
+	n, err = br.Evict(ctx, "kessoku", brain.Quota{Bytes: 60})
+	if err != nil {
+		t.Errorf("couldn't evict: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("wrong number of messages evicted for bytes: want 1, got %d", n)
+	}
+	st, err := br.Stats(ctx, "kessoku")
+	if err != nil {
+		t.Fatalf("couldn't get stats: %v", err)
+	}
+	if want := (brain.Stats{Tuples: 4, Messages: 1}); *st != want {
+		t.Errorf("wrong stats after eviction: want %+v, got %+v", want, *st)
+	}
+	// Evicted messages aren't learned again.
+	if err := brain.Learn(ctx, br, "kessoku", "1", userhash.Hash{1}, time.Unix(1, 0), brain.Tokens(nil, "bocchi plays guitar")); err != nil {
+		t.Errorf("couldn't relearn: %v", err)
+	}
+	if s, _ := br.Recall(ctx, "kessoku", "1"); s != "" {
+		t.Errorf("evicted message learned again: %q", s)
+	}
+	// Other tags are unaffected.
+	if s, _ := br.Recall(ctx, "sickhack", "4"); s == "" {
+		t.Errorf("message in other tag evicted")
+	}
+}
@@ -0,0 +1,63 @@
+package sqlbrain
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/zephyrtronium/robot/brain"
+)
+
+var _ brain.Exporter = (*Brain)(nil)
+
+// Export calls f with each distinct tuple learned and not forgotten under a
+// tag and the number of times it was learned, in order of prefix and suffix.
+func (br *Brain) Export(ctx context.Context, tag string, f func(brain.Counted) error) error {
+	conn, err := br.db.Take(ctx)
+	defer br.db.Put(conn)
+	if err != nil {
+		return fmt.Errorf("couldn't get connection to export: %w", err)
+	}
+	const sel = `SELECT prefix, suffix, COUNT(*) FROM knowledge
+		WHERE tag = :tag AND deleted IS NULL
+		GROUP BY prefix, suffix
+		ORDER BY prefix, suffix`
+	opts := sqlitex.ExecOptions{
+		Named: map[string]any{":tag": tag},
+		ResultFunc: func(st *sqlite.Stmt) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			p := make([]byte, st.ColumnLen(0))
+			st.ColumnBytes(0, p)
+			c := brain.Counted{
+				Prefix: unprefix(p),
+				Suffix: st.ColumnText(1),
+				Count:  st.ColumnInt64(2),
+			}
+			return f(c)
+		},
+	}
+	if err := sqlitex.Execute(conn, sel, &opts); err != nil {
+		return fmt.Errorf("couldn't export tuples: %w", err)
+	}
+	return nil
+}
+
+// unprefix is the inverse of prefix, given the terminated form stored in the
+// knowledge table.
+func unprefix(b []byte) []string {
+	b = bytes.TrimSuffix(b, []byte{0})
+	if len(b) == 0 {
+		return nil
+	}
+	w := bytes.Split(bytes.TrimSuffix(b, []byte{0}), []byte{0})
+	r := make([]string, len(w))
+	for i, s := range w {
+		r[i] = string(s)
+	}
+	return r
+}
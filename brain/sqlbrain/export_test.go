@@ -0,0 +1,52 @@
+package sqlbrain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/sqlbrain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+	br, err := sqlbrain.Open(ctx, testDB(ctx))
+	if err != nil {
+		t.Fatalf("couldn't open brain: %v", err)
+	}
+	msgs := []struct {
+		id, text string
+	}{
+		{"1", "bocchi rock"},
+		{"2", "bocchi rock"},
+		{"3", "kita"},
+	}
+	for _, m := range msgs {
+		if err := brain.Learn(ctx, br, "kessoku", m.id, userhash.Hash{1}, time.Unix(1, 0), brain.Tokens(nil, m.text)); err != nil {
+			t.Fatalf("couldn't learn %q: %v", m.text, err)
+		}
+	}
+	if err := br.ForgetMessage(ctx, "kessoku", "3"); err != nil {
+		t.Fatalf("couldn't forget: %v", err)
+	}
+	var got []brain.Counted
+	err = br.Export(ctx, "kessoku", func(c brain.Counted) error {
+		got = append(got, c)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("couldn't export: %v", err)
+	}
+	want := []brain.Counted{
+		{Prefix: nil, Suffix: "bocchi ", Count: 2},
+		{Prefix: []string{"bocchi "}, Suffix: "rock ", Count: 2},
+		{Prefix: []string{"rock ", "bocchi "}, Suffix: "", Count: 2},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("wrong export (-want/+got):\n%s", diff)
+	}
+}
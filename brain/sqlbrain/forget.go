@@ -9,9 +9,39 @@ import (
 	"zombiezen.com/go/sqlite"
 	"zombiezen.com/go/sqlite/sqlitex"
 
+	"github.com/zephyrtronium/robot/brain"
 	"github.com/zephyrtronium/robot/userhash"
 )
 
+// prepareDeletion prepares a statement recording that a message was forgotten
+// by the operation attached to ctx, so that the operation can be undone.
+// If ctx has no operation, the statement uses a new one.
+// The caller sets :tag and :id.
+func prepareDeletion(ctx context.Context, conn *sqlite.Conn) (*sqlite.Stmt, error) {
+	const record = `INSERT INTO deletions (op, tag, id, time) VALUES (:op, :tag, :id, :time) ON CONFLICT DO NOTHING`
+	st, err := conn.Prepare(record)
+	if err != nil {
+		return nil, err
+	}
+	op := brain.Op(ctx)
+	if op == "" {
+		op = brain.NewOp()
+	}
+	st.SetText(":op", op)
+	st.SetInt64(":time", time.Now().UnixNano())
+	return st, nil
+}
+
+// recordDeletion runs a statement from prepareDeletion for one message.
+func recordDeletion(st *sqlite.Stmt, tag, id string) error {
+	st.SetText(":tag", tag)
+	st.SetText(":id", id)
+	if err := allsteps(st); err != nil {
+		return err
+	}
+	return st.Reset()
+}
+
 // ForgetMessage forgets everything learned from a single given message.
 // If nothing has been learned from the message, nothing happens.
 func (br *Brain) ForgetMessage(ctx context.Context, tag, id string) (err error) {
@@ -50,6 +80,13 @@ func (br *Brain) ForgetMessage(ctx context.Context, tag, id string) (err error)
 			return fmt.Errorf("couldn't delete tuples of message %v: %w", id, err)
 		}
 	}
+	sd, err := prepareDeletion(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("couldn't prepare deletion record for message %v: %w", id, err)
+	}
+	if err := recordDeletion(sd, tag, id); err != nil {
+		return fmt.Errorf("couldn't record deletion of message %v: %w", id, err)
+	}
 	return nil
 }
 
@@ -76,6 +113,10 @@ func (br *Brain) ForgetDuring(ctx context.Context, tag string, since time.Time,
 		return fmt.Errorf("couldn't prepare delete for tuples in time span: %w", err)
 	}
 	st.SetText(":tag", tag)
+	sd, err := prepareDeletion(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("couldn't prepare deletion records for time span: %w", err)
+	}
 	// Now forget tuples by the IDs.
 	for {
 		ok, err := sm.Step()
@@ -93,6 +134,9 @@ func (br *Brain) ForgetDuring(ctx context.Context, tag string, since time.Time,
 		if err := st.Reset(); err != nil {
 			return fmt.Errorf("couldn't reset delete for tuples in time span: %w", err)
 		}
+		if err := recordDeletion(sd, tag, id); err != nil {
+			return fmt.Errorf("couldn't record deletion in time span: %w", err)
+		}
 	}
 	return nil
 }
@@ -117,6 +161,10 @@ func (br *Brain) ForgetUser(ctx context.Context, user *userhash.Hash) error {
 	if err != nil {
 		return fmt.Errorf("couldn't prepare delete for tuples from user: %w", err)
 	}
+	sd, err := prepareDeletion(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("couldn't prepare deletion records for user: %w", err)
+	}
 	// Now forget by the IDs.
 	for {
 		ok, err := sm.Step()
@@ -136,6 +184,9 @@ func (br *Brain) ForgetUser(ctx context.Context, user *userhash.Hash) error {
 		if err := st.Reset(); err != nil {
 			return fmt.Errorf("couldn't reset delete for tuples from user: %w", err)
 		}
+		if err := recordDeletion(sd, tag, id); err != nil {
+			return fmt.Errorf("couldn't record deletion from user: %w", err)
+		}
 	}
 	return nil
 }
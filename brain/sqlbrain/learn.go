@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"zombiezen.com/go/sqlite"
 	"zombiezen.com/go/sqlite/sqlitex"
 
 	"github.com/zephyrtronium/robot/brain"
@@ -12,6 +13,8 @@ import (
 )
 
 // Learn records a set of tuples.
+// If a message with the same tag and ID has already been learned or forgotten,
+// it does nothing.
 func (br *Brain) Learn(ctx context.Context, tag, id string, user userhash.Hash, t time.Time, tuples []brain.Tuple) (err error) {
 	conn, err := br.db.Take(ctx)
 	defer br.db.Put(conn)
@@ -19,6 +22,47 @@ func (br *Brain) Learn(ctx context.Context, tag, id string, user userhash.Hash,
 		return fmt.Errorf("couldn't get connection to learn: %w", err)
 	}
 	defer sqlitex.Transaction(conn)(&err)
+	return learn(conn, tag, id, user, t, tuples)
+}
+
+// LearnBatch records the tuples of each message in a single transaction.
+// Messages already learned or forgotten are skipped.
+func (br *Brain) LearnBatch(ctx context.Context, msgs []brain.Message) (err error) {
+	conn, err := br.db.Take(ctx)
+	defer br.db.Put(conn)
+	if err != nil {
+		return fmt.Errorf("couldn't get connection to learn: %w", err)
+	}
+	defer sqlitex.Transaction(conn)(&err)
+	for _, m := range msgs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := learn(conn, m.Tag, m.ID, m.User, m.Time, m.Tuples); err != nil {
+			return fmt.Errorf("couldn't learn message %v: %w", m.ID, err)
+		}
+	}
+	return nil
+}
+
+// learn records a set of tuples using conn, which should be in a transaction.
+func learn(conn *sqlite.Conn, tag, id string, user userhash.Hash, t time.Time, tuples []brain.Tuple) error {
+	// Skip messages we've seen before, so that replays of the same message
+	// don't weight its tuples twice. This also keeps messages forgotten
+	// before they arrive from being learned.
+	se, err := conn.Prepare(`SELECT EXISTS(SELECT 1 FROM messages WHERE tag=:tag AND id=:id)`)
+	if err != nil {
+		return fmt.Errorf("couldn't prepare message check: %w", err)
+	}
+	se.SetText(":tag", tag)
+	se.SetText(":id", id)
+	seen, err := sqlitex.ResultBool(se)
+	if err != nil {
+		return fmt.Errorf("couldn't check for message: %w", err)
+	}
+	if seen {
+		return nil
+	}
 
 	st, err := conn.Prepare(`INSERT INTO knowledge(tag, id, prefix, suffix) VALUES (:tag, :id, :prefix, :suffix)`)
 	if err != nil {
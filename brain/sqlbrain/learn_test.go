@@ -390,6 +390,53 @@ func TestLearn(t *testing.T) {
 	}
 }
 
+func TestLearnBatch(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(ctx)
+	br, err := sqlbrain.Open(ctx, db)
+	if err != nil {
+		t.Fatalf("couldn't open brain: %v", err)
+	}
+	msgs := []brain.Message{
+		{Tag: "kessoku", ID: "1", Time: time.Unix(0, 1), Tuples: []brain.Tuple{{Prefix: nil, Suffix: "bocchi"}, {Prefix: []string{"bocchi"}, Suffix: ""}}},
+		{Tag: "kessoku", ID: "2", Time: time.Unix(0, 2), Tuples: []brain.Tuple{{Prefix: nil, Suffix: "ryo"}, {Prefix: []string{"ryo"}, Suffix: ""}}},
+	}
+	if err := br.LearnBatch(ctx, msgs); err != nil {
+		t.Fatalf("couldn't learn batch: %v", err)
+	}
+	// Messages already learned are skipped.
+	again := []brain.Message{
+		{Tag: "kessoku", ID: "3", Time: time.Unix(0, 3), Tuples: []brain.Tuple{{Prefix: nil, Suffix: "kita"}, {Prefix: []string{"kita"}, Suffix: ""}}},
+		msgs[0],
+	}
+	if err := br.LearnBatch(ctx, again); err != nil {
+		t.Errorf("couldn't learn batch with duplicate: %v", err)
+	}
+	conn, err := db.Take(ctx)
+	defer db.Put(conn)
+	if err != nil {
+		t.Fatalf("couldn't get conn to check db state: %v", err)
+	}
+	want := []know{
+		{tag: "kessoku", id: "1", prefix: "\x00", suffix: "bocchi"},
+		{tag: "kessoku", id: "1", prefix: "bocchi\x00\x00", suffix: ""},
+		{tag: "kessoku", id: "2", prefix: "\x00", suffix: "ryo"},
+		{tag: "kessoku", id: "2", prefix: "ryo\x00\x00", suffix: ""},
+	}
+	want = append(want,
+		know{tag: "kessoku", id: "3", prefix: "\x00", suffix: "kita"},
+		know{tag: "kessoku", id: "3", prefix: "kita\x00\x00", suffix: ""},
+	)
+	contents(t, conn, want, []msg{{tag: "kessoku", id: "1", time: 1}, {tag: "kessoku", id: "2", time: 2}, {tag: "kessoku", id: "3", time: 3}})
+	n, err := sqlitex.ResultInt64(conn.Prep(`SELECT COUNT(*) FROM knowledge`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 6 {
+		t.Errorf("wrong number of tuples: want 6, got %d", n)
+	}
+}
+
 func BenchmarkLearn(b *testing.B) {
 	dir := filepath.ToSlash(b.TempDir())
 	new := func(ctx context.Context, b *testing.B) brain.Learner {
@@ -0,0 +1,279 @@
+package sqlbrain
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+var _ brain.Batcher = (*Postgres)(nil)
+
+// Postgres is an implementation of knowledge using a PostgreSQL database.
+// It stores knowledge the same way as [Brain], but many processes may learn
+// into it at once without contending for a database lock.
+//
+// Postgres supports learning, forgetting, and speaking. The other
+// capabilities of [Brain], like time windows, views, and read replicas, are
+// only available with SQLite.
+type Postgres struct {
+	db *sql.DB
+}
+
+// OpenPostgres returns a brain within the given PostgreSQL database.
+// The db must remain open for the lifetime of the brain. The caller is
+// responsible for importing a driver for it.
+func OpenPostgres(ctx context.Context, db *sql.DB) (*Postgres, error) {
+	if _, err := db.ExecContext(ctx, schemaPostgres); err != nil {
+		return nil, fmt.Errorf("couldn't run migration: %w", err)
+	}
+	return &Postgres{db: db}, nil
+}
+
+//go:embed schema_postgres.sql
+var schemaPostgres string
+
+// IsPostgres reports whether a DSN names a PostgreSQL database rather than
+// an SQLite one.
+func IsPostgres(dsn string) bool {
+	return strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+}
+
+// Close closes the underlying database.
+func (br *Postgres) Close() error {
+	return br.db.Close()
+}
+
+// transact runs f in a transaction, committing if it succeeds and rolling
+// back otherwise.
+func (br *Postgres) transact(ctx context.Context, f func(tx *sql.Tx) error) error {
+	tx, err := br.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("couldn't start transaction: %w", err)
+	}
+	if err := f(tx); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	return tx.Commit()
+}
+
+// Learn records a set of tuples.
+// If a message with the same tag and ID has already been learned or forgotten,
+// it does nothing.
+func (br *Postgres) Learn(ctx context.Context, tag, id string, user userhash.Hash, t time.Time, tuples []brain.Tuple) error {
+	return br.transact(ctx, func(tx *sql.Tx) error {
+		return learnPostgres(ctx, tx, tag, id, user, t, tuples)
+	})
+}
+
+// LearnBatch records the tuples of each message in a single transaction.
+// Messages already learned or forgotten are skipped.
+func (br *Postgres) LearnBatch(ctx context.Context, msgs []brain.Message) error {
+	return br.transact(ctx, func(tx *sql.Tx) error {
+		for _, m := range msgs {
+			if err := learnPostgres(ctx, tx, m.Tag, m.ID, m.User, m.Time, m.Tuples); err != nil {
+				return fmt.Errorf("couldn't learn message %v: %w", m.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// learnPostgres records a set of tuples in a transaction.
+func learnPostgres(ctx context.Context, tx *sql.Tx, tag, id string, user userhash.Hash, t time.Time, tuples []brain.Tuple) error {
+	// Claim the message first. If it's already there, whether learned or
+	// forgotten, another writer got to it, and we skip it. Unlike checking
+	// for the message separately, this can't race with concurrent writers.
+	const claim = `INSERT INTO messages (tag, id, time, userhash) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING`
+	r, err := tx.ExecContext(ctx, claim, tag, id, t.UnixNano(), user[:])
+	if err != nil {
+		return fmt.Errorf("couldn't insert message: %w", err)
+	}
+	if n, err := r.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	st, err := tx.PrepareContext(ctx, `INSERT INTO knowledge (tag, id, prefix, suffix) VALUES ($1, $2, $3, $4)`)
+	if err != nil {
+		return fmt.Errorf("couldn't prepare tuple insert: %w", err)
+	}
+	defer st.Close()
+	p := make([]byte, 0, 256)
+	for _, tt := range tuples {
+		p = append(prefix(p[:0], tt.Prefix), 0)
+		if _, err := st.ExecContext(ctx, tag, id, p, []byte(tt.Suffix)); err != nil {
+			return fmt.Errorf("couldn't insert tuple: %w", err)
+		}
+	}
+	return nil
+}
+
+// ForgetMessage forgets everything learned from a single given message.
+// If nothing has been learned from the message, nothing happens.
+func (br *Postgres) ForgetMessage(ctx context.Context, tag, id string) error {
+	// Forget the message even if it hasn't been learned yet, so that an
+	// attempt to learn it later does nothing.
+	const forget = `
+		WITH m AS (
+			INSERT INTO messages (tag, id, deleted) VALUES ($1, $2, 'CLEARMSG')
+			ON CONFLICT (tag, id) DO UPDATE SET deleted = 'CLEARMSG'
+			RETURNING tag, id
+		), k AS (
+			UPDATE knowledge SET deleted = 'CLEARMSG' FROM m
+			WHERE knowledge.tag = m.tag AND knowledge.id = m.id
+		)
+		INSERT INTO deletions (op, tag, id, time) SELECT $3::text, tag, id, $4::bigint FROM m
+		ON CONFLICT DO NOTHING
+	`
+	if _, err := br.db.ExecContext(ctx, forget, tag, id, deletionOp(ctx), time.Now().UnixNano()); err != nil {
+		return fmt.Errorf("couldn't forget message %v: %w", id, err)
+	}
+	return nil
+}
+
+// ForgetDuring forgets all messages learned in the given time span.
+func (br *Postgres) ForgetDuring(ctx context.Context, tag string, since, before time.Time) error {
+	const forget = `
+		WITH m AS (
+			UPDATE messages SET deleted = 'TIME'
+			WHERE tag = $1 AND time BETWEEN $2 AND $3
+			RETURNING tag, id
+		), k AS (
+			UPDATE knowledge SET deleted = 'TIME' FROM m
+			WHERE knowledge.tag = m.tag AND knowledge.id = m.id
+		)
+		INSERT INTO deletions (op, tag, id, time) SELECT $4::text, tag, id, $5::bigint FROM m
+		ON CONFLICT DO NOTHING
+	`
+	if _, err := br.db.ExecContext(ctx, forget, tag, since.UnixNano(), before.UnixNano(), deletionOp(ctx), time.Now().UnixNano()); err != nil {
+		return fmt.Errorf("couldn't forget time span: %w", err)
+	}
+	return nil
+}
+
+// ForgetUser forgets all messages associated with a userhash.
+func (br *Postgres) ForgetUser(ctx context.Context, user *userhash.Hash) error {
+	const forget = `
+		WITH m AS (
+			UPDATE messages SET deleted = 'CLEARCHAT'
+			WHERE userhash = $1
+			RETURNING tag, id
+		), k AS (
+			UPDATE knowledge SET deleted = 'CLEARCHAT' FROM m
+			WHERE knowledge.tag = m.tag AND knowledge.id = m.id
+		)
+		INSERT INTO deletions (op, tag, id, time) SELECT $2::text, tag, id, $3::bigint FROM m
+		ON CONFLICT DO NOTHING
+	`
+	if _, err := br.db.ExecContext(ctx, forget, user[:], deletionOp(ctx), time.Now().UnixNano()); err != nil {
+		return fmt.Errorf("couldn't forget from user: %w", err)
+	}
+	return nil
+}
+
+// deletionOp returns the operation attached to ctx, or a new one if it has
+// none.
+func deletionOp(ctx context.Context) string {
+	if op := brain.Op(ctx); op != "" {
+		return op
+	}
+	return brain.NewOp()
+}
+
+// Speak generates a full message and appends it to w.
+// The prompt is in reverse order and has entropy reduction applied.
+func (br *Postgres) Speak(ctx context.Context, tag string, prompt []string, w *brain.Builder) error {
+	search := prependerPool.Get().Append("").Prepend(prompt...)
+	defer func() { prependerPool.Put(search.Reset()) }()
+
+	conn, err := br.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't get connection to speak: %w", err)
+	}
+	defer conn.Close()
+	st, err := conn.PrepareContext(ctx, `SELECT id, suffix FROM knowledge WHERE tag = $1 AND prefix >= $2 AND prefix < $3 AND deleted IS NULL`)
+	if err != nil {
+		return fmt.Errorf("couldn't prepare term selection: %w", err)
+	}
+	defer st.Close()
+
+	b := make([]byte, 0, 128)
+	for range 1024 {
+		var l int
+		var id string
+		b, id, l, err = nextPostgres(ctx, st, tag, b, search.Slice())
+		if err != nil {
+			return err
+		}
+		if len(b) == 0 {
+			break
+		}
+		w.Append(id, b)
+		search = search.DropEnd(search.Len() - l - 1).Prepend(brain.ReduceEntropy(string(b)))
+	}
+	return nil
+}
+
+// nextPostgres selects the term to follow a prompt using a term selection
+// statement. It returns the term, the ID of the message it came from, and
+// the length of the prompt which selected it.
+func nextPostgres(ctx context.Context, st *sql.Stmt, tag string, b []byte, prompt []string) ([]byte, string, int, error) {
+	for {
+		// The start of a message is the prefix with only the terminator.
+		b = prefix(b[:0], prompt)
+		if len(prompt) == 0 {
+			b = append(b, 0)
+		}
+		lower, upper := searchbounds(b)
+		id, term, picked, err := pickPostgres(ctx, st, tag, lower, upper)
+		if err != nil {
+			return b[:0], "", len(prompt), err
+		}
+		if picked < 3 && len(prompt) > 3 {
+			// We haven't seen enough options, and we have context we could
+			// lose. Do so and try again.
+			prompt = prompt[:len(prompt)-1]
+			continue
+		}
+		// Note that this also handles the case where there were no results.
+		return append(b[:0], term...), id, len(prompt), nil
+	}
+}
+
+// pickPostgres selects a random row from a term selection. It returns the
+// ID and term of the row and the number of rows it considered.
+func pickPostgres(ctx context.Context, st *sql.Stmt, tag string, lower, upper []byte) (string, []byte, int, error) {
+	rows, err := st.QueryContext(ctx, tag, lower, upper)
+	if err != nil {
+		return "", nil, 0, fmt.Errorf("couldn't select terms: %w", err)
+	}
+	defer rows.Close()
+	var (
+		id   string
+		term []byte
+		skip brain.Skip
+	)
+	picked := 0
+sel:
+	for rows.Next() {
+		if err := rows.Scan(&id, &term); err != nil {
+			return "", nil, 0, fmt.Errorf("couldn't scan term: %w", err)
+		}
+		picked++
+		for range skip.N(rand.Uint64(), rand.Uint64()) {
+			if !rows.Next() {
+				break sel
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", nil, 0, fmt.Errorf("couldn't step term selection: %w", err)
+	}
+	return id, term, picked, nil
+}
@@ -0,0 +1,60 @@
+package sqlbrain_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/braintest"
+	"github.com/zephyrtronium/robot/brain/sqlbrain"
+)
+
+var _ brain.Brain = (*sqlbrain.Postgres)(nil)
+
+// TestPostgres runs the brain tests against the PostgreSQL database named by
+// ROBOT_TEST_POSTGRES. The database's brain tables are emptied for each test.
+func TestPostgres(t *testing.T) {
+	dsn := os.Getenv("ROBOT_TEST_POSTGRES")
+	if dsn == "" {
+		t.Skip("ROBOT_TEST_POSTGRES not set")
+	}
+	ctx := context.Background()
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	new := func(ctx context.Context) brain.Brain {
+		br, err := sqlbrain.OpenPostgres(ctx, db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.ExecContext(ctx, `TRUNCATE knowledge, messages, deletions`); err != nil {
+			t.Fatal(err)
+		}
+		return br
+	}
+	braintest.Test(ctx, t, new)
+}
+
+func TestIsPostgres(t *testing.T) {
+	cases := []struct {
+		dsn  string
+		want bool
+	}{
+		{"postgres://robot@localhost/robot", true},
+		{"postgresql://robot@localhost/robot?sslmode=disable", true},
+		{"file:robot.db", false},
+		{"robot.db", false},
+		{"", false},
+	}
+	for _, c := range cases {
+		if got := sqlbrain.IsPostgres(c.dsn); got != c.want {
+			t.Errorf("wrong result for %q: want %t, got %t", c.dsn, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package sqlbrain
+
+import (
+	"context"
+	"fmt"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/zephyrtronium/robot/brain"
+)
+
+var _ brain.Preloader = (*Brain)(nil)
+
+// Preload reads the knowledge under a tag through the same index used to
+// speak, so that the database pages are in the operating system's file cache
+// before they are needed. If top is positive, only tuples with the top most
+// frequent prefixes are read, although finding them still reads the whole
+// index for the tag. If the brain has a replica, the replica is read.
+func (br *Brain) Preload(ctx context.Context, tag string, top int, progress func(n int64)) (int64, error) {
+	// Read from wherever we speak.
+	db := br.reader()
+	conn, err := db.Take(ctx)
+	defer db.Put(conn)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't get connection to preload: %w", err)
+	}
+	sel := `SELECT suffix FROM knowledge INDEXED BY prefixes WHERE tag = :tag`
+	named := map[string]any{":tag": tag}
+	if top > 0 {
+		sel = `SELECT k.suffix FROM knowledge AS k INDEXED BY prefixes
+			JOIN (
+				SELECT prefix FROM knowledge
+				WHERE tag = :tag AND deleted IS NULL
+				GROUP BY prefix
+				ORDER BY COUNT(*) DESC
+				LIMIT :top
+			) AS p ON k.prefix = p.prefix
+			WHERE k.tag = :tag`
+		named[":top"] = top
+	}
+	var n int64
+	opts := sqlitex.ExecOptions{
+		Named: named,
+		ResultFunc: func(st *sqlite.Stmt) error {
+			n++
+			if n%4096 == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if progress != nil {
+					progress(n)
+				}
+			}
+			return nil
+		},
+	}
+	if err := sqlitex.Execute(conn, sel, &opts); err != nil {
+		return n, fmt.Errorf("couldn't preload tuples: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,55 @@
+package sqlbrain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/sqlbrain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+func TestPreload(t *testing.T) {
+	ctx := context.Background()
+	br, err := sqlbrain.Open(ctx, testDB(ctx))
+	if err != nil {
+		t.Fatalf("couldn't open brain: %v", err)
+	}
+	msgs := []struct {
+		tag, id, text string
+	}{
+		{"kessoku", "1", "bocchi rock"},
+		{"kessoku", "2", "bocchi rock"},
+		{"kessoku", "3", "bocchi rock"},
+		{"kessoku", "4", "kita"},
+		{"sickhack", "5", "kikuri"},
+	}
+	for _, m := range msgs {
+		if err := brain.Learn(ctx, br, m.tag, m.id, userhash.Hash{1}, time.Unix(1, 0), brain.Tokens(nil, m.text)); err != nil {
+			t.Fatalf("couldn't learn %q: %v", m.text, err)
+		}
+	}
+	n, err := br.Preload(ctx, "kessoku", 0, nil)
+	if err != nil {
+		t.Errorf("couldn't preload: %v", err)
+	}
+	if n != 11 {
+		t.Errorf("wrong number of tuples preloaded for whole tag: want 11, got %d", n)
+	}
+	// The most frequent prefix is the start of a message.
+	n, err = br.Preload(ctx, "kessoku", 1, nil)
+	if err != nil {
+		t.Errorf("couldn't preload: %v", err)
+	}
+	if n != 4 {
+		t.Errorf("wrong number of tuples preloaded for top prefix: want 4, got %d", n)
+	}
+	n, err = br.Preload(ctx, "nothing", 0, nil)
+	if err != nil {
+		t.Errorf("couldn't preload unknown tag: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("preloaded %d tuples from unknown tag", n)
+	}
+}
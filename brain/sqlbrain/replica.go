@@ -0,0 +1,76 @@
+package sqlbrain
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// replicaStats counts how speaking divides between a replica and the primary
+// database. It is shared between a brain and its views.
+type replicaStats struct {
+	spoken    atomic.Int64
+	fallbacks atomic.Int64
+}
+
+// WithReplica returns a brain which speaks from a read replica of br's
+// database, while learning and forgetting still go to br's database.
+// The replica may lag behind, so whenever it generates nothing, e.g. because
+// the knowledge a prompt needs isn't visible there yet, or it fails, the
+// brain speaks from the primary instead.
+// The replica must remain open for the lifetime of the brain.
+func (br *Brain) WithReplica(replica *sqlitex.Pool) *Brain {
+	r := *br
+	r.replica = replica
+	r.replicaStats = new(replicaStats)
+	return &r
+}
+
+// ReplicaStats is a snapshot of how speaking divides between a replica and
+// the primary database.
+type ReplicaStats struct {
+	// Spoken is the number of messages spoken from the replica.
+	Spoken int64 `json:"spoken"`
+	// Fallbacks is the number of messages spoken from the primary because
+	// the replica generated nothing or failed.
+	Fallbacks int64 `json:"fallbacks"`
+}
+
+// ReplicaStats returns a snapshot of the brain's replica counters.
+// If the brain has no replica, the counters are all zero.
+func (br *Brain) ReplicaStats() ReplicaStats {
+	if br.replicaStats == nil {
+		return ReplicaStats{}
+	}
+	return ReplicaStats{
+		Spoken:    br.replicaStats.spoken.Load(),
+		Fallbacks: br.replicaStats.fallbacks.Load(),
+	}
+}
+
+// reader returns the pool from which the brain reads knowledge to speak.
+func (br *Brain) reader() *sqlitex.Pool {
+	if br.replica != nil {
+		return br.replica
+	}
+	return br.db
+}
+
+// ReplicaPrep is an [sqlitex.ConnPrepareFunc] that sets options recommended
+// for a read replica of a brain. In particular, connections refuse to write,
+// so that nothing is ever learned into the replica by mistake.
+func ReplicaPrep(conn *sqlite.Conn) error {
+	s, _, err := conn.PrepareTransient("PRAGMA query_only = true")
+	if err != nil {
+		panic(fmt.Errorf("couldn't set query_only: %w", err))
+	}
+	if err := allsteps(s); err != nil {
+		return fmt.Errorf("couldn't run query_only: %w", err)
+	}
+	if err := s.Finalize(); err != nil {
+		panic(fmt.Errorf("couldn't finalize statement for query_only: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package sqlbrain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/sqlbrain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+func TestReplica(t *testing.T) {
+	ctx := context.Background()
+	primary, err := sqlbrain.Open(ctx, testDB(ctx))
+	if err != nil {
+		t.Fatalf("couldn't open primary: %v", err)
+	}
+	// Stand in for a replica with its own database, so that we control what
+	// it has caught up to.
+	rdb := testDB(ctx)
+	lagging, err := sqlbrain.Open(ctx, rdb)
+	if err != nil {
+		t.Fatalf("couldn't open replica: %v", err)
+	}
+	br := primary.WithReplica(rdb)
+	learn := func(br brain.Learner, id, text string) {
+		t.Helper()
+		if err := brain.Learn(ctx, br, "kessoku", id, userhash.Hash{1}, time.Unix(1, 0), brain.Tokens(nil, text)); err != nil {
+			t.Fatalf("couldn't learn %q: %v", text, err)
+		}
+	}
+	learn(br, "1", "bocchi plays guitar")
+	learn(lagging, "1", "bocchi plays guitar")
+	// Learning through the brain with a replica goes to the primary only.
+	learn(br, "2", "nijika plays drums")
+
+	s, trace, err := brain.Speak(ctx, br, "kessoku", "bocchi")
+	if err != nil {
+		t.Fatalf("couldn't speak from replica: %v", err)
+	}
+	if s != "bocchi plays guitar" || len(trace) != 1 || trace[0] != "1" {
+		t.Errorf("wrong speech from replica: %q %q", s, trace)
+	}
+	if st := br.ReplicaStats(); st.Spoken != 1 || st.Fallbacks != 0 {
+		t.Errorf("wrong stats after speaking from replica: %+v", st)
+	}
+	// The replica hasn't seen the second message, so prompting with it
+	// falls back to the primary.
+	s, trace, err = brain.Speak(ctx, br, "kessoku", "nijika")
+	if err != nil {
+		t.Fatalf("couldn't speak from primary: %v", err)
+	}
+	if s != "nijika plays drums" || len(trace) != 1 || trace[0] != "2" {
+		t.Errorf("wrong speech from primary: %q %q", s, trace)
+	}
+	if st := br.ReplicaStats(); st.Spoken != 1 || st.Fallbacks != 1 {
+		t.Errorf("wrong stats after falling back: %+v", st)
+	}
+	// Views share the replica.
+	v := br.During(time.Unix(0, 0), time.Unix(2, 0))
+	if s, _, err := brain.Speak(ctx, v, "kessoku", "bocchi"); err != nil || s != "bocchi plays guitar" {
+		t.Errorf("wrong speech from view: %q, %v", s, err)
+	}
+	if st := br.ReplicaStats(); st.Spoken != 2 {
+		t.Errorf("view didn't speak from replica: %+v", st)
+	}
+}
+
+func TestReplicaPrep(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(ctx)
+	if _, err := sqlbrain.Open(ctx, db); err != nil {
+		t.Fatalf("couldn't open brain: %v", err)
+	}
+	conn, err := db.Take(ctx)
+	if err != nil {
+		t.Fatalf("couldn't get connection: %v", err)
+	}
+	defer db.Put(conn)
+	if err := sqlbrain.ReplicaPrep(conn); err != nil {
+		t.Fatalf("couldn't prepare connection: %v", err)
+	}
+	defer execute(t, conn, "PRAGMA query_only = false")
+	err = sqlitex.ExecuteTransient(conn, "CREATE TABLE probe(x)", nil)
+	if code := sqlite.ErrCode(err).ToPrimary(); code != sqlite.ResultReadOnly {
+		t.Errorf("replica connection allowed writing: %v", err)
+	}
+}
+
+func execute(t *testing.T, conn *sqlite.Conn, q string) {
+	t.Helper()
+	if err := sqlitex.ExecuteTransient(conn, q, nil); err != nil {
+		t.Fatalf("couldn't run %s: %v", q, err)
+	}
+}
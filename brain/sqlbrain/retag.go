@@ -0,0 +1,38 @@
+package sqlbrain
+
+import (
+	"context"
+	"fmt"
+
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/zephyrtronium/robot/brain"
+)
+
+var _ brain.Retagger = (*Brain)(nil)
+
+// Retag moves all messages and tuples learned under one tag to another,
+// including the records of forgotten messages which can still be restored.
+func (br *Brain) Retag(ctx context.Context, from, to string) (n int64, err error) {
+	conn, err := br.db.Take(ctx)
+	defer br.db.Put(conn)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't get connection to retag: %w", err)
+	}
+	defer sqlitex.Transaction(conn)(&err)
+	opts := &sqlitex.ExecOptions{Named: map[string]any{":from": from, ":to": to}}
+	const messages = `UPDATE messages SET tag = :to WHERE tag = :from`
+	if err := sqlitex.Execute(conn, messages, opts); err != nil {
+		return 0, fmt.Errorf("couldn't move messages: %w", err)
+	}
+	n = int64(conn.Changes())
+	const tuples = `UPDATE knowledge SET tag = :to WHERE tag = :from`
+	if err := sqlitex.Execute(conn, tuples, opts); err != nil {
+		return 0, fmt.Errorf("couldn't move tuples: %w", err)
+	}
+	const deletions = `UPDATE deletions SET tag = :to WHERE tag = :from`
+	if err := sqlitex.Execute(conn, deletions, opts); err != nil {
+		return 0, fmt.Errorf("couldn't move deletion records: %w", err)
+	}
+	return n, nil
+}
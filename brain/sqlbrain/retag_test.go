@@ -0,0 +1,66 @@
+package sqlbrain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/sqlbrain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+func TestRetag(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(ctx)
+	br, err := sqlbrain.Open(ctx, db)
+	if err != nil {
+		t.Fatalf("couldn't open brain: %v", err)
+	}
+	msgs := []struct {
+		tag, id, text string
+	}{
+		{"kessoku", "1", "bocchi the rock"},
+		{"kessoku", "2", "kita"},
+		{"sickhack", "3", "kikuri"},
+	}
+	for _, m := range msgs {
+		err := brain.Learn(ctx, br, m.tag, m.id, userhash.Hash{}, time.Unix(0, 0), brain.Tokens(nil, m.text))
+		if err != nil {
+			t.Fatalf("couldn't learn %q: %v", m.text, err)
+		}
+	}
+	if err := br.ForgetMessage(brain.WithOp(ctx, "op"), "kessoku", "2"); err != nil {
+		t.Fatalf("couldn't forget: %v", err)
+	}
+	n, err := br.Retag(ctx, "kessoku", "twitch/kessoku")
+	if err != nil {
+		t.Fatalf("couldn't retag: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("wrong number of messages moved: want 2, got %d", n)
+	}
+	if st, err := br.Stats(ctx, "kessoku"); err != nil || *st != (brain.Stats{}) {
+		t.Errorf("knowledge left under old tag: %+v, %v", st, err)
+	}
+	if st, err := br.Stats(ctx, "twitch/kessoku"); err != nil || *st != (brain.Stats{Tuples: 4, Messages: 1}) {
+		t.Errorf("wrong knowledge under new tag: %+v, %v", st, err)
+	}
+	// Forgotten messages move too, so they can still be restored.
+	if _, err := br.Undelete(ctx, "op"); err != nil {
+		t.Fatalf("couldn't undelete: %v", err)
+	}
+	if s, err := br.Recall(ctx, "twitch/kessoku", "2"); err != nil || s != "kita" {
+		t.Errorf("wrong restored message: %q, %v", s, err)
+	}
+	// Moving onto a tag which has the same message is an error.
+	if err := brain.Learn(ctx, br, "kessoku", "1", userhash.Hash{}, time.Unix(0, 0), brain.Tokens(nil, "nijika")); err != nil {
+		t.Fatalf("couldn't learn again: %v", err)
+	}
+	if _, err := br.Retag(ctx, "kessoku", "twitch/kessoku"); err == nil {
+		t.Error("retagging onto the same message succeeded")
+	}
+	if s, err := br.Recall(ctx, "kessoku", "1"); err != nil || s != "nijika" {
+		t.Errorf("failed retag changed knowledge: %q, %v", s, err)
+	}
+}
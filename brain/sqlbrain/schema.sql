@@ -17,6 +17,7 @@ CREATE TABLE IF NOT EXISTS knowledge (
 	-- 'CLEARCHAT', for messages deleted by userhash;
 	-- 'TIME', for messages deleted in a time range;
 	-- or NULL, for tuples which have not been deleted.
+	-- Tuples evicted to stay within a quota are removed entirely.
 	-- These values are only for analytics; any non-null value indicates the
 	-- tuple should be treated as deleted.
 	deleted TEXT
@@ -37,7 +38,8 @@ CREATE TABLE IF NOT EXISTS messages (
 	user BLOB,
 	-- Reason for delete, if any.
 	-- Same meaning as in knowledge, except that the value 'FORGET' will never
-	-- appear (since that is specifically for operating on tuples).
+	-- appear (since that is specifically for operating on tuples), and the
+	-- value 'QUOTA' means the message's tuples were evicted.
 	-- Denormalized here to allow soft deletes of messages before they are
 	-- actually learned.
 	deleted TEXT,
@@ -45,7 +47,22 @@ CREATE TABLE IF NOT EXISTS messages (
 	PRIMARY KEY(tag, id)
 ) STRICT;
 
+CREATE TABLE IF NOT EXISTS deletions (
+	-- Operation which forgot the message.
+	op TEXT NOT NULL,
+	-- Tag and ID of the forgotten message.
+	tag TEXT NOT NULL,
+	id TEXT NOT NULL,
+	-- Time at which the message was forgotten as nanoseconds from the UNIX
+	-- epoch. Tuples of messages forgotten long enough ago are removed
+	-- entirely, after which the operation can no longer be undone.
+	time INTEGER NOT NULL,
+
+	PRIMARY KEY(op, tag, id)
+) STRICT;
+
 CREATE INDEX IF NOT EXISTS ids ON knowledge (tag, id);
 CREATE INDEX IF NOT EXISTS prefixes ON knowledge (tag, prefix);
 CREATE INDEX IF NOT EXISTS times ON messages (tag, time);
 CREATE INDEX IF NOT EXISTS users ON messages (user);
+CREATE INDEX IF NOT EXISTS deletion_times ON deletions (time);
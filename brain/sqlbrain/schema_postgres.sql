@@ -0,0 +1,36 @@
+-- The PostgreSQL schema mirrors schema.sql. See there for descriptions of
+-- the columns. The message sender is userhash rather than user, which is
+-- reserved in PostgreSQL.
+
+CREATE TABLE IF NOT EXISTS knowledge (
+	tag TEXT NOT NULL,
+	id TEXT NOT NULL,
+	prefix BYTEA NOT NULL,
+	suffix BYTEA NOT NULL,
+	deleted TEXT
+);
+
+CREATE TABLE IF NOT EXISTS messages (
+	tag TEXT NOT NULL,
+	id TEXT NOT NULL,
+	time BIGINT,
+	userhash BYTEA,
+	deleted TEXT,
+
+	PRIMARY KEY(tag, id)
+);
+
+CREATE TABLE IF NOT EXISTS deletions (
+	op TEXT NOT NULL,
+	tag TEXT NOT NULL,
+	id TEXT NOT NULL,
+	time BIGINT NOT NULL,
+
+	PRIMARY KEY(op, tag, id)
+);
+
+CREATE INDEX IF NOT EXISTS ids ON knowledge (tag, id);
+CREATE INDEX IF NOT EXISTS prefixes ON knowledge (tag, prefix);
+CREATE INDEX IF NOT EXISTS times ON messages (tag, time);
+CREATE INDEX IF NOT EXISTS users ON messages (userhash);
+CREATE INDEX IF NOT EXISTS deletion_times ON deletions (time);
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"math/rand/v2"
+	"strings"
 
 	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
 
 	"github.com/zephyrtronium/robot/brain"
 	"github.com/zephyrtronium/robot/deque"
@@ -16,22 +18,47 @@ var prependerPool tpool.Pool[deque.Deque[string]]
 
 // Speak generates a full message and appends it to w.
 // The prompt is in reverse order and has entropy reduction applied.
+// If the brain has a replica, it speaks from the replica first and falls back
+// to the primary database if the replica generates nothing.
 func (br *Brain) Speak(ctx context.Context, tag string, prompt []string, w *brain.Builder) error {
+	if br.replica != nil {
+		err := br.speak(ctx, br.replica, tag, prompt, w)
+		switch {
+		case len(w.Trace()) != 0:
+			// Once anything is generated, we're committed to the replica.
+			br.replicaStats.spoken.Add(1)
+			return err
+		case ctx.Err() != nil:
+			return ctx.Err()
+		}
+		// Either the replica hasn't caught up to what the prompt needs, or
+		// it failed before generating anything. Either way, the primary is
+		// authoritative.
+		br.replicaStats.fallbacks.Add(1)
+	}
+	return br.speak(ctx, br.db, tag, prompt, w)
+}
+
+// speak generates a full message from a particular database.
+func (br *Brain) speak(ctx context.Context, db *sqlitex.Pool, tag string, prompt []string, w *brain.Builder) error {
 	search := prependerPool.Get().Append("").Prepend(prompt...)
 	defer func() { prependerPool.Put(search.Reset()) }()
 
-	conn, err := br.db.Take(ctx)
-	defer br.db.Put(conn)
+	conn, err := db.Take(ctx)
+	defer db.Put(conn)
 	if err != nil {
 		return fmt.Errorf("couldn't get connection to speak: %w", err)
 	}
 
 	b := make([]byte, 0, 128)
 	for range 1024 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		var err error
 		var l int
 		var id string
-		b, id, l, err = next(conn, tag, b, search.Slice())
+		b, id, l, err = br.next(conn, tag, b, search.Slice())
 		if err != nil {
 			return err
 		}
@@ -44,18 +71,50 @@ func (br *Brain) Speak(ctx context.Context, tag string, prompt []string, w *brai
 	return nil
 }
 
-func next(conn *sqlite.Conn, tag string, b []byte, prompt []string) ([]byte, string, int, error) {
+// inWindow is the condition added to knowledge selections to restrict them to
+// messages within a time span.
+const inWindow = ` AND id IN (SELECT id FROM messages WHERE tag = :tag AND time >= :since AND time < :before AND deleted IS NULL)`
+
+// inView replaces tag selections when the brain speaks from a view.
+const inView = `tag IN (SELECT value FROM json_each(:tags))`
+
+// prepare prepares a knowledge selection, restricted to the brain's time span
+// if it has one. The tail follows the restriction in the query. If the brain
+// speaks from a view, the selection uses the view's tags in place of tag.
+func (br *Brain) prepare(conn *sqlite.Conn, query, tail, tag string) (*sqlite.Stmt, error) {
+	if br.before != 0 {
+		query += inWindow
+	}
+	if br.tags != "" {
+		query = strings.ReplaceAll(query, "tag = :tag", inView)
+	}
+	st, err := conn.Prepare(query + tail)
+	if err != nil {
+		return nil, err
+	}
+	if br.tags != "" {
+		st.SetText(":tags", br.tags)
+	} else {
+		st.SetText(":tag", tag)
+	}
+	if br.before != 0 {
+		st.SetInt64(":since", br.since)
+		st.SetInt64(":before", br.before)
+	}
+	return st, nil
+}
+
+func (br *Brain) next(conn *sqlite.Conn, tag string, b []byte, prompt []string) ([]byte, string, int, error) {
 	var id string
 	if len(prompt) == 0 {
 		var err error
-		b, id, err = first(conn, tag, b)
+		b, id, err = br.first(conn, tag, b)
 		return b, id, 0, err
 	}
-	st, err := conn.Prepare(`SELECT id, suffix FROM knowledge WHERE tag = :tag AND prefix >= :lower AND prefix < :upper AND LIKELY(deleted IS NULL)`)
+	st, err := br.prepare(conn, `SELECT id, suffix FROM knowledge WHERE tag = :tag AND prefix >= :lower AND prefix < :upper AND LIKELY(deleted IS NULL)`, "", tag)
 	if err != nil {
 		return b[:0], "", len(prompt), fmt.Errorf("couldn't prepare term selection: %w", err)
 	}
-	st.SetText(":tag", tag)
 	w := make([]byte, 0, 32)
 	var d []byte
 	var skip brain.Skip
@@ -67,7 +126,7 @@ func next(conn *sqlite.Conn, tag string, b []byte, prompt []string) ([]byte, str
 		st.SetBytes(":upper", d)
 	sel:
 		for {
-			ok, err := st.Step()
+			ok, err := br.step(st)
 			if err != nil {
 				return b[:0], "", len(prompt), fmt.Errorf("couldn't step term selection: %w", err)
 			}
@@ -82,7 +141,7 @@ func next(conn *sqlite.Conn, tag string, b []byte, prompt []string) ([]byte, str
 			w = w[:st.ColumnBytes(1, w[:n])]
 			picked++
 			for range skip.N(rand.Uint64(), rand.Uint64()) {
-				ok, err := st.Step()
+				ok, err := br.step(st)
 				if err != nil {
 					return b[:0], "", len(prompt), fmt.Errorf("couldn't step term selection: %w", err)
 				}
@@ -106,6 +165,20 @@ func next(conn *sqlite.Conn, tag string, b []byte, prompt []string) ([]byte, str
 	}
 }
 
+// step advances a term selection to the next row whose term the brain
+// doesn't block. The term must be the second column.
+func (br *Brain) step(st *sqlite.Stmt) (bool, error) {
+	for {
+		ok, err := st.Step()
+		if err != nil || !ok || br.block == nil {
+			return ok, err
+		}
+		if st.ColumnLen(1) == 0 || !br.block.MatchString(st.ColumnText(1)) {
+			return true, nil
+		}
+	}
+}
+
 // searchbounds produces the lower and upper bounds for a search by prefix.
 // The upper bound is always a slice of the lower bound's underlying array.
 func searchbounds(prefix []byte) (lower, upper []byte) {
@@ -120,18 +193,17 @@ func searchbounds(prefix []byte) (lower, upper []byte) {
 	return lower, upper
 }
 
-func first(conn *sqlite.Conn, tag string, b []byte) ([]byte, string, error) {
+func (br *Brain) first(conn *sqlite.Conn, tag string, b []byte) ([]byte, string, error) {
 	var id string
 	b = b[:0] // in case we get no rows
-	s, err := conn.Prepare(`SELECT id, suffix FROM knowledge WHERE tag = :tag AND prefix = x'00' AND LIKELY(deleted IS NULL)`)
+	s, err := br.prepare(conn, `SELECT id, suffix FROM knowledge WHERE tag = :tag AND prefix = x'00' AND LIKELY(deleted IS NULL)`, "", tag)
 	if err != nil {
 		return b, "", fmt.Errorf("couldn't prepare first term selection: %w", err)
 	}
-	s.SetText(":tag", tag)
 	var skip brain.Skip
 sel:
 	for {
-		ok, err := s.Step()
+		ok, err := br.step(s)
 		if err != nil {
 			return b[:0], "", fmt.Errorf("couldn't step first term selection: %w", err)
 		}
@@ -145,7 +217,7 @@ sel:
 		}
 		b = b[:s.ColumnBytes(1, b[:n])]
 		for range skip.N(rand.Uint64(), rand.Uint64()) {
-			ok, err := s.Step()
+			ok, err := br.step(s)
 			if err != nil {
 				return b[:0], "", fmt.Errorf("couldn't step first term selection: %w", err)
 			}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"slices"
+	"strings"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"zombiezen.com/go/sqlite"
 	"zombiezen.com/go/sqlite/sqlitex"
@@ -13,6 +15,7 @@ import (
 	"github.com/zephyrtronium/robot/brain"
 	"github.com/zephyrtronium/robot/brain/braintest"
 	"github.com/zephyrtronium/robot/brain/sqlbrain"
+	"github.com/zephyrtronium/robot/userhash"
 )
 
 func TestSpeak(t *testing.T) {
@@ -536,3 +539,38 @@ func BenchmarkSpeak(b *testing.B) {
 	}
 	braintest.BenchSpeak(context.Background(), b, new, cleanup)
 }
+
+func TestSpeakDuring(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := testDB(ctx)
+	br, err := sqlbrain.Open(ctx, db)
+	if err != nil {
+		t.Fatalf("couldn't open brain: %v", err)
+	}
+	msgs := []struct {
+		id   string
+		t    time.Time
+		text string
+	}{
+		{"1", time.Unix(100, 0), "bocchi"},
+		{"2", time.Unix(200, 0), "ryo"},
+		{"3", time.Unix(300, 0), "kita"},
+	}
+	for _, m := range msgs {
+		err := brain.Learn(ctx, br, "kessoku", m.id, userhash.Hash{}, m.t, strings.Fields(m.text))
+		if err != nil {
+			t.Fatalf("couldn't learn %q: %v", m.text, err)
+		}
+	}
+	v := br.During(time.Unix(150, 0), time.Unix(250, 0))
+	for range 100 {
+		s, _, err := brain.Speak(ctx, v, "kessoku", "")
+		if err != nil {
+			t.Errorf("couldn't speak: %v", err)
+		}
+		if s != "ryo" {
+			t.Errorf("wrong result: should always say %q but got %q", "ryo", s)
+		}
+	}
+}
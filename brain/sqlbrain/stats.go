@@ -0,0 +1,173 @@
+package sqlbrain
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/zephyrtronium/robot/brain"
+)
+
+var (
+	_ brain.Stater    = (*Brain)(nil)
+	_ brain.Backuper  = (*Brain)(nil)
+	_ brain.Continuer = (*Brain)(nil)
+	_ brain.Recaller  = (*Brain)(nil)
+)
+
+// Stats counts the tuples and messages learned under a tag.
+func (br *Brain) Stats(ctx context.Context, tag string) (*brain.Stats, error) {
+	conn, err := br.db.Take(ctx)
+	defer br.db.Put(conn)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get connection for stats: %w", err)
+	}
+	var r brain.Stats
+	const countTuples = `SELECT COUNT(*) FROM knowledge WHERE tag = :tag AND deleted IS NULL`
+	st := conn.Prep(countTuples)
+	st.SetText(":tag", tag)
+	r.Tuples, err = sqlitex.ResultInt64(st)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't count tuples: %w", err)
+	}
+	const countMessages = `SELECT COUNT(*) FROM messages WHERE tag = :tag AND deleted IS NULL`
+	st = conn.Prep(countMessages)
+	st.SetText(":tag", tag)
+	r.Messages, err = sqlitex.ResultInt64(st)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't count messages: %w", err)
+	}
+	return &r, nil
+}
+
+// Backup writes a copy of the brain's database to w as an SQLite database
+// file.
+func (br *Brain) Backup(ctx context.Context, w io.Writer) error {
+	dir, err := os.MkdirTemp("", "sqlbrain-backup-")
+	if err != nil {
+		return fmt.Errorf("couldn't create directory for backup: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "brain.sql")
+	if err := br.copyTo(ctx, file); err != nil {
+		return err
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("couldn't open backup: %w", err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(w, f); err != nil {
+		return fmt.Errorf("couldn't write backup: %w", err)
+	}
+	return nil
+}
+
+// copyTo writes a copy of the database to a new file using the SQLite online
+// backup API.
+func (br *Brain) copyTo(ctx context.Context, file string) error {
+	dst, err := sqlite.OpenConn(file, sqlite.OpenReadWrite|sqlite.OpenCreate)
+	if err != nil {
+		return fmt.Errorf("couldn't create backup database: %w", err)
+	}
+	defer dst.Close()
+	conn, err := br.db.Take(ctx)
+	defer br.db.Put(conn)
+	if err != nil {
+		return fmt.Errorf("couldn't get connection for backup: %w", err)
+	}
+	b, err := sqlite.NewBackup(dst, "main", conn, "main")
+	if err != nil {
+		return fmt.Errorf("couldn't start backup: %w", err)
+	}
+	for {
+		more, err := b.Step(1024)
+		if err != nil {
+			b.Close()
+			return fmt.Errorf("couldn't copy database: %w", err)
+		}
+		if !more {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			b.Close()
+			return err
+		}
+	}
+	if err := b.Close(); err != nil {
+		return fmt.Errorf("couldn't finish backup: %w", err)
+	}
+	return nil
+}
+
+// Continuations counts the terms learned to follow a prompt.
+func (br *Brain) Continuations(ctx context.Context, tag string, prompt []string) (map[string]int, error) {
+	conn, err := br.db.Take(ctx)
+	defer br.db.Put(conn)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get connection for continuations: %w", err)
+	}
+	var st *sqlite.Stmt
+	if len(prompt) == 0 {
+		st, err = br.prepare(conn, `SELECT suffix, COUNT(*) FROM knowledge WHERE tag = :tag AND prefix = x'00' AND LIKELY(deleted IS NULL)`, ` GROUP BY suffix`, tag)
+	} else {
+		st, err = br.prepare(conn, `SELECT suffix, COUNT(*) FROM knowledge WHERE tag = :tag AND prefix >= :lower AND prefix < :upper AND LIKELY(deleted IS NULL)`, ` GROUP BY suffix`, tag)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("couldn't prepare continuations: %w", err)
+	}
+	defer st.Reset()
+	if len(prompt) != 0 {
+		lower, upper := searchbounds(prefix(nil, prompt))
+		st.SetBytes(":lower", lower)
+		st.SetBytes(":upper", upper)
+	}
+	r := make(map[string]int)
+	for {
+		ok, err := st.Step()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't step continuations: %w", err)
+		}
+		if !ok {
+			break
+		}
+		r[st.ColumnText(0)] += int(st.ColumnInt64(1))
+	}
+	return r, nil
+}
+
+// Recall reconstructs the text of a learned message from its tuples.
+func (br *Brain) Recall(ctx context.Context, tag, id string) (string, error) {
+	conn, err := br.db.Take(ctx)
+	defer br.db.Put(conn)
+	if err != nil {
+		return "", fmt.Errorf("couldn't get connection to recall: %w", err)
+	}
+	// Tuples are learned from the end of the message to the start.
+	const sel = `SELECT suffix FROM knowledge WHERE tag = :tag AND id = :id AND deleted IS NULL ORDER BY rowid DESC`
+	st, err := conn.Prepare(sel)
+	if err != nil {
+		return "", fmt.Errorf("couldn't prepare recall: %w", err)
+	}
+	defer st.Reset()
+	st.SetText(":tag", tag)
+	st.SetText(":id", id)
+	var b strings.Builder
+	for {
+		ok, err := st.Step()
+		if err != nil {
+			return "", fmt.Errorf("couldn't step recall: %w", err)
+		}
+		if !ok {
+			break
+		}
+		b.WriteString(st.ColumnText(0))
+	}
+	return strings.TrimSpace(b.String()), nil
+}
@@ -0,0 +1,83 @@
+package sqlbrain_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/sqlbrain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(ctx)
+	br, err := sqlbrain.Open(ctx, db)
+	if err != nil {
+		t.Fatalf("couldn't open brain: %v", err)
+	}
+	msgs := []struct {
+		tag, id, text string
+	}{
+		{"kessoku", "1", "bocchi the rock"},
+		{"kessoku", "2", "kita"},
+		{"sickhack", "3", "kikuri"},
+	}
+	for _, m := range msgs {
+		err := brain.Learn(ctx, br, m.tag, m.id, userhash.Hash{}, time.Unix(0, 0), strings.Fields(m.text))
+		if err != nil {
+			t.Fatalf("couldn't learn %q: %v", m.text, err)
+		}
+	}
+	if err := br.ForgetMessage(ctx, "kessoku", "2"); err != nil {
+		t.Fatalf("couldn't forget: %v", err)
+	}
+	st, err := br.Stats(ctx, "kessoku")
+	if err != nil {
+		t.Fatalf("couldn't get stats: %v", err)
+	}
+	if want := (brain.Stats{Tuples: 4, Messages: 1}); *st != want {
+		t.Errorf("wrong stats: want %+v, got %+v", want, *st)
+	}
+	c, err := br.Continuations(ctx, "kessoku", []string{"bocchi"})
+	if err != nil {
+		t.Fatalf("couldn't get continuations: %v", err)
+	}
+	if len(c) != 1 || c["the"] != 1 {
+		t.Errorf("wrong continuations of bocchi: %v", c)
+	}
+	c, err = br.Continuations(ctx, "kessoku", nil)
+	if err != nil {
+		t.Fatalf("couldn't get continuations: %v", err)
+	}
+	if len(c) != 1 || c["bocchi"] != 1 {
+		t.Errorf("wrong starting continuations: %v", c)
+	}
+	if err := brain.Learn(ctx, br, "sickhack", "4", userhash.Hash{}, time.Unix(0, 0), brain.Tokens(nil, "Kikuri drinks sake, always")); err != nil {
+		t.Fatalf("couldn't learn: %v", err)
+	}
+	r, err := br.Recall(ctx, "sickhack", "4")
+	if err != nil {
+		t.Fatalf("couldn't recall: %v", err)
+	}
+	if want := "Kikuri drinks sake, always"; r != want {
+		t.Errorf("wrong recall: want %q, got %q", want, r)
+	}
+	r, err = br.Recall(ctx, "kessoku", "2")
+	if err != nil {
+		t.Fatalf("couldn't recall: %v", err)
+	}
+	if r != "" {
+		t.Errorf("recalled forgotten message: %q", r)
+	}
+	var b bytes.Buffer
+	if err := br.Backup(ctx, &b); err != nil {
+		t.Fatalf("couldn't back up: %v", err)
+	}
+	if !bytes.HasPrefix(b.Bytes(), []byte("SQLite format 3\x00")) {
+		t.Errorf("backup isn't an sqlite database: %q", b.Bytes()[:min(b.Len(), 16)])
+	}
+}
@@ -0,0 +1,89 @@
+package sqlbrain
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/zephyrtronium/robot/brain"
+)
+
+var _ brain.Undeleter = (*Brain)(nil)
+
+// onlyOp selects the messages forgotten by :op and by no other operation.
+const onlyOp = `SELECT tag, id FROM deletions AS d WHERE op = :op
+	AND NOT EXISTS (SELECT 1 FROM deletions AS o WHERE o.tag = d.tag AND o.id = d.id AND o.op != :op)`
+
+// expired selects the messages whose newest deletion records are before
+// :before.
+const expired = `SELECT tag, id FROM deletions GROUP BY tag, id HAVING MAX(time) < :before`
+
+// Undelete restores the messages and tuples forgotten by an operation which
+// haven't yet been purged. Messages which other operations also forgot stay
+// forgotten, e.g. so that undoing a timeout doesn't restore a message which a
+// moderator deleted individually.
+func (br *Brain) Undelete(ctx context.Context, op string) (n int, err error) {
+	conn, err := br.db.Take(ctx)
+	defer br.db.Put(conn)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't get connection to undelete: %w", err)
+	}
+	defer sqlitex.Transaction(conn)(&err)
+	opts := &sqlitex.ExecOptions{Named: map[string]any{":op": op}}
+	const tuples = `UPDATE knowledge SET deleted = NULL WHERE (tag, id) IN (` + onlyOp + `)`
+	if err := sqlitex.Execute(conn, tuples, opts); err != nil {
+		return 0, fmt.Errorf("couldn't restore tuples: %w", err)
+	}
+	const messages = `UPDATE messages SET deleted = NULL WHERE (tag, id) IN (` + onlyOp + `)`
+	if err := sqlitex.Execute(conn, messages, opts); err != nil {
+		return 0, fmt.Errorf("couldn't restore messages: %w", err)
+	}
+	n = conn.Changes()
+	const done = `DELETE FROM deletions WHERE op = :op`
+	if err := sqlitex.Execute(conn, done, opts); err != nil {
+		return 0, fmt.Errorf("couldn't remove deletion records: %w", err)
+	}
+	return n, nil
+}
+
+// Forgotten counts the messages forgotten by an operation which haven't yet
+// been purged.
+func (br *Brain) Forgotten(ctx context.Context, op string) (int, error) {
+	conn, err := br.db.Take(ctx)
+	defer br.db.Put(conn)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't get connection to count forgotten messages: %w", err)
+	}
+	st := conn.Prep(`SELECT COUNT(*) FROM deletions WHERE op = :op`)
+	st.SetText(":op", op)
+	n, err := sqlitex.ResultInt(st)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't count forgotten messages: %w", err)
+	}
+	return n, nil
+}
+
+// Purge removes the tuples of messages forgotten before a time entirely.
+// The messages themselves are kept, marked deleted, so that they can't be
+// learned again. Messages forgotten again since the time are kept along with
+// all their deletion records until the newest is old enough.
+func (br *Brain) Purge(ctx context.Context, before time.Time) (n int, err error) {
+	conn, err := br.db.Take(ctx)
+	defer br.db.Put(conn)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't get connection to purge: %w", err)
+	}
+	defer sqlitex.Transaction(conn)(&err)
+	opts := &sqlitex.ExecOptions{Named: map[string]any{":before": before.UnixNano()}}
+	const tuples = `DELETE FROM knowledge WHERE deleted IS NOT NULL AND (tag, id) IN (` + expired + `)`
+	if err := sqlitex.Execute(conn, tuples, opts); err != nil {
+		return 0, fmt.Errorf("couldn't purge tuples: %w", err)
+	}
+	const records = `DELETE FROM deletions WHERE (tag, id) IN (` + expired + `)`
+	if err := sqlitex.Execute(conn, records, opts); err != nil {
+		return 0, fmt.Errorf("couldn't remove deletion records: %w", err)
+	}
+	return conn.Changes(), nil
+}
@@ -0,0 +1,157 @@
+package sqlbrain_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/sqlbrain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+func TestUndelete(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(ctx)
+	br, err := sqlbrain.Open(ctx, db)
+	if err != nil {
+		t.Fatalf("couldn't open brain: %v", err)
+	}
+	msgs := []struct {
+		id, text string
+		user     userhash.Hash
+	}{
+		{"1", "bocchi the rock", userhash.Hash{1}},
+		{"2", "kita", userhash.Hash{2}},
+		{"3", "ryo", userhash.Hash{2}},
+	}
+	for _, m := range msgs {
+		err := brain.Learn(ctx, br, "kessoku", m.id, m.user, time.Unix(0, 0), brain.Tokens(nil, m.text))
+		if err != nil {
+			t.Fatalf("couldn't learn %q: %v", m.text, err)
+		}
+	}
+	stats := func() brain.Stats {
+		t.Helper()
+		st, err := br.Stats(ctx, "kessoku")
+		if err != nil {
+			t.Fatalf("couldn't get stats: %v", err)
+		}
+		return *st
+	}
+	all := stats()
+	if err := br.ForgetMessage(brain.WithOp(ctx, "op1"), "kessoku", "1"); err != nil {
+		t.Fatalf("couldn't forget message: %v", err)
+	}
+	if err := br.ForgetUser(brain.WithOp(ctx, "op2"), &userhash.Hash{2}); err != nil {
+		t.Fatalf("couldn't forget user: %v", err)
+	}
+	if got := stats(); got.Messages != 0 {
+		t.Fatalf("messages remain after forgetting: %+v", got)
+	}
+	if n, err := br.Forgotten(ctx, "op2"); err != nil || n != 2 {
+		t.Errorf("wrong count of forgotten messages: want 2, got %d (%v)", n, err)
+	}
+	n, err := br.Undelete(ctx, "op2")
+	if err != nil {
+		t.Fatalf("couldn't undelete: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("wrong number of messages undeleted: want 2, got %d", n)
+	}
+	if got, want := stats(), (brain.Stats{Tuples: 4, Messages: 2}); got != want {
+		t.Errorf("wrong stats after undelete: want %+v, got %+v", want, got)
+	}
+	if n, err := br.Undelete(ctx, "op2"); err != nil || n != 0 {
+		t.Errorf("undeleted again: %d, %v", n, err)
+	}
+	n, err = br.Purge(ctx, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("couldn't purge: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("wrong number of messages purged: want 1, got %d", n)
+	}
+	if n, err := br.Undelete(ctx, "op1"); err != nil || n != 0 {
+		t.Errorf("undeleted after purge: %d, %v", n, err)
+	}
+	if got := stats(); got.Messages != 2 || got.Tuples != 4 {
+		t.Errorf("wrong stats after purge: %+v (from %+v)", got, all)
+	}
+	// The purged message stays forgotten.
+	if err := brain.Learn(ctx, br, "kessoku", "1", userhash.Hash{1}, time.Unix(0, 0), brain.Tokens(nil, "bocchi the rock")); err != nil {
+		t.Fatalf("couldn't learn: %v", err)
+	}
+	if got := stats(); got.Messages != 2 || got.Tuples != 4 {
+		t.Errorf("relearned purged message: %+v", got)
+	}
+}
+
+func TestUndeleteOverlapping(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(ctx)
+	br, err := sqlbrain.Open(ctx, db)
+	if err != nil {
+		t.Fatalf("couldn't open brain: %v", err)
+	}
+	for _, id := range []string{"1", "2"} {
+		err := brain.Learn(ctx, br, "kessoku", id, userhash.Hash{1}, time.Unix(0, 0), brain.Tokens(nil, "bocchi the rock "+id))
+		if err != nil {
+			t.Fatalf("couldn't learn %s: %v", id, err)
+		}
+	}
+	messages := func() int64 {
+		t.Helper()
+		st, err := br.Stats(ctx, "kessoku")
+		if err != nil {
+			t.Fatalf("couldn't get stats: %v", err)
+		}
+		return st.Messages
+	}
+	// A moderator deletes one message, then a timeout forgets both.
+	if err := br.ForgetMessage(brain.WithOp(ctx, "clearmsg"), "kessoku", "1"); err != nil {
+		t.Fatalf("couldn't forget message: %v", err)
+	}
+	if err := br.ForgetUser(brain.WithOp(ctx, "timeout"), &userhash.Hash{1}); err != nil {
+		t.Fatalf("couldn't forget user: %v", err)
+	}
+	// Undoing the timeout restores only the message it alone forgot.
+	n, err := br.Undelete(ctx, "timeout")
+	if err != nil {
+		t.Fatalf("couldn't undelete: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("wrong number of messages undeleted: want 1, got %d", n)
+	}
+	if got := messages(); got != 1 {
+		t.Errorf("wrong messages after undoing timeout: want 1, got %d", got)
+	}
+	// Forget again, then purge with a window covering only the first
+	// deletion. The message is kept until its newest deletion expires.
+	time.Sleep(time.Millisecond)
+	mid := time.Now()
+	time.Sleep(time.Millisecond)
+	if err := br.ForgetUser(brain.WithOp(ctx, "ban"), &userhash.Hash{1}); err != nil {
+		t.Fatalf("couldn't forget user: %v", err)
+	}
+	if n, err := br.Purge(ctx, mid); err != nil || n != 0 {
+		t.Errorf("purged message forgotten again: %d, %v", n, err)
+	}
+	if n, err := br.Forgotten(ctx, "clearmsg"); err != nil || n != 1 {
+		t.Errorf("deletion record purged early: %d, %v", n, err)
+	}
+	n, err = br.Undelete(ctx, "ban")
+	if err != nil {
+		t.Fatalf("couldn't undelete: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("wrong number of messages undeleted after purge: want 1, got %d", n)
+	}
+	// Once everything is old enough, all of it goes.
+	if n, err := br.Purge(ctx, time.Now().Add(time.Minute)); err != nil || n != 1 {
+		t.Errorf("wrong purge: want 1, got %d (%v)", n, err)
+	}
+	if n, err := br.Undelete(ctx, "clearmsg"); err != nil || n != 0 {
+		t.Errorf("undeleted after purge: %d, %v", n, err)
+	}
+}
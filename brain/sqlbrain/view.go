@@ -0,0 +1,24 @@
+package sqlbrain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/zephyrtronium/robot/brain"
+)
+
+var _ brain.Viewer = (*Brain)(nil)
+
+// SpeakView generates a full message from the combined knowledge of a view's
+// tags, leaving out terms the view blocks. The view is computed as each term
+// is selected, so nothing is copied between tags.
+func (br *Brain) SpeakView(ctx context.Context, v *brain.View, prompt []string, w *brain.Builder) error {
+	tags, err := json.Marshal(v.Tags)
+	if err != nil {
+		return fmt.Errorf("couldn't encode view tags: %w", err)
+	}
+	vb := *br
+	vb.tags, vb.block = string(tags), v.Block
+	return vb.Speak(ctx, "", prompt, w)
+}
@@ -0,0 +1,66 @@
+package sqlbrain_test
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/sqlbrain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+func TestSpeakView(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	db := testDB(ctx)
+	br, err := sqlbrain.Open(ctx, db)
+	if err != nil {
+		t.Fatalf("couldn't open brain: %v", err)
+	}
+	msgs := []struct {
+		tag, id, text string
+	}{
+		{"kessoku", "1", "bocchi"},
+		{"sickhack", "2", "kikuri"},
+		{"sickhack", "3", "kikuri drinks"},
+		{"starry", "4", "seika"},
+	}
+	for _, m := range msgs {
+		err := brain.Learn(ctx, br, m.tag, m.id, userhash.Hash{}, time.Unix(0, 0), strings.Fields(m.text))
+		if err != nil {
+			t.Fatalf("couldn't learn %q: %v", m.text, err)
+		}
+	}
+	speak := func(v *brain.View) map[string]bool {
+		t.Helper()
+		r := make(map[string]bool)
+		for range 100 {
+			var w brain.Builder
+			if err := br.SpeakView(ctx, v, nil, &w); err != nil {
+				t.Fatalf("couldn't speak: %v", err)
+			}
+			r[w.String()] = true
+		}
+		return r
+	}
+	got := speak(&brain.View{Tags: []string{"kessoku", "sickhack"}})
+	for _, s := range []string{"bocchi", "kikuri", "kikuridrinks"} {
+		if !got[s] {
+			t.Errorf("view never said %q: %v", s, got)
+		}
+	}
+	if len(got) != 3 {
+		t.Errorf("view said something else: %v", got)
+	}
+	got = speak(&brain.View{Tags: []string{"kessoku", "sickhack"}, Block: regexp.MustCompile(`^drinks$`)})
+	if len(got) != 2 || !got["bocchi"] || !got["kikuri"] {
+		t.Errorf("wrong messages with terms blocked: %v", got)
+	}
+	got = speak(&brain.View{Tags: []string{"kessoku", "sickhack"}, Block: regexp.MustCompile(`^kikuri$`)})
+	if len(got) != 1 || !got["bocchi"] {
+		t.Errorf("wrong messages with start blocked: %v", got)
+	}
+}
@@ -0,0 +1,287 @@
+// Package tier keeps recent knowledge in memory in front of a durable brain.
+package tier
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/membrain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// Config is the configuration for a tiered brain.
+type Config struct {
+	// Window is how long messages stay in memory after they are sent.
+	Window time.Duration
+	// Recency is the probability of speaking only from recent messages in
+	// memory instead of from the durable brain.
+	Recency float64
+	// Flush is the interval at which learned messages are written to the
+	// durable brain.
+	Flush time.Duration
+	// Batch is the most messages to write to the durable brain at once.
+	// If it is not positive, all pending messages are written together.
+	Batch int
+}
+
+// Brain is a brain which learns into memory first and writes to a durable
+// brain in the background.
+//
+// Recent messages are kept in memory for a window of time, so that speaking
+// from them doesn't touch the disk. Speaking uses only the recent messages
+// with a configured probability and otherwise uses the durable brain.
+// Forgetting applies to both, including messages not yet written.
+//
+// Memory starts empty, so after a restart, recent knowledge comes only from
+// the durable brain until chat fills it again.
+type Brain struct {
+	cold brain.Brain
+	hot  *membrain.Brain
+	cfg  Config
+
+	// mu guards pending and tags.
+	mu sync.Mutex
+	// pending is the messages learned and not yet written, oldest first.
+	pending []brain.Message
+	// tags is the set of tags learned into memory.
+	tags map[string]struct{}
+	// flushing is held while writing to the durable brain so that forgetting
+	// can't miss messages in flight.
+	flushing sync.Mutex
+
+	flushed   atomic.Int64
+	failures  atomic.Int64
+	evicted   atomic.Int64
+	hotSpoken atomic.Int64
+}
+
+var (
+	_ brain.Brain   = (*Brain)(nil)
+	_ brain.Wrapper = (*Brain)(nil)
+)
+
+// New wraps a durable brain with a tier of recent messages in memory.
+func New(br brain.Brain, cfg Config) *Brain {
+	if cfg.Flush <= 0 {
+		cfg.Flush = 5 * time.Second
+	}
+	return &Brain{
+		cold: br,
+		hot:  membrain.New(),
+		cfg:  cfg,
+		tags: make(map[string]struct{}),
+	}
+}
+
+// Unwrap returns the durable brain.
+func (b *Brain) Unwrap() brain.Brain {
+	return b.cold
+}
+
+// Stats is a snapshot of a tiered brain's counters.
+type Stats struct {
+	// Pending is the number of messages not yet written to the durable brain.
+	Pending int `json:"pending"`
+	// Flushed is the number of messages written to the durable brain.
+	Flushed int64 `json:"flushed"`
+	// Failures is the number of failed attempts to write.
+	Failures int64 `json:"failures"`
+	// Evicted is the number of messages removed from memory for being
+	// outside the window.
+	Evicted int64 `json:"evicted"`
+	// HotSpoken is the number of messages spoken from memory.
+	HotSpoken int64 `json:"hot_spoken"`
+}
+
+// Stats returns a snapshot of the counters.
+func (b *Brain) Stats() Stats {
+	b.mu.Lock()
+	n := len(b.pending)
+	b.mu.Unlock()
+	return Stats{
+		Pending:   n,
+		Flushed:   b.flushed.Load(),
+		Failures:  b.failures.Load(),
+		Evicted:   b.evicted.Load(),
+		HotSpoken: b.hotSpoken.Load(),
+	}
+}
+
+// Learn records a set of tuples in memory and queues them for the durable
+// brain.
+func (b *Brain) Learn(ctx context.Context, tag, id string, user userhash.Hash, t time.Time, tuples []brain.Tuple) error {
+	if err := b.hot.Learn(ctx, tag, id, user, t, tuples); err != nil {
+		return err
+	}
+	// Callers may reuse the tuples once we return.
+	tt := make([]brain.Tuple, len(tuples))
+	for i, v := range tuples {
+		tt[i] = brain.Tuple{Prefix: slices.Clone(v.Prefix), Suffix: v.Suffix}
+	}
+	b.mu.Lock()
+	b.pending = append(b.pending, brain.Message{Tag: tag, ID: id, User: user, Time: t, Tuples: tt})
+	b.tags[tag] = struct{}{}
+	b.mu.Unlock()
+	return nil
+}
+
+// Speak generates a message from recent messages with the configured
+// probability, or from the durable brain otherwise or if memory has nothing
+// to say.
+func (b *Brain) Speak(ctx context.Context, tag string, prompt []string, w *brain.Builder) error {
+	if b.cfg.Recency > 0 && rand.Float64() < b.cfg.Recency {
+		if err := b.hot.Speak(ctx, tag, prompt, w); err != nil {
+			return err
+		}
+		if len(w.Trace()) != 0 {
+			b.hotSpoken.Add(1)
+			return nil
+		}
+	}
+	return b.cold.Speak(ctx, tag, prompt, w)
+}
+
+// ForgetMessage forgets everything learned from a single given message.
+func (b *Brain) ForgetMessage(ctx context.Context, tag, id string) error {
+	b.flushing.Lock()
+	defer b.flushing.Unlock()
+	b.drop(func(m *brain.Message) bool { return m.Tag == tag && m.ID == id })
+	if err := b.hot.ForgetMessage(ctx, tag, id); err != nil {
+		return err
+	}
+	return b.cold.ForgetMessage(ctx, tag, id)
+}
+
+// ForgetDuring forgets all messages learned in the given time span.
+func (b *Brain) ForgetDuring(ctx context.Context, tag string, since, before time.Time) error {
+	b.flushing.Lock()
+	defer b.flushing.Unlock()
+	b.drop(func(m *brain.Message) bool {
+		return m.Tag == tag && !m.Time.Before(since) && !m.Time.After(before)
+	})
+	if err := b.hot.ForgetDuring(ctx, tag, since, before); err != nil {
+		return err
+	}
+	return b.cold.ForgetDuring(ctx, tag, since, before)
+}
+
+// ForgetUser forgets all messages associated with a userhash.
+func (b *Brain) ForgetUser(ctx context.Context, user *userhash.Hash) error {
+	b.flushing.Lock()
+	defer b.flushing.Unlock()
+	u := *user
+	b.drop(func(m *brain.Message) bool { return m.User == u })
+	if err := b.hot.ForgetUser(ctx, user); err != nil {
+		return err
+	}
+	return b.cold.ForgetUser(ctx, user)
+}
+
+// drop removes pending messages matching f.
+func (b *Brain) drop(f func(m *brain.Message) bool) {
+	b.mu.Lock()
+	b.pending = slices.DeleteFunc(b.pending, func(m brain.Message) bool { return f(&m) })
+	b.mu.Unlock()
+}
+
+// Run writes learned messages to the durable brain and removes old ones from
+// memory until ctx is canceled. Before returning, it writes whatever is still
+// pending.
+func (b *Brain) Run(ctx context.Context) error {
+	t := time.NewTicker(b.cfg.Flush)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// Use a fresh context so that shutting down doesn't lose what
+			// was learned.
+			b.Flush(context.WithoutCancel(ctx))
+			return ctx.Err()
+		case <-t.C:
+			b.Flush(ctx)
+			b.evict(ctx, time.Now())
+		}
+	}
+}
+
+// Flush writes pending messages to the durable brain. Messages which fail to
+// be written stay pending to retry later.
+func (b *Brain) Flush(ctx context.Context) {
+	b.flushing.Lock()
+	defer b.flushing.Unlock()
+	for {
+		b.mu.Lock()
+		n := len(b.pending)
+		if b.cfg.Batch > 0 {
+			n = min(n, b.cfg.Batch)
+		}
+		batch := b.pending[:n:n]
+		b.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		if err := b.write(ctx, batch); err != nil {
+			b.failures.Add(1)
+			slog.WarnContext(ctx, "couldn't write recent knowledge", slog.Int("pending", n), slog.Any("err", err))
+			return
+		}
+		b.flushed.Add(int64(n))
+		b.mu.Lock()
+		// Forgetting waits for flushing, so nothing else removed from the
+		// front of pending in the meantime.
+		b.pending = slices.Delete(b.pending, 0, n)
+		b.mu.Unlock()
+	}
+}
+
+// write writes a batch of messages to the durable brain.
+func (b *Brain) write(ctx context.Context, batch []brain.Message) error {
+	if l, ok := brain.As[brain.Batcher](b.cold); ok {
+		return l.LearnBatch(ctx, batch)
+	}
+	for _, m := range batch {
+		if err := b.cold.Learn(ctx, m.Tag, m.ID, m.User, m.Time, m.Tuples); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evict removes messages older than the window from memory, as long as they
+// have been written to the durable brain.
+func (b *Brain) evict(ctx context.Context, now time.Time) {
+	if b.cfg.Window <= 0 {
+		return
+	}
+	cut := now.Add(-b.cfg.Window)
+	b.mu.Lock()
+	// Messages still pending must stay in memory, or they would be
+	// forgotten entirely if writing them fails for a while.
+	for _, m := range b.pending {
+		if m.Time.Before(cut) {
+			cut = m.Time
+		}
+	}
+	tags := make([]string, 0, len(b.tags))
+	for tag := range b.tags {
+		tags = append(tags, tag)
+	}
+	b.mu.Unlock()
+	for _, tag := range tags {
+		st, _ := b.hot.Stats(ctx, tag)
+		if err := b.hot.ForgetDuring(ctx, tag, time.Unix(0, math.MinInt64), cut.Add(-1)); err != nil {
+			slog.WarnContext(ctx, "couldn't evict old knowledge from memory", slog.String("tag", tag), slog.Any("err", err))
+			continue
+		}
+		if r, _ := b.hot.Stats(ctx, tag); st != nil && r != nil {
+			b.evicted.Add(st.Messages - r.Messages)
+		}
+	}
+}
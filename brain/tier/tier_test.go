@@ -0,0 +1,132 @@
+package tier_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/braintest"
+	"github.com/zephyrtronium/robot/brain/membrain"
+	"github.com/zephyrtronium/robot/brain/tier"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+func TestBrain(t *testing.T) {
+	// With no recency, speaking always goes to the durable brain, so the
+	// suite needs everything flushed. Flushing after every learn is the
+	// simplest way to do that.
+	braintest.Test(context.Background(), t, func(ctx context.Context) brain.Brain {
+		return &flushing{tier.New(membrain.New(), tier.Config{})}
+	})
+}
+
+// flushing is a tiered brain which writes through immediately.
+type flushing struct {
+	*tier.Brain
+}
+
+func (b *flushing) Learn(ctx context.Context, tag, id string, user userhash.Hash, t time.Time, tuples []brain.Tuple) error {
+	if err := b.Brain.Learn(ctx, tag, id, user, t, tuples); err != nil {
+		return err
+	}
+	b.Flush(ctx)
+	return nil
+}
+
+func learn(ctx context.Context, t *testing.T, br brain.Learner, tag, id string, user userhash.Hash, tm time.Time, text string) {
+	t.Helper()
+	if err := brain.Learn(ctx, br, tag, id, user, tm, brain.Tokens(nil, text)); err != nil {
+		t.Fatalf("couldn't learn %q: %v", text, err)
+	}
+}
+
+func recall(ctx context.Context, t *testing.T, br brain.Recaller, tag, id string) string {
+	t.Helper()
+	s, err := br.Recall(ctx, tag, id)
+	if err != nil {
+		t.Fatalf("couldn't recall %s: %v", id, err)
+	}
+	return s
+}
+
+func TestFlush(t *testing.T) {
+	ctx := context.Background()
+	cold := membrain.New()
+	br := tier.New(cold, tier.Config{Recency: 1, Batch: 1})
+	now := time.Now()
+	learn(ctx, t, br, "kessoku", "1", userhash.Hash{1}, now, "bocchi plays guitar")
+	learn(ctx, t, br, "kessoku", "2", userhash.Hash{2}, now, "nijika plays drums")
+	learn(ctx, t, br, "kessoku", "3", userhash.Hash{3}, now, "ryo plays bass")
+	if s := recall(ctx, t, cold, "kessoku", "1"); s != "" {
+		t.Errorf("message written before flushing: %q", s)
+	}
+	// Speaking with full recency uses memory.
+	s, _, err := brain.Speak(ctx, br, "kessoku", "bocchi")
+	if err != nil {
+		t.Fatalf("couldn't speak: %v", err)
+	}
+	if s != "bocchi plays guitar" {
+		t.Errorf("wrong speech from memory: %q", s)
+	}
+	// Forgetting a pending message means it is never written.
+	if err := br.ForgetMessage(ctx, "kessoku", "2"); err != nil {
+		t.Fatalf("couldn't forget: %v", err)
+	}
+	if err := br.ForgetUser(ctx, &userhash.Hash{3}); err != nil {
+		t.Fatalf("couldn't forget user: %v", err)
+	}
+	if st := br.Stats(); st.Pending != 1 {
+		t.Errorf("wrong pending count after forgetting: %+v", st)
+	}
+	br.Flush(ctx)
+	if s := recall(ctx, t, cold, "kessoku", "1"); s != "bocchi plays guitar" {
+		t.Errorf("wrong message after flushing: %q", s)
+	}
+	for _, id := range []string{"2", "3"} {
+		if s := recall(ctx, t, cold, "kessoku", id); s != "" {
+			t.Errorf("forgotten message %s written: %q", id, s)
+		}
+	}
+	if st := br.Stats(); st.Pending != 0 || st.Flushed != 1 {
+		t.Errorf("wrong stats after flushing: %+v", st)
+	}
+	// Forgetting after flushing reaches the durable brain.
+	if err := br.ForgetDuring(ctx, "kessoku", now.Add(-time.Second), now.Add(time.Second)); err != nil {
+		t.Fatalf("couldn't forget during: %v", err)
+	}
+	if s := recall(ctx, t, cold, "kessoku", "1"); s != "" {
+		t.Errorf("message not forgotten after flushing: %q", s)
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cold := membrain.New()
+	br := tier.New(cold, tier.Config{Window: time.Millisecond, Recency: 1, Flush: time.Millisecond})
+	learn(ctx, t, br, "sickhack", "1", userhash.Hash{1}, time.Now().Add(-time.Hour), "kikuri drinks sake")
+	done := make(chan error, 1)
+	go func() { done <- br.Run(ctx) }()
+	// Wait for the old message to be written and evicted from memory.
+	for br.Stats().Evicted == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if s := recall(ctx, t, cold, "sickhack", "1"); s != "kikuri drinks sake" {
+		t.Errorf("wrong message after running: %q", s)
+	}
+	// Memory has nothing, so speaking falls back to the durable brain.
+	s, _, err := brain.Speak(ctx, br, "sickhack", "")
+	if err != nil {
+		t.Fatalf("couldn't speak: %v", err)
+	}
+	if s != "kikuri drinks sake" {
+		t.Errorf("wrong speech after eviction: %q", s)
+	}
+	// Anything pending at shutdown is written.
+	learn(ctx, t, br, "sickhack", "2", userhash.Hash{1}, time.Now(), "seika runs starry")
+	cancel()
+	<-done
+	if s := recall(context.Background(), t, cold, "sickhack", "2"); s != "seika runs starry" {
+		t.Errorf("wrong message after shutdown: %q", s)
+	}
+}
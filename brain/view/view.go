@@ -0,0 +1,69 @@
+// Package view speaks from tags composed of other tags' knowledge.
+package view
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// Brain is a brain which speaks from views under their names as tags.
+// Everything else, including speaking from other tags, goes to the wrapped
+// brain.
+type Brain struct {
+	br    brain.Brain
+	vw    brain.Viewer
+	views map[string]*brain.View
+}
+
+var (
+	_ brain.Brain   = (*Brain)(nil)
+	_ brain.Wrapper = (*Brain)(nil)
+)
+
+// New wraps a brain to speak from views by name.
+// It returns an error if the brain can't speak from views.
+func New(br brain.Brain, views map[string]*brain.View) (*Brain, error) {
+	vw, ok := brain.As[brain.Viewer](br)
+	if !ok {
+		return nil, errors.New("brain does not support views")
+	}
+	return &Brain{br: br, vw: vw, views: views}, nil
+}
+
+// Unwrap returns the underlying brain.
+func (b *Brain) Unwrap() brain.Brain {
+	return b.br
+}
+
+// Learn records a set of tuples.
+func (b *Brain) Learn(ctx context.Context, tag, id string, user userhash.Hash, t time.Time, tuples []brain.Tuple) error {
+	return b.br.Learn(ctx, tag, id, user, t, tuples)
+}
+
+// Speak generates a message from the view named by tag, or from the tag itself
+// if there is no such view.
+func (b *Brain) Speak(ctx context.Context, tag string, prompt []string, w *brain.Builder) error {
+	if v := b.views[tag]; v != nil {
+		return b.vw.SpeakView(ctx, v, prompt, w)
+	}
+	return b.br.Speak(ctx, tag, prompt, w)
+}
+
+// ForgetMessage forgets everything learned from a single given message.
+func (b *Brain) ForgetMessage(ctx context.Context, tag, id string) error {
+	return b.br.ForgetMessage(ctx, tag, id)
+}
+
+// ForgetDuring forgets all messages learned in the given time span.
+func (b *Brain) ForgetDuring(ctx context.Context, tag string, since, before time.Time) error {
+	return b.br.ForgetDuring(ctx, tag, since, before)
+}
+
+// ForgetUser forgets all messages associated with a userhash.
+func (b *Brain) ForgetUser(ctx context.Context, user *userhash.Hash) error {
+	return b.br.ForgetUser(ctx, user)
+}
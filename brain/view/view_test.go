@@ -0,0 +1,52 @@
+package view_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/membrain"
+	"github.com/zephyrtronium/robot/brain/view"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// viewer is a brain which speaks from a view by listing its tags.
+type viewer struct {
+	*membrain.Brain
+}
+
+func (viewer) SpeakView(ctx context.Context, v *brain.View, prompt []string, w *brain.Builder) error {
+	w.Append("view", []byte(strings.Join(v.Tags, "+")))
+	return nil
+}
+
+func TestSpeak(t *testing.T) {
+	ctx := context.Background()
+	br := viewer{membrain.New()}
+	if err := brain.Learn(ctx, br, "kessoku", "1", userhash.Hash{}, time.Unix(0, 0), brain.Tokens(nil, "bocchi")); err != nil {
+		t.Fatalf("couldn't learn: %v", err)
+	}
+	b, err := view.New(br, map[string]*brain.View{"band": {Tags: []string{"kessoku", "sickhack"}}})
+	if err != nil {
+		t.Fatalf("couldn't wrap brain: %v", err)
+	}
+	s, _, err := brain.Speak(ctx, b, "band", "")
+	if err != nil {
+		t.Fatalf("couldn't speak from view: %v", err)
+	}
+	if want := "kessoku+sickhack"; s != want {
+		t.Errorf("wrong message from view: want %q, got %q", want, s)
+	}
+	s, _, err = brain.Speak(ctx, b, "kessoku", "")
+	if err != nil {
+		t.Fatalf("couldn't speak from tag: %v", err)
+	}
+	if want := "bocchi"; s != want {
+		t.Errorf("wrong message from tag: want %q, got %q", want, s)
+	}
+	if _, err := view.New(membrain.New(), nil); err == nil {
+		t.Error("wrapped brain without views")
+	}
+}
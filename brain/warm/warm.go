@@ -0,0 +1,292 @@
+// Package warm keeps pools of messages generated ahead of time so that random
+// responses don't wait on the brain.
+package warm
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// Message is a message generated ahead of time.
+type Message struct {
+	// Text is the generated text.
+	Text string
+	// Trace is the IDs of the messages used to generate the text.
+	Trace []string
+	// Time is the time at which the message was generated.
+	Time time.Time
+	// Cost is the time it took to generate the message.
+	Cost time.Duration
+}
+
+// Brain is a brain which generates unprompted messages in the background for
+// each tag which has been asked for them.
+//
+// Learning under a tag replaces its oldest message with a new one, so that
+// pools follow what the brain learns. Forgetting anything discards the
+// affected pools entirely, so that cached messages never contain forgotten
+// knowledge.
+type Brain struct {
+	br   brain.Brain
+	size int
+	ttl  time.Duration
+
+	// mu guards pools.
+	mu sync.Mutex
+	// pools is the messages for each tag.
+	pools map[string]*pool
+	// wake signals the fill loop.
+	wake chan struct{}
+
+	hits      atomic.Int64
+	misses    atomic.Int64
+	generated atomic.Int64
+	discarded atomic.Int64
+}
+
+// pool is the messages generated for a tag.
+type pool struct {
+	// msgs is the messages, oldest first.
+	msgs []Message
+	// learned indicates that the brain has learned under the tag since the
+	// pool was last filled.
+	learned bool
+	// epoch counts times the pool has been discarded, so that messages
+	// generated before forgetting are not added after it.
+	epoch uint64
+}
+
+var (
+	_ brain.Brain   = (*Brain)(nil)
+	_ brain.Wrapper = (*Brain)(nil)
+)
+
+// New wraps a brain with pools of size messages per tag. Messages older than
+// ttl are never used. If ttl is zero, messages never become stale.
+func New(br brain.Brain, size int, ttl time.Duration) *Brain {
+	return &Brain{
+		br:    br,
+		size:  size,
+		ttl:   ttl,
+		pools: make(map[string]*pool),
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// Unwrap returns the underlying brain.
+func (b *Brain) Unwrap() brain.Brain {
+	return b.br
+}
+
+// Stats is a snapshot of a warm brain's counters.
+type Stats struct {
+	// Hits is the number of times a cached message was available.
+	Hits int64 `json:"hits"`
+	// Misses is the number of times no cached message was available.
+	Misses int64 `json:"misses"`
+	// Generated is the number of messages generated for pools.
+	Generated int64 `json:"generated"`
+	// Discarded is the number of messages dropped for being stale or
+	// replaced after learning or forgetting.
+	Discarded int64 `json:"discarded"`
+}
+
+// Stats returns a snapshot of the counters.
+func (b *Brain) Stats() Stats {
+	return Stats{
+		Hits:      b.hits.Load(),
+		Misses:    b.misses.Load(),
+		Generated: b.generated.Load(),
+		Discarded: b.discarded.Load(),
+	}
+}
+
+// Take removes and returns the newest fresh message for a tag.
+// If there is none, the result is false, and the pool for the tag is filled
+// in the background.
+func (b *Brain) Take(tag string, now time.Time) (Message, bool) {
+	defer b.signal()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p := b.pools[tag]
+	if p == nil {
+		b.pools[tag] = new(pool)
+		b.misses.Add(1)
+		return Message{}, false
+	}
+	b.expireLocked(p, now)
+	if len(p.msgs) == 0 {
+		b.misses.Add(1)
+		return Message{}, false
+	}
+	k := len(p.msgs) - 1
+	m := p.msgs[k]
+	p.msgs[k] = Message{}
+	p.msgs = p.msgs[:k]
+	b.hits.Add(1)
+	return m, true
+}
+
+// expireLocked drops stale messages from a pool.
+func (b *Brain) expireLocked(p *pool, now time.Time) {
+	if b.ttl <= 0 {
+		return
+	}
+	k := 0
+	for k < len(p.msgs) && now.Sub(p.msgs[k].Time) >= b.ttl {
+		k++
+	}
+	if k == 0 {
+		return
+	}
+	b.discarded.Add(int64(k))
+	n := copy(p.msgs, p.msgs[k:])
+	clear(p.msgs[n:])
+	p.msgs = p.msgs[:n]
+}
+
+// signal wakes the fill loop if it is waiting.
+func (b *Brain) signal() {
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run fills pools in the background until ctx is canceled.
+func (b *Brain) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-b.wake:
+			b.fill(ctx)
+		}
+	}
+}
+
+// fill generates messages for pools which need them.
+func (b *Brain) fill(ctx context.Context) {
+	b.mu.Lock()
+	tags := make([]string, 0, len(b.pools))
+	for tag := range b.pools {
+		tags = append(tags, tag)
+	}
+	b.mu.Unlock()
+	for _, tag := range tags {
+		for ctx.Err() == nil {
+			epoch, ok := b.want(tag)
+			if !ok {
+				break
+			}
+			start := time.Now()
+			m, trace, err := brain.Speak(ctx, b.br, tag, "")
+			if err != nil {
+				slog.WarnContext(ctx, "couldn't generate ahead", slog.String("tag", tag), slog.Any("err", err))
+				return
+			}
+			if m == "" {
+				// Nothing to say under this tag yet.
+				break
+			}
+			b.add(tag, epoch, Message{Text: m, Trace: trace, Time: time.Now(), Cost: time.Since(start)})
+		}
+	}
+}
+
+// want reports whether a pool needs a new message and its current epoch.
+func (b *Brain) want(tag string) (uint64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p := b.pools[tag]
+	if p == nil {
+		return 0, false
+	}
+	b.expireLocked(p, time.Now())
+	return p.epoch, len(p.msgs) < b.size || p.learned
+}
+
+// add adds a message to a pool unless the pool was discarded since its epoch.
+func (b *Brain) add(tag string, epoch uint64, m Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p := b.pools[tag]
+	if p == nil || p.epoch != epoch {
+		b.discarded.Add(1)
+		return
+	}
+	b.generated.Add(1)
+	p.learned = false
+	if len(p.msgs) >= b.size {
+		// Replace the oldest message with the new one.
+		b.discarded.Add(1)
+		n := copy(p.msgs, p.msgs[1:])
+		p.msgs = p.msgs[:n]
+	}
+	p.msgs = append(p.msgs, m)
+}
+
+// discard drops the messages for a tag, or for every tag if all is true.
+func (b *Brain) discard(tag string, all bool) {
+	b.mu.Lock()
+	for t, p := range b.pools {
+		if !all && t != tag {
+			continue
+		}
+		b.discarded.Add(int64(len(p.msgs)))
+		clear(p.msgs)
+		p.msgs = p.msgs[:0]
+		p.epoch++
+	}
+	b.mu.Unlock()
+	b.signal()
+}
+
+// Learn records a set of tuples and marks the tag's pool for refreshing.
+func (b *Brain) Learn(ctx context.Context, tag, id string, user userhash.Hash, t time.Time, tuples []brain.Tuple) error {
+	if err := b.br.Learn(ctx, tag, id, user, t, tuples); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	p := b.pools[tag]
+	if p != nil {
+		p.learned = true
+	}
+	b.mu.Unlock()
+	if p != nil {
+		b.signal()
+	}
+	return nil
+}
+
+// Speak generates a message directly from the underlying brain.
+func (b *Brain) Speak(ctx context.Context, tag string, prompt []string, w *brain.Builder) error {
+	return b.br.Speak(ctx, tag, prompt, w)
+}
+
+// ForgetMessage forgets everything learned from a single given message and
+// discards the tag's pool.
+func (b *Brain) ForgetMessage(ctx context.Context, tag, id string) error {
+	defer b.discard(tag, false)
+	return b.br.ForgetMessage(ctx, tag, id)
+}
+
+// ForgetDuring forgets all messages learned in the given time span and
+// discards the tag's pool.
+func (b *Brain) ForgetDuring(ctx context.Context, tag string, since, before time.Time) error {
+	defer b.discard(tag, false)
+	return b.br.ForgetDuring(ctx, tag, since, before)
+}
+
+// ForgetUser forgets all messages associated with a userhash and discards
+// every pool, since users are not associated with tags.
+func (b *Brain) ForgetUser(ctx context.Context, user *userhash.Hash) error {
+	defer b.discard("", true)
+	return b.br.ForgetUser(ctx, user)
+}
@@ -0,0 +1,75 @@
+package warm_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/membrain"
+	"github.com/zephyrtronium/robot/brain/warm"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// await takes from a pool until a message is available.
+func await(t *testing.T, b *warm.Brain, tag string) warm.Message {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if m, ok := b.Take(tag, time.Now()); ok {
+			return m
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("no message generated")
+	panic("unreachable")
+}
+
+func TestTake(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := warm.New(membrain.New(), 3, time.Hour)
+	go b.Run(ctx)
+	if err := brain.Learn(ctx, b, "kessoku", "1", userhash.Hash{}, time.Now(), brain.Tokens(nil, "bocchi the rock")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.Take("kessoku", time.Now()); ok {
+		t.Error("message available before asking for any")
+	}
+	m := await(t, b, "kessoku")
+	if m.Text != "bocchi the rock" {
+		t.Errorf("wrong message: want %q, got %q", "bocchi the rock", m.Text)
+	}
+	if len(m.Trace) != 1 || m.Trace[0] != "1" {
+		t.Errorf("wrong trace: want [1], got %q", m.Trace)
+	}
+	// Stale messages are never used.
+	await(t, b, "kessoku")
+	if _, ok := b.Take("kessoku", time.Now().Add(2*time.Hour)); ok {
+		t.Error("took stale message")
+	}
+	st := b.Stats()
+	if st.Hits < 2 || st.Misses < 2 || st.Generated < 2 {
+		t.Errorf("wrong stats: %+v", st)
+	}
+}
+
+func TestForget(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := warm.New(membrain.New(), 3, 0)
+	go b.Run(ctx)
+	if err := brain.Learn(ctx, b, "kessoku", "1", userhash.Hash{}, time.Now(), brain.Tokens(nil, "bocchi the rock")); err != nil {
+		t.Fatal(err)
+	}
+	b.Take("kessoku", time.Now())
+	await(t, b, "kessoku")
+	if err := b.ForgetMessage(ctx, "kessoku", "1"); err != nil {
+		t.Fatal(err)
+	}
+	// Nothing remains to generate, so nothing can be available.
+	time.Sleep(10 * time.Millisecond)
+	if m, ok := b.Take("kessoku", time.Now()); ok {
+		t.Errorf("took message %q after forgetting", m.Text)
+	}
+}
@@ -25,6 +25,12 @@ func Tokens(dst []string, msg string) []string {
 		// Then, skip remaining spaces and repeat.
 		c, l := utf8.DecodeRuneInString(msg)
 		switch {
+		case c == utf8.RuneError && l == 1:
+			// Invalid UTF-8. The replacement character is a symbol, but we
+			// don't want raw bytes in terms, where they could collide with
+			// the sentinels brains use in their keys.
+			msg = msg[l:]
+			continue
 		case c == '@':
 			// Since we're at the start of a token, treat this as a letter or
 			// number so it combines with a subsequent username.
@@ -46,7 +52,7 @@ func Tokens(dst []string, msg string) []string {
 		case unicode.Is(syms, c):
 			for l < len(msg) {
 				c, k := utf8.DecodeRuneInString(msg[l:])
-				if !unicode.Is(syms, c) {
+				if !unicode.Is(syms, c) || c == utf8.RuneError && k == 1 {
 					break
 				}
 				l += k
@@ -53,6 +53,11 @@ func TestWords(t *testing.T) {
 			msg:  "@bocchi ryo",
 			want: s("@bocchi ", "ryo "),
 		},
+		{
+			name: "invalid",
+			msg:  "bocchi \xff\xffryo !\xff",
+			want: s("bocchi ", "ryo ", "! "),
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/template"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/zephyrtronium/robot/command"
+	"github.com/zephyrtronium/robot/message"
+	"github.com/zephyrtronium/robot/platform"
+)
+
+// bridgeLink relays chat from one channel to another.
+type bridgeLink struct {
+	// name is the name of the bridge configuration.
+	name string
+	// to is the name of the channel to which to relay.
+	to string
+	// attr formats relayed messages.
+	attr *template.Template
+}
+
+// bridgeAttribution is the attribution format for bridges that don't set one.
+const bridgeAttribution = `[{{.Platform}}] {{.Name}}: {{.Text}}`
+
+// SetBridges configures relaying chat between channels.
+// It must be called after all channels are configured.
+func (robo *Robot) SetBridges(bridges map[string]*BridgeCfg) error {
+	robo.bridges = make(map[string][]bridgeLink)
+	for nm, cfg := range bridges {
+		if len(cfg.Channels) < 2 {
+			return fmt.Errorf("bridge.%s needs at least two channels", nm)
+		}
+		for _, p := range cfg.Channels {
+			if _, ok := robo.channels.Load(p); !ok {
+				return fmt.Errorf("bridge.%s has unconfigured channel %s", nm, p)
+			}
+		}
+		attr := cfg.Attribution
+		if attr == "" {
+			attr = bridgeAttribution
+		}
+		t, err := template.New(nm).Parse(attr)
+		if err != nil {
+			return fmt.Errorf("bad attribution for bridge.%s: %w", nm, err)
+		}
+		for _, from := range cfg.Channels {
+			for _, to := range cfg.Channels {
+				if from == to {
+					continue
+				}
+				robo.bridges[from] = append(robo.bridges[from], bridgeLink{name: nm, to: to, attr: t})
+			}
+		}
+	}
+	return nil
+}
+
+// relay sends a message to the channels bridged to its channel.
+// Relayed messages go through the destination channels' works, so they share
+// ordering with everything else the bot says there, and they must pass the
+// destination's block expression, profanity policy, classifier, and rate
+// limit like any other message the bot sends.
+func (robo *Robot) relay(ctx context.Context, group *errgroup.Group, c platform.Client, m *message.Incoming) {
+	links := robo.bridges[m.To]
+	if len(links) == 0 {
+		return
+	}
+	// Never relay the bot's own messages. Those include messages relayed
+	// from other channels, so this prevents loops.
+	if id, _ := c.Bot(); m.Sender == id {
+		return
+	}
+	data := struct {
+		Platform, Name, Text string
+	}{c.Platform(), m.Name, m.Text}
+	for _, l := range links {
+		to, _ := robo.channels.Load(l.to)
+		if to == nil || to.Halted.Load() {
+			continue
+		}
+		var b strings.Builder
+		if err := l.attr.Execute(&b, &data); err != nil {
+			slog.ErrorContext(ctx, "couldn't format relayed message", slog.Any("err", err), slog.String("bridge", l.name))
+			continue
+		}
+		text := b.String()
+		work := func(ctx context.Context) {
+			if to.Block.MatchString(text) || !to.Profanity.Sendable(text) {
+				slog.InfoContext(ctx, "won't relay blocked message", slog.String("bridge", l.name), slog.String("to", to.Name), slog.String("text", text))
+				return
+			}
+			if !command.Classify(ctx, to, text) {
+				return
+			}
+			if _, err := to.Reserve(time.Now()); err != nil {
+				slog.InfoContext(ctx, "won't relay", slog.String("bridge", l.name), slog.String("to", to.Name), slog.String("err", err.Error()))
+				return
+			}
+			slog.DebugContext(ctx, "relay", slog.String("bridge", l.name), slog.String("from", m.To), slog.String("to", to.Name))
+			to.Message(ctx, "", text)
+		}
+		robo.enqueueDroppable(ctx, group, to, work)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"testing"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
+
+	"github.com/zephyrtronium/robot/channel"
+	"github.com/zephyrtronium/robot/message"
+	"github.com/zephyrtronium/robot/platform"
+)
+
+// testClient is a platform client that does nothing.
+type testClient struct{}
+
+func (testClient) Platform() string                            { return "test" }
+func (testClient) Bot() (id, name string)                      { return "bot", "Bocchi" }
+func (testClient) Owner() string                               { return "" }
+func (testClient) Capabilities() platform.Capabilities         { return platform.Capabilities{} }
+func (testClient) Run(context.Context, platform.Handler) error { return nil }
+func (testClient) Send(ctx context.Context, channel, reply, text string) error {
+	return nil
+}
+
+func TestRelay(t *testing.T) {
+	ctx := context.Background()
+	robo := New(4)
+	var mu sync.Mutex
+	got := make(map[string][]string)
+	for _, nm := range []string{"#bocchi", "#ryou", "#nijika"} {
+		ch := &channel.Channel{
+			Name:  nm,
+			Block: regexp.MustCompile(`$^`),
+			Rate:  rate.NewLimiter(rate.Inf, 1),
+		}
+		ch.Message = func(ctx context.Context, reply, text string) {
+			mu.Lock()
+			got[nm] = append(got[nm], text)
+			mu.Unlock()
+		}
+		robo.channels.Store(nm, ch)
+	}
+	bridges := map[string]*BridgeCfg{
+		"kessoku": {Channels: []string{"#bocchi", "#ryou"}, Attribution: "<{{.Name}}> {{.Text}}"},
+	}
+	if err := robo.SetBridges(bridges); err != nil {
+		t.Fatal(err)
+	}
+	var group errgroup.Group
+	msgs := []message.Incoming{
+		{To: "#bocchi", Sender: "1", Name: "kita", Text: "ikuyo"},
+		{To: "#ryou", Sender: "2", Name: "ryou", Text: "money"},
+		{To: "#ryou", Sender: "bot", Name: "Bocchi", Text: "<kita> ikuyo"},
+		{To: "#nijika", Sender: "3", Name: "nijika", Text: "drums"},
+	}
+	for i := range msgs {
+		robo.relay(ctx, &group, testClient{}, &msgs[i])
+	}
+	group.Wait()
+	want := map[string][]string{
+		"#ryou":   {"<kita> ikuyo"},
+		"#bocchi": {"<ryou> money"},
+	}
+	if len(got) != len(want) {
+		t.Errorf("wrong channels relayed to: want %v, got %v", want, got)
+	}
+	for nm, w := range want {
+		g := got[nm]
+		if len(g) != len(w) || g[0] != w[0] {
+			t.Errorf("wrong relays to %s: want %q, got %q", nm, w, g)
+		}
+	}
+}
+
+func TestRelayFiltered(t *testing.T) {
+	ctx := context.Background()
+	robo := New(4)
+	var mu sync.Mutex
+	var got []string
+	robo.channels.Store("#bocchi", &channel.Channel{
+		Name:  "#bocchi",
+		Block: regexp.MustCompile(`$^`),
+		Rate:  rate.NewLimiter(rate.Inf, 1),
+	})
+	ryou := &channel.Channel{
+		Name:  "#ryou",
+		Block: regexp.MustCompile(`(?i)\bmoney\b`),
+		Rate:  rate.NewLimiter(0, 1),
+	}
+	ryou.Message = func(ctx context.Context, reply, text string) {
+		mu.Lock()
+		got = append(got, text)
+		mu.Unlock()
+	}
+	robo.channels.Store("#ryou", ryou)
+	bridges := map[string]*BridgeCfg{
+		"kessoku": {Channels: []string{"#bocchi", "#ryou"}, Attribution: "<{{.Name}}> {{.Text}}"},
+	}
+	if err := robo.SetBridges(bridges); err != nil {
+		t.Fatal(err)
+	}
+	var group errgroup.Group
+	msgs := []message.Incoming{
+		// Blocked in #ryou, so it doesn't use the rate limit.
+		{To: "#bocchi", Sender: "1", Name: "kita", Text: "lend me money"},
+		{To: "#bocchi", Sender: "1", Name: "kita", Text: "ikuyo"},
+		// The rate limit allows only one message ever.
+		{To: "#bocchi", Sender: "1", Name: "kita", Text: "kessoku band"},
+	}
+	for i := range msgs {
+		robo.relay(ctx, &group, testClient{}, &msgs[i])
+		group.Wait()
+	}
+	want := []string{"<kita> ikuyo"}
+	if len(got) != len(want) || got[0] != want[0] {
+		t.Errorf("wrong relays: want %q, got %q", want, got)
+	}
+}
+
+func TestSetBridgesErrors(t *testing.T) {
+	robo := New(1)
+	robo.channels.Store("#bocchi", &channel.Channel{Name: "#bocchi"})
+	robo.channels.Store("#ryou", &channel.Channel{Name: "#ryou"})
+	cases := []struct {
+		name string
+		cfg  BridgeCfg
+	}{
+		{"one", BridgeCfg{Channels: []string{"#bocchi"}}},
+		{"unknown", BridgeCfg{Channels: []string{"#bocchi", "#kita"}}},
+		{"template", BridgeCfg{Channels: []string{"#bocchi", "#ryou"}, Attribution: "{{.Name"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := robo.SetBridges(map[string]*BridgeCfg{"kessoku": &c.cfg}); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
@@ -2,12 +2,22 @@ package channel
 
 import (
 	"context"
+	"fmt"
 	"regexp"
+	"slices"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"gitlab.com/zephyrtronium/pick"
 	"golang.org/x/time/rate"
+
+	"github.com/zephyrtronium/robot/classify"
+	"github.com/zephyrtronium/robot/fault"
+	"github.com/zephyrtronium/robot/locale"
+	"github.com/zephyrtronium/robot/overlay"
+	"github.com/zephyrtronium/robot/profanity"
+	"github.com/zephyrtronium/robot/tts"
 )
 
 type Channel struct {
@@ -17,12 +27,28 @@ type Channel struct {
 	Message func(ctx context.Context, reply, text string)
 	// Learn and Send are the channel tags.
 	Learn, Send string
+	// Tags is the list of other tags from which moderators may have the bot
+	// speak in the channel.
+	Tags []string
 	// Block is a regex that matches messages which should not be used for
 	// learning.
 	Block *regexp.Regexp
+	// Forbidden is the prompts which the bot refuses to continue in the
+	// channel. It is nil if every prompt is allowed.
+	Forbidden *Forbidden
+	// Profanity is the limits on profanity in learned and sent messages.
+	// It is nil if the channel doesn't rate profanity.
+	Profanity *profanity.Policy
 	// Responses is the probability that a received message will trigger a
 	// random response.
 	Responses float64
+	// Context is the number of most recent messages from which a random
+	// response may take its prompt. If it is zero, random responses are
+	// always unprompted.
+	Context int
+	// ContextProb is the probability that a random response takes its prompt
+	// from recent messages.
+	ContextProb float64
 	// Rate is the rate limiter for messages. Attempts to speak in excess of
 	// the rate limit are dropped.
 	Rate *rate.Limiter
@@ -30,18 +56,158 @@ type Channel struct {
 	Ignore map[string]bool
 	// Mod is the set of designated moderators' user IDs.
 	Mod map[string]bool
+	// Ops is the set of designated operators' user IDs.
+	Ops map[string]bool
+	// Roles is the channel's moderators and VIPs as synced from the platform.
+	// Synced moderators have moderator privileges.
+	Roles *Roles
+	// VIPMod gives synced VIPs moderator privileges.
+	VIPMod bool
+	// Events is the set of platform events, like raid, which the bot
+	// acknowledges in chat.
+	Events map[string]bool
+	// Replies is the framings for generated replies to users who address
+	// the bot.
+	Replies Replies
+	// Pace is the target time between a message and the bot's reply.
+	Pace Pace
+	// Latency tracks the time the bot takes to reply to messages.
+	// It may be nil.
+	Latency *Latency
 	// History is a list of recent messages seen in the channel.
 	// Note that messages which are forgotten due to moderation are not removed
 	// from this list in general.
 	History *History
+	// Speakers is the list of recent opted-in chatters who may be called out
+	// in random responses. It is nil if callouts are disabled.
+	Speakers *Speakers
+	// Callouts is the probability that a random response calls out a recent
+	// chatter.
+	Callouts float64
 	// Memery is the meme detector for the channel.
 	Memery *MemeDetector
-	// Emotes is the distribution of emotes.
-	Emotes *pick.Dist[string]
+	// Outputs tracks generated messages recently sent by every channel which
+	// shares the channel's send tag. It is nil if identical messages aren't
+	// suppressed.
+	Outputs *Outputs
+	// LearnLimit limits the messages learned from each user. It is nil if
+	// there is no limit.
+	LearnLimit *LearnLimit
+	// Emotes is the distribution of emotes, which may change at runtime.
+	Emotes *Emotes
+	// EmoteBlend is the fraction of emote weight derived from the EmoteTop
+	// emotes most used in chat. If it is zero, emote weights are only those
+	// configured.
+	EmoteBlend float64
+	EmoteTop   int
+	// EmoteServices is the weight of each emote from third-party emote
+	// services by service, bttv or 7tv. It is nil if the channel uses none.
+	EmoteServices map[string]int
 	// Effects is the distribution of effects.
 	Effects *pick.Dist[string]
+	// Personality shapes how the bot speaks in the channel.
+	Personality Personality
+	// Classify decides whether generated messages may be sent.
+	// It is nil if the channel doesn't classify messages.
+	Classify *classify.Policy
+	// Engagement tracks responses to the bot's random messages.
+	// It is nil if engagement tracking is disabled.
+	Engagement *Engagement
+	// Leaderboard enables counting learned messages from users who opt in.
+	Leaderboard bool
+	// Location is the channel's time zone, which decides the boundaries of
+	// days for daily stats. If it is nil, the local time zone is used.
+	Location *time.Location
+	// Learned counts messages learned in the channel today.
+	Learned Daily
+	// Counts is running totals of messages learned, generated, and filtered.
+	Counts Counts
+	// Spoke is the time in Unix milliseconds at which the bot last sent a
+	// message to the channel.
+	Spoke atomic.Int64
+	// Speech is the feed of generated messages spoken on stream.
+	// It is nil if text-to-speech is disabled for the channel.
+	Speech *tts.Feed
+	// Overlay distributes the bot's messages to stream overlays.
+	Overlay *overlay.Hub
+	// Templates is the set of fixed responses for commands.
+	Templates *locale.Templates
+	// Prefix is a prefix which invokes commands in addition to addressing the
+	// bot by name. If it is empty, only addressing the bot invokes commands.
+	Prefix string
+	// NoCommands disables commands in the channel.
+	NoCommands bool
+	// Fallback is what to do when asked to speak but the brain generates
+	// nothing.
+	Fallback Fallback
+	// Limits is the channel's restrictions on commands by name.
+	Limits map[string]Limit
 	// Extra is extra channel data that may be added by commands.
 	Extra sync.Map // map[any]any; key is a type
 	// Enabled indicates whether a channel is allowed to learn messages.
 	Enabled atomic.Bool
+	// Game is the game or category the channel is streaming, if known.
+	Game atomic.Pointer[string]
+	// Suspend is the chat restrictions which suspend learning.
+	Suspend Suspend
+	// Suspended indicates that learning is suspended because of restrictions
+	// on chat in the channel.
+	Suspended atomic.Bool
+	// Panics tracks panics while handling messages in the channel.
+	Panics *Failures
+	// Halted indicates that the channel has stopped handling chat messages
+	// because of repeated panics. Moderation events are still handled.
+	Halted atomic.Bool
+}
+
+// Limit is a channel's restrictions on a command.
+type Limit struct {
+	// Off disables the command in the channel.
+	Off bool
+	// Cooldown and UserCooldown replace the command's cooldowns in the
+	// channel and for each user in the channel if they are positive.
+	Cooldown, UserCooldown time.Duration
+}
+
+// Fallback is a response to generating nothing when asked to speak.
+type Fallback int
+
+const (
+	// FallbackSilent sends nothing.
+	FallbackSilent Fallback = iota
+	// FallbackTemplate sends the speak-empty response.
+	FallbackTemplate
+	// FallbackUnprompted generates again without the prompt. If that also
+	// generates nothing, the bot stays silent.
+	FallbackUnprompted
+)
+
+// In returns t in the channel's time zone.
+func (ch *Channel) In(t time.Time) time.Time {
+	if ch.Location == nil {
+		return t.Local()
+	}
+	return t.In(ch.Location)
+}
+
+// Reserve takes a message from the channel's rate limit at now.
+// If the limit is exhausted, it returns an error wrapping
+// [fault.ErrRateLimited] and takes nothing. Otherwise, the caller may cancel
+// the returned reservation if it decides not to send the message after all.
+func (ch *Channel) Reserve(now time.Time) (*rate.Reservation, error) {
+	r := ch.Rate.ReserveN(now, 1)
+	if d := r.DelayFrom(now); d > 0 {
+		r.CancelAt(now)
+		return nil, fmt.Errorf("%w: next message in %v", fault.ErrRateLimited, d)
+	}
+	return r, nil
+}
+
+// UseTag checks whether moderators may have the bot speak from a tag in the
+// channel. If not, it returns an error wrapping [fault.ErrNotPermitted].
+func (ch *Channel) UseTag(tag string) error {
+	if !slices.Contains(ch.Tags, tag) {
+		return fmt.Errorf("%w: tag %q in %s", fault.ErrNotPermitted, tag, ch.Name)
+	}
+	return nil
 }
@@ -0,0 +1,62 @@
+package channel_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/zephyrtronium/robot/channel"
+	"github.com/zephyrtronium/robot/fault"
+)
+
+func TestReserve(t *testing.T) {
+	ch := &channel.Channel{Rate: rate.NewLimiter(1, 1)}
+	now := time.Unix(1, 0)
+	r, err := ch.Reserve(now)
+	if err != nil {
+		t.Fatalf("first reservation failed: %v", err)
+	}
+	if _, err := ch.Reserve(now); !errors.Is(err, fault.ErrRateLimited) {
+		t.Errorf("wrong error for exhausted limit: %v", err)
+	}
+	r.CancelAt(now)
+	if _, err := ch.Reserve(now); err != nil {
+		t.Errorf("reservation after cancel failed: %v", err)
+	}
+	if _, err := ch.Reserve(now.Add(time.Second)); err != nil {
+		t.Errorf("reservation after limit refilled failed: %v", err)
+	}
+}
+
+func TestUseTag(t *testing.T) {
+	ch := &channel.Channel{Name: "#bocchi", Send: "bocchi", Tags: []string{"ryo", "kita"}}
+	if err := ch.UseTag("kita"); err != nil {
+		t.Errorf("allowed tag was refused: %v", err)
+	}
+	if err := ch.UseTag("nijika"); !errors.Is(err, fault.ErrNotPermitted) {
+		t.Errorf("wrong error for disallowed tag: %v", err)
+	}
+}
+
+func TestInLocation(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*3600)
+	ch := &channel.Channel{Location: tokyo}
+	// 20:00 UTC is 05:00 the next day in Tokyo.
+	now := time.Date(2024, 4, 1, 20, 0, 0, 0, time.UTC)
+	if got, want := channel.Today(ch.In(now)), time.Date(2024, 4, 2, 0, 0, 0, 0, tokyo); !got.Equal(want) {
+		t.Errorf("wrong day start: want %v, got %v", want, got)
+	}
+	var d channel.Daily
+	d.Add(ch.In(now))
+	if got := d.Count(ch.In(now.Add(18 * time.Hour))); got != 1 {
+		t.Errorf("wrong count before midnight in channel time zone: want 1, got %d", got)
+	}
+	if got := d.Count(ch.In(now.Add(19 * time.Hour))); got != 0 {
+		t.Errorf("count didn't reset at midnight in channel time zone: got %d", got)
+	}
+	if got := (&channel.Channel{}).In(now).Location(); got != time.Local {
+		t.Errorf("channel without location used %v", got)
+	}
+}
@@ -0,0 +1,20 @@
+package channel
+
+import "sync/atomic"
+
+// Counts is running totals of what happens to messages in a channel, for
+// watching how fast it grows and how much its filters catch.
+type Counts struct {
+	// Seen is the number of messages considered for learning.
+	Seen atomic.Int64
+	// Filtered is the number of messages seen which the channel's block
+	// expression or profanity limits kept from being learned.
+	Filtered atomic.Int64
+	// Learned is the number of messages learned.
+	Learned atomic.Int64
+	// Generated is the number of messages generated to send.
+	Generated atomic.Int64
+	// Rejected is the number of messages generated which filters or the
+	// classifier kept from being sent.
+	Rejected atomic.Int64
+}
@@ -0,0 +1,46 @@
+package channel
+
+import (
+	"sync"
+	"time"
+)
+
+// Daily counts events that happened on the current day.
+type Daily struct {
+	// mu guards day and n.
+	mu sync.Mutex
+	// day is the start of the day being counted.
+	day time.Time
+	// n is the count for the day.
+	n int64
+}
+
+// Add counts an event at now.
+func (d *Daily) Add(now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rollLocked(now)
+	d.n++
+}
+
+// Count returns the number of events on the day of now.
+func (d *Daily) Count(now time.Time) int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rollLocked(now)
+	return d.n
+}
+
+// rollLocked resets the count if now is on a later day.
+func (d *Daily) rollLocked(now time.Time) {
+	day := Today(now)
+	if day.After(d.day) {
+		d.day, d.n = day, 0
+	}
+}
+
+// Today returns the start of the day of t in its location.
+func Today(t time.Time) time.Time {
+	y, m, dd := t.Date()
+	return time.Date(y, m, dd, 0, 0, 0, 0, t.Location())
+}
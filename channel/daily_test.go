@@ -0,0 +1,34 @@
+package channel_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/channel"
+)
+
+func TestDaily(t *testing.T) {
+	var d channel.Daily
+	day := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	steps := []struct {
+		name string
+		add  bool
+		at   time.Duration
+		want int64
+	}{
+		{"empty", false, 0, 0},
+		{"first", true, time.Hour, 1},
+		{"second", true, 2 * time.Hour, 2},
+		{"count", false, 23 * time.Hour, 2},
+		{"next", false, 25 * time.Hour, 0},
+		{"add-next", true, 26 * time.Hour, 1},
+	}
+	for _, s := range steps {
+		if s.add {
+			d.Add(day.Add(s.at))
+		}
+		if got := d.Count(day.Add(s.at)); got != s.want {
+			t.Errorf("wrong count at %s: want %d, got %d", s.name, s.want, got)
+		}
+	}
+}
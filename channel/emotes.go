@@ -0,0 +1,308 @@
+package channel
+
+import (
+	"cmp"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"gitlab.com/zephyrtronium/pick"
+)
+
+// Emotes is a distribution of emotes which can be updated at runtime.
+type Emotes struct {
+	// dist is the current distribution.
+	dist atomic.Pointer[pick.Dist[string]]
+	// names maps lowercased single-word emotes to their spellings.
+	names atomic.Pointer[map[string]string]
+	// mu guards base, services, extra, observed, and blend.
+	mu sync.Mutex
+	// base is the configured weights of emotes.
+	base map[string]int
+	// services is the weights of emotes from third-party emote services like
+	// 7TV and BTTV, which are refreshed at runtime. They are added to the
+	// configured weights.
+	services map[string]int
+	// extra is the weights of emotes added at runtime. They are added to the
+	// configured weights.
+	extra map[string]int
+	// observed is the weights of emotes derived from their use in chat.
+	// They are blended with the configured and runtime weights.
+	observed map[string]int
+	// blend is the fraction of weight that observed emotes take.
+	blend float64
+	// seenMu guards seen and usage.
+	seenMu sync.Mutex
+	// seen is emotes observed in chat messages.
+	seen map[string]bool
+	// usage is the decaying counts of emotes used in chat.
+	usage Usage
+}
+
+// Usage is counts of emotes used in chat which decay over time, so that
+// recent use counts more than old use.
+type Usage struct {
+	// HalfLife is the time over which a use's count halves.
+	// If it is not positive, counts never decay.
+	HalfLife time.Duration
+	// counts is each emote's count scaled by the decay at epoch.
+	counts map[string]float64
+	// epoch is the time relative to which counts are scaled.
+	epoch time.Time
+}
+
+// EmoteCount is an emote and the decayed count of its uses in chat.
+type EmoteCount struct {
+	Emote string  `json:"emote"`
+	Count float64 `json:"count"`
+}
+
+// scale returns the factor by which to multiply a use at t.
+func (u *Usage) scale(t time.Time) float64 {
+	if u.HalfLife <= 0 {
+		return 1
+	}
+	return math.Exp2(float64(t.Sub(u.epoch)) / float64(u.HalfLife))
+}
+
+// add counts a use of an emote at t.
+func (u *Usage) add(emote string, t time.Time) {
+	if u.counts == nil {
+		u.counts = make(map[string]float64)
+		u.epoch = t
+	}
+	s := u.scale(t)
+	if s > 1<<32 {
+		// Rescale before counts lose precision, dropping emotes which have
+		// decayed to nothing.
+		for k, v := range u.counts {
+			v /= s
+			if v < 1e-3 {
+				delete(u.counts, k)
+				continue
+			}
+			u.counts[k] = v
+		}
+		u.epoch, s = t, 1
+	}
+	if _, ok := u.counts[emote]; !ok && len(u.counts) >= maxSeen {
+		return
+	}
+	u.counts[emote] += s
+}
+
+// top returns the n most used emotes as of now, most used first.
+// If n is not positive, it returns all of them.
+func (u *Usage) top(now time.Time, n int) []EmoteCount {
+	s := u.scale(now)
+	r := make([]EmoteCount, 0, len(u.counts))
+	for k, v := range u.counts {
+		r = append(r, EmoteCount{Emote: k, Count: v / s})
+	}
+	slices.SortFunc(r, func(a, b EmoteCount) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Emote, b.Emote)
+	})
+	if n > 0 && len(r) > n {
+		r = r[:n]
+	}
+	return r
+}
+
+// maxSeen is the number of observed emotes an [Emotes] remembers.
+const maxSeen = 8192
+
+// NewEmotes creates a distribution of emotes with the given weights.
+func NewEmotes(base map[string]int) *Emotes {
+	e := &Emotes{
+		base:  maps.Clone(base),
+		extra: make(map[string]int),
+	}
+	e.rebuildLocked()
+	return e
+}
+
+// Pick selects an emote using the given random number.
+func (e *Emotes) Pick(x uint32) string {
+	return e.dist.Load().Pick(x)
+}
+
+// Spelling returns the emote spelled the same as word ignoring case, or word
+// itself if there is no such emote.
+func (e *Emotes) Spelling(word string) string {
+	if s, ok := (*e.names.Load())[strings.ToLower(word)]; ok {
+		return s
+	}
+	return word
+}
+
+// Observe records emotes seen in chat at t so that [Emotes.Is] recognizes
+// them and their uses are counted.
+func (e *Emotes) Observe(t time.Time, names ...string) {
+	e.seenMu.Lock()
+	defer e.seenMu.Unlock()
+	if e.seen == nil {
+		e.seen = make(map[string]bool)
+	}
+	for _, s := range names {
+		if s == "" {
+			continue
+		}
+		e.usage.add(s, t)
+		if len(e.seen) < maxSeen {
+			e.seen[s] = true
+		}
+	}
+}
+
+// SetHalfLife sets the time over which counts of emote uses halve.
+// It should be called before observing any emotes.
+func (e *Emotes) SetHalfLife(d time.Duration) {
+	e.seenMu.Lock()
+	defer e.seenMu.Unlock()
+	e.usage.HalfLife = d
+}
+
+// Usage returns the n emotes most used in chat as of now, most used first.
+// If n is not positive, it returns all of them.
+func (e *Emotes) Usage(now time.Time, n int) []EmoteCount {
+	e.seenMu.Lock()
+	defer e.seenMu.Unlock()
+	return e.usage.top(now, n)
+}
+
+// Reweigh blends the weights of the n emotes most used in chat as of now into
+// the distribution. blend is the fraction of the distribution's total weight
+// which comes from observed use, from 0 for none to 1 for all of it.
+// Each call replaces the weights derived by the last.
+func (e *Emotes) Reweigh(now time.Time, n int, blend float64) {
+	use := e.Usage(now, n)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.blend = min(max(blend, 0), 1)
+	e.observed = observedWeights(use, e.blend, e.base, e.services, e.extra)
+	e.rebuildLocked()
+}
+
+// observedWeights derives weights from emote use such that they make up the
+// blend fraction of the total with the other weights.
+func observedWeights(use []EmoteCount, blend float64, weights ...map[string]int) map[string]int {
+	var used float64
+	for _, u := range use {
+		used += u.Count
+	}
+	if blend == 0 || used == 0 {
+		return nil
+	}
+	var total int
+	for _, w := range weights {
+		for _, v := range w {
+			total += v
+		}
+	}
+	// Derived weights are the blend fraction of the whole, so they sum to
+	// total*blend/(1-blend). If nothing is configured or everything comes
+	// from observation, use a fixed scale instead.
+	scale := 10000.0
+	if total > 0 && blend < 1 {
+		scale = float64(total) * blend / (1 - blend)
+	}
+	r := make(map[string]int, len(use))
+	for _, u := range use {
+		if w := int(math.Round(u.Count / used * scale)); w > 0 {
+			r[u.Emote] = w
+		}
+	}
+	return r
+}
+
+// Is reports whether a word is an emote, either one that the distribution
+// picks from or one observed in chat.
+func (e *Emotes) Is(word string) bool {
+	if _, ok := (*e.names.Load())[strings.ToLower(word)]; ok {
+		return true
+	}
+	e.seenMu.Lock()
+	defer e.seenMu.Unlock()
+	return e.seen[word]
+}
+
+// Set sets the weight of an emote added at runtime.
+// If the weight is not positive, the runtime addition is removed,
+// leaving any configured weight for the emote.
+func (e *Emotes) Set(emote string, weight int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if weight <= 0 {
+		delete(e.extra, emote)
+	} else {
+		e.extra[emote] = weight
+	}
+	e.rebuildLocked()
+}
+
+// Has reports whether an emote was added at runtime and whether it has a
+// weight from the configuration or a third-party emote service, which
+// removing runtime additions doesn't affect.
+func (e *Emotes) Has(emote string) (added, configured bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, added = e.extra[emote]
+	_, base := e.base[emote]
+	_, service := e.services[emote]
+	return added, base || service
+}
+
+// SetServices replaces the emotes from third-party emote services.
+func (e *Emotes) SetServices(emotes map[string]int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.services = maps.Clone(emotes)
+	e.rebuildLocked()
+}
+
+// Load replaces all emotes added at runtime.
+func (e *Emotes) Load(extra map[string]int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.extra = maps.Clone(extra)
+	if e.extra == nil {
+		e.extra = make(map[string]int)
+	}
+	e.rebuildLocked()
+}
+
+func (e *Emotes) rebuildLocked() {
+	u := maps.Clone(e.base)
+	if u == nil {
+		u = make(map[string]int, len(e.services)+len(e.extra))
+	}
+	for k, v := range e.services {
+		u[k] += v
+	}
+	for k, v := range e.extra {
+		u[k] += v
+	}
+	if e.blend >= 1 && len(e.observed) != 0 {
+		// Everything comes from observation.
+		clear(u)
+	}
+	for k, v := range e.observed {
+		u[k] += v
+	}
+	e.dist.Store(pick.New(pick.FromMap(u)))
+	names := make(map[string]string, len(u))
+	for k := range u {
+		if k != "" && !strings.ContainsFunc(k, unicode.IsSpace) {
+			names[strings.ToLower(k)] = k
+		}
+	}
+	e.names.Store(&names)
+}
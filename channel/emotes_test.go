@@ -0,0 +1,152 @@
+package channel_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/channel"
+)
+
+func TestEmotes(t *testing.T) {
+	e := channel.NewEmotes(map[string]int{"Kappa": 1})
+	if got := e.Pick(^uint32(0)); got != "Kappa" {
+		t.Errorf("wrong configured emote: want %q, got %q", "Kappa", got)
+	}
+	e.Set("KEKW", 1000)
+	if got := e.Pick(^uint32(0) / 2); got != "KEKW" {
+		t.Errorf("wrong emote after add: want %q, got %q", "KEKW", got)
+	}
+	e.Set("KEKW", 0)
+	if got := e.Pick(^uint32(0) / 2); got != "Kappa" {
+		t.Errorf("wrong emote after remove: want %q, got %q", "Kappa", got)
+	}
+	e.Load(map[string]int{"Kappa": 1000})
+	// Loading doesn't remove the configured weight.
+	e.Set("Kappa", 0)
+	if got := e.Pick(^uint32(0) / 2); got != "Kappa" {
+		t.Errorf("wrong emote after removing addition: want %q, got %q", "Kappa", got)
+	}
+}
+
+func TestEmotesServices(t *testing.T) {
+	e := channel.NewEmotes(map[string]int{"Kappa": 1})
+	e.SetServices(map[string]int{"catJAM": 1000})
+	if got := e.Pick(^uint32(0) / 2); got != "catJAM" {
+		t.Errorf("wrong emote from services: want %q, got %q", "catJAM", got)
+	}
+	e.Set("KEKW", 1)
+	cases := []struct {
+		emote             string
+		added, configured bool
+	}{
+		{"Kappa", false, true},
+		{"catJAM", false, true},
+		{"KEKW", true, false},
+		{"PogChamp", false, false},
+	}
+	for _, c := range cases {
+		added, configured := e.Has(c.emote)
+		if added != c.added || configured != c.configured {
+			t.Errorf("wrong status for %s: want %t %t, got %t %t", c.emote, c.added, c.configured, added, configured)
+		}
+	}
+	// Refreshing replaces the old set.
+	e.SetServices(nil)
+	if _, configured := e.Has("catJAM"); configured {
+		t.Error("emote from services remains after refresh")
+	}
+	if got := e.Spelling("catjam"); got != "catjam" {
+		t.Errorf("spelling remains after refresh: %q", got)
+	}
+}
+
+func TestEmotesSpelling(t *testing.T) {
+	e := channel.NewEmotes(map[string]int{"Kappa": 1, "<3 <3": 1})
+	e.Load(map[string]int{"KEKW": 1})
+	cases := []struct {
+		word string
+		want string
+	}{
+		{"Kappa", "Kappa"},
+		{"kappa", "Kappa"},
+		{"KAPPA", "Kappa"},
+		{"kekw", "KEKW"},
+		{"<3", "<3"},
+		{"bocchi", "bocchi"},
+	}
+	for _, c := range cases {
+		if got := e.Spelling(c.word); got != c.want {
+			t.Errorf("wrong spelling of %q: want %q, got %q", c.word, c.want, got)
+		}
+	}
+}
+
+func TestEmotesIs(t *testing.T) {
+	e := channel.NewEmotes(map[string]int{"Kappa": 1, "": 1})
+	e.Observe(time.Unix(0, 0), "bocchiLove", "")
+	cases := []struct {
+		word string
+		want bool
+	}{
+		{"Kappa", true},
+		{"kappa", true},
+		{"bocchiLove", true},
+		{"bocchilove", false},
+		{"bocchi", false},
+		{"", false},
+	}
+	for _, c := range cases {
+		if got := e.Is(c.word); got != c.want {
+			t.Errorf("wrong emote status of %q: want %t, got %t", c.word, c.want, got)
+		}
+	}
+}
+
+func TestEmotesUsage(t *testing.T) {
+	e := channel.NewEmotes(nil)
+	e.SetHalfLife(time.Hour)
+	at := func(m int) time.Time { return time.Unix(int64(m)*60, 0) }
+	e.Observe(at(0), "Kappa", "Kappa", "Kappa", "Kappa")
+	e.Observe(at(60), "bocchiLove", "bocchiLove", "bocchiLove")
+	u := e.Usage(at(60), 0)
+	if len(u) != 2 {
+		t.Fatalf("wrong usage: %+v", u)
+	}
+	// Kappa's four uses decayed to two.
+	if u[0].Emote != "bocchiLove" || u[0].Count != 3 || u[1].Emote != "Kappa" || u[1].Count != 2 {
+		t.Errorf("wrong usage after decay: %+v", u)
+	}
+	if u := e.Usage(at(60), 1); len(u) != 1 || u[0].Emote != "bocchiLove" {
+		t.Errorf("wrong top usage: %+v", u)
+	}
+}
+
+func TestEmotesReweigh(t *testing.T) {
+	share := func(e *channel.Emotes, emote string) float64 {
+		n := 0
+		for i := range 1000 {
+			if e.Pick(uint32(i)*(1<<32/1000)) == emote {
+				n++
+			}
+		}
+		return float64(n) / 1000
+	}
+	now := time.Unix(0, 0)
+	e := channel.NewEmotes(map[string]int{"Kappa": 3})
+	e.Observe(now, "bocchiLove")
+	e.Reweigh(now, 10, 0.5)
+	if s := share(e, "bocchiLove"); s < 0.45 || s > 0.55 {
+		t.Errorf("wrong share at half blend: %v", s)
+	}
+	if got := e.Spelling("bocchilove"); got != "bocchiLove" {
+		t.Errorf("observed emote not spelled: %q", got)
+	}
+	e.Reweigh(now, 10, 1)
+	if s := share(e, "bocchiLove"); s != 1 {
+		t.Errorf("wrong share at full blend: %v", s)
+	}
+	e.Reweigh(now, 10, 0)
+	if s := share(e, "Kappa"); s != 1 {
+		t.Errorf("wrong share without blend: %v", s)
+	}
+}
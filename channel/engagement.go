@@ -0,0 +1,68 @@
+package channel
+
+import (
+	"sync"
+	"time"
+)
+
+// Engagement tracks whether anyone responds to the bot's messages in a
+// channel within a window of time.
+type Engagement struct {
+	// mu guards pending.
+	mu sync.Mutex
+	// window is how long a message waits for a response.
+	window time.Duration
+	// pending is the times of messages still waiting for a response, in order.
+	pending []time.Time
+}
+
+// Outcome is the result of tracking engagement with one message.
+type Outcome struct {
+	// Time is the time at which the bot sent the message.
+	Time time.Time
+	// Engaged indicates whether anyone responded to the message.
+	Engaged bool
+}
+
+// NewEngagement creates an engagement tracker with the given window.
+func NewEngagement(window time.Duration) *Engagement {
+	return &Engagement{window: window}
+}
+
+// Sent starts tracking a message sent at now.
+// It returns the outcomes of messages whose windows have passed.
+func (e *Engagement) Sent(now time.Time) []Outcome {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	r := e.expireLocked(now)
+	e.pending = append(e.pending, now)
+	return r
+}
+
+// Engage records a response at now to the most recent message still waiting
+// for one. It returns the outcomes of that message and of messages whose
+// windows have passed.
+func (e *Engagement) Engage(now time.Time) []Outcome {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	r := e.expireLocked(now)
+	if len(e.pending) == 0 {
+		return r
+	}
+	k := len(e.pending) - 1
+	r = append(r, Outcome{Time: e.pending[k], Engaged: true})
+	e.pending = e.pending[:k]
+	return r
+}
+
+// expireLocked removes messages whose windows have passed at now.
+func (e *Engagement) expireLocked(now time.Time) []Outcome {
+	var r []Outcome
+	k := 0
+	for k < len(e.pending) && now.Sub(e.pending[k]) > e.window {
+		r = append(r, Outcome{Time: e.pending[k]})
+		k++
+	}
+	e.pending = append(e.pending[:0], e.pending[k:]...)
+	return r
+}
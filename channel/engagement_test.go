@@ -0,0 +1,40 @@
+package channel_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/channel"
+)
+
+func TestEngagement(t *testing.T) {
+	e := channel.NewEngagement(time.Minute)
+	at := func(s int) time.Time { return time.Unix(int64(s), 0) }
+	steps := []struct {
+		name   string
+		engage bool
+		at     int
+		want   []channel.Outcome
+	}{
+		{"first", false, 0, nil},
+		{"second", false, 10, nil},
+		{"engage", true, 20, []channel.Outcome{{Time: at(10), Engaged: true}}},
+		{"engage-older", true, 30, []channel.Outcome{{Time: at(0), Engaged: true}}},
+		{"engage-none", true, 40, nil},
+		{"third", false, 50, nil},
+		{"expire", false, 200, []channel.Outcome{{Time: at(50)}}},
+		{"engage-late", true, 300, []channel.Outcome{{Time: at(200)}}},
+	}
+	for _, s := range steps {
+		var got []channel.Outcome
+		if s.engage {
+			got = e.Engage(at(s.at))
+		} else {
+			got = e.Sent(at(s.at))
+		}
+		if !slices.Equal(got, s.want) {
+			t.Errorf("wrong outcomes at %s: want %v, got %v", s.name, s.want, got)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package channel
+
+import (
+	"sync"
+	"time"
+)
+
+// Failures tracks recent failures in order to decide when to stop trying.
+type Failures struct {
+	// mu guards times.
+	mu sync.Mutex
+	// times is the times of recent failures as Unix nanoseconds, oldest first.
+	times []int64
+	// need is the number of failures within the window that trips.
+	need int
+	// within is the window duration.
+	within time.Duration
+}
+
+// NewFailures creates a failure tracker which trips when need failures occur
+// within the given duration.
+func NewFailures(need int, within time.Duration) *Failures {
+	return &Failures{
+		times:  make([]int64, 0, need),
+		need:   need,
+		within: within,
+	}
+}
+
+// Add records a failure at t and reports whether the number of failures
+// within the window ending at t has reached the threshold.
+func (f *Failures) Add(t time.Time) bool {
+	now := t.UnixNano()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	k := 0
+	for k < len(f.times) && f.times[k] <= now-f.within.Nanoseconds() {
+		k++
+	}
+	f.times = append(f.times[:0], f.times[k:]...)
+	f.times = append(f.times, now)
+	return len(f.times) >= f.need
+}
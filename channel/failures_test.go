@@ -0,0 +1,30 @@
+package channel_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/channel"
+)
+
+func TestFailures(t *testing.T) {
+	f := channel.NewFailures(3, time.Minute)
+	steps := []struct {
+		at   time.Duration
+		trip bool
+	}{
+		{0, false},
+		{10 * time.Second, false},
+		{20 * time.Second, true},
+		{30 * time.Second, true},
+		{75 * time.Second, true},   // first two have expired
+		{100 * time.Second, false}, // third has expired
+		{10 * time.Minute, false},
+	}
+	start := time.Unix(1e9, 0)
+	for i, s := range steps {
+		if got := f.Add(start.Add(s.at)); got != s.trip {
+			t.Errorf("wrong trip at step %d: want %t, got %t", i, s.trip, got)
+		}
+	}
+}
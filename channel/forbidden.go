@@ -0,0 +1,54 @@
+package channel
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Forbidden is the prompts which a channel refuses to continue and the
+// deflections it gives instead. A nil Forbidden allows every prompt.
+type Forbidden struct {
+	prompts *regexp.Regexp
+	deflect []string
+}
+
+// ParseForbidden compiles patterns of forbidden prompts. Each pattern is a
+// regular expression which matches anywhere in a prompt regardless of case.
+// If there are no patterns, the result is nil.
+func ParseForbidden(patterns, deflect []string) (*Forbidden, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	alts := make([]string, len(patterns))
+	for i, p := range patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return nil, fmt.Errorf("bad forbidden prompt %q: %w", p, err)
+		}
+		alts[i] = "(?i:" + p + ")"
+	}
+	f := &Forbidden{
+		prompts: regexp.MustCompile(strings.Join(alts, "|")),
+		deflect: deflect,
+	}
+	return f, nil
+}
+
+// Match reports whether a prompt is forbidden.
+func (f *Forbidden) Match(prompt string) bool {
+	if f == nil || prompt == "" {
+		return false
+	}
+	return f.prompts.MatchString(prompt)
+}
+
+// Deflection selects a deflection by x, with {user} replaced by the name of
+// the user who gave the prompt. If the channel has no deflections of its own,
+// the result is the empty string.
+func (f *Forbidden) Deflection(x uint32, user string) string {
+	if f == nil || len(f.deflect) == 0 {
+		return ""
+	}
+	d := f.deflect[uint64(x)*uint64(len(f.deflect))>>32]
+	return strings.ReplaceAll(d, "{user}", user)
+}
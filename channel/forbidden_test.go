@@ -0,0 +1,56 @@
+package channel_test
+
+import (
+	"testing"
+
+	"github.com/zephyrtronium/robot/channel"
+)
+
+func TestForbidden(t *testing.T) {
+	f, err := channel.ParseForbidden([]string{`\bkikuri\b`, "sake"}, []string{"not that, {user}", "anyway"})
+	if err != nil {
+		t.Fatalf("couldn't parse: %v", err)
+	}
+	cases := []struct {
+		prompt string
+		want   bool
+	}{
+		{"", false},
+		{"bocchi", false},
+		{"kikuri", true},
+		{"what does Kikuri drink", true},
+		{"kikurin", false},
+		{"SAKE please", true},
+	}
+	for _, c := range cases {
+		if got := f.Match(c.prompt); got != c.want {
+			t.Errorf("wrong match for %q: want %t, got %t", c.prompt, c.want, got)
+		}
+	}
+	if got := f.Deflection(0, "Bocchi"); got != "not that, Bocchi" {
+		t.Errorf("wrong first deflection: %q", got)
+	}
+	if got := f.Deflection(1<<31, "Bocchi"); got != "anyway" {
+		t.Errorf("wrong second deflection: %q", got)
+	}
+	t.Run("none", func(t *testing.T) {
+		f, err := channel.ParseForbidden(nil, []string{"anyway"})
+		if err != nil {
+			t.Fatalf("couldn't parse: %v", err)
+		}
+		if f.Match("kikuri") || f.Deflection(0, "Bocchi") != "" {
+			t.Errorf("nil Forbidden forbids or deflects")
+		}
+	})
+	t.Run("default", func(t *testing.T) {
+		f, _ := channel.ParseForbidden([]string{"kikuri"}, nil)
+		if got := f.Deflection(0, "Bocchi"); got != "" {
+			t.Errorf("deflection without any configured: %q", got)
+		}
+	})
+	t.Run("bad", func(t *testing.T) {
+		if _, err := channel.ParseForbidden([]string{"("}, nil); err == nil {
+			t.Error("bad pattern accepted")
+		}
+	})
+}
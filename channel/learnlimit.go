@@ -0,0 +1,58 @@
+package channel
+
+import (
+	"sync"
+	"time"
+)
+
+// LearnLimit limits the number of messages learned from each user within a
+// window, so that one chatty user doesn't dominate what a channel learns.
+// A nil LearnLimit allows every message.
+type LearnLimit struct {
+	// mu guards users.
+	mu sync.Mutex
+	// num is the most messages to learn from a user within the window.
+	num int
+	// within is the window duration.
+	within time.Duration
+	// users is the times of messages learned from each user within the
+	// window, oldest first.
+	users map[string][]time.Time
+}
+
+// NewLearnLimit creates a limit of num messages from each user within the
+// given duration.
+func NewLearnLimit(num int, within time.Duration) *LearnLimit {
+	return &LearnLimit{num: num, within: within, users: make(map[string][]time.Time)}
+}
+
+// Allow reports whether a message from user at t is within the limit, and
+// counts it if so.
+func (l *LearnLimit) Allow(user string, t time.Time) bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.users) >= 1024 {
+		// Clear out users who haven't spoken recently so that the map
+		// doesn't grow forever.
+		for u, times := range l.users {
+			if t.Sub(times[len(times)-1]) >= l.within {
+				delete(l.users, u)
+			}
+		}
+	}
+	times := l.users[user]
+	k := 0
+	for k < len(times) && t.Sub(times[k]) >= l.within {
+		k++
+	}
+	times = append(times[:0], times[k:]...)
+	if len(times) >= l.num {
+		l.users[user] = times
+		return false
+	}
+	l.users[user] = append(times, t)
+	return true
+}
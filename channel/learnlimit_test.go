@@ -0,0 +1,37 @@
+package channel_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/channel"
+)
+
+func TestLearnLimit(t *testing.T) {
+	l := channel.NewLearnLimit(2, time.Minute)
+	at := func(s int) time.Time { return time.Unix(int64(s), 0) }
+	steps := []struct {
+		name string
+		user string
+		at   int
+		want bool
+	}{
+		{"first", "bocchi", 0, true},
+		{"second", "bocchi", 10, true},
+		{"over", "bocchi", 20, false},
+		{"other", "kita", 30, true},
+		{"still-over", "bocchi", 59, false},
+		{"expired-one", "bocchi", 60, true},
+		{"over-again", "bocchi", 65, false},
+		{"expired-all", "bocchi", 200, true},
+	}
+	for _, s := range steps {
+		if got := l.Allow(s.user, at(s.at)); got != s.want {
+			t.Errorf("wrong result at %s: want %t, got %t", s.name, s.want, got)
+		}
+	}
+	var none *channel.LearnLimit
+	if !none.Allow("bocchi", at(0)) {
+		t.Error("nil limit rejected message")
+	}
+}
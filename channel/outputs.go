@@ -0,0 +1,53 @@
+package channel
+
+import (
+	"sync"
+	"time"
+)
+
+// Outputs tracks generated messages recently sent from a tag, so that
+// channels sharing the tag don't send the same message.
+// A nil Outputs allows every message.
+type Outputs struct {
+	// mu guards sent.
+	mu sync.Mutex
+	// window is how long a message stays claimed by a channel.
+	window time.Duration
+	// sent is the channel that last sent each message and when.
+	sent map[string]output
+}
+
+// output is a use of a message by a channel.
+type output struct {
+	channel string
+	time    time.Time
+}
+
+// NewOutputs creates a tracker which keeps messages for the given window.
+func NewOutputs(window time.Duration) *Outputs {
+	return &Outputs{window: window, sent: make(map[string]output)}
+}
+
+// Claim records that a channel is sending a message at now. It returns false
+// without recording anything if a different channel sent the same message
+// within the window.
+func (o *Outputs) Claim(channel, msg string, now time.Time) bool {
+	if o == nil {
+		return true
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if u, ok := o.sent[msg]; ok && u.channel != channel && now.Sub(u.time) < o.window {
+		return false
+	}
+	if len(o.sent) >= 1024 {
+		// Clear out old messages so that the map doesn't grow forever.
+		for k, u := range o.sent {
+			if now.Sub(u.time) >= o.window {
+				delete(o.sent, k)
+			}
+		}
+	}
+	o.sent[msg] = output{channel: channel, time: now}
+	return true
+}
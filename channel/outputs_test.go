@@ -0,0 +1,37 @@
+package channel_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/channel"
+)
+
+func TestOutputs(t *testing.T) {
+	o := channel.NewOutputs(time.Minute)
+	at := func(s int) time.Time { return time.Unix(int64(s), 0) }
+	steps := []struct {
+		name    string
+		channel string
+		msg     string
+		at      int
+		want    bool
+	}{
+		{"first", "#bocchi", "kessoku band", 0, true},
+		{"other", "#kita", "kessoku band", 10, false},
+		{"same", "#bocchi", "kessoku band", 20, true},
+		{"different", "#kita", "sick hack", 30, true},
+		{"renewed", "#kita", "kessoku band", 70, false},
+		{"expired", "#kita", "kessoku band", 80, true},
+		{"claimed", "#bocchi", "kessoku band", 90, false},
+	}
+	for _, s := range steps {
+		if got := o.Claim(s.channel, s.msg, at(s.at)); got != s.want {
+			t.Errorf("wrong claim at %s: want %t, got %t", s.name, s.want, got)
+		}
+	}
+	var none *channel.Outputs
+	if !none.Claim("#bocchi", "kessoku band", at(0)) {
+		t.Error("nil outputs rejected message")
+	}
+}
@@ -0,0 +1,95 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Pace is the range of time the bot aims to take between a message and its
+// reply, so that replies feel typed rather than instant. Replies which take
+// longer than the range to generate are sent as soon as they are ready.
+type Pace struct {
+	// Min and Max bound the target time. If Max is not positive, replies are
+	// sent as soon as they are ready.
+	Min, Max time.Duration
+}
+
+// Delay returns how long to wait before sending a reply to a message from at
+// which is ready at now. x in [0, 1) selects the target time within the range.
+func (p Pace) Delay(at, now time.Time, x float64) time.Duration {
+	if p.Max <= 0 {
+		return 0
+	}
+	target := p.Min + time.Duration(x*float64(p.Max-p.Min))
+	return min(max(target-now.Sub(at), 0), p.Max)
+}
+
+// Latency tracks the time between messages and the bot's replies to them.
+// It is an [expvar.Var].
+type Latency struct {
+	// mu guards the other fields.
+	mu sync.Mutex
+	// n is the number of replies observed.
+	n int64
+	// total is the sum of the latencies of all replies.
+	total time.Duration
+	// last is the latency of the most recent reply.
+	last time.Duration
+}
+
+// Observe records a reply's latency.
+func (l *Latency) Observe(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.n++
+	l.total += d
+	l.last = d
+}
+
+// Mean returns the mean latency of all replies and the number of replies.
+func (l *Latency) Mean() (time.Duration, int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.n == 0 {
+		return 0, 0
+	}
+	return l.total / time.Duration(l.n), l.n
+}
+
+// String formats the latency as JSON with times in seconds.
+func (l *Latency) String() string {
+	mean, n := l.Mean()
+	l.mu.Lock()
+	last := l.last
+	l.mu.Unlock()
+	b, _ := json.Marshal(map[string]any{
+		"replies": n,
+		"mean":    mean.Seconds(),
+		"last":    last.Seconds(),
+	})
+	return string(b)
+}
+
+// Reply sends a message in response to one sent at at. If the reply is ready
+// sooner than the channel's pace, it waits in the background before sending.
+func (ch *Channel) Reply(ctx context.Context, at time.Time, reply, text string) {
+	send := func() {
+		if ch.Latency != nil {
+			ch.Latency.Observe(time.Since(at))
+		}
+		ch.Message(ctx, reply, text)
+	}
+	d := ch.Pace.Delay(at, time.Now(), rand.Float64())
+	if d <= 0 {
+		send()
+		return
+	}
+	time.AfterFunc(d, func() {
+		if ctx.Err() == nil {
+			send()
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package channel_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/channel"
+)
+
+func TestPaceDelay(t *testing.T) {
+	at := time.Unix(100, 0)
+	cases := []struct {
+		name string
+		pace channel.Pace
+		took time.Duration
+		x    float64
+		want time.Duration
+	}{
+		{"off", channel.Pace{}, 0, 0.5, 0},
+		{"min", channel.Pace{Min: time.Second, Max: 3 * time.Second}, 0, 0, time.Second},
+		{"mid", channel.Pace{Min: time.Second, Max: 3 * time.Second}, 0, 0.5, 2 * time.Second},
+		{"partial", channel.Pace{Min: time.Second, Max: 3 * time.Second}, 1500 * time.Millisecond, 0.5, 500 * time.Millisecond},
+		{"slow", channel.Pace{Min: time.Second, Max: 3 * time.Second}, 5 * time.Second, 0.5, 0},
+		{"future", channel.Pace{Min: time.Second, Max: 3 * time.Second}, -time.Hour, 0.5, 3 * time.Second},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.pace.Delay(at, at.Add(c.took), c.x); got != c.want {
+				t.Errorf("wrong delay: want %v, got %v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestReplyPace(t *testing.T) {
+	sent := make(chan string, 1)
+	ch := &channel.Channel{
+		Pace:    channel.Pace{Min: 50 * time.Millisecond, Max: 50 * time.Millisecond},
+		Latency: new(channel.Latency),
+		Message: func(ctx context.Context, reply, text string) { sent <- text },
+	}
+	at := time.Now()
+	ch.Reply(context.Background(), at, "", "kessoku band")
+	select {
+	case s := <-sent:
+		t.Fatalf("sent %q before pace", s)
+	default:
+	}
+	if s := <-sent; s != "kessoku band" {
+		t.Errorf("wrong message: %q", s)
+	}
+	if d := time.Since(at); d < 50*time.Millisecond {
+		t.Errorf("sent too soon: %v", d)
+	}
+	mean, n := ch.Latency.Mean()
+	if n != 1 || mean < 50*time.Millisecond {
+		t.Errorf("wrong latency: %v over %d", mean, n)
+	}
+}
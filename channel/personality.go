@@ -0,0 +1,103 @@
+package channel
+
+import (
+	"math"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Personality is parameters that shape how the bot speaks in a channel.
+type Personality struct {
+	// Emote is the probability that a generated message ends with an emote.
+	Emote float64
+	// Effect is the probability that a generated message has an effect
+	// applied.
+	Effect float64
+	// Exclaim is the probability that a generated message ends with an
+	// exclamation point.
+	Exclaim float64
+	// Caps is the probability that a generated message is in all caps.
+	Caps float64
+	// MinWords and MaxWords are the preferred range of the number of words in
+	// random responses. Each is unbounded if it is zero.
+	MinWords, MaxWords int
+	// MaxEmotes is the most emotes allowed in a generated message.
+	// If it is zero, any number is allowed.
+	MaxEmotes int
+	// MinEntropy is the least entropy in bits of the distribution of words in
+	// a generated message. It rejects messages which repeat a few words over
+	// and over. If it is zero, any message is allowed.
+	MinEntropy float64
+	// Lurk is the time after which the bot breaks its silence in a channel
+	// with a random response regardless of the response probability.
+	// If it is zero, the bot only speaks up at random.
+	Lurk time.Duration
+}
+
+// Fits reports whether a message is within the preferred length.
+func (p *Personality) Fits(msg string) bool {
+	n := len(strings.Fields(msg))
+	if p.MinWords > 0 && n < p.MinWords {
+		return false
+	}
+	if p.MaxWords > 0 && n > p.MaxWords {
+		return false
+	}
+	return true
+}
+
+// Varied reports whether a message has no more emotes and no less entropy
+// than allowed. emote reports whether a word is an emote.
+func (p *Personality) Varied(msg string, emote func(word string) bool) bool {
+	words := strings.Fields(msg)
+	if p.MaxEmotes > 0 {
+		n := 0
+		for _, w := range words {
+			if emote(w) {
+				n++
+			}
+		}
+		if n > p.MaxEmotes {
+			return false
+		}
+	}
+	if p.MinEntropy > 0 && entropy(words) < p.MinEntropy {
+		return false
+	}
+	return true
+}
+
+// entropy computes the Shannon entropy in bits of the distribution of words.
+func entropy(words []string) float64 {
+	c := make(map[string]int, len(words))
+	for _, w := range words {
+		c[w]++
+	}
+	var h float64
+	n := float64(len(words))
+	for _, k := range c {
+		q := float64(k) / n
+		h -= q * math.Log2(q)
+	}
+	return h
+}
+
+// Style applies the exclamation and caps tendencies to a message.
+// exclaim and caps are uniform random numbers in [0, 1) which decide whether
+// each applies.
+func (p *Personality) Style(msg string, exclaim, caps float64) string {
+	if exclaim < p.Exclaim {
+		msg = strings.TrimRightFunc(msg, func(r rune) bool {
+			return r == '.' || r == ',' || r == ';' || unicode.IsSpace(r)
+		})
+		if r, _ := utf8.DecodeLastRuneInString(msg); msg != "" && r != '!' && r != '?' {
+			msg += "!"
+		}
+	}
+	if caps < p.Caps {
+		msg = strings.ToUpper(msg)
+	}
+	return msg
+}
@@ -0,0 +1,83 @@
+package channel_test
+
+import (
+	"testing"
+
+	"github.com/zephyrtronium/robot/channel"
+)
+
+func TestPersonalityFits(t *testing.T) {
+	cases := []struct {
+		name     string
+		min, max int
+		msg      string
+		want     bool
+	}{
+		{"unbounded", 0, 0, "bocchi", true},
+		{"short", 2, 0, "bocchi", false},
+		{"min", 2, 0, "bocchi the", true},
+		{"long", 0, 2, "bocchi the rock", false},
+		{"max", 0, 3, "bocchi the rock", true},
+		{"range", 2, 3, "bocchi  the\trock", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := channel.Personality{MinWords: c.min, MaxWords: c.max}
+			if got := p.Fits(c.msg); got != c.want {
+				t.Errorf("wrong fit for %q in [%d, %d]: want %t, got %t", c.msg, c.min, c.max, c.want, got)
+			}
+		})
+	}
+}
+
+func TestPersonalityVaried(t *testing.T) {
+	emote := func(w string) bool { return w == "KEKW" || w == "Kappa" }
+	cases := []struct {
+		name    string
+		emotes  int
+		entropy float64
+		msg     string
+		want    bool
+	}{
+		{"unbounded", 0, 0, "KEKW KEKW KEKW KEKW", true},
+		{"emotes-under", 2, 0, "bocchi KEKW the Kappa rock", true},
+		{"emotes-over", 2, 0, "KEKW Kappa bocchi KEKW", false},
+		{"entropy-over", 0, 1.5, "bocchi the rock", true},
+		{"entropy-under", 0, 1.5, "bocchi bocchi bocchi rock", false},
+		{"entropy-repeat", 0, 0.5, "KEKW KEKW KEKW KEKW", false},
+		{"both", 1, 1, "bocchi the rock Kappa", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := channel.Personality{MaxEmotes: c.emotes, MinEntropy: c.entropy}
+			if got := p.Varied(c.msg, emote); got != c.want {
+				t.Errorf("wrong variety for %q: want %t, got %t", c.msg, c.want, got)
+			}
+		})
+	}
+}
+
+func TestPersonalityStyle(t *testing.T) {
+	p := channel.Personality{Exclaim: 0.5, Caps: 0.5}
+	cases := []struct {
+		name          string
+		msg           string
+		exclaim, caps float64
+		want          string
+	}{
+		{"none", "bocchi the rock.", 0.9, 0.9, "bocchi the rock."},
+		{"exclaim", "bocchi the rock", 0.1, 0.9, "bocchi the rock!"},
+		{"exclaim-punct", "bocchi the rock. ", 0.1, 0.9, "bocchi the rock!"},
+		{"exclaim-already", "bocchi the rock?", 0.1, 0.9, "bocchi the rock?"},
+		{"exclaim-empty", "", 0.1, 0.9, ""},
+		{"caps", "bocchi the rock", 0.9, 0.1, "BOCCHI THE ROCK"},
+		{"both", "bocchi the rock", 0.1, 0.1, "BOCCHI THE ROCK!"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := p.Style(c.msg, c.exclaim, c.caps); got != c.want {
+				t.Errorf("wrong style for %q: want %q, got %q", c.msg, c.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package channel
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Replies is the framings in which a channel wraps addressed replies, like
+// "@{user} {text}". A nil Replies leaves replies unchanged.
+type Replies []string
+
+// ReplyVars is the variables available to reply framings.
+type ReplyVars struct {
+	// User is the display name of the user being replied to, as {user}.
+	User string
+	// Channel is the channel name, as {channel}.
+	Channel string
+	// Game is the game or category the channel is streaming, as {game}.
+	Game string
+	// Text is the generated reply, as {text}.
+	Text string
+}
+
+// ParseReplies checks reply framings. Every framing must include {text}.
+func ParseReplies(framings []string) (Replies, error) {
+	if len(framings) == 0 {
+		return nil, nil
+	}
+	for _, f := range framings {
+		if !strings.Contains(f, "{text}") {
+			return nil, fmt.Errorf("reply framing %q doesn't include {text}", f)
+		}
+	}
+	return Replies(framings), nil
+}
+
+// Frame wraps a reply in a framing selected by x.
+func (r Replies) Frame(x uint32, v ReplyVars) string {
+	if len(r) == 0 {
+		return v.Text
+	}
+	f := r[uint64(x)*uint64(len(r))>>32]
+	s := strings.NewReplacer("{user}", v.User, "{channel}", v.Channel, "{game}", v.Game, "{text}", v.Text).Replace(f)
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,45 @@
+package channel_test
+
+import (
+	"testing"
+
+	"github.com/zephyrtronium/robot/channel"
+)
+
+func TestReplies(t *testing.T) {
+	v := channel.ReplyVars{User: "Bocchi", Channel: "#kessoku", Game: "Guitar Hero", Text: "i love guitar"}
+	cases := []struct {
+		name     string
+		framings []string
+		x        uint32
+		want     string
+	}{
+		{"none", nil, 0, "i love guitar"},
+		{"user", []string{"@{user} {text}"}, 0, "@Bocchi i love guitar"},
+		{"all", []string{"{text} ({user} in {channel} playing {game})"}, 0, "i love guitar (Bocchi in #kessoku playing Guitar Hero)"},
+		{"first", []string{"@{user} {text}", "{text}"}, 0, "@Bocchi i love guitar"},
+		{"second", []string{"@{user} {text}", "{text}"}, 1 << 31, "i love guitar"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r, err := channel.ParseReplies(c.framings)
+			if err != nil {
+				t.Fatalf("couldn't parse framings: %v", err)
+			}
+			if got := r.Frame(c.x, v); got != c.want {
+				t.Errorf("wrong reply: want %q, got %q", c.want, got)
+			}
+		})
+	}
+	t.Run("empty", func(t *testing.T) {
+		r, _ := channel.ParseReplies([]string{"{text} now playing {game}"})
+		if got := r.Frame(0, channel.ReplyVars{Text: "hi"}); got != "hi now playing" {
+			t.Errorf("wrong reply with empty variable: %q", got)
+		}
+	})
+	t.Run("bad", func(t *testing.T) {
+		if _, err := channel.ParseReplies([]string{"@{user}"}); err == nil {
+			t.Error("no error for framing without text")
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package channel
+
+import "sync"
+
+// Roles is the users holding roles in a channel according to its platform,
+// kept in sync with the platform periodically rather than configured.
+// A nil Roles has no users in any role.
+type Roles struct {
+	// mu guards mods and vips.
+	mu sync.RWMutex
+	// mods and vips are the sets of user IDs of the channel's moderators and
+	// VIPs.
+	mods, vips map[string]bool
+}
+
+// Set replaces the users holding each role.
+func (r *Roles) Set(mods, vips []string) {
+	m := make(map[string]bool, len(mods))
+	for _, id := range mods {
+		m[id] = true
+	}
+	v := make(map[string]bool, len(vips))
+	for _, id := range vips {
+		v[id] = true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mods, r.vips = m, v
+}
+
+// Mod reports whether a user is a moderator.
+func (r *Roles) Mod(id string) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.mods[id]
+}
+
+// VIP reports whether a user is a VIP.
+func (r *Roles) VIP(id string) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.vips[id]
+}
@@ -0,0 +1,29 @@
+package channel_test
+
+import (
+	"testing"
+
+	"github.com/zephyrtronium/robot/channel"
+)
+
+func TestRoles(t *testing.T) {
+	var r channel.Roles
+	if r.Mod("nijika") || r.VIP("kita") {
+		t.Error("empty roles have users")
+	}
+	r.Set([]string{"nijika", "ryo"}, []string{"kita"})
+	if !r.Mod("nijika") || !r.Mod("ryo") || r.Mod("kita") {
+		t.Error("wrong moderators")
+	}
+	if !r.VIP("kita") || r.VIP("nijika") {
+		t.Error("wrong VIPs")
+	}
+	r.Set([]string{"ryo"}, nil)
+	if r.Mod("nijika") || r.VIP("kita") {
+		t.Error("set didn't replace roles")
+	}
+	var none *channel.Roles
+	if none.Mod("ryo") || none.VIP("ryo") {
+		t.Error("nil roles have users")
+	}
+}
@@ -0,0 +1,77 @@
+package channel
+
+import "sync"
+
+// Speakers is a short list of recent chatters, most recent first.
+type Speakers struct {
+	// mu guards the lists.
+	mu sync.Mutex
+	// ids and names are the user IDs and display names of recent chatters.
+	ids   []string
+	names []string
+}
+
+// NewSpeakers creates a list holding up to n recent chatters.
+func NewSpeakers(n int) *Speakers {
+	return &Speakers{
+		ids:   make([]string, 0, n),
+		names: make([]string, 0, n),
+	}
+}
+
+// Add records a chatter as the most recent.
+// If the list is full, the least recent chatter is dropped.
+func (s *Speakers) Add(id, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cap(s.ids) == 0 {
+		return
+	}
+	k := s.indexLocked(id)
+	switch {
+	case k >= 0:
+		// Already present. Shift everyone before them down by one.
+	case len(s.ids) < cap(s.ids):
+		s.ids = s.ids[:len(s.ids)+1]
+		s.names = s.names[:len(s.names)+1]
+		k = len(s.ids) - 1
+	default:
+		k = len(s.ids) - 1
+	}
+	copy(s.ids[1:k+1], s.ids[:k])
+	copy(s.names[1:k+1], s.names[:k])
+	s.ids[0], s.names[0] = id, name
+}
+
+// Remove removes a chatter from the list, e.g. because they have asked for
+// privacy.
+func (s *Speakers) Remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := s.indexLocked(id)
+	if k < 0 {
+		return
+	}
+	s.ids = append(s.ids[:k], s.ids[k+1:]...)
+	s.names = append(s.names[:k], s.names[k+1:]...)
+}
+
+// Pick selects a recent chatter's name using the given random number.
+// The result is the empty string if there are no recent chatters.
+func (s *Speakers) Pick(x uint32) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.names) == 0 {
+		return ""
+	}
+	return s.names[uint64(x)*uint64(len(s.names))>>32]
+}
+
+func (s *Speakers) indexLocked(id string) int {
+	for i, v := range s.ids {
+		if v == id {
+			return i
+		}
+	}
+	return -1
+}
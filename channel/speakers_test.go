@@ -0,0 +1,40 @@
+package channel_test
+
+import (
+	"testing"
+
+	"github.com/zephyrtronium/robot/channel"
+)
+
+func TestSpeakers(t *testing.T) {
+	s := channel.NewSpeakers(3)
+	if got := s.Pick(0); got != "" {
+		t.Errorf("picked %q from empty list", got)
+	}
+	s.Add("1", "Bocchi")
+	s.Add("2", "Ryou")
+	s.Add("3", "Nijika")
+	s.Add("4", "Kita")
+	// Most recent first, with Bocchi dropped.
+	want := []string{"Kita", "Nijika", "Ryou"}
+	for i, w := range want {
+		x := uint32((uint64(i)<<32 + 1<<31) / uint64(len(want)))
+		if got := s.Pick(x); got != w {
+			t.Errorf("wrong pick %d: want %q, got %q", i, w, got)
+		}
+	}
+	// Re-adding moves to the front without growing.
+	s.Add("2", "Ryou")
+	if got := s.Pick(0); got != "Ryou" {
+		t.Errorf("wrong most recent: want %q, got %q", "Ryou", got)
+	}
+	if got := s.Pick(^uint32(0)); got != "Nijika" {
+		t.Errorf("wrong least recent: want %q, got %q", "Nijika", got)
+	}
+	s.Remove("2")
+	s.Remove("3")
+	s.Remove("4")
+	if got := s.Pick(0); got != "" {
+		t.Errorf("picked %q after removing all", got)
+	}
+}
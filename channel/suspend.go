@@ -0,0 +1,16 @@
+package channel
+
+import "time"
+
+// Suspend is the chat restrictions under which a channel stops learning.
+// Moderators tend to restrict chat when it is under stress, e.g. during raids
+// or spam waves, and what people say then is rarely worth learning.
+type Suspend struct {
+	// EmoteOnly suspends learning in emote-only mode.
+	EmoteOnly bool
+	// SubOnly suspends learning in subscriber-only mode.
+	SubOnly bool
+	// Slow suspends learning in slow mode at least this slow.
+	// If it is zero, slow mode does not suspend learning.
+	Slow time.Duration
+}
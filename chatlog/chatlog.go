@@ -0,0 +1,289 @@
+// Package chatlog follows chat log files written by other tools, so that the
+// bot can learn from chat without connecting to it.
+package chatlog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/zephyrtronium/tmi"
+)
+
+// Format is a layout of chat log files.
+type Format int
+
+const (
+	// Chatterino is the layout of Chatterino logs, a directory per channel
+	// holding a file per day named like bocchi-2024-08-05.log, with lines
+	// like [12:34:56] nijika: text.
+	Chatterino Format = iota
+	// Justlog is the layout of justlog, a directory per channel ID holding
+	// year/month/day/channel.txt files of raw IRC lines.
+	Justlog
+	// Plain is text with one message per line and nothing else. It can
+	// only be read, not followed.
+	Plain
+	// RustyLog is the JSON served by rustlog, either an object with a
+	// messages array or one message object per line. It can only be read,
+	// not followed.
+	RustyLog
+)
+
+// ParseFormat parses the name of a log format.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "", "chatterino":
+		return Chatterino, nil
+	case "justlog":
+		return Justlog, nil
+	case "plain":
+		return Plain, nil
+	case "rustylog", "rustlog":
+		return RustyLog, nil
+	default:
+		return 0, fmt.Errorf("unknown log format %q", s)
+	}
+}
+
+// Event is something that happened in a channel's chat.
+type Event struct {
+	// Kind is "message", "deleted", or "cleared".
+	Kind string
+	// ID is the ID of the message sent or deleted. For Chatterino logs,
+	// which don't record IDs, it is derived from the line's position.
+	ID string
+	// Sender is the user ID of the sender of a message or of the user whose
+	// messages were cleared. It is empty when an entire chat is cleared or
+	// when the log doesn't record user IDs.
+	Sender string
+	// Login is the login of a message's sender when the log records it but
+	// not the user ID, as in Chatterino logs. Privacy and userhashes use user
+	// IDs, so consumers must resolve it before attributing the message.
+	Login string
+	// Name is the display name of a message's sender.
+	Name string
+	// Text is the text of a message sent or deleted.
+	Text string
+	// Time is the time of the event.
+	Time time.Time
+	// IRC is the raw message for Justlog logs.
+	IRC *tmi.Message
+}
+
+// Tail follows the newest log file of one channel.
+type Tail struct {
+	// dir is the channel's log directory.
+	dir string
+	// format is the layout of the logs.
+	format Format
+	// file is the file being followed. It is empty before the first poll.
+	file string
+	// off is the offset in file through the last complete line read.
+	off int64
+}
+
+// New creates a tail of the logs of a channel in dir.
+// Only lines written after the first poll are read.
+func New(dir string, format Format) *Tail {
+	return &Tail{dir: dir, format: format}
+}
+
+// Poll returns the events logged since the last poll. When the tool writing
+// logs moves on to a new file, the rest of the old one is read first.
+// Lines which aren't chat events are skipped.
+func (t *Tail) Poll() ([]Event, error) {
+	newest, err := t.newest()
+	if err != nil {
+		return nil, err
+	}
+	if newest == "" {
+		// No logs yet.
+		return nil, nil
+	}
+	if t.file == "" {
+		// Start from the end of whatever is logged now.
+		fi, err := os.Stat(newest)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't start log tail: %w", err)
+		}
+		t.file, t.off = newest, fi.Size()
+		return nil, nil
+	}
+	var r []Event
+	if newest != t.file {
+		// Finish the old file. If it's gone, so be it.
+		r, err = t.read(r)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return r, err
+		}
+		t.file, t.off = newest, 0
+	}
+	return t.read(r)
+}
+
+// read appends the events in complete lines after the offset in the current
+// file and advances the offset past them.
+func (t *Tail) read(r []Event) ([]Event, error) {
+	f, err := os.Open(t.file)
+	if err != nil {
+		return r, fmt.Errorf("couldn't open log: %w", err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return r, fmt.Errorf("couldn't stat log: %w", err)
+	}
+	if fi.Size() < t.off {
+		// Truncated. Start over.
+		t.off = 0
+	}
+	b, err := io.ReadAll(io.NewSectionReader(f, t.off, fi.Size()-t.off))
+	if err != nil {
+		return r, fmt.Errorf("couldn't read log: %w", err)
+	}
+	for {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			// Leave the partial line for the next poll.
+			break
+		}
+		line := strings.TrimRight(string(b[:i]), "\r")
+		if ev, ok := t.parse(line, t.off); ok {
+			r = append(r, ev)
+		}
+		b = b[i+1:]
+		t.off += int64(i + 1)
+	}
+	return r, nil
+}
+
+// parse parses a line at the given offset in the current file.
+func (t *Tail) parse(line string, off int64) (Event, bool) {
+	switch t.format {
+	case Justlog:
+		return parseJustlog(line)
+	default:
+		return parseChatterino(line, t.file, off)
+	}
+}
+
+// newest finds the newest log file in the channel's directory. It returns the
+// empty string if there are none.
+func (t *Tail) newest() (string, error) {
+	switch t.format {
+	case Justlog:
+		// Descend through the largest year, month, and day.
+		dir := t.dir
+		for range 3 {
+			ents, err := os.ReadDir(dir)
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return "", nil
+				}
+				return "", fmt.Errorf("couldn't read log directory: %w", err)
+			}
+			best, n := "", -1
+			for _, e := range ents {
+				k, err := strconv.Atoi(e.Name())
+				if err == nil && e.IsDir() && k > n {
+					best, n = e.Name(), k
+				}
+			}
+			if best == "" {
+				return "", nil
+			}
+			dir = filepath.Join(dir, best)
+		}
+		p := filepath.Join(dir, "channel.txt")
+		if _, err := os.Stat(p); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return "", nil
+			}
+			return "", fmt.Errorf("couldn't stat log: %w", err)
+		}
+		return p, nil
+	default:
+		// Names end with the date, so the greatest name is the newest.
+		m, err := filepath.Glob(filepath.Join(t.dir, "*.log"))
+		if err != nil {
+			return "", fmt.Errorf("couldn't list logs: %w", err)
+		}
+		if len(m) == 0 {
+			return "", nil
+		}
+		return slices.Max(m), nil
+	}
+}
+
+// parseChatterino parses a Chatterino log line, which is a message like
+// [12:34:56] nijika: text. Other lines, like timeout notices, are skipped.
+// The date comes from the name of the file.
+func parseChatterino(line, file string, off int64) (Event, bool) {
+	if len(line) < len("[00:00:00] ") || line[0] != '[' || line[9] != ']' || line[10] != ' ' {
+		return Event{}, false
+	}
+	name, text, ok := strings.Cut(line[11:], ": ")
+	if !ok || name == "" || strings.ContainsAny(name, " \t") || text == "" {
+		return Event{}, false
+	}
+	base := filepath.Base(file)
+	day := strings.TrimSuffix(base, ".log")
+	if len(day) >= len("2006-01-02") {
+		day = day[len(day)-len("2006-01-02"):]
+	}
+	ts, err := time.ParseInLocation("2006-01-02 15:04:05", day+" "+line[1:9], time.Local)
+	if err != nil {
+		ts = time.Now()
+	}
+	ev := Event{
+		Kind:  "message",
+		ID:    base + ":" + strconv.FormatInt(off, 10),
+		Login: strings.ToLower(name),
+		Name:  name,
+		Text:  text,
+		Time:  ts,
+	}
+	return ev, true
+}
+
+// parseJustlog parses a raw IRC line from justlog. PRIVMSG, CLEARMSG, and
+// CLEARCHAT are events; other commands are skipped.
+func parseJustlog(line string) (Event, bool) {
+	// Parsing needs the line ending that reading lines removes.
+	msg, err := tmi.Parse(strings.NewReader(line + "\r\n"))
+	if err != nil {
+		return Event{}, false
+	}
+	switch msg.Command {
+	case "PRIVMSG":
+		id, _ := msg.Tag("id")
+		sender, _ := msg.Tag("user-id")
+		ev := Event{
+			Kind:   "message",
+			ID:     id,
+			Sender: sender,
+			Name:   msg.DisplayName(),
+			Text:   msg.Trailing,
+			Time:   msg.Time(),
+			IRC:    msg,
+		}
+		return ev, true
+	case "CLEARMSG":
+		id, _ := msg.Tag("target-msg-id")
+		return Event{Kind: "deleted", ID: id, Text: msg.Trailing, Time: msg.Time(), IRC: msg}, true
+	case "CLEARCHAT":
+		user, _ := msg.Tag("target-user-id")
+		return Event{Kind: "cleared", Sender: user, Time: msg.Time(), IRC: msg}, true
+	default:
+		return Event{}, false
+	}
+}
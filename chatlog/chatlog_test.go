@@ -0,0 +1,205 @@
+package chatlog_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/chatlog"
+)
+
+func appendFile(t *testing.T, name, text string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(text); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func poll(t *testing.T, tail *chatlog.Tail) []chatlog.Event {
+	t.Helper()
+	ev, err := tail.Poll()
+	if err != nil {
+		t.Fatalf("couldn't poll: %v", err)
+	}
+	return ev
+}
+
+func TestChatterino(t *testing.T) {
+	dir := t.TempDir()
+	day1 := filepath.Join(dir, "bocchi-2024-08-05.log")
+	day2 := filepath.Join(dir, "bocchi-2024-08-06.log")
+	tail := chatlog.New(dir, chatlog.Chatterino)
+	if ev := poll(t, tail); len(ev) != 0 {
+		t.Errorf("events without logs: %+v", ev)
+	}
+	appendFile(t, day1, "# Start logging at 2024-08-05 00:00:00\n[00:00:01] ryo: already logged\n")
+	if ev := poll(t, tail); len(ev) != 0 {
+		t.Errorf("events from before tailing: %+v", ev)
+	}
+	appendFile(t, day1, "[12:34:56] Nijika: kessoku band\n[12:35:00] kita has been timed out for 10s.\n[12:35:01] ryo: partial")
+	ev := poll(t, tail)
+	if len(ev) != 1 {
+		t.Fatalf("wrong events: %+v", ev)
+	}
+	if ev[0].Kind != "message" || ev[0].Sender != "" || ev[0].Login != "nijika" || ev[0].Name != "Nijika" || ev[0].Text != "kessoku band" {
+		t.Errorf("wrong message: %+v", ev[0])
+	}
+	if got := ev[0].Time.Format("2006-01-02 15:04:05"); got != "2024-08-05 12:34:56" {
+		t.Errorf("wrong time: %s", got)
+	}
+	appendFile(t, day1, " line\n")
+	appendFile(t, day2, "[00:00:02] bocchi: guitar\n")
+	ev = poll(t, tail)
+	if len(ev) != 2 || ev[0].Text != "partial line" || ev[1].Text != "guitar" {
+		t.Fatalf("wrong events across files: %+v", ev)
+	}
+	if ev[0].ID == ev[1].ID || ev[0].ID == "" {
+		t.Errorf("bad IDs: %q and %q", ev[0].ID, ev[1].ID)
+	}
+	if ev := poll(t, tail); len(ev) != 0 {
+		t.Errorf("events repeated: %+v", ev)
+	}
+}
+
+func TestJustlog(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "2024", "9", "30", "channel.txt")
+	cur := filepath.Join(dir, "2024", "10", "1", "channel.txt")
+	appendFile(t, old, "")
+	tail := chatlog.New(dir, chatlog.Justlog)
+	poll(t, tail)
+	lines := "@id=abc;user-id=1;display-name=Nijika;tmi-sent-ts=1727740800000 :nijika!nijika@nijika.tmi.twitch.tv PRIVMSG #bocchi :kessoku band\r\n" +
+		"@target-msg-id=abc;tmi-sent-ts=1727740801000 :tmi.twitch.tv CLEARMSG #bocchi :kessoku band\r\n" +
+		"@room-id=2;target-user-id=1;tmi-sent-ts=1727740802000 :tmi.twitch.tv CLEARCHAT #bocchi :nijika\r\n" +
+		"@msg-id=sub :tmi.twitch.tv USERNOTICE #bocchi\r\n"
+	appendFile(t, cur, lines)
+	ev := poll(t, tail)
+	if len(ev) != 3 {
+		t.Fatalf("wrong events: %+v", ev)
+	}
+	if ev[0].Kind != "message" || ev[0].ID != "abc" || ev[0].Sender != "1" || ev[0].Name != "Nijika" || ev[0].Text != "kessoku band" || ev[0].IRC == nil {
+		t.Errorf("wrong message: %+v", ev[0])
+	}
+	if ev[1].Kind != "deleted" || ev[1].ID != "abc" {
+		t.Errorf("wrong delete: %+v", ev[1])
+	}
+	if ev[2].Kind != "cleared" || ev[2].Sender != "1" {
+		t.Errorf("wrong clear: %+v", ev[2])
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	cases := []struct {
+		in   string
+		want chatlog.Format
+		err  bool
+	}{
+		{"", chatlog.Chatterino, false},
+		{"Chatterino", chatlog.Chatterino, false},
+		{"justlog", chatlog.Justlog, false},
+		{"plain", chatlog.Plain, false},
+		{"rustylog", chatlog.RustyLog, false},
+		{"irssi", 0, true},
+	}
+	for _, c := range cases {
+		got, err := chatlog.ParseFormat(c.in)
+		if (err != nil) != c.err || got != c.want {
+			t.Errorf("ParseFormat(%q): want %v (err %t), got %v, %v", c.in, c.want, c.err, got, err)
+		}
+	}
+}
+
+func read(t *testing.T, name, text string, format chatlog.Format) []chatlog.Event {
+	t.Helper()
+	var r []chatlog.Event
+	err := chatlog.Read(strings.NewReader(text), name, format, func(ev *chatlog.Event) error {
+		r = append(r, *ev)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("couldn't read: %v", err)
+	}
+	return r
+}
+
+func TestReadChatterino(t *testing.T) {
+	text := "# Start logging at 2024-08-05 00:00:00\r\n[12:34:56] Nijika: kessoku band\r\n[12:35:00] kita has been timed out for 10s.\r\n[12:35:01] ryo: no newline"
+	ev := read(t, "logs/bocchi-2024-08-05.log", text, chatlog.Chatterino)
+	if len(ev) != 2 {
+		t.Fatalf("wrong events: %+v", ev)
+	}
+	if ev[0].Sender != "" || ev[0].Login != "nijika" || ev[0].Text != "kessoku band" || ev[1].Text != "no newline" {
+		t.Errorf("wrong messages: %+v", ev)
+	}
+	if got := ev[1].Time.Format("2006-01-02 15:04:05"); got != "2024-08-05 12:35:01" {
+		t.Errorf("wrong time: %s", got)
+	}
+	// IDs must be stable so that reading the same file twice gives the same
+	// messages.
+	again := read(t, "bocchi-2024-08-05.log", text, chatlog.Chatterino)
+	if ev[0].ID != again[0].ID || ev[1].ID != again[1].ID || ev[0].ID == ev[1].ID {
+		t.Errorf("unstable IDs: %q %q and %q %q", ev[0].ID, ev[1].ID, again[0].ID, again[1].ID)
+	}
+}
+
+func TestReadPlain(t *testing.T) {
+	ev := read(t, "quotes.txt", "kessoku band\n\n  bocchi the rock  \n", chatlog.Plain)
+	if len(ev) != 2 || ev[0].Text != "kessoku band" || ev[1].Text != "bocchi the rock" {
+		t.Fatalf("wrong events: %+v", ev)
+	}
+	if ev[0].Sender != "" || !ev[0].Time.IsZero() || ev[0].ID == ev[1].ID {
+		t.Errorf("wrong metadata: %+v", ev)
+	}
+}
+
+func TestReadRustyLog(t *testing.T) {
+	text := `{"messages": [
+		{"id": "abc", "text": "kessoku band", "username": "nijika", "displayName": "Nijika", "timestamp": "2024-10-01T00:00:00Z", "type": 1, "tags": {"user-id": "1"}},
+		{"text": "nijika has been timed out", "timestamp": "2024-10-01T00:00:01Z", "type": 2},
+		{"raw": "@id=def;user-id=2;display-name=Ryo;tmi-sent-ts=1727740802000 :ryo!ryo@ryo.tmi.twitch.tv PRIVMSG #bocchi :bass\r\n", "type": 1}
+	]}
+	{"id": "ghi", "text": "guitar", "username": "bocchi", "timestamp": "2024-10-01T00:00:03Z", "type": 1}
+	`
+	ev := read(t, "bocchi.json", text, chatlog.RustyLog)
+	if len(ev) != 3 {
+		t.Fatalf("wrong events: %+v", ev)
+	}
+	if ev[0].ID != "abc" || ev[0].Sender != "1" || ev[0].Name != "Nijika" || ev[0].Text != "kessoku band" || !ev[0].Time.Equal(time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("wrong first message: %+v", ev[0])
+	}
+	if ev[1].ID != "def" || ev[1].Sender != "2" || ev[1].Text != "bass" {
+		t.Errorf("wrong raw message: %+v", ev[1])
+	}
+	if ev[2].ID != "ghi" || ev[2].Sender != "" || ev[2].Login != "bocchi" || ev[2].Name != "bocchi" {
+		t.Errorf("wrong line message: %+v", ev[2])
+	}
+}
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		name string
+		want chatlog.Format
+		ok   bool
+	}{
+		{"bocchi-2024-08-05.log", chatlog.Chatterino, true},
+		{"bocchi.json", chatlog.RustyLog, true},
+		{"bocchi.NDJSON", chatlog.RustyLog, true},
+		{"channel.txt", 0, false},
+	}
+	for _, c := range cases {
+		got, ok := chatlog.Detect(c.name)
+		if got != c.want || ok != c.ok {
+			t.Errorf("Detect(%q): want %v, %t; got %v, %t", c.name, c.want, c.ok, got, ok)
+		}
+	}
+}
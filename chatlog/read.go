@@ -0,0 +1,151 @@
+package chatlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Detect guesses the format of a log file from its name: .log files are
+// Chatterino logs, and .json and .ndjson files are RustyLog. It returns false
+// for any other name.
+func Detect(name string) (Format, bool) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".log":
+		return Chatterino, true
+	case ".json", ".ndjson":
+		return RustyLog, true
+	}
+	return 0, false
+}
+
+// Read reads every event in a complete log file and calls f with each in
+// order. name is the name of the file, which gives the date of Chatterino
+// logs and the IDs of messages in logs which don't record them.
+// Events in plain logs have no sender and no time.
+// If f returns an error, Read stops and returns it.
+func Read(r io.Reader, name string, format Format, f func(*Event) error) error {
+	if format == RustyLog {
+		return readRustyLog(r, f)
+	}
+	br := bufio.NewReader(r)
+	var off int64
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			ev, ok := parseLine(strings.TrimRight(line, "\r\n"), name, off, format)
+			if ok {
+				if err := f(&ev); err != nil {
+					return err
+				}
+			}
+			off += int64(len(line))
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("couldn't read log: %w", err)
+		}
+	}
+}
+
+// parseLine parses a line at the given offset in a file of a line-based
+// format.
+func parseLine(line, name string, off int64, format Format) (Event, bool) {
+	switch format {
+	case Justlog:
+		return parseJustlog(line)
+	case Plain:
+		line = strings.TrimSpace(line)
+		if line == "" {
+			return Event{}, false
+		}
+		ev := Event{
+			Kind: "message",
+			ID:   filepath.Base(name) + ":" + strconv.FormatInt(off, 10),
+			Text: line,
+		}
+		return ev, true
+	default:
+		return parseChatterino(line, name, off)
+	}
+}
+
+// rustylogMessage is a message in RustyLog JSON.
+type rustylogMessage struct {
+	ID          string            `json:"id"`
+	Text        string            `json:"text"`
+	Username    string            `json:"username"`
+	DisplayName string            `json:"displayName"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Type        int               `json:"type"`
+	Raw         string            `json:"raw"`
+	Tags        map[string]string `json:"tags"`
+}
+
+// readRustyLog reads RustyLog JSON, which is either objects with a messages
+// array or message objects themselves, one after another.
+func readRustyLog(r io.Reader, f func(*Event) error) error {
+	dec := json.NewDecoder(r)
+	for {
+		var v struct {
+			Messages []rustylogMessage `json:"messages"`
+			rustylogMessage
+		}
+		if err := dec.Decode(&v); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("couldn't decode log: %w", err)
+		}
+		if v.Messages == nil {
+			v.Messages = []rustylogMessage{v.rustylogMessage}
+		}
+		for i := range v.Messages {
+			ev, ok := parseRustyLog(&v.Messages[i])
+			if !ok {
+				continue
+			}
+			if err := f(&ev); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// parseRustyLog converts a RustyLog message to an event. Messages with raw
+// IRC are parsed like justlog lines; otherwise only chat messages, which
+// have type 1, are events.
+func parseRustyLog(m *rustylogMessage) (Event, bool) {
+	if m.Raw != "" {
+		return parseJustlog(strings.TrimRight(m.Raw, "\r\n"))
+	}
+	if m.Type != 1 || m.Text == "" {
+		return Event{}, false
+	}
+	ev := Event{
+		Kind:   "message",
+		ID:     m.ID,
+		Sender: m.Tags["user-id"],
+		Name:   m.DisplayName,
+		Text:   m.Text,
+		Time:   m.Timestamp,
+	}
+	if ev.ID == "" {
+		ev.ID = m.Tags["id"]
+	}
+	if ev.Sender == "" {
+		ev.Login = strings.ToLower(m.Username)
+	}
+	if ev.Name == "" {
+		ev.Name = m.Username
+	}
+	return ev, true
+}
@@ -0,0 +1,126 @@
+// Package classify consults external content classifiers about generated
+// messages before the bot sends them.
+package classify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Classifier rates text. Scores are by category, e.g. toxicity, and
+// conventionally range from 0 to 1 with higher scores being worse.
+type Classifier interface {
+	Classify(ctx context.Context, text string) (map[string]float64, error)
+}
+
+// Command is a [Classifier] which runs a local program with the text on its
+// standard input and reads a JSON object of scores from its standard output.
+type Command struct {
+	// Args is the program and its arguments.
+	Args []string
+}
+
+// Classify runs the command.
+func (c *Command) Classify(ctx context.Context, text string) (map[string]float64, error) {
+	cmd := exec.CommandContext(ctx, c.Args[0], c.Args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	b, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't run %s: %w (%s)", c.Args[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	var r map[string]float64
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("couldn't decode scores from %s: %w", c.Args[0], err)
+	}
+	return r, nil
+}
+
+// HTTP is a [Classifier] which POSTs the text as text/plain to an API which
+// responds with a JSON object of scores.
+type HTTP struct {
+	// URL is the endpoint of the API.
+	URL string
+	// Client is the HTTP client to use. If it is nil, http.DefaultClient is
+	// used.
+	Client *http.Client
+}
+
+// Classify requests scores from the API.
+func (h *HTTP) Classify(ctx context.Context, text string) (map[string]float64, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", h.URL, strings.NewReader(text))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't make classifier request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("Accept", "application/json")
+	cl := h.Client
+	if cl == nil {
+		cl = http.DefaultClient
+	}
+	resp, err := cl.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get classification: %w", err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read classification: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("classifier returned %s: %s", resp.Status, bytes.TrimSpace(b))
+	}
+	var r map[string]float64
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("couldn't decode classification: %w", err)
+	}
+	return r, nil
+}
+
+// Policy decides whether text may be sent according to a classifier.
+type Policy struct {
+	// Classifier is the classifier to consult.
+	Classifier Classifier
+	// Thresholds are the highest allowed score for each category.
+	// Categories without thresholds are ignored.
+	Thresholds map[string]float64
+	// FailClosed rejects text when the classifier fails.
+	// Otherwise, text is allowed when the classifier fails.
+	FailClosed bool
+	// Timeout is the maximum time to wait for the classifier.
+	// If it is not positive, only ctx bounds the wait.
+	Timeout time.Duration
+}
+
+// Allow reports whether text may be sent. If it may not, category is the
+// category which exceeded its threshold, or empty if the classifier failed.
+// A non-nil error describes a classifier failure, in which case ok follows the
+// failure policy. A nil policy allows everything.
+func (p *Policy) Allow(ctx context.Context, text string) (ok bool, category string, err error) {
+	if p == nil {
+		return true, "", nil
+	}
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+	scores, err := p.Classifier.Classify(ctx, text)
+	if err != nil {
+		return !p.FailClosed, "", err
+	}
+	for c, lim := range p.Thresholds {
+		if scores[c] > lim {
+			return false, c, nil
+		}
+	}
+	return true, "", nil
+}
@@ -0,0 +1,77 @@
+package classify_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/zephyrtronium/robot/classify"
+)
+
+// fixed is a classifier which gives fixed scores.
+type fixed struct {
+	scores map[string]float64
+	err    error
+}
+
+func (f fixed) Classify(ctx context.Context, text string) (map[string]float64, error) {
+	return f.scores, f.err
+}
+
+func TestPolicy(t *testing.T) {
+	scores := map[string]float64{"toxicity": 0.3, "sexual": 0.7}
+	fail := errors.New("broken")
+	cases := []struct {
+		name     string
+		p        *classify.Policy
+		ok       bool
+		category string
+		err      bool
+	}{
+		{"nil", nil, true, "", false},
+		{"under", &classify.Policy{Classifier: fixed{scores: scores}, Thresholds: map[string]float64{"toxicity": 0.5}}, true, "", false},
+		{"over", &classify.Policy{Classifier: fixed{scores: scores}, Thresholds: map[string]float64{"sexual": 0.5}}, false, "sexual", false},
+		{"missing", &classify.Policy{Classifier: fixed{scores: scores}, Thresholds: map[string]float64{"violence": 0.1}}, true, "", false},
+		{"fail-open", &classify.Policy{Classifier: fixed{err: fail}, Thresholds: map[string]float64{"sexual": 0.5}}, true, "", true},
+		{"fail-closed", &classify.Policy{Classifier: fixed{err: fail}, Thresholds: map[string]float64{"sexual": 0.5}, FailClosed: true}, false, "", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ok, category, err := c.p.Allow(context.Background(), "bocchi")
+			if ok != c.ok {
+				t.Errorf("wrong decision: want %t, got %t", c.ok, ok)
+			}
+			if category != c.category {
+				t.Errorf("wrong category: want %q, got %q", c.category, category)
+			}
+			if (err != nil) != c.err {
+				t.Errorf("wrong error: want error %t, got %v", c.err, err)
+			}
+		})
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(b), "guitar") {
+			fmt.Fprint(w, `{"toxicity": 0.9}`)
+			return
+		}
+		fmt.Fprint(w, `{"toxicity": 0.1}`)
+	}))
+	defer srv.Close()
+	h := classify.HTTP{URL: srv.URL}
+	r, err := h.Classify(context.Background(), "guitar hero")
+	if err != nil {
+		t.Fatalf("couldn't classify: %v", err)
+	}
+	if r["toxicity"] != 0.9 {
+		t.Errorf("wrong scores: %v", r)
+	}
+}
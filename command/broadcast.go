@@ -0,0 +1,89 @@
+package command
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"path"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/channel"
+	"github.com/zephyrtronium/robot/locale"
+)
+
+// Broadcast sends a message to every channel, or to those whose names match a
+// pattern. Messages go out one at a time, so they wait on platform rate limits
+// rather than bursting past them.
+//   - to: Pattern of channel names as for [path.Match], e.g. #* for all Twitch
+//     channels. Optional.
+//   - msg: Message to send. If it is empty, each channel gets a newly
+//     generated message instead.
+func Broadcast(ctx context.Context, robo *Robot, call *Invocation) {
+	pat := call.Args["to"]
+	if pat == "" {
+		pat = "*"
+	}
+	if _, err := path.Match(pat, ""); err != nil {
+		call.Channel.Message(ctx, call.Message.ID, say(call, "broadcast-pattern", locale.Args{"Pattern": pat}))
+		return
+	}
+	msg := call.Args["msg"]
+	n := 0
+	for nm, ch := range robo.Channels.All() {
+		if ok, _ := path.Match(pat, nm); !ok {
+			continue
+		}
+		if ch.Halted.Load() {
+			robo.Log.InfoContext(ctx, "not broadcasting to halted channel", slog.String("in", nm))
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		text := msg
+		if text == "" {
+			text = broadcastSpeak(ctx, robo, call, ch)
+			if text == "" {
+				continue
+			}
+		}
+		robo.Log.InfoContext(ctx, "broadcast", slog.String("in", nm), slog.String("text", text))
+		ch.Message(ctx, "", text)
+		n++
+	}
+	call.Channel.Message(ctx, call.Message.ID, say(call, "broadcast", locale.Args{"Count": n}))
+}
+
+// broadcastSpeak generates a message for a broadcast to a channel.
+// It returns the empty string if there is nothing to say.
+func broadcastSpeak(ctx context.Context, robo *Robot, call *Invocation, ch *channel.Channel) string {
+	start := time.Now()
+	m, trace, err := brain.Speak(ctx, robo.Brain, ch.Send, "")
+	cost := time.Since(start)
+	if err != nil {
+		robo.Log.ErrorContext(ctx, "couldn't speak for broadcast", slog.Any("err", err), slog.String("in", ch.Name))
+		return ""
+	}
+	if m == "" {
+		return ""
+	}
+	e := ch.Emotes.Pick(rand.Uint32())
+	s := m + " " + e
+	if err := robo.Spoken.Record(ctx, ch.Send, s, trace, call.Message.Time(), cost, m, e, ""); err != nil {
+		robo.Log.ErrorContext(ctx, "couldn't record trace", slog.Any("err", err))
+		return ""
+	}
+	if ch.Block.MatchString(s) || !ch.Profanity.Sendable(s) {
+		robo.Log.WarnContext(ctx, "generated blocked message for broadcast", slog.String("in", ch.Name), slog.String("text", m))
+		return ""
+	}
+	if !Classify(ctx, ch, s) {
+		return ""
+	}
+	if !ch.Outputs.Claim(ch.Name, m, time.Now()) {
+		robo.Log.InfoContext(ctx, "generated message another channel just sent", slog.String("in", ch.Name), slog.String("text", m))
+		return ""
+	}
+	return lenlimit(s, 450)
+}
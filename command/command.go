@@ -6,6 +6,11 @@ import (
 
 	"github.com/zephyrtronium/robot/brain"
 	"github.com/zephyrtronium/robot/channel"
+	"github.com/zephyrtronium/robot/credit"
+	"github.com/zephyrtronium/robot/emotes"
+	"github.com/zephyrtronium/robot/ignore"
+	"github.com/zephyrtronium/robot/journal"
+	"github.com/zephyrtronium/robot/locale"
 	"github.com/zephyrtronium/robot/message"
 	"github.com/zephyrtronium/robot/privacy"
 	"github.com/zephyrtronium/robot/spoken"
@@ -19,7 +24,12 @@ type Robot struct {
 	Channels *syncmap.Map[string, *channel.Channel]
 	Brain    brain.Brain
 	Privacy  *privacy.List
+	Credit   *credit.Board
 	Spoken   *spoken.History
+	Emotes   *emotes.Store
+	Ignores  *ignore.Store
+	Journal  *journal.Journal
+	Commands *Router
 }
 
 // Invocation is a command invocation. An Invocation and its fields must not
@@ -29,12 +39,19 @@ type Invocation struct {
 	Channel *channel.Channel
 	// Message is the message which triggered the invocation. It is always
 	// non-nil, but not all fields are guaranteed to be populated.
-	Message *message.Received
+	Message *message.Incoming
 	// Args is the parsed arguments to the command.
 	Args map[string]string
+	// Level is the privilege level of the invoker.
+	Level Level
 	// Hasher is a user hasher for the command's use.
 	Hasher userhash.Hasher
 }
 
 // Func is a command function.
 type Func func(ctx context.Context, robo *Robot, call *Invocation)
+
+// say produces the text of a fixed response in the invocation's channel.
+func say(call *Invocation, name string, args locale.Args) string {
+	return call.Channel.Templates.Text(name, args)
+}
@@ -0,0 +1,51 @@
+package command
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/zephyrtronium/robot/locale"
+)
+
+// Credit opts the invoker in to or out of the leaderboard and callouts.
+//   - opt: Either on or off.
+func Credit(ctx context.Context, robo *Robot, call *Invocation) {
+	e := call.Channel.Emotes.Pick(rand.Uint32())
+	opt := call.Args["opt"]
+	if !strings.EqualFold(opt, "on") && !strings.EqualFold(opt, "off") {
+		call.Channel.Message(ctx, call.Message.ID, say(call, "credit-usage", nil))
+		return
+	}
+	if strings.EqualFold(opt, "off") {
+		if err := robo.Credit.OptOut(ctx, call.Message.Sender); err != nil {
+			robo.Log.ErrorContext(ctx, "credit opt-out failed", slog.Any("err", err), slog.String("channel", call.Channel.Name))
+			call.Channel.Message(ctx, call.Message.ID, say(call, "credit-fail", nil))
+			return
+		}
+		call.Channel.Message(ctx, call.Message.ID, say(call, "credit-off", locale.Args{"Emote": e}))
+		return
+	}
+	if err := robo.Credit.OptIn(ctx, call.Message.Sender); err != nil {
+		robo.Log.ErrorContext(ctx, "credit opt-in failed", slog.Any("err", err), slog.String("channel", call.Channel.Name))
+		call.Channel.Message(ctx, call.Message.ID, say(call, "credit-fail", nil))
+		return
+	}
+	call.Channel.Message(ctx, call.Message.ID, say(call, "credit-on", locale.Args{"Emote": e}))
+}
+
+// Top lists the users who have opted in to the leaderboard with the most
+// messages learned in the channel.
+func Top(ctx context.Context, robo *Robot, call *Invocation) {
+	if !call.Channel.Leaderboard {
+		call.Channel.Message(ctx, call.Message.ID, say(call, "top-disabled", nil))
+		return
+	}
+	top, err := robo.Credit.Top(ctx, call.Channel.Name, 5)
+	if err != nil {
+		robo.Log.ErrorContext(ctx, "couldn't list leaderboard", slog.Any("err", err), slog.String("channel", call.Channel.Name))
+		return
+	}
+	call.Channel.Message(ctx, call.Message.ID, say(call, "top", locale.Args{"Top": top}))
+}
@@ -3,6 +3,8 @@ package command
 import (
 	"context"
 	"log/slog"
+
+	"github.com/zephyrtronium/robot/locale"
 )
 
 // EchoIn sends a plain text message to any channel.
@@ -23,3 +25,20 @@ func EchoIn(ctx context.Context, robo *Robot, call *Invocation) {
 func Echo(ctx context.Context, robo *Robot, call *Invocation) {
 	call.Channel.Message(ctx, "", call.Args["msg"])
 }
+
+// Resume resumes handling messages in a channel which has been halted.
+//   - in: Name of the channel to resume.
+func Resume(ctx context.Context, robo *Robot, call *Invocation) {
+	t := call.Args["in"]
+	ch, _ := robo.Channels.Load(t)
+	if ch == nil {
+		robo.Log.WarnContext(ctx, "resume unknown channel", slog.String("target", t))
+		return
+	}
+	if !ch.Halted.Swap(false) {
+		call.Channel.Message(ctx, call.Message.ID, say(call, "resume-running", locale.Args{"Channel": t}))
+		return
+	}
+	robo.Log.InfoContext(ctx, "resumed channel", slog.String("target", t))
+	call.Channel.Message(ctx, call.Message.ID, say(call, "resume", locale.Args{"Channel": t}))
+}
@@ -21,10 +21,10 @@ func Effect(name, msg string) string {
 	case strings.EqualFold(name, "o"):
 		r = oize(msg)
 	default:
-		slog.Error("no such effect", slog.String("name", name), slog.String("msg", msg))
+		slog.Error("no such effect", slog.String("name", name), slog.String("text", msg))
 		return msg
 	}
-	slog.Info("applied effect", slog.String("name", name), slog.String("in", msg), slog.String("out", r))
+	slog.Info("applied effect", slog.String("name", name), slog.String("text", msg), slog.String("result", r))
 	return r
 }
 
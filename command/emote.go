@@ -0,0 +1,67 @@
+package command
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+
+	"github.com/zephyrtronium/robot/locale"
+)
+
+// AddEmote adds an emote to the channel's emote distribution and persists it.
+//   - emote: Emote to add.
+//   - weight: Relative weight of the emote. Optional; defaults to 1.
+func AddEmote(ctx context.Context, robo *Robot, call *Invocation) {
+	e := call.Args["emote"]
+	w := 1
+	if s := call.Args["weight"]; s != "" {
+		var err error
+		w, err = strconv.Atoi(s)
+		if err != nil || w <= 0 {
+			call.Channel.Message(ctx, call.Message.ID, say(call, "emote-weight", nil))
+			return
+		}
+	}
+	if err := robo.Emotes.Set(ctx, call.Channel.Name, e, w); err != nil {
+		robo.Log.ErrorContext(ctx, "couldn't save emote",
+			slog.Any("err", err),
+			slog.String("in", call.Channel.Name),
+			slog.String("emote", e),
+			slog.Int("weight", w),
+		)
+		call.Channel.Message(ctx, call.Message.ID, say(call, "emote-add-fail", nil))
+		return
+	}
+	call.Channel.Emotes.Set(e, w)
+	robo.Log.InfoContext(ctx, "added emote", slog.String("in", call.Channel.Name), slog.String("emote", e), slog.Int("weight", w))
+	call.Channel.Message(ctx, call.Message.ID, say(call, "emote-add", locale.Args{"Emote": e, "Weight": w}))
+}
+
+// RemoveEmote removes an emote added with [AddEmote].
+// Emotes given in the configuration or by third-party emote services are not
+// affected, and asking to remove only those says so.
+//   - emote: Emote to remove.
+func RemoveEmote(ctx context.Context, robo *Robot, call *Invocation) {
+	e := call.Args["emote"]
+	added, configured := call.Channel.Emotes.Has(e)
+	if !added {
+		msg := "emote-remove-missing"
+		if configured {
+			msg = "emote-remove-configured"
+		}
+		call.Channel.Message(ctx, call.Message.ID, say(call, msg, locale.Args{"Emote": e}))
+		return
+	}
+	if err := robo.Emotes.Set(ctx, call.Channel.Name, e, 0); err != nil {
+		robo.Log.ErrorContext(ctx, "couldn't remove emote",
+			slog.Any("err", err),
+			slog.String("in", call.Channel.Name),
+			slog.String("emote", e),
+		)
+		call.Channel.Message(ctx, call.Message.ID, say(call, "emote-remove-fail", nil))
+		return
+	}
+	call.Channel.Emotes.Set(e, 0)
+	robo.Log.InfoContext(ctx, "removed emote", slog.String("in", call.Channel.Name), slog.String("emote", e))
+	call.Channel.Message(ctx, call.Message.ID, say(call, "emote-remove", locale.Args{"Emote": e}))
+}
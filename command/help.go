@@ -0,0 +1,30 @@
+package command
+
+import (
+	"context"
+
+	"github.com/zephyrtronium/robot/locale"
+)
+
+// Help lists the commands the invoker can use, or describes one of them.
+//   - cmd: Name of the command to describe. Optional.
+func Help(ctx context.Context, robo *Robot, call *Invocation) {
+	if robo.Commands == nil {
+		return
+	}
+	if name := call.Args["cmd"]; name != "" {
+		c := robo.Commands.Lookup(call.Level, name)
+		if c == nil || c.Hidden {
+			call.Channel.Message(ctx, call.Message.ID, say(call, "help-unknown", locale.Args{"Command": name}))
+			return
+		}
+		args := locale.Args{"Command": c.Name, "Aliases": c.Aliases, "Usage": c.Usage}
+		call.Channel.Message(ctx, call.Message.ID, say(call, "help-command", args))
+		return
+	}
+	var names []string
+	for _, c := range robo.Commands.Available(call.Level) {
+		names = append(names, c.Name)
+	}
+	call.Channel.Message(ctx, call.Message.ID, say(call, "help", locale.Args{"Commands": names}))
+}
@@ -0,0 +1,40 @@
+package command
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/zephyrtronium/robot/locale"
+)
+
+// IgnoreUser ignores a user in every channel and persists it.
+//   - user: User ID or login to ignore.
+func IgnoreUser(ctx context.Context, robo *Robot, call *Invocation) {
+	u := call.Args["user"]
+	if err := robo.Ignores.Add(ctx, u); err != nil {
+		robo.Log.ErrorContext(ctx, "couldn't ignore user", slog.Any("err", err), slog.String("user", u))
+		call.Channel.Message(ctx, call.Message.ID, say(call, "ignore-user-fail", nil))
+		return
+	}
+	robo.Log.InfoContext(ctx, "ignored user", slog.String("user", u))
+	call.Channel.Message(ctx, call.Message.ID, say(call, "ignore-user", locale.Args{"User": u}))
+}
+
+// UnignoreUser stops ignoring a user ignored with [IgnoreUser].
+// Users ignored in the configuration are not affected.
+//   - user: User ID or login to unignore.
+func UnignoreUser(ctx context.Context, robo *Robot, call *Invocation) {
+	u := call.Args["user"]
+	ok, err := robo.Ignores.Remove(ctx, u)
+	if err != nil {
+		robo.Log.ErrorContext(ctx, "couldn't unignore user", slog.Any("err", err), slog.String("user", u))
+		call.Channel.Message(ctx, call.Message.ID, say(call, "unignore-user-fail", nil))
+		return
+	}
+	if !ok {
+		call.Channel.Message(ctx, call.Message.ID, say(call, "unignore-user-missing", locale.Args{"User": u}))
+		return
+	}
+	robo.Log.InfoContext(ctx, "unignored user", slog.String("user", u))
+	call.Channel.Message(ctx, call.Message.ID, say(call, "unignore-user", locale.Args{"User": u}))
+}
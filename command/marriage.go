@@ -2,13 +2,13 @@ package command
 
 import (
 	"context"
-	"fmt"
 	"log/slog"
 	"math"
 	"math/rand/v2"
 	"time"
 
 	"github.com/zephyrtronium/robot/channel"
+	"github.com/zephyrtronium/robot/locale"
 )
 
 func score(h *channel.History, user string) float64 {
@@ -68,10 +68,10 @@ func Affection(ctx context.Context, robo *Robot, call *Invocation) {
 	e := call.Channel.Emotes.Pick(rand.Uint32())
 	if x == 0 {
 		// possible!
-		call.Channel.Message(ctx, call.Message.ID, "literally zero "+e)
+		call.Channel.Message(ctx, call.Message.ID, say(call, "affection-zero", locale.Args{"Emote": e}))
 		return
 	}
-	call.Channel.Message(ctx, call.Message.ID, fmt.Sprintf("about %f %s", x, e))
+	call.Channel.Message(ctx, call.Message.ID, say(call, "affection", locale.Args{"Score": x, "Emote": e}))
 }
 
 type partnerKey struct{}
@@ -86,8 +86,9 @@ type partner struct {
 func Marry(ctx context.Context, robo *Robot, call *Invocation) {
 	x := score(call.Channel.History, call.Message.Sender)
 	e := call.Channel.Emotes.Pick(rand.Uint32())
+	args := locale.Args{"Emote": e, "Partnership": call.Args["partnership"]}
 	if x < 10 {
-		call.Channel.Message(ctx, call.Message.ID, "no "+e)
+		call.Channel.Message(ctx, call.Message.ID, say(call, "marry-no", args))
 		return
 	}
 	me := &partner{who: call.Message.Sender, until: call.Message.Time().Add(time.Hour)}
@@ -95,7 +96,7 @@ func Marry(ctx context.Context, robo *Robot, call *Invocation) {
 		l, ok := call.Channel.Extra.LoadOrStore(partnerKey{}, me)
 		if ok {
 			// No competition. We're a shoo-in.
-			call.Channel.Message(ctx, call.Message.ID, "sure why not "+e)
+			call.Channel.Message(ctx, call.Message.ID, say(call, "marry-first", args))
 			return
 		}
 		cur := l.(*partner)
@@ -107,32 +108,28 @@ func Marry(ctx context.Context, robo *Robot, call *Invocation) {
 					// but start over anyway.
 					continue
 				}
-				call.Channel.Message(ctx, call.Message.ID, "How could you forget we're already together? I hate you! Unsubbed, unfollowed, unloved! "+e)
+				call.Channel.Message(ctx, call.Message.ID, say(call, "marry-forgot", args))
 				return
 			}
-			call.Channel.Message(ctx, call.Message.ID, "We're already together, silly! You're so funny and cute haha. "+e)
+			call.Channel.Message(ctx, call.Message.ID, say(call, "marry-already", args))
 			return
 		}
 		if call.Message.Time().Before(cur.until) {
-			call.Channel.Message(ctx, call.Message.ID, "My heart yet belongs to another... "+e)
+			call.Channel.Message(ctx, call.Message.ID, say(call, "marry-taken", args))
 			return
 		}
 		y := score(call.Channel.History, cur.who)
 		if x < y {
-			call.Channel.Message(ctx, call.Message.ID, "I'm touched, but I must decline. I'm in love with someone else. "+e)
+			call.Channel.Message(ctx, call.Message.ID, say(call, "marry-lose", args))
 			return
 		}
 		if !call.Channel.Extra.CompareAndSwap(partnerKey{}, cur, me) {
 			// Partner changed concurrently.
 			continue
 		}
-		// We win. Now just decide which message to send.
+		// We win.
 		// TODO(zeph): since pick.Dist exists now, we could randomize
-		if call.Args["partnership"] != "" {
-			call.Channel.Message(ctx, call.Message.ID, fmt.Sprintf("Yes! I'll be your %s! %s", call.Args["partnership"], e))
-		} else {
-			call.Channel.Message(ctx, call.Message.ID, "Yes! I'll marry you! "+e)
-		}
+		call.Channel.Message(ctx, call.Message.ID, say(call, "marry-win", args))
 		return
 	}
 }
@@ -140,6 +137,5 @@ func Marry(ctx context.Context, robo *Robot, call *Invocation) {
 // DescribeMarriage gives some exposition about the marriage system.
 // No args.
 func DescribeMarriage(ctx context.Context, robo *Robot, call *Invocation) {
-	const s = `I am looking for a long series of short-term relationships and am holding a ranked competitive how-much-I-like-you tournament to decide my suitors! Politely ask me to marry you (or become your partner) and I'll evaluate your score. I like copypasta, memes, and long walks in the chat.`
-	call.Channel.Message(ctx, "", s)
+	call.Channel.Message(ctx, "", say(call, "describe-marriage", nil))
 }
@@ -4,11 +4,28 @@ import (
 	"context"
 	"log/slog"
 	"strings"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/journal"
 )
 
+// Forget forgets recent messages in the channel containing a term.
+//   - term: Text to look for in recent messages.
+//   - everything: Non-empty to forget all recent messages regardless of term.
 func Forget(ctx context.Context, robo *Robot, call *Invocation) {
-	h := call.Channel.History.All()
 	term := strings.ToLower(call.Args["term"])
+	if term == "" && call.Args["everything"] == "" {
+		// An empty term matches every message. Only forget everything when
+		// asked explicitly.
+		call.Channel.Message(ctx, call.Message.ID, say(call, "forget-usage", nil))
+		return
+	}
+	h := call.Channel.History.All()
+	op := brain.NewOp()
+	ctx = brain.WithOp(ctx, op)
+	robo.Log.InfoContext(ctx, "forget term", slog.String("in", call.Channel.Name), slog.String("op", op))
+	var n int64
 	for m := range h {
 		if !strings.Contains(strings.ToLower(m.Text), term) {
 			continue
@@ -24,6 +41,24 @@ func Forget(ctx context.Context, robo *Robot, call *Invocation) {
 				slog.String("tag", call.Channel.Learn),
 				slog.String("id", m.ID),
 			)
+			continue
 		}
+		n++
+	}
+	if robo.Journal == nil {
+		return
+	}
+	e := journal.Entry{
+		Op:      op,
+		Time:    time.Now(),
+		Actor:   call.Message.Sender,
+		Channel: call.Channel.Name,
+		Tag:     call.Channel.Learn,
+		Action:  "forget-term",
+		Count:   n,
+		Params:  map[string]string{"term": term},
+	}
+	if err := robo.Journal.Record(ctx, e); err != nil {
+		robo.Log.ErrorContext(ctx, "failed to journal forget", slog.Any("err", err), slog.String("op", op))
 	}
 }
@@ -4,31 +4,42 @@ import (
 	"context"
 	"log/slog"
 	"math/rand/v2"
+
+	"github.com/zephyrtronium/robot/locale"
 )
 
 func Private(ctx context.Context, robo *Robot, call *Invocation) {
 	err := robo.Privacy.Add(ctx, call.Message.Sender)
 	if err != nil {
 		robo.Log.ErrorContext(ctx, "privacy add failed", slog.Any("err", err), slog.String("channel", call.Channel.Name))
-		call.Channel.Message(ctx, call.Message.ID, "Something went wrong while trying to add you to the privacy list. Try again. Sorry!")
+		call.Channel.Message(ctx, call.Message.ID, say(call, "private-fail", nil))
 		return
 	}
+	// Being private means no credit, either.
+	if err := robo.Credit.OptOut(ctx, call.Message.Sender); err != nil {
+		robo.Log.ErrorContext(ctx, "credit opt-out failed", slog.Any("err", err), slog.String("channel", call.Channel.Name))
+	}
+	for _, ch := range robo.Channels.All() {
+		if ch.Speakers != nil {
+			ch.Speakers.Remove(call.Message.Sender)
+		}
+	}
 	e := call.Channel.Emotes.Pick(rand.Uint32())
-	call.Channel.Message(ctx, call.Message.ID, `Sure, I won't learn from your messages. Most of my functionality will still work for you. If you'd like to have me learn from you again, just tell me, "learn from me again." `+e)
+	call.Channel.Message(ctx, call.Message.ID, say(call, "private", locale.Args{"Emote": e}))
 }
 
 func Unprivate(ctx context.Context, robo *Robot, call *Invocation) {
 	err := robo.Privacy.Remove(ctx, call.Message.Sender)
 	if err != nil {
 		robo.Log.ErrorContext(ctx, "privacy remove failed", slog.Any("err", err), slog.String("channel", call.Channel.Name))
-		call.Channel.Message(ctx, call.Message.ID, "Something went wrong while trying to add you to the privacy list. Try again. Sorry!")
+		call.Channel.Message(ctx, call.Message.ID, say(call, "unprivate-fail", nil))
 		return
 	}
 	e := call.Channel.Emotes.Pick(rand.Uint32())
-	call.Channel.Message(ctx, call.Message.ID, `Sure, I'll learn from you again! `+e)
+	call.Channel.Message(ctx, call.Message.ID, say(call, "unprivate", locale.Args{"Emote": e}))
 }
 
 func DescribePrivacy(ctx context.Context, robo *Robot, call *Invocation) {
 	// TODO(zeph): describe privacy
-	call.Channel.Message(ctx, call.Message.ID, `See here for a description of what information I collect, and how to opt out of all collection: https://github.com/zephyrtronium/robot#what-data-does-robot-store`)
+	call.Channel.Message(ctx, call.Message.ID, say(call, "describe-privacy", nil))
 }
@@ -0,0 +1,34 @@
+package command
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/zephyrtronium/robot/brain"
+)
+
+// maxPromptTokens is the most tokens of a prompt used to seed generation.
+const maxPromptTokens = 12
+
+var (
+	// ircFormat matches IRC formatting codes, including color arguments.
+	ircFormat = regexp.MustCompile(`\x03(?:\d{1,2}(?:,\d{1,2})?)?|[\x02\x0f\x11\x16\x1d\x1e\x1f]`)
+	// promptURL matches links.
+	promptURL = regexp.MustCompile(`(?i)\b(?:[a-z][a-z0-9+.-]*://|www\.)\S*`)
+)
+
+// SanitizePrompt cleans up user text before it seeds generation. It removes
+// IRC formatting codes, links, and leading command sigils like / and !, then
+// keeps only the first several tokens. The result is the empty string if
+// nothing usable remains.
+func SanitizePrompt(prompt string) string {
+	prompt = strings.ToValidUTF8(prompt, "")
+	prompt = ircFormat.ReplaceAllString(prompt, "")
+	prompt = promptURL.ReplaceAllString(prompt, "")
+	prompt = strings.TrimLeft(strings.TrimSpace(prompt), "/!.\\~$#")
+	toks := brain.Tokens(nil, prompt)
+	if len(toks) > maxPromptTokens {
+		toks = toks[:maxPromptTokens]
+	}
+	return strings.TrimSpace(strings.Join(toks, ""))
+}
@@ -0,0 +1,37 @@
+package command_test
+
+import (
+	"testing"
+
+	"github.com/zephyrtronium/robot/command"
+)
+
+func TestSanitizePrompt(t *testing.T) {
+	cases := []struct {
+		name   string
+		prompt string
+		want   string
+	}{
+		{"empty", "", ""},
+		{"plain", "bocchi the rock", "bocchi the rock"},
+		{"spaces", "  bocchi   the rock ", "bocchi the rock"},
+		{"slash", "/ban bocchi", "ban bocchi"},
+		{"bang", "!commands", "commands"},
+		{"dot", ".w bocchi", "w bocchi"},
+		{"url", "look at https://example.com/bocchi?x=1 wow", "look at wow"},
+		{"www", "www.example.com bocchi", "bocchi"},
+		{"url-only", "https://example.com", ""},
+		{"bold", "\x02bocchi\x02 rock", "bocchi rock"},
+		{"color", "\x0304,01bocchi\x03 \x0312rock", "bocchi rock"},
+		{"invalid", "bocchi\xff\xfe rock", "bocchi rock"},
+		{"long", "a b c d e f g h i j k l m n o p", "a b c d e f g h i j k l"},
+		{"punct", "bocchi, the rock!", "bocchi, the rock!"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := command.SanitizePrompt(c.prompt); got != c.want {
+				t.Errorf("wrong sanitized prompt for %q: want %q, got %q", c.prompt, c.want, got)
+			}
+		})
+	}
+}
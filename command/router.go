@@ -0,0 +1,240 @@
+package command
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the privilege level required to use a command, or equivalently,
+// the role of a user. Each level includes the permissions of those below it.
+type Level int
+
+const (
+	// Any allows anyone to use a command. It is the viewer role.
+	Any Level = iota
+	// Moderator allows channel moderators and above to use a command.
+	// It is the channel-moderator role.
+	Moderator
+	// Operator allows operators and the owner to use a command. Operators
+	// manage the bot across all channels without full owner control.
+	Operator
+	// Owner allows only the owner to use a command.
+	Owner
+)
+
+func (l Level) String() string {
+	switch l {
+	case Any:
+		return "any"
+	case Moderator:
+		return "mod"
+	case Operator:
+		return "operator"
+	case Owner:
+		return "owner"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseLevel parses a role name: viewer, channel-moderator, operator, or
+// owner. It also accepts any, mod, and moderator.
+func ParseLevel(s string) (Level, bool) {
+	switch strings.ToLower(s) {
+	case "viewer", "any":
+		return Any, true
+	case "channel-moderator", "moderator", "mod":
+		return Moderator, true
+	case "operator":
+		return Operator, true
+	case "owner":
+		return Owner, true
+	default:
+		return Any, false
+	}
+}
+
+// Command is a command registered with a [Router].
+type Command struct {
+	// Name is the name of the command. It is used in help, and it invokes the
+	// command when it is the first word of the command text, if Parse is nil
+	// or ByName is set.
+	Name string
+	// Aliases are other names which invoke the command.
+	Aliases []string
+	// Parse matches natural language invocations of the command. Its named
+	// groups are the arguments. If it is nil, the command can only be
+	// invoked by name.
+	Parse *regexp.Regexp
+	// ByName allows invoking the command by its name or aliases even when
+	// Parse does not match. Commands whose names are ordinary words should
+	// leave it unset so that chat starting with those words isn't taken as
+	// a command.
+	ByName bool
+	// Args names the arguments of the command when it is invoked by name.
+	// See [ParseArgs].
+	Args []string
+	// Usage describes how to use the command, for help.
+	Usage string
+	// Level is the privilege level required to use the command.
+	Level Level
+	// Cooldown is the minimum time between uses of the command in a channel.
+	Cooldown time.Duration
+	// UserCooldown is the minimum time between uses of the command by one
+	// user in a channel.
+	UserCooldown time.Duration
+	// Hidden omits the command from help listings.
+	Hidden bool
+	// Fn is the command function.
+	Fn Func
+}
+
+// named reports whether the command has the given name or alias.
+func (c *Command) named(name string) bool {
+	if strings.EqualFold(c.Name, name) {
+		return true
+	}
+	for _, a := range c.Aliases {
+		if strings.EqualFold(a, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Router finds commands and tracks their cooldowns.
+// Its methods are safe to call concurrently once all commands are registered.
+type Router struct {
+	// cmds is the registered commands in order.
+	cmds []*Command
+	// mu guards until.
+	mu sync.Mutex
+	// until is the time at which each cooldown ends.
+	until map[cooldown]time.Time
+}
+
+// cooldown identifies a command's cooldown in a channel, optionally for a
+// single user.
+type cooldown struct {
+	cmd, channel, user string
+}
+
+// Register adds commands to the router. Commands are matched in the order
+// they are registered, so commands which match broadly should be last.
+func (r *Router) Register(cmds ...*Command) {
+	r.cmds = append(r.cmds, cmds...)
+}
+
+// Find finds the first command usable at level which matches text, either by
+// its parse expression or, for commands without one or with ByName set, by
+// name. It returns nil if there is none.
+func (r *Router) Find(level Level, text string) (*Command, map[string]string) {
+	first, rest, _ := strings.Cut(strings.TrimSpace(text), " ")
+	for _, c := range r.cmds {
+		if c.Level > level {
+			continue
+		}
+		if c.Parse != nil {
+			u := c.Parse.FindStringSubmatch(text)
+			switch len(u) {
+			case 0:
+				if !c.ByName {
+					continue
+				}
+			case 1:
+				return c, nil
+			default:
+				m := make(map[string]string, len(u)-1)
+				s := c.Parse.SubexpNames()
+				for k, v := range u[1:] {
+					m[s[k+1]] = v
+				}
+				return c, m
+			}
+		}
+		if c.named(first) {
+			return c, ParseArgs(c.Args, rest)
+		}
+	}
+	return nil, nil
+}
+
+// Lookup finds a command usable at level by its name or an alias.
+// It returns nil if there is none.
+func (r *Router) Lookup(level Level, name string) *Command {
+	for _, c := range r.cmds {
+		if c.Level <= level && c.named(name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Available lists the commands usable at level which are not hidden.
+func (r *Router) Available(level Level) []*Command {
+	var s []*Command
+	for _, c := range r.cmds {
+		if c.Level <= level && !c.Hidden {
+			s = append(s, c)
+		}
+	}
+	return s
+}
+
+// Use records a use of a command in a channel by a user.
+// It returns false without recording anything if the command is cooling down
+// in the channel or for the user.
+func (r *Router) Use(c *Command, channel, user string, now time.Time) bool {
+	return r.UseWith(c, c.Cooldown, c.UserCooldown, channel, user, now)
+}
+
+// UseWith is like [Router.Use], but with the given cooldowns in place of the
+// command's own, e.g. to apply a channel's configuration.
+func (r *Router) UseWith(c *Command, cool, userCool time.Duration, channel, user string, now time.Time) bool {
+	if cool <= 0 && userCool <= 0 {
+		return true
+	}
+	ck := cooldown{cmd: c.Name, channel: channel}
+	uk := cooldown{cmd: c.Name, channel: channel, user: user}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if now.Before(r.until[ck]) || now.Before(r.until[uk]) {
+		return false
+	}
+	if r.until == nil {
+		r.until = make(map[cooldown]time.Time)
+	}
+	if len(r.until) >= 4096 {
+		// Clear out finished cooldowns so that the map doesn't grow forever.
+		for k, t := range r.until {
+			if !now.Before(t) {
+				delete(r.until, k)
+			}
+		}
+	}
+	if cool > 0 {
+		r.until[ck] = now.Add(cool)
+	}
+	if userCool > 0 {
+		r.until[uk] = now.Add(userCool)
+	}
+	return true
+}
+
+// ParseArgs splits text into the named arguments. Each argument but the last
+// is one word, and the last is the remainder of the text. Arguments without
+// a corresponding word are the empty string.
+func ParseArgs(names []string, text string) map[string]string {
+	if len(names) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(names))
+	for _, name := range names[:len(names)-1] {
+		text = strings.TrimSpace(text)
+		m[name], text, _ = strings.Cut(text, " ")
+	}
+	m[names[len(names)-1]] = strings.TrimSpace(text)
+	return m
+}
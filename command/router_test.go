@@ -0,0 +1,217 @@
+package command_test
+
+import (
+	"context"
+	"maps"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/command"
+)
+
+func nop(ctx context.Context, robo *command.Robot, call *command.Invocation) {}
+
+func testRouter() *command.Router {
+	r := new(command.Router)
+	r.Register(
+		&command.Command{
+			Name:  "resume",
+			Parse: regexp.MustCompile(`^(?i:resume)\s+(?<in>#\S+)`),
+			Args:  []string{"in"},
+			Level: command.Owner,
+			Fn:    nop,
+		},
+		&command.Command{
+			Name:    "forget",
+			Aliases: []string{"unlearn"},
+			Parse:   regexp.MustCompile(`(?i)^forget\s+(?:(?<everything>everything)$|(?<term>.+))`),
+			ByName:  true,
+			Args:    []string{"term"},
+			Level:   command.Moderator,
+			Fn:      nop,
+		},
+		&command.Command{
+			Name:  "emote",
+			Args:  []string{"emote", "weight"},
+			Level: command.Moderator,
+			Fn:    nop,
+		},
+		&command.Command{
+			Name:  "who",
+			Parse: regexp.MustCompile(`(?i)^who\s+are\s+you`),
+			Level: command.Any,
+			Fn:    nop,
+		},
+		&command.Command{
+			Name:   "rawr",
+			Parse:  regexp.MustCompile(`^(?i:rawr)`),
+			Level:  command.Any,
+			Hidden: true,
+			Fn:     nop,
+		},
+		&command.Command{
+			Name:  "speak",
+			Parse: regexp.MustCompile(`^(?i:say)\s*(?<prompt>.*)|`),
+			Level: command.Any,
+			Fn:    nop,
+		},
+	)
+	return r
+}
+
+func TestRouterFind(t *testing.T) {
+	r := testRouter()
+	cases := []struct {
+		name  string
+		level command.Level
+		text  string
+		cmd   string
+		args  map[string]string
+	}{
+		{"owner", command.Owner, "resume #bocchi", "resume", map[string]string{"in": "#bocchi"}},
+		{"owner-denied", command.Moderator, "resume #bocchi", "speak", map[string]string{"prompt": ""}},
+		{"parse", command.Moderator, "forget everything", "forget", map[string]string{"everything": "everything", "term": ""}},
+		{"parse-term", command.Owner, "forget guitar hero", "forget", map[string]string{"everything": "", "term": "guitar hero"}},
+		{"alias", command.Moderator, "UNLEARN guitar hero", "forget", map[string]string{"term": "guitar hero"}},
+		{"name", command.Moderator, "emote  Kappa 5", "emote", map[string]string{"emote": "Kappa", "weight": "5"}},
+		{"name-missing", command.Moderator, "emote Kappa", "emote", map[string]string{"emote": "Kappa", "weight": ""}},
+		{"any", command.Any, "rawr", "rawr", nil},
+		{"fallback", command.Any, "say bocchi", "speak", map[string]string{"prompt": "bocchi"}},
+		{"named-bare", command.Moderator, "forget", "forget", map[string]string{"term": ""}},
+		{"prose", command.Any, "who is bocchi", "speak", map[string]string{"prompt": ""}},
+		{"prose-level", command.Owner, "resume the show", "speak", map[string]string{"prompt": ""}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cmd, args := r.Find(c.level, c.text)
+			if cmd == nil {
+				t.Fatalf("no command found")
+			}
+			if cmd.Name != c.cmd {
+				t.Errorf("wrong command: want %q, got %q", c.cmd, cmd.Name)
+			}
+			if !maps.Equal(args, c.args) {
+				t.Errorf("wrong args: want %v, got %v", c.args, args)
+			}
+		})
+	}
+}
+
+func TestRouterAvailable(t *testing.T) {
+	r := testRouter()
+	cases := []struct {
+		level command.Level
+		want  []string
+	}{
+		{command.Any, []string{"who", "speak"}},
+		{command.Moderator, []string{"forget", "emote", "who", "speak"}},
+		{command.Operator, []string{"forget", "emote", "who", "speak"}},
+		{command.Owner, []string{"resume", "forget", "emote", "who", "speak"}},
+	}
+	for _, c := range cases {
+		var got []string
+		for _, cmd := range r.Available(c.level) {
+			got = append(got, cmd.Name)
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("wrong commands at %v: want %q, got %q", c.level, c.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("wrong commands at %v: want %q, got %q", c.level, c.want, got)
+				break
+			}
+		}
+	}
+	if c := r.Lookup(command.Moderator, "unlearn"); c == nil || c.Name != "forget" {
+		t.Errorf("wrong lookup by alias: %v", c)
+	}
+	if c := r.Lookup(command.Moderator, "resume"); c != nil {
+		t.Errorf("lookup found command above level: %v", c)
+	}
+}
+
+func TestRouterUse(t *testing.T) {
+	var r command.Router
+	c := &command.Command{Name: "marry", Cooldown: time.Minute, UserCooldown: time.Hour}
+	now := time.Unix(1e9, 0)
+	steps := []struct {
+		channel, user string
+		at            time.Duration
+		want          bool
+	}{
+		{"#bocchi", "kita", 0, true},
+		{"#bocchi", "kita", time.Second, false},
+		{"#bocchi", "ryou", time.Second, false},
+		{"#ryou", "ryou", time.Second, true},
+		{"#bocchi", "ryou", time.Minute, true},
+		{"#bocchi", "kita", 2 * time.Minute, false},
+		{"#bocchi", "kita", time.Hour, true},
+	}
+	for i, s := range steps {
+		if got := r.Use(c, s.channel, s.user, now.Add(s.at)); got != s.want {
+			t.Errorf("wrong result at step %d: want %t, got %t", i, s.want, got)
+		}
+	}
+	free := &command.Command{Name: "rawr"}
+	for range 3 {
+		if !r.Use(free, "#bocchi", "kita", now) {
+			t.Error("command without cooldown was cooling down")
+		}
+	}
+	// Channel cooldowns replace the command's.
+	if !r.UseWith(free, time.Minute, 0, "#bocchi", "kita", now) {
+		t.Error("first use with cooldown was cooling down")
+	}
+	if r.UseWith(free, time.Minute, 0, "#bocchi", "ryou", now.Add(time.Second)) {
+		t.Error("second use with cooldown wasn't cooling down")
+	}
+	if !r.UseWith(free, time.Minute, 0, "#kessoku", "ryou", now.Add(time.Second)) {
+		t.Error("use with cooldown in another channel was cooling down")
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	cases := []struct {
+		name  string
+		names []string
+		text  string
+		want  map[string]string
+	}{
+		{"none", nil, "bocchi", nil},
+		{"one", []string{"msg"}, "  bocchi the rock ", map[string]string{"msg": "bocchi the rock"}},
+		{"two", []string{"in", "msg"}, "#bocchi the rock", map[string]string{"in": "#bocchi", "msg": "the rock"}},
+		{"short", []string{"in", "msg"}, "", map[string]string{"in": "", "msg": ""}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := command.ParseArgs(c.names, c.text)
+			if !maps.Equal(got, c.want) {
+				t.Errorf("wrong args: want %v, got %v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	cases := []struct {
+		in   string
+		want command.Level
+		ok   bool
+	}{
+		{"viewer", command.Any, true},
+		{"channel-moderator", command.Moderator, true},
+		{"Moderator", command.Moderator, true},
+		{"operator", command.Operator, true},
+		{"owner", command.Owner, true},
+		{"admin", command.Any, false},
+	}
+	for _, c := range cases {
+		got, ok := command.ParseLevel(c.in)
+		if got != c.want || ok != c.ok {
+			t.Errorf("wrong level for %q: want %v %t, got %v %t", c.in, c.want, c.ok, got, ok)
+		}
+	}
+}
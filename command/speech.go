@@ -0,0 +1,24 @@
+package command
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/zephyrtronium/robot/channel"
+)
+
+// Aloud sends a generated message to the channel's text-to-speech feed, if it
+// has one. Synthesis happens in the background so that it doesn't delay chat.
+func Aloud(ctx context.Context, ch *channel.Channel, text string) {
+	if ch.Speech == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		if err := ch.Speech.Say(ctx, text); err != nil {
+			slog.ErrorContext(ctx, "couldn't synthesize speech", slog.Any("err", err), slog.String("in", ch.Name))
+		}
+	}()
+}
@@ -0,0 +1,79 @@
+package command
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/channel"
+	"github.com/zephyrtronium/robot/locale"
+)
+
+// statsTTL is how long channel stats are reused before being gathered again.
+const statsTTL = 5 * time.Minute
+
+// statsKey is the key for cached stats in a channel's extra data.
+type statsKey struct{}
+
+// statsCache is stats gathered for a channel.
+type statsCache struct {
+	mu   sync.Mutex
+	at   time.Time
+	args locale.Args
+}
+
+// Stats describes what the bot has done in the channel today, by the channel's
+// time zone: messages learned and spoken, the size of its vocabulary for the
+// channel, and how often it responds at random. The numbers are cached for a
+// few minutes since counting the vocabulary can be slow.
+func Stats(ctx context.Context, robo *Robot, call *Invocation) {
+	v, _ := call.Channel.Extra.LoadOrStore(statsKey{}, new(statsCache))
+	c := v.(*statsCache)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if c.args == nil || now.Sub(c.at) >= statsTTL {
+		c.args = channelStats(ctx, robo, call.Channel, now)
+		c.at = now
+	}
+	call.Channel.Message(ctx, call.Message.ID, say(call, "stats", c.args))
+}
+
+// channelStats gathers the stats for a channel.
+// Stats which can't be gathered are -1.
+func channelStats(ctx context.Context, robo *Robot, ch *channel.Channel, now time.Time) locale.Args {
+	now = ch.In(now)
+	today := channel.Today(now)
+	args := locale.Args{
+		"Learned":   ch.Learned.Count(now),
+		"Spoke":     int64(-1),
+		"Tuples":    int64(-1),
+		"Messages":  int64(-1),
+		"Responses": 100 * ch.Responses,
+		"Sent":      int64(0),
+		"Engaged":   int64(0),
+	}
+	if n, err := robo.Spoken.Count(ctx, ch.Send, today); err == nil {
+		args["Spoke"] = n
+	} else {
+		robo.Log.ErrorContext(ctx, "couldn't count spoken messages", slog.Any("err", err), slog.String("in", ch.Name))
+	}
+	if st, ok := brain.As[brain.Stater](robo.Brain); ok && ch.Learn != "" {
+		if r, err := st.Stats(ctx, ch.Learn); err == nil {
+			args["Tuples"], args["Messages"] = r.Tuples, r.Messages
+		} else {
+			robo.Log.ErrorContext(ctx, "couldn't get brain stats", slog.Any("err", err), slog.String("tag", ch.Learn))
+		}
+	}
+	if ch.Engagement != nil {
+		sent, engaged, err := robo.Spoken.Engagement(ctx, ch.Name, today)
+		if err == nil {
+			args["Sent"], args["Engaged"] = sent, engaged
+		} else {
+			robo.Log.ErrorContext(ctx, "couldn't count engagement", slog.Any("err", err), slog.String("in", ch.Name))
+		}
+	}
+	return args
+}
@@ -2,64 +2,156 @@ package command
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"math/rand/v2"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/channel"
+	"github.com/zephyrtronium/robot/fault"
+	"github.com/zephyrtronium/robot/locale"
 )
 
-func speakCmd(ctx context.Context, robo *Robot, call *Invocation, effect string) string {
+func speakCmd(ctx context.Context, robo *Robot, call *Invocation, tag, effect string) string {
 	// Don't continue prompts that look like they start with TMI commands
 	// (even though those don't do anything anymore).
 	if ngPrompt.MatchString(call.Args["prompt"]) {
 		robo.Log.WarnContext(ctx, "nasty prompt",
 			slog.String("in", call.Channel.Name),
-			slog.String("from", call.Message.Name),
+			slog.String("user", call.Message.Name),
 			slog.String("prompt", call.Args["prompt"]),
 		)
 		e := call.Channel.Emotes.Pick(rand.Uint32())
-		return "no " + e
+		return say(call, "nasty-prompt", locale.Args{"Emote": e})
 	}
+	if call.Channel.Forbidden.Match(call.Args["prompt"]) {
+		robo.Log.InfoContext(ctx, "forbidden prompt",
+			slog.String("in", call.Channel.Name),
+			slog.String("user", call.Message.Name),
+			slog.String("prompt", call.Args["prompt"]),
+		)
+		// Respond directly so that effects don't apply to the deflection.
+		d := call.Channel.Forbidden.Deflection(rand.Uint32(), call.Message.Name)
+		if d == "" {
+			d = say(call, "forbidden-prompt", locale.Args{"Emote": call.Channel.Emotes.Pick(rand.Uint32())})
+		}
+		call.Channel.Message(ctx, call.Message.ID, d)
+		return ""
+	}
+	prompt := SanitizePrompt(call.Args["prompt"])
+	call.Channel.Overlay.Thinking()
 	start := time.Now()
-	m, trace, err := brain.Speak(ctx, robo.Brain, call.Channel.Send, call.Args["prompt"])
+	p := &call.Channel.Personality
+	m, trace, err := brain.Speak(ctx, robo.Brain, tag, prompt)
+	if err == nil && m == "" && prompt != "" && call.Channel.Fallback == channel.FallbackUnprompted {
+		robo.Log.InfoContext(ctx, "prompt spoke nothing; trying unprompted", slog.String("tag", tag), slog.String("prompt", prompt))
+		m, trace, err = brain.Speak(ctx, robo.Brain, tag, "")
+	}
+	// Try a couple more times for a message without too many emotes.
+	for i := 0; i < 2 && err == nil && m != "" && !p.Varied(m, call.Channel.Emotes.Is); i++ {
+		u, tr, err := brain.Speak(ctx, robo.Brain, tag, prompt)
+		if err != nil || u == "" {
+			break
+		}
+		m, trace = u, tr
+	}
 	cost := time.Since(start)
+	if errors.Is(err, fault.ErrBrainUnavailable) {
+		// Apologize directly so that effects don't apply to the apology.
+		robo.Log.WarnContext(ctx, "couldn't speak; brain is unavailable", slog.String("in", call.Channel.Name))
+		call.Channel.Message(ctx, call.Message.ID, say(call, "speak-fail", nil))
+		return ""
+	}
 	if err != nil {
 		robo.Log.ErrorContext(ctx, "couldn't speak", "err", err.Error())
 		return ""
 	}
 	if m == "" {
-		slog.InfoContext(ctx, "spoke nothing", slog.String("tag", call.Channel.Send), slog.String("prompt", call.Args["prompt"]))
+		slog.InfoContext(ctx, "spoke nothing", slog.String("tag", tag), slog.String("prompt", prompt))
+		if call.Channel.Fallback == channel.FallbackTemplate {
+			// Respond directly so that effects don't apply to the response.
+			e := call.Channel.Emotes.Pick(rand.Uint32())
+			call.Channel.Message(ctx, call.Message.ID, say(call, "speak-empty", locale.Args{"Prompt": prompt, "Emote": e}))
+		}
 		return ""
 	}
-	e := call.Channel.Emotes.Pick(rand.Uint32())
-	s := m + " " + e
-	if err := robo.Spoken.Record(ctx, call.Channel.Send, s, trace, call.Message.Time(), cost, m, e, effect); err != nil {
+	if !p.Varied(m, call.Channel.Emotes.Is) {
+		robo.Log.InfoContext(ctx, "won't speak; too many emotes or too repetitive", slog.String("in", call.Channel.Name), slog.String("text", m))
+		return ""
+	}
+	gen := m
+	m = p.Style(m, rand.Float64(), rand.Float64())
+	var e string
+	if rand.Float64() < p.Emote {
+		e = call.Channel.Emotes.Pick(rand.Uint32())
+	}
+	s := strings.TrimSpace(m + " " + e)
+	if effect == "" {
+		// Effects reshape the whole message, so only plain replies are framed.
+		var game string
+		if g := call.Channel.Game.Load(); g != nil {
+			game = *g
+		}
+		s = call.Channel.Replies.Frame(rand.Uint32(), channel.ReplyVars{
+			User:    call.Message.Name,
+			Channel: call.Channel.Name,
+			Game:    game,
+			Text:    s,
+		})
+	}
+	if err := robo.Spoken.Record(ctx, tag, s, trace, call.Message.Time(), cost, m, e, effect); err != nil {
 		robo.Log.ErrorContext(ctx, "couldn't record trace", slog.Any("err", err))
 		return ""
 	}
-	if call.Channel.Block.MatchString(s) {
+	call.Channel.Counts.Generated.Add(1)
+	if call.Channel.Block.MatchString(s) || !call.Channel.Profanity.Sendable(s) {
 		robo.Log.WarnContext(ctx, "generated blocked message",
 			slog.String("in", call.Channel.Name),
 			slog.String("text", m),
 			slog.String("emote", e),
 		)
+		call.Channel.Counts.Rejected.Add(1)
+		return ""
+	}
+	if !Classify(ctx, call.Channel, s) {
+		call.Channel.Counts.Rejected.Add(1)
 		return ""
 	}
-	t := time.Now()
-	r := call.Channel.Rate.ReserveN(t, 1)
-	if d := r.DelayFrom(t); d > 0 {
-		robo.Log.InfoContext(ctx, "won't speak; rate limited",
+	now := time.Now()
+	r, err := call.Channel.Reserve(now)
+	if err != nil {
+		robo.Log.InfoContext(ctx, "won't speak",
 			slog.String("action", "command"),
 			slog.String("in", call.Channel.Name),
-			slog.String("delay", d.String()),
+			slog.String("err", err.Error()),
 		)
-		r.CancelAt(t)
+		return ""
+	}
+	if !call.Channel.Outputs.Claim(call.Channel.Name, gen, now) {
+		robo.Log.InfoContext(ctx, "won't speak; another channel just sent the same message", slog.String("in", call.Channel.Name), slog.String("text", gen))
+		r.CancelAt(now)
 		return ""
 	}
 	slog.InfoContext(ctx, "speak", "in", call.Channel.Name, "text", m, "emote", e)
-	return m + " " + e
+	Aloud(ctx, call.Channel, m)
+	call.Channel.Overlay.Message(m)
+	return s
+}
+
+// Classify reports whether a generated message may be sent to a channel
+// according to its classifier, logging the reason if not.
+func Classify(ctx context.Context, ch *channel.Channel, msg string) bool {
+	ok, category, err := ch.Classify.Allow(ctx, msg)
+	if err != nil {
+		slog.ErrorContext(ctx, "classifier failed", slog.Any("err", err), slog.String("in", ch.Name), slog.Bool("send", ok))
+	}
+	if !ok && category != "" {
+		slog.WarnContext(ctx, "classifier rejected message", slog.String("in", ch.Name), slog.String("category", category), slog.String("text", msg))
+	}
+	return ok
 }
 
 var ngPrompt = regexp.MustCompile(`^/|^\.\w`)
@@ -67,23 +159,41 @@ var ngPrompt = regexp.MustCompile(`^/|^\.\w`)
 // Speak generates a message.
 //   - prompt: Start of the message to use. Optional.
 func Speak(ctx context.Context, robo *Robot, call *Invocation) {
-	u := speakCmd(ctx, robo, call, "")
+	u := speakCmd(ctx, robo, call, call.Channel.Send, "")
+	if u == "" {
+		return
+	}
+	u = lenlimit(u, 450)
+	call.Channel.Reply(ctx, call.Message.Time(), "", u)
+}
+
+// SpeakAs generates a message using another tag allowed in the channel.
+//   - tag: Tag from which to generate.
+//   - prompt: Start of the message to use. Optional.
+func SpeakAs(ctx context.Context, robo *Robot, call *Invocation) {
+	tag := call.Args["tag"]
+	if err := call.Channel.UseTag(tag); err != nil {
+		robo.Log.InfoContext(ctx, "tag not allowed", slog.String("err", err.Error()))
+		call.Channel.Message(ctx, call.Message.ID, say(call, "speak-as-forbidden", locale.Args{"Tag": tag, "Tags": call.Channel.Tags}))
+		return
+	}
+	u := speakCmd(ctx, robo, call, tag, "")
 	if u == "" {
 		return
 	}
 	u = lenlimit(u, 450)
-	call.Channel.Message(ctx, "", u)
+	call.Channel.Reply(ctx, call.Message.Time(), "", u)
 }
 
 // OwO genyewates an uwu message.
 //   - prompt: Start of the message to use. Optional.
 func OwO(ctx context.Context, robo *Robot, call *Invocation) {
-	u := speakCmd(ctx, robo, call, "cmd OwO")
+	u := speakCmd(ctx, robo, call, call.Channel.Send, "cmd OwO")
 	if u == "" {
 		return
 	}
 	u = lenlimit(owoize(u), 450)
-	call.Channel.Message(ctx, "", u)
+	call.Channel.Reply(ctx, call.Message.Time(), "", u)
 }
 
 // AAAAA AAAAAAAAA A AAAAAAA.
@@ -93,12 +203,12 @@ func AAAAA(ctx context.Context, robo *Robot, call *Invocation) {
 	if call.Args["prompt"] != "" {
 		delete(call.Args, "prompt")
 	}
-	u := speakCmd(ctx, robo, call, "cmd AAAAA")
+	u := speakCmd(ctx, robo, call, call.Channel.Send, "cmd AAAAA")
 	if u == "" {
 		return
 	}
 	u = lenlimit(aaaaaize(u), 40)
-	call.Channel.Message(ctx, "", u)
+	call.Channel.Reply(ctx, call.Message.Time(), "", u)
 }
 
 // Rawr says rawr.
@@ -107,32 +217,25 @@ func Rawr(ctx context.Context, robo *Robot, call *Invocation) {
 	if e == "" {
 		e = ":3"
 	}
-	t := time.Now()
-	r := call.Channel.Rate.ReserveN(t, 1)
-	if d := r.DelayFrom(t); d > 0 {
-		robo.Log.InfoContext(ctx, "won't rawr; rate limited",
+	if _, err := call.Channel.Reserve(time.Now()); err != nil {
+		robo.Log.InfoContext(ctx, "won't rawr",
 			slog.String("action", "rawr"),
 			slog.String("in", call.Channel.Name),
-			slog.String("delay", d.String()),
+			slog.String("err", err.Error()),
 		)
-		r.CancelAt(t)
 		return
 	}
-	call.Channel.Message(ctx, call.Message.ID, "rawr "+e)
+	call.Channel.Message(ctx, call.Message.ID, say(call, "rawr", locale.Args{"Emote": e}))
 }
 
 // Source gives a link to the source code.
 func Source(ctx context.Context, robo *Robot, call *Invocation) {
-	const srcMessage = `My source code is at https://github.com/zephyrtronium/robot – ` +
-		`I'm written in Go, and I'm free, open-source software licensed ` +
-		`under the GNU General Public License, Version 3.`
-	call.Channel.Message(ctx, call.Message.ID, srcMessage)
+	call.Channel.Message(ctx, call.Message.ID, say(call, "source", nil))
 }
 
 // Who describes Robot.
 func Who(ctx context.Context, robo *Robot, call *Invocation) {
 	// TODO(zeph): give link to readme section describing how robot works
-	const whoMessage = `I'm a Markov chain bot! I learn from things people say in chat, then spew vaguely intelligible memes back.`
 	e := call.Channel.Emotes.Pick(rand.Uint32())
-	call.Channel.Message(ctx, call.Message.ID, whoMessage+" "+e)
+	call.Channel.Message(ctx, call.Message.ID, say(call, "who", locale.Args{"Emote": e}))
 }
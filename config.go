@@ -3,7 +3,11 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
 	"errors"
+	"expvar"
 	"fmt"
 	"io"
 	"log/slog"
@@ -16,9 +20,10 @@ import (
 
 	"github.com/BurntSushi/toml"
 	"github.com/dgraph-io/badger/v4"
-	"github.com/dgraph-io/badger/v4/options"
+	_ "github.com/lib/pq"
 	"gitlab.com/zephyrtronium/pick"
 	"gitlab.com/zephyrtronium/tmi"
+	"go.etcd.io/bbolt"
 	"golang.org/x/crypto/hkdf"
 	"golang.org/x/crypto/sha3"
 	"golang.org/x/oauth2"
@@ -26,86 +31,390 @@ import (
 	"golang.org/x/time/rate"
 	"zombiezen.com/go/sqlite/sqlitex"
 
+	"github.com/zephyrtronium/robot/apikey"
+	"github.com/zephyrtronium/robot/audit"
 	"github.com/zephyrtronium/robot/auth"
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/boltbrain"
+	"github.com/zephyrtronium/robot/brain/breaker"
+	"github.com/zephyrtronium/robot/brain/busy"
+	"github.com/zephyrtronium/robot/brain/deadline"
 	"github.com/zephyrtronium/robot/brain/kvbrain"
+	"github.com/zephyrtronium/robot/brain/queue"
+	"github.com/zephyrtronium/robot/brain/remote"
 	"github.com/zephyrtronium/robot/brain/sqlbrain"
+	"github.com/zephyrtronium/robot/brain/tier"
+	"github.com/zephyrtronium/robot/brain/view"
+	"github.com/zephyrtronium/robot/brain/warm"
 	"github.com/zephyrtronium/robot/channel"
-	"github.com/zephyrtronium/robot/message"
+	"github.com/zephyrtronium/robot/classify"
+	"github.com/zephyrtronium/robot/credit"
+	"github.com/zephyrtronium/robot/emotes"
+	"github.com/zephyrtronium/robot/fault"
+	"github.com/zephyrtronium/robot/ignore"
+	"github.com/zephyrtronium/robot/journal"
+	"github.com/zephyrtronium/robot/locale"
+	"github.com/zephyrtronium/robot/overlay"
+	"github.com/zephyrtronium/robot/platform"
 	"github.com/zephyrtronium/robot/privacy"
+	"github.com/zephyrtronium/robot/profanity"
 	"github.com/zephyrtronium/robot/spoken"
+	"github.com/zephyrtronium/robot/sqlpool"
+	"github.com/zephyrtronium/robot/tts"
 	"github.com/zephyrtronium/robot/twitch"
 )
 
 // Load loads Robot from a TOML configuration.
-func Load(ctx context.Context, r io.Reader) (*Config, *toml.MetaData, error) {
+// Keys which don't correspond to any option are logged with their lines, or
+// if strict is true, they are an error.
+func Load(ctx context.Context, r io.Reader, strict bool) (*Config, *toml.MetaData, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't read config: %w", err)
+	}
 	var cfg Config
-	md, err := toml.NewDecoder(r).Decode(&cfg)
+	md, err := toml.Decode(string(src), &cfg)
 	if err != nil {
+		var pe toml.ParseError
+		if errors.As(err, &pe) {
+			return nil, nil, fmt.Errorf("couldn't decode config: %s", pe.ErrorWithPosition())
+		}
 		return nil, nil, fmt.Errorf("couldn't decode config: %w", err)
 	}
+	if u := md.Undecoded(); len(u) != 0 {
+		lines := strings.Split(string(src), "\n")
+		var errs []error
+		for _, k := range u {
+			if strict {
+				errs = append(errs, fmt.Errorf("line %d: unknown key %s", keyLine(lines, k), k))
+				continue
+			}
+			slog.WarnContext(ctx, "unknown config key", slog.String("key", k.String()), slog.Int("line", keyLine(lines, k)))
+		}
+		if err := errors.Join(errs...); err != nil {
+			return nil, nil, fmt.Errorf("invalid config:\n%w", err)
+		}
+	}
+	if err := applyProfiles(&cfg, func(key ...string) bool { return md.IsDefined(key...) }); err != nil {
+		return nil, nil, fmt.Errorf("invalid config:\n%w", err)
+	}
 	expandcfg(&cfg, os.Getenv)
 	return &cfg, &md, nil
 }
 
-// SetOwner sets owner metadata used in self-description commands.
-func (robo *Robot) SetOwner(ownerName, ownerContact string) {
+// tomlHeader and tomlAssign match table headers and key assignments in TOML
+// source. Dotted keys and quoted keys are captured whole.
+var (
+	tomlHeader = regexp.MustCompile(`^\s*\[\[?\s*([^\]]+?)\s*\]\]?\s*(?:#.*)?$`)
+	tomlAssign = regexp.MustCompile(`^\s*((?:[A-Za-z0-9_-]+|'[^']*'|"[^"]*")(?:\s*\.\s*(?:[A-Za-z0-9_-]+|'[^']*'|"[^"]*"))*)\s*=`)
+)
+
+// keyLine finds the line at which a key is defined in TOML source.
+// If the key is within an inline table, it gives the line of the inline
+// table. If the key can't be found, it returns 0.
+func keyLine(lines []string, k toml.Key) int {
+	for n := len(k); n > 0; n-- {
+		want := k[:n].String()
+		var table toml.Key
+		for i, line := range lines {
+			if m := tomlHeader.FindStringSubmatch(line); m != nil {
+				table = splitKey(m[1])
+				if table.String() == want {
+					return i + 1
+				}
+				continue
+			}
+			if m := tomlAssign.FindStringSubmatch(line); m != nil {
+				key := append(table[:len(table):len(table)], splitKey(m[1])...)
+				if key.String() == want {
+					return i + 1
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// splitKey splits a possibly dotted TOML key into its parts.
+func splitKey(s string) toml.Key {
+	var k toml.Key
+	for s != "" {
+		s = strings.TrimSpace(s)
+		var part string
+		switch s[0] {
+		case '\'', '"':
+			end := strings.IndexByte(s[1:], s[0])
+			if end < 0 {
+				return append(k, s)
+			}
+			part, s = s[1:end+1], s[end+2:]
+		default:
+			part, s, _ = strings.Cut(s, ".")
+			part = strings.TrimSpace(part)
+		}
+		k = append(k, part)
+		s = strings.TrimSpace(s)
+		s = strings.TrimPrefix(s, ".")
+	}
+	return k
+}
+
+// SetOwner sets owner metadata used in self-description commands and the
+// destination for notifications to the owner.
+func (robo *Robot) SetOwner(ownerName, ownerContact, notify string) {
 	robo.owner = ownerName
 	robo.ownerContact = ownerContact
+	robo.notify = notify
 }
 
 // SetSecrets loads the robot's fixed secret and initializes derived secrets.
 func (robo *Robot) SetSecrets(file string) error {
+	var err error
+	robo.secrets, err = loadSecrets(file)
+	return err
+}
+
+// loadSecrets derives the bot's keys from the secret key in a file.
+func loadSecrets(file string) (*keys, error) {
 	k, err := os.ReadFile(file)
 	if err != nil {
-		return fmt.Errorf("couldn't read secret key: %w", err)
+		return nil, fmt.Errorf("couldn't read secret key: %w", err)
 	}
 	uk := domainkey(make([]byte, 64), k, []byte("userhash"))
 	tk := domainkey(make([]byte, auth.KeySize), k, []byte("oauth2.twitch"))
-	robo.secrets = &keys{
+	ak := domainkey(make([]byte, 32), k, []byte("apikey"))
+	r := &keys{
 		userhash: uk,
 		twitch:   (*[32]byte)(tk),
+		apikey:   ak,
 	}
-	return nil
+	return r, nil
 }
 
-// SetSources opens the brain and privacy list wrappers around the respective
-// databases. Use [loadDBs] to open the databases themselves from DSNs.
-// Panics if both kv and sql are nil.
-func (robo *Robot) SetSources(ctx context.Context, kv *badger.DB, sql, priv, spoke *sqlitex.Pool) error {
+// SetSources opens the brain, privacy list, spoken history, and emote store
+// wrappers around the respective databases. Use [loadDBs] to open the
+// databases themselves from DSNs.
+// Panics if db.kv, db.bolt, db.sql, db.pg, and db.remote are all nil.
+func (robo *Robot) SetSources(ctx context.Context, db *databases) error {
 	var err error
-	if sql == nil {
-		if kv == nil {
-			panic("robot: no brain")
+	switch {
+	case db.sql != nil:
+		var br *sqlbrain.Brain
+		br, err = sqlbrain.Open(ctx, db.sql)
+		if err == nil && db.replica != nil {
+			br = br.WithReplica(db.replica)
+			robo.metrics.Set("sqlbrain_replica", expvar.Func(func() any { return br.ReplicaStats() }))
 		}
-		robo.brain = kvbrain.New(kv)
-	} else {
-		robo.brain, err = sqlbrain.Open(ctx, sql)
+		robo.brain = br
+	case db.pg != nil:
+		robo.brain, err = sqlbrain.OpenPostgres(ctx, db.pg)
+	case db.bolt != nil:
+		robo.brain = boltbrain.New(db.bolt)
+	case db.remote != nil:
+		robo.brain = db.remote
+	case db.kv != nil:
+		br := kvbrain.New(db.kv)
+		robo.brain = br
+		robo.metrics.Set("kvbrain", expvar.Func(func() any { return br.Metrics() }))
+	default:
+		panic("robot: no brain")
 	}
 	if err != nil {
 		return fmt.Errorf("couldn't open brain: %w", err)
 	}
-	robo.privacy, err = privacy.Open(ctx, priv)
+	robo.privacy, err = privacy.Open(ctx, db.priv)
 	if err != nil {
 		return fmt.Errorf("couldn't open privacy list: %w", err)
 	}
-	robo.spoken, err = spoken.Open(ctx, spoke)
+	if db.retry != nil {
+		if db.sql != nil {
+			robo.brain = busy.New(robo.brain, db.retry)
+		}
+		robo.privacy.SetRetry(db.retry)
+		robo.metrics.Set("sqlite_busy", expvar.Func(func() any { return db.retry.Stats() }))
+	}
+	robo.credit, err = credit.Open(ctx, db.priv)
+	if err != nil {
+		return fmt.Errorf("couldn't open leaderboard: %w", err)
+	}
+	robo.spoken, err = spoken.Open(ctx, db.spoke)
 	if err != nil {
 		return fmt.Errorf("couldn't open spoken history: %w", err)
 	}
+	robo.emotes, err = emotes.Open(ctx, db.emotes)
+	if err != nil {
+		return fmt.Errorf("couldn't open emote store: %w", err)
+	}
+	robo.ignores, err = ignore.Open(ctx, db.priv)
+	if err != nil {
+		return fmt.Errorf("couldn't open ignore list: %w", err)
+	}
+	robo.audit, err = audit.Open(ctx, db.priv)
+	if err != nil {
+		return fmt.Errorf("couldn't open audit log: %w", err)
+	}
+	robo.journal, err = journal.Open(ctx, db.priv)
+	if err != nil {
+		return fmt.Errorf("couldn't open operation journal: %w", err)
+	}
+	if robo.secrets != nil {
+		robo.apikeys, err = apikey.Open(ctx, db.priv, robo.secrets.apikey)
+		if err != nil {
+			return fmt.Errorf("couldn't open API keys: %w", err)
+		}
+	}
 	return nil
 }
 
+// SetDeadlines wraps the brain to give up on operations which take too long.
+// It must be called immediately after SetSources so that other wrappers see
+// timeouts as failures.
+func (robo *Robot) SetDeadlines(cfg DeadlineCfg) {
+	dur := func(s, def float64) time.Duration {
+		if s == 0 {
+			s = def
+		}
+		return max(fseconds(s), 0)
+	}
+	stalled := cfg.Stalled
+	if stalled == 0 {
+		stalled = 64
+	}
+	c := deadline.Config{
+		Learn:      dur(cfg.Learn, 5),
+		Speak:      dur(cfg.Speak, 10),
+		Forget:     dur(cfg.Forget, 300),
+		MaxStalled: int64(max(stalled, 0)),
+	}
+	if c.Learn == 0 && c.Speak == 0 && c.Forget == 0 {
+		return
+	}
+	br := deadline.New(robo.brain, c)
+	robo.brain = br
+	robo.metrics.Set("deadline", expvar.Func(func() any { return br.Stats() }))
+}
+
+// SetBreaker wraps the brain in a circuit breaker which stops learning and
+// speaking while the brain is failing. It must be called after SetSources.
+// If cfg.Num is not positive, the brain is left as-is.
+func (robo *Robot) SetBreaker(cfg Breaker) {
+	if cfg.Num <= 0 {
+		return
+	}
+	br := breaker.New(robo.brain, breaker.Config{
+		Failures: cfg.Num,
+		Window:   fseconds(cfg.Within),
+		Slow:     fseconds(cfg.Slow),
+		Cooldown: fseconds(cfg.Cooldown),
+	})
+	robo.brain = br
+	robo.metrics.Set("brain", expvar.Func(func() any { return br.Stats() }))
+}
+
+// SetHot wraps the brain to keep recent messages in memory and write them to
+// the brain database in the background. It must be called after SetSources
+// and before SetViews. If cfg.Window is not positive, the brain is left as-is.
+func (robo *Robot) SetHot(cfg HotCfg) error {
+	if cfg.Window <= 0 {
+		return nil
+	}
+	if cfg.Recency < 0 || cfg.Recency > 1 {
+		return fmt.Errorf("hot recency must be between 0 and 1, not %v", cfg.Recency)
+	}
+	br := tier.New(robo.brain, tier.Config{
+		Window:  fseconds(cfg.Window),
+		Recency: cfg.Recency,
+		Flush:   fseconds(cfg.Flush),
+		Batch:   cfg.Batch,
+	})
+	robo.brain = br
+	robo.tier = br
+	robo.metrics.Set("hot", expvar.Func(func() any { return br.Stats() }))
+	return nil
+}
+
+// SetQueue wraps the brain to learn and forget by publishing to a queue for
+// robot ingest to apply. It must be called before SetHot so that memory holds
+// what chat sends rather than waiting on the queue.
+func (robo *Robot) SetQueue(ctx context.Context, cfg QueueCfg) error {
+	if cfg.URL == "" {
+		return nil
+	}
+	t, err := cfg.dial(ctx)
+	if err != nil {
+		return err
+	}
+	robo.brain = queue.New(robo.brain, t)
+	return nil
+}
+
+// SetViews wraps the brain to speak from views as tags.
+// It must be called before SetBreaker and SetWarm so that speaking from views
+// goes through them.
+func (robo *Robot) SetViews(cfg map[string]*ViewCfg) error {
+	br, err := viewBrain(robo.brain, cfg)
+	if err != nil {
+		return err
+	}
+	robo.brain = br
+	return nil
+}
+
+// viewBrain wraps a brain to speak from the configured views.
+// If there are none, the brain is returned as-is.
+func viewBrain(br brain.Brain, cfg map[string]*ViewCfg) (brain.Brain, error) {
+	if len(cfg) == 0 {
+		return br, nil
+	}
+	views := make(map[string]*brain.View, len(cfg))
+	for nm, v := range cfg {
+		if len(v.Tags) == 0 {
+			return nil, fmt.Errorf("view %s has no tags", nm)
+		}
+		w := brain.View{Tags: v.Tags}
+		if v.Block != "" {
+			var err error
+			w.Block, err = regexp.Compile(v.Block)
+			if err != nil {
+				return nil, fmt.Errorf("bad block expression for view %s: %w", nm, err)
+			}
+		}
+		views[nm] = &w
+	}
+	r, err := view.New(br, views)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't use views: %w", err)
+	}
+	return r, nil
+}
+
+// SetWarm wraps the brain to generate random responses ahead of time.
+// It must be called after SetBreaker so that generating ahead respects the
+// breaker. If cfg.Size is not positive, the brain is left as-is.
+func (robo *Robot) SetWarm(cfg Warm) {
+	if cfg.Size <= 0 {
+		return
+	}
+	br := warm.New(robo.brain, cfg.Size, fseconds(cfg.TTL))
+	robo.brain = br
+	robo.warm = br
+	robo.metrics.Set("warm", expvar.Func(func() any { return br.Stats() }))
+}
+
+// twitchEndpoint is the OAuth2 endpoint for Twitch.
+var twitchEndpoint = oauth2.Endpoint{
+	DeviceAuthURL: "https://id.twitch.tv/oauth2/device",
+	TokenURL:      "https://id.twitch.tv/oauth2/token",
+}
+
 // InitTwitch initializes the Twitch and TMI clients and channel configuration.
 // It must be called after SetSecrets.
 func (robo *Robot) InitTwitch(ctx context.Context, cfg ClientCfg) error {
-	cfg.endpoint = oauth2.Endpoint{
-		DeviceAuthURL: "https://id.twitch.tv/oauth2/device",
-		TokenURL:      "https://id.twitch.tv/oauth2/token",
-	}
+	cfg.endpoint = twitchEndpoint
 	send := make(chan *tmi.Message, 1)
 	recv := make(chan *tmi.Message, 8) // 8 is enough for on-connect msgs
 	client := &http.Client{Timeout: 30 * time.Second}
-	robo.twitch = twitch.Client{HTTP: client, ID: cfg.CID}
+	robo.twitch = &twitchClient{api: twitch.Client{HTTP: client, ID: cfg.CID}}
 	tmi, err := loadClient(
 		cfg,
 		send,
@@ -114,24 +423,25 @@ func (robo *Robot) InitTwitch(ctx context.Context, cfg ClientCfg) error {
 			return auth.DeviceCodeFlow(c, s, client, deviceCodePrompt)
 		},
 		*robo.secrets.twitch,
-		"chat:read", "chat:edit",
+		twitchScopesFor(&cfg)...,
 	)
 	if err != nil {
 		return fmt.Errorf("couldn't load TMI client: %w", err)
 	}
-	robo.tmi = tmi
+	robo.twitch.tmi = tmi
 	// Validate the Twitch access token now to get our user ID and login.
-	tok, err := robo.tmi.tokens.Token(ctx)
+	tok, err := robo.twitch.tmi.tokens.Token(ctx)
 	if err != nil {
 		return fmt.Errorf("couldn't obtain Twitch access token: %w", err)
 	}
+	reauthorized := false
 	for range 5 {
-		val, err := twitch.Validate(ctx, robo.twitch.HTTP, tok)
+		val, err := twitch.Validate(ctx, robo.twitch.api.HTTP, tok)
 		slog.InfoContext(ctx, "Twitch validation", slog.Any("response", val), slog.Any("err", err))
 		switch {
 		case err == nil: // do nothing
 		case errors.Is(err, twitch.ErrNeedRefresh):
-			tok, err = robo.tmi.tokens.Refresh(ctx, tok)
+			tok, err = robo.twitch.tmi.tokens.Refresh(ctx, tok)
 			if err != nil {
 				return fmt.Errorf("couldn't refresh Twitch token: %w", err)
 			}
@@ -139,8 +449,37 @@ func (robo *Robot) InitTwitch(ctx context.Context, cfg ClientCfg) error {
 		default:
 			return fmt.Errorf("couldn't validate Twitch token: %w", err)
 		}
-		robo.tmi.name = val.Login
-		robo.tmi.userID = val.UserID
+		if missing := missingFeatures(&cfg, val.Scopes); len(missing) != 0 {
+			re, ok := robo.twitch.tmi.tokens.(auth.Reauthorizer)
+			if ok && !reauthorized {
+				// Ask for consent to the new scopes now rather than failing
+				// with 401s once the features try to use them.
+				fmt.Println("\nThe bot's Twitch authorization doesn't cover these enabled features:")
+				for _, f := range missing {
+					fmt.Printf("\t%s, which needs %s\n", f.name, strings.Join(f.scopes, " "))
+				}
+				fmt.Println("Authorize the bot again to grant them.")
+				tok, err = re.Reauthorize(ctx)
+				if err != nil {
+					return fmt.Errorf("couldn't reauthorize Twitch for new scopes: %w", err)
+				}
+				reauthorized = true
+				continue
+			}
+			for _, f := range missing {
+				slog.WarnContext(ctx, "Twitch authorization lacks scopes; disabling feature", slog.String("feature", f.name), slog.Any("scopes", f.scopes))
+				f.disable(&cfg)
+			}
+		}
+		robo.twitch.tmi.name = val.Login
+		robo.twitch.tmi.userID = val.UserID
+		robo.twitch.roles = fseconds(cfg.Roles)
+		robo.twitch.eventsub = cfg.EventSub
+		robo.twitch.liveness = fseconds(cfg.Liveness)
+		robo.twitch.notify = robo.notifyOwner
+		if robo.twitch.liveness <= 0 {
+			robo.twitch.liveness = 2 * time.Minute
+		}
 		return nil
 	}
 	return fmt.Errorf("gave up on validation attempts")
@@ -149,7 +488,7 @@ func (robo *Robot) InitTwitch(ctx context.Context, cfg ClientCfg) error {
 // InitTwitchUsers resolves Twitch usernames in the configuration to user IDs.
 // It must be called after SetTMI.
 func (robo *Robot) InitTwitchUsers(ctx context.Context, owner *Privilege, global []Privilege, channels map[string]*ChannelCfg) error {
-	tok, err := robo.tmi.tokens.Token(ctx)
+	tok, err := robo.twitch.tmi.tokens.Token(ctx)
 	if err != nil {
 		return err
 	}
@@ -160,15 +499,20 @@ func (robo *Robot) InitTwitchUsers(ctx context.Context, owner *Privilege, global
 	default:
 		for {
 			r := []twitch.User{{ID: owner.ID, Login: owner.Name}}
-			r, err := twitch.Users(ctx, robo.twitch, tok, r)
+			r, err := twitch.Users(ctx, robo.twitch.api, tok, r)
 			switch {
 			case err == nil: // do nothing
 			case errors.Is(err, twitch.ErrNeedRefresh):
-				tok, err = robo.tmi.tokens.Refresh(ctx, tok)
+				tok, err = robo.twitch.tmi.tokens.Refresh(ctx, tok)
 				if err != nil {
 					return fmt.Errorf("couldn't refresh Twitch token: %w", err)
 				}
 				continue
+			case errors.Is(err, fault.ErrRateLimited):
+				if err := backoff(ctx, time.Second); err != nil {
+					return err
+				}
+				continue
 			default:
 				return fmt.Errorf("couldn't resolve owner info: %w", err)
 			}
@@ -209,16 +553,20 @@ func (robo *Robot) InitTwitchUsers(ctx context.Context, owner *Privilege, global
 		in = in[len(l):]
 		group.Go(func() error {
 			for {
-				// TODO(zeph): rate limit
-				l, err := twitch.Users(ctx, robo.twitch, tok, l)
+				l, err := twitch.Users(ctx, robo.twitch.api, tok, l)
 				switch {
 				case err == nil: // do nothing
 				case errors.Is(err, twitch.ErrNeedRefresh):
-					tok, err = robo.tmi.tokens.Refresh(ctx, tok)
+					tok, err = robo.twitch.tmi.tokens.Refresh(ctx, tok)
 					if err != nil {
 						return fmt.Errorf("couldn't refresh Twitch token: %w", err)
 					}
 					continue
+				case errors.Is(err, fault.ErrRateLimited):
+					if err := backoff(ctx, time.Second); err != nil {
+						return err
+					}
+					continue
 				default:
 					return err
 				}
@@ -260,25 +608,114 @@ func (robo *Robot) InitTwitchUsers(ctx context.Context, owner *Privilege, global
 	return nil
 }
 
+// backoff waits for d or until ctx is done, for retrying requests that were
+// rate limited.
+func backoff(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// ignorePrivileges converts globally ignored users to Twitch privileges so
+// that their logins resolve to user IDs. Entries which are all digits are
+// taken as user IDs and all others as logins.
+func ignorePrivileges(users []string) []Privilege {
+	r := make([]Privilege, 0, len(users))
+	for _, u := range users {
+		if u == "" {
+			continue
+		}
+		p := Privilege{Name: u, Level: "ignore"}
+		if strings.Trim(u, "0123456789") == "" {
+			p = Privilege{ID: u, Level: "ignore"}
+		}
+		r = append(r, p)
+	}
+	return r
+}
+
 // SetTwitchChannels initializes Twitch channel configuration.
 // It must be called after SetTMI.
 func (robo *Robot) SetTwitchChannels(ctx context.Context, global Global, channels map[string]*ChannelCfg) error {
-	// TODO(zeph): we can convert this to a SetChannels, where it just adds the
-	// channels for any given service
+	for _, ch := range channels {
+		robo.twitch.channels = append(robo.twitch.channels, ch.Channels...)
+	}
+	return robo.setChannels(ctx, global, robo.twitch, global.Privileges.Twitch, channels)
+}
+
+// setChannels initializes channel configuration for a platform and adds the
+// platform to those the robot runs.
+// privs is the global privileges on the platform.
+func (robo *Robot) setChannels(ctx context.Context, global Global, c platform.Client, privs []Privilege, channels map[string]*ChannelCfg) error {
+	service := c.Platform()
+	format := c.Capabilities().Format
+	send := func(ctx context.Context, ch *channel.Channel, reply, text string) {
+		text = format.Apply(text, ch.Emotes.Spelling)
+		if text == "" {
+			return
+		}
+		if err := c.Send(ctx, ch.Name, reply, text); err != nil {
+			slog.ErrorContext(ctx, "couldn't send", slog.Any("err", err), slog.String("platform", service), slog.String("in", ch.Name))
+		}
+	}
+	panics := global.Panics
+	if panics.Num <= 0 {
+		panics = Threshold{Num: 5, Within: 600}
+	}
+	base := locale.Default()
+	if global.Templates != "" {
+		var err error
+		base, err = base.ParseFile(global.Templates)
+		if err != nil {
+			return fmt.Errorf("bad global templates: %w", err)
+		}
+	}
+	words := profanityLists(global.Profanity)
 	for nm, ch := range channels {
+		tmpl := base
+		if ch.Templates != "" {
+			var err error
+			tmpl, err = base.ParseFile(ch.Templates)
+			if err != nil {
+				return fmt.Errorf("bad templates for %s.%s: %w", service, nm, err)
+			}
+		}
 		blk, err := regexp.Compile("(" + global.Block + ")|(" + ch.Block + ")")
 		if err != nil {
-			return fmt.Errorf("bad global or channel block expression for twitch.%s: %w", nm, err)
+			return fmt.Errorf("bad global or channel block expression for %s.%s: %w", service, nm, err)
+		}
+		forbid, err := channel.ParseForbidden(ch.Forbid.Prompts, ch.Forbid.Deflect)
+		if err != nil {
+			return fmt.Errorf("bad forbid for %s.%s: %w", service, nm, err)
+		}
+		prof, err := profanityPolicy(words, ch.Profanity)
+		if err != nil {
+			return fmt.Errorf("bad profanity for %s.%s: %w", service, nm, err)
 		}
-		emotes := pick.New(pick.FromMap(mergemaps(global.Emotes, ch.Emotes)))
 		effects := pick.New(pick.FromMap(mergemaps(global.Effects, ch.Effects)))
-		ign, mod := make(map[string]bool), make(map[string]bool)
-		for _, p := range global.Privileges.Twitch {
+		events, err := channelEvents(ch.Events)
+		if err != nil {
+			return fmt.Errorf("bad events for %s.%s: %w", service, nm, err)
+		}
+		loc, err := timeZone(ch.TimeZone)
+		if err != nil {
+			return fmt.Errorf("bad timezone for %s.%s: %w", service, nm, err)
+		}
+		ign, mod, ops := make(map[string]bool), make(map[string]bool), make(map[string]bool)
+		for _, u := range global.Ignore {
+			ign[u] = true
+		}
+		for _, p := range privs {
 			switch {
 			case strings.EqualFold(p.Level, "ignore"):
 				ign[p.ID] = true
 			case strings.EqualFold(p.Level, "moderator"):
 				mod[p.ID] = true
+			case strings.EqualFold(p.Level, "operator"):
+				ops[p.ID] = true
 			}
 		}
 		for _, p := range ch.Privileges {
@@ -287,88 +724,474 @@ func (robo *Robot) SetTwitchChannels(ctx context.Context, global Global, channel
 				ign[p.ID] = true
 			case strings.EqualFold(p.Level, "moderator"):
 				mod[p.ID] = true
+			case strings.EqualFold(p.Level, "operator"):
+				ops[p.ID] = true
 			}
 		}
 		for _, p := range ch.Channels {
 			v := &channel.Channel{
-				Name:      p,
-				Learn:     ch.Learn,
-				Send:      ch.Send,
-				Block:     blk,
-				Responses: ch.Responses,
-				Rate:      rate.NewLimiter(rate.Every(fseconds(ch.Rate.Every)), ch.Rate.Num),
-				Ignore:    ign,
-				Mod:       mod,
-				Memery:    channel.NewMemeDetector(ch.Copypasta.Need, fseconds(ch.Copypasta.Within)),
-				Emotes:    emotes,
-				Effects:   effects,
+				Name:        p,
+				Learn:       ch.Learn,
+				Send:        ch.Send,
+				Tags:        ch.Tags,
+				Block:       blk,
+				Forbidden:   forbid,
+				Profanity:   prof,
+				Responses:   ch.Responses,
+				Context:     ch.Context.Messages,
+				ContextProb: ch.Context.Prob,
+				Rate:        rate.NewLimiter(rate.Every(fseconds(ch.Rate.Every)), ch.Rate.Num),
+				Ignore:      ign,
+				Mod:         mod,
+				Ops:         ops,
+				Roles:       new(channel.Roles),
+				VIPMod:      ch.VIPMod,
+				Events:      events,
+				History:     new(channel.History),
+				Panics:      channel.NewFailures(panics.Num, fseconds(panics.Within)),
+				Callouts:    ch.Callout.Prob,
+				Memery:      channel.NewMemeDetector(ch.Copypasta.Need, fseconds(ch.Copypasta.Within)),
+				Outputs:     robo.outputs(ch.Send),
+				Emotes:      channel.NewEmotes(mergemaps(global.Emotes, ch.Emotes)),
+				Effects:     effects,
+				Overlay:     new(overlay.Hub),
+				Templates:   tmpl,
+				Prefix:      ch.Commands.Prefix,
+				NoCommands:  ch.Commands.Off,
+				Limits:      commandLimits(ch.Commands.Limits),
+				Personality: personality(ch.Personality),
+				Leaderboard: ch.Leaderboard,
+				Location:    loc,
+				Suspend: channel.Suspend{
+					EmoteOnly: ch.Suspend.EmoteOnly,
+					SubOnly:   ch.Suspend.SubOnly,
+					Slow:      fseconds(ch.Suspend.Slow),
+				},
+			}
+			v.Spoke.Store(time.Now().UnixMilli())
+			robo.addQuota(ch.Learn, brain.Quota{Tuples: ch.Quota.Tuples, Bytes: ch.Quota.Bytes})
+			robo.addPreload(ch.Send, ch.Preload)
+			extra, err := robo.emotes.All(ctx, p)
+			if err != nil {
+				return fmt.Errorf("couldn't load emotes added to %s: %w", p, err)
+			}
+			v.Emotes.Load(extra)
+			halfLife := fseconds(ch.EmoteUsage.HalfLife)
+			if halfLife <= 0 {
+				halfLife = 7 * 24 * time.Hour
+			}
+			v.Emotes.SetHalfLife(halfLife)
+			v.EmoteBlend = ch.EmoteUsage.Blend
+			v.EmoteTop = ch.EmoteUsage.Top
+			if v.EmoteTop <= 0 {
+				v.EmoteTop = 20
+			}
+			v.EmoteServices = emoteServices(ch.EmoteServices)
+			if ch.LearnLimit.Num > 0 {
+				v.LearnLimit = channel.NewLearnLimit(ch.LearnLimit.Num, fseconds(ch.LearnLimit.Within))
+			}
+			if ch.Engagement > 0 {
+				v.Engagement = channel.NewEngagement(fseconds(ch.Engagement))
+			}
+			if ch.Callout.Speakers > 0 {
+				v.Speakers = channel.NewSpeakers(ch.Callout.Speakers)
+			}
+			v.Speech, err = speechFeed(ch.TTS)
+			if err != nil {
+				return fmt.Errorf("bad tts for %s.%s: %w", service, nm, err)
+			}
+			v.Pace = channel.Pace{Min: fseconds(ch.Pace.Min), Max: fseconds(ch.Pace.Max)}
+			if v.Pace.Min > v.Pace.Max {
+				return fmt.Errorf("bad pace for %s.%s: min %v is more than max %v", service, nm, v.Pace.Min, v.Pace.Max)
+			}
+			v.Latency = new(channel.Latency)
+			robo.latency.Set(p, v.Latency)
+			v.Replies, err = channel.ParseReplies(ch.Replies)
+			if err != nil {
+				return fmt.Errorf("bad replies for %s.%s: %w", service, nm, err)
+			}
+			v.Fallback, err = fallback(ch.Fallback)
+			if err != nil {
+				return fmt.Errorf("bad fallback for %s.%s: %w", service, nm, err)
+			}
+			v.Classify, err = classifyPolicy(global.Classifier, ch.Classify)
+			if err != nil {
+				return fmt.Errorf("bad classify for %s.%s: %w", service, nm, err)
 			}
 			v.Message = func(ctx context.Context, reply, text string) {
-				msg := message.Format(reply, v.Name, "%s", text)
-				robo.sendTMI(ctx, robo.tmi.send, msg)
+				v.Spoke.Store(time.Now().UnixMilli())
+				send(ctx, v, reply, text)
 			}
+			// Channels on platforms without a notion of being live are
+			// always online, so always learn.
+			v.Enabled.Store(!c.Capabilities().Live)
 			robo.channels.Store(p, v)
 		}
 	}
+	robo.platforms = append(robo.platforms, c)
 	return nil
 }
 
-func loadDBs(ctx context.Context, cfg DBCfg) (kv *badger.DB, sql, priv, spoke *sqlitex.Pool, err error) {
-	if cfg.KVBrain != "" && cfg.SQLBrain != "" {
-		return nil, nil, nil, nil, fmt.Errorf("multiple brain backends requested; use exactly one")
+// timeZone loads a channel's time zone. The empty string is the local time
+// zone, represented as nil.
+func timeZone(name string) (*time.Location, error) {
+	if name == "" {
+		return nil, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// channelGroup is a set of channel configurations for one platform.
+type channelGroup struct {
+	// platform is the name of the platform.
+	platform string
+	// key is the TOML key of the set, e.g. matrix.rooms.
+	key string
+	// channels is the set of channel configurations.
+	channels map[string]*ChannelCfg
+}
+
+// channelGroups returns the sets of channel configurations in a config.
+func channelGroups(cfg *Config) []channelGroup {
+	return []channelGroup{
+		{"twitch", "twitch", cfg.Twitch},
+		{"matrix", "matrix.rooms", cfg.Matrix.Rooms},
+		{"telegram", "telegram.groups", cfg.Telegram.Groups},
+		{"kick", "kick.channels", cfg.Kick.Channels},
+		{"slack", "slack.channels", cfg.Slack.Channels},
+		{"discord", "discord.channels", cfg.Discord.Channels},
+		{"logs", "logs.channels", cfg.Logs.Channels},
+	}
+}
+
+// channelZones maps each configured channel to its time zone name.
+func channelZones(cfg *Config) map[string]string {
+	r := make(map[string]string)
+	for _, g := range channelGroups(cfg) {
+		for _, ch := range g.channels {
+			for _, p := range ch.Channels {
+				r[p] = ch.TimeZone
+			}
+		}
+	}
+	return r
+}
+
+// fallback parses a channel's response to generating nothing.
+func fallback(s string) (channel.Fallback, error) {
+	switch strings.ToLower(s) {
+	case "", "silent":
+		return channel.FallbackSilent, nil
+	case "template":
+		return channel.FallbackTemplate, nil
+	case "unprompted":
+		return channel.FallbackUnprompted, nil
+	default:
+		return 0, fmt.Errorf("unknown fallback %q", s)
+	}
+}
+
+// commandLimits converts command limit configurations for a channel.
+func commandLimits(cfg map[string]CommandLimit) map[string]channel.Limit {
+	if len(cfg) == 0 {
+		return nil
+	}
+	m := make(map[string]channel.Limit, len(cfg))
+	for k, v := range cfg {
+		m[k] = channel.Limit{
+			Off:          v.Off,
+			Cooldown:     fseconds(v.Cooldown),
+			UserCooldown: fseconds(v.UserCooldown),
+		}
+	}
+	return m
+}
+
+// personality converts a personality configuration for a channel.
+func personality(cfg Personality) channel.Personality {
+	p := channel.Personality{
+		Emote:      1,
+		Effect:     1,
+		Exclaim:    cfg.Exclaim,
+		Caps:       cfg.Caps,
+		MinWords:   cfg.MinWords,
+		MaxWords:   cfg.MaxWords,
+		MaxEmotes:  cfg.MaxEmotes,
+		MinEntropy: cfg.MinEntropy,
+		Lurk:       fseconds(cfg.Lurk),
+	}
+	if cfg.Emote != nil {
+		p.Emote = *cfg.Emote
+	}
+	if cfg.Effect != nil {
+		p.Effect = *cfg.Effect
+	}
+	return p
+}
+
+// classifyPolicy creates the classification policy for a channel.
+func classifyPolicy(global ClassifierCfg, cfg ClassifyCfg) (*classify.Policy, error) {
+	if len(cfg.Thresholds) == 0 {
+		return nil, nil
+	}
+	p := &classify.Policy{
+		Thresholds: cfg.Thresholds,
+		Timeout:    fseconds(global.Timeout),
+	}
+	if p.Timeout <= 0 {
+		// A hung classifier would otherwise hold up the channel's works
+		// forever.
+		p.Timeout = 10 * time.Second
+	}
+	switch {
+	case len(global.Command) != 0 && global.URL != "":
+		return nil, errors.New("only one of global classifier command and url may be set")
+	case len(global.Command) != 0:
+		p.Classifier = &classify.Command{Args: global.Command}
+	case global.URL != "":
+		p.Classifier = &classify.HTTP{URL: global.URL}
+	default:
+		return nil, errors.New("classify thresholds set without a global classifier")
+	}
+	switch strings.ToLower(cfg.Fail) {
+	case "", "open":
+	case "closed":
+		p.FailClosed = true
+	default:
+		return nil, fmt.Errorf("unknown classifier failure policy %q", cfg.Fail)
+	}
+	return p, nil
+}
+
+// profanityLists creates the profanity word lists from the global
+// configuration.
+func profanityLists(cfg ProfanityCfg) *profanity.Lists {
+	l := profanity.New()
+	if !cfg.NoDefaults {
+		l = profanity.Default()
+	}
+	l.Add(profanity.Mild, cfg.Mild...)
+	l.Add(profanity.Strong, cfg.Strong...)
+	l.Add(profanity.Never, cfg.Never...)
+	return l
+}
+
+// profanityPolicy creates the profanity policy for a channel.
+// The default for each limit is strong, so only words in the never tier are
+// blocked.
+func profanityPolicy(words *profanity.Lists, cfg ProfanityLimits) (*profanity.Policy, error) {
+	p := &profanity.Policy{Lists: words, Learn: profanity.Strong, Send: profanity.Strong}
+	var err error
+	if cfg.Learn != "" {
+		p.Learn, err = profanity.ParseTier(cfg.Learn)
+		if err != nil {
+			return nil, fmt.Errorf("bad learn limit: %w", err)
+		}
+	}
+	if cfg.Send != "" {
+		p.Send, err = profanity.ParseTier(cfg.Send)
+		if err != nil {
+			return nil, fmt.Errorf("bad send limit: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// speechFeed creates the TTS feed for a channel configuration.
+// It returns nil if TTS is disabled.
+func speechFeed(cfg TTSCfg) (*tts.Feed, error) {
+	q := cfg.Queue
+	if q <= 0 {
+		q = 5
 	}
-	if cfg.KVBrain == "" && cfg.SQLBrain == "" {
-		return nil, nil, nil, nil, fmt.Errorf("no brain backends requested; use exactly one")
+	switch {
+	case len(cfg.Command) != 0 && cfg.URL != "":
+		return nil, errors.New("only one of command and url may be set")
+	case len(cfg.Command) != 0:
+		return tts.NewFeed(&tts.Command{Args: cfg.Command, Type: cfg.Type}, q), nil
+	case cfg.URL != "":
+		return tts.NewFeed(&tts.HTTP{URL: cfg.URL}, q), nil
+	default:
+		return nil, nil
+	}
+}
+
+// databases is the set of databases opened from a [DBCfg].
+// Databases which share a DSN share the same pool.
+type databases struct {
+	// kv, bolt, sql, and pg are the brain databases, and remote is a brain
+	// served elsewhere. Exactly one is non-nil.
+	kv     *badger.DB
+	bolt   *bbolt.DB
+	sql    *sqlitex.Pool
+	pg     *sql.DB
+	remote *remote.Brain
+	// replica is the read replica of sql. It may be nil.
+	replica *sqlitex.Pool
+	// priv is the privacy list database.
+	priv *sqlitex.Pool
+	// spoke is the spoken history database.
+	spoke *sqlitex.Pool
+	// emotes is the runtime emote additions database.
+	emotes *sqlitex.Pool
+	// retry retries operations on the brain and privacy databases which
+	// find them busy. It is nil if busy operations aren't retried.
+	retry *sqlpool.Retrier
+}
+
+func loadDBs(ctx context.Context, cfg DBCfg) (*databases, error) {
+	n := 0
+	for _, b := range []string{cfg.KVBrain, cfg.BoltBrain, cfg.SQLBrain, cfg.Remote.Addr} {
+		if b != "" {
+			n++
+		}
+	}
+	switch {
+	case n > 1:
+		return nil, fmt.Errorf("multiple brain backends requested; use exactly one")
+	case n == 0:
+		return nil, fmt.Errorf("no brain backends requested; use exactly one")
+	}
+	for _, how := range cfg.Recover {
+		switch how {
+		case "truncate", "restore", "fresh": // do nothing
+		default:
+			return nil, fmt.Errorf("unknown kvbrain recovery strategy %q", how)
+		}
+	}
+	var db databases
+	var err error
+	popts := sqlpool.Options{Size: cfg.Pool, BusyTimeout: fseconds(cfg.BusyTimeout)}
+	if cfg.BusyRetries > 0 {
+		db.retry = sqlpool.NewRetrier(cfg.BusyRetries+1, 10*time.Millisecond)
 	}
 
 	if cfg.KVBrain != "" {
 		slog.DebugContext(ctx, "using kvbrain", slog.String("path", cfg.KVBrain), slog.String("flags", cfg.KVFlag))
-		opts := badger.DefaultOptions(cfg.KVBrain)
-		// TODO(zeph): logger?
-		opts = opts.WithLogger(nil)
-		opts = opts.WithCompression(options.None)
-		opts = opts.WithBloomFalsePositive(0)
-		kv, err = badger.Open(opts.FromSuperFlag(cfg.KVFlag))
+		db.kv, err = openKV(ctx, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't open kvbrain db: %w", err)
+		}
+	}
+	if cfg.BoltBrain != "" {
+		slog.DebugContext(ctx, "using boltbrain", slog.String("path", cfg.BoltBrain))
+		// Only one process can open the file at a time. Fail rather than
+		// waiting forever for another one to exit.
+		db.bolt, err = bbolt.Open(cfg.BoltBrain, 0o600, &bbolt.Options{Timeout: 10 * time.Second})
 		if err != nil {
-			return nil, nil, nil, nil, fmt.Errorf("couldn't open kvbrain db: %w", err)
+			return nil, fmt.Errorf("couldn't open boltbrain db: %w", err)
 		}
 	}
-	if cfg.SQLBrain != "" {
+	pg := sqlbrain.IsPostgres(cfg.SQLBrain)
+	if pg {
+		// The other databases are SQLite, so they can't share the brain's.
+		switch cfg.SQLBrain {
+		case cfg.Privacy:
+			return nil, errors.New("privacy db can't be shared with sqlbrain on postgres")
+		case cfg.Spoken:
+			return nil, errors.New("spoken history db can't be shared with sqlbrain on postgres")
+		case cfg.Emotes:
+			return nil, errors.New("emotes db can't be shared with sqlbrain on postgres")
+		}
+		if cfg.SQLReplica != "" {
+			return nil, errors.New("sqlreplica requires sqlbrain on sqlite")
+		}
+	}
+	switch {
+	case pg:
+		slog.DebugContext(ctx, "using sqlbrain on postgres")
+		db.pg, err = openPostgres(ctx, cfg.SQLBrain, cfg.Pool)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't open sqlbrain db: %w", err)
+		}
+	case cfg.SQLBrain != "":
 		slog.DebugContext(ctx, "using sqlbrain", slog.String("path", cfg.SQLBrain))
-		sql, err = sqlitex.NewPool(cfg.SQLBrain, sqlitex.PoolOptions{PrepareConn: sqlbrain.RecommendedPrep})
+		db.sql, err = sqlpool.Open(cfg.SQLBrain, popts, sqlbrain.RecommendedPrep)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't open sqlbrain db: %w", err)
+		}
+	}
+	if cfg.Remote.Addr != "" {
+		slog.DebugContext(ctx, "using remote brain", slog.String("addr", cfg.Remote.Addr))
+		db.remote, err = cfg.Remote.dial()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.SQLReplica != "" {
+		if cfg.SQLBrain == "" {
+			return nil, errors.New("sqlreplica requires sqlbrain")
+		}
+		slog.DebugContext(ctx, "using sqlbrain replica", slog.String("path", cfg.SQLReplica))
+		db.replica, err = sqlpool.Open(cfg.SQLReplica, popts, sqlbrain.ReplicaPrep)
 		if err != nil {
-			return nil, nil, nil, nil, fmt.Errorf("couldn't open sqlbrain db: %w", err)
+			return nil, fmt.Errorf("couldn't open sqlbrain replica db: %w", err)
 		}
 	}
 
 	switch cfg.Privacy {
 	case cfg.SQLBrain:
 		slog.DebugContext(ctx, "privacy db shared with sqlbrain")
-		priv = sql
+		db.priv = db.sql
 	default:
 		slog.DebugContext(ctx, "privacy db", slog.String("path", cfg.Privacy))
-		priv, err = sqlitex.NewPool(cfg.Privacy, sqlitex.PoolOptions{})
+		db.priv, err = sqlpool.Open(cfg.Privacy, popts, nil)
 		if err != nil {
-			return nil, nil, nil, nil, fmt.Errorf("couldn't open privacy db: %w", err)
+			return nil, fmt.Errorf("couldn't open privacy db: %w", err)
 		}
 	}
 
 	switch cfg.Spoken {
 	case cfg.SQLBrain:
 		slog.DebugContext(ctx, "spoken history db shared with sqlbrain")
-		spoke = sql
+		db.spoke = db.sql
 	case cfg.Privacy:
 		slog.DebugContext(ctx, "spoken history db shared with privacy db")
-		spoke = priv
+		db.spoke = db.priv
 	default:
 		slog.DebugContext(ctx, "spoken history db", slog.String("path", cfg.Spoken))
-		spoke, err = sqlitex.NewPool(cfg.Spoken, sqlitex.PoolOptions{})
+		db.spoke, err = sqlpool.Open(cfg.Spoken, popts, nil)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't open spoken history db: %w", err)
+		}
+	}
+
+	switch cfg.Emotes {
+	case "", cfg.Privacy:
+		slog.DebugContext(ctx, "emotes db shared with privacy db")
+		db.emotes = db.priv
+	case cfg.SQLBrain:
+		slog.DebugContext(ctx, "emotes db shared with sqlbrain")
+		db.emotes = db.sql
+	case cfg.Spoken:
+		slog.DebugContext(ctx, "emotes db shared with spoken history db")
+		db.emotes = db.spoke
+	default:
+		slog.DebugContext(ctx, "emotes db", slog.String("path", cfg.Emotes))
+		db.emotes, err = sqlpool.Open(cfg.Emotes, popts, nil)
 		if err != nil {
-			return nil, nil, nil, nil, fmt.Errorf("couldn't open spoken history db: %w", err)
+			return nil, fmt.Errorf("couldn't open emotes db: %w", err)
 		}
 	}
 
-	return kv, sql, priv, spoke, nil
+	return &db, nil
+}
+
+// openPostgres connects to a PostgreSQL database. If pool is positive, it
+// limits the open connections.
+func openPostgres(ctx context.Context, dsn string, pool int) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if pool > 0 {
+		db.SetMaxOpenConns(pool)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
 }
 
 func mergemaps(ms ...map[string]int) map[string]int {
@@ -425,6 +1248,8 @@ type keys struct {
 	userhash []byte
 	// twitch is the key for Twitch OAuth2 token storage.
 	twitch *[auth.KeySize]byte
+	// apikey is the key for hashing HTTP API keys.
+	apikey []byte
 }
 
 // domainkey fills o with a key derived from k for the given domain. Panics if
@@ -449,23 +1274,56 @@ type Config struct {
 	DB DBCfg `toml:"db"`
 	// Global is the table of global settings.
 	Global Global `toml:"global"`
+	// HTTP is the configuration for the bot's HTTP server.
+	HTTP HTTPCfg `toml:"http"`
 	// TMI is the configuration for connecting to Twitch chat.
 	TMI ClientCfg `toml:"tmi"`
 	// Twitch is the set of channel configurations for twitch. Each key
 	// represents a group of one or more channels sharing a config.
 	Twitch map[string]*ChannelCfg `toml:"twitch"`
+	// Matrix is the configuration for Matrix.
+	Matrix MatrixCfg `toml:"matrix"`
+	// Telegram is the configuration for Telegram.
+	Telegram TelegramCfg `toml:"telegram"`
+	// Kick is the configuration for Kick.
+	Kick KickCfg `toml:"kick"`
+	// Slack is the configuration for Slack.
+	Slack SlackCfg `toml:"slack"`
+	// Discord is the configuration for Discord.
+	Discord DiscordCfg `toml:"discord"`
+	// Logs is the configuration for learning from chat logs.
+	Logs LogsCfg `toml:"logs"`
+	// Poster is the set of configurations for posting generated messages to
+	// social media.
+	Poster map[string]*PosterCfg `toml:"poster"`
+	// Bridge is the set of configurations for relaying chat between
+	// channels.
+	Bridge map[string]*BridgeCfg `toml:"bridge"`
+	// View is the set of tags composed from other tags.
+	View map[string]*ViewCfg `toml:"view"`
 }
 
 // ChannelCfg is the configuration for a channel.
 type ChannelCfg struct {
 	// Channels is the list of channels using this config.
 	Channels []string `toml:"channels"`
+	// Profile is the name of a set of defaults for responses, rate,
+	// copypasta, profanity, and effects: conservative, standard, or chaotic.
+	// Options set in the channel config override the profile's.
+	Profile string `toml:"profile"`
 	// Learn is the tag used for learning from these channels.
 	Learn string `toml:"learn"`
 	// Send is the tag used for generating messages for these channels.
 	Send string `toml:"send"`
+	// Tags is the list of other tags from which moderators may have the bot
+	// speak in these channels.
+	Tags []string `toml:"tags"`
 	// Block is a regular expression of messages to ignore.
 	Block string `toml:"block"`
+	// Forbid is the prompts the bot refuses to continue in these channels.
+	Forbid Forbid `toml:"forbid"`
+	// Profanity is the highest tiers of profanity allowed in the channel.
+	Profanity ProfanityLimits `toml:"profanity"`
 	// Responses is the probability of generating a random message when
 	// a non-command message is received.
 	Responses float64 `toml:"responses"`
@@ -473,12 +1331,76 @@ type ChannelCfg struct {
 	Rate Rate `toml:"rate"`
 	// Copypasta is the configuration for copypasta.
 	Copypasta Copypasta `toml:"copypasta"`
+	// Context is the configuration for seeding random responses from recent
+	// chat messages.
+	Context Seeding `toml:"context"`
+	// Callout is the configuration for calling out recent chatters in random
+	// responses.
+	Callout Callout `toml:"callout"`
 	// Emotes is the emotes and their weights for the channel.
 	Emotes map[string]int `toml:"emotes"`
+	// EmoteUsage is the configuration for deriving emote weights from the
+	// emotes used in chat.
+	EmoteUsage EmoteUsage `toml:"emote_usage"`
+	// EmoteServices is the weights given to each emote from third-party
+	// emote services in the channel. It applies only to Twitch channels.
+	EmoteServices EmoteServices `toml:"emote_services"`
 	// Effects is the effects and their weights for the channel.
 	Effects map[string]int `toml:"effects"`
 	// Privileges is the user access controls for the channel.
 	Privileges []Privilege `toml:"privileges"`
+	// VIPMod gives the channel's VIPs moderator privileges. It applies only
+	// on platforms whose roles the bot syncs.
+	VIPMod bool `toml:"vip_moderator"`
+	// Events is the channel events the bot acknowledges in chat: raid,
+	// follow, subscribe, and redeem. It applies only to Twitch channels with
+	// tmi.eventsub enabled.
+	Events []string `toml:"events"`
+	// Templates is the path to a file of response templates overriding the
+	// global ones for the channel.
+	Templates string `toml:"templates"`
+	// TTS is the configuration for speaking generated messages on stream.
+	TTS TTSCfg `toml:"tts"`
+	// Commands is the configuration for invoking commands.
+	Commands Commands `toml:"commands"`
+	// Personality is the configuration for how the bot speaks.
+	Personality Personality `toml:"personality"`
+	// Classify is the configuration for consulting the global classifier
+	// before sending generated messages.
+	Classify ClassifyCfg `toml:"classify"`
+	// Engagement is the number of seconds within which a response to a
+	// random message from the bot counts as engagement with it.
+	// If it is zero, engagement is not tracked.
+	Engagement float64 `toml:"engagement"`
+	// Suspend is the chat restrictions under which the bot stops learning.
+	Suspend Suspend `toml:"suspend"`
+	// Leaderboard enables counting learned messages from users who opt in
+	// for a leaderboard.
+	Leaderboard bool `toml:"leaderboard"`
+	// TimeZone is the IANA time zone name, e.g. America/New_York, which
+	// decides where days begin and end for daily stats in these channels.
+	// The default is the server's local time zone.
+	TimeZone string `toml:"timezone"`
+	// Replies is the framings in which to wrap generated replies to users
+	// who address the bot, like "@{user} {text}". One is chosen at random for
+	// each reply. {user}, {channel}, {game}, and {text} are replaced with the
+	// user's display name, the channel, the game being streamed, and the
+	// reply, respectively.
+	Replies []string `toml:"replies"`
+	// Pace is the range of time the bot aims to take to reply to a message,
+	// so that replies don't arrive faster than a person could type them.
+	Pace Pace `toml:"pace"`
+	// Fallback is what to do when asked to speak but the brain generates
+	// nothing: silent, template, or unprompted. The default is silent.
+	Fallback string `toml:"fallback"`
+	// Quota is the limit on knowledge kept under the channel's learn tag.
+	Quota Quota `toml:"quota"`
+	// LearnLimit is the most messages to learn from each user within a
+	// number of seconds. If num is zero, there is no limit.
+	LearnLimit Threshold `toml:"learn_limit"`
+	// Preload is the knowledge under the channel's send tag to read into
+	// memory at startup.
+	Preload Preload `toml:"preload"`
 }
 
 // Global is the configuration for globally applied options.
@@ -491,11 +1413,73 @@ type Global struct {
 	Effects map[string]int `toml:"effects"`
 	// Privileges is the user access controls across entire services.
 	Privileges GlobalPrivs `toml:"privileges"`
+	// Ignore is user IDs and Twitch logins to ignore in every channel on
+	// every service.
+	Ignore []string `toml:"ignore"`
+	// Templates is the path to a file of response templates overriding the
+	// built-in ones.
+	Templates string `toml:"templates"`
+	// Panics is the number of panics within a time span after which a
+	// channel stops handling messages.
+	Panics Threshold `toml:"panics"`
+	// Breaker is the configuration for the brain circuit breaker.
+	Breaker Breaker `toml:"breaker"`
+	// Workers is the number of messages to handle at once across all
+	// channels. If it is not positive, the number of CPUs is used.
+	Workers int `toml:"workers"`
+	// Queue is the number of messages which may wait to be handled in each
+	// channel before the oldest are dropped. If it is not positive, 1000 are
+	// allowed.
+	Queue int `toml:"queue"`
+	// Backpressure is the configuration for dropping messages from learning
+	// when the bot is behind.
+	Backpressure Backpressure `toml:"backpressure"`
+	// Warm is the configuration for generating random responses ahead of
+	// time.
+	Warm Warm `toml:"warm"`
+	// Sweep is the interval in seconds at which storage quotas are enforced.
+	// If it is not positive, quotas are enforced hourly.
+	Sweep float64 `toml:"sweep"`
+	// Disk is the configuration for watching free space on the volumes
+	// holding the databases.
+	Disk DiskCfg `toml:"disk"`
+	// Alerts is the thresholds on channel rates beyond which to alert.
+	Alerts Alerts `toml:"alerts"`
+	// UpdateCheck is the interval in seconds at which to check for new
+	// releases. If it is not positive, the bot doesn't check.
+	UpdateCheck float64 `toml:"update_check"`
+	// Duplicates is the window in seconds within which channels sharing a
+	// send tag won't send the same generated message. If it is not positive,
+	// they may.
+	Duplicates float64 `toml:"duplicates"`
+	// Undelete is the time in seconds for which forgotten knowledge can be
+	// restored before it is purged. If it is not positive, forgotten
+	// knowledge is kept indefinitely.
+	Undelete float64 `toml:"undelete"`
+	// Jobs is the configuration for running background jobs.
+	Jobs JobsCfg `toml:"jobs"`
+	// Classifier is the content classifier consulted before sending generated
+	// messages in channels which configure thresholds for it.
+	Classifier ClassifierCfg `toml:"classifier"`
+	// Profanity is the word lists used to rate profanity.
+	Profanity ProfanityCfg `toml:"profanity"`
+	// Bots is the configuration for recognizing messages directed at other
+	// chat bots, which are neither learned nor answered.
+	Bots Bots `toml:"bots"`
+	// LegacyTags allows tags which are not namespaced by platform, logging
+	// a warning for them instead of refusing to start.
+	LegacyTags bool `toml:"legacy_tags"`
 }
 
 // GlobalPrivs is the configuration for privileges across entire services.
 type GlobalPrivs struct {
-	Twitch []Privilege `toml:"twitch"`
+	Twitch   []Privilege `toml:"twitch"`
+	Matrix   []Privilege `toml:"matrix"`
+	Telegram []Privilege `toml:"telegram"`
+	Kick     []Privilege `toml:"kick"`
+	Slack    []Privilege `toml:"slack"`
+	Discord  []Privilege `toml:"discord"`
+	Logs     []Privilege `toml:"logs"`
 }
 
 // Owner is metadata about the bot owner.
@@ -504,6 +1488,10 @@ type Owner struct {
 	Name string `toml:"name"`
 	// Contact describes owner contact information.
 	Contact string `toml:"contact"`
+	// Notify is the destination for notifications to the owner about
+	// problems with the bot. It may be an HTTP URL to receive a webhook or
+	// the name of a channel the bot is in.
+	Notify string `toml:"notify"`
 }
 
 // ClientCfg is the configuration for connecting to an OAuth2 interface.
@@ -525,6 +1513,17 @@ type ClientCfg struct {
 	Owner Privilege `toml:"owner"`
 	// Rate is the global rate limit for this client.
 	Rate Rate `toml:"rate"`
+	// Roles is the interval in seconds at which to sync each channel's
+	// moderators and VIPs from the platform. If it is not positive, they
+	// aren't synced.
+	Roles float64 `toml:"roles"`
+	// EventSub connects to Twitch EventSub to receive raids, follows,
+	// subscriptions, and channel point redemptions in each channel.
+	EventSub bool `toml:"eventsub"`
+	// Liveness is the time in seconds that a TMI connection may go without
+	// receiving anything before the bot reconnects. The bot pings the server
+	// once half of it passes. If it is not positive, it is 120.
+	Liveness float64 `toml:"liveness"`
 
 	endpoint oauth2.Endpoint `toml:"-"`
 }
@@ -538,17 +1537,157 @@ type Privilege struct {
 	// Level is the access level granted to the user.
 	// Valid values are the empty string as the default capability,
 	// "ignore" to disable access to all commands including prompting,
-	// or "moderator" to enable access to moderation commands.
+	// "moderator" to enable access to moderation commands,
+	// or "operator" to also enable access to commands managing the bot
+	// across channels.
 	Level string `toml:"level"`
 }
 
 // DBCfg is the configuration of databases.
 type DBCfg struct {
+	// SQLBrain is the connection string for the SQLite database holding the
+	// brain, or a postgres:// URL for a PostgreSQL database.
 	SQLBrain string `toml:"sqlbrain"`
-	KVBrain  string `toml:"kvbrain"`
-	KVFlag   string `toml:"kvflag"`
-	Privacy  string `toml:"privacy"`
-	Spoken   string `toml:"spoken"`
+	// SQLReplica is the connection string for a read replica of SQLBrain
+	// from which the brain speaks.
+	SQLReplica string `toml:"sqlreplica"`
+	KVBrain    string `toml:"kvbrain"`
+	KVFlag     string `toml:"kvflag"`
+	// BoltBrain is the bbolt database file holding the brain.
+	BoltBrain string `toml:"boltbrain"`
+	// Remote is the configuration for using a brain served by another
+	// process.
+	Remote RemoteCfg `toml:"remote"`
+	// Recover is the list of strategies to try in order when the kvbrain
+	// database fails to open: "truncate", "restore", or "fresh".
+	Recover []string `toml:"recover"`
+	// Backups is a kvbrain backup file or a directory of them, the newest of
+	// which is used to restore a database that fails to open.
+	Backups string `toml:"backups"`
+	Privacy string `toml:"privacy"`
+	Spoken  string `toml:"spoken"`
+	Emotes  string `toml:"emotes"`
+	// Pool is the number of connections in each SQLite connection pool.
+	Pool int `toml:"pool"`
+	// BusyTimeout is the longest in seconds that an SQLite connection waits
+	// for a lock held by another connection.
+	BusyTimeout float64 `toml:"busy_timeout"`
+	// BusyRetries is the number of times to retry brain and privacy list
+	// operations which fail because the database is locked.
+	BusyRetries int `toml:"busy_retries"`
+	// Hot is the configuration for keeping recent messages in memory.
+	Hot HotCfg `toml:"hot"`
+	// Queue is the configuration for learning through a message queue.
+	Queue QueueCfg `toml:"queue"`
+	// Deadline is the configuration for bounding the time brain operations
+	// may take.
+	Deadline DeadlineCfg `toml:"deadline"`
+}
+
+// DeadlineCfg is the configuration for brain operation deadlines.
+// Times are in seconds. Zero values use defaults, and negative values
+// disable the respective limits.
+type DeadlineCfg struct {
+	// Learn is the longest learning a message may take.
+	Learn float64 `toml:"learn"`
+	// Speak is the longest generating a message may take.
+	Speak float64 `toml:"speak"`
+	// Forget is the longest forgetting may take.
+	Forget float64 `toml:"forget"`
+	// Stalled is the number of operations still running past their
+	// deadlines at which the brain is treated as unavailable.
+	Stalled int `toml:"stalled"`
+}
+
+// RemoteCfg is the configuration for a brain served by robot serve-brain.
+type RemoteCfg struct {
+	// Addr is the gRPC target of the brain service.
+	Addr string `toml:"addr"`
+	// Token is the path to a file containing the token shared with the
+	// service. If it is empty, no token is presented.
+	Token string `toml:"token"`
+	// TLS connects to the service with TLS.
+	TLS bool `toml:"tls"`
+	// CA is the path to a PEM file of certificates to trust for the service
+	// instead of the system's. It implies TLS.
+	CA string `toml:"ca"`
+	// Insecure allows sending the token without TLS to a service that isn't
+	// on the loopback interface.
+	Insecure bool `toml:"insecure"`
+}
+
+// dial creates the client for the remote brain.
+func (cfg RemoteCfg) dial() (*remote.Brain, error) {
+	tok, err := readToken(cfg.Token)
+	if err != nil {
+		return nil, err
+	}
+	dc := remote.DialConfig{Token: tok, Insecure: cfg.Insecure}
+	if cfg.TLS || cfg.CA != "" {
+		dc.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if cfg.CA != "" {
+		b, err := os.ReadFile(cfg.CA)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read remote brain CA: %w", err)
+		}
+		dc.TLS.RootCAs = x509.NewCertPool()
+		if !dc.TLS.RootCAs.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificates in remote brain CA %s", cfg.CA)
+		}
+	}
+	return remote.Dial(cfg.Addr, dc)
+}
+
+// readToken reads a shared token from a file. If file is empty, so is the
+// token.
+func readToken(file string) (string, error) {
+	if file == "" {
+		return "", nil
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("couldn't read token: %w", err)
+	}
+	return string(bytes.TrimSuffix(bytes.TrimSuffix(b, []byte{'\n'}), []byte{'\r'})), nil
+}
+
+// QueueCfg is the configuration for sending learned messages through a NATS
+// JetStream queue to a separate learner process.
+type QueueCfg struct {
+	// URL is the NATS server URL. If it is empty, the bot learns directly.
+	URL string `toml:"url"`
+	// Stream is the name of the JetStream stream holding queued operations.
+	Stream string `toml:"stream"`
+	// Subject is the subject to which operations are published.
+	Subject string `toml:"subject"`
+}
+
+// dial connects to the queue. Unset names get defaults.
+func (cfg QueueCfg) dial(ctx context.Context) (*queue.NATS, error) {
+	stream, subject := cfg.Stream, cfg.Subject
+	if stream == "" {
+		stream = "ROBOT"
+	}
+	if subject == "" {
+		subject = "robot.learn"
+	}
+	return queue.DialNATS(ctx, cfg.URL, stream, subject)
+}
+
+// HotCfg is the configuration for keeping recent messages in memory in front
+// of the brain database.
+type HotCfg struct {
+	// Window is the time in seconds for which messages stay in memory.
+	// If it is not positive, there is no memory tier.
+	Window float64 `toml:"window"`
+	// Recency is the probability of speaking only from messages in memory.
+	Recency float64 `toml:"recency"`
+	// Flush is the interval in seconds at which messages in memory are
+	// written to the brain database.
+	Flush float64 `toml:"flush"`
+	// Batch is the most messages to write at once.
+	Batch int `toml:"batch"`
 }
 
 // Rate is a rate limit configuration.
@@ -557,36 +1696,606 @@ type Rate struct {
 	Num   int     `toml:"num"`
 }
 
+// Threshold is a number of events within a time span in seconds.
+type Threshold struct {
+	Num    int     `toml:"num"`
+	Within float64 `toml:"within"`
+}
+
+// Breaker is a brain circuit breaker configuration.
+// Times are in seconds.
+type Breaker struct {
+	Num      int     `toml:"num"`
+	Within   float64 `toml:"within"`
+	Slow     float64 `toml:"slow"`
+	Cooldown float64 `toml:"cooldown"`
+}
+
+// Warm is the configuration for generating random responses ahead of time.
+type Warm struct {
+	// Size is the number of messages to keep ready for each tag.
+	Size int `toml:"size"`
+	// TTL is the number of seconds after which a message generated ahead is
+	// too stale to use. Zero means messages never become stale.
+	TTL float64 `toml:"ttl"`
+}
+
+// Backpressure is the configuration for dropping low-value messages from
+// learning under load.
+type Backpressure struct {
+	// Pending is the number of messages waiting to be handled beyond which
+	// low-value messages are not learned.
+	Pending int `toml:"pending"`
+	// Short is the number of tokens below which a message is low-value.
+	Short int `toml:"short"`
+}
+
+// MatrixCfg is the configuration for connecting to Matrix.
+type MatrixCfg struct {
+	// Homeserver is the base URL of the homeserver.
+	// If it is empty, the bot does not connect to Matrix.
+	Homeserver string `toml:"homeserver"`
+	// Token is the bot's access token. If it is empty, the bot logs in with
+	// User and Password instead.
+	Token string `toml:"token"`
+	// User is the bot's user ID or localpart for password login.
+	User string `toml:"user"`
+	// Password is the bot's password.
+	Password string `toml:"password"`
+	// Owner is the full user ID of the bot owner.
+	Owner string `toml:"owner"`
+	// SkipEncrypted prevents the bot from sending messages to rooms with
+	// encryption enabled. The bot can never read encrypted messages.
+	SkipEncrypted bool `toml:"skip_encrypted"`
+	// Rooms is the set of room configurations. The channels of each are room
+	// IDs like !abc:example.org.
+	Rooms map[string]*ChannelCfg `toml:"rooms"`
+}
+
+// TelegramCfg is the configuration for the Telegram Bot API.
+type TelegramCfg struct {
+	// Token is the bot token. If it is empty, the bot does not connect to
+	// Telegram.
+	Token string `toml:"token"`
+	// Owner is the user ID of the bot owner.
+	Owner string `toml:"owner"`
+	// Groups is the set of group configurations. The channels of each are
+	// chat IDs like -1001234567890.
+	Groups map[string]*ChannelCfg `toml:"groups"`
+}
+
+// KickCfg is the configuration for connecting to Kick.
+type KickCfg struct {
+	// Token is the bot's user access token for the Kick public API.
+	// If it is empty, the bot does not connect to Kick.
+	Token string `toml:"token"`
+	// Owner is the user ID of the bot owner.
+	Owner string `toml:"owner"`
+	// Key is the Pusher application key for Kick chat.
+	// If it is empty, a known key is used.
+	Key string `toml:"key"`
+	// Cluster is the Pusher cluster for Kick chat. If it is empty, us2 is
+	// used.
+	Cluster string `toml:"cluster"`
+	// Channels is the set of channel configurations, in the same shape as
+	// Twitch channels. The channels of each are Kick channel names prefixed
+	// with kick:, like kick:bocchi.
+	Channels map[string]*ChannelCfg `toml:"channels"`
+}
+
+// SlackCfg is the configuration for connecting to a Slack workspace through
+// Socket Mode.
+type SlackCfg struct {
+	// Token is the bot token, starting with xoxb-.
+	// If it is empty, the bot does not connect to Slack.
+	Token string `toml:"token"`
+	// AppToken is the app-level token with the connections:write scope,
+	// starting with xapp-.
+	AppToken string `toml:"app_token"`
+	// Owner is the user ID of the bot owner.
+	Owner string `toml:"owner"`
+	// Admins gives workspace admins and owners moderator privileges in every
+	// channel.
+	Admins bool `toml:"admins"`
+	// Channels is the set of channel configurations. The channels of each are
+	// Slack channel IDs like C0123456789.
+	Channels map[string]*ChannelCfg `toml:"channels"`
+}
+
+// DiscordCfg is the configuration for connecting to Discord as a bot.
+type DiscordCfg struct {
+	// Token is the bot token. The bot needs the message content intent.
+	// If it is empty, the bot does not connect to Discord.
+	Token string `toml:"token"`
+	// Owner is the user ID of the bot owner.
+	Owner string `toml:"owner"`
+	// ModRoles is the IDs of roles which give moderator privileges in every
+	// channel.
+	ModRoles []string `toml:"mod_roles"`
+	// Channels is the set of channel configurations. The channels of each are
+	// Discord channel IDs prefixed with discord:, like discord:1234567890.
+	Channels map[string]*ChannelCfg `toml:"channels"`
+}
+
+// LogsCfg is the configuration for learning from chat logs written by another
+// tool.
+type LogsCfg struct {
+	// Dir is the directory holding a log directory for each channel.
+	// If it is empty, the bot does not follow logs.
+	Dir string `toml:"dir"`
+	// Format is the layout of the logs, chatterino or justlog.
+	// The default is chatterino.
+	Format string `toml:"format"`
+	// Poll is the number of seconds between checks for new lines.
+	// The default is 1.
+	Poll float64 `toml:"poll"`
+	// Owner is the user ID of the bot owner.
+	Owner string `toml:"owner"`
+	// Channels is the set of channel configurations. The channels of each are
+	// the names of log directories prefixed with log:, like log:bocchi.
+	Channels map[string]*ChannelCfg `toml:"channels"`
+}
+
+// PosterCfg is the configuration for periodically posting generated messages
+// to social media accounts.
+type PosterCfg struct {
+	// Tag is the tag from which to generate posts.
+	Tag string `toml:"tag"`
+	// Every is the number of seconds between posts.
+	Every float64 `toml:"every"`
+	// Block is a regular expression of messages not to post, in addition to
+	// the global block expression.
+	Block string `toml:"block"`
+	// Review holds generated posts for approval instead of publishing them
+	// immediately.
+	Review bool `toml:"review"`
+	// Expire is the number of seconds after which posts awaiting review are
+	// discarded. If it is not positive, they never expire.
+	Expire float64 `toml:"expire"`
+	// Mastodon is the Mastodon account to which to post.
+	Mastodon MastodonCfg `toml:"mastodon"`
+	// Bluesky is the Bluesky account to which to post.
+	Bluesky BlueskyCfg `toml:"bluesky"`
+}
+
+// BridgeCfg is the configuration for relaying chat among channels.
+type BridgeCfg struct {
+	// Channels is the list of channels among which to relay chat.
+	// Each receives messages from all the others.
+	Channels []string `toml:"channels"`
+	// Attribution is a template for relayed messages. It may use .Platform,
+	// .Name, and .Text, which are the source platform, the sender's name, and
+	// the message text.
+	Attribution string `toml:"attribution"`
+}
+
+// ViewCfg is the configuration for a tag composed from other tags.
+type ViewCfg struct {
+	// Tags is the list of tags the view combines.
+	Tags []string `toml:"tags"`
+	// Block is a regular expression of terms the view leaves out.
+	Block string `toml:"block"`
+}
+
+// MastodonCfg is the configuration for a Mastodon account.
+type MastodonCfg struct {
+	// Server is the base URL of the account's server.
+	// If it is empty, the bot does not post to Mastodon.
+	Server string `toml:"server"`
+	// Token is an access token with the write:statuses scope.
+	Token string `toml:"token"`
+	// Visibility is the visibility of posts, e.g. public or unlisted.
+	Visibility string `toml:"visibility"`
+}
+
+// BlueskyCfg is the configuration for a Bluesky account.
+type BlueskyCfg struct {
+	// Service is the base URL of the account's PDS.
+	// If it is empty, https://bsky.social is used.
+	Service string `toml:"service"`
+	// Handle is the account's handle.
+	// If it is empty, the bot does not post to Bluesky.
+	Handle string `toml:"handle"`
+	// Password is an app password for the account.
+	Password string `toml:"password"`
+}
+
+// HTTPCfg is the configuration for the bot's HTTP server.
+type HTTPCfg struct {
+	// Listen is the address on which to serve HTTP.
+	// If it is empty, the bot does not serve HTTP.
+	Listen string `toml:"listen"`
+	// Tokens is the API tokens which grant roles on HTTP endpoints.
+	// If there are none, all endpoints are open.
+	Tokens []APIToken `toml:"tokens"`
+}
+
+// APIToken is an HTTP API token and the role it grants.
+type APIToken struct {
+	// Token is the secret token value.
+	Token string `toml:"token"`
+	// Role is the role the token grants: viewer, channel-moderator, operator,
+	// or owner.
+	Role string `toml:"role"`
+}
+
+// TTSCfg is the configuration for text-to-speech.
+// At most one of Command and URL may be set. If neither is, TTS is disabled.
+type TTSCfg struct {
+	// Command is a program and its arguments which reads text on its standard
+	// input and writes audio to its standard output.
+	Command []string `toml:"command"`
+	// Type is the MIME type of the command's output. Default is audio/wav.
+	Type string `toml:"type"`
+	// URL is the endpoint of an HTTP TTS API which receives text as a POST
+	// body and responds with audio.
+	URL string `toml:"url"`
+	// Queue is the number of clips held waiting to be played. Default is 5.
+	Queue int `toml:"queue"`
+}
+
+// ClassifierCfg is the configuration for a content classifier.
+type ClassifierCfg struct {
+	// Command is a program and its arguments which reads text on its standard
+	// input and writes a JSON object of scores to its standard output.
+	Command []string `toml:"command"`
+	// URL is the endpoint of an HTTP API which receives text as a POST body
+	// and responds with a JSON object of scores.
+	URL string `toml:"url"`
+	// Timeout is the number of seconds to wait for a classification.
+	// If it is not positive, it is 10.
+	Timeout float64 `toml:"timeout"`
+}
+
+// ClassifyCfg is the configuration for classifying generated messages in a
+// channel.
+type ClassifyCfg struct {
+	// Thresholds is the highest allowed score for each category. If it is
+	// empty, the channel does not use the classifier.
+	Thresholds map[string]float64 `toml:"thresholds"`
+	// Fail is what to do when the classifier fails, either open to send the
+	// message anyway or closed to drop it. The default is open.
+	Fail string `toml:"fail"`
+}
+
+// ProfanityCfg is the configuration for profanity word lists.
+// Each word matches whole words regardless of case. A word ending in * matches
+// any word beginning with the rest of it.
+type ProfanityCfg struct {
+	// NoDefaults disables the built-in word lists.
+	NoDefaults bool `toml:"no_defaults"`
+	// Mild is extra words of mild profanity.
+	Mild []string `toml:"mild"`
+	// Strong is extra words of strong profanity.
+	Strong []string `toml:"strong"`
+	// Never is extra words which are never learned or sent.
+	Never []string `toml:"never"`
+}
+
+// ProfanityLimits is the highest tiers of profanity allowed in a channel.
+// Each is one of none, mild, or strong. The default is strong. Words in the
+// never tier are always blocked.
+type ProfanityLimits struct {
+	// Learn is the highest tier allowed in learned messages.
+	Learn string `toml:"learn"`
+	// Send is the highest tier allowed in sent messages.
+	Send string `toml:"send"`
+}
+
 // Copypasta is a copypasta configuration.
 type Copypasta struct {
 	Need   int     `toml:"need"`
 	Within float64 `toml:"within"`
 }
 
+// Callout is a configuration for calling out recent chatters.
+type Callout struct {
+	// Prob is the probability that a random response calls out a chatter.
+	Prob float64 `toml:"prob"`
+	// Speakers is the number of most recent chatters who have opted in to
+	// credit from whom to choose. If it is zero, callouts are disabled.
+	Speakers int `toml:"speakers"`
+}
+
+// Commands is a configuration for how chat invokes commands.
+type Commands struct {
+	// Prefix is a prefix which invokes commands in addition to addressing
+	// the bot by name. If it is empty, only addressing the bot does.
+	Prefix string `toml:"prefix"`
+	// Off disables commands entirely. Messages addressing the bot are
+	// treated like any others.
+	Off bool `toml:"off"`
+	// Limits is restrictions on individual commands by name.
+	Limits map[string]CommandLimit `toml:"limits"`
+}
+
+// CommandLimit is a configuration restricting a command in a channel.
+type CommandLimit struct {
+	// Off disables the command.
+	Off bool `toml:"off"`
+	// Cooldown is the number of seconds between uses of the command in the
+	// channel. Zero means the command's usual cooldown.
+	Cooldown float64 `toml:"cooldown"`
+	// UserCooldown is the number of seconds between uses of the command by
+	// each user in the channel. Zero means the command's usual cooldown.
+	UserCooldown float64 `toml:"user_cooldown"`
+}
+
+// Personality is a configuration for how the bot speaks in a channel.
+type Personality struct {
+	// Emote is the probability that a generated message ends with an emote.
+	// If it is omitted, generated messages always do.
+	Emote *float64 `toml:"emote"`
+	// Effect is the probability that a generated message has an effect
+	// applied. If it is omitted, effects always apply according to their
+	// weights.
+	Effect *float64 `toml:"effect"`
+	// Exclaim is the probability that a generated message ends with an
+	// exclamation point.
+	Exclaim float64 `toml:"exclaim"`
+	// Caps is the probability that a generated message is in all caps.
+	Caps float64 `toml:"caps"`
+	// MinWords and MaxWords are the preferred range of words in random
+	// responses. Zero means unbounded.
+	MinWords int `toml:"min_words"`
+	MaxWords int `toml:"max_words"`
+	// MaxEmotes is the most emotes allowed in a generated message before it
+	// is regenerated. Zero means unlimited.
+	MaxEmotes int `toml:"max_emotes"`
+	// MinEntropy is the least entropy in bits of the words in a generated
+	// message before it is regenerated. Zero means unlimited.
+	MinEntropy float64 `toml:"min_entropy"`
+	// Lurk is the number of seconds of the bot being silent after which it
+	// speaks up on the next message regardless of the response probability.
+	// If it is zero, the bot only speaks up at random.
+	Lurk float64 `toml:"lurk"`
+}
+
+// DiskCfg is the configuration for watching free disk space.
+// Thresholds are in megabytes, and each is disabled if it is zero.
+type DiskCfg struct {
+	// Every is the interval in seconds between checks.
+	// If it is not positive, space is checked every minute.
+	Every float64 `toml:"every"`
+	// Alert is the free space below which the owner is notified.
+	Alert float64 `toml:"alert"`
+	// Learn is the free space below which learning is paused.
+	Learn float64 `toml:"learn"`
+	// Pause is the free space below which the bot stops handling messages.
+	Pause float64 `toml:"pause"`
+}
+
+// Alerts is the configuration for warning about channels which grow or trip
+// filters unusually fast, as happens when someone tries to poison what the
+// bot learns. Each threshold is disabled if it is not positive.
+type Alerts struct {
+	// Every is the interval in seconds over which rates are measured.
+	// If it is not positive, it is 300.
+	Every float64 `toml:"every"`
+	// Growth is the messages learned per minute in a channel above which to
+	// alert.
+	Growth float64 `toml:"growth"`
+	// Rejection is the fraction of messages generated in a channel which
+	// filters or the classifier reject above which to alert.
+	Rejection float64 `toml:"rejection"`
+	// Filter is the fraction of messages in a channel which filters keep from
+	// learning above which to alert.
+	Filter float64 `toml:"filter"`
+	// Min is the fewest messages generated or seen within an interval for
+	// their rates to count. If it is not positive, it is 10.
+	Min int64 `toml:"min"`
+	// Notify sends alerts to the owner's notification destination rather
+	// than only logging them.
+	Notify bool `toml:"notify"`
+}
+
+// JobsCfg is the configuration for running background jobs.
+type JobsCfg struct {
+	// Concurrency is the number of jobs which may run at once.
+	// If it is not positive, jobs run one at a time.
+	Concurrency int `toml:"concurrency"`
+	// Jitter is the fraction of each job's interval by which its runs are
+	// randomly delayed.
+	Jitter float64 `toml:"jitter"`
+}
+
+// Quota is a limit on the knowledge kept under a tag.
+// Zero limits are unlimited.
+type Quota struct {
+	// Tuples is the most tuples to keep.
+	Tuples int64 `toml:"tuples"`
+	// Bytes is the most bytes of text to keep.
+	Bytes int64 `toml:"bytes"`
+}
+
+// Preload is a configuration for reading knowledge into memory at startup,
+// trading startup time for consistent latency when speaking.
+type Preload struct {
+	// All preloads the entire tag.
+	All bool `toml:"all"`
+	// Top preloads only the tuples with this many of the most frequent
+	// prefixes. It is ignored if All is set.
+	Top int `toml:"top"`
+}
+
+// Bots is a configuration for recognizing messages directed at other bots.
+type Bots struct {
+	// NoDefaults disables the built-in names of popular bots and the default
+	// command prefix, !.
+	NoDefaults bool `toml:"no_defaults"`
+	// Names is the names of other bots. Messages which start by addressing
+	// or mention one of them are directed at it.
+	Names []string `toml:"names"`
+	// Prefixes is the prefixes of other bots' commands. Messages which start
+	// with one followed by a letter are directed at other bots. If it is
+	// unset, the prefix is ! unless NoDefaults is set.
+	Prefixes []string `toml:"prefixes"`
+}
+
+// Forbid is a configuration for prompts the bot refuses to continue.
+type Forbid struct {
+	// Prompts is regular expressions matching forbidden prompts anywhere and
+	// regardless of case.
+	Prompts []string `toml:"prompts"`
+	// Deflect is the responses to a forbidden prompt, one chosen at random.
+	// {user} is replaced with the name of the user who gave the prompt. If it
+	// is empty, the forbidden-prompt template is used.
+	Deflect []string `toml:"deflect"`
+}
+
+// Suspend is a configuration for suspending learning while chat is
+// restricted. Learning resumes once the restrictions are lifted.
+type Suspend struct {
+	// EmoteOnly suspends learning in emote-only mode.
+	EmoteOnly bool `toml:"emote_only"`
+	// SubOnly suspends learning in subscriber-only mode.
+	SubOnly bool `toml:"sub_only"`
+	// Slow suspends learning in slow mode of at least this many seconds.
+	// Zero means slow mode doesn't suspend learning.
+	Slow float64 `toml:"slow"`
+}
+
+// Pace is a range of reply times in seconds.
+type Pace struct {
+	// Min and Max bound the time between a message and the bot's reply.
+	// If Max is zero, replies are sent as soon as they are generated.
+	Min float64 `toml:"min"`
+	Max float64 `toml:"max"`
+}
+
+// EmoteUsage is a configuration for tracking emotes used in chat and blending
+// them into a channel's emote weights.
+type EmoteUsage struct {
+	// Blend is the fraction of emote weight derived from use in chat, from 0
+	// for none to 1 for all of it. The default is 0.
+	Blend float64 `toml:"blend"`
+	// HalfLife is the number of seconds over which the count of each use
+	// halves. The default is one week.
+	HalfLife float64 `toml:"half_life"`
+	// Top is the number of most used emotes which get weights.
+	// The default is 20.
+	Top int `toml:"top"`
+}
+
+// emoteServices converts emote service weights to the map a channel uses.
+// The result is nil if no service has a weight.
+func emoteServices(cfg EmoteServices) map[string]int {
+	var r map[string]int
+	for k, w := range map[string]int{"bttv": cfg.BTTV, "7tv": cfg.SevenTV} {
+		if w > 0 {
+			if r == nil {
+				r = make(map[string]int)
+			}
+			r[k] = w
+		}
+	}
+	return r
+}
+
+// EmoteServices is the weight of each emote from each third-party emote
+// service. A service with no weight is not used.
+type EmoteServices struct {
+	BTTV    int `toml:"bttv"`
+	SevenTV int `toml:"seventv"`
+}
+
+// Seeding is a configuration for prompting random responses with terms from
+// recent chat messages.
+type Seeding struct {
+	// Prob is the probability that a random response is prompted.
+	Prob float64 `toml:"prob"`
+	// Messages is the number of most recent messages from which to take the
+	// prompt.
+	Messages int `toml:"messages"`
+}
+
 func expandcfg(cfg *Config, expand func(s string) string) {
 	fields := []*string{
 		&cfg.SecretFile,
 		&cfg.Owner.Name,
 		&cfg.Owner.Contact,
+		&cfg.Owner.Notify,
 		&cfg.DB.SQLBrain,
+		&cfg.DB.SQLReplica,
 		&cfg.DB.KVBrain,
+		&cfg.DB.BoltBrain,
+		&cfg.DB.Remote.Addr,
+		&cfg.DB.Remote.Token,
+		&cfg.DB.Remote.CA,
+		&cfg.DB.Backups,
 		&cfg.DB.KVFlag,
 		&cfg.DB.Privacy,
 		&cfg.DB.Spoken,
+		&cfg.DB.Emotes,
+		&cfg.DB.Queue.URL,
+		&cfg.Global.Templates,
+		&cfg.Global.Classifier.URL,
+		&cfg.HTTP.Listen,
 		&cfg.TMI.CID,
 		&cfg.TMI.SecretFile,
 		&cfg.TMI.TokenFile,
 		&cfg.TMI.Owner.Name,
 		&cfg.TMI.Owner.ID,
+		&cfg.Matrix.Homeserver,
+		&cfg.Matrix.Token,
+		&cfg.Matrix.User,
+		&cfg.Matrix.Password,
+		&cfg.Matrix.Owner,
+		&cfg.Telegram.Token,
+		&cfg.Telegram.Owner,
+		&cfg.Kick.Token,
+		&cfg.Kick.Owner,
+		&cfg.Slack.Token,
+		&cfg.Slack.AppToken,
+		&cfg.Slack.Owner,
+		&cfg.Discord.Token,
+		&cfg.Discord.Owner,
+		&cfg.Logs.Dir,
+		&cfg.Logs.Owner,
 	}
 	for _, f := range fields {
 		*f = os.Expand(*f, expand)
 	}
 	for _, v := range cfg.Twitch {
-		for i, s := range v.Channels {
-			v.Channels[i] = os.Expand(s, expand)
-		}
-		v.Learn = os.Expand(v.Learn, expand)
-		v.Send = os.Expand(v.Send, expand)
+		expandChannel(v, expand)
+	}
+	for _, v := range cfg.Matrix.Rooms {
+		expandChannel(v, expand)
+	}
+	for _, v := range cfg.Telegram.Groups {
+		expandChannel(v, expand)
+	}
+	for _, v := range cfg.Kick.Channels {
+		expandChannel(v, expand)
+	}
+	for _, v := range cfg.Slack.Channels {
+		expandChannel(v, expand)
+	}
+	for _, v := range cfg.Discord.Channels {
+		expandChannel(v, expand)
+	}
+	for _, v := range cfg.Logs.Channels {
+		expandChannel(v, expand)
+	}
+	for i := range cfg.HTTP.Tokens {
+		cfg.HTTP.Tokens[i].Token = os.Expand(cfg.HTTP.Tokens[i].Token, expand)
+	}
+	for _, v := range cfg.Poster {
+		v.Tag = os.Expand(v.Tag, expand)
+		v.Mastodon.Server = os.Expand(v.Mastodon.Server, expand)
+		v.Mastodon.Token = os.Expand(v.Mastodon.Token, expand)
+		v.Bluesky.Service = os.Expand(v.Bluesky.Service, expand)
+		v.Bluesky.Handle = os.Expand(v.Bluesky.Handle, expand)
+		v.Bluesky.Password = os.Expand(v.Bluesky.Password, expand)
+	}
+}
+
+func expandChannel(v *ChannelCfg, expand func(s string) string) {
+	for i, s := range v.Channels {
+		v.Channels[i] = os.Expand(s, expand)
 	}
+	v.Learn = os.Expand(v.Learn, expand)
+	v.Send = os.Expand(v.Send, expand)
+	v.Templates = os.Expand(v.Templates, expand)
+	v.TTS.URL = os.Expand(v.TTS.URL, expand)
 }
@@ -20,7 +20,7 @@ func eqcase[T comparable](t *testing.T, name string, val T, eq T) {
 }
 
 func TestExampleConfig(t *testing.T) {
-	cfg, _, err := main.Load(context.Background(), strings.NewReader(exampleToml))
+	cfg, _, err := main.Load(context.Background(), strings.NewReader(exampleToml), true)
 	if err != nil {
 		t.Errorf("failed to load example.toml: %v", err)
 	}
@@ -28,6 +28,11 @@ func TestExampleConfig(t *testing.T) {
 	eqcase(t, "Owner.Name", cfg.Owner.Name, `zephyrtronium`)
 	eqcase(t, "Owner.Contact", cfg.Owner.Contact, `/w zephyrtronium`)
 	eqcase(t, "DB.KVBrain", cfg.DB.KVBrain, "")
+	eqcase(t, "DB.BoltBrain", cfg.DB.BoltBrain, "")
+	eqcase(t, "DB.SQLReplica", cfg.DB.SQLReplica, "")
+	eqcase(t, "DB.Queue", cfg.DB.Queue, main.QueueCfg{})
+	eqcase(t, "DB.Remote", cfg.DB.Remote, main.RemoteCfg{})
+	eqcase(t, "DB.Deadline", cfg.DB.Deadline, main.DeadlineCfg{Learn: 5, Speak: 10, Forget: 300, Stalled: 64})
 	eqcase(t, "DB.KVFlag", cfg.DB.KVFlag, "")
 	eqcase(t, "Global.Block", cfg.Global.Block, `(?i)bad\s+stuff[^$x]`)
 	eqcase(t, "Global.Emotes[``]", cfg.Global.Emotes[``], 4)
@@ -38,6 +43,44 @@ func TestExampleConfig(t *testing.T) {
 	eqcase(t, "Global.Effects[`o`]", cfg.Global.Effects[`o`], 1)
 	eqcase(t, "Global.Privileges.Twitch[0].Name", cfg.Global.Privileges.Twitch[0].Name, "nightbot")
 	eqcase(t, "Global.Privileges.Twitch[0].Level", cfg.Global.Privileges.Twitch[0].Level, "ignore")
+	eqcase(t, "len(Global.Ignore)", len(cfg.Global.Ignore), 2)
+	eqcase(t, "Global.Ignore[0]", cfg.Global.Ignore[0], "moobot")
+	eqcase(t, "Global.Ignore[1]", cfg.Global.Ignore[1], "100135110")
+	eqcase(t, "Global.Breaker", cfg.Global.Breaker, main.Breaker{Num: 10, Within: 60, Slow: 5, Cooldown: 30})
+	eqcase(t, "Global.Workers", cfg.Global.Workers, 8)
+	eqcase(t, "Global.Queue", cfg.Global.Queue, 500)
+	eqcase(t, "Global.Backpressure", cfg.Global.Backpressure, main.Backpressure{Pending: 200, Short: 3})
+	eqcase(t, "Global.Classifier.URL", cfg.Global.Classifier.URL, `http://localhost:8081/classify`)
+	eqcase(t, "Global.Classifier.Timeout", cfg.Global.Classifier.Timeout, 2)
+	eqcase(t, "Global.Warm.Size", cfg.Global.Warm.Size, 4)
+	eqcase(t, "Global.Warm.TTL", cfg.Global.Warm.TTL, 600)
+	eqcase(t, "Global.Sweep", cfg.Global.Sweep, 3600)
+	eqcase(t, "Global.Undelete", cfg.Global.Undelete, 604800)
+	eqcase(t, "Global.Disk.Every", cfg.Global.Disk.Every, 60)
+	eqcase(t, "Global.Disk.Alert", cfg.Global.Disk.Alert, 4096)
+	eqcase(t, "Global.Disk.Learn", cfg.Global.Disk.Learn, 1024)
+	eqcase(t, "Global.Disk.Pause", cfg.Global.Disk.Pause, 256)
+	eqcase(t, "Global.Alerts.Every", cfg.Global.Alerts.Every, 300)
+	eqcase(t, "Global.Alerts.Growth", cfg.Global.Alerts.Growth, 120)
+	eqcase(t, "Global.Alerts.Rejection", cfg.Global.Alerts.Rejection, 0.5)
+	eqcase(t, "Global.Alerts.Filter", cfg.Global.Alerts.Filter, 0.3)
+	eqcase(t, "Global.Alerts.Min", cfg.Global.Alerts.Min, 20)
+	eqcase(t, "Global.Alerts.Notify", cfg.Global.Alerts.Notify, true)
+	eqcase(t, "Global.UpdateCheck", cfg.Global.UpdateCheck, 86400)
+	eqcase(t, "Global.Duplicates", cfg.Global.Duplicates, 600)
+	eqcase(t, "Global.Jobs.Concurrency", cfg.Global.Jobs.Concurrency, 2)
+	eqcase(t, "Global.Jobs.Jitter", cfg.Global.Jobs.Jitter, 0.1)
+	eqcase(t, "Global.Profanity.NoDefaults", cfg.Global.Profanity.NoDefaults, false)
+	eqcase(t, "Global.Profanity.Mild[0]", cfg.Global.Profanity.Mild[0], `frick*`)
+	eqcase(t, "len(Global.Profanity.Strong)", len(cfg.Global.Profanity.Strong), 0)
+	eqcase(t, "Global.Profanity.Never[0]", cfg.Global.Profanity.Never[0], `cucumbers`)
+	eqcase(t, "Global.Bots.NoDefaults", cfg.Global.Bots.NoDefaults, false)
+	eqcase(t, "Global.Bots.Names[1]", cfg.Global.Bots.Names[1], `kessokubot`)
+	eqcase(t, "Global.Bots.Prefixes[1]", cfg.Global.Bots.Prefixes[1], `?`)
+	eqcase(t, "Global.LegacyTags", cfg.Global.LegacyTags, false)
+	eqcase(t, "HTTP.Listen", cfg.HTTP.Listen, "localhost:8075")
+	eqcase(t, "len(HTTP.Tokens)", len(cfg.HTTP.Tokens), 1)
+	eqcase(t, "HTTP.Tokens[0].Role", cfg.HTTP.Tokens[0].Role, "operator")
 	eqcase(t, "TMI.CID", cfg.TMI.CID, `hof5gwx0su6owfnys0nyan9c87zr6t`)
 	eqcase(t, "TMI.RedirectURL", cfg.TMI.RedirectURL, `http://localhost`)
 	eqcase(t, "TMI.TokenFile", cfg.TMI.TokenFile, `/var/robot/tmi_refresh`)
@@ -45,17 +88,40 @@ func TestExampleConfig(t *testing.T) {
 	eqcase(t, "TMI.Owner.Name", cfg.TMI.Owner.Name, `zephyrtronium`)
 	eqcase(t, "TMI.Rate.Every", cfg.TMI.Rate.Every, 30)
 	eqcase(t, "TMI.Rate.Num", cfg.TMI.Rate.Num, 20)
+	eqcase(t, "TMI.Roles", cfg.TMI.Roles, 600)
+	eqcase(t, "TMI.EventSub", cfg.TMI.EventSub, true)
+	eqcase(t, "TMI.Liveness", cfg.TMI.Liveness, 120)
 	eqcase(t, "Twitch[`bocchi`].Channels[0]", cfg.Twitch[`bocchi`].Channels[0], `#bocchi`)
-	eqcase(t, "Twitch[`bocchi`].Learn", cfg.Twitch[`bocchi`].Learn, `bocchi`)
-	eqcase(t, "Twitch[`bocchi`].Send", cfg.Twitch[`bocchi`].Send, `bocchi`)
+	eqcase(t, "Twitch[`bocchi`].Profile", cfg.Twitch[`bocchi`].Profile, `standard`)
+	eqcase(t, "Twitch[`bocchi`].Learn", cfg.Twitch[`bocchi`].Learn, `twitch/bocchi`)
+	eqcase(t, "Twitch[`bocchi`].Send", cfg.Twitch[`bocchi`].Send, `twitch/bocchi`)
+	eqcase(t, "len(Twitch[`bocchi`].Tags)", len(cfg.Twitch[`bocchi`].Tags), 1)
+	eqcase(t, "Twitch[`bocchi`].Tags[0]", cfg.Twitch[`bocchi`].Tags[0], `shared/kessoku`)
+	eqcase(t, "len(Twitch[`bocchi`].TTS.Command)", len(cfg.Twitch[`bocchi`].TTS.Command), 3)
+	eqcase(t, "Twitch[`bocchi`].TTS.Command[0]", cfg.Twitch[`bocchi`].TTS.Command[0], `espeak-ng`)
+	eqcase(t, "Twitch[`bocchi`].TTS.Queue", cfg.Twitch[`bocchi`].TTS.Queue, 3)
 	eqcase(t, "Twitch[`bocchi`].Block", cfg.Twitch[`bocchi`].Block, `(?i)cucumber[^$x]`)
+	eqcase(t, "Twitch[`bocchi`].Forbid.Prompts[0]", cfg.Twitch[`bocchi`].Forbid.Prompts[0], `\bkikuri\b`)
+	eqcase(t, "Twitch[`bocchi`].Forbid.Prompts[1]", cfg.Twitch[`bocchi`].Forbid.Prompts[1], `sake`)
+	eqcase(t, "Twitch[`bocchi`].Forbid.Deflect[0]", cfg.Twitch[`bocchi`].Forbid.Deflect[0], `let's talk about something else, {user}`)
 	eqcase(t, "Twitch[`bocchi`].Responses", cfg.Twitch[`bocchi`].Responses, 0.02)
 	eqcase(t, "Twitch[`bocchi`].Rate.Every", cfg.Twitch[`bocchi`].Rate.Every, 10.1)
 	eqcase(t, "Twitch[`bocchi`].Rate.Num", cfg.Twitch[`bocchi`].Rate.Num, 2)
 	eqcase(t, "Twitch[`bocchi`].Copypasta.Need", cfg.Twitch[`bocchi`].Copypasta.Need, 2)
 	eqcase(t, "Twitch[`bocchi`].Copypasta.Within", cfg.Twitch[`bocchi`].Copypasta.Within, 30)
+	eqcase(t, "Twitch[`bocchi`].Context.Prob", cfg.Twitch[`bocchi`].Context.Prob, 0.5)
+	eqcase(t, "Twitch[`bocchi`].Context.Messages", cfg.Twitch[`bocchi`].Context.Messages, 5)
+	eqcase(t, "Twitch[`bocchi`].Callout.Prob", cfg.Twitch[`bocchi`].Callout.Prob, 0.1)
+	eqcase(t, "Twitch[`bocchi`].Callout.Speakers", cfg.Twitch[`bocchi`].Callout.Speakers, 8)
 	eqcase(t, "Twitch[`bocchi`].Privileges[0].Name", cfg.Twitch[`bocchi`].Privileges[0].Name, `zephyrtronium`)
 	eqcase(t, "Twitch[`bocchi`].Privileges[0].Level", cfg.Twitch[`bocchi`].Privileges[0].Level, `moderator`)
+	eqcase(t, "Twitch[`bocchi`].VIPMod", cfg.Twitch[`bocchi`].VIPMod, true)
+	eqcase(t, "Twitch[`bocchi`].Events[1]", cfg.Twitch[`bocchi`].Events[1], `subscribe`)
+	eqcase(t, "Twitch[`bocchi`].EmoteUsage.Blend", cfg.Twitch[`bocchi`].EmoteUsage.Blend, 0.25)
+	eqcase(t, "Twitch[`bocchi`].EmoteUsage.HalfLife", cfg.Twitch[`bocchi`].EmoteUsage.HalfLife, 604800)
+	eqcase(t, "Twitch[`bocchi`].EmoteUsage.Top", cfg.Twitch[`bocchi`].EmoteUsage.Top, 20)
+	eqcase(t, "Twitch[`bocchi`].EmoteServices.BTTV", cfg.Twitch[`bocchi`].EmoteServices.BTTV, 1)
+	eqcase(t, "Twitch[`bocchi`].EmoteServices.SevenTV", cfg.Twitch[`bocchi`].EmoteServices.SevenTV, 1)
 	eqcase(t, "Twitch[`bocchi`].Emotes[`btw`]", cfg.Twitch[`bocchi`].Emotes[`btw make sure to stretch, hydrate, and take care of yourself <3`], 1)
 	eqcase(t, "Twitch[`bocchi`].Effects[`AAAAA`]", cfg.Twitch[`bocchi`].Effects[`AAAAA`], 44444)
 	substrings := []struct {
@@ -67,6 +133,7 @@ func TestExampleConfig(t *testing.T) {
 		{"DB.SQLBrain", cfg.DB.SQLBrain, "file:"},
 		{"DB.Privacy", cfg.DB.Privacy, "file:"},
 		{"DB.Spoken", cfg.DB.Spoken, "file:"},
+		{"DB.Emotes", cfg.DB.Emotes, "file:"},
 		{"TMI.SecretFile", cfg.TMI.SecretFile, "/twitch_client_secret"},
 	}
 	for _, c := range substrings {
@@ -74,4 +141,128 @@ func TestExampleConfig(t *testing.T) {
 			t.Errorf("wrong %s: %q does not contain %q", c.name, c.val, c.has)
 		}
 	}
+	eqcase(t, "DB.Pool", cfg.DB.Pool, 8)
+	eqcase(t, "DB.BusyTimeout", cfg.DB.BusyTimeout, 5)
+	eqcase(t, "DB.BusyRetries", cfg.DB.BusyRetries, 3)
+	eqcase(t, "DB.Hot", cfg.DB.Hot, main.HotCfg{Window: 259200, Recency: 0.25, Flush: 5, Batch: 500})
+	eqcase(t, "Global.Privileges.Matrix[0].Name", cfg.Global.Privileges.Matrix[0].Name, `@mjolnir:example.org`)
+	eqcase(t, "Matrix.Homeserver", cfg.Matrix.Homeserver, `https://matrix.example.org`)
+	eqcase(t, "Matrix.Owner", cfg.Matrix.Owner, `@zephyrtronium:example.org`)
+	eqcase(t, "Matrix.SkipEncrypted", cfg.Matrix.SkipEncrypted, true)
+	eqcase(t, "len(Matrix.Rooms[`kessoku`].Channels)", len(cfg.Matrix.Rooms[`kessoku`].Channels), 1)
+	eqcase(t, "Matrix.Rooms[`kessoku`].Channels[0]", cfg.Matrix.Rooms[`kessoku`].Channels[0], `!kessoku:example.org`)
+	eqcase(t, "Matrix.Rooms[`kessoku`].Learn", cfg.Matrix.Rooms[`kessoku`].Learn, `shared/kessoku`)
+	eqcase(t, "Matrix.Rooms[`kessoku`].Privileges[0].Level", cfg.Matrix.Rooms[`kessoku`].Privileges[0].Level, `moderator`)
+	eqcase(t, "Telegram.Owner", cfg.Telegram.Owner, `1234567`)
+	eqcase(t, "Telegram.Groups[`kessoku`].Channels[0]", cfg.Telegram.Groups[`kessoku`].Channels[0], `-1001234567890`)
+	eqcase(t, "Telegram.Groups[`kessoku`].Privileges[0].ID", cfg.Telegram.Groups[`kessoku`].Privileges[0].ID, `7654321`)
+	eqcase(t, "Kick.Owner", cfg.Kick.Owner, `4242`)
+	eqcase(t, "Kick.Channels[`bocchi`].Channels[0]", cfg.Kick.Channels[`bocchi`].Channels[0], `kick:bocchi`)
+	eqcase(t, "Kick.Channels[`bocchi`].Privileges[0].ID", cfg.Kick.Channels[`bocchi`].Privileges[0].ID, `1001`)
+	eqcase(t, "Slack.Owner", cfg.Slack.Owner, `U0123456789`)
+	eqcase(t, "Slack.Admins", cfg.Slack.Admins, true)
+	eqcase(t, "Slack.Channels[`kessoku`].Channels[0]", cfg.Slack.Channels[`kessoku`].Channels[0], `C0123456789`)
+	eqcase(t, "Slack.Channels[`kessoku`].Privileges[0].ID", cfg.Slack.Channels[`kessoku`].Privileges[0].ID, `U0987654321`)
+	eqcase(t, "Discord.Owner", cfg.Discord.Owner, `123456789012345678`)
+	eqcase(t, "Discord.ModRoles[0]", cfg.Discord.ModRoles[0], `234567890123456789`)
+	eqcase(t, "Discord.Channels[`kessoku`].Channels[0]", cfg.Discord.Channels[`kessoku`].Channels[0], `discord:345678901234567890`)
+	eqcase(t, "Discord.Channels[`kessoku`].Learn", cfg.Discord.Channels[`kessoku`].Learn, `shared/kessoku`)
+	eqcase(t, "Discord.Channels[`kessoku`].Responses", cfg.Discord.Channels[`kessoku`].Responses, 0.1)
+	eqcase(t, "Discord.Channels[`kessoku`].Rate.Every", cfg.Discord.Channels[`kessoku`].Rate.Every, 10)
+	eqcase(t, "Discord.Channels[`kessoku`].Rate.Num", cfg.Discord.Channels[`kessoku`].Rate.Num, 4)
+	eqcase(t, "Discord.Channels[`kessoku`].Privileges[0].ID", cfg.Discord.Channels[`kessoku`].Privileges[0].ID, `456789012345678901`)
+	eqcase(t, "Logs.Dir", cfg.Logs.Dir, `/var/lib/chatterino/Logs/Twitch/Channels`)
+	eqcase(t, "Logs.Format", cfg.Logs.Format, `chatterino`)
+	eqcase(t, "Logs.Poll", cfg.Logs.Poll, 1)
+	eqcase(t, "Logs.Owner", cfg.Logs.Owner, `51421897`)
+	eqcase(t, "Logs.Channels[`kessoku`].Channels[0]", cfg.Logs.Channels[`kessoku`].Channels[0], `log:kessokuband`)
+	eqcase(t, "Logs.Channels[`kessoku`].Commands.Off", cfg.Logs.Channels[`kessoku`].Commands.Off, true)
+	eqcase(t, "Twitch[`bocchi`].Commands.Prefix", cfg.Twitch[`bocchi`].Commands.Prefix, `!robot`)
+	eqcase(t, "Twitch[`bocchi`].Commands.Off", cfg.Twitch[`bocchi`].Commands.Off, false)
+	eqcase(t, "*Twitch[`bocchi`].Personality.Emote", *cfg.Twitch[`bocchi`].Personality.Emote, 0.8)
+	eqcase(t, "Twitch[`bocchi`].Personality.Effect", cfg.Twitch[`bocchi`].Personality.Effect, nil)
+	eqcase(t, "Twitch[`bocchi`].Personality.Exclaim", cfg.Twitch[`bocchi`].Personality.Exclaim, 0.1)
+	eqcase(t, "Twitch[`bocchi`].Personality.MaxWords", cfg.Twitch[`bocchi`].Personality.MaxWords, 30)
+	eqcase(t, "Twitch[`bocchi`].Personality.MaxEmotes", cfg.Twitch[`bocchi`].Personality.MaxEmotes, 3)
+	eqcase(t, "Twitch[`bocchi`].Personality.MinEntropy", cfg.Twitch[`bocchi`].Personality.MinEntropy, 1.5)
+	eqcase(t, "Twitch[`bocchi`].Personality.Lurk", cfg.Twitch[`bocchi`].Personality.Lurk, 3600)
+	eqcase(t, "Twitch[`bocchi`].Engagement", cfg.Twitch[`bocchi`].Engagement, 300)
+	eqcase(t, "Twitch[`bocchi`].Classify.Thresholds[`toxicity`]", cfg.Twitch[`bocchi`].Classify.Thresholds[`toxicity`], 0.8)
+	eqcase(t, "Twitch[`bocchi`].Classify.Fail", cfg.Twitch[`bocchi`].Classify.Fail, `closed`)
+	eqcase(t, "Twitch[`bocchi`].Suspend.EmoteOnly", cfg.Twitch[`bocchi`].Suspend.EmoteOnly, true)
+	eqcase(t, "Twitch[`bocchi`].Suspend.SubOnly", cfg.Twitch[`bocchi`].Suspend.SubOnly, false)
+	eqcase(t, "Twitch[`bocchi`].Suspend.Slow", cfg.Twitch[`bocchi`].Suspend.Slow, 30)
+	eqcase(t, "Twitch[`bocchi`].Commands.Limits[`speak`].Cooldown", cfg.Twitch[`bocchi`].Commands.Limits[`speak`].Cooldown, 10)
+	eqcase(t, "Twitch[`bocchi`].Commands.Limits[`speak`].UserCooldown", cfg.Twitch[`bocchi`].Commands.Limits[`speak`].UserCooldown, 60)
+	eqcase(t, "Twitch[`bocchi`].Commands.Limits[`marry`].Off", cfg.Twitch[`bocchi`].Commands.Limits[`marry`].Off, true)
+	eqcase(t, "Twitch[`bocchi`].Leaderboard", cfg.Twitch[`bocchi`].Leaderboard, true)
+	eqcase(t, "Twitch[`bocchi`].TimeZone", cfg.Twitch[`bocchi`].TimeZone, "Asia/Tokyo")
+	eqcase(t, "Twitch[`bocchi`].Fallback", cfg.Twitch[`bocchi`].Fallback, `unprompted`)
+	eqcase(t, "Twitch[`bocchi`].Replies[0]", cfg.Twitch[`bocchi`].Replies[0], `@{user} {text}`)
+	eqcase(t, "Twitch[`bocchi`].Replies[1]", cfg.Twitch[`bocchi`].Replies[1], `{text}`)
+	eqcase(t, "Twitch[`bocchi`].Pace.Min", cfg.Twitch[`bocchi`].Pace.Min, 1.5)
+	eqcase(t, "Twitch[`bocchi`].Pace.Max", cfg.Twitch[`bocchi`].Pace.Max, 4)
+	eqcase(t, "Twitch[`bocchi`].Quota.Tuples", cfg.Twitch[`bocchi`].Quota.Tuples, 5000000)
+	eqcase(t, "Twitch[`bocchi`].Quota.Bytes", cfg.Twitch[`bocchi`].Quota.Bytes, 1000000000)
+	eqcase(t, "Twitch[`bocchi`].LearnLimit.Num", cfg.Twitch[`bocchi`].LearnLimit.Num, 10)
+	eqcase(t, "Twitch[`bocchi`].LearnLimit.Within", cfg.Twitch[`bocchi`].LearnLimit.Within, 60)
+	eqcase(t, "Twitch[`bocchi`].Preload", cfg.Twitch[`bocchi`].Preload, main.Preload{Top: 10000})
+	eqcase(t, "Twitch[`bocchi`].Profanity.Learn", cfg.Twitch[`bocchi`].Profanity.Learn, `strong`)
+	eqcase(t, "Twitch[`bocchi`].Profanity.Send", cfg.Twitch[`bocchi`].Profanity.Send, `mild`)
+	eqcase(t, "Bridge[`kessoku`].Channels[0]", cfg.Bridge[`kessoku`].Channels[0], `#bocchi`)
+	eqcase(t, "Bridge[`kessoku`].Channels[1]", cfg.Bridge[`kessoku`].Channels[1], `!kessoku:example.org`)
+	eqcase(t, "Bridge[`kessoku`].Attribution", cfg.Bridge[`kessoku`].Attribution, `<{{.Name}}> {{.Text}}`)
+	eqcase(t, "View[`view/everyone`].Tags[1]", cfg.View[`view/everyone`].Tags[1], `kick/bocchi`)
+	eqcase(t, "View[`view/everyone`].Block", cfg.View[`view/everyone`].Block, `(?i)^guitar\s*$`)
+	eqcase(t, "Poster[`bocchi`].Tag", cfg.Poster[`bocchi`].Tag, `twitch/bocchi`)
+	eqcase(t, "Poster[`bocchi`].Every", cfg.Poster[`bocchi`].Every, 14400)
+	eqcase(t, "Poster[`bocchi`].Review", cfg.Poster[`bocchi`].Review, true)
+	eqcase(t, "Poster[`bocchi`].Expire", cfg.Poster[`bocchi`].Expire, 86400)
+	eqcase(t, "Poster[`bocchi`].Mastodon.Server", cfg.Poster[`bocchi`].Mastodon.Server, `https://mastodon.example.org`)
+	eqcase(t, "Poster[`bocchi`].Bluesky.Handle", cfg.Poster[`bocchi`].Bluesky.Handle, `bocchi.bsky.social`)
+}
+
+func TestLoadStrict(t *testing.T) {
+	const src = `secret = 'key'
+
+[db]
+sqlbrain = 'file:robot.db'
+pool = 8
+bogus = 1
+
+[twitch.bocchi]
+channels = ['#bocchi']
+rate = { every = 10, nun = 2 }
+
+[nonsense]
+x = 1
+`
+	cases := []struct {
+		name string
+		want string
+	}{
+		{"db", "line 6: unknown key db.bogus"},
+		{"inline", "line 10: unknown key twitch.bocchi.rate.nun"},
+		{"table", "line 12: unknown key nonsense"},
+	}
+	_, _, err := main.Load(context.Background(), strings.NewReader(src), true)
+	if err == nil {
+		t.Fatal("strict load with unknown keys succeeded")
+	}
+	for _, c := range cases {
+		if !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: error doesn't contain %q:\n%v", c.name, c.want, err)
+		}
+	}
+	if _, _, err := main.Load(context.Background(), strings.NewReader(src), false); err != nil {
+		t.Errorf("lax load failed: %v", err)
+	}
+}
+
+func TestLoadTypeMismatch(t *testing.T) {
+	const src = "[db]\npool = 'eight'\n"
+	_, _, err := main.Load(context.Background(), strings.NewReader(src), false)
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("wrong error for type mismatch: %v", err)
+	}
 }
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+)
+
+// SetConfig records the config with which the robot is running and the file
+// from which it was loaded, so that changes to the file can be reviewed.
+// The file is empty if the config came from the environment.
+func (robo *Robot) SetConfig(file string, cfg *Config) {
+	robo.configFile = file
+	robo.config = cfg
+}
+
+// configChange is a difference between two configs.
+type configChange struct {
+	// Kind is the kind of change: added or removed for channels, or regex,
+	// rate, tag, profile, profanity, or effects for options of channels in
+	// both configs.
+	Kind string `json:"kind"`
+	// Channel is the channel changed, or empty for global options.
+	Channel string `json:"channel,omitempty"`
+	// Key is the TOML key of the option or channel group changed.
+	Key string `json:"key"`
+	// Old and New are the option's values before and after the change.
+	Old string `json:"old,omitempty"`
+	New string `json:"new,omitempty"`
+}
+
+// configChannel is a channel in a config with the key of its group.
+type configChannel struct {
+	key string
+	cfg *ChannelCfg
+}
+
+// configChannels maps each channel in a config to its configuration.
+func configChannels(cfg *Config) map[string]configChannel {
+	r := make(map[string]configChannel)
+	for _, g := range channelGroups(cfg) {
+		for nm, ch := range g.channels {
+			for _, p := range ch.Channels {
+				r[p] = configChannel{key: g.key + "." + nm, cfg: ch}
+			}
+		}
+	}
+	return r
+}
+
+// diffConfig lists the changes which applying cur would make to a bot
+// running with old: channels added and removed, and changes to the
+// expressions, rates, tags, profiles, profanity limits, and effects of
+// channels in both. Both configs have their profiles applied, so options a
+// channel inherits from a profile are compared like any others.
+func diffConfig(old, cur *Config) []configChange {
+	var r []configChange
+	if old.Global.Block != cur.Global.Block {
+		r = append(r, configChange{Kind: "regex", Key: "global.block", Old: old.Global.Block, New: cur.Global.Block})
+	}
+	was, now := configChannels(old), configChannels(cur)
+	for _, p := range slices.Sorted(maps.Keys(was)) {
+		if _, ok := now[p]; !ok {
+			r = append(r, configChange{Kind: "removed", Channel: p, Key: was[p].key})
+		}
+	}
+	for _, p := range slices.Sorted(maps.Keys(now)) {
+		n := now[p]
+		w, ok := was[p]
+		if !ok {
+			r = append(r, configChange{Kind: "added", Channel: p, Key: n.key})
+			continue
+		}
+		a, b := w.cfg, n.cfg
+		opt := func(kind, key string, x, y any) {
+			xs, ys := fmt.Sprint(x), fmt.Sprint(y)
+			if xs != ys {
+				r = append(r, configChange{Kind: kind, Channel: p, Key: n.key + "." + key, Old: xs, New: ys})
+			}
+		}
+		opt("tag", "learn", a.Learn, b.Learn)
+		opt("tag", "send", a.Send, b.Send)
+		opt("tag", "tags", a.Tags, b.Tags)
+		opt("profile", "profile", a.Profile, b.Profile)
+		opt("regex", "block", a.Block, b.Block)
+		opt("regex", "forbid.prompts", a.Forbid.Prompts, b.Forbid.Prompts)
+		opt("rate", "responses", a.Responses, b.Responses)
+		opt("rate", "rate.every", a.Rate.Every, b.Rate.Every)
+		opt("rate", "rate.num", a.Rate.Num, b.Rate.Num)
+		opt("rate", "copypasta.need", a.Copypasta.Need, b.Copypasta.Need)
+		opt("rate", "copypasta.within", a.Copypasta.Within, b.Copypasta.Within)
+		opt("profanity", "profanity.learn", a.Profanity.Learn, b.Profanity.Learn)
+		opt("profanity", "profanity.send", a.Profanity.Send, b.Profanity.Send)
+		opt("effects", "effects", a.Effects, b.Effects)
+	}
+	return r
+}
+
+// configDiffHTTP reloads the config file and reports how it differs from the
+// config with which the bot is running, without applying anything.
+// The bot doesn't reload its config while running; the reported changes take
+// effect when it restarts.
+func (robo *Robot) configDiffHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if robo.configFile == "" {
+		http.Error(w, "config is not from a file", http.StatusNotFound)
+		return
+	}
+	cur, _, err := loadConfigFile(ctx, robo.configFile, false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	d := diffConfig(robo.config, cur)
+	for _, c := range d {
+		slog.InfoContext(ctx, "config change",
+			slog.String("kind", c.Kind),
+			slog.String("channel", c.Channel),
+			slog.String("key", c.Key),
+			slog.String("old", c.Old),
+			slog.String("new", c.New),
+		)
+	}
+	if d == nil {
+		d = []configChange{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDiffConfig(t *testing.T) {
+	load := func(src string) *Config {
+		t.Helper()
+		cfg, _, err := Load(context.Background(), strings.NewReader(src), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+	old := load(`
+[twitch.bocchi]
+channels = ['#bocchi', '#ryo']
+learn = 'twitch/bocchi'
+block = 'cucumber'
+rate = { every = 10, num = 2 }
+
+[kick.channels.kita]
+channels = ['kick:kita']
+`)
+	cur := load(`
+[global]
+block = 'sake'
+
+[twitch.bocchi]
+channels = ['#bocchi', '#nijika']
+learn = 'shared/kessoku'
+block = 'cucumber|kikuri'
+rate = { every = 10, num = 3 }
+
+[kick.channels.kita]
+channels = ['kick:kita']
+`)
+	want := []configChange{
+		{Kind: "regex", Key: "global.block", New: "sake"},
+		{Kind: "removed", Channel: "#ryo", Key: "twitch.bocchi"},
+		{Kind: "tag", Channel: "#bocchi", Key: "twitch.bocchi.learn", Old: "twitch/bocchi", New: "shared/kessoku"},
+		{Kind: "regex", Channel: "#bocchi", Key: "twitch.bocchi.block", Old: "cucumber", New: "cucumber|kikuri"},
+		{Kind: "rate", Channel: "#bocchi", Key: "twitch.bocchi.rate.num", Old: "2", New: "3"},
+		{Kind: "added", Channel: "#nijika", Key: "twitch.bocchi"},
+	}
+	got := diffConfig(old, cur)
+	if !slices.Equal(got, want) {
+		t.Errorf("wrong diff:\nwant %+v\ngot  %+v", want, got)
+	}
+	if d := diffConfig(cur, cur); len(d) != 0 {
+		t.Errorf("diff of a config with itself: %+v", d)
+	}
+}
+
+func TestDiffConfigProfile(t *testing.T) {
+	load := func(src string) *Config {
+		t.Helper()
+		cfg, _, err := Load(context.Background(), strings.NewReader(src), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+	old := load(`
+[twitch.bocchi]
+channels = ['#bocchi']
+profile = 'standard'
+`)
+	cur := load(`
+[twitch.bocchi]
+channels = ['#bocchi']
+profile = 'chaotic'
+rate = { num = 2 }
+`)
+	want := []configChange{
+		{Kind: "profile", Channel: "#bocchi", Key: "twitch.bocchi.profile", Old: "standard", New: "chaotic"},
+		{Kind: "rate", Channel: "#bocchi", Key: "twitch.bocchi.responses", Old: "0.02", New: "0.1"},
+		{Kind: "rate", Channel: "#bocchi", Key: "twitch.bocchi.rate.every", Old: "10", New: "5"},
+		{Kind: "rate", Channel: "#bocchi", Key: "twitch.bocchi.copypasta.within", Old: "30", New: "60"},
+		{Kind: "profanity", Channel: "#bocchi", Key: "twitch.bocchi.profanity.send", Old: "mild", New: "strong"},
+		{Kind: "effects", Channel: "#bocchi", Key: "twitch.bocchi.effects", Old: "map[:18 OwO:1 o:1]", New: "map[:6 AAAAA:1 OwO:2 o:2]"},
+	}
+	got := diffConfig(old, cur)
+	if !slices.Equal(got, want) {
+		t.Errorf("wrong diff:\nwant %+v\ngot  %+v", want, got)
+	}
+}
@@ -0,0 +1,150 @@
+// Package credit implements a leaderboard of users whose messages the bot
+// learns. Only users who opt in are counted or listed, or called out in
+// random messages.
+package credit
+
+import (
+	"context"
+	"fmt"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Board is a leaderboard backed by an SQL database.
+type Board struct {
+	db *sqlitex.Pool
+}
+
+// Entry is a user's place on a leaderboard.
+type Entry struct {
+	// Name is the user's display name as of their latest counted message.
+	Name string
+	// Count is the number of the user's messages learned.
+	Count int64
+}
+
+const schemaSQL = `
+CREATE TABLE IF NOT EXISTS credit_optin (user TEXT PRIMARY KEY) STRICT, WITHOUT ROWID;
+CREATE TABLE IF NOT EXISTS credit (
+	channel TEXT NOT NULL,
+	user TEXT NOT NULL,
+	name TEXT NOT NULL,
+	count INTEGER NOT NULL,
+	PRIMARY KEY (channel, user)
+) STRICT, WITHOUT ROWID;
+CREATE INDEX IF NOT EXISTS credit_rank ON credit (channel, count DESC);
+`
+
+// Open opens a leaderboard in an SQL database, creating its tables if needed.
+func Open(ctx context.Context, db *sqlitex.Pool) (*Board, error) {
+	conn, err := db.Take(ctx)
+	defer db.Put(conn)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get connection from pool: %w", err)
+	}
+	if err := sqlitex.ExecuteScript(conn, schemaSQL, nil); err != nil {
+		return nil, fmt.Errorf("couldn't run migration: %w", err)
+	}
+	return &Board{db: db}, nil
+}
+
+// OptIn starts counting a user's messages.
+func (b *Board) OptIn(ctx context.Context, user string) error {
+	conn, err := b.db.Take(ctx)
+	defer b.db.Put(conn)
+	if err != nil {
+		return fmt.Errorf("couldn't get connection to opt in user: %w", err)
+	}
+	opts := sqlitex.ExecOptions{Args: []any{user}}
+	return sqlitex.Execute(conn, `INSERT INTO credit_optin (user) VALUES (?) ON CONFLICT DO NOTHING`, &opts)
+}
+
+// OptOut stops counting a user's messages and removes their counts.
+func (b *Board) OptOut(ctx context.Context, user string) (err error) {
+	conn, err := b.db.Take(ctx)
+	defer b.db.Put(conn)
+	if err != nil {
+		return fmt.Errorf("couldn't get connection to opt out user: %w", err)
+	}
+	defer sqlitex.Transaction(conn)(&err)
+	opts := sqlitex.ExecOptions{Args: []any{user}}
+	if err := sqlitex.Execute(conn, `DELETE FROM credit_optin WHERE user=?`, &opts); err != nil {
+		return fmt.Errorf("couldn't remove opt-in: %w", err)
+	}
+	if err := sqlitex.Execute(conn, `DELETE FROM credit WHERE user=?`, &opts); err != nil {
+		return fmt.Errorf("couldn't remove counts: %w", err)
+	}
+	return nil
+}
+
+// OptedIn reports whether a user has opted in.
+func (b *Board) OptedIn(ctx context.Context, user string) (bool, error) {
+	conn, err := b.db.Take(ctx)
+	defer b.db.Put(conn)
+	if err != nil {
+		return false, fmt.Errorf("couldn't get connection to check opt-in: %w", err)
+	}
+	var ok bool
+	opts := sqlitex.ExecOptions{
+		Args: []any{user},
+		ResultFunc: func(st *sqlite.Stmt) error {
+			ok = true
+			return nil
+		},
+	}
+	if err := sqlitex.Execute(conn, `SELECT 1 FROM credit_optin WHERE user=?`, &opts); err != nil {
+		return false, fmt.Errorf("couldn't check opt-in: %w", err)
+	}
+	return ok, nil
+}
+
+// Add counts a message from a user in a channel if the user has opted in.
+// name is the user's current display name.
+func (b *Board) Add(ctx context.Context, channel, user, name string) error {
+	conn, err := b.db.Take(ctx)
+	defer b.db.Put(conn)
+	if err != nil {
+		return fmt.Errorf("couldn't get connection to count message: %w", err)
+	}
+	const add = `INSERT INTO credit (channel, user, name, count)
+		SELECT :channel, :user, :name, 1 WHERE :user IN (SELECT user FROM credit_optin)
+		ON CONFLICT DO UPDATE SET name=excluded.name, count=count+1`
+	st, err := conn.Prepare(add)
+	if err != nil {
+		return fmt.Errorf("couldn't prepare statement to count message: %w", err)
+	}
+	st.SetText(":channel", channel)
+	st.SetText(":user", user)
+	st.SetText(":name", name)
+	if _, err := st.Step(); err != nil {
+		return fmt.Errorf("couldn't count message: %w", err)
+	}
+	return nil
+}
+
+// Top lists the n users with the most messages counted in a channel.
+func (b *Board) Top(ctx context.Context, channel string, n int) ([]Entry, error) {
+	conn, err := b.db.Take(ctx)
+	defer b.db.Put(conn)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get connection to list leaderboard: %w", err)
+	}
+	// Join on opt-ins so that no one who has opted out can appear even if
+	// their counts somehow remain.
+	const sel = `SELECT name, count FROM credit
+		WHERE channel=:channel AND user IN (SELECT user FROM credit_optin)
+		ORDER BY count DESC, name LIMIT :n`
+	var r []Entry
+	opts := sqlitex.ExecOptions{
+		Named: map[string]any{":channel": channel, ":n": n},
+		ResultFunc: func(st *sqlite.Stmt) error {
+			r = append(r, Entry{Name: st.ColumnText(0), Count: st.ColumnInt64(1)})
+			return nil
+		},
+	}
+	if err := sqlitex.Execute(conn, sel, &opts); err != nil {
+		return nil, fmt.Errorf("couldn't list leaderboard: %w", err)
+	}
+	return r, nil
+}
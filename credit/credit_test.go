@@ -0,0 +1,108 @@
+package credit_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/zephyrtronium/robot/credit"
+)
+
+var dbcount atomic.Uint64
+
+func testDB() *sqlitex.Pool {
+	k := dbcount.Add(1)
+	pool, err := sqlitex.NewPool(fmt.Sprintf("file:%d.db?mode=memory&cache=shared", k), sqlitex.PoolOptions{Flags: sqlite.OpenReadWrite | sqlite.OpenCreate | sqlite.OpenMemory | sqlite.OpenSharedCache | sqlite.OpenURI})
+	if err != nil {
+		panic(err)
+	}
+	return pool
+}
+
+func TestBoard(t *testing.T) {
+	ctx := context.Background()
+	b, err := credit.Open(ctx, testDB())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []string{"1", "2", "3"} {
+		if err := b.OptIn(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Opting in twice is fine.
+	if err := b.OptIn(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+	msgs := []struct {
+		channel, user, name string
+	}{
+		{"#bocchi", "1", "bocchi"},
+		{"#bocchi", "1", "Bocchi"},
+		{"#bocchi", "2", "kita"},
+		{"#bocchi", "4", "ryo"},
+		{"#bocchi", "4", "ryo"},
+		{"#bocchi", "4", "ryo"},
+		{"#bocchi", "3", "nijika"},
+		{"#bocchi", "3", "nijika"},
+		{"#bocchi", "3", "nijika"},
+		{"#kessoku", "2", "kita"},
+	}
+	for _, m := range msgs {
+		if err := b.Add(ctx, m.channel, m.user, m.name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []credit.Entry{{Name: "nijika", Count: 3}, {Name: "Bocchi", Count: 2}, {Name: "kita", Count: 1}}
+	got, err := b.Top(ctx, "#bocchi", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("wrong leaderboard: want %v, got %v", want, got)
+	}
+	got, err = b.Top(ctx, "#bocchi", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, want[:1]) {
+		t.Errorf("wrong limited leaderboard: want %v, got %v", want[:1], got)
+	}
+	if err := b.OptOut(ctx, "3"); err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []struct {
+		user string
+		want bool
+	}{{"1", true}, {"3", false}, {"4", false}} {
+		ok, err := b.OptedIn(ctx, u.user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != u.want {
+			t.Errorf("wrong opt-in for %s: want %t, got %t", u.user, u.want, ok)
+		}
+	}
+	if err := b.Add(ctx, "#bocchi", "3", "nijika"); err != nil {
+		t.Fatal(err)
+	}
+	got, err = b.Top(ctx, "#bocchi", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, want[1:]) {
+		t.Errorf("wrong leaderboard after opt-out: want %v, got %v", want[1:], got)
+	}
+	got, err = b.Top(ctx, "#kessoku", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []credit.Entry{{Name: "kita", Count: 1}}; !slices.Equal(got, want) {
+		t.Errorf("wrong leaderboard in other channel: want %v, got %v", want, got)
+	}
+}
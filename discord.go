@@ -0,0 +1,347 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/zephyrtronium/robot/discord"
+	"github.com/zephyrtronium/robot/message"
+	"github.com/zephyrtronium/robot/platform"
+)
+
+// discordClient is the bot's gateway connection to Discord.
+type discordClient struct {
+	cl *discord.Client
+	// id is the bot's user ID.
+	id string
+	// name is the bot's username, used to recognize commands.
+	name string
+	// owner is the user ID of the owner.
+	owner string
+	// modRoles is the role IDs which give moderator privileges.
+	modRoles []string
+	// app is the ID of the bot's application, which owns its slash commands.
+	app string
+	// mu guards pending.
+	mu sync.Mutex
+	// pending is the slash command interactions which may still be answered,
+	// by interaction ID.
+	pending map[string]*discordInteraction
+}
+
+// discordInteraction is a deferred slash command interaction.
+type discordInteraction struct {
+	// token is the interaction token used to answer it.
+	token string
+	// channel is the channel ID where the command was used.
+	channel string
+	// at is when the interaction arrived.
+	at time.Time
+	// answered is whether the bot has answered the interaction.
+	answered bool
+}
+
+// discordModPerms is the default permissions for moderator slash commands.
+var discordModPerms = discord.PermManageMessages
+
+// discordCommands are the slash commands the bot registers. Each stands for a
+// chat command, so slash commands have the same privileges, limits, and
+// cooldowns as the chat commands do. Moderator commands additionally need the
+// manage messages permission by default, which server admins can change.
+var discordCommands = []discord.Command{
+	{
+		Name:        "speak",
+		Description: "Have me say something.",
+		Options: []discord.CommandOption{
+			{Type: discord.OptionString, Name: "prompt", Description: "What my message starts with."},
+		},
+		Contexts: []int{0},
+	},
+	{
+		Name:        "forgetme",
+		Description: "Stop me from learning from your messages.",
+		Contexts:    []int{0},
+	},
+	{
+		Name:        "robot",
+		Description: "Manage me in this channel.",
+		Options: []discord.CommandOption{
+			{Type: discord.OptionSubcommand, Name: "stats", Description: "Show how much I've learned and spoken here today."},
+		},
+		Permissions: &discordModPerms,
+		Contexts:    []int{0},
+	},
+}
+
+// discordInvocation returns the chat command text which a slash command
+// interaction stands for, or the empty string if it isn't one of ours.
+func discordInvocation(in *discord.Interaction) string {
+	d := &in.Data
+	switch d.Name {
+	case "speak":
+		return strings.TrimSpace("speak " + discord.String(d.Options, "prompt"))
+	case "forgetme":
+		return "ignore me"
+	case "robot":
+		if len(d.Options) == 1 && d.Options[0].Name == "stats" {
+			return "stats"
+		}
+	}
+	return ""
+}
+
+var _ platform.Client = (*discordClient)(nil)
+
+func (dc *discordClient) Platform() string { return "discord" }
+
+func (dc *discordClient) Bot() (id, name string) { return dc.id, dc.name }
+
+func (dc *discordClient) Owner() string { return dc.owner }
+
+func (dc *discordClient) Capabilities() platform.Capabilities {
+	return platform.Capabilities{Replies: true, Format: platform.Format{Newlines: true, MaxLength: 2000}}
+}
+
+// Send sends a message to a channel. Replies to slash commands answer the
+// interaction instead, as do messages which reply to nothing while a slash
+// command in the channel is unanswered, since generated messages don't reply
+// to the commands that ask for them.
+func (dc *discordClient) Send(ctx context.Context, channel, reply, text string) error {
+	id, ok := discordChannel(channel)
+	if !ok {
+		return fmt.Errorf("no Discord channel %s", channel)
+	}
+	if tok := dc.answer(id, reply); tok != "" {
+		return dc.cl.Followup(ctx, dc.app, tok, text)
+	}
+	return dc.cl.CreateMessage(ctx, id, reply, text)
+}
+
+// answer marks a pending interaction as answered and returns its token.
+// If reply is empty, the interaction is the oldest unanswered one in the
+// channel. If there is no such interaction, the result is the empty string.
+func (dc *discordClient) answer(channel, reply string) string {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	in := dc.pending[reply]
+	if reply == "" {
+		for _, p := range dc.pending {
+			if p.channel == channel && !p.answered && (in == nil || p.at.Before(in.at)) {
+				in = p
+			}
+		}
+	}
+	if in == nil {
+		return ""
+	}
+	in.answered = true
+	return in.token
+}
+
+// discordChannel returns the Discord channel ID of a channel name like
+// discord:1234567890.
+func discordChannel(name string) (string, bool) {
+	return strings.CutPrefix(name, "discord:")
+}
+
+// InitDiscord checks the Discord bot token and registers the bot's slash
+// commands.
+func (robo *Robot) InitDiscord(ctx context.Context, cfg DiscordCfg) error {
+	cl := &discord.Client{Token: cfg.Token}
+	me, err := cl.Me(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't get Discord bot: %w", err)
+	}
+	slog.InfoContext(ctx, "Discord bot", slog.String("id", me.ID), slog.String("user", me.Username))
+	app, err := cl.Application(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't get Discord application: %w", err)
+	}
+	if err := cl.SetCommands(ctx, app, discordCommands); err != nil {
+		return err
+	}
+	robo.discord = &discordClient{
+		cl:       cl,
+		id:       me.ID,
+		name:     me.Username,
+		owner:    cfg.Owner,
+		modRoles: cfg.ModRoles,
+		app:      app,
+		pending:  make(map[string]*discordInteraction),
+	}
+	return nil
+}
+
+// SetDiscordChannels initializes Discord channel configuration.
+// It must be called after InitDiscord.
+func (robo *Robot) SetDiscordChannels(ctx context.Context, global Global, channels map[string]*ChannelCfg) error {
+	for nm, cfg := range channels {
+		for _, p := range cfg.Channels {
+			if id, ok := discordChannel(p); !ok || id == "" {
+				return fmt.Errorf("bad channel %q in discord.channels.%s: must be like discord:1234567890", p, nm)
+			}
+		}
+	}
+	return robo.setChannels(ctx, global, robo.discord, global.Privileges.Discord, channels)
+}
+
+func (dc *discordClient) Run(ctx context.Context, h platform.Handler) error {
+	group, ctx := errgroup.WithContext(ctx)
+	events := make(chan *discord.Event)
+	group.Go(func() error {
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case ev := <-events:
+				dc.event(ctx, h, ev)
+			}
+		}
+	})
+	group.Go(func() error {
+		t := time.NewTicker(time.Minute)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case now := <-t.C:
+				dc.sweep(ctx, now)
+			}
+		}
+	})
+	group.Go(func() error {
+		for {
+			err := dc.cl.Listen(ctx, events)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, discord.ErrInvalidSession) {
+				// Reconnecting won't help until the config changes.
+				return err
+			}
+			if err == nil {
+				// Discord asked us to reconnect.
+				slog.InfoContext(ctx, "Discord reconnecting")
+				continue
+			}
+			slog.ErrorContext(ctx, "Discord gateway failed", slog.Any("err", err))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+			}
+		}
+	})
+	return group.Wait()
+}
+
+// event processes a message or command event from Discord.
+func (dc *discordClient) event(ctx context.Context, h platform.Handler, ev *discord.Event) {
+	m := &ev.Message
+	to := "discord:" + m.ChannelID
+	switch ev.Type {
+	case "MESSAGE_CREATE":
+		if m.Author.Bot || m.WebhookID != "" || m.Author.ID == dc.id {
+			return
+		}
+		text := discord.Plain(m.Content, m.Mentions)
+		name, mod := m.Author.DisplayName(), false
+		if mem := m.Member; mem != nil {
+			name, mod = dc.member(name, mem.Nick, mem.Roles)
+		}
+		msg := message.Incoming{
+			ID:          m.ID,
+			To:          to,
+			Sender:      m.Author.ID,
+			Name:        name,
+			Text:        text,
+			Timestamp:   m.Timestamp.UnixMilli(),
+			IsModerator: mod,
+			Raw:         m,
+		}
+		if r := m.MessageReference; r != nil {
+			msg.ReplyParent = r.MessageID
+		}
+		h.Message(ctx, dc, &msg)
+	case "MESSAGE_DELETE":
+		h.Delete(ctx, dc, to, m.ID, "", false)
+	case "INTERACTION_CREATE":
+		dc.interaction(ctx, h, ev.Interaction)
+	}
+}
+
+// member returns the name and moderator status of a guild member.
+func (dc *discordClient) member(name, nick string, roles []string) (string, bool) {
+	if nick != "" {
+		name = nick
+	}
+	return name, slices.ContainsFunc(roles, func(r string) bool { return slices.Contains(dc.modRoles, r) })
+}
+
+// interaction handles a slash command as the chat command it stands for,
+// addressed to the bot. The interaction is deferred first, since commands can
+// take longer to answer than Discord waits, and the command's reply answers
+// it through Send.
+func (dc *discordClient) interaction(ctx context.Context, h platform.Handler, in *discord.Interaction) {
+	text := discordInvocation(in)
+	u := in.Invoker()
+	if text == "" || u == nil {
+		slog.WarnContext(ctx, "unknown Discord command", slog.String("name", in.Data.Name))
+		return
+	}
+	if err := dc.cl.Defer(ctx, in); err != nil {
+		slog.ErrorContext(ctx, "couldn't defer Discord command", slog.Any("err", err), slog.String("name", in.Data.Name))
+		return
+	}
+	dc.mu.Lock()
+	dc.pending[in.ID] = &discordInteraction{token: in.Token, channel: in.ChannelID, at: time.Now()}
+	dc.mu.Unlock()
+	name, mod := u.DisplayName(), false
+	if mem := in.Member; mem != nil {
+		name, mod = dc.member(name, mem.Nick, mem.Roles)
+	}
+	msg := message.Incoming{
+		ID:          in.ID,
+		To:          "discord:" + in.ChannelID,
+		Sender:      u.ID,
+		Name:        name,
+		Text:        "@" + dc.name + " " + text,
+		Timestamp:   time.Now().UnixMilli(),
+		IsModerator: mod,
+		Raw:         in,
+	}
+	h.Message(ctx, dc, &msg)
+}
+
+// sweep clears the loading state of slash commands which got no answer
+// within a minute, e.g. because the user lacks the privilege or the command
+// is cooling down, and forgets interactions whose tokens have expired.
+func (dc *discordClient) sweep(ctx context.Context, now time.Time) {
+	var stale []string
+	dc.mu.Lock()
+	for id, in := range dc.pending {
+		age := now.Sub(in.at)
+		if !in.answered && age >= time.Minute {
+			stale = append(stale, in.token)
+			in.answered = true
+		}
+		if age >= 15*time.Minute {
+			delete(dc.pending, id)
+		}
+	}
+	dc.mu.Unlock()
+	for _, tok := range stale {
+		if err := dc.cl.DeleteResponse(ctx, dc.app, tok); err != nil {
+			slog.WarnContext(ctx, "couldn't clear unanswered Discord command", slog.Any("err", err))
+		}
+	}
+}
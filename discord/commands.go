@@ -0,0 +1,146 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Command is an application command, i.e. a slash command.
+type Command struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Options     []CommandOption `json:"options,omitempty"`
+	// Permissions is the permission bit set which members need to use the
+	// command by default, as a decimal string. If it is nil, everyone can.
+	Permissions *string `json:"default_member_permissions"`
+	// Contexts is where the command can be used. Robot only uses commands in
+	// guilds, context 0.
+	Contexts []int `json:"contexts,omitempty"`
+}
+
+// Command option types.
+const (
+	OptionSubcommand = 1
+	OptionString     = 3
+)
+
+// CommandOption is a parameter or subcommand of a command.
+type CommandOption struct {
+	Type        int             `json:"type"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Required    bool            `json:"required,omitempty"`
+	Options     []CommandOption `json:"options,omitempty"`
+}
+
+// PermManageMessages is the permission to manage messages, which Robot
+// requires for moderator commands.
+const PermManageMessages = "8192"
+
+// Application returns the ID of the bot's application.
+func (c *Client) Application(ctx context.Context) (string, error) {
+	var r struct {
+		ID string `json:"id"`
+	}
+	if err := c.call(ctx, "GET", "applications/@me", nil, &r); err != nil {
+		return "", err
+	}
+	return r.ID, nil
+}
+
+// SetCommands replaces the application's global commands.
+func (c *Client) SetCommands(ctx context.Context, app string, cmds []Command) error {
+	if err := c.call(ctx, "PUT", "applications/"+app+"/commands", cmds, nil); err != nil {
+		return fmt.Errorf("couldn't register commands: %w", err)
+	}
+	return nil
+}
+
+// Interaction is an interaction with one of the application's commands.
+type Interaction struct {
+	ID        string `json:"id"`
+	Type      int    `json:"type"`
+	Token     string `json:"token"`
+	ChannelID string `json:"channel_id"`
+	GuildID   string `json:"guild_id"`
+	// Member is the invoking member, present for interactions in guilds.
+	Member *Member `json:"member"`
+	// User is the invoking user, present for interactions outside guilds.
+	User *User `json:"user"`
+	Data struct {
+		Name    string              `json:"name"`
+		Options []InteractionOption `json:"options"`
+	} `json:"data"`
+}
+
+// Member is a user's membership in a guild.
+type Member struct {
+	User  User     `json:"user"`
+	Nick  string   `json:"nick"`
+	Roles []string `json:"roles"`
+}
+
+// InteractionCommand is the interaction type for using a command.
+const InteractionCommand = 2
+
+// InteractionOption is an option given to a command.
+type InteractionOption struct {
+	Name    string              `json:"name"`
+	Type    int                 `json:"type"`
+	Value   json.RawMessage     `json:"value"`
+	Options []InteractionOption `json:"options"`
+}
+
+// Invoker returns the user who used the command.
+func (in *Interaction) Invoker() *User {
+	if in.Member != nil {
+		return &in.Member.User
+	}
+	return in.User
+}
+
+// String returns the value of a string option, or the empty string if it is
+// absent or not a string.
+func String(opts []InteractionOption, name string) string {
+	for _, o := range opts {
+		if o.Name == name {
+			var s string
+			json.Unmarshal(o.Value, &s)
+			return s
+		}
+	}
+	return ""
+}
+
+// Defer acknowledges an interaction with a loading state, to be followed up
+// with [Client.Followup] within fifteen minutes.
+func (c *Client) Defer(ctx context.Context, in *Interaction) error {
+	body := map[string]any{"type": 5}
+	if err := c.call(ctx, "POST", "interactions/"+in.ID+"/"+in.Token+"/callback", body, nil); err != nil {
+		return fmt.Errorf("couldn't acknowledge interaction: %w", err)
+	}
+	return nil
+}
+
+// Followup sends a message responding to a deferred interaction. Mentions in
+// the text never notify anyone.
+func (c *Client) Followup(ctx context.Context, app, token, text string) error {
+	body := map[string]any{
+		"content":          text,
+		"allowed_mentions": map[string]any{"parse": []string{}},
+	}
+	if err := c.call(ctx, "POST", "webhooks/"+app+"/"+token, body, nil); err != nil {
+		return fmt.Errorf("couldn't follow up interaction: %w", err)
+	}
+	return nil
+}
+
+// DeleteResponse deletes the original response to an interaction, e.g. the
+// loading state of a deferred interaction which got no followup.
+func (c *Client) DeleteResponse(ctx context.Context, app, token string) error {
+	if err := c.call(ctx, "DELETE", "webhooks/"+app+"/"+token+"/messages/@original", nil, nil); err != nil {
+		return fmt.Errorf("couldn't delete interaction response: %w", err)
+	}
+	return nil
+}
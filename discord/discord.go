@@ -0,0 +1,178 @@
+// Package discord implements the parts of the Discord HTTP API and gateway
+// that Robot uses.
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// Client holds the context for requests to Discord.
+type Client struct {
+	// HTTP is the HTTP client for performing requests.
+	// If nil, http.DefaultClient is used.
+	HTTP *http.Client
+	// Token is the bot token.
+	Token string
+	// API is the base URL of the HTTP API. If it is empty,
+	// https://discord.com/api/v10 is used.
+	API string
+}
+
+// call performs an API request with an optional JSON body and decodes the
+// response into r. The response body is truncated to 2 MB.
+func (c *Client) call(ctx context.Context, method, path string, body, r any) error {
+	base := c.API
+	if base == "" {
+		base = "https://discord.com/api/v10"
+	}
+	u, err := url.JoinPath(base, path)
+	if err != nil {
+		panic("discord: bad url join with " + path)
+	}
+	var rd io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("couldn't encode request: %w", err)
+		}
+		rd = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, rd)
+	if err != nil {
+		return fmt.Errorf("couldn't make request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bot "+c.Token)
+	req.Header.Set("User-Agent", "DiscordBot (https://github.com/zephyrtronium/robot, 1)")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	hc := c.HTTP
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("couldn't %s %s: %w", method, path, err)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 2<<20))
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("couldn't read response: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(b, &e)
+		return fmt.Errorf("%s %s failed: %s: %s", method, path, resp.Status, e.Message)
+	}
+	if r == nil {
+		return nil
+	}
+	if err := json.Unmarshal(b, r); err != nil {
+		return fmt.Errorf("couldn't decode JSON response: %w", err)
+	}
+	return nil
+}
+
+// User is a Discord user.
+type User struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name"`
+	Bot        bool   `json:"bot"`
+}
+
+// DisplayName returns the user's display name, or their username if they
+// have none.
+func (u *User) DisplayName() string {
+	if u.GlobalName != "" {
+		return u.GlobalName
+	}
+	return u.Username
+}
+
+// Me returns the bot's user.
+func (c *Client) Me(ctx context.Context) (*User, error) {
+	var r User
+	if err := c.call(ctx, "GET", "users/@me", nil, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// GatewayURL returns the URL at which to connect to the gateway.
+func (c *Client) GatewayURL(ctx context.Context) (string, error) {
+	var r struct {
+		URL string `json:"url"`
+	}
+	if err := c.call(ctx, "GET", "gateway/bot", nil, &r); err != nil {
+		return "", err
+	}
+	return r.URL + "/?v=10&encoding=json", nil
+}
+
+// CreateMessage sends a message to a channel. If reply is not empty, the
+// message replies to the message with that ID. Mentions in the text never
+// notify anyone.
+func (c *Client) CreateMessage(ctx context.Context, channel, reply, text string) error {
+	body := map[string]any{
+		"content":          text,
+		"allowed_mentions": map[string]any{"parse": []string{}},
+	}
+	if reply != "" {
+		body["message_reference"] = map[string]any{"message_id": reply, "fail_if_not_exists": false}
+	}
+	if err := c.call(ctx, "POST", "channels/"+channel+"/messages", body, nil); err != nil {
+		return fmt.Errorf("couldn't send to %s: %w", channel, err)
+	}
+	return nil
+}
+
+// Message is a message sent in a channel.
+type Message struct {
+	ID        string    `json:"id"`
+	ChannelID string    `json:"channel_id"`
+	GuildID   string    `json:"guild_id"`
+	Author    User      `json:"author"`
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+	Mentions  []User    `json:"mentions"`
+	// Member is the author's membership in the guild, present for messages
+	// sent in guilds.
+	Member *struct {
+		Nick  string   `json:"nick"`
+		Roles []string `json:"roles"`
+	} `json:"member"`
+	// MessageReference identifies the message to which this one replies.
+	MessageReference *struct {
+		MessageID string `json:"message_id"`
+	} `json:"message_reference"`
+	// WebhookID is set for messages sent by webhooks.
+	WebhookID string `json:"webhook_id"`
+}
+
+var mention = regexp.MustCompile(`<@!?(\d+)>`)
+
+// Plain converts user mentions in message content to @ followed by the
+// mentioned user's name. Mentions of users the message doesn't list are
+// left alone.
+func Plain(content string, mentions []User) string {
+	return mention.ReplaceAllStringFunc(content, func(s string) string {
+		id := mention.FindStringSubmatch(s)[1]
+		for _, u := range mentions {
+			if u.ID == id {
+				return "@" + u.Username
+			}
+		}
+		return s
+	})
+}
@@ -0,0 +1,231 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestParse(t *testing.T) {
+	b, err := os.ReadFile("testdata/events.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ps []payload
+	if err := json.Unmarshal(b, &ps); err != nil {
+		t.Fatal(err)
+	}
+	var got []*Event
+	for i := range ps {
+		if ev := parse(&ps[i]); ev != nil {
+			got = append(got, ev)
+		}
+	}
+	if len(got) != 3 {
+		t.Fatalf("wrong number of events: want 3, got %d", len(got))
+	}
+	m := got[0].Message
+	if got[0].Type != "MESSAGE_CREATE" || m.ID != "300" || m.ChannelID != "200" || m.Author.DisplayName() != "Bocchi" {
+		t.Errorf("wrong message: %+v", got[0])
+	}
+	if m.MessageReference == nil || m.MessageReference.MessageID != "299" || m.Member == nil || len(m.Member.Roles) != 1 {
+		t.Errorf("wrong reply or member: %+v", m)
+	}
+	if want := time.Date(2024, 4, 5, 12, 34, 56, 789e6, time.UTC); !m.Timestamp.Equal(want) {
+		t.Errorf("wrong time: want %v, got %v", want, m.Timestamp)
+	}
+	if got := Plain(m.Content, m.Mentions); got != "@robot hi @nijika" {
+		t.Errorf("wrong plain text: %q", got)
+	}
+	d := got[1]
+	if d.Type != "MESSAGE_DELETE" || d.Message.ID != "300" || d.Message.ChannelID != "200" {
+		t.Errorf("wrong deletion: %+v", d)
+	}
+	in := got[2].Interaction
+	if got[2].Type != "INTERACTION_CREATE" || in == nil {
+		t.Fatalf("wrong interaction: %+v", got[2])
+	}
+	if in.ID != "500" || in.Token != "tok" || in.ChannelID != "200" || in.Data.Name != "speak" {
+		t.Errorf("wrong interaction: %+v", in)
+	}
+	if u := in.Invoker(); u == nil || u.ID != "400" || in.Member.Nick != "bocchi-chan" {
+		t.Errorf("wrong invoker: %+v", in.Member)
+	}
+	if p := String(in.Data.Options, "prompt"); p != "guitar" {
+		t.Errorf("wrong prompt: %q", p)
+	}
+	if p := String(in.Data.Options, "tag"); p != "" {
+		t.Errorf("absent option has value %q", p)
+	}
+}
+
+func TestPlain(t *testing.T) {
+	mentions := []User{{ID: "1", Username: "bocchi"}}
+	cases := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"hello", "hello"},
+		{"<@1> hi", "@bocchi hi"},
+		{"hi <@!1>", "hi @bocchi"},
+		{"hi <@2>", "hi <@2>"},
+		{"<#3> <:pog:4>", "<#3> <:pog:4>"},
+	}
+	for _, c := range cases {
+		if got := Plain(c.in, mentions); got != c.want {
+			t.Errorf("wrong plain text for %q: want %q, got %q", c.in, c.want, got)
+		}
+	}
+}
+
+func TestCreateMessage(t *testing.T) {
+	var auth, path string
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/channels/200/messages":
+			auth, path = r.Header.Get("Authorization"), r.URL.Path
+			json.NewDecoder(r.Body).Decode(&body)
+			w.Write([]byte(`{"id":"301"}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"401: Unauthorized","code":0}`))
+		}
+	}))
+	defer srv.Close()
+	cl := Client{Token: "bocchi", API: srv.URL}
+	if err := cl.CreateMessage(context.Background(), "200", "300", "hi"); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Bot bocchi" || path != "/channels/200/messages" {
+		t.Errorf("wrong request: %q %q", auth, path)
+	}
+	ref, _ := body["message_reference"].(map[string]any)
+	if body["content"] != "hi" || ref["message_id"] != "300" {
+		t.Errorf("wrong body: %v", body)
+	}
+	if am, _ := body["allowed_mentions"].(map[string]any); am == nil || len(am["parse"].([]any)) != 0 {
+		t.Errorf("mentions allowed: %v", body)
+	}
+	if _, err := cl.Me(context.Background()); err == nil || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Errorf("wrong error from failed call: %v", err)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	var reqs []string
+	var registered []Command
+	var followup map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqs = append(reqs, r.Method+" "+r.URL.Path)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /applications/@me":
+			w.Write([]byte(`{"id":"101"}`))
+		case "PUT /applications/101/commands":
+			json.NewDecoder(r.Body).Decode(&registered)
+			w.Write([]byte(`[]`))
+		case "POST /webhooks/101/tok":
+			json.NewDecoder(r.Body).Decode(&followup)
+			w.Write([]byte(`{"id":"302"}`))
+		case "POST /interactions/500/tok/callback", "DELETE /webhooks/101/tok/messages/@original":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	cl := Client{Token: "bocchi", API: srv.URL}
+	app, err := cl.Application(ctx)
+	if err != nil || app != "101" {
+		t.Fatalf("wrong application: %q, %v", app, err)
+	}
+	perm := PermManageMessages
+	cmds := []Command{
+		{Name: "speak", Description: "speak", Options: []CommandOption{{Type: OptionString, Name: "prompt", Description: "prompt"}}},
+		{Name: "robot", Description: "robot", Permissions: &perm, Options: []CommandOption{{Type: OptionSubcommand, Name: "stats", Description: "stats"}}},
+	}
+	if err := cl.SetCommands(ctx, app, cmds); err != nil {
+		t.Fatal(err)
+	}
+	if len(registered) != 2 || registered[0].Permissions != nil || registered[1].Permissions == nil || *registered[1].Permissions != perm {
+		t.Errorf("wrong registered commands: %+v", registered)
+	}
+	in := &Interaction{ID: "500", Token: "tok"}
+	if err := cl.Defer(ctx, in); err != nil {
+		t.Error(err)
+	}
+	if err := cl.Followup(ctx, app, in.Token, "hi"); err != nil {
+		t.Error(err)
+	}
+	if followup["content"] != "hi" {
+		t.Errorf("wrong followup: %v", followup)
+	}
+	if err := cl.DeleteResponse(ctx, app, in.Token); err != nil {
+		t.Error(err)
+	}
+	want := []string{
+		"GET /applications/@me",
+		"PUT /applications/101/commands",
+		"POST /interactions/500/tok/callback",
+		"POST /webhooks/101/tok",
+		"DELETE /webhooks/101/tok/messages/@original",
+	}
+	if !slices.Equal(reqs, want) {
+		t.Errorf("wrong requests:\nwant %q\ngot  %q", want, reqs)
+	}
+}
+
+func TestListen(t *testing.T) {
+	var identified map[string]any
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gateway/bot" {
+			json.NewEncoder(w).Encode(map[string]string{"url": "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"})
+			return
+		}
+		websocket.Handler(func(ws *websocket.Conn) {
+			websocket.JSON.Send(ws, map[string]any{"op": opHello, "d": map[string]any{"heartbeat_interval": 45000}})
+			var p payload
+			if err := websocket.JSON.Receive(ws, &p); err != nil || p.Op != opIdentify {
+				t.Errorf("didn't identify: %v %+v", err, p)
+				return
+			}
+			json.Unmarshal(p.D, &identified)
+			// The gateway may ask for a heartbeat at any time.
+			websocket.JSON.Send(ws, map[string]any{"op": opHeartbeat})
+			if err := websocket.JSON.Receive(ws, &p); err != nil || p.Op != opHeartbeat {
+				t.Errorf("didn't heartbeat: %v %+v", err, p)
+				return
+			}
+			websocket.JSON.Send(ws, map[string]any{"op": opHeartbeatACK})
+			websocket.JSON.Send(ws, map[string]any{"op": opDispatch, "s": 1, "t": "MESSAGE_CREATE", "d": map[string]any{"id": "300", "channel_id": "200", "content": "hi"}})
+			websocket.JSON.Send(ws, map[string]any{"op": opReconnect})
+		}).ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	cl := Client{Token: "bocchi", API: srv.URL}
+	events := make(chan *Event, 1)
+	if err := cl.Listen(context.Background(), events); err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	if identified["token"] != "bocchi" || identified["intents"] != float64(Intents) {
+		t.Errorf("wrong identify: %v", identified)
+	}
+	select {
+	case ev := <-events:
+		if ev.Type != "MESSAGE_CREATE" || ev.Message.ID != "300" || ev.Message.Content != "hi" {
+			t.Errorf("wrong event: %+v", ev)
+		}
+	default:
+		t.Error("no event")
+	}
+}
@@ -0,0 +1,210 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// Gateway opcodes.
+const (
+	opDispatch       = 0
+	opHeartbeat      = 1
+	opIdentify       = 2
+	opReconnect      = 7
+	opInvalidSession = 9
+	opHello          = 10
+	opHeartbeatACK   = 11
+)
+
+// Intents is the gateway intents the client requests: guild messages and
+// their content.
+const Intents = 1<<9 | 1<<15
+
+// payload is a gateway message.
+type payload struct {
+	Op int             `json:"op"`
+	D  json.RawMessage `json:"d"`
+	S  *int64          `json:"s,omitempty"`
+	T  string          `json:"t,omitempty"`
+}
+
+// Event is a message or interaction event from the gateway.
+type Event struct {
+	// Type is the type of the event, MESSAGE_CREATE, MESSAGE_DELETE, or
+	// INTERACTION_CREATE.
+	Type string
+	// Message is the message created. For MESSAGE_DELETE, only its ID,
+	// ChannelID, and GuildID are set.
+	Message Message
+	// Interaction is the command interaction for INTERACTION_CREATE.
+	// It is nil for other events.
+	Interaction *Interaction
+}
+
+// ErrInvalidSession is returned by Listen when the gateway rejects the
+// session, e.g. because the token is wrong or intents aren't enabled.
+var ErrInvalidSession = errors.New("discord: invalid session")
+
+// conn is a gateway connection which can be written concurrently.
+type conn struct {
+	mu sync.Mutex
+	ws *websocket.Conn
+}
+
+func (c *conn) send(op int, d any) error {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return websocket.JSON.Send(c.ws, payload{Op: op, D: b})
+}
+
+// Listen connects to the gateway and sends message and command events to
+// events until ctx is canceled or the connection ends. The gateway ends
+// connections from time to time, so callers should reconnect when Listen
+// returns nil.
+func (c *Client) Listen(ctx context.Context, events chan<- *Event) error {
+	u, err := c.GatewayURL(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't get gateway URL: %w", err)
+	}
+	ws, err := websocket.Dial(u, "", "https://discord.com")
+	if err != nil {
+		return fmt.Errorf("couldn't connect to gateway: %w", err)
+	}
+	defer ws.Close()
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go func() {
+		<-ctx.Done()
+		ws.Close()
+	}()
+	gw := &conn{ws: ws}
+	var hello payload
+	if err := websocket.JSON.Receive(ws, &hello); err != nil {
+		return fmt.Errorf("couldn't read hello: %w", err)
+	}
+	var h struct {
+		Interval int64 `json:"heartbeat_interval"`
+	}
+	if hello.Op != opHello || json.Unmarshal(hello.D, &h) != nil || h.Interval <= 0 {
+		return fmt.Errorf("gateway didn't say hello: op %d", hello.Op)
+	}
+	identify := map[string]any{
+		"token":   c.Token,
+		"intents": Intents,
+		"properties": map[string]string{
+			"os":      "linux",
+			"browser": "robot",
+			"device":  "robot",
+		},
+	}
+	if err := gw.send(opIdentify, identify); err != nil {
+		return fmt.Errorf("couldn't identify: %w", err)
+	}
+	var (
+		mu    sync.Mutex
+		last  *int64
+		acked = true
+	)
+	heartbeat := func() error {
+		mu.Lock()
+		s := last
+		acked = false
+		mu.Unlock()
+		return gw.send(opHeartbeat, s)
+	}
+	interval := time.Duration(h.Interval) * time.Millisecond
+	go func() {
+		// The first heartbeat is jittered so that many clients reconnecting
+		// at once don't beat together.
+		t := time.NewTimer(time.Duration(rand.Float64() * float64(interval)))
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+			mu.Lock()
+			ok := acked
+			mu.Unlock()
+			if !ok {
+				cancel(errors.New("gateway stopped acknowledging heartbeats"))
+				return
+			}
+			if err := heartbeat(); err != nil {
+				cancel(fmt.Errorf("couldn't send heartbeat: %w", err))
+				return
+			}
+			t.Reset(interval)
+		}
+	}()
+	for {
+		ws.SetReadDeadline(time.Now().Add(2 * interval))
+		var p payload
+		if err := websocket.JSON.Receive(ws, &p); err != nil {
+			if err := context.Cause(ctx); err != nil {
+				return err
+			}
+			return fmt.Errorf("couldn't read from gateway: %w", err)
+		}
+		switch p.Op {
+		case opDispatch:
+			if p.S != nil {
+				mu.Lock()
+				last = p.S
+				mu.Unlock()
+			}
+			ev := parse(&p)
+			if ev == nil {
+				continue
+			}
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				return context.Cause(ctx)
+			}
+		case opHeartbeat:
+			if err := heartbeat(); err != nil {
+				return fmt.Errorf("couldn't send heartbeat: %w", err)
+			}
+		case opHeartbeatACK:
+			mu.Lock()
+			acked = true
+			mu.Unlock()
+		case opReconnect:
+			return nil
+		case opInvalidSession:
+			return ErrInvalidSession
+		}
+	}
+}
+
+// parse returns the message or command event in a dispatch, if any.
+func parse(p *payload) *Event {
+	switch p.T {
+	case "MESSAGE_CREATE", "MESSAGE_DELETE":
+		ev := Event{Type: p.T}
+		if err := json.Unmarshal(p.D, &ev.Message); err != nil {
+			return nil
+		}
+		return &ev
+	case "INTERACTION_CREATE":
+		var in Interaction
+		if err := json.Unmarshal(p.D, &in); err != nil || in.Type != InteractionCommand {
+			return nil
+		}
+		return &Event{Type: p.T, Interaction: &in}
+	}
+	return nil
+}
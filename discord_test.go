@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/discord"
+	"github.com/zephyrtronium/robot/message"
+	"github.com/zephyrtronium/robot/platform"
+)
+
+// messageHandler is a platform handler which records messages.
+type messageHandler struct {
+	platform.Handler
+	msgs []*message.Incoming
+}
+
+func (h *messageHandler) Message(ctx context.Context, c platform.Client, m *message.Incoming) {
+	h.msgs = append(h.msgs, m)
+}
+
+func TestDiscordInteraction(t *testing.T) {
+	var mu sync.Mutex
+	var reqs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Content string `json:"content"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		reqs = append(reqs, r.Method+" "+r.URL.Path+" "+body.Content)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	dc := &discordClient{
+		cl:       &discord.Client{Token: "bocchi", API: srv.URL},
+		id:       "100",
+		name:     "robot",
+		modRoles: []string{"50"},
+		app:      "101",
+		pending:  make(map[string]*discordInteraction),
+	}
+	var h messageHandler
+	interact := func(id, name string, opts ...discord.InteractionOption) {
+		t.Helper()
+		in := &discord.Interaction{ID: id, Type: discord.InteractionCommand, Token: "tok" + id, ChannelID: "200"}
+		in.Member = &discord.Member{User: discord.User{ID: "400", Username: "bocchi"}, Nick: "bocchi-chan", Roles: []string{"50"}}
+		in.Data.Name = name
+		in.Data.Options = opts
+		dc.event(ctx, &h, &discord.Event{Type: "INTERACTION_CREATE", Interaction: in})
+	}
+	interact("500", "robot", discord.InteractionOption{Name: "stats", Type: discord.OptionSubcommand})
+	interact("501", "speak", discord.InteractionOption{Name: "prompt", Type: discord.OptionString, Value: json.RawMessage(`"guitar"`)})
+	interact("502", "forgetme")
+	interact("503", "kita")
+	want := []string{"@robot stats", "@robot speak guitar", "@robot ignore me"}
+	var got []string
+	for _, m := range h.msgs {
+		got = append(got, m.Text)
+		if m.To != "discord:200" || m.Sender != "400" || m.Name != "bocchi-chan" || !m.IsModerator {
+			t.Errorf("wrong message: %+v", m)
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("wrong commands:\nwant %q\ngot  %q", want, got)
+	}
+	// A command's reply answers its interaction, and a message replying to
+	// nothing answers the oldest unanswered interaction in the channel.
+	if err := dc.Send(ctx, "discord:200", "500", "stats"); err != nil {
+		t.Error(err)
+	}
+	if err := dc.Send(ctx, "discord:200", "", "guitar hero"); err != nil {
+		t.Error(err)
+	}
+	// Unanswered interactions lose their loading state.
+	dc.sweep(ctx, time.Now().Add(2*time.Minute))
+	if err := dc.Send(ctx, "discord:200", "", "hi"); err != nil {
+		t.Error(err)
+	}
+	// Expired interactions are forgotten.
+	dc.sweep(ctx, time.Now().Add(time.Hour))
+	if len(dc.pending) != 0 {
+		t.Errorf("expired interactions remain: %v", dc.pending)
+	}
+	wantReqs := []string{
+		"POST /interactions/500/tok500/callback ",
+		"POST /interactions/501/tok501/callback ",
+		"POST /interactions/502/tok502/callback ",
+		"POST /webhooks/101/tok500 stats",
+		"POST /webhooks/101/tok501 guitar hero",
+		"DELETE /webhooks/101/tok502/messages/@original ",
+		"POST /channels/200/messages hi",
+	}
+	if !slices.Equal(reqs, wantReqs) {
+		t.Errorf("wrong requests:\nwant %q\ngot  %q", wantReqs, reqs)
+	}
+}
@@ -0,0 +1,22 @@
+// Package disk reports free space on storage volumes.
+package disk
+
+import "errors"
+
+// ErrUnsupported is returned by [Free] on platforms where it isn't
+// implemented.
+var ErrUnsupported = errors.New("disk space is not available on this platform")
+
+// Usage is the space on the volume containing a path.
+type Usage struct {
+	// Free is the number of bytes available to unprivileged users.
+	Free uint64
+	// Total is the size of the volume in bytes.
+	Total uint64
+}
+
+// Free reports the space on the volume containing path.
+// The path must exist.
+func Free(path string) (Usage, error) {
+	return free(path)
+}
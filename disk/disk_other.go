@@ -0,0 +1,7 @@
+//go:build !(linux || darwin || freebsd || windows)
+
+package disk
+
+func free(path string) (Usage, error) {
+	return Usage{}, ErrUnsupported
+}
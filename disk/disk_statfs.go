@@ -0,0 +1,19 @@
+//go:build linux || darwin || freebsd
+
+package disk
+
+import (
+	"fmt"
+	"syscall"
+)
+
+func free(path string) (Usage, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return Usage{}, fmt.Errorf("couldn't stat volume of %s: %w", path, err)
+	}
+	return Usage{
+		Free:  uint64(st.Bavail) * uint64(st.Bsize),
+		Total: uint64(st.Blocks) * uint64(st.Bsize),
+	}, nil
+}
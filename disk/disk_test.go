@@ -0,0 +1,24 @@
+package disk_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zephyrtronium/robot/disk"
+)
+
+func TestFree(t *testing.T) {
+	u, err := disk.Free(t.TempDir())
+	if errors.Is(err, disk.ErrUnsupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Total == 0 || u.Free > u.Total {
+		t.Errorf("implausible usage: %+v", u)
+	}
+	if _, err := disk.Free(t.TempDir() + "/nonexistent"); err == nil {
+		t.Errorf("no error for nonexistent path")
+	}
+}
@@ -0,0 +1,21 @@
+//go:build windows
+
+package disk
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+func free(path string) (Usage, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return Usage{}, fmt.Errorf("bad path %s: %w", path, err)
+	}
+	var u Usage
+	if err := windows.GetDiskFreeSpaceEx(p, &u.Free, &u.Total, nil); err != nil {
+		return Usage{}, fmt.Errorf("couldn't get space on volume of %s: %w", path, err)
+	}
+	return u, nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain/sqlbrain"
+	"github.com/zephyrtronium/robot/disk"
+	"github.com/zephyrtronium/robot/jobs"
+)
+
+// diskLevel is how short the robot is on disk space.
+type diskLevel int32
+
+const (
+	// diskOK means there is enough space.
+	diskOK diskLevel = iota
+	// diskAlert means the owner has been told that space is running low.
+	diskAlert
+	// diskNoLearn means learning is paused to save space.
+	diskNoLearn
+	// diskPaused means the robot has stopped handling messages.
+	diskPaused
+)
+
+// diskWatch is the configuration for watching free space on the volumes
+// holding the robot's databases.
+type diskWatch struct {
+	// paths is the directories on the volumes to watch.
+	paths []string
+	// every is the interval between checks.
+	every time.Duration
+	// alert, learn, and pause are the free space in bytes below which the
+	// robot alerts the owner, pauses learning, and pauses entirely,
+	// respectively. Each is disabled if it is zero.
+	alert, learn, pause uint64
+}
+
+// SetDiskWatch sets up watching free space on the volumes holding the
+// databases in db. If no thresholds are configured, space is not watched.
+func (robo *Robot) SetDiskWatch(cfg DiskCfg, db DBCfg) {
+	if cfg.Alert <= 0 && cfg.Learn <= 0 && cfg.Pause <= 0 {
+		return
+	}
+	w := &diskWatch{
+		every: fseconds(cfg.Every),
+		alert: megabytes(cfg.Alert),
+		learn: megabytes(cfg.Learn),
+		pause: megabytes(cfg.Pause),
+	}
+	if w.every <= 0 {
+		w.every = time.Minute
+	}
+	seen := make(map[string]bool)
+	for _, p := range []string{db.KVBrain, dsnDir(db.BoltBrain), dsnDir(db.SQLBrain), dsnDir(db.Privacy), dsnDir(db.Spoken), dsnDir(db.Emotes)} {
+		if p == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		w.paths = append(w.paths, p)
+	}
+	if len(w.paths) == 0 {
+		return
+	}
+	robo.diskWatch = w
+}
+
+// megabytes converts a number of megabytes to bytes.
+func megabytes(mb float64) uint64 {
+	if mb <= 0 {
+		return 0
+	}
+	return uint64(mb * (1 << 20))
+}
+
+// dsnDir returns the directory holding the file of an SQLite DSN, or the
+// empty string if the DSN doesn't name a file on disk, including when it names
+// a PostgreSQL database.
+func dsnDir(dsn string) string {
+	p, q, _ := strings.Cut(strings.TrimPrefix(dsn, "file:"), "?")
+	if p == "" || p == ":memory:" || strings.Contains(q, "mode=memory") || sqlbrain.IsPostgres(dsn) {
+		return ""
+	}
+	return filepath.Dir(p)
+}
+
+// level determines the disk level for an amount of free space.
+func (w *diskWatch) level(free uint64) diskLevel {
+	switch {
+	case free < w.pause:
+		return diskPaused
+	case free < w.learn:
+		return diskNoLearn
+	case free < w.alert:
+		return diskAlert
+	default:
+		return diskOK
+	}
+}
+
+// addDiskJob adds a job to check free space periodically, pausing and
+// resuming learning and handling messages as it changes.
+func (robo *Robot) addDiskJob() {
+	if robo.diskWatch == nil {
+		return
+	}
+	free := new(expvar.Map)
+	robo.metrics.Set("disk_free", free)
+	robo.jobs.Add(jobs.Job{
+		Name:  "disk",
+		Every: robo.diskWatch.every,
+		Fn: func(ctx context.Context, _ string) (string, error) {
+			return "", robo.checkDisk(ctx, free)
+		},
+	})
+}
+
+// checkDisk checks free space once and updates the disk level.
+func (robo *Robot) checkDisk(ctx context.Context, free *expvar.Map) error {
+	w := robo.diskWatch
+	least := uint64(1<<64 - 1)
+	var at string
+	var errs []error
+	for _, p := range w.paths {
+		u, err := disk.Free(p)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("couldn't check disk space at %s: %w", p, err))
+			continue
+		}
+		v := new(expvar.Int)
+		v.Set(int64(u.Free))
+		free.Set(p, v)
+		if u.Free < least {
+			least, at = u.Free, p
+		}
+	}
+	if at != "" {
+		robo.setDiskLevel(ctx, w.level(least), at, least)
+	}
+	return errors.Join(errs...)
+}
+
+// setDiskLevel changes the disk level and tells the owner about it.
+func (robo *Robot) setDiskLevel(ctx context.Context, l diskLevel, path string, free uint64) {
+	old := diskLevel(robo.disk.Swap(int32(l)))
+	if l == old {
+		return
+	}
+	mb := free >> 20
+	var text string
+	switch l {
+	case diskOK:
+		text = fmt.Sprintf("Disk space at %s has recovered to %d MB. Back to normal.", path, mb)
+	case diskAlert:
+		text = fmt.Sprintf("Disk space at %s is low: %d MB free.", path, mb)
+	case diskNoLearn:
+		text = fmt.Sprintf("Disk space at %s is very low: %d MB free. I've stopped learning until there's more room.", path, mb)
+	case diskPaused:
+		text = fmt.Sprintf("Disk space at %s is critically low: %d MB free. I've stopped handling messages until there's more room.", path, mb)
+	}
+	if l < old && l != diskOK {
+		text += " It's better than before, though."
+	}
+	robo.notifyOwner(ctx, text)
+}
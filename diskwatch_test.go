@@ -0,0 +1,55 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDiskLevel(t *testing.T) {
+	w := &diskWatch{alert: 300, learn: 200, pause: 100}
+	cases := []struct {
+		name string
+		free uint64
+		want diskLevel
+	}{
+		{"plenty", 1000, diskOK},
+		{"alert", 300, diskOK},
+		{"low", 250, diskAlert},
+		{"learn", 150, diskNoLearn},
+		{"pause", 50, diskPaused},
+		{"empty", 0, diskPaused},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := w.level(c.free); got != c.want {
+				t.Errorf("wrong level for %d: want %d, got %d", c.free, c.want, got)
+			}
+		})
+	}
+	t.Run("disabled", func(t *testing.T) {
+		w := &diskWatch{alert: 300}
+		if got := w.level(0); got != diskAlert {
+			t.Errorf("wrong level with only alert: want %d, got %d", diskAlert, got)
+		}
+	})
+}
+
+func TestDSNDir(t *testing.T) {
+	cases := []struct {
+		dsn, want string
+	}{
+		{"", ""},
+		{":memory:", ""},
+		{"file:foo?mode=memory&cache=shared", ""},
+		{"file:/data/robot.db", filepath.Dir("/data/robot.db")},
+		{"file:/data/robot.db?_journal=WAL", filepath.Dir("/data/robot.db")},
+		{"/var/robot/privacy.db", filepath.Dir("/var/robot/privacy.db")},
+		{"robot.db", "."},
+		{"postgres://robot@db.example/robot?sslmode=disable", ""},
+	}
+	for _, c := range cases {
+		if got := dsnDir(c.dsn); got != c.want {
+			t.Errorf("wrong dir for %q: want %q, got %q", c.dsn, c.want, got)
+		}
+	}
+}
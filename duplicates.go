@@ -0,0 +1,29 @@
+package main
+
+import (
+	"github.com/zephyrtronium/robot/channel"
+)
+
+// SetDuplicates sets the window in seconds within which channels sharing a
+// send tag won't send the same generated message. If it is not positive,
+// channels may send identical messages.
+func (robo *Robot) SetDuplicates(window float64) {
+	robo.dupWindow = fseconds(window)
+}
+
+// outputs returns the tracker of recent messages shared by channels which
+// send from a tag. It returns nil if identical messages aren't suppressed.
+func (robo *Robot) outputs(tag string) *channel.Outputs {
+	if robo.dupWindow <= 0 || tag == "" {
+		return nil
+	}
+	if robo.dups == nil {
+		robo.dups = make(map[string]*channel.Outputs)
+	}
+	o := robo.dups[tag]
+	if o == nil {
+		o = channel.NewOutputs(robo.dupWindow)
+		robo.dups[tag] = o
+	}
+	return o
+}
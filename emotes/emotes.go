@@ -0,0 +1,69 @@
+// Package emotes provides persistent storage for emotes added to channels
+// at runtime and a client for the third-party emote services whose emotes
+// channels use.
+package emotes
+
+import (
+	"context"
+	"fmt"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Store is a record of emotes added to channels backed by an SQL database.
+type Store struct {
+	db *sqlitex.Pool
+}
+
+// Open opens an existing emote store in an SQL database.
+func Open(ctx context.Context, db *sqlitex.Pool) (*Store, error) {
+	conn, err := db.Take(ctx)
+	defer db.Put(conn)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get connection from pool: %w", err)
+	}
+	const schemaSQL = `CREATE TABLE IF NOT EXISTS emotes (channel TEXT NOT NULL, emote TEXT NOT NULL, weight INTEGER NOT NULL, PRIMARY KEY(channel, emote)) STRICT, WITHOUT ROWID`
+	if err := sqlitex.ExecuteTransient(conn, schemaSQL, nil); err != nil {
+		return nil, fmt.Errorf("couldn't run migration: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Set records the weight of an emote in a channel.
+// If the weight is not positive, the emote is removed.
+func (s *Store) Set(ctx context.Context, channel, emote string, weight int) error {
+	conn, err := s.db.Take(ctx)
+	defer s.db.Put(conn)
+	if err != nil {
+		return fmt.Errorf("couldn't get connection to set emote: %w", err)
+	}
+	if weight <= 0 {
+		opts := sqlitex.ExecOptions{Args: []any{channel, emote}}
+		return sqlitex.Execute(conn, `DELETE FROM emotes WHERE channel=? AND emote=?`, &opts)
+	}
+	opts := sqlitex.ExecOptions{Args: []any{channel, emote, weight}}
+	const upsert = `INSERT INTO emotes (channel, emote, weight) VALUES (?1, ?2, ?3) ON CONFLICT DO UPDATE SET weight = ?3`
+	return sqlitex.Execute(conn, upsert, &opts)
+}
+
+// All gets all emotes and their weights recorded for a channel.
+func (s *Store) All(ctx context.Context, channel string) (map[string]int, error) {
+	conn, err := s.db.Take(ctx)
+	defer s.db.Put(conn)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get connection to list emotes: %w", err)
+	}
+	r := make(map[string]int)
+	opts := sqlitex.ExecOptions{
+		Args: []any{channel},
+		ResultFunc: func(st *sqlite.Stmt) error {
+			r[st.ColumnText(0)] = st.ColumnInt(1)
+			return nil
+		},
+	}
+	if err := sqlitex.Execute(conn, `SELECT emote, weight FROM emotes WHERE channel=?`, &opts); err != nil {
+		return nil, fmt.Errorf("couldn't list emotes: %w", err)
+	}
+	return r, nil
+}
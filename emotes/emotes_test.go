@@ -0,0 +1,64 @@
+package emotes_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/zephyrtronium/robot/emotes"
+)
+
+var dbcount atomic.Uint64
+
+func testConn() *sqlitex.Pool {
+	k := dbcount.Add(1)
+	pool, err := sqlitex.NewPool(fmt.Sprintf("file:%d.db?mode=memory&cache=shared", k), sqlitex.PoolOptions{Flags: sqlite.OpenReadWrite | sqlite.OpenCreate | sqlite.OpenMemory | sqlite.OpenSharedCache | sqlite.OpenURI})
+	if err != nil {
+		panic(err)
+	}
+	return pool
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	db := testConn()
+	s, err := emotes.Open(ctx, db)
+	if err != nil {
+		t.Fatalf("couldn't open store: %v", err)
+	}
+	steps := []struct {
+		channel string
+		emote   string
+		weight  int
+	}{
+		{"#bocchi", "KEKW", 5},
+		{"#bocchi", "Kappa", 1},
+		{"#ryou", "KEKW", 2},
+		{"#bocchi", "KEKW", 7},
+		{"#bocchi", "Kappa", 0},
+	}
+	for _, c := range steps {
+		if err := s.Set(ctx, c.channel, c.emote, c.weight); err != nil {
+			t.Errorf("couldn't set %s in %s to %d: %v", c.emote, c.channel, c.weight, err)
+		}
+	}
+	want := map[string]map[string]int{
+		"#bocchi": {"KEKW": 7},
+		"#ryou":   {"KEKW": 2},
+		"#nijika": {},
+	}
+	for ch, w := range want {
+		got, err := s.All(ctx, ch)
+		if err != nil {
+			t.Errorf("couldn't list %s: %v", ch, err)
+		}
+		if diff := cmp.Diff(w, got); diff != "" {
+			t.Errorf("wrong emotes in %s (+got/-want):\n%s", ch, diff)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package emotes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Services is a client for third-party emote services, which give Twitch
+// channels emotes beyond Twitch's own.
+type Services struct {
+	// HTTP is the HTTP client for performing requests.
+	// If nil, http.DefaultClient is used.
+	HTTP *http.Client
+	// BTTVAPI is the base URL of the BetterTTV API. If it is empty,
+	// https://api.betterttv.net/3 is used.
+	BTTVAPI string
+	// SevenTVAPI is the base URL of the 7TV API. If it is empty,
+	// https://7tv.io/v3 is used.
+	SevenTVAPI string
+}
+
+// get fetches JSON from a URL. If the resource doesn't exist, it returns
+// false with no error. The response body is truncated to 8 MB.
+func (s *Services) get(ctx context.Context, base, path string, r any) (bool, error) {
+	u, err := url.JoinPath(base, path)
+	if err != nil {
+		return false, fmt.Errorf("couldn't make URL: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return false, fmt.Errorf("couldn't make request: %w", err)
+	}
+	hc := s.HTTP
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("couldn't get %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		// The channel doesn't use the service.
+		return false, nil
+	case resp.StatusCode/100 != 2:
+		return false, fmt.Errorf("couldn't get %s: %s", u, resp.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 8<<20)).Decode(r); err != nil {
+		return false, fmt.Errorf("couldn't decode response from %s: %w", u, err)
+	}
+	return true, nil
+}
+
+// BTTV gets the names of the BetterTTV emotes in a Twitch channel, including
+// shared ones, by the broadcaster's user ID.
+func (s *Services) BTTV(ctx context.Context, twitchID string) ([]string, error) {
+	base := s.BTTVAPI
+	if base == "" {
+		base = "https://api.betterttv.net/3"
+	}
+	type emote struct {
+		Code string `json:"code"`
+	}
+	var r struct {
+		Channel []emote `json:"channelEmotes"`
+		Shared  []emote `json:"sharedEmotes"`
+	}
+	if ok, err := s.get(ctx, base, "cached/users/twitch/"+twitchID, &r); !ok {
+		return nil, err
+	}
+	names := make([]string, 0, len(r.Channel)+len(r.Shared))
+	for _, e := range append(r.Channel, r.Shared...) {
+		names = append(names, e.Code)
+	}
+	return names, nil
+}
+
+// SevenTV gets the names of the emotes in a Twitch channel's active 7TV emote
+// set by the broadcaster's user ID.
+func (s *Services) SevenTV(ctx context.Context, twitchID string) ([]string, error) {
+	base := s.SevenTVAPI
+	if base == "" {
+		base = "https://7tv.io/v3"
+	}
+	var r struct {
+		Set *struct {
+			Emotes []struct {
+				Name string `json:"name"`
+			} `json:"emotes"`
+		} `json:"emote_set"`
+	}
+	if ok, err := s.get(ctx, base, "users/twitch/"+twitchID, &r); !ok || r.Set == nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(r.Set.Emotes))
+	for _, e := range r.Set.Emotes {
+		names = append(names, e.Name)
+	}
+	return names, nil
+}
@@ -0,0 +1,57 @@
+package emotes_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/zephyrtronium/robot/emotes"
+)
+
+func TestServices(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bttv/cached/users/twitch/1":
+			w.Write([]byte(`{"id":"x","channelEmotes":[{"id":"a","code":"catJAM"}],"sharedEmotes":[{"id":"b","code":"monkaS"}]}`))
+		case "/7tv/users/twitch/1":
+			w.Write([]byte(`{"id":"y","emote_set":{"id":"z","emotes":[{"id":"c","name":"peepoHappy"},{"id":"d","name":"Clap"}]}}`))
+		case "/bttv/cached/users/twitch/3", "/7tv/users/twitch/3":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	s := emotes.Services{BTTVAPI: srv.URL + "/bttv", SevenTVAPI: srv.URL + "/7tv"}
+	cases := []struct {
+		name string
+		f    func(context.Context, string) ([]string, error)
+		want []string
+	}{
+		{"bttv", s.BTTV, []string{"catJAM", "monkaS"}},
+		{"7tv", s.SevenTV, []string{"peepoHappy", "Clap"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := c.f(ctx, "1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("wrong emotes (+got/-want):\n%s", diff)
+			}
+			// Channels which don't use the service have no emotes.
+			got, err = c.f(ctx, "2")
+			if err != nil || len(got) != 0 {
+				t.Errorf("wrong result for unknown channel: %q, %v", got, err)
+			}
+			if _, err := c.f(ctx, "3"); err == nil {
+				t.Error("no error from failed request")
+			}
+		})
+	}
+}
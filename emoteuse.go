@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/zephyrtronium/robot/channel"
+	"github.com/zephyrtronium/robot/jobs"
+	"github.com/zephyrtronium/robot/twitch"
+)
+
+// reweighEvery is the interval at which emote weights derived from use in
+// chat are updated.
+const reweighEvery = 10 * time.Minute
+
+// addReweighJob adds a job to update the emote weights of channels which blend
+// in emotes used in chat, if there are any.
+func (robo *Robot) addReweighJob() {
+	blended := false
+	for _, ch := range robo.channels.All() {
+		if ch.EmoteBlend > 0 {
+			blended = true
+			break
+		}
+	}
+	if !blended {
+		return
+	}
+	robo.jobs.Add(jobs.Job{
+		Name:  "emote-weights",
+		Every: reweighEvery,
+		Fn: func(ctx context.Context, _ string) (string, error) {
+			now := time.Now()
+			for _, ch := range robo.channels.All() {
+				if ch.EmoteBlend > 0 {
+					ch.Emotes.Reweigh(now, ch.EmoteTop, ch.EmoteBlend)
+				}
+			}
+			return "", nil
+		},
+	})
+}
+
+// emotesHTTP lists the emotes most used in a channel's chat.
+// The channel is named without the leading #. The n parameter limits the
+// number of emotes listed.
+func (robo *Robot) emotesHTTP(w http.ResponseWriter, r *http.Request) {
+	ch, _ := robo.channels.Load("#" + r.PathValue("channel"))
+	if ch == nil {
+		http.NotFound(w, r)
+		return
+	}
+	n, _ := strconv.Atoi(r.FormValue("n"))
+	u := ch.Emotes.Usage(time.Now(), n)
+	if u == nil {
+		u = []channel.EmoteCount{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(u)
+}
+
+// emoteServicesEvery is the interval at which emotes from third-party emote
+// services are refreshed.
+const emoteServicesEvery = time.Hour
+
+// addEmoteServicesJob adds a job to refresh the emotes of Twitch channels from
+// third-party emote services, if any channel uses them.
+func (robo *Robot) addEmoteServicesJob() {
+	if robo.twitch == nil {
+		return
+	}
+	used := false
+	for _, name := range robo.twitch.channels {
+		if ch, _ := robo.channels.Load(name); ch != nil && ch.EmoteServices != nil {
+			used = true
+			break
+		}
+	}
+	if !used {
+		return
+	}
+	robo.jobs.Add(jobs.Job{
+		Name:  "emote-services",
+		Every: emoteServicesEvery,
+		Fn: func(ctx context.Context, _ string) (string, error) {
+			return "", robo.refreshEmoteServices(ctx)
+		},
+	})
+}
+
+// refreshEmoteServices fetches the emotes of each Twitch channel which uses
+// third-party emote services.
+func (robo *Robot) refreshEmoteServices(ctx context.Context) error {
+	tc := robo.twitch
+	tok, err := tc.tmi.tokens.Token(ctx)
+	if err != nil {
+		return err
+	}
+	ids, err := robo.twitchIDs(ctx, tok)
+	if errors.Is(err, twitch.ErrNeedRefresh) {
+		tok, err = tc.tmi.tokens.Refresh(ctx, tok)
+		if err != nil {
+			return fmt.Errorf("couldn't refresh token: %w", err)
+		}
+		ids, err = robo.twitchIDs(ctx, tok)
+	}
+	if err != nil {
+		return err
+	}
+	return robo.refreshEmoteServicesWith(ctx, ids)
+}
+
+// refreshEmoteServicesWith fetches the emotes of the channels which use
+// third-party emote services given their broadcasters' user IDs by channel.
+// If any service fails for a channel, the channel keeps its previous emotes
+// from all services rather than losing some until the next refresh.
+func (robo *Robot) refreshEmoteServicesWith(ctx context.Context, ids map[string]string) error {
+	fetch := map[string]func(context.Context, string) ([]string, error){
+		"bttv": robo.emoteServices.BTTV,
+		"7tv":  robo.emoteServices.SevenTV,
+	}
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(ids)) {
+		ch, _ := robo.channels.Load(name)
+		id := ids[name]
+		if ch == nil || id == "" || ch.EmoteServices == nil {
+			continue
+		}
+		set := make(map[string]int)
+		ok := true
+		for svc, w := range ch.EmoteServices {
+			names, err := fetch[svc](ctx, id)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("couldn't get %s emotes for %s: %w", svc, name, err))
+				ok = false
+				continue
+			}
+			for _, e := range names {
+				set[e] += w
+			}
+		}
+		if !ok {
+			continue
+		}
+		ch.Emotes.SetServices(set)
+		slog.InfoContext(ctx, "refreshed emotes from services", slog.String("in", name), slog.Int("emotes", len(set)))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/zephyrtronium/robot/channel"
+	"github.com/zephyrtronium/robot/emotes"
+)
+
+func TestRefreshEmoteServices(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bttv/cached/users/twitch/1":
+			w.Write([]byte(`{"channelEmotes":[{"code":"catJAM"}],"sharedEmotes":[]}`))
+		case "/7tv/users/twitch/1":
+			w.Write([]byte(`{"emote_set":{"emotes":[{"name":"catJAM"},{"name":"Clap"}]}}`))
+		case "/bttv/cached/users/twitch/2":
+			w.Write([]byte(`{"channelEmotes":[{"code":"monkaS"}],"sharedEmotes":[]}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	robo := New(1)
+	robo.emoteServices = emotes.Services{BTTVAPI: srv.URL + "/bttv", SevenTVAPI: srv.URL + "/7tv"}
+	bocchi := &channel.Channel{Name: "#bocchi", Emotes: channel.NewEmotes(nil), EmoteServices: map[string]int{"bttv": 2, "7tv": 1}}
+	ryou := &channel.Channel{Name: "#ryou", Emotes: channel.NewEmotes(nil), EmoteServices: map[string]int{"bttv": 1, "7tv": 1}}
+	ryou.Emotes.SetServices(map[string]int{"Kappa": 1})
+	robo.channels.Store("#bocchi", bocchi)
+	robo.channels.Store("#ryou", ryou)
+	err := robo.refreshEmoteServicesWith(context.Background(), map[string]string{"#bocchi": "1", "#ryou": "2"})
+	if err == nil {
+		t.Error("no error from failed service")
+	}
+	for _, e := range []string{"catJAM", "Clap"} {
+		if _, ok := bocchi.Emotes.Has(e); !ok {
+			t.Errorf("%s missing after refresh", e)
+		}
+	}
+	// A channel with a failed service keeps its old emotes.
+	if _, ok := ryou.Emotes.Has("Kappa"); !ok {
+		t.Error("old emotes lost after failed refresh")
+	}
+	if _, ok := ryou.Emotes.Has("monkaS"); ok {
+		t.Error("partial refresh applied")
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// defaultDataDir is the directory for data files in environment-only configs
+// when ROBOT_DATA is not set.
+const defaultDataDir = "/var/lib/robot"
+
+// LoadEnv creates a config entirely from environment variables, for running
+// without a config file, e.g. in a container.
+//
+// Each option is named by ROBOT_ followed by its TOML path in upper case with
+// underscores between keys, e.g. ROBOT_TMI_CID for cid in the tmi table or
+// ROBOT_DB_BUSY_TIMEOUT for busy_timeout in the db table. Lists are separated
+// by commas. Tables keyed by name and lists of tables can't be set this way,
+// except that ROBOT_TWITCH_ variables configure a single group of Twitch
+// channels, e.g. ROBOT_TWITCH_CHANNELS and ROBOT_TWITCH_RESPONSES.
+//
+// Files and databases which are not set are placed in the directory named by
+// ROBOT_DATA, or /var/lib/robot by default.
+func LoadEnv(getenv func(string) string) (*Config, error) {
+	var cfg Config
+	if err := envStruct(reflect.ValueOf(&cfg).Elem(), "ROBOT", getenv); err != nil {
+		return nil, err
+	}
+	if getenv("ROBOT_TWITCH_CHANNELS") != "" {
+		var ch ChannelCfg
+		if err := envStruct(reflect.ValueOf(&ch).Elem(), "ROBOT_TWITCH", getenv); err != nil {
+			return nil, err
+		}
+		defined := func(key ...string) bool {
+			return getenv("ROBOT_TWITCH_"+strings.ToUpper(strings.Join(key, "_"))) != ""
+		}
+		if err := applyProfile(&ch, defined); err != nil {
+			return nil, fmt.Errorf("bad ROBOT_TWITCH_PROFILE: %w", err)
+		}
+		cfg.Twitch = map[string]*ChannelCfg{"env": &ch}
+	}
+	dir := getenv("ROBOT_DATA")
+	if dir == "" {
+		dir = defaultDataDir
+	}
+	envDefaults(&cfg, dir)
+	return &cfg, nil
+}
+
+// envDefaults sets data paths which are unset in cfg to files in dir.
+func envDefaults(cfg *Config, dir string) {
+	def := func(p *string, v string) {
+		if *p == "" {
+			*p = v
+		}
+	}
+	def(&cfg.SecretFile, filepath.Join(dir, "secret.key"))
+	if cfg.DB.KVBrain == "" && cfg.DB.BoltBrain == "" && cfg.DB.Remote.Addr == "" {
+		def(&cfg.DB.SQLBrain, "file:"+filepath.Join(dir, "robot.db"))
+	}
+	db := "file:" + filepath.Join(dir, "robot.db")
+	def(&cfg.DB.Privacy, db)
+	def(&cfg.DB.Spoken, db)
+	def(&cfg.DB.Emotes, db)
+	if cfg.TMI.CID != "" {
+		def(&cfg.TMI.SecretFile, filepath.Join(dir, "twitch.secret"))
+		def(&cfg.TMI.TokenFile, filepath.Join(dir, "twitch.token"))
+	}
+}
+
+// envStruct sets the fields of a struct from environment variables named by
+// prefix and their TOML keys.
+func envStruct(v reflect.Value, prefix string, getenv func(string) string) error {
+	t := v.Type()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		key, _, _ := strings.Cut(f.Tag.Get("toml"), ",")
+		if !f.IsExported() || key == "" || key == "-" {
+			continue
+		}
+		name := prefix + "_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct {
+			if err := envStruct(fv, name, getenv); err != nil {
+				return err
+			}
+			continue
+		}
+		s := getenv(name)
+		if s == "" {
+			continue
+		}
+		if err := envValue(fv, s); err != nil {
+			return fmt.Errorf("bad value for %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// envValue parses s into a scalar or list value.
+// Values of other kinds are left as they are.
+func envValue(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		x, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(x)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Struct {
+			return nil
+		}
+		parts := strings.Split(s, ",")
+		r := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			if err := envValue(r.Index(i), strings.TrimSpace(p)); err != nil {
+				return err
+			}
+		}
+		v.Set(r)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLoadEnv(t *testing.T) {
+	env := map[string]string{
+		"ROBOT_DATA":               "/data",
+		"ROBOT_OWNER_NAME":         "bocchi",
+		"ROBOT_TMI_CID":            "kessoku",
+		"ROBOT_TMI_OWNER_ID":       "1",
+		"ROBOT_DB_POOL":            "4",
+		"ROBOT_DB_BUSY_TIMEOUT":    "2.5",
+		"ROBOT_GLOBAL_IGNORE":      "moobot, nightbot",
+		"ROBOT_HTTP_LISTEN":        ":4959",
+		"ROBOT_TWITCH_CHANNELS":    "#bocchi,#ryo",
+		"ROBOT_TWITCH_LEARN":       "kessoku",
+		"ROBOT_TWITCH_RATE_EVERY":  "10",
+		"ROBOT_TWITCH_PROFILE":     "chaotic",
+		"ROBOT_TWITCH_LEADERBOARD": "true",
+	}
+	cfg, err := LoadEnv(func(s string) string { return env[s] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Owner.Name != "bocchi" || cfg.TMI.CID != "kessoku" || cfg.TMI.Owner.ID != "1" {
+		t.Errorf("wrong strings: %+v %+v", cfg.Owner, cfg.TMI)
+	}
+	if cfg.DB.Pool != 4 || cfg.DB.BusyTimeout != 2.5 {
+		t.Errorf("wrong numbers: %+v", cfg.DB)
+	}
+	if !slices.Equal(cfg.Global.Ignore, []string{"moobot", "nightbot"}) {
+		t.Errorf("wrong list: %q", cfg.Global.Ignore)
+	}
+	if cfg.HTTP.Listen != ":4959" {
+		t.Errorf("wrong listen address: %q", cfg.HTTP.Listen)
+	}
+	ch := cfg.Twitch["env"]
+	if ch == nil {
+		t.Fatalf("no channels: %v", cfg.Twitch)
+	}
+	if !slices.Equal(ch.Channels, []string{"#bocchi", "#ryo"}) || ch.Learn != "kessoku" || ch.Rate.Every != 10 || !ch.Leaderboard {
+		t.Errorf("wrong channel config: %+v", ch)
+	}
+	if ch.Rate.Num != 4 || ch.Responses != 0.1 {
+		t.Errorf("profile not applied: %+v", ch)
+	}
+	if cfg.SecretFile != "/data/secret.key" || cfg.DB.SQLBrain != "file:/data/robot.db" || cfg.DB.Privacy != "file:/data/robot.db" {
+		t.Errorf("wrong default paths: %q %+v", cfg.SecretFile, cfg.DB)
+	}
+	if cfg.TMI.TokenFile != "/data/twitch.token" {
+		t.Errorf("wrong token file: %q", cfg.TMI.TokenFile)
+	}
+}
+
+func TestLoadEnvDefaults(t *testing.T) {
+	cfg, err := LoadEnv(func(s string) string { return "" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SecretFile != "/var/lib/robot/secret.key" {
+		t.Errorf("wrong secret file: %q", cfg.SecretFile)
+	}
+	if cfg.Twitch != nil || cfg.TMI.TokenFile != "" {
+		t.Errorf("twitch configured without env: %+v %+v", cfg.Twitch, cfg.TMI)
+	}
+}
+
+func TestLoadEnvBad(t *testing.T) {
+	_, err := LoadEnv(func(s string) string {
+		if s == "ROBOT_DB_POOL" {
+			return "many"
+		}
+		return ""
+	})
+	if err == nil {
+		t.Error("bad number wasn't an error")
+	}
+}
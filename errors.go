@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/zephyrtronium/robot/fault"
+)
+
+// knownTag checks that some channel or poster uses a tag.
+// If none does, it returns an error wrapping [fault.ErrTagUnknown].
+func (robo *Robot) knownTag(tag string) error {
+	for _, ch := range robo.channels.All() {
+		if tag == ch.Learn || tag == ch.Send || slices.Contains(ch.Tags, tag) {
+			return nil
+		}
+	}
+	for _, p := range robo.posters {
+		if tag == p.tag {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", fault.ErrTagUnknown, tag)
+}
+
+// errorStatus maps an error to the HTTP status code describing it.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, fault.ErrTagUnknown):
+		return http.StatusNotFound
+	case errors.Is(err, fault.ErrNotPermitted):
+		return http.StatusForbidden
+	case errors.Is(err, fault.ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, fault.ErrBrainUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// httpError responds to a request with an error and the status describing it.
+// Only unexpected errors are logged.
+func httpError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	code := errorStatus(err)
+	if code == http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), msg, slog.Any("err", err))
+	}
+	http.Error(w, err.Error(), code)
+}
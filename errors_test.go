@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/zephyrtronium/robot/brain/breaker"
+	"github.com/zephyrtronium/robot/channel"
+	"github.com/zephyrtronium/robot/fault"
+)
+
+func TestErrorStatus(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"tag", fmt.Errorf("%w: bocchi", fault.ErrTagUnknown), http.StatusNotFound},
+		{"permission", fault.ErrNotPermitted, http.StatusForbidden},
+		{"rate", fmt.Errorf("a: %w", fault.ErrRateLimited), http.StatusTooManyRequests},
+		{"breaker", breaker.ErrOpen, http.StatusServiceUnavailable},
+		{"other", errors.New("ryo"), http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := errorStatus(c.err); got != c.want {
+				t.Errorf("wrong status: want %d, got %d", c.want, got)
+			}
+		})
+	}
+}
+
+func TestKnownTag(t *testing.T) {
+	robo := New(1)
+	robo.channels.Store("#bocchi", &channel.Channel{
+		Name:  "#bocchi",
+		Learn: "kessoku",
+		Send:  "band",
+		Tags:  []string{"sick hack"},
+	})
+	for _, tag := range []string{"kessoku", "band", "sick hack"} {
+		if err := robo.knownTag(tag); err != nil {
+			t.Errorf("%q should be known: %v", tag, err)
+		}
+	}
+	if err := robo.knownTag("starry"); !errors.Is(err, fault.ErrTagUnknown) {
+		t.Errorf("wrong error for unknown tag: %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/zephyrtronium/robot/eventsub"
+	"github.com/zephyrtronium/robot/locale"
+	"github.com/zephyrtronium/robot/twitch"
+)
+
+// channelEventTypes maps the names of channel events in configs to their
+// EventSub subscription types.
+var channelEventTypes = map[string]string{
+	"raid":      eventsub.TypeRaid,
+	"follow":    eventsub.TypeFollow,
+	"subscribe": eventsub.TypeSubscribe,
+	"redeem":    eventsub.TypeRedeem,
+}
+
+// channelEvents parses the events a channel acknowledges.
+func channelEvents(names []string) (map[string]bool, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	r := make(map[string]bool, len(names))
+	for _, nm := range names {
+		if _, ok := channelEventTypes[nm]; !ok {
+			return nil, fmt.Errorf("unknown event %q; want one of %v", nm, slices.Sorted(maps.Keys(channelEventTypes)))
+		}
+		r[nm] = true
+	}
+	return r, nil
+}
+
+// runEventSub receives Twitch channel events until ctx is canceled,
+// reconnecting when the connection fails.
+func (robo *Robot) runEventSub(ctx context.Context) error {
+	for {
+		err := robo.eventSub(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		slog.ErrorContext(ctx, "EventSub failed", slog.Any("err", err))
+		// Every connection creates its subscriptions again, so don't hurry.
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(30 * time.Second):
+		}
+	}
+}
+
+// eventSub connects to EventSub and subscribes to the events each Twitch
+// channel acknowledges.
+func (robo *Robot) eventSub(ctx context.Context) error {
+	tc := robo.twitch
+	tok, err := tc.tmi.tokens.Token(ctx)
+	if err != nil {
+		return err
+	}
+	ids, err := robo.twitchIDs(ctx, tok)
+	if err != nil {
+		return err
+	}
+	m := &eventsub.Mux{
+		Revoked: func(ctx context.Context, typ, status string) {
+			slog.WarnContext(ctx, "Twitch revoked EventSub subscription", slog.String("type", typ), slog.String("status", status))
+		},
+	}
+	for _, name := range tc.channels {
+		if id := ids[name]; id != "" {
+			m.Handle(id, &twitchEvents{robo: robo, channel: name})
+		}
+	}
+	subscribe := func(ctx context.Context, session string) error {
+		n := 0
+		for _, name := range tc.channels {
+			ch, _ := robo.channels.Load(name)
+			id := ids[name]
+			if ch == nil || id == "" {
+				continue
+			}
+			for _, s := range eventsub.Subscriptions(id, tc.tmi.userID) {
+				if !ch.Events[eventName(s.Type)] {
+					continue
+				}
+				err := twitch.Subscribe(ctx, tc.api, tok, s.Type, s.Version, s.Condition, session)
+				switch {
+				case err == nil:
+					n++
+				case errors.Is(err, twitch.ErrNeedRefresh):
+					if _, err := tc.tmi.tokens.Refresh(ctx, tok); err != nil {
+						return fmt.Errorf("couldn't refresh token: %w", err)
+					}
+					return err
+				default:
+					// Subscriptions and redemptions need the broadcaster's
+					// own token, so this is expected in channels the bot
+					// doesn't own.
+					slog.WarnContext(ctx, "couldn't subscribe to Twitch event", slog.String("in", name), slog.String("type", s.Type), slog.Any("err", err))
+				}
+			}
+		}
+		slog.InfoContext(ctx, "EventSub connected", slog.String("session", session), slog.Int("subscriptions", n))
+		return nil
+	}
+	return eventsub.Listen(ctx, "", m, subscribe)
+}
+
+// eventName returns the config name of a subscription type.
+func eventName(typ string) string {
+	for k, v := range channelEventTypes {
+		if v == typ {
+			return k
+		}
+	}
+	return ""
+}
+
+// twitchEvents acknowledges the events of one Twitch channel.
+type twitchEvents struct {
+	robo    *Robot
+	channel string
+}
+
+var _ eventsub.Handler = (*twitchEvents)(nil)
+
+// ack sends the template for an event to the channel, if it acknowledges the
+// event and its rate limit allows.
+func (e *twitchEvents) ack(ctx context.Context, event string, args locale.Args) {
+	slog.InfoContext(ctx, "Twitch event", slog.String("in", e.channel), slog.String("event", event), slog.Any("args", args))
+	ch, _ := e.robo.channels.Load(e.channel)
+	if ch == nil || !ch.Events[event] {
+		return
+	}
+	if _, err := ch.Reserve(time.Now()); err != nil {
+		slog.InfoContext(ctx, "not acknowledging event", slog.String("in", e.channel), slog.String("event", event), slog.Any("err", err))
+		return
+	}
+	args["Emote"] = ch.Emotes.Pick(rand.Uint32())
+	ch.Message(ctx, "", ch.Templates.Text("event-"+event, args))
+}
+
+func (e *twitchEvents) Raid(ctx context.Context, ev *eventsub.Raid) {
+	e.ack(ctx, "raid", locale.Args{"Name": ev.FromBroadcasterUserName, "Viewers": ev.Viewers})
+}
+
+func (e *twitchEvents) Follow(ctx context.Context, ev *eventsub.Follow) {
+	e.ack(ctx, "follow", locale.Args{"Name": ev.UserName})
+}
+
+func (e *twitchEvents) Subscribe(ctx context.Context, ev *eventsub.Subscribe) {
+	tier, _ := strconv.Atoi(ev.Tier)
+	e.ack(ctx, "subscribe", locale.Args{"Name": ev.UserName, "Tier": tier / 1000, "Gift": ev.IsGift})
+}
+
+func (e *twitchEvents) Redeem(ctx context.Context, ev *eventsub.Redemption) {
+	e.ack(ctx, "redeem", locale.Args{"Name": ev.UserName, "Reward": ev.Reward.Title, "Input": ev.UserInput})
+}
+
+// twitchIDs gets the user IDs of the broadcasters of the Twitch channels,
+// keyed by channel name.
+func (robo *Robot) twitchIDs(ctx context.Context, tok *oauth2.Token) (map[string]string, error) {
+	tc := robo.twitch
+	users := make([]twitch.User, 0, len(tc.channels))
+	for _, name := range tc.channels {
+		users = append(users, twitch.User{Login: strings.TrimPrefix(name, "#")})
+	}
+	byLogin := make(map[string]string, len(users))
+	for u := range slices.Chunk(users, 100) {
+		r, err := twitch.Users(ctx, tc.api, tok, u)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't get broadcaster IDs: %w", err)
+		}
+		for _, v := range r {
+			byLogin["#"+strings.ToLower(v.Login)] = v.ID
+		}
+	}
+	ids := make(map[string]string, len(tc.channels))
+	for _, name := range tc.channels {
+		ids[name] = byLogin[strings.ToLower(name)]
+	}
+	return ids, nil
+}
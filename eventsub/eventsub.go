@@ -0,0 +1,175 @@
+// Package eventsub implements a client for Twitch EventSub over websockets,
+// dispatching the events Robot reacts to by channel.
+package eventsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Subscription types.
+const (
+	TypeRaid      = "channel.raid"
+	TypeFollow    = "channel.follow"
+	TypeSubscribe = "channel.subscribe"
+	TypeRedeem    = "channel.channel_points_custom_reward_redemption.add"
+)
+
+// Subscription is an EventSub subscription to create.
+type Subscription struct {
+	Type      string
+	Version   string
+	Condition map[string]string
+}
+
+// Subscriptions returns the subscriptions for every event a [Handler]
+// handles in a channel. broadcaster is the channel's user ID, and bot is the
+// user ID of the bot, which must be a moderator in the channel to receive
+// follows. Subscriptions and redemptions can only be received with the
+// broadcaster's own token.
+func Subscriptions(broadcaster, bot string) []Subscription {
+	return []Subscription{
+		{Type: TypeRaid, Version: "1", Condition: map[string]string{"to_broadcaster_user_id": broadcaster}},
+		{Type: TypeFollow, Version: "2", Condition: map[string]string{"broadcaster_user_id": broadcaster, "moderator_user_id": bot}},
+		{Type: TypeSubscribe, Version: "1", Condition: map[string]string{"broadcaster_user_id": broadcaster}},
+		{Type: TypeRedeem, Version: "1", Condition: map[string]string{"broadcaster_user_id": broadcaster}},
+	}
+}
+
+// Raid is a channel.raid event.
+type Raid struct {
+	FromBroadcasterUserID    string `json:"from_broadcaster_user_id"`
+	FromBroadcasterUserLogin string `json:"from_broadcaster_user_login"`
+	FromBroadcasterUserName  string `json:"from_broadcaster_user_name"`
+	ToBroadcasterUserID      string `json:"to_broadcaster_user_id"`
+	ToBroadcasterUserLogin   string `json:"to_broadcaster_user_login"`
+	ToBroadcasterUserName    string `json:"to_broadcaster_user_name"`
+	Viewers                  int    `json:"viewers"`
+}
+
+// Follow is a channel.follow event.
+type Follow struct {
+	UserID               string    `json:"user_id"`
+	UserLogin            string    `json:"user_login"`
+	UserName             string    `json:"user_name"`
+	BroadcasterUserID    string    `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string    `json:"broadcaster_user_login"`
+	BroadcasterUserName  string    `json:"broadcaster_user_name"`
+	FollowedAt           time.Time `json:"followed_at"`
+}
+
+// Subscribe is a channel.subscribe event.
+type Subscribe struct {
+	UserID               string `json:"user_id"`
+	UserLogin            string `json:"user_login"`
+	UserName             string `json:"user_name"`
+	BroadcasterUserID    string `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string `json:"broadcaster_user_login"`
+	BroadcasterUserName  string `json:"broadcaster_user_name"`
+	// Tier is the subscription tier: 1000, 2000, or 3000.
+	Tier   string `json:"tier"`
+	IsGift bool   `json:"is_gift"`
+}
+
+// Redemption is a channel.channel_points_custom_reward_redemption.add event.
+type Redemption struct {
+	ID                   string `json:"id"`
+	UserID               string `json:"user_id"`
+	UserLogin            string `json:"user_login"`
+	UserName             string `json:"user_name"`
+	BroadcasterUserID    string `json:"broadcaster_user_id"`
+	BroadcasterUserLogin string `json:"broadcaster_user_login"`
+	BroadcasterUserName  string `json:"broadcaster_user_name"`
+	// UserInput is the text the user entered, if the reward asks for any.
+	UserInput string `json:"user_input"`
+	Reward    struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+		Cost  int    `json:"cost"`
+	} `json:"reward"`
+	RedeemedAt time.Time `json:"redeemed_at"`
+}
+
+// Handler handles the events of one channel.
+type Handler interface {
+	// Raid handles another channel raiding this one.
+	Raid(ctx context.Context, ev *Raid)
+	// Follow handles a user following the channel.
+	Follow(ctx context.Context, ev *Follow)
+	// Subscribe handles a user subscribing to the channel.
+	Subscribe(ctx context.Context, ev *Subscribe)
+	// Redeem handles a user redeeming a channel points reward.
+	Redeem(ctx context.Context, ev *Redemption)
+}
+
+// Mux dispatches events to handlers by channel.
+type Mux struct {
+	// Revoked is called when Twitch revokes a subscription, e.g. because
+	// the user revoked the bot's authorization. status is the reason.
+	// It may be nil.
+	Revoked func(ctx context.Context, typ, status string)
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+}
+
+// Handle sets the handler for events in the channel with the given
+// broadcaster user ID, replacing any previous one.
+func (m *Mux) Handle(broadcaster string, h Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.handlers == nil {
+		m.handlers = make(map[string]Handler)
+	}
+	m.handlers[broadcaster] = h
+}
+
+func (m *Mux) handler(broadcaster string) Handler {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.handlers[broadcaster]
+}
+
+// Dispatch decodes an event of a subscription type and calls the handler for
+// its channel. Events of other types and in channels without handlers are
+// ignored.
+func (m *Mux) Dispatch(ctx context.Context, typ string, event json.RawMessage) error {
+	switch typ {
+	case TypeRaid:
+		var ev Raid
+		if err := json.Unmarshal(event, &ev); err != nil {
+			return fmt.Errorf("couldn't decode %s event: %w", typ, err)
+		}
+		if h := m.handler(ev.ToBroadcasterUserID); h != nil {
+			h.Raid(ctx, &ev)
+		}
+	case TypeFollow:
+		var ev Follow
+		if err := json.Unmarshal(event, &ev); err != nil {
+			return fmt.Errorf("couldn't decode %s event: %w", typ, err)
+		}
+		if h := m.handler(ev.BroadcasterUserID); h != nil {
+			h.Follow(ctx, &ev)
+		}
+	case TypeSubscribe:
+		var ev Subscribe
+		if err := json.Unmarshal(event, &ev); err != nil {
+			return fmt.Errorf("couldn't decode %s event: %w", typ, err)
+		}
+		if h := m.handler(ev.BroadcasterUserID); h != nil {
+			h.Subscribe(ctx, &ev)
+		}
+	case TypeRedeem:
+		var ev Redemption
+		if err := json.Unmarshal(event, &ev); err != nil {
+			return fmt.Errorf("couldn't decode %s event: %w", typ, err)
+		}
+		if h := m.handler(ev.BroadcasterUserID); h != nil {
+			h.Redeem(ctx, &ev)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package eventsub_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/zephyrtronium/robot/eventsub"
+)
+
+// spy records the events it handles.
+type spy struct {
+	mu  sync.Mutex
+	got []string
+}
+
+func (s *spy) add(ev string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.got = append(s.got, ev)
+}
+
+func (s *spy) events() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.got...)
+}
+
+func (s *spy) Raid(ctx context.Context, ev *eventsub.Raid) {
+	s.add(fmt.Sprintf("raid %s %d", ev.FromBroadcasterUserName, ev.Viewers))
+}
+
+func (s *spy) Follow(ctx context.Context, ev *eventsub.Follow) {
+	s.add(fmt.Sprintf("follow %s %d", ev.UserName, ev.FollowedAt.Year()))
+}
+
+func (s *spy) Subscribe(ctx context.Context, ev *eventsub.Subscribe) {
+	s.add(fmt.Sprintf("subscribe %s %s %t", ev.UserName, ev.Tier, ev.IsGift))
+}
+
+func (s *spy) Redeem(ctx context.Context, ev *eventsub.Redemption) {
+	s.add(fmt.Sprintf("redeem %s %s %q", ev.UserName, ev.Reward.Title, ev.UserInput))
+}
+
+func testEvents(t *testing.T) map[string]json.RawMessage {
+	t.Helper()
+	b, err := os.ReadFile("testdata/events.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r map[string]json.RawMessage
+	if err := json.Unmarshal(b, &r); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestDispatch(t *testing.T) {
+	ctx := context.Background()
+	events := testEvents(t)
+	bocchi, other := new(spy), new(spy)
+	var m eventsub.Mux
+	m.Handle("1", bocchi)
+	m.Handle("2", other)
+	for _, typ := range []string{eventsub.TypeRaid, eventsub.TypeFollow, eventsub.TypeSubscribe, eventsub.TypeRedeem, "channel.update"} {
+		if err := m.Dispatch(ctx, typ, events[typ]); err != nil {
+			t.Errorf("couldn't dispatch %s: %v", typ, err)
+		}
+	}
+	want := []string{
+		"raid Kikuri 9001",
+		"follow Nijika 2024",
+		"subscribe Ryo 1000 true",
+		`redeem Kita Song request "play guitar"`,
+	}
+	if got := bocchi.events(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("wrong events:\nwant %q\ngot  %q", want, got)
+	}
+	if got := other.events(); len(got) != 0 {
+		t.Errorf("events went to the wrong channel: %q", got)
+	}
+	if err := m.Dispatch(ctx, eventsub.TypeRaid, json.RawMessage(`[]`)); err == nil {
+		t.Error("no error for bad event")
+	}
+}
+
+func TestSubscriptions(t *testing.T) {
+	subs := eventsub.Subscriptions("1", "100")
+	if len(subs) != 4 {
+		t.Fatalf("wrong number of subscriptions: %+v", subs)
+	}
+	for _, s := range subs {
+		switch s.Type {
+		case eventsub.TypeRaid:
+			if s.Condition["to_broadcaster_user_id"] != "1" {
+				t.Errorf("wrong raid condition: %v", s.Condition)
+			}
+		case eventsub.TypeFollow:
+			if s.Version != "2" || s.Condition["moderator_user_id"] != "100" {
+				t.Errorf("wrong follow subscription: %+v", s)
+			}
+		default:
+			if s.Condition["broadcaster_user_id"] != "1" {
+				t.Errorf("wrong %s condition: %v", s.Type, s.Condition)
+			}
+		}
+	}
+}
+
+func welcome(id string) map[string]any {
+	return map[string]any{
+		"metadata": map[string]any{"message_id": "w-" + id, "message_type": "session_welcome"},
+		"payload":  map[string]any{"session": map[string]any{"id": id, "keepalive_timeout_seconds": 10}},
+	}
+}
+
+func notification(id, typ string, event json.RawMessage) map[string]any {
+	return map[string]any{
+		"metadata": map[string]any{"message_id": id, "message_type": "notification", "subscription_type": typ},
+		"payload": map[string]any{
+			"subscription": map[string]any{"type": typ, "status": "enabled"},
+			"event":        event,
+		},
+	}
+}
+
+func TestListen(t *testing.T) {
+	events := testEvents(t)
+	var srv *httptest.Server
+	srv = httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		send := func(v any) {
+			if err := websocket.JSON.Send(ws, v); err != nil {
+				t.Errorf("couldn't send: %v", err)
+			}
+		}
+		switch ws.Request().URL.Path {
+		case "/ws":
+			send(welcome("s1"))
+			// Keepalives are ignored, and duplicates are dropped.
+			send(map[string]any{"metadata": map[string]any{"message_id": "k", "message_type": "session_keepalive"}, "payload": map[string]any{}})
+			send(notification("n1", eventsub.TypeRaid, events[eventsub.TypeRaid]))
+			send(notification("n1", eventsub.TypeRaid, events[eventsub.TypeRaid]))
+			send(map[string]any{
+				"metadata": map[string]any{"message_id": "r", "message_type": "session_reconnect"},
+				"payload":  map[string]any{"session": map[string]any{"id": "s1", "reconnect_url": "ws" + strings.TrimPrefix(srv.URL, "http") + "/moved"}},
+			})
+			// Wait for the client to close this connection.
+			var v any
+			websocket.JSON.Receive(ws, &v)
+		case "/moved":
+			send(welcome("s2"))
+			send(notification("n2", eventsub.TypeFollow, events[eventsub.TypeFollow]))
+			send(map[string]any{
+				"metadata": map[string]any{"message_id": "v", "message_type": "revocation"},
+				"payload":  map[string]any{"subscription": map[string]any{"type": eventsub.TypeSubscribe, "status": "authorization_revoked"}},
+			})
+		}
+	}))
+	defer srv.Close()
+	h := new(spy)
+	var m eventsub.Mux
+	m.Handle("1", h)
+	var revoked []string
+	m.Revoked = func(ctx context.Context, typ, status string) { revoked = append(revoked, typ+" "+status) }
+	var sessions []string
+	subscribe := func(ctx context.Context, session string) error {
+		sessions = append(sessions, session)
+		return nil
+	}
+	err := eventsub.Listen(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", &m, subscribe)
+	if err == nil {
+		t.Error("no error when connection closed")
+	}
+	if len(sessions) != 1 || sessions[0] != "s1" {
+		t.Errorf("wrong subscriptions: %q", sessions)
+	}
+	want := []string{"raid Kikuri 9001", "follow Nijika 2024"}
+	if got := h.events(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("wrong events:\nwant %q\ngot  %q", want, got)
+	}
+	if len(revoked) != 1 || revoked[0] != "channel.subscribe authorization_revoked" {
+		t.Errorf("wrong revocations: %q", revoked)
+	}
+}
+
+func TestListenSubscribeFails(t *testing.T) {
+	srv := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		websocket.JSON.Send(ws, welcome("s1"))
+		var v any
+		websocket.JSON.Receive(ws, &v)
+	}))
+	defer srv.Close()
+	want := fmt.Errorf("bocchi")
+	err := eventsub.Listen(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http"), new(eventsub.Mux), func(ctx context.Context, session string) error { return want })
+	if err != want {
+		t.Errorf("wrong error: want %v, got %v", want, err)
+	}
+}
@@ -0,0 +1,161 @@
+package eventsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// URL is the EventSub websocket URL.
+const URL = "wss://eventsub.wss.twitch.tv/ws"
+
+// message is a websocket message from EventSub.
+type message struct {
+	Metadata struct {
+		MessageID   string `json:"message_id"`
+		MessageType string `json:"message_type"`
+	} `json:"metadata"`
+	Payload struct {
+		Session *struct {
+			ID                      string `json:"id"`
+			KeepaliveTimeoutSeconds int    `json:"keepalive_timeout_seconds"`
+			ReconnectURL            string `json:"reconnect_url"`
+		} `json:"session"`
+		Subscription *struct {
+			Type   string `json:"type"`
+			Status string `json:"status"`
+		} `json:"subscription"`
+		Event json.RawMessage `json:"event"`
+	} `json:"payload"`
+}
+
+// session is a websocket connection to EventSub which may move to another
+// server.
+type session struct {
+	mu sync.Mutex
+	ws *websocket.Conn
+	// keepalive is how long to wait for a message before deciding the
+	// connection is dead.
+	keepalive time.Duration
+}
+
+// dial connects to EventSub and waits for the welcome message.
+func dial(u string) (*websocket.Conn, *message, error) {
+	ws, err := websocket.Dial(u, "", "https://www.twitch.tv")
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't connect to EventSub: %w", err)
+	}
+	ws.SetReadDeadline(time.Now().Add(30 * time.Second))
+	var welcome message
+	if err := websocket.JSON.Receive(ws, &welcome); err != nil {
+		ws.Close()
+		return nil, nil, fmt.Errorf("couldn't read welcome: %w", err)
+	}
+	if welcome.Metadata.MessageType != "session_welcome" || welcome.Payload.Session == nil {
+		ws.Close()
+		return nil, nil, fmt.Errorf("EventSub didn't welcome: %s", welcome.Metadata.MessageType)
+	}
+	return ws, &welcome, nil
+}
+
+// use switches the session to a new connection, closing the old one.
+func (s *session) use(ws *websocket.Conn, welcome *message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ws != nil {
+		s.ws.Close()
+	}
+	s.ws = ws
+	// Twitch sends a keepalive whenever the connection has been quiet this
+	// long, so allow a little more for latency.
+	s.keepalive = time.Duration(welcome.Payload.Session.KeepaliveTimeoutSeconds)*time.Second + 5*time.Second
+}
+
+func (s *session) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ws.Close()
+}
+
+func (s *session) receive(msg *message) error {
+	s.mu.Lock()
+	ws, k := s.ws, s.keepalive
+	s.mu.Unlock()
+	ws.SetReadDeadline(time.Now().Add(k))
+	return websocket.JSON.Receive(ws, msg)
+}
+
+// Listen connects to EventSub at u, or [URL] if it is empty, and dispatches
+// notifications to m until ctx is canceled or the connection fails.
+// Once connected, it calls subscribe with the session ID to create
+// subscriptions. Subscriptions belong to the session, so callers should
+// reconnect by calling Listen again, but they follow the session when Twitch
+// moves it to another server.
+func Listen(ctx context.Context, u string, m *Mux, subscribe func(ctx context.Context, session string) error) error {
+	if u == "" {
+		u = URL
+	}
+	ws, welcome, err := dial(u)
+	if err != nil {
+		return err
+	}
+	s := new(session)
+	s.use(ws, welcome)
+	defer s.close()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		s.close()
+	}()
+	if err := subscribe(ctx, welcome.Payload.Session.ID); err != nil {
+		return err
+	}
+	// Twitch may send a notification more than once. Remember recent
+	// message IDs to drop duplicates.
+	var recent [64]string
+	var next int
+	for {
+		var msg message
+		if err := s.receive(&msg); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("couldn't read from EventSub: %w", err)
+		}
+		switch msg.Metadata.MessageType {
+		case "notification":
+			id := msg.Metadata.MessageID
+			if id != "" && slices.Contains(recent[:], id) {
+				continue
+			}
+			recent[next] = id
+			next = (next + 1) % len(recent)
+			if msg.Payload.Subscription == nil {
+				continue
+			}
+			if err := m.Dispatch(ctx, msg.Payload.Subscription.Type, msg.Payload.Event); err != nil {
+				return err
+			}
+		case "session_reconnect":
+			if msg.Payload.Session == nil || msg.Payload.Session.ReconnectURL == "" {
+				return errors.New("EventSub asked to reconnect without a URL")
+			}
+			ws, welcome, err := dial(msg.Payload.Session.ReconnectURL)
+			if err != nil {
+				return err
+			}
+			s.use(ws, welcome)
+		case "revocation":
+			if sub := msg.Payload.Subscription; sub != nil && m.Revoked != nil {
+				m.Revoked(ctx, sub.Type, sub.Status)
+			}
+		}
+	}
+}
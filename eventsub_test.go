@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/time/rate"
+
+	"github.com/zephyrtronium/robot/channel"
+	"github.com/zephyrtronium/robot/eventsub"
+)
+
+func TestChannelEvents(t *testing.T) {
+	got, err := channelEvents([]string{"raid", "redeem"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || !got["raid"] || !got["redeem"] {
+		t.Errorf("wrong events: %v", got)
+	}
+	if _, err := channelEvents([]string{"raid", "host"}); err == nil {
+		t.Error("no error for unknown event")
+	}
+	for nm, typ := range channelEventTypes {
+		if eventName(typ) != nm {
+			t.Errorf("wrong name for %s: want %q, got %q", typ, nm, eventName(typ))
+		}
+	}
+}
+
+func TestTwitchEvents(t *testing.T) {
+	ctx := context.Background()
+	robo := New(1)
+	var sent []string
+	ch := &channel.Channel{
+		Name:    "#bocchi",
+		Events:  map[string]bool{"raid": true},
+		Rate:    rate.NewLimiter(rate.Inf, 1),
+		Emotes:  channel.NewEmotes(map[string]int{"bocchiSpin": 1}),
+		Message: func(ctx context.Context, reply, text string) { sent = append(sent, text) },
+	}
+	robo.channels.Store("#bocchi", ch)
+	e := &twitchEvents{robo: robo, channel: "#bocchi"}
+	e.Raid(ctx, &eventsub.Raid{FromBroadcasterUserName: "Kikuri", Viewers: 50})
+	e.Follow(ctx, &eventsub.Follow{UserName: "Nijika"})
+	if len(sent) != 1 || sent[0] != "Welcome, raiders from Kikuri! bocchiSpin" {
+		t.Errorf("wrong acknowledgements: %q", sent)
+	}
+	// Acknowledgements respect the rate limit.
+	ch.Rate = rate.NewLimiter(0, 0)
+	e.Raid(ctx, &eventsub.Raid{FromBroadcasterUserName: "Seika"})
+	if len(sent) != 1 {
+		t.Errorf("acknowledged while rate limited: %q", sent)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/spoken"
+)
+
+// explanation is the attribution of a generated message to the learned
+// messages that formed it.
+type explanation struct {
+	Message  string            `json:"message,omitempty"`
+	Trace    []string          `json:"trace"`
+	Segments []brain.Segment   `json:"segments,omitempty"`
+	Sources  map[string]string `json:"sources"`
+}
+
+// explain attributes a generated message to learned messages.
+// If trace is empty, it is found from the spoken history by the message text.
+// If msg is empty, only the sources are recalled.
+func explain(ctx context.Context, br brain.Brain, sp *spoken.History, tag, msg string, trace []string) (*explanation, error) {
+	rc, ok := brain.As[brain.Recaller](br)
+	if !ok {
+		return nil, errors.New("brain does not support recalling messages")
+	}
+	if len(trace) == 0 {
+		var err error
+		trace, _, err = sp.Trace(ctx, tag, msg)
+		if err != nil {
+			return nil, err
+		}
+		if trace == nil {
+			return nil, fmt.Errorf("no record of generating %q from %s", msg, tag)
+		}
+	}
+	src, err := brain.Recall(ctx, rc, tag, trace)
+	if err != nil {
+		return nil, err
+	}
+	x := explanation{Message: msg, Trace: trace, Sources: src}
+	if msg != "" {
+		x.Segments = brain.Explain(msg, src)
+	}
+	return &x, nil
+}
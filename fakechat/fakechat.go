@@ -0,0 +1,170 @@
+// Package fakechat synthesizes chat messages for developing and benchmarking
+// without real chat data.
+package fakechat
+
+import (
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is a synthesized chat message.
+type Message struct {
+	// ID is a unique message ID.
+	ID string
+	// User is the sender's user ID and Name is their username.
+	User, Name string
+	// Text is the message text.
+	Text string
+	// Time is when the message was sent.
+	Time time.Time
+}
+
+// Style is a flavor of chat.
+type Style struct {
+	// Words is the vocabulary, roughly in order of how common each word is.
+	Words []string
+	// Emotes is the emotes chatters use.
+	Emotes []string
+	// Length is the typical number of words in a message.
+	Length int
+	// Emote is the probability that each word is instead an emote.
+	Emote float64
+	// Burst is the probability that a message starts a copypasta burst, in
+	// which several chatters repeat a message.
+	Burst float64
+	// Gap is the mean time between messages.
+	Gap time.Duration
+}
+
+// Styles is the available chat styles by name.
+var Styles = map[string]*Style{
+	"casual": {
+		Words: strings.Fields(`i the you it is a to and that this lol
+			what so like just no yeah was he she they we do not my of in
+			game good play chat going oh wait why how me have be are can
+			one guy think know really bro that's it's don't nice time
+			gonna right now got back here get gg boss again with for
+			stream today music song love guitar band favorite part
+			actually literally honestly maybe probably never always
+			again ok okay sure true real based cursed crazy insane`),
+		Emotes: []string{"Kappa", "LUL", "PogChamp", "KEKW", "monkaS", "Sadge", "catJAM", "<3"},
+		Length: 7,
+		Emote:  0.06,
+		Burst:  0.01,
+		Gap:    2 * time.Second,
+	},
+	"hype": {
+		Words: strings.Fields(`LETS GO POG HYPE W GG CLUTCH NO WAY
+			OMG YES insane clip that it chat we go let's huge`),
+		Emotes: []string{"PogChamp", "POGGERS", "KEKW", "LUL", "Kreygasm", "EZ", "catJAM"},
+		Length: 3,
+		Emote:  0.3,
+		Burst:  0.05,
+		Gap:    300 * time.Millisecond,
+	},
+	"quiet": {
+		Words: strings.Fields(`hello hi how is everyone doing today
+			i am just working on some things listening to the stream
+			nice that sounds good thanks for the stream see you later
+			what are you playing it looks fun have a good night`),
+		Emotes: []string{":)", "<3", "Kappa"},
+		Length: 9,
+		Emote:  0.02,
+		Burst:  0.002,
+		Gap:    20 * time.Second,
+	},
+}
+
+// Generator synthesizes a stream of chat messages.
+type Generator struct {
+	style *Style
+	rng   *rand.Rand
+	// users is the number of distinct chatters.
+	users int
+	// now is the time of the last message.
+	now time.Time
+	// n counts messages for IDs.
+	n int
+	// burst is the copypasta being repeated and the number of repeats left.
+	burst string
+	left  int
+}
+
+// New creates a generator of messages in a style from a number of chatters,
+// with the first message just after start. The same seed produces the same
+// messages.
+func New(style *Style, users int, start time.Time, seed uint64) *Generator {
+	return &Generator{
+		style: style,
+		rng:   rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15)),
+		users: max(users, 1),
+		now:   start,
+	}
+}
+
+// Next synthesizes the next message.
+func (g *Generator) Next() Message {
+	g.n++
+	g.now = g.now.Add(time.Duration(g.rng.ExpFloat64() * float64(g.style.Gap)))
+	u := g.user()
+	m := Message{
+		ID:   "fake-" + strconv.Itoa(g.n),
+		User: strconv.Itoa(1000 + u),
+		Name: "chatter" + strconv.Itoa(u),
+		Time: g.now,
+	}
+	switch {
+	case g.left > 0:
+		g.left--
+		m.Text = g.burst
+	case g.rng.Float64() < g.style.Burst:
+		g.burst = g.text(3 * g.style.Length)
+		g.left = 3 + g.rng.IntN(8)
+		m.Text = g.burst
+	default:
+		m.Text = g.text(g.style.Length)
+	}
+	return m
+}
+
+// user picks a chatter. A few chatters send most messages.
+func (g *Generator) user() int {
+	// Zipf-like: squaring a uniform variate favors small indices.
+	x := g.rng.Float64()
+	return int(x * x * float64(g.users))
+}
+
+// text synthesizes message text of around n words.
+func (g *Generator) text(n int) string {
+	k := 1 + g.rng.IntN(2*n)
+	w := make([]string, 0, k)
+	for range k {
+		if g.rng.Float64() < g.style.Emote {
+			w = append(w, g.style.Emotes[g.rng.IntN(len(g.style.Emotes))])
+			continue
+		}
+		// Favor words early in the vocabulary.
+		x := g.rng.Float64()
+		w = append(w, g.style.Words[int(x*x*float64(len(g.style.Words)))])
+	}
+	return strings.Join(w, " ")
+}
+
+// Names returns the names of the available styles in sorted order.
+func Names() []string {
+	return slices.Sorted(maps.Keys(Styles))
+}
+
+// Lookup returns the style with the given name.
+func Lookup(name string) (*Style, error) {
+	s := Styles[name]
+	if s == nil {
+		return nil, fmt.Errorf("unknown chat style %q", name)
+	}
+	return s, nil
+}
@@ -0,0 +1,58 @@
+package fakechat_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/fakechat"
+)
+
+func TestGenerator(t *testing.T) {
+	for _, name := range fakechat.Names() {
+		t.Run(name, func(t *testing.T) {
+			style, err := fakechat.Lookup(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			start := time.Unix(1e9, 0)
+			a := fakechat.New(style, 50, start, 1)
+			b := fakechat.New(style, 50, start, 1)
+			ids := make(map[string]bool)
+			users := make(map[string]bool)
+			repeats := 0
+			last := start
+			prev := ""
+			for range 5000 {
+				m := a.Next()
+				if m != b.Next() {
+					t.Fatalf("same seed gave different messages")
+				}
+				if m.Text == "" || m.User == "" || m.Name == "" {
+					t.Fatalf("incomplete message: %+v", m)
+				}
+				if ids[m.ID] {
+					t.Fatalf("repeated ID %q", m.ID)
+				}
+				ids[m.ID] = true
+				users[m.User] = true
+				if m.Time.Before(last) {
+					t.Fatalf("time went backward: %v after %v", m.Time, last)
+				}
+				last = m.Time
+				if m.Text == prev {
+					repeats++
+				}
+				prev = m.Text
+			}
+			if len(users) < 10 {
+				t.Errorf("too few users: %d", len(users))
+			}
+			if repeats == 0 {
+				t.Errorf("no copypasta bursts")
+			}
+		})
+	}
+	if _, err := fakechat.Lookup("formal"); err == nil {
+		t.Error("no error for unknown style")
+	}
+}
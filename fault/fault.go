@@ -0,0 +1,22 @@
+// Package fault defines the kinds of errors that subsystems share, so that
+// callers can map failures to the right user-facing behavior with
+// [errors.Is] rather than matching error text.
+//
+// Subsystems wrap these errors with details, e.g.
+// fmt.Errorf("%w: circuit breaker is open", fault.ErrBrainUnavailable).
+package fault
+
+import "errors"
+
+var (
+	// ErrTagUnknown indicates that a tag is not one the bot uses.
+	ErrTagUnknown = errors.New("unknown tag")
+	// ErrRateLimited indicates that an action was refused because a rate
+	// limit is exhausted. Trying again later may succeed.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrBrainUnavailable indicates that the brain is temporarily unable to
+	// learn or speak, e.g. because it is failing or its database is locked.
+	ErrBrainUnavailable = errors.New("brain is unavailable")
+	// ErrNotPermitted indicates that the actor lacks permission for an action.
+	ErrNotPermitted = errors.New("not permitted")
+)
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/sqlbrain"
+	"github.com/zephyrtronium/robot/fakechat"
+	"github.com/zephyrtronium/robot/sqlpool"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+func cliGenCorpus(ctx context.Context, cmd *cli.Command) error {
+	style, err := fakechat.Lookup(cmd.String("style"))
+	if err != nil {
+		return err
+	}
+	file := cmd.String("db")
+	if file == "" {
+		dir, err := os.MkdirTemp("", "robot-corpus-")
+		if err != nil {
+			return fmt.Errorf("couldn't create directory for brain: %w", err)
+		}
+		file = filepath.Join(dir, "brain.sql")
+	}
+	db, err := sqlpool.Open(file, sqlpool.Options{}, sqlbrain.RecommendedPrep)
+	if err != nil {
+		return fmt.Errorf("couldn't open brain: %w", err)
+	}
+	defer db.Close()
+	br, err := sqlbrain.Open(ctx, db)
+	if err != nil {
+		return err
+	}
+	// A fresh key, since nobody real needs to be recognized later.
+	key := make([]byte, 32)
+	rand.Read(key)
+	hasher := userhash.New(key)
+	tag := cmd.String("tag")
+	n := int(cmd.Int("messages"))
+	g := fakechat.New(style, int(cmd.Int("users")), time.Now().Add(-time.Duration(n)*style.Gap), uint64(cmd.Int("seed")))
+	start := time.Now()
+	msgs := make([]brain.Message, 0, 500)
+	toks := make([][]string, 0, 500)
+	for i := range n {
+		m := g.Next()
+		var u userhash.Hash
+		hasher.Hash(&u, m.User, "#fake", m.Time)
+		msgs = append(msgs, brain.Message{Tag: tag, ID: m.ID, User: u, Time: m.Time})
+		toks = append(toks, brain.Tokens(nil, m.Text))
+		if len(msgs) == cap(msgs) || i == n-1 {
+			if err := brain.LearnBatch(ctx, br, msgs, toks); err != nil {
+				return fmt.Errorf("couldn't learn corpus: %w", err)
+			}
+			msgs, toks = msgs[:0], toks[:0]
+		}
+	}
+	fmt.Printf("learned %d %s messages under tag %s in %v\n", n, cmd.String("style"), tag, time.Since(start).Round(time.Millisecond))
+	fmt.Printf("sqlbrain at %s\n", file)
+	for range 3 {
+		s, _, err := brain.Speak(ctx, br, tag, "")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("sample: %s\n", s)
+	}
+	return nil
+}
@@ -6,14 +6,21 @@ require (
 	github.com/BurntSushi/toml v1.4.0
 	github.com/dgraph-io/badger/v4 v4.2.0
 	github.com/google/go-cmp v0.6.0
+	github.com/lib/pq v1.10.9
+	github.com/nats-io/nats.go v1.37.0
 	github.com/urfave/cli/v3 v3.0.0-alpha9
 	gitlab.com/zephyrtronium/pick v1.0.0
 	gitlab.com/zephyrtronium/tmi v0.0.0-20240325132202-7adf62e91c49
+	go.etcd.io/bbolt v1.4.3
 	golang.org/x/crypto v0.26.0
+	golang.org/x/net v0.28.0
 	golang.org/x/oauth2 v0.22.0
-	golang.org/x/sync v0.8.0
+	golang.org/x/sync v0.10.0
+	golang.org/x/sys v0.29.0
 	golang.org/x/text v0.17.0
 	golang.org/x/time v0.6.0
+	google.golang.org/grpc v1.66.3
+	google.golang.org/protobuf v1.34.2
 	gopkg.in/typ.v4 v4.3.0
 	zombiezen.com/go/sqlite v1.3.0
 )
@@ -31,14 +38,14 @@ require (
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/klauspost/compress v1.17.9 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/nats-io/nkeys v0.4.7 // indirect
+	github.com/nats-io/nuid v1.0.1 // indirect
 	github.com/ncruces/go-strftime v0.1.9 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
 	github.com/xrash/smetrics v0.0.0-20240521201337-686a1a2994c1 // indirect
 	go.opencensus.io v0.24.0 // indirect
-	golang.org/x/net v0.28.0 // indirect
-	golang.org/x/sys v0.24.0 // indirect
-	google.golang.org/protobuf v1.34.2 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240604185151-ef581f913117 // indirect
 	modernc.org/libc v1.58.0 // indirect
 	modernc.org/mathutil v1.6.0 // indirect
 	modernc.org/memory v1.8.0 // indirect
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/channel"
+	"github.com/zephyrtronium/robot/journal"
+	"github.com/zephyrtronium/robot/message"
+	"github.com/zephyrtronium/robot/platform"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// handler connects platform clients to the robot.
+type handler struct {
+	robo  *Robot
+	group *errgroup.Group
+}
+
+var _ platform.Handler = (*handler)(nil)
+
+// channel gets a configured channel that isn't halted.
+func (h *handler) channel(ctx context.Context, name string) *channel.Channel {
+	ch, _ := h.robo.channels.Load(name)
+	if ch == nil {
+		return nil
+	}
+	if ch.Halted.Load() {
+		slog.DebugContext(ctx, "message in halted channel", slog.String("in", ch.Name))
+		return nil
+	}
+	if diskLevel(h.robo.disk.Load()) >= diskPaused {
+		slog.DebugContext(ctx, "message while paused for disk space", slog.String("in", ch.Name))
+		return nil
+	}
+	return ch
+}
+
+// Message handles a chat message in a worker for the channel.
+func (h *handler) Message(ctx context.Context, c platform.Client, m *message.Incoming) {
+	ch := h.channel(ctx, m.To)
+	if ch == nil {
+		return
+	}
+	if !h.robo.ignored(ch, m) {
+		h.robo.relay(ctx, h.group, c, m)
+	}
+	_, name := c.Bot()
+	owner := c.Owner()
+	work := func(ctx context.Context) {
+		h.robo.chat(ctx, ch, name, owner, m)
+	}
+	h.robo.enqueueDroppable(ctx, h.group, ch, work)
+}
+
+// Delete forgets a deleted message.
+func (h *handler) Delete(ctx context.Context, c platform.Client, channel, id, text string, own bool) {
+	ch, _ := h.robo.channels.Load(channel)
+	if ch == nil {
+		return
+	}
+	robo := h.robo
+	work := func(ctx context.Context) {
+		op := brain.NewOp()
+		ctx = brain.WithOp(ctx, op)
+		if !own {
+			// Forget a message from someone else.
+			slog.InfoContext(ctx, "forget message", slog.String("channel", channel), slog.String("id", id), slog.String("op", op))
+			err := robo.brain.ForgetMessage(ctx, ch.Learn, id)
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to forget message",
+					slog.Any("err", err),
+					slog.String("channel", channel),
+					slog.String("tag", ch.Learn),
+					slog.String("id", id),
+				)
+			}
+			robo.recordOp(ctx, journal.Entry{
+				Op:      op,
+				Time:    time.Now(),
+				Actor:   c.Platform(),
+				Channel: channel,
+				Tag:     ch.Learn,
+				Action:  "forget-message",
+				Count:   robo.forgotten(ctx, op),
+				Params:  map[string]string{"id": id},
+			})
+			return
+		}
+		// Forget a message from the robo.
+		// This may or may not be a generated message; it could be a command
+		// output or copypasta. Regardless, if it was deleted, we should try
+		// not to say it.
+		// Note that we use the send tag rather than the learn tag for this,
+		// because we are unlearning something that we sent.
+		tag := ch.Send
+		trace, tm, err := robo.spoken.Trace(ctx, tag, text)
+		// Messages generated from other tags are recorded under those tags.
+		for _, other := range ch.Tags {
+			if err != nil || trace != nil {
+				break
+			}
+			tag = other
+			trace, tm, err = robo.spoken.Trace(ctx, tag, text)
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to get message trace",
+				slog.Any("err", err),
+				slog.String("channel", channel),
+				slog.String("tag", tag),
+				slog.String("id", id),
+			)
+			return
+		}
+		slog.InfoContext(ctx, "forget trace",
+			slog.String("channel", channel),
+			slog.String("tag", tag),
+			slog.Any("learned", tm),
+			slog.Any("trace", trace),
+			slog.String("op", op),
+		)
+		for _, id := range trace {
+			err := robo.brain.ForgetMessage(ctx, tag, id)
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to forget from trace",
+					slog.Any("err", err),
+					slog.String("channel", channel),
+					slog.String("tag", tag),
+					slog.String("id", id),
+				)
+			}
+		}
+		robo.recordOp(ctx, journal.Entry{
+			Op:      op,
+			Time:    time.Now(),
+			Actor:   c.Platform(),
+			Channel: channel,
+			Tag:     tag,
+			Action:  "forget-trace",
+			Count:   robo.forgotten(ctx, op),
+			Params:  map[string]string{"id": id},
+		})
+	}
+	robo.enqueue(ctx, h.group, ch, work)
+}
+
+// Clear forgets recent messages from a user or from everyone.
+func (h *handler) Clear(ctx context.Context, c platform.Client, channel, user string, t time.Time) {
+	ch, _ := h.robo.channels.Load(channel)
+	if ch == nil {
+		return
+	}
+	robo := h.robo
+	self, _ := c.Bot()
+	op := brain.NewOp()
+	var work func(ctx context.Context)
+	var action, tag string
+	switch user {
+	case "":
+		// Delete all recent chat.
+		action, tag = "clear-chat", ch.Learn
+		work = func(ctx context.Context) {
+			slog.InfoContext(ctx, "clear all chat", slog.String("channel", channel), slog.String("tag", tag), slog.String("op", op))
+			err := robo.brain.ForgetDuring(ctx, tag, t.Add(-15*time.Minute), t)
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to forget from all chat", slog.Any("err", err), slog.String("channel", channel))
+			}
+		}
+	case self:
+		// We use the send tag because we are forgetting something we sent.
+		action, tag = "forget-recent", ch.Send
+		work = func(ctx context.Context) {
+			slog.InfoContext(ctx, "forget recent generated", slog.String("channel", channel), slog.String("tag", tag), slog.String("op", op))
+			for id, err := range robo.spoken.Since(ctx, tag, t.Add(-15*time.Minute)) {
+				if err != nil {
+					slog.ErrorContext(ctx, "failed to get recent traces",
+						slog.Any("err", err),
+						slog.String("channel", channel),
+						slog.String("tag", tag),
+					)
+					continue
+				}
+				if err := robo.brain.ForgetMessage(ctx, tag, id); err != nil {
+					slog.ErrorContext(ctx, "failed to forget from recent trace",
+						slog.Any("err", err),
+						slog.String("channel", channel),
+						slog.String("tag", tag),
+						slog.String("id", id),
+					)
+				}
+			}
+		}
+	default:
+		// Delete from user.
+		// We use the user's current and previous userhash, since userhashes
+		// are time-based. Userhashes are the same across tags, so this
+		// forgets from every tag.
+		action = "clear-user"
+		work = func(ctx context.Context) {
+			slog.InfoContext(ctx, "clear user chat", slog.String("channel", channel), slog.String("op", op))
+			hr := userhash.New(robo.secrets.userhash)
+			u := hr.Hash(new(userhash.Hash), user, channel, t)
+			if err := robo.brain.ForgetUser(ctx, u); err != nil {
+				slog.ErrorContext(ctx, "failed to forget recent messages from user", slog.Any("err", err), slog.String("channel", channel))
+				// Try the previous userhash anyway.
+			}
+			u = hr.Hash(u, user, channel, t.Add(-userhash.TimeQuantum))
+			if err := robo.brain.ForgetUser(ctx, u); err != nil {
+				slog.ErrorContext(ctx, "failed to forget older messages from user", slog.Any("err", err), slog.String("channel", channel))
+			}
+		}
+	}
+	robo.enqueue(ctx, h.group, ch, func(ctx context.Context) {
+		ctx = brain.WithOp(ctx, op)
+		work(ctx)
+		robo.recordOp(ctx, journal.Entry{
+			Op:      op,
+			Time:    time.Now(),
+			Actor:   c.Platform(),
+			Channel: channel,
+			Tag:     tag,
+			Action:  action,
+			Count:   robo.forgotten(ctx, op),
+		})
+	})
+}
+
+// Live enables or disables learning in a channel.
+func (h *handler) Live(ctx context.Context, c platform.Client, channel string, s platform.Stream) {
+	ch, _ := h.robo.channels.Load(channel)
+	if ch == nil {
+		return
+	}
+	ch.Enabled.Store(s.Live)
+	ch.Game.Store(&s.Game)
+}
+
+// Mode suspends or resumes learning in a channel according to its chat
+// restrictions.
+func (h *handler) Mode(ctx context.Context, c platform.Client, channel string, mode platform.Mode) {
+	ch, _ := h.robo.channels.Load(channel)
+	if ch == nil {
+		return
+	}
+	s := suspends(&ch.Suspend, mode)
+	if ch.Suspended.Swap(s) != s {
+		slog.InfoContext(ctx, "chat restrictions changed",
+			slog.String("in", ch.Name),
+			slog.Bool("suspended", s),
+			slog.Bool("emote-only", mode.EmoteOnly),
+			slog.Bool("sub-only", mode.SubOnly),
+			slog.Duration("slow", mode.Slow),
+		)
+	}
+}
+
+// suspends reports whether chat restrictions suspend learning under a policy.
+func suspends(p *channel.Suspend, mode platform.Mode) bool {
+	switch {
+	case p.EmoteOnly && mode.EmoteOnly:
+		return true
+	case p.SubOnly && mode.SubOnly:
+		return true
+	case p.Slow > 0 && mode.Slow >= p.Slow:
+		return true
+	default:
+		return false
+	}
+}
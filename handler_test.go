@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/channel"
+	"github.com/zephyrtronium/robot/platform"
+)
+
+func TestSuspends(t *testing.T) {
+	p := channel.Suspend{EmoteOnly: true, Slow: 30 * time.Second}
+	cases := []struct {
+		name string
+		mode platform.Mode
+		want bool
+	}{
+		{"none", platform.Mode{}, false},
+		{"emote-only", platform.Mode{EmoteOnly: true}, true},
+		{"sub-only", platform.Mode{SubOnly: true}, false},
+		{"slow", platform.Mode{Slow: 10 * time.Second}, false},
+		{"slower", platform.Mode{Slow: 30 * time.Second}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := suspends(&p, c.mode); got != c.want {
+				t.Errorf("wrong suspension for %+v: want %t, got %t", c.mode, c.want, got)
+			}
+		})
+	}
+	if suspends(&channel.Suspend{}, platform.Mode{EmoteOnly: true, SubOnly: true, Slow: time.Hour}) {
+		t.Errorf("empty policy suspended")
+	}
+}
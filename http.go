@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zephyrtronium/robot/apikey"
+	"github.com/zephyrtronium/robot/audit"
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/command"
+	"github.com/zephyrtronium/robot/overlay"
+	"github.com/zephyrtronium/robot/poster"
+	"github.com/zephyrtronium/robot/tts"
+)
+
+// SetHTTP sets the address on which the robot serves HTTP and the API tokens
+// which grant roles on its endpoints.
+// If the address is empty, the robot does not serve HTTP.
+func (robo *Robot) SetHTTP(cfg HTTPCfg) error {
+	robo.listen = cfg.Listen
+	if len(cfg.Tokens) == 0 {
+		if cfg.Listen != "" {
+			slog.Warn("no HTTP API tokens; admin endpoints are open to anyone who can reach them")
+		}
+		return nil
+	}
+	robo.apiTokens = make(map[[sha256.Size]byte]command.Level, len(cfg.Tokens))
+	for i, t := range cfg.Tokens {
+		if t.Token == "" {
+			return fmt.Errorf("HTTP token %d is empty", i)
+		}
+		l, ok := command.ParseLevel(t.Role)
+		if !ok {
+			return fmt.Errorf("unknown role %q for HTTP token %d", t.Role, i)
+		}
+		robo.apiTokens[sha256.Sum256([]byte(t.Token))] = l
+	}
+	return nil
+}
+
+// serveHTTP serves the robot's HTTP endpoints on addr until ctx is canceled.
+func (robo *Robot) serveHTTP(ctx context.Context, addr string) error {
+	expvar.Publish("robot", robo.metrics)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /debug/vars", robo.require(command.Operator, apikey.Stats, expvar.Handler().ServeHTTP))
+	mux.HandleFunc("GET /metrics", robo.require(command.Operator, apikey.Stats, robo.metricsHTTP))
+	mux.HandleFunc("GET /explain", robo.require(command.Operator, apikey.Stats, robo.explainHTTP))
+	mux.HandleFunc("GET /speak", robo.require(command.Operator, apikey.Speak, robo.speakHTTP))
+	mux.HandleFunc("GET /audit", robo.require(command.Operator, apikey.Admin, robo.auditHTTP))
+	mux.HandleFunc("GET /config/diff", robo.require(command.Owner, apikey.Admin, robo.configDiffHTTP))
+	mux.HandleFunc("GET /jobs", robo.require(command.Operator, apikey.Admin, robo.jobsHTTP))
+	mux.HandleFunc("GET /emotes/{channel}", robo.require(command.Operator, apikey.Stats, robo.emotesHTTP))
+	mux.HandleFunc("GET /apikeys", robo.require(command.Owner, apikey.Admin, robo.listKeysHTTP))
+	mux.HandleFunc("POST /apikeys", robo.require(command.Owner, apikey.Admin, robo.createKeyHTTP))
+	mux.HandleFunc("DELETE /apikeys/{id}", robo.require(command.Owner, apikey.Admin, robo.revokeKeyHTTP))
+	// Overlays and TTS are browser sources for streams, so anyone may view
+	// them.
+	mux.HandleFunc("GET /overlay/{channel}", robo.require(command.Any, "", overlay.Page))
+	mux.HandleFunc("GET /overlay/{channel}/ws", robo.require(command.Any, "", robo.overlayHTTP))
+	mux.HandleFunc("GET /tts/{channel}", robo.require(command.Any, "", tts.Page))
+	mux.HandleFunc("GET /tts/{channel}/next", robo.require(command.Any, "", robo.speechHTTP))
+	mux.HandleFunc("GET /poster/{name}", robo.require(command.Operator, apikey.Admin, robo.reviewPageHTTP))
+	mux.HandleFunc("GET /poster/{name}/review", robo.require(command.Operator, apikey.Admin, robo.reviewListHTTP))
+	mux.HandleFunc("POST /poster/{name}/review/{id}/{action}", robo.require(command.Operator, apikey.Admin, robo.reviewHTTP))
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+	slog.InfoContext(ctx, "serving HTTP", slog.String("addr", addr))
+	err := srv.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return ctx.Err()
+	}
+	return err
+}
+
+// explainHTTP serves explanations of generated messages.
+// The tag query parameter is required, along with either msg or trace, the
+// latter being a comma-separated list of message IDs.
+func (robo *Robot) explainHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	tag, msg := q.Get("tag"), q.Get("msg")
+	var trace []string
+	if t := q.Get("trace"); t != "" {
+		trace = strings.Split(t, ",")
+	}
+	if tag == "" || (msg == "" && trace == nil) {
+		http.Error(w, "need tag and one of msg or trace", http.StatusBadRequest)
+		return
+	}
+	if err := robo.knownTag(tag); err != nil {
+		httpError(w, r, "couldn't explain", err)
+		return
+	}
+	x, err := explain(r.Context(), robo.brain, robo.spoken, tag, msg, trace)
+	if err != nil {
+		httpError(w, r, "couldn't explain", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(x)
+}
+
+// speakHTTP generates a message. The tag query parameter is required, and
+// prompt optionally gives the start of the message.
+func (robo *Robot) speakHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	tag, prompt := q.Get("tag"), q.Get("prompt")
+	if tag == "" {
+		http.Error(w, "need tag", http.StatusBadRequest)
+		return
+	}
+	if err := robo.knownTag(tag); err != nil {
+		httpError(w, r, "couldn't speak", err)
+		return
+	}
+	m, trace, err := brain.Speak(r.Context(), robo.brain, tag, prompt)
+	if err != nil {
+		httpError(w, r, "couldn't speak", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Text  string   `json:"text"`
+		Trace []string `json:"trace"`
+	}{m, trace})
+}
+
+// overlayHTTP serves the WebSocket of events for a channel's overlay.
+// The channel is named without the leading #.
+func (robo *Robot) overlayHTTP(w http.ResponseWriter, r *http.Request) {
+	ch, _ := robo.channels.Load("#" + r.PathValue("channel"))
+	if ch == nil || ch.Overlay == nil {
+		http.NotFound(w, r)
+		return
+	}
+	ch.Overlay.ServeHTTP(w, r)
+}
+
+// speechHTTP serves the next text-to-speech clip for a channel.
+// The channel is named without the leading #.
+func (robo *Robot) speechHTTP(w http.ResponseWriter, r *http.Request) {
+	ch, _ := robo.channels.Load("#" + r.PathValue("channel"))
+	if ch == nil || ch.Speech == nil {
+		http.NotFound(w, r)
+		return
+	}
+	ch.Speech.ServeHTTP(w, r)
+}
+
+// reviewPageHTTP serves the page for reviewing a poster's candidates.
+func (robo *Robot) reviewPageHTTP(w http.ResponseWriter, r *http.Request) {
+	p := robo.posters[r.PathValue("name")]
+	if p == nil || p.review == nil {
+		http.NotFound(w, r)
+		return
+	}
+	poster.ReviewPage(w, r)
+}
+
+// reviewListHTTP serves the list of a poster's candidates awaiting review.
+func (robo *Robot) reviewListHTTP(w http.ResponseWriter, r *http.Request) {
+	p := robo.posters[r.PathValue("name")]
+	if p == nil || p.review == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p.review.List(time.Now()))
+}
+
+// reviewHTTP approves, rejects, or edits a poster's candidate.
+// Approve and edit take the form value text to replace the candidate's text;
+// it is optional for approve and required for edit.
+// Approved candidates stay in the queue until they are posted, so that a
+// failure to post can be retried. A candidate posted to some accounts but not
+// others is removed anyway, so that retrying can't post it twice.
+func (robo *Robot) reviewHTTP(w http.ResponseWriter, r *http.Request) {
+	p := robo.posters[r.PathValue("name")]
+	if p == nil || p.review == nil {
+		http.NotFound(w, r)
+		return
+	}
+	ctx := r.Context()
+	id, action, text := r.PathValue("id"), r.PathValue("action"), strings.TrimSpace(r.FormValue("text"))
+	switch action {
+	case "approve", "reject":
+	case "edit":
+		if text == "" {
+			http.Error(w, "edit needs text", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "action must be approve, reject, or edit", http.StatusBadRequest)
+		return
+	}
+	if text != "" {
+		// Reviewers' text has to meet the same rules as generated posts.
+		if why := unpostable(text, p.block, p.limit()); why != "" {
+			http.Error(w, "text is unpostable: "+why, http.StatusBadRequest)
+			return
+		}
+	}
+	var (
+		c  poster.Candidate
+		ok bool
+	)
+	switch action {
+	case "approve":
+		c, ok = p.review.Get(id, time.Now())
+	case "reject":
+		c, ok = p.review.Remove(id, time.Now())
+	case "edit":
+		c, ok = p.review.Edit(id, text, time.Now())
+	}
+	if !ok {
+		http.Error(w, "no such candidate or it expired", http.StatusNotFound)
+		return
+	}
+	var err error
+	if action == "approve" {
+		if text != "" {
+			c.Text = text
+		}
+		var n int
+		n, err = robo.publish(ctx, p, c.Text)
+		if err != nil && n == 0 {
+			http.Error(w, "couldn't post: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		p.review.Remove(id, time.Now())
+	}
+	slog.InfoContext(ctx, "reviewed post", slog.String("poster", p.name), slog.String("id", c.ID), slog.String("action", action))
+	params := map[string]string{"poster": p.name, "id": c.ID}
+	if text != "" {
+		params["text"] = text
+	}
+	robo.record(ctx, audit.Entry{
+		Time:   time.Now(),
+		Actor:  robo.actor(r),
+		Action: "review-" + action,
+		Params: params,
+	})
+	if err != nil {
+		http.Error(w, "posted to some accounts but not others: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// auditHTTP serves entries from the audit log, newest first.
+// The query parameters since, actor, channel, action, and limit filter them;
+// since is RFC 3339, a date, or a duration ago, and limit defaults to 100.
+func (robo *Robot) auditHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	a := audit.Query{
+		Actor:   q.Get("actor"),
+		Channel: q.Get("channel"),
+		Action:  q.Get("action"),
+		Limit:   100,
+	}
+	if s := q.Get("since"); s != "" {
+		var err error
+		a.Since, err = parseWhen(s, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if s := q.Get("limit"); s != "" {
+		var err error
+		a.Limit, err = strconv.Atoi(s)
+		if err != nil {
+			http.Error(w, "limit must be a number", http.StatusBadRequest)
+			return
+		}
+	}
+	l, err := robo.audit.List(r.Context(), a)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "couldn't list audit log", slog.Any("err", err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(l)
+}
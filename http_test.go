@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/zephyrtronium/robot/apikey"
+	"github.com/zephyrtronium/robot/command"
+)
+
+func TestRequire(t *testing.T) {
+	robo := New(1)
+	err := robo.SetHTTP(HTTPCfg{Tokens: []APIToken{
+		{Token: "bocchi", Role: "channel-moderator"},
+		{Token: "ryo", Role: "operator"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	cases := []struct {
+		name   string
+		need   command.Level
+		header string
+		query  string
+		want   int
+	}{
+		{"open", command.Any, "", "", http.StatusNoContent},
+		{"missing", command.Operator, "", "", http.StatusUnauthorized},
+		{"unknown", command.Operator, "Bearer kita", "", http.StatusUnauthorized},
+		{"bearer", command.Operator, "Bearer ryo", "", http.StatusNoContent},
+		{"query", command.Operator, "", "?token=ryo", http.StatusNoContent},
+		{"below", command.Operator, "Bearer bocchi", "", http.StatusForbidden},
+		{"above", command.Moderator, "Bearer ryo", "", http.StatusNoContent},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/debug/vars"+c.query, nil)
+			if c.header != "" {
+				r.Header.Set("Authorization", c.header)
+			}
+			w := httptest.NewRecorder()
+			robo.require(c.need, apikey.Admin, ok)(w, r)
+			if w.Code != c.want {
+				t.Errorf("wrong status: want %d, got %d", c.want, w.Code)
+			}
+		})
+	}
+}
+
+func TestRequireNoTokens(t *testing.T) {
+	robo := New(1)
+	if err := robo.SetHTTP(HTTPCfg{}); err != nil {
+		t.Fatal(err)
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	w := httptest.NewRecorder()
+	robo.require(command.Owner, apikey.Admin, ok)(w, httptest.NewRequest("GET", "/debug/vars", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("wrong status without tokens: want %d, got %d", http.StatusNoContent, w.Code)
+	}
+}
+
+func TestRequireKeys(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlitex.NewPool("file:TestRequireKeys.db?mode=memory&cache=shared", sqlitex.PoolOptions{Flags: sqlite.OpenReadWrite | sqlite.OpenCreate | sqlite.OpenMemory | sqlite.OpenSharedCache | sqlite.OpenURI})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	robo := New(1)
+	robo.apikeys, err = apikey.Open(ctx, db, []byte("bocchi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	try := func(need apikey.Scope, tok string) int {
+		r := httptest.NewRequest("GET", "/speak", nil)
+		if tok != "" {
+			r.Header.Set("Authorization", "Bearer "+tok)
+		}
+		w := httptest.NewRecorder()
+		robo.require(command.Operator, need, ok)(w, r)
+		return w.Code
+	}
+	if got := try(apikey.Speak, ""); got != http.StatusNoContent {
+		t.Errorf("wrong status with no keys: want %d, got %d", http.StatusNoContent, got)
+	}
+	speak, _, err := robo.apikeys.Create(ctx, "", apikey.Speak, time.Hour, 2, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, _, err := robo.apikeys.Create(ctx, "", apikey.Admin, 0, 0, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name string
+		need apikey.Scope
+		tok  string
+		want int
+	}{
+		{"missing", apikey.Speak, "", http.StatusUnauthorized},
+		{"unknown", apikey.Speak, "robot_x_y", http.StatusUnauthorized},
+		{"speak", apikey.Speak, speak, http.StatusNoContent},
+		{"scope", apikey.Stats, speak, http.StatusForbidden},
+		{"admin", apikey.Stats, admin, http.StatusNoContent},
+		{"burst", apikey.Speak, speak, http.StatusNoContent},
+		{"limited", apikey.Speak, speak, http.StatusTooManyRequests},
+		{"unlimited", apikey.Speak, admin, http.StatusNoContent},
+	}
+	for _, c := range cases {
+		if got := try(c.need, c.tok); got != c.want {
+			t.Errorf("%s: wrong status: want %d, got %d", c.name, c.want, got)
+		}
+	}
+}
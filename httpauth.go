@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/zephyrtronium/robot/apikey"
+	"github.com/zephyrtronium/robot/audit"
+	"github.com/zephyrtronium/robot/command"
+)
+
+// apiClient is the identity of an HTTP API client.
+type apiClient struct {
+	// role is the role granted by a configured token.
+	role command.Level
+	// key is the managed API key used, if any.
+	key *apikey.Key
+}
+
+// String describes the client for the audit log.
+func (c apiClient) String() string {
+	if c.key != nil {
+		return "http:key:" + c.key.ID
+	}
+	return "http:" + c.role.String()
+}
+
+// token gets the API token of a request, given either as a bearer token or in
+// the token query parameter.
+func token(r *http.Request) string {
+	tok, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		tok = r.URL.Query().Get("token")
+	}
+	return tok
+}
+
+// identify finds the client using an API token.
+// The second result is false if the token is unknown.
+func (robo *Robot) identify(ctx context.Context, tok string) (apiClient, bool, error) {
+	// Looking up by hash keeps timing from revealing anything about the
+	// tokens themselves.
+	if l, ok := robo.apiTokens[sha256.Sum256([]byte(tok))]; ok {
+		return apiClient{role: l}, true, nil
+	}
+	if robo.apikeys == nil {
+		return apiClient{}, false, nil
+	}
+	k, ok, err := robo.apikeys.Check(ctx, tok)
+	if !ok || err != nil {
+		return apiClient{}, false, err
+	}
+	return apiClient{key: &k}, true, nil
+}
+
+// open reports whether HTTP endpoints are open to anyone because there are
+// neither configured tokens nor managed keys.
+func (robo *Robot) open(ctx context.Context) (bool, error) {
+	if robo.apiTokens != nil {
+		return false, nil
+	}
+	if robo.apikeys == nil {
+		return true, nil
+	}
+	n, err := robo.apikeys.Count(ctx)
+	return n == 0, err
+}
+
+// require wraps an HTTP handler to allow only requests with a configured token
+// granting at least the given role or a managed key with the given scope.
+// Managed keys are also subject to their rate limits.
+// If there are no tokens or keys, all requests are allowed.
+func (robo *Robot) require(l command.Level, scope apikey.Scope, h http.HandlerFunc) http.HandlerFunc {
+	if l == command.Any {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		tok := token(r)
+		if tok == "" {
+			open, err := robo.open(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "couldn't check for API keys", slog.Any("err", err))
+				http.Error(w, "couldn't check API keys", http.StatusInternalServerError)
+				return
+			}
+			if open {
+				h(w, r)
+				return
+			}
+		}
+		c, ok, err := robo.identify(ctx, tok)
+		if err != nil {
+			slog.ErrorContext(ctx, "couldn't check API key", slog.Any("err", err))
+			http.Error(w, "couldn't check API key", http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "missing or unknown API token", http.StatusUnauthorized)
+			return
+		}
+		if c.key == nil {
+			if c.role < l {
+				slog.WarnContext(ctx, "HTTP request without permission",
+					slog.String("path", r.URL.Path),
+					slog.String("role", c.role.String()),
+					slog.String("need", l.String()),
+				)
+				http.Error(w, "token does not grant "+l.String(), http.StatusForbidden)
+				return
+			}
+			h(w, r)
+			return
+		}
+		if !c.key.Scope.Allows(scope) {
+			slog.WarnContext(ctx, "HTTP request without scope",
+				slog.String("path", r.URL.Path),
+				slog.String("key", c.key.ID),
+				slog.String("scope", string(c.key.Scope)),
+				slog.String("need", string(scope)),
+			)
+			http.Error(w, "key does not have scope "+string(scope), http.StatusForbidden)
+			return
+		}
+		if lim := robo.keyLimit(c.key); lim != nil && !lim.Allow() {
+			w.Header().Set("Retry-After", strconv.Itoa(int(c.key.Every/time.Second)+1))
+			http.Error(w, "rate limited", http.StatusTooManyRequests)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// keyLimit gets the rate limiter for a managed key.
+// It returns nil if the key is not rate limited.
+func (robo *Robot) keyLimit(k *apikey.Key) *rate.Limiter {
+	if k.Every <= 0 {
+		return nil
+	}
+	lim, ok := robo.keyLimits.Load(k.ID)
+	if !ok {
+		// Racing requests may each create a limiter, but only briefly.
+		lim = rate.NewLimiter(rate.Every(k.Every), k.Burst)
+		robo.keyLimits.Store(k.ID, lim)
+	}
+	return lim
+}
+
+// actor describes the client of an HTTP request for the audit log.
+func (robo *Robot) actor(r *http.Request) string {
+	c, ok, _ := robo.identify(r.Context(), token(r))
+	if !ok {
+		return "http:" + r.RemoteAddr
+	}
+	return c.String()
+}
+
+// listKeysHTTP serves the list of managed API keys, without their tokens.
+func (robo *Robot) listKeysHTTP(w http.ResponseWriter, r *http.Request) {
+	l, err := robo.apikeys.List(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "couldn't list API keys", slog.Any("err", err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(l)
+}
+
+// createKeyHTTP creates a managed API key. The form values are name, scope,
+// and optionally every and burst for a rate limit of a request every every
+// seconds with bursts of up to burst. The response includes the key's token,
+// which can't be retrieved again.
+func (robo *Robot) createKeyHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	scope, ok := apikey.ParseScope(r.FormValue("scope"))
+	if !ok {
+		http.Error(w, "scope must be speak, stats, or admin", http.StatusBadRequest)
+		return
+	}
+	var every float64
+	if s := r.FormValue("every"); s != "" {
+		var err error
+		every, err = strconv.ParseFloat(s, 64)
+		if err != nil || every < 0 {
+			http.Error(w, "every must be a non-negative number of seconds", http.StatusBadRequest)
+			return
+		}
+	}
+	burst := 1
+	if s := r.FormValue("burst"); s != "" {
+		var err error
+		burst, err = strconv.Atoi(s)
+		if err != nil || burst <= 0 {
+			http.Error(w, "burst must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+	name := r.FormValue("name")
+	tok, k, err := robo.apikeys.Create(ctx, name, scope, fseconds(every), burst, time.Now())
+	if err != nil {
+		slog.ErrorContext(ctx, "couldn't create API key", slog.Any("err", err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	robo.record(ctx, audit.Entry{
+		Time:   k.Created,
+		Actor:  robo.actor(r),
+		Action: "apikey-create",
+		Params: map[string]string{"id": k.ID, "name": name, "scope": string(scope)},
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(struct {
+		Token string     `json:"token"`
+		Key   apikey.Key `json:"key"`
+	}{tok, k})
+}
+
+// revokeKeyHTTP revokes a managed API key.
+func (robo *Robot) revokeKeyHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := r.PathValue("id")
+	// Identify the actor first, in case the key is revoking itself.
+	actor := robo.actor(r)
+	ok, err := robo.apikeys.Revoke(ctx, id)
+	if err != nil {
+		slog.ErrorContext(ctx, "couldn't revoke API key", slog.Any("err", err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	robo.keyLimits.Delete(id)
+	robo.record(ctx, audit.Entry{
+		Time:   time.Now(),
+		Actor:  actor,
+		Action: "apikey-revoke",
+		Params: map[string]string{"id": id},
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,92 @@
+// Package ignore provides persistent storage for users ignored across all
+// channels at runtime.
+package ignore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Store is a set of globally ignored users backed by an SQL database.
+// Its methods are safe to call concurrently.
+type Store struct {
+	db *sqlitex.Pool
+	// mu guards users.
+	mu sync.RWMutex
+	// users is the ignored user IDs and logins.
+	users map[string]bool
+}
+
+// Open opens an ignore list in an SQL database, creating its table if needed
+// and loading its contents.
+func Open(ctx context.Context, db *sqlitex.Pool) (*Store, error) {
+	conn, err := db.Take(ctx)
+	defer db.Put(conn)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get connection from pool: %w", err)
+	}
+	const schemaSQL = `CREATE TABLE IF NOT EXISTS ignored (user TEXT PRIMARY KEY) STRICT, WITHOUT ROWID`
+	if err := sqlitex.ExecuteTransient(conn, schemaSQL, nil); err != nil {
+		return nil, fmt.Errorf("couldn't run migration: %w", err)
+	}
+	s := &Store{db: db, users: make(map[string]bool)}
+	opts := sqlitex.ExecOptions{
+		ResultFunc: func(st *sqlite.Stmt) error {
+			s.users[st.ColumnText(0)] = true
+			return nil
+		},
+	}
+	if err := sqlitex.Execute(conn, `SELECT user FROM ignored`, &opts); err != nil {
+		return nil, fmt.Errorf("couldn't load ignored users: %w", err)
+	}
+	return s, nil
+}
+
+// Add ignores a user by user ID or login.
+func (s *Store) Add(ctx context.Context, user string) error {
+	conn, err := s.db.Take(ctx)
+	defer s.db.Put(conn)
+	if err != nil {
+		return fmt.Errorf("couldn't get connection to ignore user: %w", err)
+	}
+	opts := sqlitex.ExecOptions{Args: []any{user}}
+	if err := sqlitex.Execute(conn, `INSERT INTO ignored (user) VALUES (?) ON CONFLICT DO NOTHING`, &opts); err != nil {
+		return fmt.Errorf("couldn't ignore user: %w", err)
+	}
+	s.mu.Lock()
+	s.users[user] = true
+	s.mu.Unlock()
+	return nil
+}
+
+// Remove stops ignoring a user added with [Store.Add].
+// It reports whether the user was ignored.
+func (s *Store) Remove(ctx context.Context, user string) (bool, error) {
+	conn, err := s.db.Take(ctx)
+	defer s.db.Put(conn)
+	if err != nil {
+		return false, fmt.Errorf("couldn't get connection to unignore user: %w", err)
+	}
+	opts := sqlitex.ExecOptions{Args: []any{user}}
+	if err := sqlitex.Execute(conn, `DELETE FROM ignored WHERE user=?`, &opts); err != nil {
+		return false, fmt.Errorf("couldn't unignore user: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ok := s.users[user]
+	delete(s.users, user)
+	return ok, nil
+}
+
+// Has reports whether a user is ignored, either by user ID or by login.
+// Logins match case-insensitively if they were added in lowercase.
+func (s *Store) Has(id, login string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.users[id] || s.users[login] || s.users[strings.ToLower(login)]
+}
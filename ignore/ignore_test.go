@@ -0,0 +1,72 @@
+package ignore_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/zephyrtronium/robot/ignore"
+)
+
+var dbcount atomic.Uint64
+
+func testConn() *sqlitex.Pool {
+	k := dbcount.Add(1)
+	pool, err := sqlitex.NewPool(fmt.Sprintf("file:%d.db?mode=memory&cache=shared", k), sqlitex.PoolOptions{Flags: sqlite.OpenReadWrite | sqlite.OpenCreate | sqlite.OpenMemory | sqlite.OpenSharedCache | sqlite.OpenURI})
+	if err != nil {
+		panic(err)
+	}
+	return pool
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	db := testConn()
+	s, err := ignore.Open(ctx, db)
+	if err != nil {
+		t.Fatalf("couldn't open store: %v", err)
+	}
+	for _, u := range []string{"nightbot", "12345", "streamelementsbot"} {
+		if err := s.Add(ctx, u); err != nil {
+			t.Errorf("couldn't add %s: %v", u, err)
+		}
+	}
+	ok, err := s.Remove(ctx, "streamelementsbot")
+	if err != nil {
+		t.Errorf("couldn't remove: %v", err)
+	}
+	if !ok {
+		t.Errorf("removed user wasn't ignored")
+	}
+	ok, err = s.Remove(ctx, "bocchi")
+	if err != nil {
+		t.Errorf("couldn't remove: %v", err)
+	}
+	if ok {
+		t.Errorf("removed user that wasn't ignored")
+	}
+	// Reopen to check that the list persists.
+	s, err = ignore.Open(ctx, db)
+	if err != nil {
+		t.Fatalf("couldn't reopen store: %v", err)
+	}
+	cases := []struct {
+		id, login string
+		want      bool
+	}{
+		{"1", "nightbot", true},
+		{"1", "Nightbot", true},
+		{"12345", "bocchi", true},
+		{"2", "streamelementsbot", false},
+		{"3", "bocchi", false},
+	}
+	for _, c := range cases {
+		if got := s.Has(c.id, c.login); got != c.want {
+			t.Errorf("wrong result for %s/%s: want %t, got %t", c.id, c.login, c.want, got)
+		}
+	}
+}
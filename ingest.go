@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/zephyrtronium/robot/brain/busy"
+	"github.com/zephyrtronium/robot/brain/queue"
+)
+
+func cliIngest(ctx context.Context, cmd *cli.Command) error {
+	slog.SetDefault(loggerFromFlags(cmd))
+	cfg, err := cliConfig(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	if cfg.DB.Queue.URL == "" {
+		return errors.New("no queue configured; set db.queue.url")
+	}
+	db, err := loadDBs(ctx, cfg.DB)
+	if err != nil {
+		return err
+	}
+	br, done, err := openBrain(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer done()
+	if db.retry != nil && db.sql != nil {
+		br = busy.New(br, db.retry)
+	}
+	t, err := cfg.DB.Queue.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+	c := queue.NewConsumer(br, t)
+	slog.InfoContext(ctx, "ingesting from queue", slog.String("url", cfg.DB.Queue.URL))
+	err = c.Run(ctx)
+	if errors.Is(err, context.Canceled) {
+		// Shutting down normally in response to a signal.
+		err = nil
+	}
+	slog.InfoContext(ctx, "stopped ingesting", slog.Any("stats", c.Stats()))
+	return err
+}
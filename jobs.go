@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/zephyrtronium/robot/jobs"
+)
+
+// SetJobs sets up running background jobs, saving their checkpoints in db.
+func (robo *Robot) SetJobs(ctx context.Context, cfg JobsCfg, db *sqlitex.Pool) error {
+	r, err := jobs.New(ctx, db, cfg.Concurrency, cfg.Jitter)
+	if err != nil {
+		return fmt.Errorf("couldn't set up jobs: %w", err)
+	}
+	robo.jobs = r
+	return nil
+}
+
+// jobsHTTP lists the statuses of background jobs.
+func (robo *Robot) jobsHTTP(w http.ResponseWriter, r *http.Request) {
+	var s []jobs.Status
+	if robo.jobs != nil {
+		s = robo.jobs.Status()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s)
+}
@@ -0,0 +1,313 @@
+// Package jobs runs periodic background work, such as enforcing storage
+// quotas and purging forgotten knowledge, with a limit on how many jobs run at
+// once and checkpoints from which interrupted work resumes.
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Func does one run of a job. It receives the checkpoint returned by the
+// previous run, or the empty string if the job has never run or its last run
+// finished its work. It returns the checkpoint from which the next run should
+// resume, or the empty string if the work is done. The checkpoint is kept even
+// if Func returns an error, so a run which fails or is canceled partway can
+// pick up where it left off.
+type Func func(ctx context.Context, checkpoint string) (string, error)
+
+// Job is a periodic job.
+type Job struct {
+	// Name identifies the job. Checkpoints are saved under it, so it should
+	// be stable across restarts.
+	Name string
+	// Every is the interval between the end of one run and the start of the
+	// next, before jitter.
+	Every time.Duration
+	// Fn does the work.
+	Fn Func
+}
+
+// Status is the state of a job.
+type Status struct {
+	// Name is the name of the job.
+	Name string `json:"name"`
+	// Running is whether the job is running now.
+	Running bool `json:"running"`
+	// Runs is the number of times the job has run.
+	Runs int64 `json:"runs"`
+	// Failures is the number of runs which returned errors.
+	Failures int64 `json:"failures"`
+	// Started and Finished are the times at which the most recent run
+	// started and finished. They are zero if the job has never run.
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	// Next is the time at which the job will next run. It is zero for jobs
+	// loaded with [List] and jobs which are running.
+	Next time.Time `json:"next"`
+	// Err is the error from the most recent run, if it failed.
+	Err string `json:"error,omitempty"`
+	// Checkpoint is the checkpoint from which the next run resumes.
+	Checkpoint string `json:"checkpoint,omitempty"`
+}
+
+// Runner runs jobs.
+type Runner struct {
+	// db is where checkpoints and statuses are saved.
+	// If it is nil, they are only kept in memory.
+	db *sqlitex.Pool
+	// sem limits the number of jobs running at once.
+	sem chan struct{}
+	// jitter is the fraction of each job's interval by which to randomly
+	// delay its runs.
+	jitter float64
+	// mu guards jobs.
+	mu sync.Mutex
+	// jobs is the jobs and their statuses.
+	jobs []*entry
+}
+
+// entry is a job and its status.
+type entry struct {
+	job    Job
+	status Status
+}
+
+const schemaSQL = `
+CREATE TABLE IF NOT EXISTS jobs (
+	name TEXT PRIMARY KEY,
+	checkpoint TEXT NOT NULL,
+	started INTEGER NOT NULL,
+	finished INTEGER NOT NULL,
+	error TEXT NOT NULL,
+	runs INTEGER NOT NULL,
+	failures INTEGER NOT NULL
+) STRICT;
+`
+
+// New creates a runner which runs at most limit jobs at once, delaying each
+// run by a random fraction up to jitter of the job's interval so that jobs
+// with equal intervals don't run in lockstep. If limit is not positive, only
+// one job runs at a time. If db is not nil, job statuses and checkpoints are
+// saved in it, creating its table if needed.
+func New(ctx context.Context, db *sqlitex.Pool, limit int, jitter float64) (*Runner, error) {
+	if db != nil {
+		conn, err := db.Take(ctx)
+		defer db.Put(conn)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't get connection from pool: %w", err)
+		}
+		if err := sqlitex.ExecuteScript(conn, schemaSQL, nil); err != nil {
+			return nil, fmt.Errorf("couldn't run migration: %w", err)
+		}
+	}
+	return &Runner{db: db, sem: make(chan struct{}, max(limit, 1)), jitter: max(jitter, 0)}, nil
+}
+
+// Add adds a job to the runner. It must be called before [Runner.Run].
+func (r *Runner) Add(j Job) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs = append(r.jobs, &entry{job: j, status: Status{Name: j.Name}})
+}
+
+// Len returns the number of jobs added to the runner.
+func (r *Runner) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.jobs)
+}
+
+// Run runs jobs until ctx is canceled. Jobs resume from their saved
+// checkpoints. Errors from jobs are logged and recorded in their statuses
+// rather than stopping the runner.
+func (r *Runner) Run(ctx context.Context) error {
+	saved, err := r.load(ctx)
+	if err != nil {
+		return err
+	}
+	var wg sync.WaitGroup
+	r.mu.Lock()
+	for _, e := range r.jobs {
+		if s, ok := saved[e.job.Name]; ok {
+			e.status = s
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.loop(ctx, e)
+		}()
+	}
+	r.mu.Unlock()
+	wg.Wait()
+	return ctx.Err()
+}
+
+// Status returns the statuses of the runner's jobs, sorted by name.
+func (r *Runner) Status() []Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := make([]Status, 0, len(r.jobs))
+	for _, e := range r.jobs {
+		s = append(s, e.status)
+	}
+	slices.SortFunc(s, func(a, b Status) int { return strings.Compare(a.Name, b.Name) })
+	return s
+}
+
+// loop runs a job periodically until ctx is canceled.
+// The first run starts after only the jitter delay.
+func (r *Runner) loop(ctx context.Context, e *entry) {
+	t := time.NewTimer(r.schedule(e, 0))
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case r.sem <- struct{}{}:
+		}
+		r.run(ctx, e)
+		<-r.sem
+		t.Reset(r.schedule(e, e.job.Every))
+	}
+}
+
+// schedule adds jitter to the time to wait before a job's next run and
+// records when it will be in the job's status.
+func (r *Runner) schedule(e *entry, d time.Duration) time.Duration {
+	if j := time.Duration(r.jitter * float64(e.job.Every)); j > 0 {
+		d += rand.N(j)
+	}
+	r.mu.Lock()
+	e.status.Next = time.Now().Add(d)
+	r.mu.Unlock()
+	return d
+}
+
+// run runs a job once and saves its status.
+func (r *Runner) run(ctx context.Context, e *entry) {
+	r.mu.Lock()
+	cp := e.status.Checkpoint
+	e.status.Running = true
+	e.status.Started = time.Now()
+	e.status.Next = time.Time{}
+	r.mu.Unlock()
+	next, err := e.job.Fn(ctx, cp)
+	r.mu.Lock()
+	e.status.Running = false
+	e.status.Finished = time.Now()
+	e.status.Runs++
+	e.status.Checkpoint = next
+	e.status.Err = ""
+	if err != nil {
+		e.status.Failures++
+		e.status.Err = err.Error()
+	}
+	s := e.status
+	r.mu.Unlock()
+	if err != nil {
+		slog.ErrorContext(ctx, "job failed", slog.String("job", s.Name), slog.Any("err", err), slog.String("checkpoint", next))
+	}
+	if err := r.save(context.WithoutCancel(ctx), s); err != nil {
+		slog.ErrorContext(ctx, "couldn't save job status", slog.String("job", s.Name), slog.Any("err", err))
+	}
+}
+
+// save saves a job's status.
+func (r *Runner) save(ctx context.Context, s Status) error {
+	if r.db == nil {
+		return nil
+	}
+	conn, err := r.db.Take(ctx)
+	defer r.db.Put(conn)
+	if err != nil {
+		return fmt.Errorf("couldn't get connection to save job status: %w", err)
+	}
+	const upsert = `INSERT OR REPLACE INTO jobs (name, checkpoint, started, finished, error, runs, failures)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+	opts := sqlitex.ExecOptions{
+		Args: []any{s.Name, s.Checkpoint, unixnano(s.Started), unixnano(s.Finished), s.Err, s.Runs, s.Failures},
+	}
+	if err := sqlitex.Execute(conn, upsert, &opts); err != nil {
+		return fmt.Errorf("couldn't save job status: %w", err)
+	}
+	return nil
+}
+
+// load loads saved job statuses by name.
+func (r *Runner) load(ctx context.Context) (map[string]Status, error) {
+	if r.db == nil {
+		return nil, nil
+	}
+	s, err := List(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]Status, len(s))
+	for _, v := range s {
+		m[v.Name] = v
+	}
+	return m, nil
+}
+
+// List gets the saved statuses of all jobs which have run in a database,
+// sorted by name. It is for inspecting jobs from outside the process running
+// them.
+func List(ctx context.Context, db *sqlitex.Pool) ([]Status, error) {
+	conn, err := db.Take(ctx)
+	defer db.Put(conn)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get connection to list jobs: %w", err)
+	}
+	var r []Status
+	opts := sqlitex.ExecOptions{
+		ResultFunc: func(st *sqlite.Stmt) error {
+			r = append(r, Status{
+				Name:       st.ColumnText(0),
+				Checkpoint: st.ColumnText(1),
+				Started:    nanotime(st.ColumnInt64(2)),
+				Finished:   nanotime(st.ColumnInt64(3)),
+				Err:        st.ColumnText(4),
+				Runs:       st.ColumnInt64(5),
+				Failures:   st.ColumnInt64(6),
+			})
+			return nil
+		},
+	}
+	const sel = `SELECT name, checkpoint, started, finished, error, runs, failures FROM jobs ORDER BY name`
+	if err := sqlitex.Execute(conn, sel, &opts); err != nil {
+		return nil, fmt.Errorf("couldn't list jobs: %w", err)
+	}
+	return r, nil
+}
+
+// unixnano converts a time to nanoseconds since the UNIX epoch, representing
+// the zero time as 0.
+func unixnano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// nanotime is the inverse of unixnano.
+func nanotime(ns int64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
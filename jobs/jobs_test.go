@@ -0,0 +1,134 @@
+package jobs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/zephyrtronium/robot/jobs"
+)
+
+var dbcount atomic.Uint64
+
+func testConn() *sqlitex.Pool {
+	k := dbcount.Add(1)
+	pool, err := sqlitex.NewPool(fmt.Sprintf("file:%d.db?mode=memory&cache=shared", k), sqlitex.PoolOptions{Flags: sqlite.OpenReadWrite | sqlite.OpenCreate | sqlite.OpenMemory | sqlite.OpenSharedCache | sqlite.OpenURI})
+	if err != nil {
+		panic(err)
+	}
+	return pool
+}
+
+func TestCheckpoints(t *testing.T) {
+	ctx := context.Background()
+	db := testConn()
+	r, err := jobs.New(ctx, db, 1, 0)
+	if err != nil {
+		t.Fatalf("couldn't create runner: %v", err)
+	}
+	got := make(chan string, 3)
+	ctx, cancel := context.WithCancel(ctx)
+	r.Add(jobs.Job{
+		Name:  "bocchi",
+		Every: time.Millisecond,
+		Fn: func(ctx context.Context, cp string) (string, error) {
+			got <- cp
+			if len(got) == cap(got) {
+				cancel()
+			}
+			switch cp {
+			case "":
+				return "guitar", nil
+			case "guitar":
+				return "band", errors.New("stage fright")
+			default:
+				return "done", nil
+			}
+		},
+	})
+	if err := r.Run(ctx); err != context.Canceled {
+		t.Errorf("wrong error from run: %v", err)
+	}
+	close(got)
+	var cps []string
+	for cp := range got {
+		cps = append(cps, cp)
+	}
+	if want := []string{"", "guitar", "band"}; fmt.Sprint(cps) != fmt.Sprint(want) {
+		t.Errorf("wrong checkpoints: want %q, got %q", want, cps)
+	}
+	s, err := jobs.List(context.Background(), db)
+	if err != nil {
+		t.Fatalf("couldn't list jobs: %v", err)
+	}
+	if len(s) != 1 {
+		t.Fatalf("wrong number of jobs: %+v", s)
+	}
+	if s[0].Name != "bocchi" || s[0].Runs != 3 || s[0].Failures != 1 || s[0].Checkpoint != "done" || s[0].Err != "" || s[0].Finished.IsZero() {
+		t.Errorf("wrong saved status: %+v", s[0])
+	}
+
+	// A new runner resumes from the saved checkpoint.
+	r, err = jobs.New(context.Background(), db, 1, 0)
+	if err != nil {
+		t.Fatalf("couldn't create second runner: %v", err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	var resumed string
+	r.Add(jobs.Job{
+		Name:  "bocchi",
+		Every: time.Hour,
+		Fn: func(ctx context.Context, cp string) (string, error) {
+			resumed = cp
+			cancel()
+			return "", nil
+		},
+	})
+	r.Run(ctx)
+	if resumed != "done" {
+		t.Errorf("didn't resume from checkpoint: got %q", resumed)
+	}
+	if s := r.Status(); len(s) != 1 || s[0].Runs != 4 || s[0].Checkpoint != "" {
+		t.Errorf("wrong status after resuming: %+v", s)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	r, err := jobs.New(context.Background(), nil, 2, 0.5)
+	if err != nil {
+		t.Fatalf("couldn't create runner: %v", err)
+	}
+	var running, most, runs atomic.Int64
+	ctx, cancel := context.WithCancel(context.Background())
+	fn := func(ctx context.Context, cp string) (string, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := most.Load()
+			if n <= m || most.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		if runs.Add(1) >= 50 {
+			cancel()
+		}
+		return "", nil
+	}
+	for _, name := range []string{"bocchi", "nijika", "ryo", "kita"} {
+		r.Add(jobs.Job{Name: name, Every: time.Millisecond, Fn: fn})
+	}
+	if r.Len() != 4 {
+		t.Errorf("wrong number of jobs: %d", r.Len())
+	}
+	r.Run(ctx)
+	if m := most.Load(); m > 2 {
+		t.Errorf("too many jobs at once: %d", m)
+	}
+}
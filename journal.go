@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/journal"
+)
+
+// recordOp adds an entry to the operation journal.
+// Failures are logged rather than stopping anything.
+func (robo *Robot) recordOp(ctx context.Context, e journal.Entry) {
+	if robo.journal == nil {
+		return
+	}
+	if err := robo.journal.Record(ctx, e); err != nil {
+		slog.ErrorContext(ctx, "couldn't record operation",
+			slog.Any("err", err),
+			slog.String("op", e.Op),
+			slog.String("action", e.Action),
+		)
+	}
+}
+
+// forgotten counts the messages forgotten by an operation, or returns -1 if
+// the brain can't tell.
+func (robo *Robot) forgotten(ctx context.Context, op string) int64 {
+	un, ok := brain.As[brain.Undeleter](robo.brain)
+	if !ok {
+		return -1
+	}
+	n, err := un.Forgotten(ctx, op)
+	if err != nil {
+		slog.ErrorContext(ctx, "couldn't count forgotten messages", slog.Any("err", err), slog.String("op", op))
+		return -1
+	}
+	return int64(n)
+}
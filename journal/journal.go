@@ -0,0 +1,155 @@
+// Package journal provides a durable record of destructive operations on the
+// bot's knowledge, such as forgetting and purging, with the operation IDs
+// needed to undo them and the number of messages each affected.
+package journal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Journal is a journal of operations backed by an SQL database.
+type Journal struct {
+	db *sqlitex.Pool
+}
+
+// Entry is one destructive operation.
+type Entry struct {
+	// Op is the operation ID, as attached to forgetting with brain.WithOp.
+	Op string `json:"op"`
+	// Time is the time at which the operation happened.
+	Time time.Time `json:"time"`
+	// Actor identifies who or what caused the operation, e.g. a user ID, a
+	// platform for moderation events, or sweep for retention.
+	Actor string `json:"actor"`
+	// Channel is the channel in which the operation happened.
+	// It is empty for operations outside any channel.
+	Channel string `json:"channel,omitempty"`
+	// Tag is the tag the operation affected.
+	// It is empty for operations across all tags.
+	Tag string `json:"tag,omitempty"`
+	// Action names the operation, e.g. forget-message or purge.
+	Action string `json:"action"`
+	// Count is the number of messages the operation affected, or -1 if it
+	// isn't known.
+	Count int64 `json:"count"`
+	// Params is the parameters of the operation.
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// Query selects entries from a journal. Empty fields match everything.
+type Query struct {
+	// Since excludes entries from before it.
+	Since time.Time
+	// Op, Actor, Channel, and Action select entries with exactly those
+	// values.
+	Op, Actor, Channel, Action string
+	// Limit is the maximum number of entries to return.
+	// If it is not positive, all matching entries are returned.
+	Limit int
+}
+
+const schemaSQL = `
+CREATE TABLE IF NOT EXISTS journal (
+	op TEXT NOT NULL,
+	time INTEGER NOT NULL,
+	actor TEXT NOT NULL,
+	channel TEXT NOT NULL,
+	tag TEXT NOT NULL,
+	action TEXT NOT NULL,
+	count INTEGER NOT NULL,
+	params TEXT NOT NULL
+) STRICT;
+CREATE INDEX IF NOT EXISTS journal_time ON journal (time);
+CREATE INDEX IF NOT EXISTS journal_op ON journal (op);
+`
+
+// Open opens a journal in an SQL database, creating its table if needed.
+func Open(ctx context.Context, db *sqlitex.Pool) (*Journal, error) {
+	conn, err := db.Take(ctx)
+	defer db.Put(conn)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get connection from pool: %w", err)
+	}
+	if err := sqlitex.ExecuteScript(conn, schemaSQL, nil); err != nil {
+		return nil, fmt.Errorf("couldn't run migration: %w", err)
+	}
+	return &Journal{db: db}, nil
+}
+
+// Record adds an entry to the journal.
+func (j *Journal) Record(ctx context.Context, e Entry) error {
+	conn, err := j.db.Take(ctx)
+	defer j.db.Put(conn)
+	if err != nil {
+		return fmt.Errorf("couldn't get connection to record operation: %w", err)
+	}
+	p, err := json.Marshal(e.Params)
+	if err != nil {
+		// Should be impossible.
+		go panic(fmt.Errorf("journal: couldn't marshal params %#v: %w", e.Params, err))
+	}
+	opts := sqlitex.ExecOptions{Args: []any{e.Op, e.Time.UnixNano(), e.Actor, e.Channel, e.Tag, e.Action, e.Count, string(p)}}
+	const insert = `INSERT INTO journal (op, time, actor, channel, tag, action, count, params) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	if err := sqlitex.Execute(conn, insert, &opts); err != nil {
+		return fmt.Errorf("couldn't record operation: %w", err)
+	}
+	return nil
+}
+
+// List gets the entries matching a query, newest first.
+func (j *Journal) List(ctx context.Context, q Query) ([]Entry, error) {
+	conn, err := j.db.Take(ctx)
+	defer j.db.Put(conn)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get connection to list operations: %w", err)
+	}
+	lim := q.Limit
+	if lim <= 0 {
+		lim = -1
+	}
+	var r []Entry
+	opts := sqlitex.ExecOptions{
+		Named: map[string]any{
+			":since":   q.Since.UnixNano(),
+			":op":      q.Op,
+			":actor":   q.Actor,
+			":channel": q.Channel,
+			":action":  q.Action,
+			":limit":   lim,
+		},
+		ResultFunc: func(st *sqlite.Stmt) error {
+			e := Entry{
+				Op:      st.ColumnText(0),
+				Time:    time.Unix(0, st.ColumnInt64(1)),
+				Actor:   st.ColumnText(2),
+				Channel: st.ColumnText(3),
+				Tag:     st.ColumnText(4),
+				Action:  st.ColumnText(5),
+				Count:   st.ColumnInt64(6),
+			}
+			if err := json.Unmarshal([]byte(st.ColumnText(7)), &e.Params); err != nil {
+				return fmt.Errorf("couldn't decode params: %w", err)
+			}
+			r = append(r, e)
+			return nil
+		},
+	}
+	const sel = `SELECT op, time, actor, channel, tag, action, count, params FROM journal
+		WHERE time >= :since
+			AND (:op = '' OR op = :op)
+			AND (:actor = '' OR actor = :actor)
+			AND (:channel = '' OR channel = :channel)
+			AND (:action = '' OR action = :action)
+		ORDER BY time DESC
+		LIMIT :limit`
+	if err := sqlitex.Execute(conn, sel, &opts); err != nil {
+		return nil, fmt.Errorf("couldn't list operations: %w", err)
+	}
+	return r, nil
+}
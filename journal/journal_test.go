@@ -0,0 +1,71 @@
+package journal_test
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/zephyrtronium/robot/journal"
+)
+
+var dbcount atomic.Uint64
+
+func testConn() *sqlitex.Pool {
+	k := dbcount.Add(1)
+	pool, err := sqlitex.NewPool(fmt.Sprintf("file:%d.db?mode=memory&cache=shared", k), sqlitex.PoolOptions{Flags: sqlite.OpenReadWrite | sqlite.OpenCreate | sqlite.OpenMemory | sqlite.OpenSharedCache | sqlite.OpenURI})
+	if err != nil {
+		panic(err)
+	}
+	return pool
+}
+
+func TestJournal(t *testing.T) {
+	ctx := context.Background()
+	j, err := journal.Open(ctx, testConn())
+	if err != nil {
+		t.Fatalf("couldn't open journal: %v", err)
+	}
+	base := time.Unix(1700000000, 0)
+	entries := []journal.Entry{
+		{Op: "1", Time: base, Actor: "bocchi", Channel: "#kessoku", Tag: "kessoku", Action: "forget-term", Count: 3, Params: map[string]string{"term": "guitar"}},
+		{Op: "2", Time: base.Add(time.Minute), Actor: "twitch", Channel: "#kessoku", Tag: "kessoku", Action: "clear-chat", Count: -1},
+		{Op: "3", Time: base.Add(2 * time.Minute), Actor: "sweep", Tag: "kessoku", Action: "evict", Count: 10},
+		{Op: "4", Time: base.Add(3 * time.Minute), Actor: "ryo", Action: "undelete", Count: 3, Params: map[string]string{"undo": "1"}},
+	}
+	for _, e := range entries {
+		if err := j.Record(ctx, e); err != nil {
+			t.Fatalf("couldn't record %+v: %v", e, err)
+		}
+	}
+	cases := []struct {
+		name string
+		q    journal.Query
+		want []journal.Entry
+	}{
+		{"all", journal.Query{}, []journal.Entry{entries[3], entries[2], entries[1], entries[0]}},
+		{"op", journal.Query{Op: "2"}, []journal.Entry{entries[1]}},
+		{"actor", journal.Query{Actor: "sweep"}, []journal.Entry{entries[2]}},
+		{"channel", journal.Query{Channel: "#kessoku"}, []journal.Entry{entries[1], entries[0]}},
+		{"action", journal.Query{Action: "undelete"}, []journal.Entry{entries[3]}},
+		{"since", journal.Query{Since: base.Add(90 * time.Second)}, []journal.Entry{entries[3], entries[2]}},
+		{"limit", journal.Query{Limit: 1}, []journal.Entry{entries[3]}},
+		{"none", journal.Query{Op: "5"}, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := j.List(ctx, c.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("wrong entries (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/zephyrtronium/robot/kick"
+	"github.com/zephyrtronium/robot/message"
+	"github.com/zephyrtronium/robot/platform"
+)
+
+// kickClient is the bot's connection to Kick.
+type kickClient struct {
+	cl *kick.Client
+	// id is the bot's user ID.
+	id string
+	// name is the bot's username, used to recognize commands.
+	name string
+	// owner is the user ID of the owner.
+	owner string
+	// url is the Pusher WebSocket URL.
+	url string
+	// rooms maps chat room IDs to channel names.
+	rooms map[int64]string
+	// info maps channel names to Kick channel information.
+	info map[string]*kick.Channel
+}
+
+var _ platform.Client = (*kickClient)(nil)
+
+func (kc *kickClient) Platform() string { return "kick" }
+
+func (kc *kickClient) Bot() (id, name string) { return kc.id, kc.name }
+
+func (kc *kickClient) Owner() string { return kc.owner }
+
+func (kc *kickClient) Capabilities() platform.Capabilities {
+	return platform.Capabilities{Replies: true, Live: true, Format: platform.Format{MaxLength: 500}}
+}
+
+// Send sends a message to a channel's chat.
+func (kc *kickClient) Send(ctx context.Context, channel, reply, text string) error {
+	info := kc.info[channel]
+	if info == nil {
+		return fmt.Errorf("no Kick channel %s", channel)
+	}
+	return kc.cl.Send(ctx, info.UserID, reply, text)
+}
+
+// kickSlug returns the Kick channel slug of a channel name like kick:bocchi.
+func kickSlug(name string) (string, bool) {
+	return strings.CutPrefix(name, "kick:")
+}
+
+// InitKick checks the Kick token.
+func (robo *Robot) InitKick(ctx context.Context, cfg KickCfg) error {
+	cl := &kick.Client{Token: cfg.Token}
+	me, err := cl.Me(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't get Kick user: %w", err)
+	}
+	slog.InfoContext(ctx, "Kick user", slog.Int64("id", me.ID), slog.String("name", me.Name))
+	key, cluster := cfg.Key, cfg.Cluster
+	if key == "" {
+		key = kick.PusherKey
+	}
+	if cluster == "" {
+		cluster = "us2"
+	}
+	robo.kick = &kickClient{
+		cl:    cl,
+		id:    strconv.FormatInt(me.ID, 10),
+		name:  me.Name,
+		owner: cfg.Owner,
+		url:   kick.PusherURL(key, cluster),
+		rooms: make(map[int64]string),
+		info:  make(map[string]*kick.Channel),
+	}
+	return nil
+}
+
+// SetKickChannels initializes Kick channel configuration.
+// It must be called after InitKick.
+func (robo *Robot) SetKickChannels(ctx context.Context, global Global, channels map[string]*ChannelCfg) error {
+	for nm, cfg := range channels {
+		for _, p := range cfg.Channels {
+			slug, ok := kickSlug(p)
+			if !ok {
+				return fmt.Errorf("bad channel %q in kick.channels.%s: must be like kick:name", p, nm)
+			}
+			info, err := robo.kick.cl.GetChannel(ctx, slug)
+			if err != nil {
+				return err
+			}
+			robo.kick.rooms[info.Chatroom.ID] = p
+			robo.kick.info[p] = info
+		}
+	}
+	return robo.setChannels(ctx, global, robo.kick, global.Privileges.Kick, channels)
+}
+
+func (kc *kickClient) Run(ctx context.Context, h platform.Handler) error {
+	group, ctx := errgroup.WithContext(ctx)
+	group.Go(func() error { return kc.streamsLoop(ctx, h) })
+	rooms := make([]int64, 0, len(kc.rooms))
+	for id := range kc.rooms {
+		rooms = append(rooms, id)
+	}
+	events := make(chan *kick.Event)
+	group.Go(func() error {
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case ev := <-events:
+				kc.event(ctx, h, ev)
+			}
+		}
+	})
+	group.Go(func() error {
+		for {
+			err := kick.Listen(ctx, kc.url, rooms, events)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			slog.ErrorContext(ctx, "Kick chat failed", slog.Any("err", err))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+			}
+		}
+	})
+	return group.Wait()
+}
+
+// streamsLoop periodically checks which Kick channels are live so that the
+// bot only learns during streams.
+func (kc *kickClient) streamsLoop(ctx context.Context, h platform.Handler) error {
+	tick := time.NewTicker(time.Minute)
+	defer tick.Stop()
+	for {
+		for name, info := range kc.info {
+			cur, err := kc.cl.GetChannel(ctx, info.Slug)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				slog.ErrorContext(ctx, "couldn't check Kick stream", slog.Any("err", err), slog.String("channel", name))
+				continue
+			}
+			h.Live(ctx, kc, name, platform.Stream{Live: cur.Live()})
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+		}
+	}
+}
+
+// event processes an event from Kick chat.
+func (kc *kickClient) event(ctx context.Context, h platform.Handler, ev *kick.Event) {
+	name := kc.rooms[ev.Chatroom]
+	if name == "" {
+		return
+	}
+	switch ev.Kind {
+	case "message":
+		m := message.Incoming{
+			ID:          ev.ID,
+			To:          name,
+			Sender:      strconv.FormatInt(ev.Sender.ID, 10),
+			Name:        ev.Sender.Username,
+			Text:        ev.Content,
+			Timestamp:   ev.Time.UnixMilli(),
+			IsModerator: ev.Sender.Has("moderator") || ev.Sender.Has("broadcaster"),
+			IsElevated:  ev.Sender.Has("subscriber") || ev.Sender.Has("vip"),
+			Emotes:      kickEmotes(ev.Content),
+			Raw:         ev,
+		}
+		h.Message(ctx, kc, &m)
+	case "deleted":
+		h.Delete(ctx, kc, name, ev.ID, "", false)
+	case "banned":
+		h.Clear(ctx, kc, name, strconv.FormatInt(ev.Sender.ID, 10), ev.Time)
+	}
+}
+
+// kickEmote matches emotes in Kick message content, which look like
+// [emote:37226:KEKW].
+var kickEmote = regexp.MustCompile(`\[emote:(\d+):[^\]]*\]`)
+
+// kickEmotes finds the spans of emotes in Kick message content.
+func kickEmotes(text string) []message.Span {
+	var r []message.Span
+	for _, m := range kickEmote.FindAllStringSubmatchIndex(text, -1) {
+		r = append(r, message.Span{Start: m[0], End: m[1], ID: text[m[2]:m[3]]})
+	}
+	return r
+}
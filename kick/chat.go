@@ -0,0 +1,178 @@
+package kick
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// PusherKey is the Pusher application key for Kick chat.
+const PusherKey = "32cbd69e4b950bf97679"
+
+// PusherURL returns the WebSocket URL for a Pusher application.
+func PusherURL(key, cluster string) string {
+	return "wss://ws-" + cluster + ".pusher.com/app/" + key + "?protocol=7&client=js&version=8.4.0&flash=false"
+}
+
+// Event is a chat event.
+type Event struct {
+	// Kind is "message", "deleted", or "banned".
+	Kind string
+	// Chatroom is the ID of the chat room where the event occurred.
+	Chatroom int64
+	// ID is the ID of the message sent or deleted.
+	ID string
+	// Sender is the sender of a message or the user banned.
+	Sender Sender
+	// Content is the text of a message.
+	Content string
+	// Time is the time of the event.
+	Time time.Time
+}
+
+// Sender is a chatter.
+type Sender struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Slug     string `json:"slug"`
+	Identity struct {
+		Badges []struct {
+			Type string `json:"type"`
+		} `json:"badges"`
+	} `json:"identity"`
+}
+
+// Has returns whether the sender has a badge of the given type, such as
+// "moderator", "broadcaster", "vip", or "subscriber".
+func (s *Sender) Has(badge string) bool {
+	for _, b := range s.Identity.Badges {
+		if b.Type == badge {
+			return true
+		}
+	}
+	return false
+}
+
+// frame is a Pusher protocol frame.
+type frame struct {
+	Event   string `json:"event"`
+	Channel string `json:"channel,omitempty"`
+	// Data is a JSON string containing a JSON object in frames from the
+	// server, or an object in frames to the server.
+	Data json.RawMessage `json:"data"`
+}
+
+// parse decodes a frame from the server into an event.
+// It returns nil for frames that aren't chat events.
+func parse(f *frame) (*Event, error) {
+	var kind string
+	switch f.Event {
+	case `App\Events\ChatMessageEvent`:
+		kind = "message"
+	case `App\Events\MessageDeletedEvent`:
+		kind = "deleted"
+	case `App\Events\UserBannedEvent`:
+		kind = "banned"
+	default:
+		return nil, nil
+	}
+	// The data is a JSON object encoded in a JSON string.
+	var s string
+	if err := json.Unmarshal(f.Data, &s); err != nil {
+		return nil, fmt.Errorf("couldn't decode %s data: %w", f.Event, err)
+	}
+	var d struct {
+		ID         string    `json:"id"`
+		ChatroomID int64     `json:"chatroom_id"`
+		Content    string    `json:"content"`
+		CreatedAt  time.Time `json:"created_at"`
+		Sender     Sender    `json:"sender"`
+		Message    struct {
+			ID string `json:"id"`
+		} `json:"message"`
+		User Sender `json:"user"`
+	}
+	if err := json.Unmarshal([]byte(s), &d); err != nil {
+		return nil, fmt.Errorf("couldn't decode %s: %w", f.Event, err)
+	}
+	ev := Event{Kind: kind, Chatroom: chatroom(f.Channel)}
+	switch kind {
+	case "message":
+		ev.ID, ev.Sender, ev.Content, ev.Time = d.ID, d.Sender, d.Content, d.CreatedAt
+	case "deleted":
+		ev.ID, ev.Time = d.Message.ID, time.Now()
+	case "banned":
+		ev.Sender, ev.Time = d.User, time.Now()
+	}
+	return &ev, nil
+}
+
+// chatroom extracts the chat room ID from a Pusher channel name.
+func chatroom(ch string) int64 {
+	s, _ := strings.CutPrefix(ch, "chatrooms.")
+	s, _, _ = strings.Cut(s, ".")
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
+
+// Listen connects to Kick chat through Pusher and sends events in the given
+// chat rooms to events until ctx is canceled or the connection fails.
+func Listen(ctx context.Context, url string, chatrooms []int64, events chan<- *Event) error {
+	ws, err := websocket.Dial(url, "", "https://kick.com")
+	if err != nil {
+		return fmt.Errorf("couldn't connect to chat: %w", err)
+	}
+	defer ws.Close()
+	go func() {
+		<-ctx.Done()
+		ws.Close()
+	}()
+	for _, id := range chatrooms {
+		sub := frame{
+			Event: "pusher:subscribe",
+			Data:  json.RawMessage(`{"auth":"","channel":"chatrooms.` + strconv.FormatInt(id, 10) + `.v2"}`),
+		}
+		if err := websocket.JSON.Send(ws, &sub); err != nil {
+			return fmt.Errorf("couldn't subscribe to chat room %d: %w", id, err)
+		}
+	}
+	for {
+		// Pusher pings every couple minutes, so a long silence means the
+		// connection is dead.
+		ws.SetReadDeadline(time.Now().Add(5 * time.Minute))
+		var f frame
+		if err := websocket.JSON.Receive(ws, &f); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("couldn't read from chat: %w", err)
+		}
+		switch f.Event {
+		case "pusher:ping":
+			pong := frame{Event: "pusher:pong", Data: json.RawMessage(`{}`)}
+			if err := websocket.JSON.Send(ws, &pong); err != nil {
+				return fmt.Errorf("couldn't pong: %w", err)
+			}
+			continue
+		case "pusher:error":
+			return fmt.Errorf("chat error: %s", f.Data)
+		}
+		ev, err := parse(&f)
+		if err != nil {
+			return err
+		}
+		if ev == nil {
+			continue
+		}
+		select {
+		case events <- ev:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Package kick implements the parts of the Kick API and chat that Robot uses.
+package kick
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Client holds the context for requests to Kick.
+type Client struct {
+	// HTTP is the HTTP client for performing requests.
+	// If nil, http.DefaultClient is used.
+	HTTP *http.Client
+	// Token is the OAuth2 user access token for the public API.
+	Token string
+	// API is the base URL of the public API. If it is empty,
+	// https://api.kick.com is used.
+	API string
+	// Site is the base URL of the site API. If it is empty, https://kick.com
+	// is used.
+	Site string
+}
+
+// reqjson performs an HTTP request and decodes the response as JSON.
+// The response body is truncated to 2 MB.
+func (c *Client) reqjson(ctx context.Context, method, u string, body, r any) error {
+	var rd io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("couldn't encode request: %w", err)
+		}
+		rd = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, rd)
+	if err != nil {
+		return fmt.Errorf("couldn't make request: %w", err)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	hc := c.HTTP
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("couldn't %s: %w", method, err)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 2<<20))
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("couldn't read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request failed: %s (%s)", b, resp.Status)
+	}
+	if r == nil {
+		return nil
+	}
+	if err := json.Unmarshal(b, r); err != nil {
+		return fmt.Errorf("couldn't decode JSON response: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) api(ep string) string {
+	base := c.API
+	if base == "" {
+		base = "https://api.kick.com"
+	}
+	u, err := url.JoinPath(base, ep)
+	if err != nil {
+		panic("kick: bad url join with " + ep)
+	}
+	return u
+}
+
+func (c *Client) site(ep string) string {
+	base := c.Site
+	if base == "" {
+		base = "https://kick.com"
+	}
+	u, err := url.JoinPath(base, ep)
+	if err != nil {
+		panic("kick: bad url join with " + ep)
+	}
+	return u
+}
+
+// User is a Kick user.
+type User struct {
+	ID   int64  `json:"user_id"`
+	Name string `json:"name"`
+}
+
+// Me returns the user who owns the client's token.
+func (c *Client) Me(ctx context.Context) (*User, error) {
+	var r struct {
+		Data []User `json:"data"`
+	}
+	if err := c.reqjson(ctx, "GET", c.api("public/v1/users"), nil, &r); err != nil {
+		return nil, fmt.Errorf("couldn't get user: %w", err)
+	}
+	if len(r.Data) == 0 {
+		return nil, fmt.Errorf("couldn't get user: no user for token")
+	}
+	return &r.Data[0], nil
+}
+
+// Channel is a Kick channel.
+type Channel struct {
+	// ID is the channel ID.
+	ID int64 `json:"id"`
+	// UserID is the user ID of the broadcaster.
+	UserID int64 `json:"user_id"`
+	// Slug is the channel's name in URLs.
+	Slug string `json:"slug"`
+	// Chatroom is the channel's chat room.
+	Chatroom struct {
+		ID int64 `json:"id"`
+	} `json:"chatroom"`
+	// Livestream is the current stream. It is nil if the channel is offline.
+	Livestream *struct {
+		ID int64 `json:"id"`
+	} `json:"livestream"`
+}
+
+// Live returns whether the channel is streaming.
+func (ch *Channel) Live() bool {
+	return ch.Livestream != nil
+}
+
+// GetChannel gets information about a channel by its slug.
+func (c *Client) GetChannel(ctx context.Context, slug string) (*Channel, error) {
+	var r Channel
+	if err := c.reqjson(ctx, "GET", c.site("api/v2/channels/"+url.PathEscape(slug)), nil, &r); err != nil {
+		return nil, fmt.Errorf("couldn't get channel %s: %w", slug, err)
+	}
+	return &r, nil
+}
+
+// Send sends a chat message as a bot to a broadcaster's chat, optionally in
+// reply to another message.
+func (c *Client) Send(ctx context.Context, broadcaster int64, reply, text string) error {
+	body := map[string]any{
+		"broadcaster_user_id": broadcaster,
+		"content":             text,
+		"type":                "bot",
+	}
+	if reply != "" {
+		body["reply_to_message_id"] = reply
+	}
+	if err := c.reqjson(ctx, "POST", c.api("public/v1/chat"), body, nil); err != nil {
+		return fmt.Errorf("couldn't send to %s: %w", strconv.FormatInt(broadcaster, 10), err)
+	}
+	return nil
+}
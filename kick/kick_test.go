@@ -0,0 +1,130 @@
+package kick
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	b, err := os.ReadFile("testdata/message.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name string
+		raw  string
+		want *Event
+	}{
+		{
+			name: "message",
+			raw:  string(b),
+			want: &Event{
+				Kind:     "message",
+				Chatroom: 668,
+				ID:       "a1b2c3",
+				Content:  "hello [emote:37226:KEKW]",
+				Time:     time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "deleted",
+			raw:  `{"event":"App\\Events\\MessageDeletedEvent","data":"{\"id\":\"x\",\"message\":{\"id\":\"a1b2c3\"}}","channel":"chatrooms.668.v2"}`,
+			want: &Event{Kind: "deleted", Chatroom: 668, ID: "a1b2c3"},
+		},
+		{
+			name: "other",
+			raw:  `{"event":"pusher_internal:subscription_succeeded","data":"{}","channel":"chatrooms.668.v2"}`,
+			want: nil,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var f frame
+			if err := json.Unmarshal([]byte(c.raw), &f); err != nil {
+				t.Fatal(err)
+			}
+			got, err := parse(&f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := []cmp.Option{
+				cmp.FilterPath(func(p cmp.Path) bool {
+					// Sender is checked separately, and deletion times are
+					// the time of receipt.
+					return p.Last().String() == ".Sender" || (c.name != "message" && p.Last().String() == ".Time")
+				}, cmp.Ignore()),
+			}
+			if diff := cmp.Diff(c.want, got, opts...); diff != "" {
+				t.Errorf("wrong event (-want/+got):\n%s", diff)
+			}
+		})
+	}
+	var f frame
+	json.Unmarshal(b, &f)
+	ev, _ := parse(&f)
+	if ev.Sender.ID != 1001 || ev.Sender.Username != "Bocchi" {
+		t.Errorf("wrong sender: %+v", ev.Sender)
+	}
+	if !ev.Sender.Has("moderator") || !ev.Sender.Has("subscriber") || ev.Sender.Has("vip") {
+		t.Errorf("wrong badges: %+v", ev.Sender.Identity.Badges)
+	}
+}
+
+func TestSend(t *testing.T) {
+	var auth string
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/public/v1/chat" {
+			http.NotFound(w, r)
+			return
+		}
+		auth = r.Header.Get("Authorization")
+		b, _ := io.ReadAll(r.Body)
+		json.Unmarshal(b, &body)
+		io.WriteString(w, `{"data":{"is_sent":true}}`)
+	}))
+	defer srv.Close()
+	cl := Client{Token: "bocchi", API: srv.URL}
+	if err := cl.Send(context.Background(), 1234, "a1b2c3", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Bearer bocchi" {
+		t.Errorf("wrong auth: %q", auth)
+	}
+	want := map[string]any{
+		"broadcaster_user_id": 1234.0,
+		"content":             "hello",
+		"type":                "bot",
+		"reply_to_message_id": "a1b2c3",
+	}
+	if diff := cmp.Diff(want, body); diff != "" {
+		t.Errorf("wrong body:\n%s", diff)
+	}
+}
+
+func TestGetChannel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/channels/bocchi" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, `{"id":55,"user_id":1001,"slug":"bocchi","chatroom":{"id":668},"livestream":{"id":9}}`)
+	}))
+	defer srv.Close()
+	cl := Client{Site: srv.URL}
+	ch, err := cl.GetChannel(context.Background(), "bocchi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch.ID != 55 || ch.UserID != 1001 || ch.Chatroom.ID != 668 || !ch.Live() {
+		t.Errorf("wrong channel: %+v", ch)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/dgraph-io/badger/v4/options"
+)
+
+// kvOptions returns the options for opening the kvbrain database.
+func kvOptions(cfg DBCfg) badger.Options {
+	opts := badger.DefaultOptions(cfg.KVBrain)
+	// TODO(zeph): logger?
+	opts = opts.WithLogger(nil)
+	opts = opts.WithCompression(options.None)
+	opts = opts.WithBloomFalsePositive(0)
+	return opts.FromSuperFlag(cfg.KVFlag)
+}
+
+// openKV opens the kvbrain database. If it fails to open because it is
+// damaged, each recovery strategy in cfg.Recover is tried in order until one
+// succeeds.
+func openKV(ctx context.Context, cfg DBCfg) (*badger.DB, error) {
+	opts := kvOptions(cfg)
+	db, err := badger.Open(opts)
+	if err == nil {
+		return db, nil
+	}
+	if len(cfg.Recover) == 0 {
+		return nil, err
+	}
+	if !kvRecoverable(err) {
+		// Recovering would set aside a healthy database, e.g. one that
+		// another instance of the bot is using.
+		return nil, err
+	}
+	slog.ErrorContext(ctx, "couldn't open kvbrain db; trying recovery", slog.String("path", cfg.KVBrain), slog.Any("err", err))
+	errs := []error{err}
+	for _, how := range cfg.Recover {
+		db, err := recoverKV(ctx, how, opts, cfg.Backups)
+		if err == nil {
+			slog.WarnContext(ctx, "recovered kvbrain db", slog.String("path", cfg.KVBrain), slog.String("how", how))
+			return db, nil
+		}
+		slog.ErrorContext(ctx, "kvbrain recovery failed", slog.String("how", how), slog.Any("err", err))
+		errs = append(errs, fmt.Errorf("%s: %w", how, err))
+	}
+	return nil, errors.Join(errs...)
+}
+
+// kvRecoverable reports whether an error opening a badger database could mean
+// that the database is damaged. Failing to lock the directory or lacking
+// permissions says nothing about its contents.
+// Badger formats most errors instead of wrapping them, so this checks the
+// messages as well.
+func kvRecoverable(err error) bool {
+	if errors.Is(err, fs.ErrPermission) {
+		return false
+	}
+	msg := err.Error()
+	for _, s := range []string{"Cannot acquire directory lock", "permission denied", "operation not permitted"} {
+		if strings.Contains(msg, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// recoverKV attempts one recovery strategy for a kvbrain database that fails
+// to open.
+func recoverKV(ctx context.Context, how string, opts badger.Options, backups string) (*badger.DB, error) {
+	switch how {
+	case "truncate":
+		// Badger discards partially written entries at the ends of its logs
+		// whenever it opens for writing, so only a read-only open can fail
+		// for want of truncation. Open for writing once to truncate, then
+		// open as configured.
+		if !opts.ReadOnly {
+			return nil, errors.New("logs are already truncated when opening for writing")
+		}
+		db, err := badger.Open(opts.WithReadOnly(false))
+		if err != nil {
+			return nil, err
+		}
+		if err := db.Close(); err != nil {
+			return nil, fmt.Errorf("couldn't close after truncating: %w", err)
+		}
+		return badger.Open(opts)
+	case "restore":
+		f, err := latestBackup(backups)
+		if err != nil {
+			return nil, err
+		}
+		if err := setAside(ctx, opts.Dir); err != nil {
+			return nil, err
+		}
+		db, err := badger.Open(opts)
+		if err != nil {
+			return nil, err
+		}
+		if err := restoreKV(db, f); err != nil {
+			db.Close()
+			return nil, err
+		}
+		slog.WarnContext(ctx, "restored kvbrain db from backup", slog.String("backup", f))
+		return db, nil
+	case "fresh":
+		if err := setAside(ctx, opts.Dir); err != nil {
+			return nil, err
+		}
+		return badger.Open(opts)
+	default:
+		return nil, fmt.Errorf("unknown recovery strategy %q", how)
+	}
+}
+
+// restoreKV loads a backup file into db.
+func restoreKV(db *badger.DB, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("couldn't open backup: %w", err)
+	}
+	defer f.Close()
+	if err := db.Load(f, 256); err != nil {
+		return fmt.Errorf("couldn't load backup %s: %w", file, err)
+	}
+	return nil
+}
+
+// latestBackup finds the most recently modified backup file. If path is a
+// file, it is the backup; if it is a directory, the newest file in it is.
+func latestBackup(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("no backups configured")
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("couldn't find backups: %w", err)
+	}
+	if !fi.IsDir() {
+		return path, nil
+	}
+	ents, err := os.ReadDir(path)
+	if err != nil {
+		return "", fmt.Errorf("couldn't list backups: %w", err)
+	}
+	var best string
+	var when time.Time
+	for _, e := range ents {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		if best == "" || fi.ModTime().After(when) {
+			best, when = filepath.Join(path, e.Name()), fi.ModTime()
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("no backups in %s", path)
+	}
+	return best, nil
+}
+
+// setAside moves a corrupt database directory out of the way, preserving it
+// for inspection.
+func setAside(ctx context.Context, dir string) error {
+	to := dir + ".corrupt-" + time.Now().UTC().Format("20060102T150405")
+	if err := os.Rename(dir, to); err != nil {
+		return fmt.Errorf("couldn't set aside corrupt db: %w", err)
+	}
+	slog.WarnContext(ctx, "moved corrupt kvbrain db", slog.String("from", dir), slog.String("to", to))
+	return nil
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+// corruptKV creates a badger database in dir holding key k and then corrupts
+// its manifest so that it fails to open.
+func corruptKV(t *testing.T, dir, k string, backup string) {
+	t.Helper()
+	db, err := badger.Open(badger.DefaultOptions(dir).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(txn *badger.Txn) error { return txn.Set([]byte(k), []byte("v")) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backup != "" {
+		f, err := os.Create(backup)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Backup(f, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "MANIFEST"), []byte("not a manifest"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// crashedKV creates a badger database in dir holding key k as if the process
+// crashed while it was open, with a torn write at the end of its log.
+func crashedKV(t *testing.T, dir, k string) {
+	t.Helper()
+	live := t.TempDir()
+	db, err := badger.Open(badger.DefaultOptions(live).WithLogger(nil).WithMemTableSize(1 << 20).WithValueThreshold(1 << 10).WithValueLogFileSize(1 << 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Update(func(txn *badger.Txn) error { return txn.Set([]byte(k), []byte("v")) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	// Copy the files while the database is open, as they would be on disk
+	// after a crash.
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	ents, err := os.ReadDir(live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range ents {
+		if e.Name() == "LOCK" {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(live, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Ext(e.Name()) == ".mem" {
+			// Tear a write after the entry.
+			copy(b[4096:], "kessoku band kessoku band")
+		}
+		if err := os.WriteFile(filepath.Join(dir, e.Name()), b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func hasKey(t *testing.T, db *badger.DB, k string) bool {
+	t.Helper()
+	err := db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get([]byte(k))
+		return err
+	})
+	return err == nil
+}
+
+func TestOpenKVRecover(t *testing.T) {
+	ctx := context.Background()
+	t.Run("none", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "kv")
+		corruptKV(t, dir, "bocchi", "")
+		if db, err := openKV(ctx, DBCfg{KVBrain: dir}); err == nil {
+			db.Close()
+			t.Fatal("opened corrupt db without recovery")
+		}
+	})
+	t.Run("truncate", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "kv")
+		crashedKV(t, dir, "bocchi")
+		cfg := DBCfg{KVBrain: dir, KVFlag: "readonly=true"}
+		if db, err := openKV(ctx, cfg); err == nil {
+			db.Close()
+			t.Fatal("opened crashed db read-only without truncating")
+		}
+		cfg.Recover = []string{"truncate"}
+		db, err := openKV(ctx, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		if !db.Opts().ReadOnly {
+			t.Error("truncated db isn't read-only")
+		}
+		if !hasKey(t, db, "bocchi") {
+			t.Error("truncated db is missing knowledge")
+		}
+	})
+	t.Run("truncate-writable", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "kv")
+		corruptKV(t, dir, "bocchi", "")
+		if db, err := openKV(ctx, DBCfg{KVBrain: dir, Recover: []string{"truncate"}}); err == nil {
+			db.Close()
+			t.Fatal("truncate opened a db with a corrupt manifest")
+		}
+	})
+	t.Run("fresh", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "kv")
+		corruptKV(t, dir, "bocchi", "")
+		db, err := openKV(ctx, DBCfg{KVBrain: dir, Recover: []string{"truncate", "fresh"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		if hasKey(t, db, "bocchi") {
+			t.Error("fresh db has old knowledge")
+		}
+		m, _ := filepath.Glob(dir + ".corrupt-*")
+		if len(m) != 1 {
+			t.Errorf("corrupt db not preserved: %q", m)
+		}
+	})
+	t.Run("restore", func(t *testing.T) {
+		tmp := t.TempDir()
+		dir := filepath.Join(tmp, "kv")
+		bk := filepath.Join(tmp, "backups")
+		if err := os.Mkdir(bk, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		corruptKV(t, dir, "bocchi", filepath.Join(bk, "robot.bak"))
+		db, err := openKV(ctx, DBCfg{KVBrain: dir, Recover: []string{"restore", "fresh"}, Backups: bk})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		if !hasKey(t, db, "bocchi") {
+			t.Error("restored db is missing knowledge")
+		}
+	})
+	t.Run("locked", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "kv")
+		cfg := DBCfg{KVBrain: dir, Recover: []string{"truncate", "restore", "fresh"}}
+		live, err := openKV(ctx, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer live.Close()
+		err = live.Update(func(txn *badger.Txn) error { return txn.Set([]byte("bocchi"), []byte("v")) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if db, err := openKV(ctx, cfg); err == nil {
+			db.Close()
+			t.Fatal("opened a db in use")
+		}
+		if m, _ := filepath.Glob(dir + ".corrupt-*"); len(m) != 0 {
+			t.Errorf("db in use set aside: %q", m)
+		}
+		if !hasKey(t, live, "bocchi") {
+			t.Error("db in use lost knowledge")
+		}
+	})
+	t.Run("nobackup", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "kv")
+		corruptKV(t, dir, "bocchi", "")
+		if db, err := openKV(ctx, DBCfg{KVBrain: dir, Recover: []string{"restore"}}); err == nil {
+			db.Close()
+			t.Fatal("restored without backups")
+		}
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("corrupt db moved without a replacement: %v", err)
+		}
+	})
+}
+
+func TestKVRecoverable(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"corrupt", errors.New("Manifest has bad magic"), true},
+		{"locked", errors.New(`Cannot acquire directory lock on "/kv".  Another process is using this Badger database. error: resource temporarily unavailable`), false},
+		{"permission", fmt.Errorf("opening: %w", fs.ErrPermission), false},
+		{"permission-text", errors.New("Error opening /kv/MANIFEST: open /kv/MANIFEST: permission denied"), false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := kvRecoverable(c.err); got != c.want {
+				t.Errorf("wrong recoverability: want %t, got %t", c.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/chatlog"
+	"github.com/zephyrtronium/robot/privacy"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// logLearnBatch is the number of messages from logs to learn at once.
+const logLearnBatch = 256
+
+// logLearner learns events read from chat logs.
+type logLearner struct {
+	// br is the brain to learn into. It is nil for dry runs.
+	br brain.Brain
+	// priv is the privacy list to check senders against.
+	priv interface {
+		Check(ctx context.Context, user string) error
+	}
+	// logins resolves the senders of logs which record only usernames.
+	// If it is nil, those messages have no sender.
+	logins loginResolver
+	// hasher computes userhashes of senders.
+	hasher userhash.Hasher
+	// tag is the tag to learn into, and channel is the channel in which the
+	// messages were sent for userhashes.
+	tag, channel string
+	// unattributable allows learning messages which have no sender.
+	unattributable bool
+
+	// msgs and toks are the batch of messages waiting to be learned.
+	msgs []brain.Message
+	toks [][]string
+
+	stats logLearnStats
+}
+
+// logLearnStats counts what happened to events read from logs.
+type logLearnStats struct {
+	// Read is the number of messages read.
+	Read int64
+	// Learned is the number of messages learned. Messages already learned
+	// by earlier runs count again but don't change the brain.
+	Learned int64
+	// Private is the number of messages skipped because their senders are
+	// private.
+	Private int64
+	// Unattributable is the number of messages skipped because they have no
+	// sender, including ones whose usernames couldn't be resolved.
+	Unattributable int64
+	// Forgotten is the number of deletions and chat clears applied.
+	Forgotten int64
+}
+
+// event processes one event from a log. Messages are batched, so flush must
+// be called after the last event.
+func (l *logLearner) event(ctx context.Context, e *chatlog.Event) error {
+	switch e.Kind {
+	case "message":
+		l.stats.Read++
+		var user userhash.Hash
+		switch {
+		case e.Login != "":
+			// Privacy and userhashes use user IDs, so messages by username
+			// are only learned once resolved, even if unattributable ones
+			// are allowed. Check the username as well in case the privacy
+			// list has it.
+			if ok, err := l.private(ctx, e.Login); ok || err != nil {
+				return err
+			}
+			if e.Sender == "" && l.logins != nil {
+				id, err := l.logins.ID(ctx, e.Login)
+				if err != nil {
+					return err
+				}
+				e.Sender = id
+			}
+			if e.Sender == "" {
+				l.stats.Unattributable++
+				return nil
+			}
+			fallthrough
+		case e.Sender != "":
+			if ok, err := l.private(ctx, e.Sender); ok || err != nil {
+				return err
+			}
+			l.hasher.Hash(&user, e.Sender, l.channel, e.Time)
+		case !l.unattributable:
+			l.stats.Unattributable++
+			return nil
+		}
+		toks := brain.Tokens(nil, e.Text)
+		if len(toks) == 0 {
+			return nil
+		}
+		l.stats.Learned++
+		l.msgs = append(l.msgs, brain.Message{Tag: l.tag, ID: e.ID, User: user, Time: e.Time})
+		l.toks = append(l.toks, toks)
+		if len(l.msgs) >= logLearnBatch {
+			return l.flush(ctx)
+		}
+	case "deleted":
+		// Learn everything before the deletion first, so that the deletion
+		// finds the message.
+		if err := l.flush(ctx); err != nil {
+			return err
+		}
+		l.stats.Forgotten++
+		if l.br == nil {
+			return nil
+		}
+		return l.br.ForgetMessage(ctx, l.tag, e.ID)
+	case "cleared":
+		if e.Sender == "" {
+			// The entire chat was cleared, which doesn't forget anything
+			// when it happens live either.
+			return nil
+		}
+		if err := l.flush(ctx); err != nil {
+			return err
+		}
+		l.stats.Forgotten++
+		if l.br == nil {
+			return nil
+		}
+		// Userhashes are time-based, so forget the current and previous
+		// ones like clearing live chat does.
+		u := l.hasher.Hash(new(userhash.Hash), e.Sender, l.channel, e.Time)
+		if err := l.br.ForgetUser(ctx, u); err != nil {
+			return err
+		}
+		u = l.hasher.Hash(u, e.Sender, l.channel, e.Time.Add(-userhash.TimeQuantum))
+		return l.br.ForgetUser(ctx, u)
+	}
+	return nil
+}
+
+// private checks whether a user is private, counting the message as skipped
+// if so.
+func (l *logLearner) private(ctx context.Context, user string) (bool, error) {
+	switch err := l.priv.Check(ctx, user); {
+	case errors.Is(err, privacy.ErrPrivate):
+		l.stats.Private++
+		return true, nil
+	case err != nil:
+		return false, err
+	}
+	return false, nil
+}
+
+// flush learns the waiting batch of messages.
+func (l *logLearner) flush(ctx context.Context) error {
+	if len(l.msgs) == 0 {
+		return nil
+	}
+	var err error
+	if l.br != nil {
+		err = brain.LearnBatch(ctx, l.br, l.msgs, l.toks)
+	}
+	l.msgs, l.toks = l.msgs[:0], l.toks[:0]
+	return err
+}
+
+func cliLearn(ctx context.Context, cmd *cli.Command) error {
+	files := cmd.Args().Slice()
+	if len(files) == 0 {
+		return errors.New("no log files given")
+	}
+	tag := cmd.String("tag")
+	if err := checkTag(tag); err != nil {
+		return err
+	}
+	format := cmd.String("format")
+	if format != "auto" {
+		if _, err := chatlog.ParseFormat(format); err != nil {
+			return err
+		}
+	}
+	cfg, err := cliConfig(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	k, err := loadSecrets(cfg.SecretFile)
+	if err != nil {
+		return err
+	}
+	db, err := loadDBs(ctx, cfg.DB)
+	if err != nil {
+		return err
+	}
+	priv, err := privacy.Open(ctx, db.priv)
+	if err != nil {
+		return fmt.Errorf("couldn't open privacy list: %w", err)
+	}
+	l := logLearner{
+		priv:           priv,
+		hasher:         userhash.New(k.userhash),
+		tag:            tag,
+		channel:        cmd.String("channel"),
+		unattributable: cmd.Bool("unattributable"),
+	}
+	if cfg.TMI.CID != "" {
+		// Chatterino and some RustyLog logs record only usernames.
+		l.logins, err = newTwitchLogins(cfg.TMI)
+		if err != nil {
+			return err
+		}
+	}
+	if !cmd.Bool("dry-run") {
+		br, done, err := openBrain(ctx, db)
+		if err != nil {
+			return err
+		}
+		defer done()
+		l.br = br
+	}
+	for _, name := range files {
+		if err := l.file(ctx, name, format); err != nil {
+			return err
+		}
+	}
+	s := &l.stats
+	verb := "learned"
+	if l.br == nil {
+		verb = "would learn"
+	}
+	fmt.Printf("read %d messages from %d files; %s %d, skipped %d from private users and %d without senders, forgot %d\n",
+		s.Read, len(files), verb, s.Learned, s.Private, s.Unattributable, s.Forgotten)
+	if s.Unattributable != 0 {
+		fmt.Println("messages without senders can't be forgotten by user; pass --unattributable to learn them anyway")
+		if l.logins == nil {
+			fmt.Println("messages with only usernames need the tmi client ID and secret in the config to look up user IDs")
+		}
+	}
+	return nil
+}
+
+// file learns from one log file, reporting progress as it goes.
+func (l *logLearner) file(ctx context.Context, name, format string) error {
+	var f chatlog.Format
+	if format == "auto" {
+		var ok bool
+		f, ok = chatlog.Detect(name)
+		if !ok {
+			return fmt.Errorf("can't tell the format of %s; use --format", name)
+		}
+	} else {
+		f, _ = chatlog.ParseFormat(format)
+	}
+	r, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("couldn't open log: %w", err)
+	}
+	defer r.Close()
+	// Plain logs don't record times, so use the time the file was written.
+	var mtime time.Time
+	if fi, err := r.Stat(); err == nil {
+		mtime = fi.ModTime()
+	}
+	last := time.Now()
+	err = chatlog.Read(r, name, f, func(e *chatlog.Event) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if e.Time.IsZero() {
+			e.Time = mtime
+		}
+		if time.Since(last) >= 5*time.Second {
+			last = time.Now()
+			fmt.Fprintf(os.Stderr, "%s: read %d messages so far\n", name, l.stats.Read)
+		}
+		return l.event(ctx, e)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if err := l.flush(ctx); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	fmt.Fprintf(os.Stderr, "%s: done, %d messages read in total\n", name, l.stats.Read)
+	return nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/membrain"
+	"github.com/zephyrtronium/robot/chatlog"
+	"github.com/zephyrtronium/robot/privacy"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+// privateUsers is a privacy list of fixed users.
+type privateUsers []string
+
+func (p privateUsers) Check(ctx context.Context, user string) error {
+	for _, u := range p {
+		if u == user {
+			return privacy.ErrPrivate
+		}
+	}
+	return nil
+}
+
+func TestLogLearner(t *testing.T) {
+	ctx := context.Background()
+	logs := `{"messages": [
+		{"id": "1", "text": "kessoku band", "username": "nijika", "timestamp": "2024-10-01T00:00:00Z", "type": 1, "tags": {"user-id": "1"}},
+		{"id": "2", "text": "private message", "username": "kita", "timestamp": "2024-10-01T00:00:01Z", "type": 1, "tags": {"user-id": "2"}},
+		{"id": "3", "text": "deleted message", "username": "ryo", "timestamp": "2024-10-01T00:00:02Z", "type": 1, "tags": {"user-id": "3"}},
+		{"raw": "@target-msg-id=3;tmi-sent-ts=1727740803000 :tmi.twitch.tv CLEARMSG #bocchi :deleted message"}
+	]}`
+	cases := []struct {
+		name    string
+		dry     bool
+		learned bool
+	}{
+		{"learn", false, true},
+		{"dry-run", true, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			br := membrain.New()
+			l := logLearner{
+				br:      br,
+				priv:    privateUsers{"2"},
+				hasher:  userhash.New(make([]byte, 64)),
+				tag:     "twitch/bocchi",
+				channel: "#bocchi",
+			}
+			if c.dry {
+				l.br = nil
+			}
+			err := chatlog.Read(strings.NewReader(logs), "bocchi.json", chatlog.RustyLog, func(e *chatlog.Event) error {
+				return l.event(ctx, e)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := l.flush(ctx); err != nil {
+				t.Fatal(err)
+			}
+			want := logLearnStats{Read: 3, Learned: 2, Private: 1, Forgotten: 1}
+			if l.stats != want {
+				t.Errorf("wrong stats: want %+v, got %+v", want, l.stats)
+			}
+			got := map[string]bool{}
+			for range 100 {
+				s, _, err := brain.Speak(ctx, br, "twitch/bocchi", "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if s != "" {
+					got[s] = true
+				}
+			}
+			if c.learned != got["kessoku band"] {
+				t.Errorf("wrong learning: %v", got)
+			}
+			if got["private message"] || got["deleted message"] {
+				t.Errorf("learned forbidden messages: %v", got)
+			}
+		})
+	}
+}
+
+func TestLogLearnerUnattributable(t *testing.T) {
+	ctx := context.Background()
+	for _, allow := range []bool{false, true} {
+		br := membrain.New()
+		l := logLearner{
+			br:             br,
+			priv:           privateUsers{},
+			hasher:         userhash.New(make([]byte, 64)),
+			tag:            "twitch/bocchi",
+			channel:        "#bocchi",
+			unattributable: allow,
+		}
+		if err := l.event(ctx, &chatlog.Event{Kind: "message", ID: "1", Text: "guitar hero"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := l.flush(ctx); err != nil {
+			t.Fatal(err)
+		}
+		s, _, err := brain.Speak(ctx, br, "twitch/bocchi", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if allow != (s == "guitar hero") {
+			t.Errorf("wrong learning with unattributable %t: %q, %+v", allow, s, l.stats)
+		}
+	}
+}
+
+func TestLogLearnerLogins(t *testing.T) {
+	ctx := context.Background()
+	logs := "[12:34:56] Nijika: kessoku band\n[12:34:57] Kita: private message\n[12:34:58] ryo: nobody\n"
+	br := membrain.New()
+	l := logLearner{
+		br:             br,
+		priv:           privateUsers{"2"},
+		logins:         fixedLogins{"nijika": "1", "kita": "2"},
+		hasher:         userhash.New(make([]byte, 64)),
+		tag:            "twitch/bocchi",
+		channel:        "#bocchi",
+		unattributable: true,
+	}
+	err := chatlog.Read(strings.NewReader(logs), "bocchi-2024-08-05.log", chatlog.Chatterino, func(e *chatlog.Event) error {
+		return l.event(ctx, e)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := logLearnStats{Read: 3, Learned: 1, Private: 1, Unattributable: 1}
+	if l.stats != want {
+		t.Errorf("wrong stats: want %+v, got %+v", want, l.stats)
+	}
+	s, _, err := brain.Speak(ctx, br, "twitch/bocchi", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "kessoku band" {
+		t.Errorf("wrong learning: %q", s)
+	}
+	// The message belongs to the user ID, so forgetting the user as live chat
+	// does removes it.
+	u := l.hasher.Hash(new(userhash.Hash), "1", "#bocchi", time.Date(2024, 8, 5, 12, 34, 56, 0, time.Local))
+	if err := br.ForgetUser(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	s, _, err = brain.Speak(ctx, br, "twitch/bocchi", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "" {
+		t.Errorf("spoke after forgetting everyone: %q", s)
+	}
+}
@@ -0,0 +1,72 @@
+// Package locale provides templates for the fixed responses that commands
+// send, so that they can be translated or otherwise customized.
+package locale
+
+import (
+	_ "embed"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// Templates is a set of response templates.
+type Templates struct {
+	t *template.Template
+}
+
+//go:embed en.tmpl
+var defaultSrc string
+
+var defaults = &Templates{t: template.Must(template.New("en").Parse(defaultSrc))}
+
+// Default returns the built-in English templates.
+func Default() *Templates {
+	return defaults
+}
+
+// Parse creates a new set of templates from t with the templates defined in
+// src replacing those of the same names.
+// Templates which src does not define are inherited from t.
+func (t *Templates) Parse(name, src string) (*Templates, error) {
+	u, err := t.t.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't clone templates: %w", err)
+	}
+	if _, err := u.New(name).Parse(src); err != nil {
+		return nil, fmt.Errorf("couldn't parse templates: %w", err)
+	}
+	return &Templates{t: u}, nil
+}
+
+// ParseFile is like [Templates.Parse] but reads the templates from a file.
+func (t *Templates) ParseFile(file string) (*Templates, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read templates: %w", err)
+	}
+	return t.Parse(file, string(b))
+}
+
+// Args is the data supplied to a template.
+type Args map[string]any
+
+// Text executes the named template with the given data.
+// If the template doesn't exist or fails to execute, the result is the
+// built-in English response, or the empty string if that fails as well.
+func (t *Templates) Text(name string, data Args) string {
+	if t == nil {
+		t = defaults
+	}
+	var b strings.Builder
+	err := t.t.ExecuteTemplate(&b, name, data)
+	if err == nil {
+		return b.String()
+	}
+	slog.Error("couldn't execute response template", slog.String("name", name), slog.Any("err", err))
+	if t == defaults {
+		return ""
+	}
+	return defaults.Text(name, data)
+}
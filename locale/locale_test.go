@@ -0,0 +1,37 @@
+package locale_test
+
+import (
+	"testing"
+
+	"github.com/zephyrtronium/robot/locale"
+)
+
+func TestTemplates(t *testing.T) {
+	def := locale.Default()
+	if got, want := def.Text("marry-no", locale.Args{"Emote": "Kappa"}), "no Kappa"; got != want {
+		t.Errorf("wrong default: want %q, got %q", want, got)
+	}
+	es, err := def.Parse("es", `{{define "marry-no"}}no {{.Emote}} lo siento{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := es.Text("marry-no", locale.Args{"Emote": "Kappa"}), "no Kappa lo siento"; got != want {
+		t.Errorf("wrong override: want %q, got %q", want, got)
+	}
+	if got, want := es.Text("rawr", locale.Args{"Emote": ":3"}), "rawr :3"; got != want {
+		t.Errorf("wrong inherited: want %q, got %q", want, got)
+	}
+	if got, want := def.Text("marry-no", locale.Args{"Emote": "Kappa"}), "no Kappa"; got != want {
+		t.Errorf("override modified default: want %q, got %q", want, got)
+	}
+	bad, err := def.Parse("bad", `{{define "rawr"}}{{.Emote.Nope}}{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := bad.Text("rawr", locale.Args{"Emote": ":3"}), "rawr :3"; got != want {
+		t.Errorf("wrong fallback: want %q, got %q", want, got)
+	}
+	if got := def.Text("nonexistent", nil); got != "" {
+		t.Errorf("nonexistent template gave %q", got)
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// contentPolicy is when logs may include the content of chat messages.
+type contentPolicy int
+
+const (
+	// contentNone redacts message content from all logs.
+	contentNone contentPolicy = iota
+	// contentDebug allows message content in debug logs only.
+	contentDebug
+	// contentAll allows message content in all logs.
+	contentAll
+)
+
+func parseContentPolicy(s string) (contentPolicy, error) {
+	switch strings.ToLower(s) {
+	case "none", "":
+		return contentNone, nil
+	case "debug":
+		return contentDebug, nil
+	case "all":
+		return contentAll, nil
+	default:
+		return 0, fmt.Errorf("unknown content logging policy %q", s)
+	}
+}
+
+// contentKeys are the attribute keys which hold chat message content.
+// Log sites must use one of these keys for anything a user wrote.
+var contentKeys = map[string]bool{
+	"text":    true,
+	"message": true,
+	"msg":     true,
+	"prompt":  true,
+	"mention": true,
+	"result":  true,
+	"args":    true,
+}
+
+// loginKeys are the attribute keys which hold user names.
+var loginKeys = map[string]bool{
+	"user":     true,
+	"login":    true,
+	"display":  true,
+	"username": true,
+	"who":      true,
+	"memer":    true,
+	"users":    true,
+}
+
+// privacyHandler is a log handler which redacts chat message content and user
+// names before passing records to another handler.
+type privacyHandler struct {
+	h       slog.Handler
+	content contentPolicy
+	// key is the key for pseudonyms of redacted logins.
+	// If it is nil, logins are not redacted.
+	key []byte
+}
+
+// newPrivacyHandler creates a handler that redacts message content according
+// to the content policy and replaces user names with pseudonyms unless logins
+// is true. Pseudonyms are consistent for the lifetime of the process, so that
+// logs about the same user can still be correlated.
+func newPrivacyHandler(h slog.Handler, content contentPolicy, logins bool) *privacyHandler {
+	p := &privacyHandler{h: h, content: content}
+	if !logins {
+		p.key = make([]byte, 32)
+		rand.Read(p.key)
+	}
+	return p
+}
+
+// Enabled reports whether the underlying handler handles records at a level.
+func (p *privacyHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return p.h.Enabled(ctx, l)
+}
+
+// Handle redacts a record and passes it on.
+func (p *privacyHandler) Handle(ctx context.Context, r slog.Record) error {
+	show := p.showContent(r.Level)
+	n := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		n.AddAttrs(p.redact(a, show))
+		return true
+	})
+	return p.h.Handle(ctx, n)
+}
+
+// WithAttrs redacts attributes and attaches them to the underlying handler.
+// Since attached attributes appear at every level, they keep message content
+// only if the policy allows content in all logs.
+func (p *privacyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	q := *p
+	s := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		s[i] = p.redact(a, p.content == contentAll)
+	}
+	q.h = p.h.WithAttrs(s)
+	return &q
+}
+
+// WithGroup opens a group on the underlying handler.
+func (p *privacyHandler) WithGroup(name string) slog.Handler {
+	q := *p
+	q.h = p.h.WithGroup(name)
+	return &q
+}
+
+// showContent reports whether records at a level may include content.
+func (p *privacyHandler) showContent(l slog.Level) bool {
+	switch p.content {
+	case contentAll:
+		return true
+	case contentDebug:
+		return l <= slog.LevelDebug
+	default:
+		return false
+	}
+}
+
+// redact redacts an attribute and any attributes it groups.
+func (p *privacyHandler) redact(a slog.Attr, show bool) slog.Attr {
+	a.Value = a.Value.Resolve()
+	switch {
+	case a.Value.Kind() == slog.KindGroup:
+		g := a.Value.Group()
+		s := make([]slog.Attr, len(g))
+		for i, b := range g {
+			s[i] = p.redact(b, show)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(s...)}
+	case contentKeys[a.Key] && !show:
+		return slog.String(a.Key, "<redacted>")
+	case loginKeys[a.Key] && p.key != nil:
+		return slog.String(a.Key, p.pseudonym(a.Value))
+	}
+	return a
+}
+
+// pseudonym replaces a user name with a stable pseudonym.
+func (p *privacyHandler) pseudonym(v slog.Value) string {
+	if v.Kind() != slog.KindString {
+		return "<redacted>"
+	}
+	if v.String() == "" {
+		return ""
+	}
+	m := hmac.New(sha256.New, p.key)
+	m.Write([]byte(strings.ToLower(v.String())))
+	return "user-" + hex.EncodeToString(m.Sum(nil)[:4])
+}
+
+// sampleHandler is a log handler which passes along only one in every n debug
+// records with the same message, so that debug logs about every chat message
+// stay manageable in busy channels. Once per window, it logs how many records
+// of each message it dropped.
+type sampleHandler struct {
+	h slog.Handler
+	s *sampler
+}
+
+// sampler is the state shared by a sample handler and those derived from it.
+type sampler struct {
+	// base is the handler for summaries.
+	base slog.Handler
+	// n is the sampling ratio.
+	n int
+	// window is the time between summaries.
+	window time.Duration
+
+	// mu guards the fields below.
+	mu sync.Mutex
+	// seen is the number of records with each message in the current window.
+	seen map[string]int
+	// dropped is the number of records with each message that were dropped
+	// in the current window.
+	dropped map[string]int
+	// start is the time at which the current window started.
+	start time.Time
+}
+
+// newSampleHandler creates a handler that samples one in n debug records with
+// each message and summarizes dropped records every window.
+// If n is less than 2, it returns h unchanged.
+func newSampleHandler(h slog.Handler, n int, window time.Duration) slog.Handler {
+	if n < 2 {
+		return h
+	}
+	s := &sampler{
+		base:    h,
+		n:       n,
+		window:  window,
+		seen:    make(map[string]int),
+		dropped: make(map[string]int),
+	}
+	return &sampleHandler{h: h, s: s}
+}
+
+// Enabled reports whether the underlying handler handles records at a level.
+func (h *sampleHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.h.Enabled(ctx, l)
+}
+
+// Handle passes along records which are above debug level or are sampled.
+func (h *sampleHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level > slog.LevelDebug {
+		return h.h.Handle(ctx, r)
+	}
+	keep, summary := h.s.sample(r.Message, r.Time)
+	if summary != nil {
+		s := slog.NewRecord(r.Time, slog.LevelDebug, "sampled debug logs", 0)
+		s.AddAttrs(slog.Int("ratio", h.s.n), slog.Any("dropped", summary))
+		if err := h.s.base.Handle(ctx, s); err != nil {
+			return err
+		}
+	}
+	if !keep {
+		return nil
+	}
+	return h.h.Handle(ctx, r)
+}
+
+// WithAttrs attaches attributes to the underlying handler.
+func (h *sampleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &sampleHandler{h: h.h.WithAttrs(attrs), s: h.s}
+}
+
+// WithGroup opens a group on the underlying handler.
+func (h *sampleHandler) WithGroup(name string) slog.Handler {
+	return &sampleHandler{h: h.h.WithGroup(name), s: h.s}
+}
+
+// sample counts a record with a message at now. It reports whether to keep
+// the record and, if the window has ended with records dropped, the counts of
+// dropped records by message.
+func (s *sampler) sample(msg string, now time.Time) (keep bool, summary map[string]int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.start) >= s.window {
+		if len(s.dropped) > 0 {
+			summary = s.dropped
+			s.dropped = make(map[string]int)
+		}
+		clear(s.seen)
+		s.start = now
+	}
+	c := s.seen[msg]
+	s.seen[msg] = c + 1
+	if c%s.n != 0 {
+		s.dropped[msg]++
+		return false, summary
+	}
+	return true, summary
+}
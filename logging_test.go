@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrivacyHandler(t *testing.T) {
+	cases := []struct {
+		name    string
+		content contentPolicy
+		logins  bool
+		level   slog.Level
+		text    bool
+		login   bool
+	}{
+		{"none-info", contentNone, false, slog.LevelInfo, false, false},
+		{"none-debug", contentNone, false, slog.LevelDebug, false, false},
+		{"debug-info", contentDebug, false, slog.LevelInfo, false, false},
+		{"debug-debug", contentDebug, false, slog.LevelDebug, true, false},
+		{"all-info", contentAll, false, slog.LevelInfo, true, false},
+		{"logins", contentNone, true, slog.LevelInfo, false, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var b bytes.Buffer
+			h := slog.NewTextHandler(&b, &slog.HandlerOptions{Level: slog.LevelDebug})
+			l := slog.New(newPrivacyHandler(h, c.content, c.logins))
+			l.Log(context.Background(), c.level, "learn",
+				slog.String("in", "#bocchi"),
+				slog.String("text", "kessoku band"),
+				slog.Group("g", slog.String("user", "Ryo")),
+			)
+			s := b.String()
+			if got := strings.Contains(s, "kessoku band"); got != c.text {
+				t.Errorf("wrong text presence: want %t, got %q", c.text, s)
+			}
+			if got := strings.Contains(s, "Ryo"); got != c.login {
+				t.Errorf("wrong login presence: want %t, got %q", c.login, s)
+			}
+			if !strings.Contains(s, "#bocchi") {
+				t.Errorf("channel was redacted: %q", s)
+			}
+		})
+	}
+}
+
+func TestPrivacyHandlerPseudonyms(t *testing.T) {
+	var b bytes.Buffer
+	l := slog.New(newPrivacyHandler(slog.NewTextHandler(&b, nil), contentNone, false))
+	l.Info("a", slog.String("user", "Ryo"))
+	l.Info("b", slog.String("user", "ryo"))
+	l.Info("c", slog.String("user", "kita"))
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("wrong number of lines: %q", lines)
+	}
+	user := func(s string) string {
+		_, u, _ := strings.Cut(s, "user=")
+		return u
+	}
+	if user(lines[0]) != user(lines[1]) {
+		t.Errorf("same user got different pseudonyms: %q", lines)
+	}
+	if user(lines[0]) == user(lines[2]) {
+		t.Errorf("different users got the same pseudonym: %q", lines)
+	}
+}
+
+func TestPrivacyHandlerWithAttrs(t *testing.T) {
+	var b bytes.Buffer
+	h := slog.NewTextHandler(&b, &slog.HandlerOptions{Level: slog.LevelDebug})
+	l := slog.New(newPrivacyHandler(h, contentDebug, true)).With(slog.String("prompt", "kessoku band"))
+	l.Debug("speak")
+	if strings.Contains(b.String(), "kessoku band") {
+		t.Errorf("attached content escaped: %q", b.String())
+	}
+}
+
+func TestSampleHandler(t *testing.T) {
+	var b bytes.Buffer
+	h := slog.NewJSONHandler(&b, &slog.HandlerOptions{Level: slog.LevelDebug})
+	l := slog.New(newSampleHandler(h, 10, time.Minute))
+	start := time.Unix(1e9, 0)
+	log := func(now time.Time, level slog.Level, msg string) {
+		r := slog.NewRecord(now, level, msg, 0)
+		l.Handler().Handle(context.Background(), r)
+	}
+	for i := range 25 {
+		log(start.Add(time.Duration(i)*time.Millisecond), slog.LevelDebug, "filtered message")
+	}
+	for range 3 {
+		log(start, slog.LevelInfo, "speak")
+	}
+	log(start.Add(time.Minute), slog.LevelDebug, "blocked message")
+	var counts = map[string]int{}
+	var summary map[string]int
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		var r struct {
+			Msg     string         `json:"msg"`
+			Dropped map[string]int `json:"dropped"`
+		}
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("couldn't decode %q: %v", line, err)
+		}
+		counts[r.Msg]++
+		if r.Dropped != nil {
+			summary = r.Dropped
+		}
+	}
+	want := map[string]int{"filtered message": 3, "speak": 3, "blocked message": 1, "sampled debug logs": 1}
+	if !maps.Equal(counts, want) {
+		t.Errorf("wrong records: want %v, got %v", want, counts)
+	}
+	if summary["filtered message"] != 22 {
+		t.Errorf("wrong summary: %v", summary)
+	}
+}
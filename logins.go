@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/zephyrtronium/robot/auth"
+	"github.com/zephyrtronium/robot/twitch"
+)
+
+// loginResolver resolves logins to user IDs for chat logs which record only
+// usernames, since privacy and userhashes use user IDs.
+type loginResolver interface {
+	// ID returns the user ID of a login, or the empty string if there is no
+	// such user.
+	ID(ctx context.Context, login string) (string, error)
+}
+
+// twitchLogins resolves Twitch logins through the Helix API with an app access
+// token, remembering the results.
+type twitchLogins struct {
+	api    twitch.Client
+	tokens auth.TokenSource
+
+	// mu guards ids.
+	mu sync.Mutex
+	// ids maps logins to user IDs, or to the empty string for logins which
+	// don't exist.
+	ids map[string]string
+}
+
+// newTwitchLogins creates a resolver of Twitch logins using the TMI client's
+// credentials.
+func newTwitchLogins(cfg ClientCfg) (*twitchLogins, error) {
+	if cfg.CID == "" {
+		return nil, errors.New("resolving Twitch logins needs the tmi client ID and secret")
+	}
+	secret, err := os.ReadFile(cfg.SecretFile)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read client secret: %w", err)
+	}
+	secret = bytes.TrimSuffix(bytes.TrimSuffix(secret, []byte{'\n'}), []byte{'\r'})
+	client := &http.Client{Timeout: 30 * time.Second}
+	oc := oauth2.Config{
+		ClientID:     cfg.CID,
+		ClientSecret: string(secret),
+		Endpoint:     twitchEndpoint,
+	}
+	l := twitchLogins{
+		api:    twitch.Client{HTTP: client, ID: cfg.CID},
+		tokens: auth.ClientCredentialsFlow(oc, client),
+		ids:    make(map[string]string),
+	}
+	return &l, nil
+}
+
+func (l *twitchLogins) ID(ctx context.Context, login string) (string, error) {
+	login = strings.ToLower(login)
+	l.mu.Lock()
+	id, ok := l.ids[login]
+	l.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+	tok, err := l.tokens.Token(ctx)
+	if err != nil {
+		return "", fmt.Errorf("couldn't obtain Twitch token: %w", err)
+	}
+	r, err := twitch.Users(ctx, l.api, tok, []twitch.User{{Login: login}})
+	if errors.Is(err, twitch.ErrNeedRefresh) {
+		tok, err = l.tokens.Refresh(ctx, tok)
+		if err != nil {
+			return "", fmt.Errorf("couldn't refresh Twitch token: %w", err)
+		}
+		r, err = twitch.Users(ctx, l.api, tok, []twitch.User{{Login: login}})
+	}
+	if err != nil {
+		return "", fmt.Errorf("couldn't resolve login %s: %w", login, err)
+	}
+	for _, u := range r {
+		if strings.EqualFold(u.Login, login) {
+			id = u.ID
+		}
+	}
+	l.mu.Lock()
+	l.ids[login] = id
+	l.mu.Unlock()
+	return id, nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zephyrtronium/robot/chatlog"
+	"github.com/zephyrtronium/robot/message"
+	"github.com/zephyrtronium/robot/platform"
+)
+
+// logsClient learns from chat logs written by another tool. It never sends
+// anything.
+type logsClient struct {
+	// dir is the directory holding a log directory per channel.
+	dir string
+	// format is the layout of the logs.
+	format chatlog.Format
+	// poll is the interval at which to check for new lines.
+	poll time.Duration
+	// owner is the user ID of the owner.
+	owner string
+	// channels is the names of the channels to follow.
+	channels []string
+	// logins resolves the usernames in logs which don't record user IDs.
+	logins loginResolver
+}
+
+var _ platform.Client = (*logsClient)(nil)
+
+func (lc *logsClient) Platform() string { return "logs" }
+
+// Bot returns no name, so that nothing in logs is a command to the bot.
+func (lc *logsClient) Bot() (id, name string) { return "", "" }
+
+func (lc *logsClient) Owner() string { return lc.owner }
+
+func (lc *logsClient) Capabilities() platform.Capabilities {
+	return platform.Capabilities{Format: platform.Format{MaxLength: 500}}
+}
+
+// Send discards messages, since there is no chat to send them to.
+func (lc *logsClient) Send(ctx context.Context, channel, reply, text string) error {
+	slog.DebugContext(ctx, "not sending to logs", slog.String("in", channel), slog.String("text", text))
+	return nil
+}
+
+// logsDir returns the log directory name of a channel name like log:bocchi.
+func logsDir(name string) (string, bool) {
+	return strings.CutPrefix(name, "log:")
+}
+
+// InitLogs sets up following chat logs. Chatterino logs record only usernames,
+// so following them resolves the usernames to user IDs with the tmi client's
+// credentials.
+func (robo *Robot) InitLogs(ctx context.Context, cfg LogsCfg, tmi ClientCfg) error {
+	format, err := chatlog.ParseFormat(cfg.Format)
+	if err != nil {
+		return err
+	}
+	if format == chatlog.Plain || format == chatlog.RustyLog {
+		return fmt.Errorf("can't follow %s logs; use robot learn to learn from them", cfg.Format)
+	}
+	poll := fseconds(cfg.Poll)
+	if poll <= 0 {
+		poll = time.Second
+	}
+	robo.logs = &logsClient{
+		dir:    cfg.Dir,
+		format: format,
+		poll:   poll,
+		owner:  cfg.Owner,
+	}
+	if format == chatlog.Chatterino {
+		logins, err := newTwitchLogins(tmi)
+		if err != nil {
+			return fmt.Errorf("can't follow chatterino logs: %w", err)
+		}
+		robo.logs.logins = logins
+	}
+	slog.InfoContext(ctx, "following chat logs", slog.String("dir", cfg.Dir), slog.String("format", cfg.Format))
+	return nil
+}
+
+// SetLogsChannels initializes the configuration of channels learned from
+// logs. It must be called after InitLogs.
+func (robo *Robot) SetLogsChannels(ctx context.Context, global Global, channels map[string]*ChannelCfg) error {
+	for _, ch := range channels {
+		for _, p := range ch.Channels {
+			if _, ok := logsDir(p); !ok {
+				return fmt.Errorf("log channel %q must be named like log:channel", p)
+			}
+			robo.logs.channels = append(robo.logs.channels, p)
+		}
+	}
+	return robo.setChannels(ctx, global, robo.logs, global.Privileges.Logs, channels)
+}
+
+func (lc *logsClient) Run(ctx context.Context, h platform.Handler) error {
+	tails := make(map[string]*chatlog.Tail, len(lc.channels))
+	for _, p := range lc.channels {
+		d, _ := logsDir(p)
+		tails[p] = chatlog.New(filepath.Join(lc.dir, d), lc.format)
+	}
+	t := time.NewTicker(lc.poll)
+	defer t.Stop()
+	for {
+		for p, tail := range tails {
+			ev, err := tail.Poll()
+			if err != nil {
+				slog.ErrorContext(ctx, "couldn't read chat logs", slog.String("in", p), slog.Any("err", err))
+			}
+			for _, e := range ev {
+				lc.event(ctx, h, p, &e)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// event processes an event from a channel's logs.
+func (lc *logsClient) event(ctx context.Context, h platform.Handler, channel string, e *chatlog.Event) {
+	switch e.Kind {
+	case "message":
+		if e.Sender == "" && e.Login != "" {
+			// Learning from a login would slip past the privacy list and
+			// userhashes, which use user IDs, so skip users we can't resolve.
+			if lc.logins == nil {
+				return
+			}
+			id, err := lc.logins.ID(ctx, e.Login)
+			if err != nil {
+				slog.ErrorContext(ctx, "couldn't resolve login in logs", slog.String("in", channel), slog.Any("err", err))
+				return
+			}
+			if id == "" {
+				slog.DebugContext(ctx, "no user for login in logs", slog.String("in", channel), slog.String("login", e.Login))
+				return
+			}
+			e.Sender = id
+		}
+		var m *message.Incoming
+		if e.IRC != nil {
+			m = message.FromTMI(e.IRC)
+		} else {
+			m = &message.Incoming{
+				ID:        e.ID,
+				Sender:    e.Sender,
+				Name:      e.Name,
+				Text:      e.Text,
+				Timestamp: e.Time.UnixMilli(),
+				Raw:       e,
+			}
+		}
+		m.To = channel
+		h.Message(ctx, lc, m)
+	case "deleted":
+		h.Delete(ctx, lc, channel, e.ID, e.Text, false)
+	case "cleared":
+		h.Clear(ctx, lc, channel, e.Sender, e.Time)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/zephyrtronium/robot/chatlog"
+	"github.com/zephyrtronium/robot/twitch"
+)
+
+// fixedLogins is a login resolver with fixed users.
+type fixedLogins map[string]string
+
+func (f fixedLogins) ID(ctx context.Context, login string) (string, error) {
+	return f[login], nil
+}
+
+func TestLogsChatterinoSenders(t *testing.T) {
+	ctx := context.Background()
+	lc := &logsClient{logins: fixedLogins{"nijika": "1"}}
+	var h messageHandler
+	lc.event(ctx, &h, "log:bocchi", &chatlog.Event{Kind: "message", ID: "a", Login: "nijika", Name: "Nijika", Text: "kessoku band"})
+	lc.event(ctx, &h, "log:bocchi", &chatlog.Event{Kind: "message", ID: "b", Login: "banned", Text: "who am i"})
+	if len(h.msgs) != 1 {
+		t.Fatalf("wrong messages: %+v", h.msgs)
+	}
+	if m := h.msgs[0]; m.Sender != "1" || m.Name != "Nijika" || m.To != "log:bocchi" {
+		t.Errorf("wrong message: %+v", m)
+	}
+	// Without a resolver, nothing from Chatterino is attributable.
+	lc.logins = nil
+	lc.event(ctx, &h, "log:bocchi", &chatlog.Event{Kind: "message", ID: "c", Login: "nijika", Text: "kessoku band"})
+	if len(h.msgs) != 1 {
+		t.Errorf("learned unresolved login: %+v", h.msgs[1:])
+	}
+}
+
+// helixUsers serves Helix get users responses, counting requests.
+type helixUsers struct {
+	reqs int
+}
+
+func (h *helixUsers) RoundTrip(req *http.Request) (*http.Response, error) {
+	h.reqs++
+	body := `{"data":[]}`
+	if req.URL.Query().Get("login") == "nijika" {
+		body = `{"data":[{"id":"1","login":"nijika"}]}`
+	}
+	resp := http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}
+	return &resp, nil
+}
+
+func TestTwitchLogins(t *testing.T) {
+	ctx := context.Background()
+	var api helixUsers
+	l := &twitchLogins{
+		api:    twitch.Client{HTTP: &http.Client{Transport: &api}, ID: "bocchi"},
+		tokens: new(staticTokens),
+		ids:    make(map[string]string),
+	}
+	cases := []struct {
+		login string
+		want  string
+		reqs  int
+	}{
+		{"Nijika", "1", 1},
+		{"nijika", "1", 1},
+		{"banned", "", 2},
+		{"banned", "", 2},
+	}
+	for _, c := range cases {
+		id, err := l.ID(ctx, c.login)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != c.want {
+			t.Errorf("wrong ID for %s: want %q, got %q", c.login, c.want, id)
+		}
+		if api.reqs != c.reqs {
+			t.Errorf("wrong number of requests after %s: want %d, got %d", c.login, c.reqs, api.reqs)
+		}
+	}
+}
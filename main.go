@@ -2,20 +2,34 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"maps"
 	"os"
 	"os/signal"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/BurntSushi/toml"
 	"github.com/urfave/cli/v3"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/zephyrtronium/robot/apikey"
+	"github.com/zephyrtronium/robot/audit"
 	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/boltbrain"
 	"github.com/zephyrtronium/robot/brain/kvbrain"
 	"github.com/zephyrtronium/robot/brain/sqlbrain"
+	"github.com/zephyrtronium/robot/fakechat"
+	"github.com/zephyrtronium/robot/jobs"
+	"github.com/zephyrtronium/robot/journal"
+	"github.com/zephyrtronium/robot/spoken"
 )
 
 var app = cli.Command{
@@ -24,8 +38,13 @@ var app = cli.Command{
 
 	Flags: []cli.Flag{
 		&flagConfig,
+		&flagStrict,
 		&flagLog,
 		&flagLogFormat,
+		&flagLogContent,
+		&flagLogLogins,
+		&flagLogSample,
+		&flagLogFile,
 	},
 	Commands: []*cli.Command{
 		{
@@ -51,123 +70,1297 @@ var app = cli.Command{
 					Name:  "trace",
 					Usage: "Print ID traces with messages",
 				},
+				&cli.StringFlag{
+					Name:  "since",
+					Usage: "Only use messages learned at or after this time, as RFC 3339, a date, or a duration ago",
+				},
+				&cli.StringFlag{
+					Name:  "until",
+					Usage: "Only use messages learned before this time, as RFC 3339, a date, or a duration ago",
+				},
 			},
 			Action: cliSpeak,
 		},
+		{
+			Name:  "stats",
+			Usage: "Summarize knowledge in the brain and engagement in channels",
+			Flags: []cli.Flag{
+				&cli.StringSliceFlag{
+					Name:  "tag",
+					Usage: "Tag to summarize; may be repeated",
+				},
+				&cli.StringSliceFlag{
+					Name:  "channel",
+					Usage: "Channel for which to summarize engagement with random messages; may be repeated",
+				},
+				&cli.StringFlag{
+					Name:  "since",
+					Usage: "Only count engagement with messages sent at or after this time, as RFC 3339, a date, or a duration ago",
+				},
+				&cli.BoolFlag{
+					Name:  "db",
+					Usage: "Summarize the brain's storage, if it is a kvbrain",
+				},
+			},
+			Action: cliStats,
+		},
+		{
+			Name:  "graph",
+			Usage: "Export the chain around a prompt as a graph",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "tag",
+					Usage:    "Tag from which to explore",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:  "prefix",
+					Usage: "Prompt from which to explore; the start of messages if empty",
+				},
+				&cli.IntFlag{
+					Name:  "depth",
+					Usage: "Number of terms to follow from the prompt",
+					Value: 3,
+				},
+				&cli.IntFlag{
+					Name:  "width",
+					Usage: "Number of most common continuations to follow from each term",
+					Value: 5,
+				},
+				&cli.StringFlag{
+					Name:  "format",
+					Usage: "Output format, dot or json",
+					Value: "dot",
+				},
+			},
+			Action: cliGraph,
+		},
+		{
+			Name:  "diff",
+			Usage: "Show terms which appeared in or disappeared from a tag over a span of time",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "tag",
+					Usage:    "Tag to compare",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:     "since",
+					Usage:    "Start of the span, as RFC 3339, a date, or a duration ago",
+					Required: true,
+				},
+				&cli.IntFlag{
+					Name:  "n",
+					Usage: "Most terms to show in each direction; all if not positive",
+					Value: 50,
+				},
+			},
+			Action: cliDiff,
+		},
+		{
+			Name:  "explain",
+			Usage: "Show which learned messages contributed to a generated message",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "tag",
+					Usage:    "Tag from which the message was generated",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:  "trace",
+					Usage: "Comma-separated message IDs in the trace; looked up from --message if omitted",
+				},
+				&cli.StringFlag{
+					Name:  "message",
+					Usage: "Text of the generated message, without emote or effect",
+				},
+			},
+			Action: cliExplain,
+		},
+		{
+			Name:  "undelete",
+			Usage: "Restore knowledge forgotten by a moderation operation",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "op",
+					Usage:    "ID of the operation to undo, as logged when it happened",
+					Required: true,
+				},
+			},
+			Action: cliUndelete,
+		},
+		{
+			Name:  "retag",
+			Usage: "Move knowledge and spoken history from one tag to another, e.g. to namespace old tags",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "from",
+					Usage:    "Tag from which to move knowledge",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:     "to",
+					Usage:    "Namespaced tag to which to move knowledge, e.g. twitch/bocchi",
+					Required: true,
+				},
+			},
+			Action: cliRetag,
+		},
+		{
+			Name:  "backup",
+			Usage: "Write a backup of the brain",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "out",
+					Usage:    "File to which to write the backup",
+					Required: true,
+				},
+			},
+			Action: cliBackup,
+		},
+		{
+			Name:  "export",
+			Usage: "Write an anonymized corpus of a tag's knowledge for sharing",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "tag",
+					Usage:    "Tag to export",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:     "out",
+					Usage:    "File to which to write the corpus",
+					Required: true,
+				},
+			},
+			Action: cliExport,
+		},
+		{
+			Name:  "import",
+			Usage: "Learn an anonymized corpus exported by another bot",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "tag",
+					Usage:    "Tag under which to learn the corpus",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:     "in",
+					Usage:    "Corpus file to import",
+					Required: true,
+				},
+				&cli.BoolFlag{
+					Name:  "unattributable",
+					Usage: "Acknowledge that imported knowledge belongs to no user, so forgetting users never removes it",
+				},
+			},
+			Action: cliImport,
+		},
+		{
+			Name:      "learn",
+			Usage:     "Learn from chat log files",
+			ArgsUsage: "<log file>...",
+			Description: "Reads Chatterino, RustyLog JSON, justlog, or plain text logs and learns their messages, " +
+				"skipping private users. Deletions and timeouts in the logs forget what they would have live. " +
+				"Messages already learned are not learned again, so the same logs can be learned more than once.",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "tag",
+					Usage:    "Tag under which to learn the logs",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:     "channel",
+					Usage:    "Channel the logs are from, like #bocchi, so that users can have what they said forgotten",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:  "format",
+					Usage: "Log format: chatterino, rustylog, justlog, plain, or auto to tell .log and .json files apart by name",
+					Value: "auto",
+				},
+				&cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "Read the logs and report what would be learned without learning it",
+				},
+				&cli.BoolFlag{
+					Name:  "unattributable",
+					Usage: "Learn messages without senders, as in plain logs, even though forgetting users never removes them",
+				},
+			},
+			Action: cliLearn,
+		},
+		{
+			Name:  "serve-brain",
+			Usage: "Serve the configured brain over gRPC for bots configured with db.remote",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "listen",
+					Usage:    "Address on which to serve, e.g. :50051",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:  "token",
+					Usage: "File containing a token which clients must present",
+				},
+				&cli.StringFlag{
+					Name:  "tls-cert",
+					Usage: "PEM certificate file with which to serve TLS",
+				},
+				&cli.StringFlag{
+					Name:  "tls-key",
+					Usage: "PEM private key file for --tls-cert",
+				},
+				&cli.BoolFlag{
+					Name:  "insecure",
+					Usage: "Accept a token without TLS on addresses other than loopback",
+				},
+			},
+			Action: cliServeBrain,
+		},
+		{
+			Name:   "ingest",
+			Usage:  "Learn from the queue that bots configured with db.queue publish to",
+			Action: cliIngest,
+		},
+		{
+			Name:  "gen-corpus",
+			Usage: "Learn a synthesized chat corpus into a new brain for development",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "style",
+					Usage: "Style of chat: " + strings.Join(fakechat.Names(), ", "),
+					Value: "casual",
+				},
+				&cli.IntFlag{
+					Name:  "messages",
+					Usage: "Number of messages to synthesize",
+					Value: 10000,
+				},
+				&cli.IntFlag{
+					Name:  "users",
+					Usage: "Number of distinct chatters",
+					Value: 200,
+				},
+				&cli.IntFlag{
+					Name:  "seed",
+					Usage: "Random seed; the same seed synthesizes the same corpus",
+					Value: 1,
+				},
+				&cli.StringFlag{
+					Name:  "tag",
+					Usage: "Tag under which to learn the corpus",
+					Value: "fake",
+				},
+				&cli.StringFlag{
+					Name:  "db",
+					Usage: "sqlbrain file to create; defaults to a new temporary file",
+				},
+			},
+			Action: cliGenCorpus,
+		},
+		{
+			Name:  "audit",
+			Usage: "List privileged actions taken on the bot, newest first",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "since",
+					Usage: "Only list actions at or after this time, as RFC 3339, a date, or a duration ago",
+				},
+				&cli.StringFlag{
+					Name:  "actor",
+					Usage: "Only list actions by this user ID or API client",
+				},
+				&cli.StringFlag{
+					Name:  "channel",
+					Usage: "Only list actions in this channel",
+				},
+				&cli.StringFlag{
+					Name:  "action",
+					Usage: "Only list actions with this name",
+				},
+				&cli.IntFlag{
+					Name:  "n",
+					Usage: "Maximum number of actions to list; all if zero",
+					Value: 50,
+				},
+			},
+			Action: cliAudit,
+		},
+		{
+			Name:  "journal",
+			Usage: "List destructive operations with their IDs and counts, newest first",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "op",
+					Usage: "Only list the operation with this ID",
+				},
+				&cli.StringFlag{
+					Name:  "since",
+					Usage: "Only list operations at or after this time, as RFC 3339, a date, or a duration ago",
+				},
+				&cli.StringFlag{
+					Name:  "actor",
+					Usage: "Only list operations by this user ID, platform, sweep, or cli",
+				},
+				&cli.StringFlag{
+					Name:  "channel",
+					Usage: "Only list operations in this channel",
+				},
+				&cli.StringFlag{
+					Name:  "action",
+					Usage: "Only list operations with this name",
+				},
+				&cli.IntFlag{
+					Name:  "n",
+					Usage: "Maximum number of operations to list; all if zero",
+					Value: 50,
+				},
+			},
+			Action: cliJournal,
+		},
+		{
+			Name:   "jobs",
+			Usage:  "List background jobs with their last runs and checkpoints",
+			Action: cliJobs,
+		},
+		{
+			Name:  "apikey",
+			Usage: "Manage keys for the HTTP API",
+			Commands: []*cli.Command{
+				{
+					Name:  "create",
+					Usage: "Create a key and print its token",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:  "name",
+							Usage: "Label for the key",
+						},
+						&cli.StringFlag{
+							Name:     "scope",
+							Usage:    "What the key may do: speak, stats, or admin",
+							Required: true,
+						},
+						&cli.FloatFlag{
+							Name:  "every",
+							Usage: "Seconds between requests allowed with the key; unlimited if zero",
+						},
+						&cli.IntFlag{
+							Name:  "burst",
+							Usage: "Requests allowed in a burst with the key",
+							Value: 1,
+						},
+					},
+					Action: cliKeyCreate,
+				},
+				{
+					Name:   "list",
+					Usage:  "List keys",
+					Action: cliKeyList,
+				},
+				{
+					Name:  "revoke",
+					Usage: "Revoke a key",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:     "id",
+							Usage:    "ID of the key to revoke",
+							Required: true,
+						},
+					},
+					Action: cliKeyRevoke,
+				},
+			},
+		},
+		{
+			Name:  "config",
+			Usage: "Describe the config file",
+			Commands: []*cli.Command{
+				{
+					Name:   "schema",
+					Usage:  "Print a JSON Schema for the config file for editor completion",
+					Action: cliSchema,
+				},
+			},
+		},
+		{
+			Name:   "version",
+			Usage:  "Print version and build information",
+			Action: cliVersion,
+		},
+		{
+			Name:  "upgrade",
+			Usage: "Install the latest release in place of the running binary",
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "check",
+					Usage: "Only report whether a new release is available",
+				},
+				&cli.BoolFlag{
+					Name:  "force",
+					Usage: "Install the latest release even if it isn't newer",
+				},
+			},
+			Action: cliUpgrade,
+		},
+		{
+			Name:  "init",
+			Usage: "Interactively create a starter config",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "out",
+					Usage: "Path to write the config",
+					Value: "robot.toml",
+				},
+			},
+			Action: cliInit,
+		},
+		{
+			Name:  "service",
+			Usage: "Manage the Windows service",
+			Commands: []*cli.Command{
+				{
+					Name:   "install",
+					Usage:  "Install the bot as a service using the current config and logging flags",
+					Action: cliServiceInstall,
+				},
+				{
+					Name:   "uninstall",
+					Usage:  "Remove the service",
+					Action: cliServiceUninstall,
+				},
+			},
+		},
 	},
 	Action: cliRun,
 
-	Authors: []any{
-		"Branden J Brown  @zephyrtronium",
-	},
-	Copyright: "Copyright 2024 Branden J Brown",
+	Authors: []any{
+		"Branden J Brown  @zephyrtronium",
+	},
+	Copyright: "Copyright 2024 Branden J Brown",
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), shutdownSignals...)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	ok, err := runService(ctx, os.Args)
+	if !ok && err == nil {
+		err = app.Run(ctx, os.Args)
+	}
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+func cliRun(ctx context.Context, cmd *cli.Command) error {
+	slog.SetDefault(loggerFromFlags(cmd))
+	cfg, tmi, err := loadConfig(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	if err := checkTags(cfg); err != nil {
+		if !cfg.Global.LegacyTags {
+			return fmt.Errorf("invalid tags (move old knowledge with robot retag, or set global.legacy_tags to allow them for now):\n%w", err)
+		}
+		slog.WarnContext(ctx, "config uses tags without namespaces", slog.Any("err", err))
+	}
+	workers := cfg.Global.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	robo := New(workers)
+	robo.SetWorkQueue(cfg.Global.Queue)
+	robo.SetOwner(cfg.Owner.Name, cfg.Owner.Contact, cfg.Owner.Notify)
+	robo.SetConfig(cmd.String("config"), cfg)
+	if err := robo.SetSecrets(cfg.SecretFile); err != nil {
+		return err
+	}
+	db, err := loadDBs(ctx, cfg.DB)
+	if err != nil {
+		return err
+	}
+	if err := robo.SetSources(ctx, db); err != nil {
+		return err
+	}
+	robo.SetDeadlines(cfg.DB.Deadline)
+	if err := robo.SetQueue(ctx, cfg.DB.Queue); err != nil {
+		return err
+	}
+	if err := robo.SetHot(cfg.DB.Hot); err != nil {
+		return err
+	}
+	if err := robo.SetViews(cfg.View); err != nil {
+		return err
+	}
+	robo.SetBreaker(cfg.Global.Breaker)
+	robo.SetWarm(cfg.Global.Warm)
+	robo.SetBackpressure(cfg.Global.Backpressure)
+	robo.SetSweep(cfg.Global.Sweep)
+	robo.SetUndelete(cfg.Global.Undelete)
+	robo.SetDiskWatch(cfg.Global.Disk, cfg.DB)
+	robo.SetAlerts(cfg.Global.Alerts)
+	robo.SetUpdateCheck(cfg.Global.UpdateCheck)
+	robo.SetDuplicates(cfg.Global.Duplicates)
+	robo.SetBots(cfg.Global.Bots)
+	if err := robo.SetJobs(ctx, cfg.Global.Jobs, db.priv); err != nil {
+		return err
+	}
+	if err := robo.SetHTTP(cfg.HTTP); err != nil {
+		return err
+	}
+	if tmi {
+		if err := robo.InitTwitch(ctx, cfg.TMI); err != nil {
+			return err
+		}
+		cfg.Global.Privileges.Twitch = append(cfg.Global.Privileges.Twitch, ignorePrivileges(cfg.Global.Ignore)...)
+		if err := robo.InitTwitchUsers(ctx, &cfg.TMI.Owner, cfg.Global.Privileges.Twitch, cfg.Twitch); err != nil {
+			return err
+		}
+		if err := robo.SetTwitchChannels(ctx, cfg.Global, cfg.Twitch); err != nil {
+			return err
+		}
+	}
+	if cfg.Matrix.Homeserver != "" {
+		if err := robo.InitMatrix(ctx, cfg.Matrix); err != nil {
+			return err
+		}
+		if err := robo.SetMatrixRooms(ctx, cfg.Global, cfg.Matrix.Rooms); err != nil {
+			return err
+		}
+	}
+	if cfg.Telegram.Token != "" {
+		if err := robo.InitTelegram(ctx, cfg.Telegram); err != nil {
+			return err
+		}
+		if err := robo.SetTelegramGroups(ctx, cfg.Global, cfg.Telegram.Groups); err != nil {
+			return err
+		}
+	}
+	if cfg.Kick.Token != "" {
+		if err := robo.InitKick(ctx, cfg.Kick); err != nil {
+			return err
+		}
+		if err := robo.SetKickChannels(ctx, cfg.Global, cfg.Kick.Channels); err != nil {
+			return err
+		}
+	}
+	if cfg.Slack.Token != "" {
+		if err := robo.InitSlack(ctx, cfg.Slack); err != nil {
+			return err
+		}
+		if err := robo.SetSlackChannels(ctx, cfg.Global, cfg.Slack.Channels); err != nil {
+			return err
+		}
+	}
+	if cfg.Discord.Token != "" {
+		if err := robo.InitDiscord(ctx, cfg.Discord); err != nil {
+			return err
+		}
+		if err := robo.SetDiscordChannels(ctx, cfg.Global, cfg.Discord.Channels); err != nil {
+			return err
+		}
+	}
+	if cfg.Logs.Dir != "" {
+		if err := robo.InitLogs(ctx, cfg.Logs, cfg.TMI); err != nil {
+			return err
+		}
+		if err := robo.SetLogsChannels(ctx, cfg.Global, cfg.Logs.Channels); err != nil {
+			return err
+		}
+	}
+	if err := robo.SetBridges(cfg.Bridge); err != nil {
+		return err
+	}
+	if err := robo.SetPosters(cfg.Global, cfg.Poster); err != nil {
+		return err
+	}
+	return robo.Run(ctx)
+}
+
+func cliSpeak(ctx context.Context, cmd *cli.Command) error {
+	br, done, err := cliBrain(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	defer done()
+	if cmd.IsSet("since") || cmd.IsSet("until") {
+		w, ok := brain.As[brain.Windower](br)
+		if !ok {
+			return errors.New("brain does not support speaking from a time span")
+		}
+		now := time.Now()
+		since, until := time.Unix(0, 0), now
+		if cmd.IsSet("since") {
+			since, err = parseWhen(cmd.String("since"), now)
+			if err != nil {
+				return fmt.Errorf("bad --since: %w", err)
+			}
+		}
+		if cmd.IsSet("until") {
+			until, err = parseWhen(cmd.String("until"), now)
+			if err != nil {
+				return fmt.Errorf("bad --until: %w", err)
+			}
+		}
+		br = w.During(since, until)
+	}
+	group, ctx := errgroup.WithContext(ctx)
+	group.SetLimit(runtime.GOMAXPROCS(0))
+	tag := cmd.String("tag")
+	trace := cmd.Bool("trace")
+	prompt := cmd.String("prompt")
+	for range cmd.Int("n") {
+		group.Go(func() error {
+			m, tr, err := brain.Speak(ctx, br, tag, prompt)
+			if err != nil {
+				return err
+			}
+			a := []any{m}
+			if trace {
+				a = append(a, tr)
+			}
+			fmt.Println(a...)
+			return nil
+		})
+	}
+	return group.Wait()
+}
+
+func cliStats(ctx context.Context, cmd *cli.Command) error {
+	tags, channels := cmd.StringSlice("tag"), cmd.StringSlice("channel")
+	if len(tags) == 0 && len(channels) == 0 && !cmd.Bool("db") {
+		return errors.New("need --tag, --channel, or --db")
+	}
+	cfg, err := cliConfig(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	db, err := loadDBs(ctx, cfg.DB)
+	if err != nil {
+		return err
+	}
+	br, done, err := openBrain(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer done()
+	if cmd.Bool("db") {
+		kv, ok := brain.As[*kvbrain.Brain](br)
+		if !ok {
+			return errors.New("brain has no storage metrics")
+		}
+		printMetrics(kv.Metrics())
+	}
+	if len(channels) != 0 {
+		sp, err := spoken.Open(ctx, db.spoke)
+		if err != nil {
+			return fmt.Errorf("couldn't open spoken history: %w", err)
+		}
+		zones := channelZones(cfg)
+		for _, ch := range channels {
+			since := time.Unix(0, 0)
+			if cmd.IsSet("since") {
+				// Dates are days in the channel's time zone.
+				loc, err := timeZone(zones[ch])
+				if err != nil {
+					return fmt.Errorf("bad timezone for %s: %w", ch, err)
+				}
+				now := time.Now()
+				if loc != nil {
+					now = now.In(loc)
+				}
+				since, err = parseWhen(cmd.String("since"), now)
+				if err != nil {
+					return fmt.Errorf("bad --since: %w", err)
+				}
+			}
+			sent, engaged, err := sp.Engagement(ctx, ch, since)
+			if err != nil {
+				return err
+			}
+			if sent == 0 {
+				fmt.Printf("%s: no random messages with tracked engagement\n", ch)
+				continue
+			}
+			fmt.Printf("%s: %d of %d random messages engaged (%.1f%%)\n", ch, engaged, sent, 100*float64(engaged)/float64(sent))
+		}
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	st, ok := brain.As[brain.Stater](br)
+	if !ok {
+		return errors.New("brain does not support stats")
+	}
+	for _, tag := range tags {
+		r, err := st.Stats(ctx, tag)
+		if err != nil {
+			return err
+		}
+		if r.Messages < 0 {
+			fmt.Printf("%s: %d tuples\n", tag, r.Tuples)
+		} else {
+			fmt.Printf("%s: %d tuples from %d messages\n", tag, r.Tuples, r.Messages)
+		}
+	}
+	return nil
+}
+
+// printMetrics prints a summary of kvbrain storage.
+func printMetrics(m kvbrain.Metrics) {
+	fmt.Printf("lsm: %d bytes\nvalue log: %d bytes\npending writes: %d\ncompacting tables: %d\n", m.LSM, m.VLog, m.Pending, m.Compacting)
+	for _, l := range m.Levels {
+		if l.Tables == 0 {
+			continue
+		}
+		fmt.Printf("L%d: %d tables, %d of %d bytes, %d stale, score %.2f\n", l.Level, l.Tables, l.Size, l.Target, l.Stale, l.Score)
+	}
+}
+
+func cliGraph(ctx context.Context, cmd *cli.Command) error {
+	format := cmd.String("format")
+	if format != "dot" && format != "json" {
+		return fmt.Errorf("unknown graph format %q", format)
+	}
+	br, done, err := cliBrain(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	defer done()
+	c, ok := brain.As[brain.Continuer](br)
+	if !ok {
+		return errors.New("brain does not support exploring continuations")
+	}
+	g, err := brain.Neighborhood(ctx, c, cmd.String("tag"), cmd.String("prefix"), int(cmd.Int("depth")), int(cmd.Int("width")))
+	if err != nil {
+		return err
+	}
+	if format == "json" {
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "\t")
+		return e.Encode(g)
+	}
+	return g.WriteDOT(os.Stdout)
 }
 
-func main() {
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
-	go func() {
-		<-ctx.Done()
-		stop()
-	}()
-	err := app.Run(ctx, os.Args)
+func cliDiff(ctx context.Context, cmd *cli.Command) error {
+	since, err := parseWhen(cmd.String("since"), time.Now())
 	if err != nil {
-		fmt.Println(err)
+		return fmt.Errorf("bad --since: %w", err)
+	}
+	br, done, err := cliBrain(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	defer done()
+	d, ok := brain.As[brain.Differ](br)
+	if !ok {
+		return errors.New("brain does not support vocabulary diffs")
+	}
+	r, err := d.Diff(ctx, cmd.String("tag"), since)
+	if err != nil {
+		return err
+	}
+	n := int(cmd.Int("n"))
+	printTerms := func(what string, s []brain.TermSpan) {
+		fmt.Printf("%s since %s: %d terms\n", what, since.Format(time.RFC3339), len(s))
+		if n > 0 && len(s) > n {
+			s = s[:n]
+		}
+		for _, t := range s {
+			fmt.Printf("\t%q %d uses, first %s, last %s\n", t.Term, t.Uses, whenTerm(t.First), whenTerm(t.Last))
+		}
 	}
+	printTerms("appeared", r.Appeared)
+	printTerms("disappeared", r.Disappeared)
+	return nil
 }
 
-func cliRun(ctx context.Context, cmd *cli.Command) error {
-	slog.SetDefault(loggerFromFlags(cmd))
-	r, err := os.Open(cmd.String("config"))
+// whenTerm formats a time at which a term was used.
+func whenTerm(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return t.Local().Format(time.RFC3339)
+}
+
+func cliExplain(ctx context.Context, cmd *cli.Command) error {
+	if !cmd.IsSet("trace") && !cmd.IsSet("message") {
+		return errors.New("need --trace or --message")
+	}
+	db, err := cliDBs(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	br, done, err := openBrain(ctx, db)
 	if err != nil {
-		return fmt.Errorf("couldn't open config file: %w", err)
+		return err
 	}
-	cfg, md, err := Load(ctx, r)
+	defer done()
+	sp, err := spoken.Open(ctx, db.spoke)
 	if err != nil {
-		return fmt.Errorf("couldn't load config: %w", err)
+		return fmt.Errorf("couldn't open spoken history: %w", err)
 	}
-	r.Close()
-	robo := New(runtime.GOMAXPROCS(0))
-	robo.SetOwner(cfg.Owner.Name, cfg.Owner.Contact)
-	if err := robo.SetSecrets(cfg.SecretFile); err != nil {
+	var trace []string
+	if cmd.IsSet("trace") {
+		trace = strings.Split(cmd.String("trace"), ",")
+	}
+	x, err := explain(ctx, br, sp, cmd.String("tag"), cmd.String("message"), trace)
+	if err != nil {
+		return err
+	}
+	if x.Message != "" {
+		fmt.Println(x.Message)
+		for _, seg := range x.Segments {
+			from := "unknown source"
+			if len(seg.Sources) != 0 {
+				from = strings.Join(seg.Sources, ", ")
+			}
+			fmt.Printf("\t%q from %s\n", seg.Text, from)
+		}
+		fmt.Println()
+	}
+	for _, id := range x.Trace {
+		s, ok := x.Sources[id]
+		if !ok {
+			s = "(forgotten or not recallable)"
+		}
+		fmt.Printf("%s: %s\n", id, s)
+	}
+	return nil
+}
+
+func cliUndelete(ctx context.Context, cmd *cli.Command) error {
+	db, err := cliDBs(ctx, cmd)
+	if err != nil {
 		return err
 	}
-	kv, sql, priv, spoke, err := loadDBs(ctx, cfg.DB)
+	br, done, err := openBrain(ctx, db)
 	if err != nil {
 		return err
 	}
-	if err := robo.SetSources(ctx, kv, sql, priv, spoke); err != nil {
+	defer done()
+	un, ok := brain.As[brain.Undeleter](br)
+	if !ok {
+		return errors.New("brain does not support undeleting")
+	}
+	j, err := journal.Open(ctx, db.priv)
+	if err != nil {
+		return fmt.Errorf("couldn't open operation journal: %w", err)
+	}
+	op := cmd.String("op")
+	n, err := un.Undelete(ctx, op)
+	if err != nil {
 		return err
 	}
-	if md.IsDefined("tmi") {
-		if err := robo.InitTwitch(ctx, cfg.TMI); err != nil {
-			return err
+	e := journal.Entry{
+		Op:     brain.NewOp(),
+		Time:   time.Now(),
+		Actor:  "cli",
+		Action: "undelete",
+		Count:  int64(n),
+		Params: map[string]string{"undo": op},
+	}
+	if err := j.Record(ctx, e); err != nil {
+		return fmt.Errorf("restored %d messages but couldn't record it: %w", n, err)
+	}
+	if n == 0 {
+		fmt.Println("nothing to restore; the operation may be unknown or already purged")
+		return nil
+	}
+	fmt.Printf("restored %d messages\n", n)
+	return nil
+}
+
+func cliBackup(ctx context.Context, cmd *cli.Command) error {
+	br, done, err := cliBrain(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	defer done()
+	bk, ok := brain.As[brain.Backuper](br)
+	if !ok {
+		return errors.New("brain does not support backups")
+	}
+	f, err := os.Create(cmd.String("out"))
+	if err != nil {
+		return fmt.Errorf("couldn't create backup file: %w", err)
+	}
+	if err := bk.Backup(ctx, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func cliExport(ctx context.Context, cmd *cli.Command) error {
+	br, done, err := cliBrain(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	defer done()
+	e, ok := brain.As[brain.Exporter](br)
+	if !ok {
+		return errors.New("brain does not support exporting corpora")
+	}
+	f, err := os.Create(cmd.String("out"))
+	if err != nil {
+		return fmt.Errorf("couldn't create corpus file: %w", err)
+	}
+	n, err := brain.WriteCorpus(ctx, e, cmd.String("tag"), f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("exported %d tuples\n", n)
+	return nil
+}
+
+func cliImport(ctx context.Context, cmd *cli.Command) error {
+	if !cmd.Bool("unattributable") {
+		// Importing adds knowledge outside the guarantee that a user can have
+		// everything learned from them forgotten. Make sure that's intended.
+		return errors.New("imported knowledge can't be forgotten by user; pass --unattributable to import anyway")
+	}
+	br, done, err := cliBrain(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	defer done()
+	f, err := os.Open(cmd.String("in"))
+	if err != nil {
+		return fmt.Errorf("couldn't open corpus: %w", err)
+	}
+	defer f.Close()
+	n, err := brain.ImportCorpus(ctx, br, cmd.String("tag"), f)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("imported %d tuples\n", n)
+	return nil
+}
+
+func cliAudit(ctx context.Context, cmd *cli.Command) error {
+	q := audit.Query{
+		Actor:   cmd.String("actor"),
+		Channel: cmd.String("channel"),
+		Action:  cmd.String("action"),
+		Limit:   int(cmd.Int("n")),
+	}
+	if cmd.IsSet("since") {
+		var err error
+		q.Since, err = parseWhen(cmd.String("since"), time.Now())
+		if err != nil {
+			return fmt.Errorf("bad --since: %w", err)
 		}
-		if err := robo.InitTwitchUsers(ctx, &cfg.TMI.Owner, cfg.Global.Privileges.Twitch, cfg.Twitch); err != nil {
-			return err
+	}
+	db, err := cliDBs(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	l, err := audit.Open(ctx, db.priv)
+	if err != nil {
+		return fmt.Errorf("couldn't open audit log: %w", err)
+	}
+	entries, err := l.List(ctx, q)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		in := e.Channel
+		if in == "" {
+			in = "-"
 		}
-		if err := robo.SetTwitchChannels(ctx, cfg.Global, cfg.Twitch); err != nil {
-			return err
+		fmt.Printf("%s %s %s %s", e.Time.Format(time.RFC3339), in, e.Actor, e.Action)
+		for _, k := range slices.Sorted(maps.Keys(e.Params)) {
+			fmt.Printf(" %s=%q", k, e.Params[k])
 		}
+		fmt.Println()
 	}
-	return robo.Run(ctx)
+	return nil
 }
 
-func cliSpeak(ctx context.Context, cmd *cli.Command) error {
-	slog.SetDefault(loggerFromFlags(cmd))
-	r, err := os.Open(cmd.String("config"))
+func cliJournal(ctx context.Context, cmd *cli.Command) error {
+	q := journal.Query{
+		Op:      cmd.String("op"),
+		Actor:   cmd.String("actor"),
+		Channel: cmd.String("channel"),
+		Action:  cmd.String("action"),
+		Limit:   int(cmd.Int("n")),
+	}
+	if cmd.IsSet("since") {
+		var err error
+		q.Since, err = parseWhen(cmd.String("since"), time.Now())
+		if err != nil {
+			return fmt.Errorf("bad --since: %w", err)
+		}
+	}
+	db, err := cliDBs(ctx, cmd)
 	if err != nil {
-		return fmt.Errorf("couldn't open config file: %w", err)
+		return err
 	}
-	cfg, _, err := Load(ctx, r)
+	j, err := journal.Open(ctx, db.priv)
 	if err != nil {
-		return fmt.Errorf("couldn't load config: %w", err)
+		return fmt.Errorf("couldn't open operation journal: %w", err)
 	}
-	r.Close()
-	kv, sql, _, _, err := loadDBs(ctx, cfg.DB)
+	entries, err := j.List(ctx, q)
 	if err != nil {
 		return err
 	}
-	var br brain.Brain
-	if sql == nil {
-		if kv == nil {
-			panic("robot: no brain")
+	for _, e := range entries {
+		in, tag, count := e.Channel, e.Tag, "?"
+		if in == "" {
+			in = "-"
+		}
+		if tag == "" {
+			tag = "-"
+		}
+		if e.Count >= 0 {
+			count = strconv.FormatInt(e.Count, 10)
+		}
+		fmt.Printf("%s %s %s %s %s %s %s", e.Time.Format(time.RFC3339), e.Op, in, e.Actor, e.Action, tag, count)
+		for _, k := range slices.Sorted(maps.Keys(e.Params)) {
+			fmt.Printf(" %s=%q", k, e.Params[k])
 		}
-		br = kvbrain.New(kv)
-		defer kv.Close()
-	} else {
-		br, err = sqlbrain.Open(ctx, sql)
-		defer sql.Close()
+		fmt.Println()
 	}
+	return nil
+}
+
+func cliJobs(ctx context.Context, cmd *cli.Command) error {
+	db, err := cliDBs(ctx, cmd)
 	if err != nil {
-		return fmt.Errorf("couldn't open brain: %w", err)
+		return err
 	}
-	group, ctx := errgroup.WithContext(ctx)
-	group.SetLimit(runtime.GOMAXPROCS(0))
-	tag := cmd.String("tag")
-	trace := cmd.Bool("trace")
-	prompt := cmd.String("prompt")
-	for range cmd.Int("n") {
-		group.Go(func() error {
-			m, tr, err := brain.Speak(ctx, br, tag, prompt)
-			if err != nil {
-				return err
-			}
-			a := []any{m}
-			if trace {
-				a = append(a, tr)
-			}
-			fmt.Println(a...)
-			return nil
-		})
+	l, err := jobs.List(ctx, db.priv)
+	if err != nil {
+		return err
 	}
-	return group.Wait()
+	for _, s := range l {
+		fmt.Printf("%s runs=%d failures=%d started=%s finished=%s", s.Name, s.Runs, s.Failures, s.Started.Format(time.RFC3339), s.Finished.Format(time.RFC3339))
+		if s.Checkpoint != "" {
+			fmt.Printf(" checkpoint=%q", s.Checkpoint)
+		}
+		if s.Err != "" {
+			fmt.Printf(" error=%q", s.Err)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+func cliKeyCreate(ctx context.Context, cmd *cli.Command) error {
+	scope, ok := apikey.ParseScope(cmd.String("scope"))
+	if !ok {
+		return errors.New("--scope must be speak, stats, or admin")
+	}
+	if cmd.Float("every") < 0 || cmd.Int("burst") <= 0 {
+		return errors.New("--every must not be negative and --burst must be positive")
+	}
+	s, err := cliKeys(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	tok, k, err := s.Create(ctx, cmd.String("name"), scope, fseconds(cmd.Float("every")), int(cmd.Int("burst")), time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created key %s; its token will not be shown again:\n%s\n", k.ID, tok)
+	return nil
+}
+
+func cliKeyList(ctx context.Context, cmd *cli.Command) error {
+	s, err := cliKeys(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	l, err := s.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, k := range l {
+		lim := "unlimited"
+		if k.Every > 0 {
+			lim = fmt.Sprintf("every %v, burst %d", k.Every, k.Burst)
+		}
+		fmt.Printf("%s %-6s %s (%s) created %s\n", k.ID, k.Scope, k.Name, lim, k.Created.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func cliKeyRevoke(ctx context.Context, cmd *cli.Command) error {
+	s, err := cliKeys(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	ok, err := s.Revoke(ctx, cmd.String("id"))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("no key %s", cmd.String("id"))
+	}
+	return nil
+}
+
+// parseWhen parses a time given as RFC 3339, as a date, or as a duration
+// before now. Dates begin at midnight in the location of now. Durations may
+// also be a whole number of days, e.g. 7d.
+func parseWhen(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, s, now.Location()); err == nil {
+		return t, nil
+	}
+	if n, ok := strings.CutSuffix(s, "d"); ok {
+		// Days, which time.ParseDuration doesn't know.
+		if d, err := strconv.Atoi(n); err == nil {
+			return now.AddDate(0, 0, -d), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a time, date, or duration", s)
+	}
+	return now.Add(-d), nil
+}
+
+// cliConfig loads the config for a CLI subcommand.
+func cliConfig(ctx context.Context, cmd *cli.Command) (*Config, error) {
+	slog.SetDefault(loggerFromFlags(cmd))
+	cfg, _, err := loadConfig(ctx, cmd)
+	return cfg, err
+}
+
+// loadConfig loads the config file named by the command's flags, or the
+// config in the environment if there is none. It also reports whether Twitch
+// is configured.
+func loadConfig(ctx context.Context, cmd *cli.Command) (*Config, bool, error) {
+	if cmd.String("config") == "" {
+		slog.InfoContext(ctx, "no config file; using environment")
+		cfg, err := LoadEnv(os.Getenv)
+		if err != nil {
+			return nil, false, fmt.Errorf("couldn't load config from environment: %w", err)
+		}
+		return cfg, cfg.TMI.CID != "", nil
+	}
+	cfg, md, err := loadConfigFile(ctx, cmd.String("config"), cmd.Bool("strict"))
+	if err != nil {
+		return nil, false, err
+	}
+	return cfg, md.IsDefined("tmi"), nil
+}
+
+// loadConfigFile loads a config file.
+func loadConfigFile(ctx context.Context, file string, strict bool) (*Config, *toml.MetaData, error) {
+	r, err := os.Open(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't open config file: %w", err)
+	}
+	defer r.Close()
+	cfg, md, err := Load(ctx, r, strict)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't load config: %w", err)
+	}
+	return cfg, md, nil
+}
+
+// cliDBs opens the databases described by the config for a CLI subcommand.
+func cliDBs(ctx context.Context, cmd *cli.Command) (*databases, error) {
+	cfg, err := cliConfig(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	return loadDBs(ctx, cfg.DB)
+}
+
+// cliKeys opens the API key store described by the config for a CLI
+// subcommand.
+func cliKeys(ctx context.Context, cmd *cli.Command) (*apikey.Store, error) {
+	cfg, err := cliConfig(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	k, err := loadSecrets(cfg.SecretFile)
+	if err != nil {
+		return nil, err
+	}
+	db, err := loadDBs(ctx, cfg.DB)
+	if err != nil {
+		return nil, err
+	}
+	s, err := apikey.Open(ctx, db.priv, k.apikey)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open API keys: %w", err)
+	}
+	return s, nil
+}
+
+// cliBrain opens the brain described by the config for a CLI subcommand,
+// including its views.
+// The returned function closes the brain's database.
+func cliBrain(ctx context.Context, cmd *cli.Command) (brain.Brain, func(), error) {
+	cfg, err := cliConfig(ctx, cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := loadDBs(ctx, cfg.DB)
+	if err != nil {
+		return nil, nil, err
+	}
+	br, done, err := openBrain(ctx, db)
+	if err != nil {
+		return nil, nil, err
+	}
+	br, err = viewBrain(br, cfg.View)
+	if err != nil {
+		done()
+		return nil, nil, err
+	}
+	return br, done, nil
+}
+
+// openBrain opens the brain in a set of databases.
+// The returned function closes the brain's database.
+func openBrain(ctx context.Context, db *databases) (brain.Brain, func(), error) {
+	switch {
+	case db.sql != nil: // handled below
+	case db.pg != nil:
+		br, err := sqlbrain.OpenPostgres(ctx, db.pg)
+		if err != nil {
+			db.pg.Close()
+			return nil, nil, fmt.Errorf("couldn't open brain: %w", err)
+		}
+		return br, func() { db.pg.Close() }, nil
+	case db.bolt != nil:
+		return boltbrain.New(db.bolt), func() { db.bolt.Close() }, nil
+	case db.remote != nil:
+		return db.remote, func() { db.remote.Close() }, nil
+	case db.kv != nil:
+		return kvbrain.New(db.kv), func() { db.kv.Close() }, nil
+	default:
+		panic("robot: no brain")
+	}
+	br, err := sqlbrain.Open(ctx, db.sql)
+	if err != nil {
+		db.sql.Close()
+		return nil, nil, fmt.Errorf("couldn't open brain: %w", err)
+	}
+	return br, func() { db.sql.Close() }, nil
 }
 
 var (
 	flagConfig = cli.StringFlag{
 		Name:       "config",
-		Required:   true,
-		Usage:      "TOML config file",
+		Usage:      "TOML config file; if omitted, configuration comes from ROBOT_ environment variables",
+		Sources:    cli.EnvVars("ROBOT_CONFIG"),
 		Persistent: true,
 		Action: func(ctx context.Context, cmd *cli.Command, s string) error {
 			i, err := os.Stat(s)
@@ -181,6 +1374,12 @@ var (
 		},
 	}
 
+	flagStrict = cli.BoolFlag{
+		Name:       "strict",
+		Usage:      "Treat unknown keys in the config file as errors",
+		Persistent: true,
+	}
+
 	flagLog = cli.StringFlag{
 		Name:       "log",
 		Usage:      "Logging level, one of debug, info, warn, error",
@@ -206,6 +1405,36 @@ var (
 			}
 		},
 	}
+
+	flagLogContent = cli.StringFlag{
+		Name:       "log-content",
+		Usage:      "When logs may include chat message content: none, debug (debug logs only), or all",
+		Value:      "none",
+		Persistent: true,
+		Action: func(ctx context.Context, c *cli.Command, s string) error {
+			_, err := parseContentPolicy(s)
+			return err
+		},
+	}
+
+	flagLogLogins = cli.BoolFlag{
+		Name:       "log-logins",
+		Usage:      "Log user names as-is instead of as pseudonyms",
+		Persistent: true,
+	}
+
+	flagLogFile = cli.StringFlag{
+		Name:       "log-file",
+		Usage:      "File to append logs to instead of stderr",
+		Persistent: true,
+	}
+
+	flagLogSample = cli.IntFlag{
+		Name:       "log-sample",
+		Usage:      "Log only one in this many debug logs with the same message, with a summary of the rest each minute; 1 logs everything",
+		Value:      100,
+		Persistent: true,
+	}
 )
 
 func loggerFromFlags(cmd *cli.Command) *slog.Logger {
@@ -213,12 +1442,26 @@ func loggerFromFlags(cmd *cli.Command) *slog.Logger {
 	if err := l.UnmarshalText([]byte(cmd.String("log"))); err != nil {
 		panic(err)
 	}
+	var w io.Writer = os.Stderr
+	if f := cmd.String("log-file"); f != "" {
+		// The file stays open for the life of the process.
+		file, err := os.OpenFile(f, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			panic(err)
+		}
+		w = file
+	}
 	var h slog.Handler
 	switch strings.ToLower(cmd.String("log-format")) {
 	case "text":
-		h = slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: l})
+		h = slog.NewTextHandler(w, &slog.HandlerOptions{Level: l})
 	case "json":
-		h = slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: l})
+		h = slog.NewJSONHandler(w, &slog.HandlerOptions{Level: l})
+	}
+	content, err := parseContentPolicy(cmd.String("log-content"))
+	if err != nil {
+		panic(err)
 	}
-	return slog.New(h)
+	h = newPrivacyHandler(h, content, cmd.Bool("log-logins"))
+	return slog.New(newSampleHandler(h, int(cmd.Int("log-sample")), time.Minute))
 }
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/zephyrtronium/robot/matrix"
+	"github.com/zephyrtronium/robot/message"
+	"github.com/zephyrtronium/robot/platform"
+	"github.com/zephyrtronium/robot/syncmap"
+)
+
+// matrixClient is the bot's connection to a Matrix homeserver.
+type matrixClient struct {
+	cl *matrix.Client
+	// user is the bot's full user ID.
+	user string
+	// name is the localpart of the bot's user ID, used to recognize commands.
+	name string
+	// owner is the full user ID of the owner.
+	owner string
+	// skipEncrypted prevents sending to rooms with encryption enabled.
+	skipEncrypted bool
+	// encrypted is the set of rooms known to have encryption enabled.
+	encrypted *syncmap.Map[string, bool]
+	// rooms is the list of room IDs to join.
+	rooms []string
+}
+
+var _ platform.Client = (*matrixClient)(nil)
+
+func (mc *matrixClient) Platform() string { return "matrix" }
+
+func (mc *matrixClient) Bot() (id, name string) { return mc.user, mc.name }
+
+func (mc *matrixClient) Owner() string { return mc.owner }
+
+func (mc *matrixClient) Capabilities() platform.Capabilities {
+	f := platform.Format{
+		Newlines: true,
+		// Matrix clients highlight display names in plain text.
+		Mention: func(name string) string { return name },
+	}
+	return platform.Capabilities{Replies: true, Format: f}
+}
+
+// Send sends a message to a room, unless the room is encrypted and the client
+// is configured to skip encrypted rooms.
+func (mc *matrixClient) Send(ctx context.Context, channel, reply, text string) error {
+	if mc.skipEncrypted {
+		if enc, _ := mc.encrypted.Load(channel); enc {
+			slog.InfoContext(ctx, "not sending to encrypted room", slog.String("in", channel))
+			return nil
+		}
+	}
+	return mc.cl.Send(ctx, channel, reply, text)
+}
+
+// InitMatrix logs in to a Matrix homeserver.
+func (robo *Robot) InitMatrix(ctx context.Context, cfg MatrixCfg) error {
+	cl := &matrix.Client{Homeserver: cfg.Homeserver, Token: cfg.Token}
+	var user string
+	var err error
+	if cfg.Token != "" {
+		user, err = cl.WhoAmI(ctx)
+	} else {
+		user, err = cl.Login(ctx, cfg.User, cfg.Password)
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't log in to Matrix: %w", err)
+	}
+	slog.InfoContext(ctx, "Matrix user", slog.String("user", user))
+	name, _, _ := strings.Cut(strings.TrimPrefix(user, "@"), ":")
+	robo.matrix = &matrixClient{
+		cl:            cl,
+		user:          user,
+		name:          name,
+		owner:         cfg.Owner,
+		skipEncrypted: cfg.SkipEncrypted,
+		encrypted:     syncmap.New[string, bool](),
+	}
+	return nil
+}
+
+// SetMatrixRooms initializes Matrix room configuration.
+// It must be called after InitMatrix.
+func (robo *Robot) SetMatrixRooms(ctx context.Context, global Global, rooms map[string]*ChannelCfg) error {
+	// Matrix user IDs are also names, so allow privileges to give either.
+	privs := global.Privileges.Matrix
+	for i := range privs {
+		if privs[i].ID == "" {
+			privs[i].ID = privs[i].Name
+		}
+	}
+	for _, r := range rooms {
+		for i := range r.Privileges {
+			if r.Privileges[i].ID == "" {
+				r.Privileges[i].ID = r.Privileges[i].Name
+			}
+		}
+	}
+	for _, r := range rooms {
+		robo.matrix.rooms = append(robo.matrix.rooms, r.Channels...)
+	}
+	return robo.setChannels(ctx, global, robo.matrix, privs, rooms)
+}
+
+func (mc *matrixClient) Run(ctx context.Context, h platform.Handler) error {
+	for _, r := range mc.rooms {
+		if err := mc.cl.Join(ctx, r); err != nil {
+			slog.ErrorContext(ctx, "couldn't join Matrix room", slog.Any("err", err))
+		}
+	}
+	var since string
+	for {
+		timeout := 30 * time.Second
+		if since == "" {
+			timeout = 0
+		}
+		b, err := mc.cl.Sync(ctx, since, timeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			slog.ErrorContext(ctx, "Matrix sync failed", slog.Any("err", err))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+		for room := range b.Encrypted {
+			mc.encrypted.Store(room, true)
+		}
+		for _, ev := range b.Events {
+			mc.event(ctx, h, &ev)
+		}
+		since = b.Next
+	}
+}
+
+// event processes a room event from Matrix.
+func (mc *matrixClient) event(ctx context.Context, h platform.Handler, ev *matrix.Event) {
+	if ev.Sender == mc.user {
+		return
+	}
+	switch ev.Type {
+	case "m.room.message":
+		if ev.MsgType != "m.text" {
+			return
+		}
+		name, _, _ := strings.Cut(strings.TrimPrefix(ev.Sender, "@"), ":")
+		m := message.Incoming{
+			ID:          ev.ID,
+			To:          ev.Room,
+			Sender:      ev.Sender,
+			Name:        name,
+			Text:        matrix.StripReply(ev.Body),
+			Timestamp:   ev.Timestamp,
+			ReplyParent: ev.Reply,
+			Raw:         ev,
+		}
+		h.Message(ctx, mc, &m)
+	case "m.room.redaction":
+		h.Delete(ctx, mc, ev.Room, ev.Redacts, "", false)
+	case "m.room.encrypted":
+		slog.DebugContext(ctx, "can't read encrypted message", slog.String("in", ev.Room))
+	}
+}
@@ -0,0 +1,145 @@
+// Package matrix implements the parts of the Matrix client-server API that
+// Robot uses.
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Client holds the context for requests to a Matrix homeserver.
+type Client struct {
+	// HTTP is the HTTP client for performing requests.
+	// If nil, http.DefaultClient is used.
+	HTTP *http.Client
+	// Homeserver is the base URL of the homeserver.
+	Homeserver string
+	// Token is the access token.
+	Token string
+
+	// txn is the counter for transaction IDs.
+	txn atomic.Int64
+}
+
+// reqjson performs an HTTP request with an optional JSON body and decodes the
+// response as JSON. The response body is truncated to 8 MB.
+func (c *Client) reqjson(ctx context.Context, method, ep string, values url.Values, body, u any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("couldn't encode request: %w", err)
+		}
+		r = bytes.NewReader(b)
+	}
+	p, err := url.JoinPath(c.Homeserver, "/_matrix/client/v3/", ep)
+	if err != nil {
+		return fmt.Errorf("bad homeserver url: %w", err)
+	}
+	if len(values) != 0 {
+		p += "?" + values.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p, r)
+	if err != nil {
+		return fmt.Errorf("couldn't make request: %w", err)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	hc := c.HTTP
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("couldn't %s: %w", method, err)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("couldn't read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Code  string `json:"errcode"`
+			Error string `json:"error"`
+		}
+		json.Unmarshal(b, &e)
+		return fmt.Errorf("request failed: %s: %s (%s)", e.Code, e.Error, resp.Status)
+	}
+	if u == nil {
+		return nil
+	}
+	if err := json.Unmarshal(b, u); err != nil {
+		return fmt.Errorf("couldn't decode JSON response: %w", err)
+	}
+	return nil
+}
+
+// Login logs in with a password and sets the client's access token.
+// It returns the full user ID of the logged-in user.
+func (c *Client) Login(ctx context.Context, user, password string) (string, error) {
+	body := map[string]any{
+		"type":       "m.login.password",
+		"identifier": map[string]string{"type": "m.id.user", "user": user},
+		"password":   password,
+	}
+	var r struct {
+		UserID      string `json:"user_id"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := c.reqjson(ctx, "POST", "login", nil, body, &r); err != nil {
+		return "", fmt.Errorf("couldn't log in: %w", err)
+	}
+	c.Token = r.AccessToken
+	return r.UserID, nil
+}
+
+// WhoAmI returns the full user ID of the client's access token.
+func (c *Client) WhoAmI(ctx context.Context) (string, error) {
+	var r struct {
+		UserID string `json:"user_id"`
+	}
+	if err := c.reqjson(ctx, "GET", "account/whoami", nil, nil, &r); err != nil {
+		return "", fmt.Errorf("couldn't get user: %w", err)
+	}
+	return r.UserID, nil
+}
+
+// Join joins a room by ID or alias.
+func (c *Client) Join(ctx context.Context, room string) error {
+	if err := c.reqjson(ctx, "POST", "join/"+url.PathEscape(room), nil, struct{}{}, nil); err != nil {
+		return fmt.Errorf("couldn't join %s: %w", room, err)
+	}
+	return nil
+}
+
+// Send sends a text message to a room, optionally in reply to another event.
+func (c *Client) Send(ctx context.Context, room, reply, text string) error {
+	body := map[string]any{
+		"msgtype": "m.text",
+		"body":    text,
+	}
+	if reply != "" {
+		body["m.relates_to"] = map[string]any{
+			"m.in_reply_to": map[string]string{"event_id": reply},
+		}
+	}
+	txn := strconv.FormatInt(time.Now().UnixNano(), 36) + "." + strconv.FormatInt(c.txn.Add(1), 36)
+	ep := "rooms/" + url.PathEscape(room) + "/send/m.room.message/" + txn
+	if err := c.reqjson(ctx, "PUT", ep, nil, body, nil); err != nil {
+		return fmt.Errorf("couldn't send to %s: %w", room, err)
+	}
+	return nil
+}
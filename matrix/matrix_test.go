@@ -0,0 +1,151 @@
+package matrix
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSync(t *testing.T) {
+	var gotAuth, gotSince string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_matrix/client/v3/sync" {
+			http.NotFound(w, r)
+			return
+		}
+		gotAuth = r.Header.Get("Authorization")
+		gotSince = r.URL.Query().Get("since")
+		http.ServeFile(w, r, "testdata/sync.json")
+	}))
+	defer srv.Close()
+	cl := Client{Homeserver: srv.URL, Token: "bocchi"}
+	b, err := cl.Sync(context.Background(), "s1", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotAuth != "Bearer bocchi" {
+		t.Errorf("wrong auth header: %q", gotAuth)
+	}
+	if gotSince != "s1" {
+		t.Errorf("wrong since: %q", gotSince)
+	}
+	if b.Next != "s72595_4483_1934" {
+		t.Errorf("wrong next batch: %q", b.Next)
+	}
+	if diff := cmp.Diff(map[string]bool{"!sickhack:example.org": true}, b.Encrypted); diff != "" {
+		t.Errorf("wrong encrypted rooms:\n%s", diff)
+	}
+	slices.SortFunc(b.Events, func(a, b Event) int { return strings.Compare(a.ID, b.ID) })
+	want := []Event{
+		{
+			Room:      "!kessoku:example.org",
+			ID:        "$bocchi",
+			Type:      "m.room.message",
+			Sender:    "@bocchi:example.org",
+			Timestamp: 1432735824653,
+			Body:      "i'm going to play guitar",
+			MsgType:   "m.text",
+		},
+		{
+			Room:      "!kessoku:example.org",
+			ID:        "$nijika",
+			Type:      "m.room.message",
+			Sender:    "@nijika:example.org",
+			Timestamp: 1432735824654,
+			Body:      "> <@bocchi:example.org> i'm going to play guitar\n\nnice",
+			MsgType:   "m.text",
+			Reply:     "$bocchi",
+		},
+		{
+			Room:      "!kessoku:example.org",
+			ID:        "$ryo",
+			Type:      "m.room.redaction",
+			Sender:    "@ryo:example.org",
+			Timestamp: 1432735824655,
+			Redacts:   "$bocchi",
+		},
+		{
+			Room:      "!sickhack:example.org",
+			ID:        "$secret",
+			Type:      "m.room.encrypted",
+			Sender:    "@kikuri:example.org",
+			Timestamp: 1432735824656,
+		},
+	}
+	if diff := cmp.Diff(want, b.Events); diff != "" {
+		t.Errorf("wrong events (-want/+got):\n%s", diff)
+	}
+}
+
+func TestInitialSync(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/sync.json")
+	}))
+	defer srv.Close()
+	cl := Client{Homeserver: srv.URL}
+	b, err := cl.Sync(context.Background(), "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Events) != 0 {
+		t.Errorf("initial sync returned events: %+v", b.Events)
+	}
+	if !b.Encrypted["!sickhack:example.org"] {
+		t.Errorf("initial sync missed encrypted room")
+	}
+}
+
+func TestSend(t *testing.T) {
+	var path string
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		b, _ := io.ReadAll(r.Body)
+		json.Unmarshal(b, &body)
+		io.WriteString(w, `{"event_id":"$x"}`)
+	}))
+	defer srv.Close()
+	cl := Client{Homeserver: srv.URL, Token: "bocchi"}
+	if err := cl.Send(context.Background(), "!kessoku:example.org", "$bocchi", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(path, "/_matrix/client/v3/rooms/%21kessoku:example.org/send/m.room.message/") {
+		t.Errorf("wrong path: %s", path)
+	}
+	want := map[string]any{
+		"msgtype":      "m.text",
+		"body":         "hello",
+		"m.relates_to": map[string]any{"m.in_reply_to": map[string]any{"event_id": "$bocchi"}},
+	}
+	if diff := cmp.Diff(want, body); diff != "" {
+		t.Errorf("wrong body:\n%s", diff)
+	}
+}
+
+func TestStripReply(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"plain", "nice", "nice"},
+		{"reply", "> <@bocchi:example.org> i'm going to play guitar\n\nnice", "nice"},
+		{"multiline", "> <@bocchi:example.org> i'm going\n> to play guitar\n\nnice\nvery nice", "nice\nvery nice"},
+		{"only", "> quote", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := StripReply(c.body); got != c.want {
+				t.Errorf("wrong result: want %q, got %q", c.want, got)
+			}
+		})
+	}
+}
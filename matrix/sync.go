@@ -0,0 +1,146 @@
+package matrix
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is a room event received from the homeserver.
+type Event struct {
+	// Room is the ID of the room in which the event occurred.
+	Room string
+	// ID is the event ID.
+	ID string
+	// Type is the event type, e.g. m.room.message.
+	Type string
+	// Sender is the full user ID of the sender.
+	Sender string
+	// Timestamp is the time of the event in milliseconds since the Unix epoch.
+	Timestamp int64
+	// Body is the text of a message event.
+	Body string
+	// MsgType is the msgtype of a message event.
+	MsgType string
+	// Reply is the ID of the event to which a message replies, if any.
+	Reply string
+	// Redacts is the ID of the event redacted by a redaction event.
+	Redacts string
+}
+
+// event is an event as it appears in a sync response.
+type event struct {
+	Type      string          `json:"type"`
+	ID        string          `json:"event_id"`
+	Sender    string          `json:"sender"`
+	Timestamp int64           `json:"origin_server_ts"`
+	Redacts   string          `json:"redacts"`
+	Content   json.RawMessage `json:"content"`
+}
+
+type content struct {
+	Body      string `json:"body"`
+	MsgType   string `json:"msgtype"`
+	Redacts   string `json:"redacts"`
+	RelatesTo struct {
+		InReplyTo struct {
+			ID string `json:"event_id"`
+		} `json:"m.in_reply_to"`
+	} `json:"m.relates_to"`
+}
+
+// syncResponse is the subset of a sync response that we use.
+type syncResponse struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join map[string]struct {
+			State struct {
+				Events []event `json:"events"`
+			} `json:"state"`
+			Timeline struct {
+				Events []event `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+	} `json:"rooms"`
+}
+
+// Batch is the result of a single sync.
+type Batch struct {
+	// Next is the token from which to continue syncing.
+	Next string
+	// Events is the list of timeline events in joined rooms.
+	Events []Event
+	// Encrypted is the set of rooms observed to have enabled encryption.
+	Encrypted map[string]bool
+}
+
+// Sync waits up to timeout for new events since the given token.
+// If since is empty, it returns only the current position without events.
+func (c *Client) Sync(ctx context.Context, since string, timeout time.Duration) (*Batch, error) {
+	v := url.Values{"timeout": {strconv.FormatInt(timeout.Milliseconds(), 10)}}
+	if since != "" {
+		v.Set("since", since)
+	} else {
+		// Don't fetch history on the initial sync, but do get room state so
+		// that we know which rooms are encrypted.
+		v.Set("filter", `{"room":{"timeline":{"limit":0}}}`)
+	}
+	var r syncResponse
+	if err := c.reqjson(ctx, "GET", "sync", v, nil, &r); err != nil {
+		return nil, fmt.Errorf("couldn't sync: %w", err)
+	}
+	return r.batch(since != ""), nil
+}
+
+// batch converts a sync response to a batch, including timeline events only
+// if events is true.
+func (r *syncResponse) batch(events bool) *Batch {
+	b := Batch{Next: r.NextBatch, Encrypted: make(map[string]bool)}
+	for room, j := range r.Rooms.Join {
+		for _, ev := range j.State.Events {
+			if ev.Type == "m.room.encryption" {
+				b.Encrypted[room] = true
+			}
+		}
+		for _, ev := range j.Timeline.Events {
+			if ev.Type == "m.room.encryption" {
+				b.Encrypted[room] = true
+			}
+			if !events {
+				continue
+			}
+			var c content
+			json.Unmarshal(ev.Content, &c)
+			e := Event{
+				Room:      room,
+				ID:        ev.ID,
+				Type:      ev.Type,
+				Sender:    ev.Sender,
+				Timestamp: ev.Timestamp,
+				Body:      c.Body,
+				MsgType:   c.MsgType,
+				Reply:     c.RelatesTo.InReplyTo.ID,
+				Redacts:   ev.Redacts,
+			}
+			if e.Redacts == "" {
+				// Room version 11 moved redacts into the content.
+				e.Redacts = c.Redacts
+			}
+			b.Events = append(b.Events, e)
+		}
+	}
+	return &b
+}
+
+// StripReply removes the quoted fallback of the replied-to message from the
+// body of a reply.
+func StripReply(body string) string {
+	for strings.HasPrefix(body, ">") {
+		_, body, _ = strings.Cut(body, "\n")
+	}
+	return strings.TrimSpace(body)
+}
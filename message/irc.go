@@ -1,30 +1,77 @@
 package message
 
 import (
+	"slices"
 	"strconv"
+	"strings"
 
 	"gitlab.com/zephyrtronium/tmi"
 )
 
 // FromTMI adapts a TMI IRC message.
-func FromTMI(m *tmi.Message) *Received {
+func FromTMI(m *tmi.Message) *Incoming {
 	id, _ := m.Tag("id")
 	sender, _ := m.Tag("user-id")
 	ts, _ := m.Tag("tmi-sent-ts")
 	u, _ := strconv.ParseInt(ts, 10, 64)
-	r := Received{
-		ID:          id,
-		To:          m.To(),
-		Sender:      sender,
-		Name:        m.DisplayName(),
-		Text:        m.Trailing,
-		Timestamp:   u,
-		IsModerator: moderator(m),
-		IsElevated:  elevated(m),
+	parent, _ := m.Tag("reply-parent-msg-id")
+	emotes, _ := m.Tag("emotes")
+	r := Incoming{
+		ID:           id,
+		To:           m.To(),
+		Sender:       sender,
+		Name:         m.DisplayName(),
+		Text:         m.Trailing,
+		Timestamp:    u,
+		IsModerator:  moderator(m),
+		IsElevated:   elevated(m),
+		ReplyParent:  parent,
+		ReplyMention: parent != "",
+		Emotes:       emoteSpans(m.Trailing, emotes),
+		Raw:          m,
 	}
 	return &r
 }
 
+// emoteSpans converts a TMI emotes tag to spans of text.
+// The tag is like 25:0-4,6-10/1902:12-16, with inclusive positions counted in
+// code points. Malformed or out of range entries are skipped.
+func emoteSpans(text, emotes string) []Span {
+	if emotes == "" {
+		return nil
+	}
+	// Map code point indices to byte offsets. off[len] is len(text).
+	off := make([]int, 0, len(text)+1)
+	for i := range text {
+		off = append(off, i)
+	}
+	off = append(off, len(text))
+	var r []Span
+	for _, e := range strings.Split(emotes, "/") {
+		id, pos, ok := strings.Cut(e, ":")
+		if !ok {
+			continue
+		}
+		for _, span := range strings.Split(pos, ",") {
+			a, b, ok := strings.Cut(span, "-")
+			if !ok {
+				continue
+			}
+			i, err := strconv.Atoi(a)
+			if err != nil {
+				continue
+			}
+			j, err := strconv.Atoi(b)
+			if err != nil || i < 0 || j < i || j >= len(off)-1 {
+				continue
+			}
+			r = append(r, Span{Start: off[i], End: off[j+1], ID: id})
+		}
+	}
+	slices.SortFunc(r, func(a, b Span) int { return a.Start - b.Start })
+	return r
+}
+
 func moderator(m *tmi.Message) bool {
 	t, _ := m.Tag("mod")
 	if t == "1" {
@@ -2,6 +2,7 @@ package message_test
 
 import (
 	"io"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -22,6 +23,8 @@ func TestFromIRC(t *testing.T) {
 		time   time.Time
 		mod    bool
 		elev   bool
+		parent string
+		emotes []message.Span
 	}{
 		{
 			name:   "regular",
@@ -58,6 +61,19 @@ func TestFromIRC(t *testing.T) {
 			time:   time.UnixMilli(1662885432414),
 			mod:    false,
 			elev:   true,
+			emotes: []message.Span{{Start: 0, End: 10, ID: "emotesv2_6a361d22e95148b3b8fabc886720d5d7"}},
+		},
+		{
+			name:   "reply-emotes",
+			msg:    `@badge-info=;badges=;display-name=Someone;emotes=25:14-18/1902:10-12,xx-1,0-99;id=b1;mod=0;reply-parent-msg-id=a0;subscriber=0;tmi-sent-ts=1662882968379;user-id=123456789;user-type= :someone!someone@someone.tmi.twitch.tv PRIVMSG #channel :@bocchi 😂 Kap Kappa`,
+			id:     "b1",
+			to:     "#channel",
+			sender: "123456789",
+			disp:   "Someone",
+			text:   "@bocchi 😂 Kap Kappa",
+			time:   time.UnixMilli(1662882968379),
+			parent: "a0",
+			emotes: []message.Span{{Start: 13, End: 16, ID: "1902"}, {Start: 17, End: 22, ID: "25"}},
 		},
 		// TODO(zeph): more cases
 	}
@@ -92,6 +108,18 @@ func TestFromIRC(t *testing.T) {
 			if got := msg.IsElevated; got != c.elev {
 				t.Errorf("wrong elev: want %t, got %t", c.elev, got)
 			}
+			if got := msg.ReplyParent; got != c.parent {
+				t.Errorf("wrong reply parent: want %q, got %q", c.parent, got)
+			}
+			if got := msg.ReplyMention; got != (c.parent != "") {
+				t.Errorf("wrong reply mention: want %t, got %t", c.parent != "", got)
+			}
+			if got := msg.Emotes; !slices.Equal(got, c.emotes) {
+				t.Errorf("wrong emotes: want %v, got %v", c.emotes, got)
+			}
+			if got := msg.Raw; got != tm {
+				t.Errorf("wrong raw: want %p, got %v", tm, got)
+			}
 		})
 	}
 }
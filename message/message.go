@@ -6,8 +6,9 @@ import (
 	"time"
 )
 
-// Received is a message received from a service.
-type Received struct {
+// Incoming is a message received from a chat platform, normalized so that
+// nothing downstream needs to know which platform it came from.
+type Incoming struct {
 	// ID is the unique ID of the message.
 	ID string
 	// To is the destination of the message. This may be the identifier of a
@@ -29,12 +30,32 @@ type Received struct {
 	// elevated privileges with respect to the bot, for example a subscriber
 	// on Twitch. This may not implicitly include moderators.
 	IsElevated bool
+	// ReplyParent is the ID of the message to which this message replies, or
+	// the empty string if it is not a reply.
+	ReplyParent string
+	// ReplyMention indicates that the platform prefixed Text with a mention
+	// of the sender of the reply parent, as Twitch does.
+	ReplyMention bool
+	// Emotes are the spans of Text which are emotes, in order.
+	Emotes []Span
+	// Raw is the payload from which the platform created the message, e.g.
+	// a *tmi.Message. It is for platform-specific handling only.
+	Raw any
 }
 
-func (m *Received) Time() time.Time {
+func (m *Incoming) Time() time.Time {
 	return time.UnixMilli(m.Timestamp)
 }
 
+// Span is a range of a message's text.
+type Span struct {
+	// Start and End are the byte offsets of the span in the text.
+	// End is exclusive.
+	Start, End int
+	// ID identifies the span's content, e.g. an emote ID.
+	ID string
+}
+
 // Sent is a message to be sent to a service.
 type Sent struct {
 	// Reply is a message to reply to. If empty, the message is not interpreted
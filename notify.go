@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// notifyOwner sends a notification to the owner.
+// The notification is always logged. If the owner has configured a
+// notification destination, it is also sent there: HTTP URLs receive a POST
+// with a JSON body like {"content": text}, which suits Discord-style webhooks,
+// and anything else is the name of a channel in which to send the message.
+func (robo *Robot) notifyOwner(ctx context.Context, text string) {
+	slog.ErrorContext(ctx, "owner notification", slog.String("text", text))
+	dst := robo.notify
+	switch {
+	case dst == "":
+		return
+	case strings.HasPrefix(dst, "https://"), strings.HasPrefix(dst, "http://"):
+		if err := postNotification(ctx, dst, text); err != nil {
+			slog.ErrorContext(ctx, "couldn't post owner notification", slog.Any("err", err))
+		}
+	default:
+		ch, _ := robo.channels.Load(dst)
+		if ch == nil {
+			slog.ErrorContext(ctx, "owner notification channel doesn't exist", slog.String("channel", dst))
+			return
+		}
+		ch.Message(ctx, "", text)
+	}
+}
+
+func postNotification(ctx context.Context, url, text string) error {
+	b, err := json.Marshal(map[string]string{"content": text})
+	if err != nil {
+		// Should be impossible.
+		panic(err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("couldn't make request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("couldn't post: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("couldn't post: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"expvar"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultBots is the names of popular chat bots whose messages are directed
+// at them unless global.bots.no_defaults is set.
+var defaultBots = []string{
+	"nightbot",
+	"streamelements",
+	"streamlabs",
+	"moobot",
+	"fossabot",
+	"wizebot",
+	"botrixoficial",
+	"sery_bot",
+}
+
+// otherBots recognizes messages directed at chat bots other than this one,
+// like !songrequest or @Nightbot, so that they aren't learned or answered.
+type otherBots struct {
+	// names is the lowercased names of other bots.
+	names map[string]bool
+	// prefixes is the prefixes of other bots' commands.
+	prefixes []string
+	// dropped counts messages directed at other bots.
+	dropped expvar.Int
+}
+
+// SetBots sets the other bots whose messages are ignored.
+func (robo *Robot) SetBots(cfg Bots) {
+	b := &otherBots{names: make(map[string]bool), prefixes: cfg.Prefixes}
+	if !cfg.NoDefaults {
+		for _, nm := range defaultBots {
+			b.names[nm] = true
+		}
+		if cfg.Prefixes == nil {
+			b.prefixes = []string{"!"}
+		}
+	}
+	for _, nm := range cfg.Names {
+		b.names[strings.ToLower(strings.TrimPrefix(nm, "@"))] = true
+	}
+	robo.bots = b
+	robo.metrics.Set("other_bot_messages", &b.dropped)
+}
+
+// directed reports whether a message is directed at another bot: it starts
+// with a command prefix followed by a letter, starts by addressing another
+// bot by name, or mentions another bot with @.
+func (b *otherBots) directed(text string) bool {
+	if b == nil {
+		return false
+	}
+	text = strings.TrimSpace(text)
+	for _, p := range b.prefixes {
+		if p == "" || !strings.HasPrefix(text, p) {
+			continue
+		}
+		r, _ := utf8.DecodeRuneInString(text[len(p):])
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	for i, w := range strings.Fields(text) {
+		at := strings.HasPrefix(w, "@")
+		if i != 0 && !at {
+			continue
+		}
+		w = strings.TrimRight(strings.TrimPrefix(w, "@"), ",:.!?")
+		if b.names[strings.ToLower(w)] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestOtherBots(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  Bots
+		text string
+		want bool
+	}{
+		{"plain", Bots{}, "bocchi the rock", false},
+		{"command", Bots{}, "!songrequest guitar hero", true},
+		{"command-space", Bots{}, "  !sr guitar hero", true},
+		{"exclaim", Bots{}, "!!! wow", false},
+		{"exclaim-space", Bots{}, "! wow", false},
+		{"mention", Bots{}, "@Nightbot !commands", true},
+		{"mention-later", Bots{}, "thanks @StreamElements", true},
+		{"address", Bots{}, "nightbot, what song is this", true},
+		{"name-later", Bots{}, "is nightbot a person", false},
+		{"custom-name", Bots{Names: []string{"@KessokuBot"}}, "@kessokubot hi", true},
+		{"custom-prefix", Bots{Prefixes: []string{"?"}}, "?quote", true},
+		{"custom-prefix-replaces", Bots{Prefixes: []string{"?"}}, "!quote", false},
+		{"no-defaults", Bots{NoDefaults: true}, "!songrequest @nightbot", false},
+		{"no-defaults-custom", Bots{NoDefaults: true, Names: []string{"kessokubot"}}, "@kessokubot hi", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			robo := New(1)
+			robo.SetBots(c.cfg)
+			if got := robo.bots.directed(c.text); got != c.want {
+				t.Errorf("wrong result for %q: want %t, got %t", c.text, c.want, got)
+			}
+		})
+	}
+	var none *otherBots
+	if none.directed("!songrequest") {
+		t.Error("nil otherBots recognized a message")
+	}
+}
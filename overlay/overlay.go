@@ -0,0 +1,113 @@
+// Package overlay serves a browser-source overlay showing the bot's messages
+// on stream.
+package overlay
+
+import (
+	_ "embed"
+	"io"
+	"net/http"
+	"sync"
+
+	"golang.org/x/net/websocket"
+)
+
+// Event is a single update sent to overlays.
+type Event struct {
+	// Kind is "thinking" when the bot begins generating a message, or
+	// "message" when it sends one.
+	Kind string `json:"kind"`
+	// Text is the text of the message.
+	Text string `json:"text,omitempty"`
+}
+
+// Hub distributes events to the overlays watching a channel.
+// The zero value is ready to use. A nil Hub discards events.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[chan Event]struct{}
+}
+
+// Publish sends an event to all current subscribers.
+// Subscribers which are not keeping up miss the event.
+func (h *Hub) Publish(ev Event) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.subs {
+		select {
+		case c <- ev:
+		default:
+		}
+	}
+}
+
+// Subscribe adds a subscriber to the hub.
+// The returned function removes the subscriber.
+func (h *Hub) Subscribe() (<-chan Event, func()) {
+	c := make(chan Event, 8)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs == nil {
+		h.subs = make(map[chan Event]struct{})
+	}
+	h.subs[c] = struct{}{}
+	return c, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subs, c)
+	}
+}
+
+// Thinking announces that the bot has begun generating a message.
+func (h *Hub) Thinking() {
+	h.Publish(Event{Kind: "thinking"})
+}
+
+// Message announces a message the bot has sent.
+func (h *Hub) Message(text string) {
+	h.Publish(Event{Kind: "message", Text: text})
+}
+
+// ServeHTTP serves events over a WebSocket as JSON objects until the client
+// disconnects.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	websocket.Handler(h.stream).ServeHTTP(w, r)
+}
+
+func (h *Hub) stream(ws *websocket.Conn) {
+	defer ws.Close()
+	c, done := h.Subscribe()
+	defer done()
+	// Clients don't send anything, so a read returning means they're gone.
+	gone := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, ws)
+		close(gone)
+	}()
+	ctx := ws.Request().Context()
+	for {
+		select {
+		case <-gone:
+			return
+		case <-ctx.Done():
+			return
+		case ev := <-c:
+			if err := websocket.JSON.Send(ws, ev); err != nil {
+				return
+			}
+		}
+	}
+}
+
+//go:embed page.html
+var page []byte
+
+// Page serves the overlay page, which connects to the WebSocket served at the
+// same path followed by /ws. With the query parameter thinking=1, the overlay
+// shows an animation while the bot is generating a message.
+func Page(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
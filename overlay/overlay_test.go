@@ -0,0 +1,79 @@
+package overlay_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/zephyrtronium/robot/overlay"
+)
+
+func TestHub(t *testing.T) {
+	var h overlay.Hub
+	a, adone := h.Subscribe()
+	b, bdone := h.Subscribe()
+	h.Thinking()
+	bdone()
+	h.Message("bocchi")
+	adone()
+	h.Message("ryo")
+	want := []overlay.Event{{Kind: "thinking"}, {Kind: "message", Text: "bocchi"}}
+	for _, w := range want {
+		select {
+		case ev := <-a:
+			if ev != w {
+				t.Errorf("wrong event: want %+v, got %+v", w, ev)
+			}
+		default:
+			t.Errorf("missing event %+v", w)
+		}
+	}
+	select {
+	case ev := <-a:
+		t.Errorf("unsubscribed got event %+v", ev)
+	default:
+	}
+	if ev := <-b; ev.Kind != "thinking" {
+		t.Errorf("wrong event: want thinking, got %+v", ev)
+	}
+	select {
+	case ev := <-b:
+		t.Errorf("unsubscribed got event %+v", ev)
+	default:
+	}
+}
+
+func TestServe(t *testing.T) {
+	var h overlay.Hub
+	srv := httptest.NewServer(&h)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	ws, err := websocket.Dial(url, "", srv.URL)
+	if err != nil {
+		t.Fatalf("couldn't dial: %v", err)
+	}
+	defer ws.Close()
+	// Publish until the server has subscribed.
+	got := make(chan overlay.Event)
+	go func() {
+		var ev overlay.Event
+		if err := websocket.JSON.Receive(ws, &ev); err != nil {
+			t.Errorf("couldn't receive: %v", err)
+		}
+		got <- ev
+	}()
+	for {
+		h.Message("kita")
+		select {
+		case ev := <-got:
+			if ev.Kind != "message" || ev.Text != "kita" {
+				t.Errorf("wrong event: %+v", ev)
+			}
+			return
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
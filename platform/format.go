@@ -0,0 +1,123 @@
+package platform
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Format describes how to adapt generated text to a platform.
+// The zero value only replaces line breaks with spaces and trims the text.
+type Format struct {
+	// MaxLength is the maximum length of a sent message in characters, or 0
+	// if the client handles long messages itself.
+	MaxLength int
+	// Newlines indicates that the platform displays line breaks. Otherwise
+	// they are replaced with spaces.
+	Newlines bool
+	// Mention formats a mention of a user given the name following an @.
+	// If it is nil, mentions stay as @name.
+	Mention func(name string) string
+	// Escape escapes text which the platform would otherwise interpret as
+	// markup. If it is nil, text is sent as is.
+	Escape func(text string) string
+}
+
+// Apply adapts text to the format. emote gives the platform's spelling of a
+// word that may be an emote; if it is nil, words are unchanged.
+// Text longer than the maximum length is cut at the last whole word that fits.
+func (f *Format) Apply(text string, emote func(string) string) string {
+	var b strings.Builder
+	n := 0
+	for len(text) > 0 {
+		// Take leading space, then a word.
+		i := strings.IndexFunc(text, func(r rune) bool { return !unicode.IsSpace(r) })
+		if i < 0 {
+			break
+		}
+		sp := text[:i]
+		text = text[i:]
+		j := strings.IndexFunc(text, unicode.IsSpace)
+		if j < 0 {
+			j = len(text)
+		}
+		w := f.word(text[:j], emote)
+		text = text[j:]
+		switch {
+		case b.Len() == 0:
+			sp = ""
+		case !f.Newlines && strings.ContainsFunc(sp, isNewline):
+			sp = " "
+		}
+		k := utf8.RuneCountInString(sp) + utf8.RuneCountInString(w)
+		if f.MaxLength > 0 && n+k > f.MaxLength {
+			if b.Len() == 0 {
+				// A single word is too long. Cut it wherever.
+				b.WriteString(cut(w, f.MaxLength))
+			}
+			break
+		}
+		b.WriteString(sp)
+		b.WriteString(w)
+		n += k
+	}
+	return b.String()
+}
+
+// word formats a single word.
+func (f *Format) word(w string, emote func(string) string) string {
+	if f.Mention != nil && len(w) > 1 && w[0] == '@' {
+		// Leave trailing punctuation after the name.
+		i := strings.IndexFunc(w[1:], func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+		})
+		switch {
+		case i < 0:
+			return f.Mention(w[1:])
+		case i > 0:
+			return f.Mention(w[1:i+1]) + f.escape(w[i+1:])
+		}
+	}
+	if emote != nil {
+		w = emote(w)
+	}
+	return f.escape(w)
+}
+
+func (f *Format) escape(s string) string {
+	if f.Escape == nil {
+		return s
+	}
+	return f.Escape(s)
+}
+
+// EscapeMarkdown escapes characters which Markdown-flavored chat platforms
+// interpret as formatting.
+func EscapeMarkdown(text string) string {
+	return markdown.Replace(text)
+}
+
+var markdown = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"_", `\_`,
+	"~", `\~`,
+	"`", "\\`",
+	"|", `\|`,
+	">", `\>`,
+)
+
+// cut truncates s to at most n runes.
+func cut(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+func isNewline(r rune) bool {
+	return r == '\n' || r == '\r'
+}
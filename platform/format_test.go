@@ -0,0 +1,47 @@
+package platform_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zephyrtronium/robot/platform"
+)
+
+func TestFormatApply(t *testing.T) {
+	emote := func(w string) string {
+		if strings.EqualFold(w, "kappa") {
+			return "Kappa"
+		}
+		return w
+	}
+	mention := func(name string) string { return "<@" + name + ">" }
+	cases := []struct {
+		name string
+		f    platform.Format
+		text string
+		want string
+	}{
+		{"zero", platform.Format{}, "bocchi the rock", "bocchi the rock"},
+		{"trim", platform.Format{}, "  bocchi the rock \n", "bocchi the rock"},
+		{"newlines-removed", platform.Format{}, "bocchi\nthe\r\nrock", "bocchi the rock"},
+		{"newlines-kept", platform.Format{Newlines: true}, "bocchi\nthe\r\nrock", "bocchi\nthe\r\nrock"},
+		{"emote", platform.Format{}, "kappa KAPPA Kappa kappa123", "Kappa Kappa Kappa kappa123"},
+		{"mention", platform.Format{Mention: mention}, "hi @bocchi, @ryou_y and @", "hi <@bocchi>, <@ryou_y> and @"},
+		{"mention-nil", platform.Format{}, "hi @bocchi", "hi @bocchi"},
+		{"mention-punct", platform.Format{Mention: mention}, "@!", "@!"},
+		{"escape", platform.Format{Escape: platform.EscapeMarkdown}, "*bold* _it_", `\*bold\* \_it\_`},
+		{"escape-mention", platform.Format{Mention: mention, Escape: platform.EscapeMarkdown}, "@bocchi>", `<@bocchi>\>`},
+		{"length", platform.Format{MaxLength: 10}, "bocchi the rock", "bocchi the"},
+		{"length-exact", platform.Format{MaxLength: 15}, "bocchi the rock", "bocchi the rock"},
+		{"length-word", platform.Format{MaxLength: 4}, "bocchi the rock", "bocc"},
+		{"length-runes", platform.Format{MaxLength: 3}, "ぼっち ちゃん", "ぼっち"},
+		{"length-escaped", platform.Format{MaxLength: 5, Escape: platform.EscapeMarkdown}, "a *b*", "a"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.f.Apply(c.text, emote); got != c.want {
+				t.Errorf("wrong result for %q: want %q, got %q", c.text, c.want, got)
+			}
+		})
+	}
+}
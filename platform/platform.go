@@ -0,0 +1,90 @@
+// Package platform defines the interface between Robot and the chat platforms
+// it connects to.
+//
+// A [Client] connects to a platform, normalizes what it receives into calls on
+// a [Handler], and sends messages back. Robot implements Handler once for all
+// platforms, so adding a platform means implementing Client and nothing in
+// the core.
+package platform
+
+import (
+	"context"
+	"time"
+
+	"github.com/zephyrtronium/robot/message"
+)
+
+// Client is a connection to a chat platform.
+type Client interface {
+	// Platform names the platform, e.g. twitch.
+	Platform() string
+	// Bot returns the bot's user ID and name on the platform.
+	// The name is used to recognize commands.
+	Bot() (id, name string)
+	// Owner returns the user ID of the bot owner on the platform.
+	// It is the empty string if there is no owner.
+	Owner() string
+	// Capabilities describes what the platform supports.
+	Capabilities() Capabilities
+	// Run connects to the platform and calls methods of h for what it
+	// receives until ctx is canceled or the connection fails permanently.
+	Run(ctx context.Context, h Handler) error
+	// Send sends text to a channel. If reply is not empty, it is the ID of
+	// a message to which the text replies.
+	Send(ctx context.Context, channel, reply, text string) error
+}
+
+// Capabilities describes optional features of a platform.
+type Capabilities struct {
+	// Replies indicates that messages can reply to other messages.
+	Replies bool
+	// Threads indicates that replies go in threads separate from the main
+	// channel.
+	Threads bool
+	// Live indicates that channels go online and offline, which the client
+	// reports with [Handler.Live]. Channels on platforms without this
+	// capability are always online.
+	Live bool
+	// Format is how to adapt generated text for the platform.
+	Format Format
+}
+
+// Handler handles what clients receive from platforms.
+// Its methods must not block for long.
+type Handler interface {
+	// Message handles a chat message. The channel is m.To.
+	Message(ctx context.Context, c Client, m *message.Incoming)
+	// Delete handles a moderator deleting a single message. If own is true,
+	// the message was sent by the bot, and text is its text.
+	Delete(ctx context.Context, c Client, channel, id, text string, own bool)
+	// Clear handles a moderator removing a user's recent messages at time t,
+	// e.g. by a timeout or ban. user is the user's ID. If user is empty, all
+	// recent messages in the channel are removed.
+	Clear(ctx context.Context, c Client, channel, user string, t time.Time)
+	// Live handles the state of a channel's stream, e.g. going online or
+	// offline.
+	Live(ctx context.Context, c Client, channel string, s Stream)
+	// Mode handles a change to the restrictions on chat in a channel.
+	// mode is the complete set of restrictions in effect.
+	Mode(ctx context.Context, c Client, channel string, mode Mode)
+}
+
+// Stream is the state of a channel's stream.
+type Stream struct {
+	// Live indicates that the channel is streaming.
+	Live bool
+	// Game is the game or category being streamed.
+	// It is empty if the channel is offline or the platform doesn't say.
+	Game string
+}
+
+// Mode is restrictions that a channel's moderators have placed on chat.
+type Mode struct {
+	// EmoteOnly indicates that messages may contain only emotes.
+	EmoteOnly bool
+	// SubOnly indicates that only subscribers may chat.
+	SubOnly bool
+	// Slow is the minimum time between each user's messages.
+	// It is zero if slow mode is off.
+	Slow time.Duration
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/fault"
+	"github.com/zephyrtronium/robot/poster"
+)
+
+// posterJob periodically posts generated messages to social media.
+type posterJob struct {
+	// name is the name of the poster's configuration.
+	name string
+	// tag is the tag from which to generate posts.
+	tag string
+	// every is the interval between posts.
+	every time.Duration
+	// block matches generated messages not to post.
+	block *regexp.Regexp
+	// targets are the accounts to which to post.
+	targets []poster.Target
+	// review holds candidates for approval. If it is nil, posts are published
+	// without review.
+	review *poster.Queue
+}
+
+// SetPosters initializes social media posting.
+func (robo *Robot) SetPosters(global Global, posters map[string]*PosterCfg) error {
+	robo.posters = make(map[string]*posterJob, len(posters))
+	for nm, cfg := range posters {
+		if cfg.Every <= 0 {
+			return fmt.Errorf("poster.%s needs a positive interval", nm)
+		}
+		blk, err := regexp.Compile("(" + global.Block + ")|(" + cfg.Block + ")")
+		if err != nil {
+			return fmt.Errorf("bad global or poster block expression for poster.%s: %w", nm, err)
+		}
+		p := &posterJob{
+			name:  nm,
+			tag:   cfg.Tag,
+			every: fseconds(cfg.Every),
+			block: blk,
+		}
+		if cfg.Mastodon.Server != "" {
+			p.targets = append(p.targets, &poster.Mastodon{
+				Server:     cfg.Mastodon.Server,
+				Token:      cfg.Mastodon.Token,
+				Visibility: cfg.Mastodon.Visibility,
+			})
+		}
+		if cfg.Bluesky.Handle != "" {
+			p.targets = append(p.targets, &poster.Bluesky{
+				Service:  cfg.Bluesky.Service,
+				Handle:   cfg.Bluesky.Handle,
+				Password: cfg.Bluesky.Password,
+			})
+		}
+		if len(p.targets) == 0 {
+			return fmt.Errorf("poster.%s has no accounts", nm)
+		}
+		if cfg.Review {
+			p.review = &poster.Queue{Expiry: fseconds(cfg.Expire)}
+		}
+		robo.posters[nm] = p
+	}
+	return nil
+}
+
+func (robo *Robot) runPoster(ctx context.Context, p *posterJob) error {
+	tick := time.NewTicker(p.every)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+		}
+		s := robo.postCandidate(ctx, p)
+		if s == "" {
+			continue
+		}
+		if p.review != nil {
+			c := p.review.Add(s, time.Now())
+			slog.InfoContext(ctx, "post awaiting review", slog.String("poster", p.name), slog.String("id", c.ID), slog.String("text", s))
+			continue
+		}
+		robo.publish(ctx, p, s)
+	}
+}
+
+// postCandidate generates a message to post.
+// The result is the empty string if nothing suitable was generated.
+func (robo *Robot) postCandidate(ctx context.Context, p *posterJob) string {
+	limit := p.limit()
+	// Try a few times, since the content filter is intentionally strict.
+	for range 5 {
+		start := time.Now()
+		s, trace, err := brain.Speak(ctx, robo.brain, p.tag, "")
+		cost := time.Since(start)
+		if errors.Is(err, fault.ErrBrainUnavailable) {
+			slog.WarnContext(ctx, "couldn't generate post; brain is unavailable", slog.String("poster", p.name))
+			return ""
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "couldn't generate post", slog.Any("err", err), slog.String("poster", p.name))
+			return ""
+		}
+		if err := robo.spoken.Record(ctx, p.tag, s, trace, time.Now(), cost, s, "", ""); err != nil {
+			slog.ErrorContext(ctx, "couldn't record trace", slog.Any("err", err))
+			return ""
+		}
+		if why := unpostable(s, p.block, limit); why != "" {
+			slog.InfoContext(ctx, "won't post", slog.String("poster", p.name), slog.String("why", why), slog.String("text", s))
+			continue
+		}
+		return s
+	}
+	return ""
+}
+
+// mentionOrLink matches text which would notify people or link elsewhere when
+// posted.
+var mentionOrLink = regexp.MustCompile(`(?i)(^|\s)[@#]\w|https?://|www\.|\w\.(com|net|org|social|tv)\b`)
+
+// unpostable returns the reason a generated message should not be posted, or
+// the empty string if it is fine.
+func unpostable(s string, block *regexp.Regexp, limit int) string {
+	switch {
+	case s == "":
+		return "empty"
+	case utf8.RuneCountInString(s) > limit:
+		return "too long"
+	case block.MatchString(s):
+		return "blocked"
+	case mentionOrLink.MatchString(s):
+		return "mention or link"
+	}
+	return ""
+}
+
+// limit returns the shortest length limit among a poster's accounts.
+func (p *posterJob) limit() int {
+	limit := 0
+	for _, t := range p.targets {
+		if limit == 0 || t.Limit() < limit {
+			limit = t.Limit()
+		}
+	}
+	return limit
+}
+
+// publish posts to all of a poster's accounts.
+// It returns the number of accounts that got the post and an error joining
+// the failures for the rest.
+func (robo *Robot) publish(ctx context.Context, p *posterJob, s string) (int, error) {
+	var (
+		n    int
+		errs []error
+	)
+	for _, t := range p.targets {
+		if err := t.Post(ctx, s); err != nil {
+			slog.ErrorContext(ctx, "couldn't post", slog.Any("err", err), slog.String("poster", p.name), slog.Any("target", t))
+			errs = append(errs, fmt.Errorf("%v: %w", t, err))
+			continue
+		}
+		slog.InfoContext(ctx, "posted", slog.String("poster", p.name), slog.Any("target", t), slog.String("text", s))
+		n++
+	}
+	return n, errors.Join(errs...)
+}
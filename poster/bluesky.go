@@ -0,0 +1,113 @@
+package poster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Bluesky posts to a Bluesky account.
+type Bluesky struct {
+	// HTTP is the HTTP client for performing requests.
+	// If nil, http.DefaultClient is used.
+	HTTP *http.Client
+	// Service is the base URL of the account's PDS.
+	// If it is empty, https://bsky.social is used.
+	Service string
+	// Handle is the account's handle or DID.
+	Handle string
+	// Password is an app password for the account.
+	Password string
+
+	// mu guards the session.
+	mu sync.Mutex
+	// did and jwt are the session's account DID and access token.
+	did, jwt string
+}
+
+var _ Target = (*Bluesky)(nil)
+
+func (b *Bluesky) xrpc(method string) string {
+	base := b.Service
+	if base == "" {
+		base = "https://bsky.social"
+	}
+	u, err := url.JoinPath(base, "xrpc", method)
+	if err != nil {
+		panic("poster: bad url join with " + method)
+	}
+	return u
+}
+
+// session creates a new session if there isn't one.
+// The lock must be held.
+func (b *Bluesky) session(ctx context.Context) error {
+	if b.jwt != "" {
+		return nil
+	}
+	req, err := jsonreq(ctx, b.xrpc("com.atproto.server.createSession"), map[string]string{
+		"identifier": b.Handle,
+		"password":   b.Password,
+	})
+	if err != nil {
+		return err
+	}
+	var r struct {
+		DID string `json:"did"`
+		JWT string `json:"accessJwt"`
+	}
+	if err := do(b.HTTP, req, &r); err != nil {
+		return fmt.Errorf("couldn't create Bluesky session for %s: %w", b.Handle, err)
+	}
+	b.did, b.jwt = r.DID, r.JWT
+	return nil
+}
+
+// Post publishes a post.
+func (b *Bluesky) Post(ctx context.Context, text string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// Access tokens are short-lived, so try once more with a new session if
+	// the first attempt is unauthorized.
+	for i := 0; ; i++ {
+		if err := b.session(ctx); err != nil {
+			return err
+		}
+		req, err := jsonreq(ctx, b.xrpc("com.atproto.repo.createRecord"), map[string]any{
+			"repo":       b.did,
+			"collection": "app.bsky.feed.post",
+			"record": map[string]string{
+				"$type":     "app.bsky.feed.post",
+				"text":      text,
+				"createdAt": time.Now().UTC().Format(time.RFC3339),
+			},
+		})
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+b.jwt)
+		err = do(b.HTTP, req, nil)
+		var se *statusError
+		if i == 0 && errors.As(err, &se) && (se.code == http.StatusUnauthorized || se.code == http.StatusBadRequest) {
+			b.jwt = ""
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("couldn't post to %v: %w", b, err)
+		}
+		return nil
+	}
+}
+
+// Limit returns the Bluesky post length limit.
+func (b *Bluesky) Limit() int {
+	return 300
+}
+
+func (b *Bluesky) String() string {
+	return "Bluesky " + b.Handle
+}
@@ -0,0 +1,56 @@
+package poster
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Mastodon posts statuses to a Mastodon account.
+type Mastodon struct {
+	// HTTP is the HTTP client for performing requests.
+	// If nil, http.DefaultClient is used.
+	HTTP *http.Client
+	// Server is the base URL of the account's server.
+	Server string
+	// Token is an access token with the write:statuses scope.
+	Token string
+	// Visibility is the visibility of posted statuses, e.g. public or
+	// unlisted. If it is empty, the account's default is used.
+	Visibility string
+}
+
+var _ Target = (*Mastodon)(nil)
+
+// Post publishes a status.
+func (m *Mastodon) Post(ctx context.Context, text string) error {
+	u, err := url.JoinPath(m.Server, "api/v1/statuses")
+	if err != nil {
+		return fmt.Errorf("bad Mastodon server %q: %w", m.Server, err)
+	}
+	f := url.Values{"status": {text}}
+	if m.Visibility != "" {
+		f.Set("visibility", m.Visibility)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", u, strings.NewReader(f.Encode()))
+	if err != nil {
+		return fmt.Errorf("couldn't make request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+m.Token)
+	if err := do(m.HTTP, req, nil); err != nil {
+		return fmt.Errorf("couldn't post to %v: %w", m, err)
+	}
+	return nil
+}
+
+// Limit returns the default Mastodon status length limit.
+func (m *Mastodon) Limit() int {
+	return 500
+}
+
+func (m *Mastodon) String() string {
+	return "Mastodon " + m.Server
+}
@@ -0,0 +1,72 @@
+// Package poster implements posting to social media accounts.
+package poster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Target is a social media account to which to post.
+type Target interface {
+	// Post publishes text.
+	Post(ctx context.Context, text string) error
+	// Limit returns the maximum length of a post in characters.
+	Limit() int
+	// String names the target for logging.
+	String() string
+}
+
+// do performs an HTTP request and decodes the JSON response into r, if it is
+// not nil. The response body is truncated to 2 MB.
+func do(hc *http.Client, req *http.Request, r any) error {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("couldn't %s: %w", req.Method, err)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 2<<20))
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("couldn't read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &statusError{code: resp.StatusCode, body: b}
+	}
+	if r == nil {
+		return nil
+	}
+	if err := json.Unmarshal(b, r); err != nil {
+		return fmt.Errorf("couldn't decode JSON response: %w", err)
+	}
+	return nil
+}
+
+// jsonreq creates a POST request with a JSON body.
+func jsonreq(ctx context.Context, url string, body any) (*http.Request, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't make request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+type statusError struct {
+	code int
+	body []byte
+}
+
+func (err *statusError) Error() string {
+	return fmt.Sprintf("request failed: %s (%d %s)", err.body, err.code, http.StatusText(err.code))
+}
@@ -0,0 +1,90 @@
+package poster
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMastodon(t *testing.T) {
+	var got, auth, vis string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/v1/statuses" {
+			http.NotFound(w, r)
+			return
+		}
+		auth = r.Header.Get("Authorization")
+		got, vis = r.FormValue("status"), r.FormValue("visibility")
+		io.WriteString(w, `{"id":"1"}`)
+	}))
+	defer srv.Close()
+	m := Mastodon{Server: srv.URL, Token: "bocchi", Visibility: "unlisted"}
+	if err := m.Post(context.Background(), "kessoku band"); err != nil {
+		t.Fatal(err)
+	}
+	if got != "kessoku band" {
+		t.Errorf("wrong status: %q", got)
+	}
+	if vis != "unlisted" {
+		t.Errorf("wrong visibility: %q", vis)
+	}
+	if auth != "Bearer bocchi" {
+		t.Errorf("wrong auth: %q", auth)
+	}
+}
+
+func TestBluesky(t *testing.T) {
+	var sessions int
+	var posted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/xrpc/com.atproto.server.createSession":
+			sessions++
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["identifier"] != "bocchi.bsky.social" || body["password"] != "guitar" {
+				http.Error(w, `{"error":"AuthenticationRequired"}`, http.StatusUnauthorized)
+				return
+			}
+			// Each session's token is only good for one post.
+			io.WriteString(w, `{"did":"did:plc:bocchi","accessJwt":"jwt`+string(rune('0'+sessions))+`"}`)
+		case "/xrpc/com.atproto.repo.createRecord":
+			want := "Bearer jwt" + string(rune('0'+sessions))
+			if r.Header.Get("Authorization") != want || len(posted) >= sessions {
+				http.Error(w, `{"error":"ExpiredToken"}`, http.StatusBadRequest)
+				return
+			}
+			var body struct {
+				Repo   string `json:"repo"`
+				Record struct {
+					Text string `json:"text"`
+				} `json:"record"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			if body.Repo != "did:plc:bocchi" {
+				t.Errorf("wrong repo: %q", body.Repo)
+			}
+			posted = append(posted, body.Record.Text)
+			io.WriteString(w, `{"uri":"at://x","cid":"y"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	b := Bluesky{Service: srv.URL, Handle: "bocchi.bsky.social", Password: "guitar"}
+	if err := b.Post(context.Background(), "one"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Post(context.Background(), "two"); err != nil {
+		t.Fatal(err)
+	}
+	if sessions != 2 {
+		t.Errorf("wrong number of sessions: want 2, got %d", sessions)
+	}
+	if len(posted) != 2 || posted[0] != "one" || posted[1] != "two" {
+		t.Errorf("wrong posts: %q", posted)
+	}
+}
@@ -0,0 +1,119 @@
+package poster
+
+import (
+	_ "embed"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Candidate is a generated post awaiting review.
+type Candidate struct {
+	ID   string    `json:"id"`
+	Text string    `json:"text"`
+	Time time.Time `json:"time"`
+	// Expires is the time after which the candidate is discarded.
+	// It is the zero time if the candidate does not expire.
+	Expires time.Time `json:"expires"`
+}
+
+// Queue holds candidates until an operator approves, rejects, or edits them.
+// The zero value is an empty queue whose candidates never expire.
+// A Queue is safe for concurrent use.
+type Queue struct {
+	// Expiry is the duration after which candidates are discarded.
+	// If it is not positive, candidates never expire.
+	Expiry time.Duration
+
+	mu    sync.Mutex
+	items []Candidate
+	next  uint64
+}
+
+// Add adds a candidate to the queue.
+func (q *Queue) Add(text string, now time.Time) Candidate {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.next++
+	c := Candidate{ID: strconv.FormatUint(q.next, 10), Text: text, Time: now}
+	if q.Expiry > 0 {
+		c.Expires = now.Add(q.Expiry)
+	}
+	q.items = append(q.items, c)
+	return c
+}
+
+// expire discards expired candidates. The lock must be held.
+func (q *Queue) expire(now time.Time) {
+	k := 0
+	for _, c := range q.items {
+		if c.Expires.IsZero() || now.Before(c.Expires) {
+			q.items[k] = c
+			k++
+		}
+	}
+	clear(q.items[k:])
+	q.items = q.items[:k]
+}
+
+// List returns the unexpired candidates in the queue, oldest first.
+func (q *Queue) List(now time.Time) []Candidate {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expire(now)
+	return append([]Candidate{}, q.items...)
+}
+
+// Get returns an unexpired candidate without removing it.
+func (q *Queue) Get(id string, now time.Time) (Candidate, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expire(now)
+	for _, c := range q.items {
+		if c.ID == id {
+			return c, true
+		}
+	}
+	return Candidate{}, false
+}
+
+// Remove removes an unexpired candidate from the queue and returns it.
+func (q *Queue) Remove(id string, now time.Time) (Candidate, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expire(now)
+	for i, c := range q.items {
+		if c.ID == id {
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			return c, true
+		}
+	}
+	return Candidate{}, false
+}
+
+// Edit replaces the text of an unexpired candidate.
+// It does not change the candidate's expiry.
+func (q *Queue) Edit(id, text string, now time.Time) (Candidate, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expire(now)
+	for i := range q.items {
+		if q.items[i].ID == id {
+			q.items[i].Text = text
+			return q.items[i], true
+		}
+	}
+	return Candidate{}, false
+}
+
+//go:embed review.html
+var reviewPage []byte
+
+// ReviewPage serves a page for reviewing candidates. It uses the JSON list
+// of candidates served at the same path followed by /review and posts actions
+// to /review/<id>/<action> under the same path.
+func ReviewPage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(reviewPage)
+}
@@ -0,0 +1,36 @@
+package poster
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueue(t *testing.T) {
+	now := time.Unix(1e9, 0)
+	q := Queue{Expiry: time.Hour}
+	a := q.Add("bocchi", now)
+	b := q.Add("ryo", now.Add(30*time.Minute))
+	if got := q.List(now); len(got) != 2 || got[0].ID != a.ID || got[1].ID != b.ID {
+		t.Fatalf("wrong list: %+v", got)
+	}
+	if c, ok := q.Edit(b.ID, "nijika", now); !ok || c.Text != "nijika" {
+		t.Errorf("wrong edit: %+v, %t", c, ok)
+	}
+	if c, ok := q.Get(b.ID, now); !ok || c.Text != "nijika" {
+		t.Errorf("wrong get: %+v, %t", c, ok)
+	}
+	// a expires.
+	got := q.List(now.Add(time.Hour))
+	if len(got) != 1 || got[0].ID != b.ID || got[0].Text != "nijika" {
+		t.Fatalf("wrong list after expiry: %+v", got)
+	}
+	if _, ok := q.Remove(a.ID, now.Add(time.Hour)); ok {
+		t.Errorf("removed expired candidate")
+	}
+	if c, ok := q.Remove(b.ID, now.Add(time.Hour)); !ok || c.Text != "nijika" {
+		t.Errorf("wrong remove: %+v, %t", c, ok)
+	}
+	if got := q.List(now); len(got) != 0 {
+		t.Errorf("queue not empty: %+v", got)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/poster"
+)
+
+func TestUnpostable(t *testing.T) {
+	block := regexp.MustCompile(`(bad)|()x\b`)
+	cases := []struct {
+		name string
+		in   string
+		ok   bool
+	}{
+		{"fine", "kessoku band plays tonight", true},
+		{"empty", "", false},
+		{"long", "bocchi the rock bocchi the rock!", false},
+		{"blocked", "this is bad", false},
+		{"mention", "hi @bocchi", false},
+		{"hashtag", "#kessoku", false},
+		{"email", "bocchi@example", true},
+		{"link", "see https://example", false},
+		{"domain", "go to kessoku.com now", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			why := unpostable(c.in, block, 30)
+			if (why == "") != c.ok {
+				t.Errorf("wrong result for %q: got %q", c.in, why)
+			}
+		})
+	}
+}
+
+// testTarget is a poster target that records posts.
+type testTarget struct {
+	err   error
+	posts []string
+}
+
+func (t *testTarget) Post(ctx context.Context, text string) error {
+	if t.err != nil {
+		return t.err
+	}
+	t.posts = append(t.posts, text)
+	return nil
+}
+
+func (t *testTarget) Limit() int     { return 30 }
+func (t *testTarget) String() string { return "test" }
+
+func TestReviewHTTP(t *testing.T) {
+	tt := &testTarget{err: errors.New("bocchi is hiding")}
+	p := &posterJob{
+		name:    "kessoku",
+		block:   regexp.MustCompile(`bad`),
+		targets: []poster.Target{tt},
+		review:  new(poster.Queue),
+	}
+	robo := New(1)
+	robo.posters = map[string]*posterJob{"kessoku": p}
+	review := func(id, action, text string) int {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/poster/kessoku/review/"+id+"/"+action, strings.NewReader(url.Values{"text": {text}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetPathValue("name", "kessoku")
+		req.SetPathValue("id", id)
+		req.SetPathValue("action", action)
+		w := httptest.NewRecorder()
+		robo.reviewHTTP(w, req)
+		return w.Code
+	}
+	c := p.review.Add("kessoku band", time.Now())
+	if code := review(c.ID, "edit", "this is bad"); code != http.StatusBadRequest {
+		t.Errorf("blocked edit: want %d, got %d", http.StatusBadRequest, code)
+	}
+	if code := review(c.ID, "approve", "bocchi the rock bocchi the rock!"); code != http.StatusBadRequest {
+		t.Errorf("long approval: want %d, got %d", http.StatusBadRequest, code)
+	}
+	if code := review(c.ID, "approve", ""); code != http.StatusBadGateway {
+		t.Errorf("failed post: want %d, got %d", http.StatusBadGateway, code)
+	}
+	if got, ok := p.review.Get(c.ID, time.Now()); !ok || got.Text != "kessoku band" {
+		t.Errorf("candidate changed after failures: %+v, %t", got, ok)
+	}
+	tt.err = nil
+	if code := review(c.ID, "approve", ""); code != http.StatusNoContent {
+		t.Errorf("approval: want %d, got %d", http.StatusNoContent, code)
+	}
+	if len(tt.posts) != 1 || tt.posts[0] != "kessoku band" {
+		t.Errorf("wrong posts: %q", tt.posts)
+	}
+	if _, ok := p.review.Get(c.ID, time.Now()); ok {
+		t.Error("candidate still queued after posting")
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+)
+
+// addPreload marks a tag to be read into memory at startup. If top is
+// positive, only tuples with that many of the most frequent prefixes are
+// read; otherwise, the whole tag is. Preloading the whole tag takes priority
+// over any top, and otherwise the largest top applies.
+func (robo *Robot) addPreload(tag string, p Preload) {
+	if tag == "" || !p.All && p.Top <= 0 {
+		return
+	}
+	if robo.preloads == nil {
+		robo.preloads = make(map[string]int)
+	}
+	top, ok := robo.preloads[tag]
+	switch {
+	case p.All || ok && top <= 0:
+		robo.preloads[tag] = 0
+	default:
+		robo.preloads[tag] = max(top, p.Top)
+	}
+}
+
+// preload reads the tags marked for preloading into memory, logging progress
+// along the way.
+func (robo *Robot) preload(ctx context.Context) error {
+	if len(robo.preloads) == 0 {
+		return nil
+	}
+	p, ok := brain.As[brain.Preloader](robo.brain)
+	if !ok {
+		slog.WarnContext(ctx, "brain doesn't support preloading, so nothing will be preloaded")
+		return nil
+	}
+	for _, tag := range slices.Sorted(maps.Keys(robo.preloads)) {
+		top := robo.preloads[tag]
+		slog.InfoContext(ctx, "preloading", slog.String("tag", tag), slog.Int("top", top))
+		start := time.Now()
+		last := start
+		progress := func(n int64) {
+			if time.Since(last) < 5*time.Second {
+				return
+			}
+			last = time.Now()
+			slog.InfoContext(ctx, "preloading", slog.String("tag", tag), slog.Int64("tuples", n), slog.Duration("elapsed", time.Since(start)))
+		}
+		n, err := p.Preload(ctx, tag, top, progress)
+		if err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "preloaded", slog.String("tag", tag), slog.Int64("tuples", n), slog.Duration("took", time.Since(start)))
+	}
+	return nil
+}
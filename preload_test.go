@@ -0,0 +1,21 @@
+package main
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestAddPreload(t *testing.T) {
+	robo := New(1)
+	robo.addPreload("kessoku", Preload{Top: 10})
+	robo.addPreload("kessoku", Preload{Top: 100})
+	robo.addPreload("kessoku", Preload{Top: 50})
+	robo.addPreload("sickhack", Preload{All: true})
+	robo.addPreload("sickhack", Preload{Top: 10})
+	robo.addPreload("starry", Preload{})
+	robo.addPreload("", Preload{All: true})
+	want := map[string]int{"kessoku": 100, "sickhack": 0}
+	if !maps.Equal(robo.preloads, want) {
+		t.Errorf("wrong preloads: want %v, got %v", want, robo.preloads)
+	}
+}
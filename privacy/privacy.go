@@ -6,6 +6,8 @@ import (
 	"fmt"
 
 	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/zephyrtronium/robot/sqlpool"
 )
 
 // ErrPrivate is an error returned by Check when the user is in the list.
@@ -14,6 +16,9 @@ var ErrPrivate = errors.New("user is private")
 // List is a List backed by an SQL database.
 type List struct {
 	db *sqlitex.Pool
+	// retry retries operations which find the database busy.
+	// If it is nil, operations are attempted once.
+	retry *sqlpool.Retrier
 }
 
 // Open opens an existing privacy list in an SQL database.
@@ -30,6 +35,12 @@ func Open(ctx context.Context, db *sqlitex.Pool) (*List, error) {
 	return &List{db: db}, nil
 }
 
+// SetRetry sets the retrier for operations which find the database busy.
+// It must be called before using the list concurrently.
+func (l *List) SetRetry(r *sqlpool.Retrier) {
+	l.retry = r
+}
+
 // Add adds a user to the database.
 func (l *List) Add(ctx context.Context, user string) error {
 	conn, err := l.db.Take(ctx)
@@ -38,8 +49,9 @@ func (l *List) Add(ctx context.Context, user string) error {
 		return fmt.Errorf("couldn't get connection to add user to privacy list: %w", err)
 	}
 	opts := sqlitex.ExecOptions{Args: []any{user}}
-	err = sqlitex.Execute(conn, `INSERT INTO privacy (user) VALUES (?)`, &opts)
-	return err
+	return l.retry.Do(ctx, func() error {
+		return sqlitex.Execute(conn, `INSERT INTO privacy (user) VALUES (?)`, &opts)
+	})
 }
 
 // Remove removes a user from the database.
@@ -50,8 +62,9 @@ func (l *List) Remove(ctx context.Context, user string) error {
 		return fmt.Errorf("couldn't get connection to remove user from privacy list: %w", err)
 	}
 	opts := sqlitex.ExecOptions{Args: []any{user}}
-	err = sqlitex.Execute(conn, `DELETE FROM privacy WHERE user=?`, &opts)
-	return err
+	return l.retry.Do(ctx, func() error {
+		return sqlitex.Execute(conn, `DELETE FROM privacy WHERE user=?`, &opts)
+	})
 }
 
 // Check checks whether a user is in the database.
@@ -66,7 +79,12 @@ func (l *List) Check(ctx context.Context, user string) error {
 		return fmt.Errorf("couldn't prepare statement to check user privacy: %w", err)
 	}
 	st.BindText(1, user)
-	ok, err := sqlitex.ResultBool(st)
+	var ok bool
+	err = l.retry.Do(ctx, func() error {
+		var err error
+		ok, err = sqlitex.ResultBool(st)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"math/rand/v2"
 	"regexp"
+	"runtime/debug"
 	"strings"
 	"time"
 	"unicode"
@@ -13,214 +16,411 @@ import (
 	"gitlab.com/zephyrtronium/tmi"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/zephyrtronium/robot/audit"
 	"github.com/zephyrtronium/robot/brain"
 	"github.com/zephyrtronium/robot/channel"
 	"github.com/zephyrtronium/robot/command"
+	"github.com/zephyrtronium/robot/fault"
 	"github.com/zephyrtronium/robot/message"
 	"github.com/zephyrtronium/robot/privacy"
 	"github.com/zephyrtronium/robot/userhash"
 )
 
-// tmiMessage processes a PRIVMSG from TMI.
-func (robo *Robot) tmiMessage(ctx context.Context, group *errgroup.Group, send chan<- *tmi.Message, msg *tmi.Message) {
-	ch, _ := robo.channels.Load(msg.To())
-	if ch == nil {
-		// TMI gives a WHISPER for a direct message, so this is a message to a
-		// channel that isn't configured. Ignore it.
+// chat handles a chat message in a channel on any service.
+// name is the bot's name on the service, used to recognize commands, and owner
+// is the owner's user ID on the service.
+// It must run in a work for the channel.
+func (robo *Robot) chat(ctx context.Context, ch *channel.Channel, name, owner string, m *message.Incoming) {
+	from := m.Sender
+	if robo.ignored(ch, m) {
+		slog.DebugContext(ctx, "message from ignored user", slog.String("in", ch.Name))
 		return
 	}
-	// Run the rest in a worker so that we don't block the message loop.
-	work := func(ctx context.Context) {
-		m := message.FromTMI(msg)
-		from := m.Sender
-		if ch.Ignore[from] {
-			slog.DebugContext(ctx, "message from ignored user", slog.String("in", ch.Name))
-			return
+	if ch.Engagement != nil && mentions(name, m.Text) {
+		robo.engage(ctx, ch, ch.Engagement.Engage(m.Time()))
+	}
+	if !ch.NoCommands {
+		cmd, ok := parseCommand(name, m.Text)
+		if !ok && ch.Prefix != "" {
+			cmd, ok = parsePrefix(ch.Prefix, m.Text)
 		}
-		if cmd, ok := parseCommand(robo.tmi.name, m.Text); ok {
-			robo.command(ctx, ch, m, from, cmd)
+		if ok {
+			robo.command(ctx, ch, m, owner, from, cmd)
 			return
 		}
-		ch.History.Add(m.Time(), m.ID, m.Sender, m.Text)
-		// If the message is a reply to e.g. Bocchi, TMI adds @Bocchi to the
-		// start of the message text.
-		// That's helpful for commands, which we've already processed, but
-		// otherwise we probably don't want to see it. Remove it.
-		if _, ok := msg.Tag("reply-parent-msg-id"); ok && strings.HasPrefix(m.Text, "@") {
-			at, t, _ := strings.Cut(m.Text, " ")
-			slog.DebugContext(ctx, "stripped reply mention", slog.String("mention", at), slog.String("text", t))
-			m.Text = t
-		}
+	}
+	if robo.bots.directed(m.Text) {
+		slog.DebugContext(ctx, "message directed at another bot", slog.String("in", ch.Name))
+		robo.bots.dropped.Add(1)
+		return
+	}
+	ch.History.Add(m.Time(), m.ID, m.Sender, m.Text)
+	ch.Emotes.Observe(m.Time(), emoteNames(m)...)
+	if ch.Speakers != nil {
+		robo.addSpeaker(ctx, ch, m)
+	}
+	// If the message is a reply to e.g. Bocchi, TMI adds @Bocchi to the
+	// start of the message text.
+	// That's helpful for commands, which we've already processed, but
+	// otherwise we probably don't want to see it. Remove it.
+	if m.ReplyMention && strings.HasPrefix(m.Text, "@") {
+		at, t, _ := strings.Cut(m.Text, " ")
+		slog.DebugContext(ctx, "stripped reply mention", slog.String("mention", at), slog.String("text", t))
+		m.Text = t
+	}
+	if why := robo.dropReason(ch, m); why != "" {
+		slog.DebugContext(ctx, "dropped message under load", slog.String("in", ch.Name), slog.String("reason", why))
+		robo.drops.Add(why, 1)
+	} else {
 		robo.learn(ctx, ch, userhash.New(robo.secrets.userhash), m)
-		switch err := ch.Memery.Check(m.Time(), from, m.Text); err {
-		case channel.ErrNotCopypasta: // do nothing
-		case nil:
-			// Meme detected. Copypasta.
-			t := time.Now()
-			r := ch.Rate.ReserveN(t, 1)
-			if d := r.DelayFrom(t); d > 0 {
-				// But we can't meme it. Restore it so we can next time.
-				slog.InfoContext(ctx, "won't copypasta; rate limited",
-					slog.String("action", "copypasta"),
-					slog.String("in", ch.Name),
-					slog.String("delay", d.String()),
-				)
-				ch.Memery.Unblock(m.Text)
-				r.CancelAt(t)
-				return
-			}
-			text := m.Text
-			f := ch.Effects.Pick(rand.Uint32())
-			s := command.Effect(f, text)
-			ch.Memery.Block(m.Time(), s)
-			if ch.Block.MatchString(s) {
-				// Don't send things we wouldn't learn.
-				slog.InfoContext(ctx, "won't copypasta blocked message", slog.String("message", s), slog.String("effect", f))
-				r.CancelAt(t)
-				break
-			}
-			slog.InfoContext(ctx, "copypasta", slog.String("message", s), slog.String("effect", f))
-			msg := message.Format("", ch.Name, "%s", s)
-			robo.sendTMI(ctx, send, msg)
+	}
+	switch err := ch.Memery.Check(m.Time(), from, m.Text); err {
+	case channel.ErrNotCopypasta: // do nothing
+	case nil:
+		// Meme detected. Copypasta.
+		t := time.Now()
+		r, err := ch.Reserve(t)
+		if err != nil {
+			// But we can't meme it. Restore it so we can next time.
+			slog.InfoContext(ctx, "won't copypasta",
+				slog.String("action", "copypasta"),
+				slog.String("in", ch.Name),
+				slog.String("err", err.Error()),
+			)
+			ch.Memery.Unblock(m.Text)
 			return
-		default:
-			slog.ErrorContext(ctx, "failed copypasta check", slog.String("err", err.Error()), slog.Any("message", m))
-			// Continue on.
 		}
-		if rand.Float64() > ch.Responses {
-			return
+		text := m.Text
+		f := ch.Effects.Pick(rand.Uint32())
+		s := command.Effect(f, text)
+		ch.Memery.Block(m.Time(), s)
+		if ch.Block.MatchString(s) || !ch.Profanity.Sendable(s) {
+			// Don't send things we wouldn't learn.
+			slog.InfoContext(ctx, "won't copypasta blocked message", slog.String("message", s), slog.String("effect", f))
+			r.CancelAt(t)
+			break
 		}
-		start := time.Now()
-		s, trace, err := brain.Speak(ctx, robo.brain, ch.Send, "")
-		cost := time.Since(start)
-		if err != nil {
-			slog.ErrorContext(ctx, "wanted to speak but failed", slog.String("err", err.Error()))
-			return
+		slog.InfoContext(ctx, "copypasta", slog.String("message", s), slog.String("effect", f))
+		ch.Message(ctx, "", s)
+		return
+	default:
+		slog.ErrorContext(ctx, "failed copypasta check", slog.String("err", err.Error()), slog.Any("message", m))
+		// Continue on.
+	}
+	// Break a long silence if the personality calls for it.
+	p := &ch.Personality
+	lurk := p.Lurk > 0 && time.Since(time.UnixMilli(ch.Spoke.Load())) >= p.Lurk
+	if !lurk && rand.Float64() > ch.Responses {
+		return
+	}
+	var prompt string
+	if ch.Context > 0 && rand.Float64() < ch.ContextProb {
+		prompt = robo.contextPrompt(ctx, ch)
+	}
+	ch.Overlay.Thinking()
+	// Use messages generated ahead of time for unprompted responses when we
+	// have them.
+	speak := func(prompt string) (string, []string, error) {
+		if prompt == "" && robo.warm != nil {
+			if m, ok := robo.warm.Take(ch.Send, time.Now()); ok {
+				return m.Text, m.Trace, nil
+			}
 		}
-		if s == "" {
-			slog.InfoContext(ctx, "spoke nothing", slog.String("tag", ch.Send))
-			return
+		return brain.Speak(ctx, robo.brain, ch.Send, prompt)
+	}
+	start := time.Now()
+	s, trace, err := speak(prompt)
+	if err == nil && s == "" && prompt != "" {
+		// The prompt led nowhere. Fall back to an unprompted message.
+		slog.InfoContext(ctx, "context prompt spoke nothing", slog.String("tag", ch.Send), slog.String("prompt", prompt))
+		s, trace, err = speak("")
+	}
+	// Try a couple more times for a message of the preferred length and
+	// without too many emotes.
+	for i := 0; i < 2 && err == nil && s != "" && !(p.Fits(s) && p.Varied(s, ch.Emotes.Is)); i++ {
+		slog.DebugContext(ctx, "message not preferred", slog.String("in", ch.Name), slog.String("text", s))
+		u, tr, err := speak(prompt)
+		if err != nil || u == "" {
+			break
 		}
-		x := rand.Uint64()
-		e := ch.Emotes.Pick(uint32(x))
-		f := ch.Effects.Pick(uint32(x >> 32))
-		slog.InfoContext(ctx, "speak", slog.String("text", s), slog.String("emote", e), slog.String("effect", f))
-		se := strings.TrimSpace(s + " " + e)
-		sef := command.Effect(f, se)
-		if err := robo.spoken.Record(ctx, ch.Send, sef, trace, time.Now(), cost, s, e, f); err != nil {
-			slog.ErrorContext(ctx, "record trace failed", slog.Any("err", err))
-			return
+		s, trace = u, tr
+	}
+	cost := time.Since(start)
+	if errors.Is(err, fault.ErrBrainUnavailable) {
+		slog.DebugContext(ctx, "wanted to speak but brain is unavailable", slog.String("in", ch.Name))
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "wanted to speak but failed", slog.String("err", err.Error()))
+		return
+	}
+	if s == "" {
+		slog.InfoContext(ctx, "spoke nothing", slog.String("tag", ch.Send))
+		return
+	}
+	if !p.Varied(s, ch.Emotes.Is) {
+		slog.InfoContext(ctx, "won't speak; too many emotes or too repetitive", slog.String("in", ch.Name), slog.String("text", s))
+		return
+	}
+	gen := s
+	if ch.Speakers != nil && rand.Float64() < ch.Callouts {
+		if who := ch.Speakers.Pick(rand.Uint32()); who != "" {
+			slog.InfoContext(ctx, "callout", slog.String("in", ch.Name), slog.String("who", who))
+			s = "@" + who + " " + s
 		}
-		if ch.Block.MatchString(se) || ch.Block.MatchString(sef) {
-			slog.WarnContext(ctx, "wanted to send blocked message", slog.String("in", ch.Name), slog.String("text", sef))
-			return
+	}
+	s = p.Style(s, rand.Float64(), rand.Float64())
+	x := rand.Uint64()
+	var e, f string
+	if rand.Float64() < p.Emote {
+		e = ch.Emotes.Pick(uint32(x))
+	}
+	if rand.Float64() < p.Effect {
+		f = ch.Effects.Pick(uint32(x >> 32))
+	}
+	slog.InfoContext(ctx, "speak", slog.String("text", s), slog.String("emote", e), slog.String("effect", f))
+	se := strings.TrimSpace(s + " " + e)
+	sef := command.Effect(f, se)
+	if err := robo.spoken.Record(ctx, ch.Send, sef, trace, time.Now(), cost, s, e, f); err != nil {
+		slog.ErrorContext(ctx, "record trace failed", slog.Any("err", err))
+		return
+	}
+	ch.Counts.Generated.Add(1)
+	// Check both what we generated and what we'd send, since an effect can
+	// disguise words from the filters.
+	if ch.Block.MatchString(se) || ch.Block.MatchString(sef) || !ch.Profanity.Sendable(se) || !ch.Profanity.Sendable(sef) {
+		slog.WarnContext(ctx, "wanted to send blocked message", slog.String("in", ch.Name), slog.String("text", sef))
+		ch.Counts.Rejected.Add(1)
+		return
+	}
+	// Classify both what we generated and what we'd send, since an effect
+	// can disguise text from the classifier.
+	if !command.Classify(ctx, ch, se) || sef != se && !command.Classify(ctx, ch, sef) {
+		ch.Counts.Rejected.Add(1)
+		return
+	}
+	// Now that we've done all the work, which might take substantial time,
+	// check whether we can use it.
+	now := time.Now()
+	r, err := ch.Reserve(now)
+	if err != nil {
+		slog.InfoContext(ctx, "won't speak",
+			slog.String("action", "copypasta"),
+			slog.String("in", ch.Name),
+			slog.String("err", err.Error()),
+		)
+		return
+	}
+	if !ch.Outputs.Claim(ch.Name, gen, now) {
+		slog.InfoContext(ctx, "won't speak; another channel just sent the same message", slog.String("in", ch.Name), slog.String("text", gen))
+		r.CancelAt(now)
+		return
+	}
+	ch.Reply(ctx, m.Time(), "", sef)
+	command.Aloud(ctx, ch, s)
+	ch.Overlay.Message(sef)
+	if ch.Engagement != nil {
+		robo.engage(ctx, ch, ch.Engagement.Sent(time.Now()))
+	}
+}
+
+// ignored reports whether a message is from a user ignored in the channel or
+// everywhere, by user ID or login.
+func (robo *Robot) ignored(ch *channel.Channel, m *message.Incoming) bool {
+	login := senderLogin(m)
+	if ch.Ignore[m.Sender] || login != "" && ch.Ignore[login] {
+		return true
+	}
+	return robo.ignores != nil && robo.ignores.Has(m.Sender, login)
+}
+
+// senderLogin gets the login of a message's sender, or the empty string if
+// the platform identifies users only by ID.
+// Display names can change and can differ from logins, so they are never used.
+func senderLogin(m *message.Incoming) string {
+	if msg, ok := m.Raw.(*tmi.Message); ok {
+		return msg.Nick
+	}
+	return ""
+}
+
+// emoteNames gets the text of each emote in a message.
+func emoteNames(m *message.Incoming) []string {
+	var r []string
+	for _, e := range m.Emotes {
+		if 0 <= e.Start && e.Start < e.End && e.End <= len(m.Text) {
+			r = append(r, m.Text[e.Start:e.End])
 		}
-		// Now that we've done all the work, which might take substantial time,
-		// check whether we can use it.
-		t := time.Now()
-		r := ch.Rate.ReserveN(t, 1)
-		if d := r.DelayFrom(t); d > 0 {
-			slog.InfoContext(ctx, "won't speak; rate limited",
-				slog.String("action", "copypasta"),
-				slog.String("in", ch.Name),
-				slog.String("delay", d.String()),
-			)
-			r.CancelAt(t)
-			return
+	}
+	return r
+}
+
+// mentions reports whether a message addresses the bot by name or mentions it.
+func mentions(name, text string) bool {
+	if _, ok := parseCommand(name, text); ok {
+		return true
+	}
+	return name != "" && strings.Contains(strings.ToLower(text), "@"+strings.ToLower(name))
+}
+
+// engage records the outcomes of tracking engagement in a channel.
+func (robo *Robot) engage(ctx context.Context, ch *channel.Channel, outcomes []channel.Outcome) {
+	for _, o := range outcomes {
+		robo.sent.Add(ch.Name, 1)
+		if o.Engaged {
+			robo.engaged.Add(ch.Name, 1)
+		}
+		if err := robo.spoken.Engage(ctx, ch.Name, o.Time, o.Engaged); err != nil {
+			slog.ErrorContext(ctx, "couldn't record engagement", slog.Any("err", err), slog.String("in", ch.Name))
 		}
-		msg := message.Format("", ch.Name, "%s", sef)
-		robo.sendTMI(ctx, send, msg)
 	}
-	robo.enqueue(ctx, group, work)
 }
 
-func (robo *Robot) command(ctx context.Context, ch *channel.Channel, m *message.Received, from, cmd string) {
-	var c *twitchCommand
-	var args map[string]string
-	level := "any"
+func (robo *Robot) command(ctx context.Context, ch *channel.Channel, m *message.Incoming, owner, from, cmd string) {
+	level := command.Any
 	switch {
-	case from == robo.tmi.owner:
-		c, args = findTwitch(twitchOwner, cmd)
-		if c != nil {
-			level = "owner"
-			break
+	case owner != "" && from == owner:
+		level = command.Owner
+	case ch.Ops[from]:
+		level = command.Operator
+	case ch.Mod[from], m.IsModerator, ch.Roles.Mod(from), ch.VIPMod && ch.Roles.VIP(from):
+		level = command.Moderator
+	}
+	c, args := robo.commands.Find(level, cmd)
+	if c == nil {
+		return
+	}
+	lim, ok := ch.Limits[c.Name]
+	if lim.Off {
+		slog.DebugContext(ctx, "command disabled", slog.String("name", c.Name), slog.String("in", ch.Name))
+		return
+	}
+	cool, userCool := c.Cooldown, c.UserCooldown
+	if ok {
+		if lim.Cooldown > 0 {
+			cool = lim.Cooldown
 		}
-		fallthrough
-	case ch.Mod[from], m.IsModerator:
-		c, args = findTwitch(twitchMod, cmd)
-		if c != nil {
-			level = "mod"
-			break
+		if lim.UserCooldown > 0 {
+			userCool = lim.UserCooldown
 		}
-		fallthrough
-	default:
-		c, args = findTwitch(twitchAny, cmd)
 	}
-	if c == nil {
+	// Moderators and the owner aren't subject to cooldowns.
+	if level == command.Any && !robo.commands.UseWith(c, cool, userCool, ch.Name, from, time.Now()) {
+		slog.DebugContext(ctx, "command cooling down", slog.String("name", c.Name), slog.String("in", ch.Name))
 		return
 	}
-	slog.InfoContext(ctx, "command", slog.String("level", level), slog.String("name", c.name), slog.Any("args", args))
+	slog.InfoContext(ctx, "command", slog.String("level", c.Level.String()), slog.String("name", c.Name), slog.Any("args", args))
+	if c.Level >= command.Moderator {
+		robo.record(ctx, audit.Entry{
+			Time:    m.Time(),
+			Actor:   from,
+			Channel: ch.Name,
+			Action:  c.Name,
+			Params:  args,
+		})
+	}
 	r := command.Robot{
 		Log:      slog.Default(),
 		Channels: robo.channels,
 		Brain:    robo.brain,
 		Privacy:  robo.privacy,
+		Credit:   robo.credit,
 		Spoken:   robo.spoken,
+		Emotes:   robo.emotes,
+		Ignores:  robo.ignores,
+		Journal:  robo.journal,
+		Commands: robo.commands,
 	}
 	inv := command.Invocation{
 		Channel: ch,
 		Message: m,
 		Args:    args,
+		Level:   level,
 		Hasher:  userhash.New(robo.secrets.userhash),
 	}
-	c.fn(ctx, &r, &inv)
+	c.Fn(ctx, &r, &inv)
 }
 
-func (robo *Robot) enqueue(ctx context.Context, group *errgroup.Group, work func(context.Context)) {
-	var w chan func(context.Context)
-	// Get a worker if one exists. Otherwise, spawn a new one.
-	select {
-	case w = <-robo.works:
-	default:
-		w = make(chan func(context.Context), 1)
+// enqueue runs work for a channel in a worker. Works for the same channel, or
+// for channels which learn into the same tag, run in the order they are
+// enqueued, so that e.g. forgetting a message cannot race ahead of learning it.
+// If the work panics, the panic is recovered and logged, and if the channel
+// has panicked too often recently, it is halted and the owner is notified.
+// The work is never dropped to make room in the channel's queue, so it is for
+// moderation; chat uses [Robot.enqueueDroppable].
+func (robo *Robot) enqueue(ctx context.Context, group *errgroup.Group, ch *channel.Channel, work func(context.Context)) {
+	robo.submit(ctx, group, ch, work, robo.works.Submit)
+}
+
+// enqueueDroppable is like [Robot.enqueue], but the work is dropped if the
+// channel's queue fills before it runs.
+func (robo *Robot) enqueueDroppable(ctx context.Context, group *errgroup.Group, ch *channel.Channel, work func(context.Context)) {
+	robo.submit(ctx, group, ch, work, robo.works.SubmitDroppable)
+}
+
+func (robo *Robot) submit(ctx context.Context, group *errgroup.Group, ch *channel.Channel, work func(context.Context), submit func(string, func(context.Context)) func(context.Context)) {
+	robo.pending.Add(1)
+	g := robo.guard(ch, work)
+	work = func(ctx context.Context) {
+		defer robo.pending.Add(-1)
+		g(ctx)
+	}
+	if run := submit(seqKey(ch), work); run != nil {
 		group.Go(func() error {
-			worker(ctx, robo.works, w)
+			run(ctx)
 			return nil
 		})
 	}
-	// Send it work.
-	select {
-	case <-ctx.Done():
-		return
-	case w <- work:
+}
+
+// seqKey returns the key under which works for a channel are sequenced.
+// Channels which learn into the same tag share a key, because moderation in one
+// can forget messages learned from another.
+func seqKey(ch *channel.Channel) string {
+	if ch.Learn == "" {
+		return "chan:" + ch.Name
 	}
+	return "tag:" + ch.Learn
 }
 
-// worker runs works for a while. The provided context is passed to each work.
-func worker(ctx context.Context, works chan chan func(context.Context), ch chan func(context.Context)) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case work := <-ch:
-			work(ctx)
-			// Replace ourselves in the pool if it needs additional capacity.
-			// Otherwise, we're done.
-			select {
-			case works <- ch:
-			default:
+// guard wraps work for a channel to recover panics.
+func (robo *Robot) guard(ch *channel.Channel, work func(context.Context)) func(context.Context) {
+	return func(ctx context.Context) {
+		defer func() {
+			r := recover()
+			if r == nil {
 				return
 			}
-		}
+			slog.ErrorContext(ctx, "recovered panic",
+				slog.String("in", ch.Name),
+				slog.Any("panic", r),
+				slog.String("stack", string(debug.Stack())),
+			)
+			if ch.Panics == nil || !ch.Panics.Add(time.Now()) {
+				return
+			}
+			if ch.Halted.CompareAndSwap(false, true) {
+				robo.notifyOwner(ctx, fmt.Sprintf("Stopped handling messages in %s after repeated panics. Check the logs, then tell me to resume %[1]s.", ch.Name))
+			}
+		}()
+		work(ctx)
 	}
 }
 
 // learn learns a given message's text if it passes ch's filters.
-func (robo *Robot) learn(ctx context.Context, ch *channel.Channel, hasher userhash.Hasher, msg *message.Received) {
+func (robo *Robot) learn(ctx context.Context, ch *channel.Channel, hasher userhash.Hasher, msg *message.Incoming) {
 	if !ch.Enabled.Load() {
 		slog.DebugContext(ctx, "not learning in disabled channel", slog.String("in", ch.Name))
 		return
 	}
+	if ch.Suspended.Load() {
+		slog.DebugContext(ctx, "not learning under chat restrictions", slog.String("in", ch.Name))
+		return
+	}
+	if diskLevel(robo.disk.Load()) >= diskNoLearn {
+		slog.DebugContext(ctx, "not learning while low on disk space", slog.String("in", ch.Name))
+		return
+	}
 	switch err := robo.privacy.Check(ctx, msg.Sender); err {
 	case nil: // do nothing
 	case privacy.ErrPrivate:
@@ -230,35 +430,131 @@ func (robo *Robot) learn(ctx context.Context, ch *channel.Channel, hasher userha
 		slog.ErrorContext(ctx, "failed to check privacy", slog.String("err", err.Error()), slog.String("in", ch.Name))
 		return
 	}
+	ch.Counts.Seen.Add(1)
 	if ch.Block.MatchString(msg.Text) {
 		slog.DebugContext(ctx, "blocked message", slog.String("in", ch.Name), slog.String("text", msg.Text))
+		ch.Counts.Filtered.Add(1)
+		return
+	}
+	if !ch.Profanity.Learnable(msg.Text) {
+		slog.DebugContext(ctx, "message exceeds profanity limit", slog.String("in", ch.Name), slog.String("text", msg.Text))
+		ch.Counts.Filtered.Add(1)
 		return
 	}
 	if ch.Learn == "" {
 		slog.DebugContext(ctx, "no learn tag", slog.String("in", ch.Name))
 		return
 	}
+	if !ch.LearnLimit.Allow(msg.Sender, msg.Time()) {
+		slog.DebugContext(ctx, "user over learn limit", slog.String("in", ch.Name))
+		robo.drops.Add("user-limit", 1)
+		return
+	}
 	user := hasher.Hash(new(userhash.Hash), msg.Sender, msg.To, msg.Time())
-	if err := brain.Learn(ctx, robo.brain, ch.Learn, msg.ID, *user, msg.Time(), brain.Tokens(nil, msg.Text)); err != nil {
+	switch err := brain.Learn(ctx, robo.brain, ch.Learn, msg.ID, *user, msg.Time(), brain.Tokens(nil, msg.Text)); {
+	case err == nil:
+		ch.Learned.Add(ch.In(msg.Time()))
+		ch.Counts.Learned.Add(1)
+		if ch.Leaderboard {
+			if err := robo.credit.Add(ctx, ch.Name, msg.Sender, msg.Name); err != nil {
+				slog.ErrorContext(ctx, "failed to count message for leaderboard", slog.String("err", err.Error()), slog.String("in", ch.Name))
+			}
+		}
+	case errors.Is(err, fault.ErrBrainUnavailable):
+		slog.DebugContext(ctx, "not learning while brain is unavailable", slog.String("in", ch.Name))
+	default:
 		slog.ErrorContext(ctx, "failed to learn", slog.String("err", err.Error()))
 	}
 }
 
-// sendTMI sends a message to TMI after waiting for the global rate limit.
-// The caller should verify that it is safe to send the message.
-func (robo *Robot) sendTMI(ctx context.Context, send chan<- *tmi.Message, msg message.Sent) {
-	if err := robo.tmi.rate.Wait(ctx); err != nil {
+// addSpeaker records the sender of a message as a recent chatter in a channel
+// if they have opted in to credit and are not private. Callouts mention
+// speakers, so no one is listed without asking.
+func (robo *Robot) addSpeaker(ctx context.Context, ch *channel.Channel, msg *message.Incoming) {
+	switch err := robo.privacy.Check(ctx, msg.Sender); err {
+	case nil: // do nothing
+	case privacy.ErrPrivate:
+		// Make sure they aren't still listed from before they went private.
+		ch.Speakers.Remove(msg.Sender)
+		return
+	default:
+		slog.ErrorContext(ctx, "failed to check privacy", slog.String("err", err.Error()), slog.String("in", ch.Name))
+		return
+	}
+	ok, err := robo.credit.OptedIn(ctx, msg.Sender)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to check credit opt-in", slog.String("err", err.Error()), slog.String("in", ch.Name))
 		return
 	}
-	resp := message.ToTMI(msg)
-	select {
-	case <-ctx.Done():
+	if !ok {
+		// They may have opted out since they were listed.
+		ch.Speakers.Remove(msg.Sender)
 		return
-	case send <- resp:
 	}
+	ch.Speakers.Add(msg.Sender, msg.Name)
+}
+
+// contextPrompt chooses a prompt for a random response from the terms of
+// recent messages in a channel.
+// The result is the empty string if there are no suitable terms or if the
+// sender of the chosen term is private.
+func (robo *Robot) contextPrompt(ctx context.Context, ch *channel.Channel) string {
+	who, term := recentTerm(ch.History, ch.Context, rand.IntN)
+	if term == "" {
+		return ""
+	}
+	switch err := robo.privacy.Check(ctx, who); err {
+	case nil:
+		if ch.Forbidden.Match(term) {
+			slog.DebugContext(ctx, "context prompt is forbidden", slog.String("in", ch.Name))
+			return ""
+		}
+		return command.SanitizePrompt(term)
+	case privacy.ErrPrivate:
+		slog.DebugContext(ctx, "context prompt from private sender", slog.String("in", ch.Name))
+	default:
+		slog.ErrorContext(ctx, "failed to check privacy", slog.String("err", err.Error()), slog.String("in", ch.Name))
+	}
+	return ""
+}
+
+// recentTerm selects a term uniformly from the n most recent messages in h
+// using pick to choose an index, along with the sender of the message from
+// which it was taken.
+// Terms without any letters or numbers are never selected.
+func recentTerm(h *channel.History, n int, pick func(int) int) (who, term string) {
+	if h == nil || n <= 0 {
+		return "", ""
+	}
+	recent := make([]channel.HistoryMessage, 0, n)
+	for m := range h.All() {
+		if len(recent) == n {
+			recent = append(recent[:0], recent[1:]...)
+		}
+		recent = append(recent, m)
+	}
+	var terms, senders []string
+	for _, m := range recent {
+		for _, t := range brain.Tokens(nil, m.Text) {
+			if !strings.ContainsFunc(t, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }) {
+				continue
+			}
+			terms = append(terms, strings.TrimSpace(t))
+			senders = append(senders, m.Sender)
+		}
+	}
+	if len(terms) == 0 {
+		return "", ""
+	}
+	k := pick(len(terms))
+	return senders[k], terms[k]
 }
 
 func parseCommand(name, text string) (string, bool) {
+	if name == "" {
+		// The bot has no name on the platform, so nothing addresses it.
+		return "", false
+	}
 	text = strings.TrimSpace(text)
 	text, _ = strings.CutPrefix(text, "@")
 	// TODO(zeph): not quite right if our name contains one of those handful of
@@ -300,114 +596,265 @@ func parseCommand(name, text string) (string, bool) {
 	return "", false
 }
 
-type twitchCommand struct {
-	parse *regexp.Regexp
-	fn    command.Func
-	name  string
+// parsePrefix gets the command text from a message starting with a command
+// prefix.
+func parsePrefix(prefix, text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if len(text) < len(prefix) || !strings.EqualFold(text[:len(prefix)], prefix) {
+		return "", false
+	}
+	text = text[len(prefix):]
+	last, _ := utf8.DecodeLastRuneInString(prefix)
+	r, _ := utf8.DecodeRuneInString(text)
+	if (unicode.IsLetter(last) || unicode.IsNumber(last)) && (unicode.IsLetter(r) || unicode.IsNumber(r)) {
+		// The prefix is a prefix of a word.
+		return "", false
+	}
+	return strings.TrimSpace(text), true
 }
 
-func findTwitch(cmds []twitchCommand, text string) (*twitchCommand, map[string]string) {
-	for i := range cmds {
-		c := &cmds[i]
-		u := c.parse.FindStringSubmatch(text)
-		switch len(u) {
-		case 0:
-			continue
-		case 1:
-			return c, nil
-		default:
-			m := make(map[string]string, len(u)-1)
-			s := c.parse.SubexpNames()
-			for k, v := range u[1:] {
-				m[s[k+1]] = v
-			}
-			return c, m
+// builtinCommands creates the router for the bot's commands.
+func builtinCommands() *command.Router {
+	r := new(command.Router)
+	r.Register(
+		&command.Command{
+			Name:   "echo-in",
+			Parse:  regexp.MustCompile(`^(?i:in\s+(?<in>#\S+)[,:]?\s+echo)\s+(?<msg>.*)`),
+			ByName: true,
+			Args:   []string{"in", "msg"},
+			Usage:  "in <channel> echo <message> sends a message to another channel.",
+			Level:  command.Owner,
+			Fn:     command.EchoIn,
+		},
+		&command.Command{
+			Name:  "resume",
+			Parse: regexp.MustCompile(`^(?i:resume)\s+(?<in>#\S+)`),
+			Args:  []string{"in"},
+			Usage: "resume <channel> resumes a channel halted after errors.",
+			Level: command.Operator,
+			Fn:    command.Resume,
+		},
+		&command.Command{
+			Name:  "broadcast",
+			Parse: regexp.MustCompile(`^(?i:broadcast)(?:\s+(?i:to)\s+(?<to>\S+))?(?:\s+(?<msg>.*))?$`),
+			Args:  []string{"msg"},
+			Usage: "broadcast [to <pattern>] [message] sends a message, or something new, to every channel or those matching a pattern like #*.",
+			Level: command.Owner,
+			Fn:    command.Broadcast,
+		},
+		&command.Command{
+			Name:   "ignore-user",
+			Parse:  regexp.MustCompile(`^(?i:ignore\s+user)\s+(?<user>\S+)\s*$`),
+			ByName: true,
+			Args:   []string{"user"},
+			Usage:  "ignore user <user> ignores a user ID or login in every channel.",
+			Level:  command.Operator,
+			Fn:     command.IgnoreUser,
+		},
+		&command.Command{
+			Name:   "unignore-user",
+			Parse:  regexp.MustCompile(`^(?i:unignore\s+user)\s+(?<user>\S+)\s*$`),
+			ByName: true,
+			Args:   []string{"user"},
+			Usage:  "unignore user <user> stops ignoring a user added with ignore user.",
+			Level:  command.Operator,
+			Fn:     command.UnignoreUser,
+		},
+		&command.Command{
+			Name:  "echo",
+			Parse: regexp.MustCompile(`^(?i:echo)\s+(?<msg>.*)`),
+			Args:  []string{"msg"},
+			Usage: "echo <message> says a message.",
+			Level: command.Moderator,
+			Fn:    command.Echo,
+		},
+		&command.Command{
+			Name:  "describe-marriage",
+			Parse: regexp.MustCompile(`(?i)^(?:tell\s+me|talk)?\s*(?:about)?\s*(?:ranked)?\s*(?:competitive)?\s*marriage`),
+			Usage: "tell me about marriage explains how marriage works.",
+			Level: command.Moderator,
+			Fn:    command.DescribeMarriage,
+		},
+		&command.Command{
+			Name:    "forget",
+			Aliases: []string{"unlearn"},
+			Parse:   regexp.MustCompile(`(?i)^forgr?[eo]?r?t\s+(?:(?<everything>everything)$|(?<term>.+))`),
+			ByName:  true,
+			Args:    []string{"term"},
+			Usage:   "forget <term> forgets recent messages containing a term, or everything recent with forget everything.",
+			Level:   command.Moderator,
+			Fn:      command.Forget,
+		},
+		&command.Command{
+			Name:   "speak-as",
+			Parse:  regexp.MustCompile(`^(?i:as)\s+(?<tag>[^\s,:]+)[,:]?\s*(?i:say|generate)?\s*(?i:something)?\s*(?i:starting)?\s*(?i:with)?\s*(?<prompt>.*)`),
+			ByName: true,
+			Args:   []string{"tag", "prompt"},
+			Usage:  "as <tag> say <prompt> generates a message from another tag.",
+			Level:  command.Moderator,
+			Fn:     command.SpeakAs,
+		},
+		&command.Command{
+			Name:     "stats",
+			Parse:    regexp.MustCompile(`^(?i:stats)\s*$`),
+			Usage:    "stats shows how much I've learned and spoken here today.",
+			Level:    command.Moderator,
+			Cooldown: 30 * time.Second,
+			Fn:       command.Stats,
+		},
+		&command.Command{
+			Name:   "add-emote",
+			Parse:  regexp.MustCompile(`(?i)^emote\s+add\s+(?<emote>\S+)(?:\s+(?<weight>\S+))?\s*$`),
+			ByName: true,
+			Args:   []string{"emote", "weight"},
+			Usage:  "emote add <emote> [weight] adds an emote to use in this channel.",
+			Level:  command.Moderator,
+			Fn:     command.AddEmote,
+		},
+		&command.Command{
+			Name:   "remove-emote",
+			Parse:  regexp.MustCompile(`(?i)^emote\s+(?:remove|rm|delete|del)\s+(?<emote>\S+)\s*$`),
+			ByName: true,
+			Args:   []string{"emote"},
+			Usage:  "emote remove <emote> removes an emote added to this channel.",
+			Level:  command.Moderator,
+			Fn:     command.RemoveEmote,
+		},
+		&command.Command{
+			Name:    "help",
+			Aliases: []string{"commands"},
+			// Parse is set once every command is registered.
+			Args:  []string{"cmd"},
+			Usage: "help [command] lists the commands you can use or describes one.",
+			Level: command.Any,
+			// Help listings are long, so don't let chat spam them.
+			Cooldown: 30 * time.Second,
+			Fn:       command.Help,
+		},
+		&command.Command{
+			Name:         "private",
+			Parse:        regexp.MustCompile(`^(?i:give\s+me\s+privacy|ignore\s+me)`),
+			Usage:        "ignore me stops me from learning from your messages.",
+			Level:        command.Any,
+			UserCooldown: 10 * time.Second,
+			Fn:           command.Private,
+		},
+		&command.Command{
+			Name:         "unprivate",
+			Parse:        regexp.MustCompile(`(?i)^(?:you\s+(?:can|may)\s+)?learn\s+from\s+me(?:\s+again)?|invade\s+my\s+privacy`),
+			Usage:        "learn from me again undoes ignore me.",
+			Level:        command.Any,
+			UserCooldown: 10 * time.Second,
+			Fn:           command.Unprivate,
+		},
+		&command.Command{
+			Name:         "credit",
+			Parse:        regexp.MustCompile(`^(?i:credit)\s+(?<opt>(?i:on|off))\s*$`),
+			Args:         []string{"opt"},
+			Usage:        "credit on counts your messages I learn for the leaderboard and lets me mention you; credit off stops.",
+			Level:        command.Any,
+			UserCooldown: 10 * time.Second,
+			Fn:           command.Credit,
+		},
+		&command.Command{
+			Name:     "top",
+			Aliases:  []string{"leaderboard"},
+			Parse:    regexp.MustCompile(`^(?i:top|leaderboard)\s*$`),
+			Usage:    "top lists who has opted in with the most messages I've learned here.",
+			Level:    command.Any,
+			Cooldown: 30 * time.Second,
+			Fn:       command.Top,
+		},
+		&command.Command{
+			Name:     "describe-privacy",
+			Parse:    regexp.MustCompile(`(?i)^what\s+(?:info(?:rmation)?\s+)do\s+you\s+(?:collect|store)`),
+			Usage:    "what info do you collect tells you where to read about what I store.",
+			Level:    command.Any,
+			Cooldown: 30 * time.Second,
+			Fn:       command.DescribePrivacy,
+		},
+		&command.Command{
+			Name:         "marry",
+			Parse:        regexp.MustCompile(`(?i)^[¿¡]*\s*(?:ple?a?se?\s+)?(?:will\s+y?o?u\s+)?(?:\s*ple?a?se?\s+)?(?:marry\s+me|be?\s+my\s+(?<partnership>wife|waifu|h[ua]su?bando?|partner|spouse|daddy|mommy))`),
+			Usage:        "marry me asks me to marry you.",
+			Level:        command.Any,
+			UserCooldown: 10 * time.Second,
+			Fn:           command.Marry,
+		},
+		&command.Command{
+			Name:         "affection",
+			Parse:        regexp.MustCompile(`^how\s+much\s+do\s+you\s+(?:like|love|luv)\s+me`),
+			Usage:        "how much do you like me tells you your marriage score.",
+			Level:        command.Any,
+			UserCooldown: 10 * time.Second,
+			Fn:           command.Affection,
+		},
+		&command.Command{
+			Name:   "OwO",
+			Parse:  regexp.MustCompile(`^(?i:OwO|uwu)`),
+			Level:  command.Any,
+			Hidden: true,
+			Fn:     command.OwO,
+		},
+		&command.Command{
+			Name:   "AAAAA",
+			Parse:  regexp.MustCompile(`^(?i:how\s*[a']?re?\s+y?o?u?)|^A(?:A|\s)+$`),
+			Level:  command.Any,
+			Hidden: true,
+			Fn:     command.AAAAA,
+		},
+		&command.Command{
+			Name:   "rawr",
+			Parse:  regexp.MustCompile(`^(?i:r+o+a+r+|r+a+w+r+)`),
+			Level:  command.Any,
+			Hidden: true,
+			Fn:     command.Rawr,
+		},
+		&command.Command{
+			Name:     "source",
+			Parse:    regexp.MustCompile(`^(?i:where(?:'?s|\s+is)?\s+y?o?u'?re?\s+so?u?rce?(?:\s*code)?)`),
+			Usage:    "where is your source code links to my source code.",
+			Level:    command.Any,
+			Cooldown: 30 * time.Second,
+			Fn:       command.Source,
+		},
+		&command.Command{
+			Name:     "who",
+			Parse:    regexp.MustCompile(`(?i)^[¿¡]*\s*(?:who\s+a?re?\s+y?o?u|how\s+do\s+y?o?u\s+w[oe]?rk)`),
+			Usage:    "who are you tells you what I am.",
+			Level:    command.Any,
+			Cooldown: 30 * time.Second,
+			Fn:       command.Who,
+		},
+		&command.Command{
+			Name:  "speak",
+			Parse: regexp.MustCompile(`^(?i:say|generate|speak)\s*(?i:something)?\s*(?i:starting)?\s*(?i:with)?\s*(?<prompt>.*)`),
+			Args:  []string{"prompt"},
+			Usage: "say something starting with <prompt> generates a message, or just mention me.",
+			Level: command.Any,
+			Fn:    command.Speak,
+		},
+		&command.Command{
+			// NOTE(zeph): This command MUST be last, because it swallows all invocations.
+			// It is separate from speak so that channels can limit asking the
+			// bot to speak without limiting talking to it.
+			Name:   "reply",
+			Parse:  regexp.MustCompile(`^`),
+			Level:  command.Any,
+			Hidden: true,
+			Fn:     command.Speak,
+		},
+	)
+	// Only take help followed by a word as a command when the word names one.
+	// Otherwise "help me" and the like are just chat.
+	var names []string
+	for _, c := range r.Available(command.Owner) {
+		names = append(names, regexp.QuoteMeta(c.Name))
+		for _, a := range c.Aliases {
+			names = append(names, regexp.QuoteMeta(a))
 		}
 	}
-	return nil, nil
-}
-
-var twitchOwner = []twitchCommand{
-	{
-		parse: regexp.MustCompile(`^(?i:in\s+(?<in>#\S+)[,:]?\s+echo)\s+(?<msg>.*)`),
-		fn:    command.EchoIn,
-		name:  "echo-in",
-	},
-}
-
-var twitchMod = []twitchCommand{
-	{
-		parse: regexp.MustCompile(`^(?i:echo)\s+(?<msg>.*)`),
-		fn:    command.Echo,
-		name:  "echo",
-	},
-	{
-		parse: regexp.MustCompile(`(?i)^(?:tell\s+me|talk)?\s*(?:about)?\s*(?:ranked)?\s*(?:competitive)?\s*marriage`),
-		fn:    command.DescribeMarriage,
-		name:  "describe-marriage",
-	},
-	{
-		parse: regexp.MustCompile(`(?i)^forgr?[eo]?r?t\s+(?:everything$|(?<term>.+))`),
-		fn:    command.Forget,
-		name:  "forget",
-	},
-}
-
-var twitchAny = []twitchCommand{
-	{
-		parse: regexp.MustCompile(`^(?i:give\s+me\s+privacy|ignore\s+me)`),
-		fn:    command.Private,
-		name:  "private",
-	},
-	{
-		parse: regexp.MustCompile(`(?i)^(?:you\s+(?:can|may)\s+)?learn\s+from\s+me(?:\s+again)?|invade\s+my\s+privacy`),
-		fn:    command.Unprivate,
-		name:  "unprivate",
-	},
-	{
-		parse: regexp.MustCompile(`(?i)^what\s+(?:info(?:rmation)?\s+)do\s+you\s+(?:collect|store)`),
-		fn:    command.DescribePrivacy,
-		name:  "describe-privacy",
-	},
-	{
-		parse: regexp.MustCompile(`(?i)^[¿¡]*\s*(?:ple?a?se?\s+)?(?:will\s+y?o?u\s+)?(?:\s*ple?a?se?\s+)?(?:marry\s+me|be?\s+my\s+(?<partnership>wife|waifu|h[ua]su?bando?|partner|spouse|daddy|mommy))`),
-		fn:    command.Marry,
-		name:  "marry",
-	},
-	{
-		parse: regexp.MustCompile(`^how\s+much\s+do\s+you\s+(?:like|love|luv)\s+me`),
-		fn:    command.Affection,
-		name:  "affection",
-	},
-	{
-		parse: regexp.MustCompile(`^(?i:OwO|uwu)`),
-		fn:    command.OwO,
-		name:  "OwO",
-	},
-	{
-		parse: regexp.MustCompile(`^(?i:how\s*[a']?re?\s+y?o?u?)|^A(?:A|\s)+$`),
-		fn:    command.AAAAA,
-		name:  "AAAAA",
-	},
-	{
-		parse: regexp.MustCompile(`^(?i:r+o+a+r+|r+a+w+r+)`),
-		fn:    command.Rawr,
-		name:  "rawr",
-	},
-	{
-		parse: regexp.MustCompile(`^(?i:where(?:'?s|\s+is)?\s+y?o?u'?re?\s+so?u?rce?(?:\s*code)?)`),
-		fn:    command.Source,
-		name:  "source",
-	},
-	{
-		parse: regexp.MustCompile(`(?i)^[¿¡]*\s*(?:who\s+a?re?\s+y?o?u|how\s+do\s+y?o?u\s+w[oe]?rk)`),
-		fn:    command.Who,
-		name:  "who",
-	},
-	{
-		// NOTE(zeph): This command MUST be last, because it swallows all invocations.
-		parse: regexp.MustCompile(`^(?i:say|generate)\s*(?i:something)?\s*(?i:starting)?\s*(?i:with)?\s*(?<prompt>.*)|`),
-		fn:    command.Speak,
-		name:  "speak",
-	},
+	help := r.Lookup(command.Any, "help")
+	help.Parse = regexp.MustCompile(`^(?i:help|commands)(?:\s+(?<cmd>(?i:` + strings.Join(names, "|") + `)))?\s*$`)
+	return r
 }
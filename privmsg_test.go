@@ -1,6 +1,24 @@
 package main
 
-import "testing"
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gitlab.com/zephyrtronium/tmi"
+	"golang.org/x/sync/errgroup"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/zephyrtronium/robot/channel"
+	"github.com/zephyrtronium/robot/command"
+	"github.com/zephyrtronium/robot/credit"
+	"github.com/zephyrtronium/robot/message"
+	"github.com/zephyrtronium/robot/privacy"
+)
 
 func TestParseCommand(t *testing.T) {
 	cases := []struct {
@@ -22,6 +40,7 @@ func TestParseCommand(t *testing.T) {
 		{"text-after", "Bocchi", "Bocchi the Rock!", "the Rock!", true},
 		{"text-before", "Bocchi", "Hitori Bocchi", "Hitori", true},
 		{"middle", "Bocchi", "Hitori Bocchi Tokyo", "", false},
+		{"nameless", "", "Bocchi the Rock!", "", false},
 	}
 	for _, c := range cases {
 		c := c
@@ -36,3 +55,298 @@ func TestParseCommand(t *testing.T) {
 		})
 	}
 }
+
+func TestBuiltinCommandsProse(t *testing.T) {
+	r := builtinCommands()
+	cases := []struct {
+		text string
+		cmd  string
+	}{
+		{"who is that", "reply"},
+		{"help me", "reply"},
+		{"help me with my homework", "reply"},
+		{"source of truth", "reply"},
+		{"marry", "reply"},
+		{"echo", "reply"},
+		{"private thoughts about bocchi", "reply"},
+		{"stats are cool", "reply"},
+		{"credit anything", "reply"},
+		{"credit", "reply"},
+		{"top of the morning", "reply"},
+		{"leaderboard of shame", "reply"},
+		{"credit on", "credit"},
+		{"CREDIT OFF", "credit"},
+		{"top", "top"},
+		{"help", "help"},
+		{"help forget", "help"},
+		{"who are you", "who"},
+		{"echo bocchi", "echo"},
+		{"forget bocchi", "forget"},
+		{"unlearn bocchi", "forget"},
+		{"ignore-user bocchi", "ignore-user"},
+	}
+	for _, c := range cases {
+		t.Run(c.text, func(t *testing.T) {
+			cmd, _ := r.Find(command.Owner, c.text)
+			if cmd == nil {
+				t.Fatal("no command found")
+			}
+			if cmd.Name != c.cmd {
+				t.Errorf("wrong command: want %q, got %q", c.cmd, cmd.Name)
+			}
+		})
+	}
+}
+
+func TestParsePrefix(t *testing.T) {
+	cases := []struct {
+		name   string
+		prefix string
+		in     string
+		text   string
+		ok     bool
+	}{
+		{"empty", "!robot", "", "", false},
+		{"exact", "!robot", "!robot", "", true},
+		{"case", "!robot", "!ROBOT help", "help", true},
+		{"space", "!robot", "  !robot   help ", "help", true},
+		{"word", "!robot", "!robots help", "", false},
+		{"middle", "!robot", "hi !robot help", "", false},
+		{"symbol", "~", "~help", "help", true},
+		{"symbol-space", "~", "~ help", "help", true},
+		{"other", "~", "!help", "", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, ok := parsePrefix(c.prefix, c.in)
+			if got != c.text {
+				t.Errorf("wrong command text: want %q, got %q", c.text, got)
+			}
+			if ok != c.ok {
+				t.Errorf("wrong commandness: want %t, got %t", c.ok, ok)
+			}
+		})
+	}
+}
+
+func TestRecentTerm(t *testing.T) {
+	var h channel.History
+	now := time.Now()
+	h.Add(now, "1", "bocchi", "guitar hero")
+	h.Add(now, "2", "ryou", "...")
+	h.Add(now, "3", "nijika", "drums !")
+	cases := []struct {
+		name string
+		n    int
+		k    int
+		who  string
+		term string
+	}{
+		{"none", 0, 0, "", ""},
+		{"last", 1, 0, "nijika", "drums"},
+		{"skip-symbols", 2, 0, "nijika", "drums"},
+		{"first", 3, 0, "bocchi", "guitar"},
+		{"second", 3, 1, "bocchi", "hero"},
+		{"more", 10, 2, "nijika", "drums"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			who, term := recentTerm(&h, c.n, func(n int) int { return min(c.k, n-1) })
+			if who != c.who {
+				t.Errorf("wrong sender: want %q, got %q", c.who, who)
+			}
+			if term != c.term {
+				t.Errorf("wrong term: want %q, got %q", c.term, term)
+			}
+		})
+	}
+}
+
+func TestEnqueueOrder(t *testing.T) {
+	ctx := context.Background()
+	robo := New(4)
+	var group errgroup.Group
+	// Two channels sharing a learn tag must be sequenced together, e.g. so
+	// that a clear in one forgets what was learned in the other.
+	a := &channel.Channel{Name: "#bocchi", Learn: "kessoku"}
+	b := &channel.Channel{Name: "#ryou", Learn: "kessoku"}
+	var mu sync.Mutex
+	var got []int
+	for i := range 200 {
+		ch := a
+		if i%2 != 0 {
+			ch = b
+		}
+		robo.enqueue(ctx, &group, ch, func(ctx context.Context) {
+			mu.Lock()
+			got = append(got, i)
+			mu.Unlock()
+		})
+	}
+	group.Wait()
+	if len(got) != 200 {
+		t.Fatalf("wrong number of works: want 200, got %d", len(got))
+	}
+	for i, x := range got {
+		if x != i {
+			t.Fatalf("works out of order at %d: %v", i, got)
+		}
+	}
+	if n := robo.pending.Load(); n != 0 {
+		t.Errorf("works still pending: %d", n)
+	}
+}
+
+func TestEnqueueDropped(t *testing.T) {
+	ctx := context.Background()
+	robo := New(1)
+	robo.SetWorkQueue(2)
+	var group errgroup.Group
+	ch := &channel.Channel{Name: "#bocchi"}
+	block := make(chan struct{})
+	robo.enqueue(ctx, &group, ch, func(ctx context.Context) { <-block })
+	var got []int
+	for i := range 5 {
+		robo.enqueueDroppable(ctx, &group, ch, func(ctx context.Context) { got = append(got, i) })
+	}
+	// Works dropped on cancellation count too.
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	other := &channel.Channel{Name: "#ryou"}
+	for range 3 {
+		robo.enqueueDroppable(canceled, &group, other, func(ctx context.Context) { t.Error("canceled work ran") })
+	}
+	close(block)
+	group.Wait()
+	if len(got) != 2 || got[0] != 3 || got[1] != 4 {
+		t.Errorf("wrong works ran: want [3 4], got %v", got)
+	}
+	if n := robo.pending.Load(); n != 0 {
+		t.Errorf("works still pending: %d", n)
+	}
+	if n := robo.queueDrops.Get("chan:#bocchi").String(); n != "3" {
+		t.Errorf("wrong drops for full queue: want 3, got %s", n)
+	}
+	if n := robo.queueDrops.Get("chan:#ryou").String(); n != "3" {
+		t.Errorf("wrong drops for canceled queue: want 3, got %s", n)
+	}
+}
+
+func TestEnqueueForgetSurvives(t *testing.T) {
+	ctx := context.Background()
+	robo := New(1)
+	robo.SetWorkQueue(2)
+	var group errgroup.Group
+	ch := &channel.Channel{Name: "#bocchi"}
+	block := make(chan struct{})
+	robo.enqueue(ctx, &group, ch, func(ctx context.Context) { <-block })
+	var got []string
+	robo.enqueueDroppable(ctx, &group, ch, func(ctx context.Context) { got = append(got, "learn") })
+	robo.enqueue(ctx, &group, ch, func(ctx context.Context) { got = append(got, "forget") })
+	// Overflow the queue with chat.
+	for range 10 {
+		robo.enqueueDroppable(ctx, &group, ch, func(ctx context.Context) { got = append(got, "chat") })
+	}
+	close(block)
+	group.Wait()
+	want := []string{"forget", "chat"}
+	if !slices.Equal(got, want) {
+		t.Errorf("wrong works ran:\nwant %q\ngot  %q", want, got)
+	}
+	if n := robo.pending.Load(); n != 0 {
+		t.Errorf("works still pending: %d", n)
+	}
+}
+
+func TestMentions(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want bool
+	}{
+		{"none", "the rock is rolling", false},
+		{"addressed", "Bocchi, say something", true},
+		{"reply", "@Bocchi lol", true},
+		{"middle", "i agree with @bocchi here", true},
+		{"word", "bocchiposting", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := mentions("Bocchi", c.in); got != c.want {
+				t.Errorf("wrong mention for %q: want %t, got %t", c.in, c.want, got)
+			}
+		})
+	}
+}
+
+func TestClassifyPolicyTimeout(t *testing.T) {
+	cfg := ClassifyCfg{Thresholds: map[string]float64{"toxicity": 0.8}}
+	p, err := classifyPolicy(ClassifierCfg{URL: "http://localhost:8081/classify"}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Timeout != 10*time.Second {
+		t.Errorf("wrong default timeout: want 10s, got %v", p.Timeout)
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	cases := []struct {
+		name string
+		msg  string
+		want bool
+	}{
+		{"id", "@display-name=Someone;user-id=100135110 :someone!someone@someone.tmi.twitch.tv PRIVMSG #bocchi :hi", true},
+		{"login", "@display-name=Nightbot;user-id=19264788 :nightbot!nightbot@nightbot.tmi.twitch.tv PRIVMSG #bocchi :hi", true},
+		{"display", "@display-name=nightbot;user-id=1 :kita!kita@kita.tmi.twitch.tv PRIVMSG #bocchi :hi", false},
+		{"none", "@display-name=Kita;user-id=1 :kita!kita@kita.tmi.twitch.tv PRIVMSG #bocchi :hi", false},
+	}
+	robo := &Robot{}
+	ch := &channel.Channel{Ignore: map[string]bool{"100135110": true, "nightbot": true}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tm, err := tmi.Parse(strings.NewReader(c.msg + "\r\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := robo.ignored(ch, message.FromTMI(tm)); got != c.want {
+				t.Errorf("wrong ignored: want %t, got %t", c.want, got)
+			}
+		})
+	}
+}
+
+func TestAddSpeakerOptIn(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlitex.NewPool("file:TestAddSpeakerOptIn.db?mode=memory&cache=shared", sqlitex.PoolOptions{Flags: sqlite.OpenReadWrite | sqlite.OpenCreate | sqlite.OpenMemory | sqlite.OpenSharedCache | sqlite.OpenURI})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	robo := New(1)
+	if robo.privacy, err = privacy.Open(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	if robo.credit, err = credit.Open(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	ch := &channel.Channel{Name: "#bocchi", Speakers: channel.NewSpeakers(4)}
+	if err := robo.credit.OptIn(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+	robo.addSpeaker(ctx, ch, &message.Incoming{Sender: "1", Name: "nijika"})
+	robo.addSpeaker(ctx, ch, &message.Incoming{Sender: "2", Name: "ryo"})
+	for x := range uint32(16) {
+		if who := ch.Speakers.Pick(x << 28); who != "nijika" {
+			t.Errorf("wrong callout: want nijika, got %q", who)
+		}
+	}
+	// Opting out stops callouts as soon as they speak again.
+	if err := robo.credit.OptOut(ctx, "1"); err != nil {
+		t.Fatal(err)
+	}
+	robo.addSpeaker(ctx, ch, &message.Incoming{Sender: "1", Name: "nijika"})
+	if who := ch.Speakers.Pick(0); who != "" {
+		t.Errorf("called out opted-out chatter %q", who)
+	}
+}
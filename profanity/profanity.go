@@ -0,0 +1,170 @@
+// Package profanity rates messages by the severity of the profanity in them.
+package profanity
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Tier is a severity of profanity.
+type Tier int
+
+const (
+	// None is the tier of text without profanity.
+	None Tier = iota
+	// Mild is the tier of mild profanity.
+	Mild
+	// Strong is the tier of strong profanity.
+	Strong
+	// Never is the tier of slurs and other words which are never acceptable.
+	Never
+)
+
+// ParseTier parses the name of a tier.
+func ParseTier(s string) (Tier, error) {
+	switch strings.ToLower(s) {
+	case "none":
+		return None, nil
+	case "mild":
+		return Mild, nil
+	case "strong":
+		return Strong, nil
+	case "never":
+		return Never, nil
+	default:
+		return 0, fmt.Errorf("unknown profanity tier %q", s)
+	}
+}
+
+func (t Tier) String() string {
+	switch t {
+	case None:
+		return "none"
+	case Mild:
+		return "mild"
+	case Strong:
+		return "strong"
+	case Never:
+		return "never"
+	default:
+		return fmt.Sprintf("Tier(%d)", int(t))
+	}
+}
+
+// Lists is a set of word lists by tier.
+// A Lists must not be modified once it is in use.
+type Lists struct {
+	// words is the tier of each whole word.
+	words map[string]Tier
+	// prefixes is the tier of each word prefix.
+	prefixes map[string]Tier
+}
+
+var (
+	//go:embed mild.txt
+	mildSrc string
+	//go:embed strong.txt
+	strongSrc string
+	//go:embed never.txt
+	neverSrc string
+)
+
+// Default returns new lists containing the built-in words.
+func Default() *Lists {
+	l := New()
+	l.Add(Mild, lines(mildSrc)...)
+	l.Add(Strong, lines(strongSrc)...)
+	l.Add(Never, lines(neverSrc)...)
+	return l
+}
+
+// New returns empty lists.
+func New() *Lists {
+	return &Lists{
+		words:    make(map[string]Tier),
+		prefixes: make(map[string]Tier),
+	}
+}
+
+// Add adds words to a tier. A word ending in * matches any word beginning
+// with the rest of it. A word already in a higher tier stays there.
+func (l *Lists) Add(tier Tier, words ...string) {
+	for _, w := range words {
+		w = strings.ToLower(strings.TrimSpace(w))
+		m := l.words
+		if p, ok := strings.CutSuffix(w, "*"); ok {
+			w, m = p, l.prefixes
+		}
+		if w == "" {
+			continue
+		}
+		m[w] = max(m[w], tier)
+	}
+}
+
+// Rate returns the highest tier of any word in text.
+func (l *Lists) Rate(text string) Tier {
+	r := None
+	words := strings.FieldsFunc(strings.ToLower(text), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsNumber(c)
+	})
+	for _, w := range words {
+		r = max(r, l.words[w])
+		if len(l.prefixes) == 0 {
+			continue
+		}
+		for i := 1; i <= len(w); i++ {
+			r = max(r, l.prefixes[w[:i]])
+		}
+	}
+	return r
+}
+
+// lines gets the non-comment lines of a list source.
+func lines(src string) []string {
+	var r []string
+	for _, s := range strings.Split(src, "\n") {
+		s = strings.TrimSpace(s)
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		r = append(r, s)
+	}
+	return r
+}
+
+// Policy is the maximum tiers of profanity allowed in a channel.
+type Policy struct {
+	// Lists is the word lists used to rate messages.
+	Lists *Lists
+	// Learn is the highest tier of profanity allowed in learned messages.
+	Learn Tier
+	// Send is the highest tier of profanity allowed in sent messages.
+	Send Tier
+}
+
+// Learnable reports whether a message may be learned.
+// A nil policy allows everything.
+func (p *Policy) Learnable(text string) bool {
+	if p == nil {
+		return true
+	}
+	return allowed(p.Lists.Rate(text), p.Learn)
+}
+
+// Sendable reports whether a message may be sent.
+// A nil policy allows everything.
+func (p *Policy) Sendable(text string) bool {
+	if p == nil {
+		return true
+	}
+	return allowed(p.Lists.Rate(text), p.Send)
+}
+
+// allowed reports whether a rating is within the limit.
+// Words in the never tier are not allowed regardless of the limit.
+func allowed(rate, limit Tier) bool {
+	return rate < Never && rate <= limit
+}
@@ -0,0 +1,95 @@
+package profanity_test
+
+import (
+	"testing"
+
+	"github.com/zephyrtronium/robot/profanity"
+)
+
+func TestRate(t *testing.T) {
+	l := profanity.New()
+	l.Add(profanity.Mild, "heck", "darn*")
+	l.Add(profanity.Strong, "bocchi")
+	l.Add(profanity.Never, "kita*", "heck")
+	cases := []struct {
+		name string
+		text string
+		want profanity.Tier
+	}{
+		{"empty", "", profanity.None},
+		{"clean", "the rock", profanity.None},
+		{"mild", "darn it", profanity.Mild},
+		{"prefix", "darnation", profanity.Mild},
+		{"case", "DARN", profanity.Mild},
+		{"strong", "bocchi the rock", profanity.Strong},
+		{"substring", "bocchis", profanity.None},
+		{"punct", "bocchi!", profanity.Strong},
+		{"highest", "darn bocchi", profanity.Strong},
+		{"never", "kitaaan", profanity.Never},
+		{"promoted", "heck", profanity.Never},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := l.Rate(c.text); got != c.want {
+				t.Errorf("wrong rating for %q: want %v, got %v", c.text, c.want, got)
+			}
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	l := profanity.Default()
+	if got := l.Rate("bocchi the rock"); got != profanity.None {
+		t.Errorf("clean text rated %v", got)
+	}
+	if got := l.Rate("well damn"); got != profanity.Mild {
+		t.Errorf("mild text rated %v", got)
+	}
+	if got := l.Rate("what the fuck"); got != profanity.Strong {
+		t.Errorf("strong text rated %v", got)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	l := profanity.New()
+	l.Add(profanity.Mild, "darn")
+	l.Add(profanity.Strong, "bocchi")
+	l.Add(profanity.Never, "kita")
+	p := &profanity.Policy{Lists: l, Learn: profanity.Mild, Send: profanity.Never}
+	cases := []struct {
+		text        string
+		learn, send bool
+	}{
+		{"the rock", true, true},
+		{"darn", true, true},
+		{"bocchi", false, true},
+		{"kita", false, false},
+	}
+	for _, c := range cases {
+		if got := p.Learnable(c.text); got != c.learn {
+			t.Errorf("wrong learnable for %q: want %t, got %t", c.text, c.learn, got)
+		}
+		if got := p.Sendable(c.text); got != c.send {
+			t.Errorf("wrong sendable for %q: want %t, got %t", c.text, c.send, got)
+		}
+	}
+	var nilp *profanity.Policy
+	if !nilp.Learnable("kita") || !nilp.Sendable("kita") {
+		t.Errorf("nil policy blocked")
+	}
+}
+
+func TestParseTier(t *testing.T) {
+	for _, tier := range []profanity.Tier{profanity.None, profanity.Mild, profanity.Strong, profanity.Never} {
+		got, err := profanity.ParseTier(tier.String())
+		if err != nil {
+			t.Errorf("couldn't parse %v: %v", tier, err)
+		}
+		if got != tier {
+			t.Errorf("wrong tier: want %v, got %v", tier, got)
+		}
+	}
+	if _, err := profanity.ParseTier("bocchi"); err == nil {
+		t.Errorf("no error for unknown tier")
+	}
+}
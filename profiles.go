@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// profiles are the named sets of defaults from which channels may start.
+// Each gives the options new channels most often tune.
+var profiles = map[string]*ChannelCfg{
+	// conservative speaks rarely, copies only clear copypasta, and keeps its
+	// language clean.
+	"conservative": {
+		Responses: 0.005,
+		Rate:      Rate{Every: 30, Num: 1},
+		Copypasta: Copypasta{Need: 4, Within: 20},
+		Profanity: ProfanityLimits{Learn: "mild", Send: "none"},
+		Effects:   map[string]int{"": 1},
+	},
+	// standard is what most channels want.
+	"standard": {
+		Responses: 0.02,
+		Rate:      Rate{Every: 10, Num: 2},
+		Copypasta: Copypasta{Need: 2, Within: 30},
+		Profanity: ProfanityLimits{Learn: "strong", Send: "mild"},
+		Effects:   map[string]int{"": 18, "OwO": 1, "o": 1},
+	},
+	// chaotic speaks often, joins in on everything, and says what chat says.
+	"chaotic": {
+		Responses: 0.1,
+		Rate:      Rate{Every: 5, Num: 4},
+		Copypasta: Copypasta{Need: 2, Within: 60},
+		Profanity: ProfanityLimits{Learn: "strong", Send: "strong"},
+		Effects:   map[string]int{"": 6, "OwO": 2, "AAAAA": 1, "o": 2},
+	},
+}
+
+// applyProfile fills the options of a channel from its profile.
+// defined reports whether the channel config sets an option by its TOML key;
+// options it sets override the profile's.
+func applyProfile(ch *ChannelCfg, defined func(key ...string) bool) error {
+	if ch.Profile == "" {
+		return nil
+	}
+	p := profiles[ch.Profile]
+	if p == nil {
+		return fmt.Errorf("unknown profile %q; want one of %s", ch.Profile, strings.Join(slices.Sorted(maps.Keys(profiles)), ", "))
+	}
+	unset := func(key ...string) bool { return !defined(key...) }
+	if unset("responses") {
+		ch.Responses = p.Responses
+	}
+	if unset("rate", "every") {
+		ch.Rate.Every = p.Rate.Every
+	}
+	if unset("rate", "num") {
+		ch.Rate.Num = p.Rate.Num
+	}
+	if unset("copypasta", "need") {
+		ch.Copypasta.Need = p.Copypasta.Need
+	}
+	if unset("copypasta", "within") {
+		ch.Copypasta.Within = p.Copypasta.Within
+	}
+	if unset("profanity", "learn") {
+		ch.Profanity.Learn = p.Profanity.Learn
+	}
+	if unset("profanity", "send") {
+		ch.Profanity.Send = p.Profanity.Send
+	}
+	if unset("effects") {
+		ch.Effects = maps.Clone(p.Effects)
+	}
+	return nil
+}
+
+// applyProfiles fills the options of every channel in a config from their
+// profiles. defined reports whether the config sets an option by its full
+// TOML key.
+func applyProfiles(cfg *Config, defined func(key ...string) bool) error {
+	var errs []error
+	for _, g := range channelGroups(cfg) {
+		for _, nm := range slices.Sorted(maps.Keys(g.channels)) {
+			prefix := append(strings.Split(g.key, "."), nm)
+			d := func(key ...string) bool {
+				return defined(append(prefix[:len(prefix):len(prefix)], key...)...)
+			}
+			if err := applyProfile(g.channels[nm], d); err != nil {
+				errs = append(errs, fmt.Errorf("%s.%s: %w", g.key, nm, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
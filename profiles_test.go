@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestProfiles(t *testing.T) {
+	src := `
+[twitch.bocchi]
+channels = ['#bocchi']
+profile = 'chaotic'
+responses = 0
+rate = { num = 1 }
+effects = { '' = 1 }
+
+[slack.channels.kessoku]
+channels = ['C0123456789']
+profile = 'conservative'
+
+[kick.channels.ryo]
+channels = ['kick:ryo']
+`
+	cfg, _, err := Load(context.Background(), strings.NewReader(src), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := cfg.Twitch["bocchi"]
+	if b.Responses != 0 || b.Rate.Num != 1 || len(b.Effects) != 1 {
+		t.Errorf("profile replaced options set in the channel: %+v", b)
+	}
+	if b.Rate.Every != 5 || b.Copypasta != (Copypasta{Need: 2, Within: 60}) || b.Profanity.Send != "strong" {
+		t.Errorf("profile didn't fill options: %+v", b)
+	}
+	k := cfg.Slack.Channels["kessoku"]
+	if k.Responses != 0.005 || k.Profanity.Send != "none" || k.Effects[""] != 1 {
+		t.Errorf("wrong conservative channel: %+v", k)
+	}
+	// Filling a channel must not share the profile's effects.
+	k.Effects["OwO"] = 1
+	if _, ok := profiles["conservative"].Effects["OwO"]; ok {
+		t.Error("changing a channel's effects changed the profile")
+	}
+	if r := cfg.Kick.Channels["ryo"]; r.Responses != 0 || r.Effects != nil {
+		t.Errorf("channel without profile got defaults: %+v", r)
+	}
+}
+
+func TestProfilesUnknown(t *testing.T) {
+	src := `
+[twitch.bocchi]
+channels = ['#bocchi']
+profile = 'bocchi'
+`
+	_, _, err := Load(context.Background(), strings.NewReader(src), true)
+	if err == nil || !strings.Contains(err.Error(), "twitch.bocchi") || !strings.Contains(err.Error(), "chaotic, conservative, standard") {
+		t.Errorf("wrong error for unknown profile: %v", err)
+	}
+}
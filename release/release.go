@@ -0,0 +1,249 @@
+// Package release finds published releases of the bot and installs them.
+package release
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// LatestURL is the GitHub API endpoint describing the latest release.
+const LatestURL = "https://api.github.com/repos/zephyrtronium/robot/releases/latest"
+
+// ChecksumFile is the name of the release asset listing SHA-256 checksums of
+// the other assets, in the format of sha256sum.
+const ChecksumFile = "checksums.txt"
+
+// Release is a published release.
+type Release struct {
+	// Tag is the release's version tag, e.g. v1.2.3.
+	Tag string `json:"tag_name"`
+	// URL is the release's web page.
+	URL string `json:"html_url"`
+	// Assets is the files attached to the release.
+	Assets []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Latest gets the latest release from a GitHub releases API URL.
+func Latest(ctx context.Context, client *http.Client, url string) (*Release, error) {
+	b, err := get(ctx, client, url, "application/vnd.github+json", 1<<20)
+	if err != nil {
+		return nil, err
+	}
+	var r Release
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("couldn't decode release: %w", err)
+	}
+	if r.Tag == "" {
+		return nil, errors.New("release has no tag")
+	}
+	return &r, nil
+}
+
+// Asset finds an asset by name.
+func (r *Release) Asset(name string) (Asset, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// BinaryName is the name of the release asset holding the binary for the
+// current platform.
+func BinaryName() string {
+	s := "robot_" + runtime.GOOS + "_" + runtime.GOARCH
+	if runtime.GOOS == "windows" {
+		s += ".exe"
+	}
+	return s
+}
+
+// Newer reports whether version a is newer than version b.
+// Versions are of the form v1.2.3 with an optional pre-release suffix, which
+// sorts before the same version without one. Versions which aren't of that
+// form are never newer, and any such version is older than those which are.
+func Newer(a, b string) bool {
+	x, ok := parse(a)
+	if !ok {
+		return false
+	}
+	y, ok := parse(b)
+	if !ok {
+		return true
+	}
+	for i := range 3 {
+		if x.n[i] != y.n[i] {
+			return x.n[i] > y.n[i]
+		}
+	}
+	switch {
+	case x.pre == y.pre:
+		return false
+	case x.pre == "":
+		return true
+	case y.pre == "":
+		return false
+	default:
+		return x.pre > y.pre
+	}
+}
+
+type version struct {
+	n   [3]int
+	pre string
+}
+
+func parse(s string) (version, bool) {
+	var v version
+	s, ok := strings.CutPrefix(s, "v")
+	if !ok {
+		return v, false
+	}
+	s, _, _ = strings.Cut(s, "+")
+	s, v.pre, _ = strings.Cut(s, "-")
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return v, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, false
+		}
+		v.n[i] = n
+	}
+	return v, true
+}
+
+// Install downloads the release's binary for the current platform, verifies
+// it against the release's checksums, and atomically replaces the file at exe
+// with it.
+func Install(ctx context.Context, client *http.Client, r *Release, exe string) error {
+	name := BinaryName()
+	bin, ok := r.Asset(name)
+	if !ok {
+		return fmt.Errorf("release %s has no binary %s", r.Tag, name)
+	}
+	sums, ok := r.Asset(ChecksumFile)
+	if !ok {
+		return fmt.Errorf("release %s has no checksums", r.Tag)
+	}
+	b, err := get(ctx, client, sums.URL, "", 1<<20)
+	if err != nil {
+		return fmt.Errorf("couldn't get checksums: %w", err)
+	}
+	want, err := checksum(b, name)
+	if err != nil {
+		return err
+	}
+	data, err := get(ctx, client, bin.URL, "", 1<<30)
+	if err != nil {
+		return fmt.Errorf("couldn't download binary: %w", err)
+	}
+	got := sha256.Sum256(data)
+	if !bytes.Equal(got[:], want) {
+		return fmt.Errorf("checksum mismatch for %s: want %x, got %x", name, want, got)
+	}
+	return replace(exe, data)
+}
+
+// checksum finds the checksum of an asset in a sha256sum listing.
+func checksum(sums []byte, name string) ([]byte, error) {
+	sc := bufio.NewScanner(bytes.NewReader(sums))
+	for sc.Scan() {
+		sum, file, ok := strings.Cut(sc.Text(), " ")
+		if !ok || strings.TrimLeft(strings.TrimSpace(file), "*") != name {
+			continue
+		}
+		b, err := hex.DecodeString(sum)
+		if err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("bad checksum for %s", name)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("no checksum for %s", name)
+}
+
+// replace atomically replaces the file at exe with data.
+func replace(exe string, data []byte) error {
+	dir := filepath.Dir(exe)
+	f, err := os.CreateTemp(dir, ".robot-upgrade-*")
+	if err != nil {
+		return fmt.Errorf("couldn't create temporary file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("couldn't write new binary: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("couldn't write new binary: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("couldn't write new binary: %w", err)
+	}
+	if err := os.Chmod(f.Name(), 0o755); err != nil {
+		return fmt.Errorf("couldn't make new binary executable: %w", err)
+	}
+	if runtime.GOOS == "windows" {
+		// Windows won't replace a running executable, but it will rename one.
+		old := exe + ".old"
+		os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return fmt.Errorf("couldn't move old binary aside: %w", err)
+		}
+	}
+	if err := os.Rename(f.Name(), exe); err != nil {
+		return fmt.Errorf("couldn't replace binary: %w", err)
+	}
+	return nil
+}
+
+// get performs a GET request and reads up to lim bytes of the response.
+// If accept is not empty, it is the request's Accept header.
+func get(ctx context.Context, client *http.Client, url, accept string, lim int64) ([]byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't make request: %w", err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't GET %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, lim))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read response: %w", err)
+	}
+	return b, nil
+}
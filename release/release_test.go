@@ -0,0 +1,106 @@
+package release_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zephyrtronium/robot/release"
+)
+
+func TestNewer(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want bool
+	}{
+		{"v1.2.3", "v1.2.3", false},
+		{"v1.2.4", "v1.2.3", true},
+		{"v1.2.3", "v1.2.4", false},
+		{"v1.10.0", "v1.9.9", true},
+		{"v2.0.0", "v1.99.99", true},
+		{"v1.2.3", "v1.2.3-rc1", true},
+		{"v1.2.3-rc1", "v1.2.3", false},
+		{"v1.2.3-rc2", "v1.2.3-rc1", true},
+		{"v1.2.3", "(devel)", true},
+		{"(devel)", "v1.2.3", false},
+		{"v1.2", "v1.0.0", false},
+	}
+	for _, c := range cases {
+		if got := release.Newer(c.a, c.b); got != c.want {
+			t.Errorf("Newer(%q, %q): want %t, got %t", c.a, c.b, c.want, got)
+		}
+	}
+}
+
+func server(t *testing.T, bin []byte, sum [sha256.Size]byte) *httptest.Server {
+	t.Helper()
+	name := release.BinaryName()
+	mux := http.NewServeMux()
+	var srv *httptest.Server
+	mux.HandleFunc("/latest", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"tag_name":"v9.9.9","assets":[{"name":%q,"browser_download_url":%q},{"name":"checksums.txt","browser_download_url":%q}]}`,
+			name, srv.URL+"/bin", srv.URL+"/sums")
+	})
+	mux.HandleFunc("/bin", func(w http.ResponseWriter, r *http.Request) { w.Write(bin) })
+	mux.HandleFunc("/sums", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%x  other\n%x  %s\n", sha256.Sum256(nil), sum, name)
+	})
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestInstall(t *testing.T) {
+	ctx := context.Background()
+	bin := []byte("kessoku band")
+	srv := server(t, bin, sha256.Sum256(bin))
+	r, err := release.Latest(ctx, srv.Client(), srv.URL+"/latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Tag != "v9.9.9" {
+		t.Errorf("wrong tag: %q", r.Tag)
+	}
+	exe := filepath.Join(t.TempDir(), "robot")
+	if err := os.WriteFile(exe, []byte("old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := release.Install(ctx, srv.Client(), r, exe); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(bin) {
+		t.Errorf("wrong binary installed: %q", got)
+	}
+}
+
+func TestInstallBadChecksum(t *testing.T) {
+	ctx := context.Background()
+	srv := server(t, []byte("kessoku band"), sha256.Sum256([]byte("sick hack")))
+	r, err := release.Latest(ctx, srv.Client(), srv.URL+"/latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exe := filepath.Join(t.TempDir(), "robot")
+	if err := os.WriteFile(exe, []byte("old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := release.Install(ctx, srv.Client(), r, exe); err == nil {
+		t.Error("install with bad checksum succeeded")
+	}
+	got, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "old" {
+		t.Errorf("binary replaced despite bad checksum: %q", got)
+	}
+}
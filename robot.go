@@ -2,49 +2,169 @@ package main
 
 import (
 	"context"
-	"crypto/tls"
-	"errors"
+	"crypto/sha256"
+	"expvar"
 	"fmt"
-	"log/slog"
-	"strings"
+	"sync/atomic"
 	"time"
 
-	"gitlab.com/zephyrtronium/tmi"
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/time/rate"
 
+	"github.com/zephyrtronium/robot/apikey"
+	"github.com/zephyrtronium/robot/audit"
 	"github.com/zephyrtronium/robot/auth"
 	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/tier"
+	"github.com/zephyrtronium/robot/brain/warm"
 	"github.com/zephyrtronium/robot/channel"
+	"github.com/zephyrtronium/robot/command"
+	"github.com/zephyrtronium/robot/credit"
+	"github.com/zephyrtronium/robot/emotes"
+	"github.com/zephyrtronium/robot/ignore"
+	"github.com/zephyrtronium/robot/jobs"
+	"github.com/zephyrtronium/robot/journal"
+	"github.com/zephyrtronium/robot/platform"
 	"github.com/zephyrtronium/robot/privacy"
+	"github.com/zephyrtronium/robot/serial"
 	"github.com/zephyrtronium/robot/spoken"
 	"github.com/zephyrtronium/robot/syncmap"
-	"github.com/zephyrtronium/robot/twitch"
 )
 
 // Robot is the overall configuration for the bot.
 type Robot struct {
 	// brain is the brain.
 	brain brain.Brain
+	// warm is the brain's pools of messages generated ahead of time for
+	// random responses. It is nil if they are disabled.
+	warm *warm.Brain
+	// tier is the brain's memory of recent messages in front of the brain
+	// database. It is nil if it is disabled.
+	tier *tier.Brain
 	// privacy is the privacy.
 	privacy *privacy.List
+	// credit is the leaderboard of users who opted in to being counted.
+	credit *credit.Board
 	// spoken is the history of generated messages.
 	spoken *spoken.History
+	// emotes is the store of emotes added to channels at runtime.
+	emotes *emotes.Store
+	// emoteServices is the client for third-party emote services.
+	emoteServices emotes.Services
+	// audit is the log of privileged actions.
+	audit *audit.Log
+	// journal is the journal of destructive operations on knowledge.
+	journal *journal.Journal
+	// ignores is the list of users ignored in all channels at runtime.
+	ignores *ignore.Store
+	// bots recognizes messages directed at other bots. It is nil if no
+	// messages are recognized.
+	bots *otherBots
 	// channels are the channels.
 	channels *syncmap.Map[string, *channel.Channel]
-	// works is the worker queue.
-	works chan chan func(context.Context)
+	// commands is the router for chat commands.
+	commands *command.Router
+	// works is the worker pool.
+	works *serial.Pool
 	// secrets are the bot's keys.
 	secrets *keys
 	// owner is the username of the owner.
 	owner string
 	// ownerContact describes contact information for the owner.
 	ownerContact string
-	// tmi contains the bot's Twitch OAuth2 settings. It may be nil if there is
-	// no Twitch configuration.
-	tmi *client[*tmi.Message, *tmi.Message]
-	// twitch is the Twitch API client.
-	twitch twitch.Client
+	// notify is the destination for owner notifications.
+	notify string
+	// platforms are the chat platforms to which the bot connects.
+	platforms []platform.Client
+	// twitch is the bot's Twitch connection. It may be nil if there is no
+	// Twitch configuration.
+	twitch *twitchClient
+	// matrix is the bot's Matrix connection. It may be nil if there is no
+	// Matrix configuration.
+	matrix *matrixClient
+	// telegram is the bot's Telegram connection. It may be nil if there is no
+	// Telegram configuration.
+	telegram *telegramClient
+	// kick is the bot's Kick connection. It may be nil if there is no Kick
+	// configuration.
+	kick *kickClient
+	// slack is the bot's Slack connection. It may be nil if there is no Slack
+	// configuration.
+	slack *slackClient
+	// discord is the bot's Discord connection. It may be nil if there is no
+	// Discord configuration.
+	discord *discordClient
+	// logs is the bot's chat log follower. It may be nil if there is no logs
+	// configuration.
+	logs *logsClient
+	// bridges are the links relaying chat between channels, keyed by the
+	// channel from which they relay.
+	bridges map[string][]bridgeLink
+	// posters are the social media posting jobs by name.
+	posters map[string]*posterJob
+	// listen is the address on which to serve HTTP, if any.
+	listen string
+	// config is the config with which the robot is running.
+	config *Config
+	// configFile is the file from which config was loaded, or empty if it
+	// came from the environment.
+	configFile string
+	// apiTokens maps the SHA-256 hashes of HTTP API tokens to the roles they
+	// grant. It is nil if no tokens are configured.
+	apiTokens map[[sha256.Size]byte]command.Level
+	// apikeys is the store of managed HTTP API keys.
+	apikeys *apikey.Store
+	// keyLimits is the rate limiter for each API key by ID.
+	keyLimits *syncmap.Map[string, *rate.Limiter]
+	// metrics is the robot's published variables.
+	metrics *expvar.Map
+	// pending is the number of works enqueued and not yet finished.
+	pending atomic.Int64
+	// backlog is the number of pending works beyond which low-value messages
+	// are not learned. If it is zero, everything is learned.
+	backlog int64
+	// short is the number of tokens below which a message is low-value.
+	short int
+	// drops counts messages not learned due to backlog by reason.
+	drops *expvar.Map
+	// queueDrops counts works dropped without running by sequencing key.
+	queueDrops *expvar.Map
+	// sent and engaged count random messages with tracked engagement and
+	// those with responses by channel.
+	sent, engaged *expvar.Map
+	// latency is the reply latency of each channel.
+	latency *expvar.Map
+	// quotas is the storage limit for each learn tag which has one.
+	quotas map[string]brain.Quota
+	// preloads is the tags to read into memory at startup, mapped to the
+	// number of most frequent prefixes to read, or zero for the whole tag.
+	preloads map[string]int
+	// sweepEvery is the interval at which quotas are enforced and forgotten
+	// knowledge is purged.
+	sweepEvery time.Duration
+	// undeleteWindow is how long forgotten knowledge can be restored before
+	// it is purged. If it is not positive, it is never purged.
+	undeleteWindow time.Duration
+	// diskWatch is the configuration for watching free disk space.
+	// It is nil if disk space is not watched.
+	diskWatch *diskWatch
+	// alerts tracks channel rates for alerts. It is nil if no alerts are
+	// configured.
+	alerts *alertWatch
+	// disk is the current diskLevel.
+	disk atomic.Int32
+	// updateEvery is the interval at which to check for new releases.
+	// If it is not positive, the bot doesn't check.
+	updateEvery time.Duration
+	// dupWindow is the window within which channels sharing a send tag
+	// don't send the same generated message.
+	dupWindow time.Duration
+	// dups is the tracker of recent generated messages for each send tag.
+	dups map[string]*channel.Outputs
+	// jobs runs background jobs like enforcing quotas and watching disk
+	// space. If it is nil when the robot runs, jobs run one at a time
+	// without saved checkpoints.
+	jobs *jobs.Runner
 }
 
 // client is the settings for OAuth2 and related elements.
@@ -69,203 +189,82 @@ type client[Send, Receive any] struct {
 }
 
 // New creates a new robot instance. Use SetOwner, SetSecrets, &c. as needed
-// to initialize the robot.
+// to initialize the robot. poolSize is the number of messages the robot may
+// handle at once across all channels.
 func New(poolSize int) *Robot {
-	return &Robot{
-		channels: syncmap.New[string, *channel.Channel](),
-		works:    make(chan chan func(context.Context), poolSize),
+	robo := &Robot{
+		channels:   syncmap.New[string, *channel.Channel](),
+		commands:   builtinCommands(),
+		keyLimits:  syncmap.New[string, *rate.Limiter](),
+		metrics:    new(expvar.Map),
+		drops:      new(expvar.Map),
+		queueDrops: new(expvar.Map),
+		sent:       new(expvar.Map),
+		engaged:    new(expvar.Map),
+		latency:    new(expvar.Map),
 	}
+	robo.works = serial.New(poolSize, robo.dropWork)
+	robo.works.SetDepth(defaultQueue)
+	robo.metrics.Set("pending", expvar.Func(func() any { return robo.pending.Load() }))
+	robo.metrics.Set("queue_drops", robo.queueDrops)
+	robo.metrics.Set("learn_drops", robo.drops)
+	robo.metrics.Set("engagement_sent", robo.sent)
+	robo.metrics.Set("engagement_engaged", robo.engaged)
+	robo.metrics.Set("reply_latency", robo.latency)
+	return robo
 }
 
 func (robo *Robot) Run(ctx context.Context) error {
+	if err := robo.checkQuotas(); err != nil {
+		return err
+	}
+	// Preload before connecting so that the first messages we speak don't
+	// wait on the disk.
+	if err := robo.preload(ctx); err != nil {
+		return fmt.Errorf("couldn't preload knowledge: %w", err)
+	}
 	group, ctx := errgroup.WithContext(ctx)
 	// TODO(zeph): stdin?
-	if robo.tmi != nil {
-		group.Go(func() error { return robo.runTwitch(ctx, group) })
+	h := &handler{robo: robo, group: group}
+	for _, c := range robo.platforms {
+		group.Go(func() error { return c.Run(ctx, h) })
 	}
-	err := group.Wait()
-	if err == context.Canceled {
-		// If the first error is context canceled, then we are shutting down
-		// normally in response to a sigint.
-		err = nil
+	for _, p := range robo.posters {
+		group.Go(func() error { return robo.runPoster(ctx, p) })
 	}
-	return err
-}
-
-func (robo *Robot) runTwitch(ctx context.Context, group *errgroup.Group) error {
-	tok, err := robo.tmi.tokens.Token(ctx)
-	if err != nil {
-		return err
+	if robo.listen != "" {
+		group.Go(func() error { return robo.serveHTTP(ctx, robo.listen) })
 	}
-	cfg := tmi.ConnectConfig{
-		Dial:         new(tls.Dialer).DialContext,
-		RetryWait:    tmi.RetryList(true, 0, time.Second, time.Minute, 5*time.Minute),
-		Nick:         strings.ToLower(robo.tmi.name),
-		Pass:         "oauth:" + tok.AccessToken,
-		Capabilities: []string{"twitch.tv/commands", "twitch.tv/tags"},
-		Timeout:      300 * time.Second,
+	if robo.warm != nil {
+		group.Go(func() error { return robo.warm.Run(ctx) })
 	}
-	group.Go(func() error {
-		robo.tmiLoop(ctx, group, robo.tmi.send, robo.tmi.recv)
-		return nil
-	})
-	group.Go(func() error {
-		return robo.twitchValidateLoop(ctx)
-	})
-	group.Go(func() error {
-		return robo.streamsLoop(ctx, robo.channels)
-	})
-	tmi.Connect(ctx, cfg, &tmiSlog{slog.Default()}, robo.tmi.send, robo.tmi.recv)
-	return ctx.Err()
-}
-
-func (robo *Robot) twitchValidateLoop(ctx context.Context) error {
-	tm := time.NewTicker(time.Hour)
-	defer tm.Stop()
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-tm.C: // continue below
-		}
-		tok, err := robo.tmi.tokens.Token(ctx)
-		if err != nil {
-			return fmt.Errorf("validation loop failed to get user access token: %w", err)
-		}
-		val, err := twitch.Validate(ctx, robo.twitch.HTTP, tok)
-		switch {
-		case err == nil:
-			slog.InfoContext(ctx, "validation loop",
-				slog.String("clientid", val.ClientID),
-				slog.String("userid", val.UserID),
-				slog.String("login", val.Login),
-				slog.Int("expires", val.ExpiresIn),
-			)
-		case errors.Is(err, twitch.ErrNeedRefresh):
-			_, err := robo.tmi.tokens.Refresh(ctx, tok)
-			if err != nil {
-				return fmt.Errorf("validation loop failed to refresh user access token: %w", err)
-			}
-		default:
-			if val != nil {
-				slog.ErrorContext(ctx, "validation loop", slog.Int("status", val.Status), slog.String("message", val.Message))
-			}
-			return fmt.Errorf("validation loop failed to validate user access token: %w", err)
-		}
+	if robo.tier != nil {
+		group.Go(func() error { return robo.tier.Run(ctx) })
 	}
-}
-
-func (robo *Robot) streamsLoop(ctx context.Context, channels *syncmap.Map[string, *channel.Channel]) error {
-	// TODO(zeph): one day we should switch to eventsub
-	// TODO(zeph): remove anything learned since the last check when offline
-	tok, err := robo.tmi.tokens.Token(ctx)
-	if err != nil {
-		return err
+	if robo.jobs == nil {
+		// Without a database, the runner can't fail to open.
+		robo.jobs, _ = jobs.New(ctx, nil, 1, 0)
 	}
-	streams := make([]twitch.Stream, 0, channels.Len())
-	m := make(map[string]bool, channels.Len())
-	// Run once at the start so we start learning in online streams immediately.
-	streams = streams[:0]
-	for _, ch := range channels.All() {
-		n := strings.ToLower(strings.TrimPrefix(ch.Name, "#"))
-		streams = append(streams, twitch.Stream{UserLogin: n})
+	robo.addSweepJobs(ctx)
+	robo.addDiskJob()
+	robo.addUpdateJob()
+	robo.addRolesJob()
+	robo.addAlertsJob()
+	if robo.twitch != nil && robo.twitch.eventsub {
+		group.Go(func() error { return robo.runEventSub(ctx) })
 	}
-	for range 5 {
-		// TODO(zeph): limit to 100
-		streams, err = twitch.UserStreams(ctx, robo.twitch, tok, streams)
-		switch {
-		case err == nil:
-			slog.InfoContext(ctx, "stream infos", slog.Int("count", len(streams)))
-			// Mark online streams as enabled.
-			// First map names to online status.
-			for _, s := range streams {
-				slog.DebugContext(ctx, "stream",
-					slog.String("login", s.UserLogin),
-					slog.String("display", s.UserName),
-					slog.String("id", s.UserID),
-					slog.String("type", s.Type),
-				)
-				n := strings.ToLower(s.UserLogin)
-				m[n] = true
-			}
-			// Now loop all streams.
-			for _, ch := range channels.All() {
-				n := strings.ToLower(strings.TrimPrefix(ch.Name, "#"))
-				ch.Enabled.Store(m[n])
-			}
-		case errors.Is(err, twitch.ErrNeedRefresh):
-			tok, err = robo.tmi.tokens.Refresh(ctx, tok)
-			if err != nil {
-				slog.ErrorContext(ctx, "failed to refresh token", slog.Any("err", err))
-				return fmt.Errorf("couldn't get valid access token: %w", err)
-			}
-			continue
-		default:
-			slog.ErrorContext(ctx, "failed to query online broadcasters", slog.Any("streams", streams), slog.Any("err", err))
-			// All streams are already offline.
-		}
-		break
+	robo.addReweighJob()
+	robo.addEmoteServicesJob()
+	if robo.jobs.Len() != 0 {
+		group.Go(func() error { return robo.jobs.Run(ctx) })
 	}
-	streams = streams[:0]
-	clear(m)
-
-	tick := time.NewTicker(time.Minute)
-	go func() {
-		<-ctx.Done()
-		tick.Stop()
-	}()
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-tick.C:
-			for _, ch := range channels.All() {
-				n := strings.TrimPrefix(ch.Name, "#")
-				streams = append(streams, twitch.Stream{UserLogin: n})
-			}
-			for range 5 {
-				// TODO(zeph): limit to 100
-				streams, err = twitch.UserStreams(ctx, robo.twitch, tok, streams)
-				switch {
-				case err == nil:
-					slog.InfoContext(ctx, "stream infos", slog.Int("count", len(streams)))
-					// Mark online streams as enabled.
-					// First map names to online status.
-					for _, s := range streams {
-						slog.DebugContext(ctx, "stream",
-							slog.String("login", s.UserLogin),
-							slog.String("display", s.UserName),
-							slog.String("id", s.UserID),
-							slog.String("type", s.Type),
-						)
-						n := strings.ToLower(s.UserLogin)
-						m[n] = true
-					}
-					// Now loop all streams.
-					for _, ch := range channels.All() {
-						n := strings.ToLower(strings.TrimPrefix(ch.Name, "#"))
-						ch.Enabled.Store(m[n])
-					}
-				case errors.Is(err, twitch.ErrNeedRefresh):
-					tok, err = robo.tmi.tokens.Refresh(ctx, tok)
-					if err != nil {
-						slog.ErrorContext(ctx, "failed to refresh token", slog.Any("err", err))
-						return fmt.Errorf("couldn't get valid access token: %w", err)
-					}
-					continue
-				default:
-					slog.ErrorContext(ctx, "failed to query online broadcasters", slog.Any("streams", streams), slog.Any("err", err))
-					// Set all streams as offline.
-					for _, ch := range channels.All() {
-						ch.Enabled.Store(false)
-					}
-				}
-				break
-			}
-			streams = streams[:0]
-			clear(m)
-		}
+	err := group.Wait()
+	if err == context.Canceled {
+		// If the first error is context canceled, then we are shutting down
+		// normally in response to a sigint.
+		err = nil
 	}
+	return err
 }
 
 func deviceCodePrompt(userCode, verURI, verURIComplete string) {
@@ -276,15 +275,3 @@ func deviceCodePrompt(userCode, verURI, verURIComplete string) {
 	fmt.Println("Enter code at", verURI)
 	fmt.Printf("\n\t%s\n\n", userCode)
 }
-
-type tmiSlog struct {
-	l *slog.Logger
-}
-
-func (l *tmiSlog) Error(err error) { l.l.Error("TMI error", slog.String("err", err.Error())) }
-func (l *tmiSlog) Status(s string) { l.l.Info("TMI status", slog.String("message", s)) }
-func (l *tmiSlog) Send(s string)   { l.l.Debug("TMI send", slog.String("message", s)) }
-func (l *tmiSlog) Recv(s string)   { l.l.Debug("TMI recv", slog.String("message", s)) }
-func (l *tmiSlog) Ping(s string) {
-	l.l.Log(context.Background(), slog.LevelDebug-1, "TMI ping", slog.String("message", s))
-}
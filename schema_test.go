@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestConfigSchema(t *testing.T) {
+	s := configSchema()
+	// Walk the schema along each key in the example config to check that
+	// every key the config uses is described.
+	var cfg Config
+	md, err := toml.DecodeFile("example.toml", &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range md.Keys() {
+		cur := s
+		for i, p := range k {
+			if props, ok := cur["properties"].(map[string]any); ok {
+				next, ok := props[p].(map[string]any)
+				if !ok {
+					t.Errorf("schema lacks %s", k[:i+1])
+					break
+				}
+				cur = next
+				continue
+			}
+			if add, ok := cur["additionalProperties"].(map[string]any); ok {
+				cur = add
+				continue
+			}
+			if items, ok := cur["items"].(map[string]any); ok && i > 0 {
+				// Arrays of tables name their keys without indices.
+				cur = items
+				if props, ok := cur["properties"].(map[string]any); ok {
+					if next, ok := props[p].(map[string]any); ok {
+						cur = next
+						continue
+					}
+				}
+			}
+			t.Errorf("schema can't describe %s at %q", k, p)
+			break
+		}
+	}
+}
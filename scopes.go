@@ -0,0 +1,69 @@
+package main
+
+import "slices"
+
+// twitchScopes is the OAuth2 scopes the bot always requests for Twitch.
+var twitchScopes = []string{"chat:read", "chat:edit"}
+
+// twitchFeature is an optional Twitch feature which needs extra scopes.
+type twitchFeature struct {
+	// name describes the feature to the operator.
+	name string
+	// scopes is the scopes the feature needs.
+	scopes []string
+	// enabled reports whether the feature is configured.
+	enabled func(cfg *ClientCfg) bool
+	// disable turns the feature off when its scopes aren't granted.
+	disable func(cfg *ClientCfg)
+}
+
+// twitchFeatures is the optional Twitch features which need extra scopes.
+var twitchFeatures = []twitchFeature{
+	{
+		name:    "moderator and VIP sync (tmi.roles)",
+		scopes:  []string{"moderator:read:moderators", "moderator:read:vips"},
+		enabled: func(cfg *ClientCfg) bool { return cfg.Roles > 0 },
+		disable: func(cfg *ClientCfg) { cfg.Roles = 0 },
+	},
+	{
+		name:    "EventSub (tmi.eventsub)",
+		scopes:  []string{"moderator:read:followers"},
+		enabled: func(cfg *ClientCfg) bool { return cfg.EventSub },
+		disable: func(cfg *ClientCfg) { cfg.EventSub = false },
+	},
+}
+
+// twitchScopesFor is the scopes to request for a TMI configuration: the base
+// scopes plus those of each enabled feature.
+func twitchScopesFor(cfg *ClientCfg) []string {
+	r := slices.Clone(twitchScopes)
+	for _, f := range twitchFeatures {
+		if !f.enabled(cfg) {
+			continue
+		}
+		for _, s := range f.scopes {
+			if !slices.Contains(r, s) {
+				r = append(r, s)
+			}
+		}
+	}
+	return r
+}
+
+// missingFeatures lists the enabled Twitch features needing scopes which
+// aren't in granted.
+func missingFeatures(cfg *ClientCfg, granted []string) []twitchFeature {
+	var r []twitchFeature
+	for _, f := range twitchFeatures {
+		if !f.enabled(cfg) {
+			continue
+		}
+		for _, s := range f.scopes {
+			if !slices.Contains(granted, s) {
+				r = append(r, f)
+				break
+			}
+		}
+	}
+	return r
+}
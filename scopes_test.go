@@ -0,0 +1,42 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTwitchScopes(t *testing.T) {
+	var cfg ClientCfg
+	if got := twitchScopesFor(&cfg); !slices.Equal(got, twitchScopes) {
+		t.Errorf("wrong scopes without features: want %q, got %q", twitchScopes, got)
+	}
+	if m := missingFeatures(&cfg, nil); len(m) != 0 {
+		t.Errorf("disabled features missing scopes: %v", m)
+	}
+	cfg.Roles = 600
+	got := twitchScopesFor(&cfg)
+	want := []string{"chat:read", "chat:edit", "moderator:read:moderators", "moderator:read:vips"}
+	if !slices.Equal(got, want) {
+		t.Errorf("wrong scopes with roles: want %q, got %q", want, got)
+	}
+	if m := missingFeatures(&cfg, got); len(m) != 0 {
+		t.Errorf("features missing granted scopes: %v", m)
+	}
+	m := missingFeatures(&cfg, []string{"chat:read", "chat:edit", "moderator:read:moderators"})
+	if len(m) != 1 {
+		t.Fatalf("wrong missing features: %v", m)
+	}
+	m[0].disable(&cfg)
+	if cfg.Roles != 0 {
+		t.Errorf("feature not disabled")
+	}
+	cfg.EventSub = true
+	m = missingFeatures(&cfg, []string{"chat:read", "chat:edit"})
+	if len(m) != 1 || !slices.Contains(m[0].scopes, "moderator:read:followers") {
+		t.Fatalf("wrong missing features for eventsub: %v", m)
+	}
+	m[0].disable(&cfg)
+	if cfg.EventSub {
+		t.Errorf("eventsub not disabled")
+	}
+}
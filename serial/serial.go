@@ -0,0 +1,161 @@
+// Package serial provides a worker pool which runs works in order per key.
+package serial
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Pool runs works with bounded concurrency. Works submitted under the same key
+// run one at a time in the order they were submitted, while works under
+// different keys run concurrently.
+type Pool struct {
+	// sem limits the number of works running at once.
+	sem chan struct{}
+	// drop is called for each work which is dropped without running.
+	// It may be nil.
+	drop func(key string)
+	// mu guards queues and depth.
+	mu sync.Mutex
+	// queues is the pending works for each key which has a runner.
+	queues map[string][]entry
+	// depth is the number of works which may wait under each key behind the
+	// one at the head of its queue. If it is not positive, queues are
+	// unbounded.
+	depth int
+}
+
+// entry is a queued work.
+type entry struct {
+	work func(context.Context)
+	// droppable indicates that the work may be dropped to keep its queue
+	// within the pool's depth.
+	droppable bool
+}
+
+// New creates a pool which runs up to n works at once.
+// If drop is not nil, it is called with the key of each work which the pool
+// drops without running, whether to keep a queue within its depth or because
+// the runner's context was canceled.
+func New(n int, drop func(key string)) *Pool {
+	return &Pool{
+		sem:    make(chan struct{}, max(n, 1)),
+		drop:   drop,
+		queues: make(map[string][]entry),
+	}
+}
+
+// SetDepth sets the number of works which may wait under each key behind the
+// one running or about to run. Once a key's queue is full, submitting another
+// work drops the oldest waiting droppable one. Works which aren't droppable
+// can overfill the queue. If depth is not positive, queues are unbounded.
+func (p *Pool) SetDepth(depth int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.depth = depth
+}
+
+// Submit adds work to the queue for key. The work is never dropped to keep the
+// queue within the pool's depth, although it is still dropped if the runner's
+// context is canceled.
+// If there is no runner for the key's queue, the result is a new runner which
+// the caller must call, typically in a new goroutine. Otherwise, the result is
+// nil, and the existing runner will run the work.
+func (p *Pool) Submit(key string, work func(context.Context)) func(context.Context) {
+	return p.submit(key, entry{work: work})
+}
+
+// SubmitDroppable is like [Pool.Submit], but the work may be dropped without
+// running once the key's queue is full.
+func (p *Pool) SubmitDroppable(key string, work func(context.Context)) func(context.Context) {
+	return p.submit(key, entry{work: work, droppable: true})
+}
+
+func (p *Pool) submit(key string, e entry) func(context.Context) {
+	p.mu.Lock()
+	q, ok := p.queues[key]
+	q = append(q, e)
+	dropped := false
+	if p.depth > 0 && len(q)-1 > p.depth {
+		// The head of the queue may already be running, so only works behind
+		// it are candidates.
+		if k := slices.IndexFunc(q[1:], func(e entry) bool { return e.droppable }); k >= 0 {
+			q = slices.Delete(q, k+1, k+2)
+			dropped = true
+		}
+	}
+	p.queues[key] = q
+	p.mu.Unlock()
+	if dropped {
+		p.dropped(key, 1)
+	}
+	if ok {
+		return nil
+	}
+	return func(ctx context.Context) { p.run(ctx, key) }
+}
+
+// Len returns the number of works queued under key, including one which may
+// be running.
+func (p *Pool) Len(key string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.queues[key])
+}
+
+// run runs the works in a key's queue until it is empty.
+// If the context is canceled, the remaining works are dropped.
+func (p *Pool) run(ctx context.Context, key string) {
+	for {
+		p.mu.Lock()
+		q := p.queues[key]
+		if len(q) == 0 {
+			delete(p.queues, key)
+			p.mu.Unlock()
+			return
+		}
+		work := q[0].work
+		p.mu.Unlock()
+		// Check the context first so that a canceled runner doesn't run
+		// works just because the semaphore happens to be free.
+		if ctx.Err() != nil {
+			p.cancel(key)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			p.cancel(key)
+			return
+		case p.sem <- struct{}{}:
+		}
+		work(ctx)
+		<-p.sem
+		// Only remove the work after it finishes, so that the queue is never
+		// empty while a work under its key is running.
+		p.mu.Lock()
+		q = p.queues[key]
+		q[0] = entry{}
+		p.queues[key] = q[1:]
+		p.mu.Unlock()
+	}
+}
+
+// cancel drops all works queued under key.
+func (p *Pool) cancel(key string) {
+	p.mu.Lock()
+	n := len(p.queues[key])
+	delete(p.queues, key)
+	p.mu.Unlock()
+	p.dropped(key, n)
+}
+
+// dropped reports n works dropped under key.
+func (p *Pool) dropped(key string, n int) {
+	if p.drop == nil {
+		return
+	}
+	for range n {
+		p.drop(key)
+	}
+}
@@ -0,0 +1,169 @@
+package serial_test
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/zephyrtronium/robot/serial"
+)
+
+func TestOrder(t *testing.T) {
+	ctx := context.Background()
+	p := serial.New(4, nil)
+	var wg sync.WaitGroup
+	got := make(map[string][]int)
+	var mu sync.Mutex
+	for i := range 100 {
+		for _, k := range []string{"bocchi", "ryou", "nijika"} {
+			wg.Add(1)
+			run := p.Submit(k, func(ctx context.Context) {
+				defer wg.Done()
+				mu.Lock()
+				got[k] = append(got[k], i)
+				mu.Unlock()
+			})
+			if run != nil {
+				go run(ctx)
+			}
+		}
+	}
+	wg.Wait()
+	for k, v := range got {
+		if len(v) != 100 {
+			t.Errorf("wrong number of works for %s: want 100, got %d", k, len(v))
+		}
+		for i, x := range v {
+			if x != i {
+				t.Errorf("works for %s out of order: %v", k, v)
+				break
+			}
+		}
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	ctx := context.Background()
+	p := serial.New(2, nil)
+	var wg sync.WaitGroup
+	var cur, most atomic.Int32
+	block := make(chan struct{})
+	for i := range 8 {
+		wg.Add(1)
+		run := p.Submit(string(rune('a'+i)), func(ctx context.Context) {
+			defer wg.Done()
+			n := cur.Add(1)
+			for {
+				m := most.Load()
+				if n <= m || most.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			<-block
+			cur.Add(-1)
+		})
+		if run == nil {
+			t.Fatalf("no runner for new key %d", i)
+		}
+		go run(ctx)
+	}
+	close(block)
+	wg.Wait()
+	if m := most.Load(); m > 2 {
+		t.Errorf("too many concurrent works: want at most 2, got %d", m)
+	}
+	if n := p.Len("a"); n != 0 {
+		t.Errorf("queue not emptied: %d remain", n)
+	}
+}
+
+func TestDepth(t *testing.T) {
+	ctx := context.Background()
+	var drops atomic.Int32
+	p := serial.New(1, func(key string) {
+		if key != "kita" {
+			t.Errorf("dropped work under wrong key %q", key)
+		}
+		drops.Add(1)
+	})
+	p.SetDepth(2)
+	block := make(chan struct{})
+	var got []int
+	var wg sync.WaitGroup
+	run := p.Submit("kita", func(ctx context.Context) { <-block })
+	for i := range 5 {
+		wg.Add(1)
+		r := p.SubmitDroppable("kita", func(ctx context.Context) {
+			defer wg.Done()
+			got = append(got, i)
+		})
+		if r != nil {
+			t.Fatalf("new runner for queued key")
+		}
+	}
+	if n := p.Len("kita"); n != 3 {
+		t.Errorf("wrong queue length: want 3, got %d", n)
+	}
+	if n := drops.Load(); n != 3 {
+		t.Errorf("wrong number of drops: want 3, got %d", n)
+	}
+	// Account for the dropped works.
+	wg.Add(-3)
+	close(block)
+	run(ctx)
+	wg.Wait()
+	if len(got) != 2 || got[0] != 3 || got[1] != 4 {
+		t.Errorf("wrong works ran: want [3 4], got %v", got)
+	}
+}
+
+func TestCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var drops atomic.Int32
+	p := serial.New(1, func(key string) { drops.Add(1) })
+	var run func(context.Context)
+	for range 4 {
+		if r := p.Submit("kikuri", func(ctx context.Context) { t.Error("canceled work ran") }); r != nil {
+			run = r
+		}
+	}
+	run(ctx)
+	if n := drops.Load(); n != 4 {
+		t.Errorf("wrong number of drops: want 4, got %d", n)
+	}
+	if n := p.Len("kikuri"); n != 0 {
+		t.Errorf("queue not emptied: %d remain", n)
+	}
+}
+
+func TestDepthUndroppable(t *testing.T) {
+	ctx := context.Background()
+	var drops atomic.Int32
+	p := serial.New(1, func(key string) { drops.Add(1) })
+	p.SetDepth(2)
+	block := make(chan struct{})
+	var got []string
+	run := p.Submit("kita", func(ctx context.Context) { <-block })
+	p.SubmitDroppable("kita", func(ctx context.Context) { got = append(got, "chat 0") })
+	p.Submit("kita", func(ctx context.Context) { got = append(got, "forget") })
+	for i := range 3 {
+		p.SubmitDroppable("kita", func(ctx context.Context) { got = append(got, fmt.Sprint("chat ", i+1)) })
+	}
+	// Undroppable works still make room by dropping droppable ones, and once
+	// only undroppable works wait, the queue overfills.
+	p.Submit("kita", func(ctx context.Context) { got = append(got, "clear") })
+	p.Submit("kita", func(ctx context.Context) { got = append(got, "clear again") })
+	close(block)
+	run(ctx)
+	want := []string{"forget", "clear", "clear again"}
+	if !slices.Equal(got, want) {
+		t.Errorf("wrong works ran:\nwant %q\ngot  %q", want, got)
+	}
+	if n := drops.Load(); n != 4 {
+		t.Errorf("wrong number of drops: want 4, got %d", n)
+	}
+}
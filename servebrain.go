@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+
+	"github.com/urfave/cli/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/zephyrtronium/robot/brain/busy"
+	"github.com/zephyrtronium/robot/brain/remote"
+)
+
+func cliServeBrain(ctx context.Context, cmd *cli.Command) error {
+	slog.SetDefault(loggerFromFlags(cmd))
+	cfg, err := cliConfig(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	if cfg.DB.Remote.Addr != "" {
+		return errors.New("can't serve a brain which is itself remote")
+	}
+	tok, err := readToken(cmd.String("token"))
+	if err != nil {
+		return err
+	}
+	var opts []grpc.ServerOption
+	switch cert, key := cmd.String("tls-cert"), cmd.String("tls-key"); {
+	case cert != "" && key != "":
+		kp, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return fmt.Errorf("couldn't load TLS certificate: %w", err)
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{kp}, MinVersion: tls.VersionTLS12})))
+	case cert != "" || key != "":
+		return errors.New("--tls-cert and --tls-key must be given together")
+	case tok != "" && !cmd.Bool("insecure") && !loopbackAddr(cmd.String("listen")):
+		// Clients would send the token in the clear.
+		return errors.New("refusing to accept a token without TLS; give --tls-cert and --tls-key, or --insecure on a private network")
+	}
+	db, err := loadDBs(ctx, cfg.DB)
+	if err != nil {
+		return err
+	}
+	br, done, err := openBrain(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer done()
+	if db.retry != nil && db.sql != nil {
+		br = busy.New(br, db.retry)
+	}
+	l, err := net.Listen("tcp", cmd.String("listen"))
+	if err != nil {
+		return fmt.Errorf("couldn't listen: %w", err)
+	}
+	s := remote.NewServer(br, tok, opts...)
+	stop := context.AfterFunc(ctx, s.GracefulStop)
+	defer stop()
+	slog.InfoContext(ctx, "serving brain", slog.String("addr", l.Addr().String()))
+	return s.Serve(l)
+}
+
+// loopbackAddr reports whether a listen address is on the loopback interface.
+func loopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,26 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/urfave/cli/v3"
+)
+
+// runService reports false, since services are only for Windows.
+// Elsewhere, use the platform's service manager with the run command.
+func runService(ctx context.Context, args []string) (bool, error) {
+	return false, nil
+}
+
+var errNoService = errors.New("services are only supported on Windows; use your platform's service manager instead")
+
+func cliServiceInstall(ctx context.Context, cmd *cli.Command) error {
+	return errNoService
+}
+
+func cliServiceUninstall(ctx context.Context, cmd *cli.Command) error {
+	return errNoService
+}
@@ -0,0 +1,123 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli/v3"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// serviceName is the name of the bot's Windows service.
+const serviceName = "robot"
+
+// runService runs the app as a Windows service if the process was started by
+// the service manager. It reports whether it did.
+func runService(ctx context.Context, args []string) (bool, error) {
+	ok, err := svc.IsWindowsService()
+	if err != nil || !ok {
+		return false, err
+	}
+	return true, svc.Run(serviceName, &service{ctx: ctx, args: args})
+}
+
+// service adapts the app to the Windows service manager.
+type service struct {
+	ctx  context.Context
+	args []string
+}
+
+// Execute runs the app until it exits or the service manager asks it to stop.
+func (s *service) Execute(_ []string, r <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- app.Run(ctx, s.args) }()
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				slog.Error("service failed", slog.Any("err", err))
+				return false, 1
+			}
+			return false, 0
+		case c := <-r:
+			switch c.Cmd {
+			case svc.Interrogate:
+				status <- c.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				cancel()
+			}
+		}
+	}
+}
+
+func cliServiceInstall(ctx context.Context, cmd *cli.Command) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("couldn't find executable: %w", err)
+	}
+	args := []string{
+		"--log", cmd.String("log"),
+		"--log-format", cmd.String("log-format"),
+	}
+	if cfg := cmd.String("config"); cfg != "" {
+		cfg, err = filepath.Abs(cfg)
+		if err != nil {
+			return fmt.Errorf("couldn't resolve config path: %w", err)
+		}
+		args = append(args, "--config", cfg)
+	}
+	if f := cmd.String("log-file"); f != "" {
+		f, err = filepath.Abs(f)
+		if err != nil {
+			return fmt.Errorf("couldn't resolve log file path: %w", err)
+		}
+		args = append(args, "--log-file", f)
+	} else {
+		slog.WarnContext(ctx, "no log file; the service's logs will be discarded")
+	}
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("couldn't connect to service manager: %w", err)
+	}
+	defer m.Disconnect()
+	s, err := m.CreateService(serviceName, exe, mgr.Config{
+		DisplayName: "Robot",
+		Description: "Markov chain chat bot",
+		StartType:   mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return fmt.Errorf("couldn't create service: %w", err)
+	}
+	s.Close()
+	fmt.Println("installed service", serviceName)
+	return nil
+}
+
+func cliServiceUninstall(ctx context.Context, cmd *cli.Command) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("couldn't connect to service manager: %w", err)
+	}
+	defer m.Disconnect()
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		return fmt.Errorf("couldn't open service: %w", err)
+	}
+	defer s.Close()
+	if err := s.Delete(); err != nil {
+		return fmt.Errorf("couldn't delete service: %w", err)
+	}
+	fmt.Println("uninstalled service", serviceName)
+	return nil
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/urfave/cli/v3"
+	"golang.org/x/oauth2"
+
+	"github.com/zephyrtronium/robot/auth"
+)
+
+// setup is the answers to the first-run setup questions.
+type setup struct {
+	// Dir is the directory for data files.
+	Dir string
+	// Owner is the owner's name.
+	Owner string
+	// CID is the Twitch application client ID.
+	CID string
+	// OwnerLogin is the owner's Twitch login.
+	OwnerLogin string
+	// Brain is the brain backend: sqlbrain, kvbrain, or boltbrain.
+	Brain string
+	// Channel is the Twitch channel to join, with its leading #.
+	Channel string
+	// Tag is the tag for the channel.
+	Tag string
+}
+
+// wizard asks setup questions interactively.
+type wizard struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// ask asks a question with a default answer, which is used if the response
+// is empty.
+func (w *wizard) ask(q, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", q, def)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", q)
+	}
+	s, err := w.in.ReadString('\n')
+	if err != nil && (s == "" || !errors.Is(err, io.EOF)) {
+		return "", fmt.Errorf("couldn't read answer: %w", err)
+	}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return def, nil
+	}
+	return s, nil
+}
+
+// need asks a question until it gets a non-empty answer.
+func (w *wizard) need(q string) (string, error) {
+	for {
+		s, err := w.ask(q, "")
+		if s != "" || err != nil {
+			return s, err
+		}
+	}
+}
+
+// confirm asks a yes or no question.
+func (w *wizard) confirm(q string, def bool) (bool, error) {
+	d := "y/N"
+	if def {
+		d = "Y/n"
+	}
+	for {
+		s, err := w.ask(q+" ("+d+")", "")
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(s) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+	}
+}
+
+// choose asks a question whose answer must be one of a list of options.
+// The first option is the default.
+func (w *wizard) choose(q string, opts ...string) (string, error) {
+	for {
+		s, err := w.ask(q+" ("+strings.Join(opts, ", ")+")", opts[0])
+		if err != nil {
+			return "", err
+		}
+		for _, o := range opts {
+			if strings.EqualFold(s, o) {
+				return o, nil
+			}
+		}
+	}
+}
+
+func cliInit(ctx context.Context, cmd *cli.Command) error {
+	out := cmd.String("out")
+	if _, err := os.Stat(out); err == nil {
+		return fmt.Errorf("%s already exists", out)
+	}
+	w := &wizard{in: bufio.NewReader(os.Stdin), out: os.Stdout}
+	return runSetup(ctx, w, out, http.DefaultClient, deviceCodePrompt)
+}
+
+// runSetup walks through first-run setup and writes a starter config to out.
+func runSetup(ctx context.Context, w *wizard, out string, client *http.Client, prompt auth.DeviceCodePrompt) error {
+	fmt.Fprintln(w.out, "Setting up Robot. Press enter to accept the default in brackets.")
+	var s setup
+	var err error
+	if s.Dir, err = w.ask("Directory for data files", "."); err != nil {
+		return err
+	}
+	if s.Dir, err = filepath.Abs(s.Dir); err != nil {
+		return fmt.Errorf("couldn't resolve data directory: %w", err)
+	}
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return fmt.Errorf("couldn't create data directory: %w", err)
+	}
+	created, err := writeSecret(filepath.Join(s.Dir, "secret.key"))
+	if err != nil {
+		return err
+	}
+	if created {
+		fmt.Fprintln(w.out, "Created secret key. Back it up; without it, stored tokens and user hashes are unusable.")
+	}
+	if s.Owner, err = w.need("Your name, for self-description commands"); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w.out, "\nRegister an application at https://dev.twitch.tv/console/apps with category Chat Bot and client type Confidential.")
+	if s.CID, err = w.need("Twitch client ID"); err != nil {
+		return err
+	}
+	secret, err := w.need("Twitch client secret")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(s.Dir, "twitch.secret"), []byte(secret), 0o600); err != nil {
+		return fmt.Errorf("couldn't write client secret: %w", err)
+	}
+	if s.OwnerLogin, err = w.need("Your Twitch login"); err != nil {
+		return err
+	}
+	ok, err := w.confirm("Authorize the bot's Twitch account now?", true)
+	if err != nil {
+		return err
+	}
+	if ok {
+		if err := authorizeTwitch(ctx, &s, secret, client, prompt); err != nil {
+			return err
+		}
+		fmt.Fprintln(w.out, "Authorized.")
+	} else {
+		fmt.Fprintln(w.out, "The bot will ask for authorization when it first connects.")
+	}
+
+	if s.Brain, err = w.choose("Brain backend", "sqlbrain", "kvbrain", "boltbrain"); err != nil {
+		return err
+	}
+	if s.Channel, err = w.need("Twitch channel to join"); err != nil {
+		return err
+	}
+	s.Channel = "#" + strings.ToLower(strings.TrimPrefix(s.Channel, "#"))
+	for {
+		if s.Tag, err = w.ask("Tag for learning and speaking in the channel", "twitch/"+s.Channel[1:]); err != nil {
+			return err
+		}
+		err := checkLearnTag("twitch", s.Tag)
+		if err == nil {
+			break
+		}
+		fmt.Fprintln(w.out, err)
+	}
+
+	f, err := os.OpenFile(out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("couldn't create config: %w", err)
+	}
+	if err := starterConfig.Execute(f, &s); err != nil {
+		f.Close()
+		return fmt.Errorf("couldn't write config: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("couldn't write config: %w", err)
+	}
+	fmt.Fprintf(w.out, "\nWrote %s. Start the bot with:\n\n\trobot --config %s\n\nSee example.toml for everything else you can configure.\n", out, out)
+	return nil
+}
+
+// writeSecret creates a new secret key file if there isn't one already.
+// It reports whether it created the file.
+func writeSecret(file string) (bool, error) {
+	if _, err := os.Stat(file); err == nil {
+		return false, nil
+	}
+	k := make([]byte, 64)
+	rand.Read(k)
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return false, fmt.Errorf("couldn't create secret key: %w", err)
+	}
+	if _, err := f.Write(k); err != nil {
+		f.Close()
+		return false, fmt.Errorf("couldn't write secret key: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return false, fmt.Errorf("couldn't write secret key: %w", err)
+	}
+	return true, nil
+}
+
+// authorizeTwitch performs the device code flow for the bot's Twitch account
+// and stores the resulting refresh token.
+func authorizeTwitch(ctx context.Context, s *setup, secret string, client *http.Client, prompt auth.DeviceCodePrompt) error {
+	k, err := loadSecrets(filepath.Join(s.Dir, "secret.key"))
+	if err != nil {
+		return err
+	}
+	stor, err := auth.NewFileAt(filepath.Join(s.Dir, "twitch.token"), *k.twitch)
+	if err != nil {
+		return fmt.Errorf("couldn't use refresh token storage: %w", err)
+	}
+	cfg := oauth2.Config{
+		ClientID:     s.CID,
+		ClientSecret: secret,
+		Endpoint:     twitchEndpoint,
+		RedirectURL:  "http://localhost",
+		Scopes:       twitchScopes,
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Minute)
+	defer cancel()
+	if _, err := auth.DeviceCodeFlow(cfg, stor, client, prompt).Token(ctx); err != nil {
+		return fmt.Errorf("couldn't authorize: %w", err)
+	}
+	return nil
+}
+
+var starterConfig = template.Must(template.New("config").Funcs(template.FuncMap{
+	"q":    strconv.Quote,
+	"path": func(dir, file string) string { return strconv.Quote(filepath.Join(dir, file)) },
+	"dsn":  func(dir, file string) string { return strconv.Quote("file:" + filepath.Join(dir, file)) },
+}).Parse(`# Robot config written by robot init.
+# See example.toml for descriptions of these and all other options.
+
+secret = {{path .Dir "secret.key"}}
+
+[owner]
+name = {{q .Owner}}
+contact = {{q (print "/w " .OwnerLogin)}}
+
+[db]
+{{- if eq .Brain "kvbrain"}}
+kvbrain = {{path .Dir "knowledge"}}
+{{- else if eq .Brain "boltbrain"}}
+boltbrain = {{path .Dir "brain.bolt"}}
+{{- else}}
+sqlbrain = {{dsn .Dir "robot.db"}}
+{{- end}}
+privacy = {{dsn .Dir "robot.db"}}
+spoken = {{dsn .Dir "robot.db"}}
+emotes = {{dsn .Dir "robot.db"}}
+
+[global]
+emotes = { '' = 4, ';)' = 1 }
+effects = { '' = 18, 'OwO' = 1, 'o' = 1 }
+
+[tmi]
+cid = {{q .CID}}
+secret = {{path .Dir "twitch.secret"}}
+redirect = 'http://localhost'
+token = {{path .Dir "twitch.token"}}
+owner = { name = {{q .OwnerLogin}} }
+rate = { every = 30, num = 20 }
+
+[twitch.{{q (slice .Channel 1)}}]
+channels = [{{q .Channel}}]
+learn = {{q .Tag}}
+send = {{q .Tag}}
+profile = 'standard'
+`))
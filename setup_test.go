@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRunSetup(t *testing.T) {
+	dir := t.TempDir()
+	data := filepath.Join(dir, "data")
+	out := filepath.Join(dir, "robot.toml")
+	answers := []string{
+		data,        // data directory
+		"",          // name, asked again
+		"bocchi",    // name
+		"kessoku",   // client ID
+		"band",      // client secret
+		"bocchi",    // Twitch login
+		"n",         // authorize now
+		"hashbrain", // brain backend, asked again
+		"kvbrain",   // brain backend
+		"#Ryo",      // channel
+		"ryo",       // tag, asked again
+		"",          // tag
+	}
+	w := &wizard{in: bufio.NewReader(strings.NewReader(strings.Join(answers, "\n") + "\n")), out: io.Discard}
+	if err := runSetup(context.Background(), w, out, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if k, err := os.ReadFile(filepath.Join(data, "secret.key")); err != nil || len(k) != 64 {
+		t.Errorf("bad secret key: %d bytes, %v", len(k), err)
+	}
+	if s, err := os.ReadFile(filepath.Join(data, "twitch.secret")); err != nil || string(s) != "band" {
+		t.Errorf("bad client secret: %q, %v", s, err)
+	}
+	r, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	cfg, _, err := Load(context.Background(), r, true)
+	if err != nil {
+		t.Fatalf("couldn't load written config: %v", err)
+	}
+	if cfg.Owner.Name != "bocchi" || cfg.TMI.CID != "kessoku" || cfg.TMI.Owner.Name != "bocchi" {
+		t.Errorf("wrong answers in config: %+v %+v", cfg.Owner, cfg.TMI)
+	}
+	if cfg.DB.KVBrain != filepath.Join(data, "knowledge") || cfg.DB.SQLBrain != "" {
+		t.Errorf("wrong brain: %+v", cfg.DB)
+	}
+	ch := cfg.Twitch["ryo"]
+	if ch == nil || !slices.Equal(ch.Channels, []string{"#ryo"}) || ch.Learn != "twitch/ryo" || ch.Send != "twitch/ryo" {
+		t.Errorf("wrong channel: %+v", ch)
+	}
+	w = &wizard{in: bufio.NewReader(strings.NewReader("")), out: io.Discard}
+	if err := runSetup(context.Background(), w, out, nil, nil); err == nil {
+		t.Error("setup with no answers succeeded")
+	}
+}
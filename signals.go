@@ -0,0 +1,11 @@
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// shutdownSignals are the signals which stop the bot cleanly.
+// On Windows, Go delivers console close, logoff, and shutdown events as
+// SIGTERM, so this covers closing the console window as well.
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/zephyrtronium/robot/message"
+	"github.com/zephyrtronium/robot/platform"
+	"github.com/zephyrtronium/robot/slack"
+	"github.com/zephyrtronium/robot/syncmap"
+)
+
+// slackClient is the bot's Socket Mode connection to a Slack workspace.
+type slackClient struct {
+	cl *slack.Client
+	// id is the bot's user ID.
+	id string
+	// name is the bot's username, used to recognize commands.
+	name string
+	// owner is the user ID of the owner.
+	owner string
+	// admins gives workspace admins and owners moderator privileges.
+	admins bool
+	// users caches user information.
+	users *syncmap.Map[string, *slackUser]
+}
+
+var _ platform.Client = (*slackClient)(nil)
+
+func (sc *slackClient) Platform() string { return "slack" }
+
+func (sc *slackClient) Bot() (id, name string) { return sc.id, sc.name }
+
+func (sc *slackClient) Owner() string { return sc.owner }
+
+func (sc *slackClient) Capabilities() platform.Capabilities {
+	f := platform.Format{Newlines: true, Escape: slack.Escape}
+	return platform.Capabilities{Replies: true, Threads: true, Format: f}
+}
+
+// Send sends a message to a channel. Replies go in the thread of the message
+// they reply to, or start a thread under it if it isn't in one.
+func (sc *slackClient) Send(ctx context.Context, channel, reply, text string) error {
+	thread, _, _ := strings.Cut(reply, "/")
+	return sc.cl.PostMessage(ctx, channel, thread, text)
+}
+
+// slackUser is cached information about a Slack user.
+type slackUser struct {
+	name  string
+	admin bool
+	at    time.Time
+}
+
+// InitSlack checks the Slack tokens.
+func (robo *Robot) InitSlack(ctx context.Context, cfg SlackCfg) error {
+	cl := &slack.Client{Token: cfg.Token, AppToken: cfg.AppToken}
+	me, err := cl.AuthTest(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't get Slack bot: %w", err)
+	}
+	slog.InfoContext(ctx, "Slack bot", slog.String("id", me.UserID), slog.String("user", me.User), slog.String("team", me.Team))
+	robo.slack = &slackClient{
+		cl:     cl,
+		id:     me.UserID,
+		name:   me.User,
+		owner:  cfg.Owner,
+		admins: cfg.Admins,
+		users:  syncmap.New[string, *slackUser](),
+	}
+	return nil
+}
+
+// SetSlackChannels initializes Slack channel configuration.
+// It must be called after InitSlack.
+func (robo *Robot) SetSlackChannels(ctx context.Context, global Global, channels map[string]*ChannelCfg) error {
+	return robo.setChannels(ctx, global, robo.slack, global.Privileges.Slack, channels)
+}
+
+func (sc *slackClient) Run(ctx context.Context, h platform.Handler) error {
+	group, ctx := errgroup.WithContext(ctx)
+	events := make(chan *slack.Event)
+	group.Go(func() error {
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case ev := <-events:
+				sc.event(ctx, h, ev)
+			}
+		}
+	})
+	group.Go(func() error {
+		for {
+			err := sc.cl.Listen(ctx, events)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err == nil {
+				// Slack asked us to reconnect.
+				slog.InfoContext(ctx, "Slack reconnecting")
+				continue
+			}
+			slog.ErrorContext(ctx, "Slack Socket Mode failed", slog.Any("err", err))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+			}
+		}
+	})
+	return group.Wait()
+}
+
+// slackID returns the message ID for a message with the given timestamp and
+// thread timestamp. Timestamps are only unique within a channel, and the
+// thread is kept so that replies can go in it.
+func slackID(ts, thread string) string {
+	if thread == "" || thread == ts {
+		return ts
+	}
+	return thread + "/" + ts
+}
+
+// event processes a message event from Slack.
+func (sc *slackClient) event(ctx context.Context, h platform.Handler, ev *slack.Event) {
+	switch ev.Subtype {
+	case "", "thread_broadcast":
+		if ev.BotID != "" || ev.User == "" || ev.User == sc.id {
+			return
+		}
+		u := sc.user(ctx, ev.User)
+		text := slack.Plain(ev.Text)
+		text = strings.ReplaceAll(text, "@"+sc.id, "@"+sc.name)
+		m := message.Incoming{
+			ID:          slackID(ev.TS, ev.ThreadTS),
+			To:          ev.Channel,
+			Sender:      ev.User,
+			Name:        u.name,
+			Text:        text,
+			Timestamp:   ev.Time().UnixMilli(),
+			IsModerator: sc.admins && u.admin,
+			Raw:         ev,
+		}
+		if ev.ThreadTS != "" && ev.ThreadTS != ev.TS {
+			m.ReplyParent = ev.ThreadTS
+		}
+		h.Message(ctx, sc, &m)
+	case "message_deleted":
+		id := ev.DeletedTS
+		if p := ev.PreviousMessage; p != nil {
+			id = slackID(ev.DeletedTS, p.ThreadTS)
+		}
+		h.Delete(ctx, sc, ev.Channel, id, "", false)
+	}
+}
+
+// slackUser gets cached information about a user, refreshing it hourly.
+// If the user can't be looked up, the result uses the user ID as the name and
+// has no privileges.
+func (sc *slackClient) user(ctx context.Context, id string) *slackUser {
+	if u, _ := sc.users.Load(id); u != nil && time.Since(u.at) < time.Hour {
+		return u
+	}
+	info, err := sc.cl.UserInfo(ctx, id)
+	if err != nil {
+		slog.WarnContext(ctx, "couldn't get Slack user", slog.Any("err", err), slog.String("user", id))
+		return &slackUser{name: id}
+	}
+	u := &slackUser{
+		name:  info.Profile.DisplayName,
+		admin: info.IsAdmin || info.IsOwner,
+		at:    time.Now(),
+	}
+	if u.name == "" {
+		u.name = info.Name
+	}
+	sc.users.Store(id, u)
+	return u
+}
@@ -0,0 +1,175 @@
+// Package slack implements the parts of the Slack Web API and Socket Mode that
+// Robot uses.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Client holds the context for requests to Slack.
+type Client struct {
+	// HTTP is the HTTP client for performing requests.
+	// If nil, http.DefaultClient is used.
+	HTTP *http.Client
+	// Token is the bot token, starting with xoxb-.
+	Token string
+	// AppToken is the app-level token for Socket Mode, starting with xapp-.
+	AppToken string
+	// API is the base URL of the Web API. If it is empty,
+	// https://slack.com/api is used.
+	API string
+}
+
+// call calls a Web API method with a JSON body and decodes the response into
+// r. The response body is truncated to 2 MB.
+func (c *Client) call(ctx context.Context, method, token string, body, r any) error {
+	base := c.API
+	if base == "" {
+		base = "https://slack.com/api"
+	}
+	u, err := url.JoinPath(base, method)
+	if err != nil {
+		panic("slack: bad url join with " + method)
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("couldn't encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", u, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("couldn't make request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	hc := c.HTTP
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("couldn't call %s: %w", method, err)
+	}
+	b, err = io.ReadAll(io.LimitReader(resp.Body, 2<<20))
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("couldn't read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s failed: %s", method, resp.Status)
+	}
+	// Slack reports errors in the body with a 200 status.
+	var e struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(b, &e); err != nil {
+		return fmt.Errorf("couldn't decode JSON response: %w", err)
+	}
+	if !e.OK {
+		return fmt.Errorf("%s failed: %s", method, e.Error)
+	}
+	if r == nil {
+		return nil
+	}
+	if err := json.Unmarshal(b, r); err != nil {
+		return fmt.Errorf("couldn't decode JSON response: %w", err)
+	}
+	return nil
+}
+
+// Auth is the identity of a token.
+type Auth struct {
+	UserID string `json:"user_id"`
+	User   string `json:"user"`
+	TeamID string `json:"team_id"`
+	Team   string `json:"team"`
+}
+
+// AuthTest returns the identity of the bot token.
+func (c *Client) AuthTest(ctx context.Context) (*Auth, error) {
+	var r Auth
+	if err := c.call(ctx, "auth.test", c.Token, struct{}{}, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// User is a Slack user.
+type User struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	IsAdmin bool   `json:"is_admin"`
+	IsOwner bool   `json:"is_owner"`
+	IsBot   bool   `json:"is_bot"`
+	Profile struct {
+		DisplayName string `json:"display_name"`
+	} `json:"profile"`
+}
+
+// UserInfo gets information about a user.
+func (c *Client) UserInfo(ctx context.Context, user string) (*User, error) {
+	var r struct {
+		User User `json:"user"`
+	}
+	// users.info doesn't accept JSON bodies, but it does accept the user as
+	// a query parameter on a POST.
+	if err := c.call(ctx, "users.info?user="+url.QueryEscape(user), c.Token, struct{}{}, &r); err != nil {
+		return nil, err
+	}
+	return &r.User, nil
+}
+
+// PostMessage sends a message to a channel. If thread is not empty, the
+// message is a reply in the thread with that timestamp.
+func (c *Client) PostMessage(ctx context.Context, channel, thread, text string) error {
+	body := map[string]string{
+		"channel": channel,
+		"text":    text,
+	}
+	if thread != "" {
+		body["thread_ts"] = thread
+	}
+	if err := c.call(ctx, "chat.postMessage", c.Token, body, nil); err != nil {
+		return fmt.Errorf("couldn't send to %s: %w", channel, err)
+	}
+	return nil
+}
+
+var markup = regexp.MustCompile(`<([@#!]?)([^|>]*)(?:\|([^>]*))?>`)
+
+// Plain converts Slack message markup to plain text. User mentions become @
+// followed by the user ID, channel mentions and special mentions become their
+// labels or IDs, and links become their URLs.
+func Plain(text string) string {
+	text = markup.ReplaceAllStringFunc(text, func(s string) string {
+		m := markup.FindStringSubmatch(s)
+		switch m[1] {
+		case "@":
+			return "@" + m[2]
+		case "#", "!":
+			if m[3] != "" {
+				return m[1] + m[3]
+			}
+			return m[1] + m[2]
+		default:
+			return m[2]
+		}
+	})
+	r := strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&")
+	return r.Replace(text)
+}
+
+// Escape escapes the characters Slack uses for markup so that text appears
+// as is.
+func Escape(text string) string {
+	r := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	return r.Replace(text)
+}
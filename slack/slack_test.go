@@ -0,0 +1,103 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	b, err := os.ReadFile("testdata/events.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var envs []envelope
+	if err := json.Unmarshal(b, &envs); err != nil {
+		t.Fatal(err)
+	}
+	var got []*Event
+	var done bool
+	for i := range envs {
+		ev, d := parse(&envs[i])
+		if ev != nil {
+			got = append(got, ev)
+		}
+		if d {
+			if i != len(envs)-1 {
+				t.Errorf("disconnect at %d", i)
+			}
+			done = true
+		}
+	}
+	if !done {
+		t.Errorf("no disconnect")
+	}
+	if len(got) != 2 {
+		t.Fatalf("wrong number of events: want 2, got %d", len(got))
+	}
+	m := got[0]
+	if m.Channel != "C123" || m.User != "U456" || m.TS != "1712345678.123456" || m.ThreadTS != "1712345000.000100" {
+		t.Errorf("wrong message: %+v", m)
+	}
+	if want := time.Unix(1712345678, 123456000); !m.Time().Equal(want) {
+		t.Errorf("wrong time: want %v, got %v", want, m.Time())
+	}
+	d := got[1]
+	if d.Subtype != "message_deleted" || d.DeletedTS != "1712345678.123456" || d.PreviousMessage == nil || d.PreviousMessage.ThreadTS != "1712345000.000100" {
+		t.Errorf("wrong deletion: %+v", d)
+	}
+}
+
+func TestPlain(t *testing.T) {
+	cases := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"hello", "hello"},
+		{"&lt;3 &amp; &gt;", "<3 & >"},
+		{"<@UBOT> hi", "@UBOT hi"},
+		{"see <https://example.org|example>", "see https://example.org"},
+		{"see <https://example.org>", "see https://example.org"},
+		{"in <#C123|general>", "in #general"},
+		{"<!here> look", "!here look"},
+	}
+	for _, c := range cases {
+		if got := Plain(c.in); got != c.want {
+			t.Errorf("wrong plain text for %q: want %q, got %q", c.in, c.want, got)
+		}
+	}
+}
+
+func TestPostMessage(t *testing.T) {
+	var auth string
+	var body map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/chat.postMessage":
+			auth = r.Header.Get("Authorization")
+			json.NewDecoder(r.Body).Decode(&body)
+			io.WriteString(w, `{"ok":true}`)
+		default:
+			io.WriteString(w, `{"ok":false,"error":"unknown_method"}`)
+		}
+	}))
+	defer srv.Close()
+	cl := Client{Token: "xoxb-bocchi", API: srv.URL}
+	if err := cl.PostMessage(context.Background(), "C123", "1712345000.000100", "hi"); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Bearer xoxb-bocchi" {
+		t.Errorf("wrong auth: %q", auth)
+	}
+	if body["channel"] != "C123" || body["thread_ts"] != "1712345000.000100" || body["text"] != "hi" {
+		t.Errorf("wrong body: %v", body)
+	}
+	if _, err := cl.AuthTest(context.Background()); err == nil {
+		t.Errorf("no error from failed call")
+	}
+}
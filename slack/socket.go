@@ -0,0 +1,124 @@
+package slack
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// OpenConnection gets a Socket Mode WebSocket URL using the app-level token.
+func (c *Client) OpenConnection(ctx context.Context) (string, error) {
+	var r struct {
+		URL string `json:"url"`
+	}
+	if err := c.call(ctx, "apps.connections.open", c.AppToken, struct{}{}, &r); err != nil {
+		return "", err
+	}
+	return r.URL, nil
+}
+
+// Event is a message event.
+type Event struct {
+	Type     string `json:"type"`
+	Subtype  string `json:"subtype"`
+	Channel  string `json:"channel"`
+	User     string `json:"user"`
+	BotID    string `json:"bot_id"`
+	Text     string `json:"text"`
+	TS       string `json:"ts"`
+	ThreadTS string `json:"thread_ts"`
+	// DeletedTS is the timestamp of the deleted message for message_deleted
+	// events.
+	DeletedTS string `json:"deleted_ts"`
+	// PreviousMessage is the deleted message for message_deleted events.
+	PreviousMessage *Event `json:"previous_message"`
+}
+
+// Time returns the time of the event from its timestamp.
+func (ev *Event) Time() time.Time {
+	return tsTime(ev.TS)
+}
+
+func tsTime(ts string) time.Time {
+	var sec, usec int64
+	fmt.Sscanf(ts, "%d.%d", &sec, &usec)
+	return time.Unix(sec, usec*1000)
+}
+
+// envelope is a Socket Mode message.
+type envelope struct {
+	Type       string `json:"type"`
+	EnvelopeID string `json:"envelope_id"`
+	Reason     string `json:"reason"`
+	Payload    struct {
+		Event Event `json:"event"`
+	} `json:"payload"`
+}
+
+// Listen opens a Socket Mode connection and sends message events to events
+// until ctx is canceled or the connection ends. Slack ends connections
+// periodically, so callers should reconnect when Listen returns.
+func (c *Client) Listen(ctx context.Context, events chan<- *Event) error {
+	u, err := c.OpenConnection(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't open Socket Mode connection: %w", err)
+	}
+	ws, err := websocket.Dial(u, "", "https://slack.com")
+	if err != nil {
+		return fmt.Errorf("couldn't connect to Socket Mode: %w", err)
+	}
+	defer ws.Close()
+	go func() {
+		<-ctx.Done()
+		ws.Close()
+	}()
+	for {
+		// Slack pings every few seconds, so a long silence means the
+		// connection is dead.
+		ws.SetReadDeadline(time.Now().Add(2 * time.Minute))
+		var env envelope
+		if err := websocket.JSON.Receive(ws, &env); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("couldn't read from Socket Mode: %w", err)
+		}
+		if env.EnvelopeID != "" {
+			// Acknowledge everything, or Slack retries it.
+			ack := map[string]string{"envelope_id": env.EnvelopeID}
+			if err := websocket.JSON.Send(ws, ack); err != nil {
+				return fmt.Errorf("couldn't acknowledge event: %w", err)
+			}
+		}
+		ev, done := parse(&env)
+		if done {
+			return nil
+		}
+		if ev == nil {
+			continue
+		}
+		select {
+		case events <- ev:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// parse returns the message event in an envelope, if any, and whether the
+// envelope asks to disconnect.
+func parse(env *envelope) (*Event, bool) {
+	switch env.Type {
+	case "disconnect":
+		return nil, true
+	case "events_api":
+		if env.Payload.Event.Type != "message" {
+			return nil, false
+		}
+		ev := env.Payload.Event
+		return &ev, false
+	}
+	return nil, false
+}
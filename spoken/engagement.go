@@ -0,0 +1,76 @@
+package spoken
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Engage records whether anyone engaged with a message the bot sent to a
+// channel at a given time.
+func (h *History) Engage(ctx context.Context, channel string, tm time.Time, engaged bool) error {
+	conn, err := h.db.Take(ctx)
+	defer h.db.Put(conn)
+	if err != nil {
+		return fmt.Errorf("couldn't get connection to record engagement: %w", err)
+	}
+	const insert = `INSERT INTO engagement (channel, time, engaged) VALUES (:channel, :time, :engaged)`
+	st, err := conn.Prepare(insert)
+	if err != nil {
+		return fmt.Errorf("couldn't prepare statement to record engagement: %w", err)
+	}
+	st.SetText(":channel", channel)
+	st.SetInt64(":time", tm.UnixNano())
+	st.SetBool(":engaged", engaged)
+	if _, err := st.Step(); err != nil {
+		return fmt.Errorf("couldn't record engagement: %w", err)
+	}
+	return nil
+}
+
+// Engagement counts the messages sent to a channel since a given time with
+// recorded engagement and how many of those anyone engaged with.
+func (h *History) Engagement(ctx context.Context, channel string, since time.Time) (sent, engaged int64, err error) {
+	conn, err := h.db.Take(ctx)
+	defer h.db.Put(conn)
+	if err != nil {
+		return 0, 0, fmt.Errorf("couldn't get connection to count engagement: %w", err)
+	}
+	const sel = `SELECT COUNT(*), COALESCE(SUM(engaged), 0) FROM engagement WHERE channel=:channel AND time>=:time`
+	st, err := conn.Prepare(sel)
+	if err != nil {
+		return 0, 0, fmt.Errorf("couldn't prepare statement to count engagement: %w", err)
+	}
+	st.SetText(":channel", channel)
+	st.SetInt64(":time", since.UnixNano())
+	if _, err := st.Step(); err != nil {
+		return 0, 0, fmt.Errorf("couldn't count engagement: %w", err)
+	}
+	sent, engaged = st.ColumnInt64(0), st.ColumnInt64(1)
+	// Clean up the statement.
+	st.Step()
+	return sent, engaged, nil
+}
+
+// Count counts the messages spoken under a tag since a given time.
+func (h *History) Count(ctx context.Context, tag string, since time.Time) (int64, error) {
+	conn, err := h.db.Take(ctx)
+	defer h.db.Put(conn)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't get connection to count spoken messages: %w", err)
+	}
+	const sel = `SELECT COUNT(*) FROM spoken WHERE tag=:tag AND time>=:time`
+	st, err := conn.Prepare(sel)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't prepare statement to count spoken messages: %w", err)
+	}
+	st.SetText(":tag", tag)
+	st.SetInt64(":time", since.UnixNano())
+	if _, err := st.Step(); err != nil {
+		return 0, fmt.Errorf("couldn't count spoken messages: %w", err)
+	}
+	n := st.ColumnInt64(0)
+	// Clean up the statement.
+	st.Step()
+	return n, nil
+}
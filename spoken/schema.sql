@@ -19,3 +19,14 @@ CREATE TABLE IF NOT EXISTS spoken (
 
 -- Covering index for lookup.
 CREATE INDEX IF NOT EXISTS traces ON spoken (tag, msg, time DESC, trace);
+
+CREATE TABLE IF NOT EXISTS engagement (
+	-- Channel in which the bot spoke.
+	channel TEXT NOT NULL,
+	-- Time the bot spoke as nanoseconds from the UNIX epoch.
+	time INTEGER NOT NULL,
+	-- Whether anyone engaged with the message, 0 or 1.
+	engaged INTEGER NOT NULL
+) STRICT;
+
+CREATE INDEX IF NOT EXISTS engagement_time ON engagement (channel, time);
@@ -160,3 +160,19 @@ func (h *History) Since(ctx context.Context, tag string, tm time.Time) iter.Seq2
 		}
 	}
 }
+
+// Retag moves the messages spoken under one tag to another.
+// It returns the number of messages moved.
+func (h *History) Retag(ctx context.Context, from, to string) (int64, error) {
+	conn, err := h.db.Take(ctx)
+	defer h.db.Put(conn)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't get conn to retag spoken messages: %w", err)
+	}
+	const upd = `UPDATE spoken SET tag = :to WHERE tag = :from`
+	opts := &sqlitex.ExecOptions{Named: map[string]any{":from": from, ":to": to}}
+	if err := sqlitex.Execute(conn, upd, opts); err != nil {
+		return 0, fmt.Errorf("couldn't retag spoken messages: %w", err)
+	}
+	return int64(conn.Changes()), nil
+}
@@ -278,3 +278,124 @@ func TestSince(t *testing.T) {
 		})
 	}
 }
+
+func TestEngagement(t *testing.T) {
+	ctx := context.Background()
+	h, err := spoken.Open(ctx, testDB())
+	if err != nil {
+		t.Fatal(err)
+	}
+	insert := []struct {
+		channel string
+		time    int64
+		engaged bool
+	}{
+		{"#bocchi", 10, true},
+		{"#bocchi", 20, false},
+		{"#ryou", 30, true},
+		{"#bocchi", 40, true},
+	}
+	for _, r := range insert {
+		if err := h.Engage(ctx, r.channel, time.Unix(0, r.time), r.engaged); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cases := []struct {
+		name    string
+		channel string
+		since   int64
+		sent    int64
+		engaged int64
+	}{
+		{"all", "#bocchi", 0, 3, 2},
+		{"since", "#bocchi", 15, 2, 1},
+		{"other", "#ryou", 0, 1, 1},
+		{"none", "#bocchi", 1000, 0, 0},
+		{"unknown", "#kita", 0, 0, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sent, engaged, err := h.Engagement(ctx, c.channel, time.Unix(0, c.since))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sent != c.sent || engaged != c.engaged {
+				t.Errorf("wrong engagement: want %d/%d, got %d/%d", c.engaged, c.sent, engaged, sent)
+			}
+		})
+	}
+}
+
+func TestCount(t *testing.T) {
+	ctx := context.Background()
+	h, err := spoken.Open(ctx, testDB())
+	if err != nil {
+		t.Fatal(err)
+	}
+	insert := []struct {
+		tag  string
+		time int64
+	}{
+		{"kessoku", 10},
+		{"kessoku", 20},
+		{"sick hack", 30},
+		{"kessoku", 40},
+	}
+	for _, r := range insert {
+		if err := h.Record(ctx, r.tag, "bocchi", []string{"1"}, time.Unix(0, r.time), 0, "bocchi", "", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cases := []struct {
+		name  string
+		tag   string
+		since int64
+		want  int64
+	}{
+		{"all", "kessoku", 0, 3},
+		{"since", "kessoku", 15, 2},
+		{"other", "sick hack", 0, 1},
+		{"none", "kessoku", 1000, 0},
+		{"unknown", "starry", 0, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := h.Count(ctx, c.tag, time.Unix(0, c.since))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.want {
+				t.Errorf("wrong count: want %d, got %d", c.want, got)
+			}
+		})
+	}
+}
+
+func TestRetag(t *testing.T) {
+	ctx := context.Background()
+	h, err := spoken.Open(ctx, testDB())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tag := range []string{"kessoku", "kessoku", "sick hack"} {
+		if err := h.Record(ctx, tag, "bocchi", []string{"1"}, time.Unix(0, int64(i)), 0, "bocchi", "", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n, err := h.Retag(ctx, "kessoku", "twitch/kessoku")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("wrong number of messages moved: want 2, got %d", n)
+	}
+	for tag, want := range map[string]int64{"kessoku": 0, "twitch/kessoku": 2, "sick hack": 1} {
+		got, err := h.Count(ctx, tag, time.Unix(0, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("wrong count under %q: want %d, got %d", tag, want, got)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Package sqlpool opens SQLite connection pools and retries operations which
+// fail because the database is busy.
+package sqlpool
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Options configures a pool.
+type Options struct {
+	// Size is the number of connections in the pool.
+	// If it is not positive, the pool uses a default size.
+	Size int
+	// BusyTimeout is the longest a connection waits for a lock before
+	// failing with SQLITE_BUSY. If it is not positive, connections wait until
+	// their operations' contexts are canceled.
+	BusyTimeout time.Duration
+}
+
+// Open opens a connection pool. prep may be nil.
+func Open(dsn string, opts Options, prep sqlitex.ConnPrepareFunc) (*sqlitex.Pool, error) {
+	f := func(conn *sqlite.Conn) error {
+		if opts.BusyTimeout > 0 {
+			conn.SetBusyTimeout(opts.BusyTimeout)
+		}
+		if prep != nil {
+			return prep(conn)
+		}
+		return nil
+	}
+	return sqlitex.NewPool(dsn, sqlitex.PoolOptions{PoolSize: opts.Size, PrepareConn: f})
+}
+
+// Busy reports whether err is due to another connection holding a lock.
+func Busy(err error) bool {
+	switch sqlite.ErrCode(err).ToPrimary() {
+	case sqlite.ResultBusy, sqlite.ResultLocked:
+		return true
+	default:
+		return false
+	}
+}
+
+// Retrier retries operations which fail because the database is busy.
+// A nil Retrier runs operations once.
+type Retrier struct {
+	// tries is the number of attempts at each operation.
+	tries int
+	// wait is the time to wait before the first retry.
+	// Each subsequent retry waits twice as long as the last.
+	wait time.Duration
+
+	busy     atomic.Int64
+	retries  atomic.Int64
+	failures atomic.Int64
+}
+
+// NewRetrier creates a Retrier which attempts each operation up to tries
+// times, waiting wait before the first retry and doubling each time after.
+func NewRetrier(tries int, wait time.Duration) *Retrier {
+	return &Retrier{tries: max(tries, 1), wait: wait}
+}
+
+// Stats is a snapshot of lock contention seen by a Retrier.
+type Stats struct {
+	// Busy is the number of attempts which found the database busy.
+	Busy int64 `json:"busy"`
+	// Retries is the number of attempts after the first.
+	Retries int64 `json:"retries"`
+	// Failures is the number of operations which were still busy after all
+	// attempts.
+	Failures int64 `json:"failures"`
+}
+
+// Stats returns a snapshot of the retrier's counters.
+func (r *Retrier) Stats() Stats {
+	return Stats{
+		Busy:     r.busy.Load(),
+		Retries:  r.retries.Load(),
+		Failures: r.failures.Load(),
+	}
+}
+
+// Do calls f until it returns an error other than a busy error, it has been
+// attempted as many times as allowed, or ctx is canceled.
+// f must have no effect when it fails, e.g. by running in a transaction.
+func (r *Retrier) Do(ctx context.Context, f func() error) error {
+	if r == nil {
+		return f()
+	}
+	wait := r.wait
+	for k := 1; ; k++ {
+		err := f()
+		if !Busy(err) {
+			return err
+		}
+		r.busy.Add(1)
+		if k >= r.tries {
+			r.failures.Add(1)
+			return err
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			r.failures.Add(1)
+			return err
+		case <-t.C:
+		}
+		wait *= 2
+		r.retries.Add(1)
+	}
+}
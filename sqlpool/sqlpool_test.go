@@ -0,0 +1,83 @@
+package sqlpool_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/zephyrtronium/robot/sqlpool"
+)
+
+func TestRetrier(t *testing.T) {
+	ctx := context.Background()
+	dsn := "file:" + filepath.ToSlash(filepath.Join(t.TempDir(), "busy.db"))
+	db, err := sqlpool.Open(dsn, sqlpool.Options{Size: 2, BusyTimeout: time.Millisecond}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	a, err := db.Take(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Put(a)
+	b, err := db.Take(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Put(b)
+	if err := sqlitex.ExecuteTransient(a, `CREATE TABLE t (x INTEGER)`, nil); err != nil {
+		t.Fatal(err)
+	}
+	// Hold the write lock on a so that b is busy.
+	if err := sqlitex.ExecuteTransient(a, `BEGIN IMMEDIATE`, nil); err != nil {
+		t.Fatal(err)
+	}
+	insert := func() error { return sqlitex.ExecuteTransient(b, `INSERT INTO t (x) VALUES (1)`, nil) }
+
+	r := sqlpool.NewRetrier(3, time.Millisecond)
+	err = r.Do(ctx, insert)
+	if !sqlpool.Busy(err) {
+		t.Errorf("expected busy error, got %v", err)
+	}
+	if got, want := r.Stats(), (sqlpool.Stats{Busy: 3, Retries: 2, Failures: 1}); got != want {
+		t.Errorf("wrong stats after failure: want %+v, got %+v", want, got)
+	}
+
+	// Release the lock partway through retrying.
+	calls := 0
+	err = r.Do(ctx, func() error {
+		calls++
+		if calls == 2 {
+			if err := sqlitex.ExecuteTransient(a, `COMMIT`, nil); err != nil {
+				return fmt.Errorf("couldn't commit: %w", err)
+			}
+		}
+		return insert()
+	})
+	if err != nil {
+		t.Errorf("couldn't insert after lock released: %v", err)
+	}
+	if got, want := r.Stats(), (sqlpool.Stats{Busy: 4, Retries: 3, Failures: 1}); got != want {
+		t.Errorf("wrong stats after success: want %+v, got %+v", want, got)
+	}
+
+	// Other errors aren't retried.
+	other := errors.New("other")
+	calls = 0
+	err = r.Do(ctx, func() error { calls++; return other })
+	if err != other || calls != 1 {
+		t.Errorf("wrong result of other error: %v after %d calls", err, calls)
+	}
+	// Nil retriers try once.
+	calls = 0
+	(*sqlpool.Retrier)(nil).Do(ctx, func() error { calls++; return insert() })
+	if calls != 1 {
+		t.Errorf("nil retrier made %d calls", calls)
+	}
+}
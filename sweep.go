@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/jobs"
+	"github.com/zephyrtronium/robot/journal"
+)
+
+// SetSweep sets the interval at which the robot enforces storage quotas.
+// If it is not positive, quotas are enforced hourly.
+func (robo *Robot) SetSweep(every float64) {
+	robo.sweepEvery = fseconds(every)
+	if robo.sweepEvery <= 0 {
+		robo.sweepEvery = time.Hour
+	}
+}
+
+// SetUndelete sets the time in seconds for which forgotten knowledge can be
+// restored before it is purged. If it is not positive, forgotten knowledge is
+// kept indefinitely.
+func (robo *Robot) SetUndelete(window float64) {
+	robo.undeleteWindow = fseconds(window)
+}
+
+// addQuota limits the knowledge under a tag. If the tag already has a quota,
+// the tighter of each limit applies.
+func (robo *Robot) addQuota(tag string, q brain.Quota) {
+	if tag == "" || q.Tuples <= 0 && q.Bytes <= 0 {
+		return
+	}
+	if robo.quotas == nil {
+		robo.quotas = make(map[string]brain.Quota)
+	}
+	robo.quotas[tag] = tighter(robo.quotas[tag], q)
+}
+
+// tighter combines two quotas into the strictest of each limit.
+func tighter(a, b brain.Quota) brain.Quota {
+	least := func(x, y int64) int64 {
+		switch {
+		case x <= 0:
+			return y
+		case y <= 0:
+			return x
+		default:
+			return min(x, y)
+		}
+	}
+	return brain.Quota{Tuples: least(a.Tuples, b.Tuples), Bytes: least(a.Bytes, b.Bytes)}
+}
+
+// checkQuotas returns an error if storage quotas are configured but the brain
+// can't enforce them, so that they aren't silently ignored.
+func (robo *Robot) checkQuotas() error {
+	if len(robo.quotas) == 0 {
+		return nil
+	}
+	if _, ok := brain.As[brain.Evicter](robo.brain); !ok {
+		return errors.New("brain doesn't support evicting, so it can't enforce channel quotas")
+	}
+	return nil
+}
+
+// addSweepJobs adds jobs to enforce storage quotas and purge forgotten
+// knowledge, if the brain supports them and they are configured.
+func (robo *Robot) addSweepJobs(ctx context.Context) {
+	if ev, ok := brain.As[brain.Evicter](robo.brain); ok && len(robo.quotas) != 0 {
+		evicted := new(expvar.Map)
+		robo.metrics.Set("evicted", evicted)
+		robo.jobs.Add(jobs.Job{
+			Name:  "evict",
+			Every: robo.sweepEvery,
+			Fn: func(ctx context.Context, after string) (string, error) {
+				return robo.sweepOnce(ctx, ev, evicted, after)
+			},
+		})
+	}
+	un, ok := brain.As[brain.Undeleter](robo.brain)
+	if ok && robo.undeleteWindow > 0 {
+		robo.jobs.Add(jobs.Job{
+			Name:  "purge",
+			Every: robo.sweepEvery,
+			Fn: func(ctx context.Context, _ string) (string, error) {
+				return "", robo.purgeOnce(ctx, un, time.Now())
+			},
+		})
+	}
+}
+
+// sweepOnce evicts knowledge beyond each tag's quota, in order of tag,
+// starting after the tag after. If it is interrupted, it returns the last tag
+// it finished as the checkpoint to resume from. Failures on some tags don't
+// stop it from enforcing the others' quotas.
+func (robo *Robot) sweepOnce(ctx context.Context, ev brain.Evicter, evicted *expvar.Map, after string) (string, error) {
+	var errs []error
+	for _, tag := range slices.Sorted(maps.Keys(robo.quotas)) {
+		if tag <= after {
+			continue
+		}
+		if ctx.Err() != nil {
+			return after, ctx.Err()
+		}
+		n, err := ev.Evict(ctx, tag, robo.quotas[tag])
+		if err != nil {
+			if ctx.Err() != nil {
+				return after, ctx.Err()
+			}
+			errs = append(errs, fmt.Errorf("couldn't enforce quota on %s: %w", tag, err))
+		}
+		if n > 0 {
+			slog.InfoContext(ctx, "evicted messages over quota", slog.String("tag", tag), slog.Int("count", n))
+			evicted.Add(tag, int64(n))
+			robo.recordOp(ctx, journal.Entry{
+				Op:     brain.NewOp(),
+				Time:   time.Now(),
+				Actor:  "sweep",
+				Tag:    tag,
+				Action: "evict",
+				Count:  int64(n),
+			})
+		}
+		after = tag
+	}
+	return "", errors.Join(errs...)
+}
+
+// purgeOnce permanently removes knowledge forgotten longer ago than the
+// undelete window.
+func (robo *Robot) purgeOnce(ctx context.Context, un brain.Undeleter, now time.Time) error {
+	before := now.Add(-robo.undeleteWindow)
+	n, err := un.Purge(ctx, before)
+	if err != nil {
+		return fmt.Errorf("couldn't purge forgotten knowledge: %w", err)
+	}
+	if n > 0 {
+		slog.InfoContext(ctx, "purged forgotten messages", slog.Int("count", n))
+		robo.recordOp(ctx, journal.Entry{
+			Op:     brain.NewOp(),
+			Time:   now,
+			Actor:  "sweep",
+			Action: "purge",
+			Count:  int64(n),
+			Params: map[string]string{"before": before.Format(time.RFC3339)},
+		})
+	}
+	return nil
+}
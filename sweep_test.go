@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/brain/membrain"
+	"github.com/zephyrtronium/robot/userhash"
+)
+
+func TestTighter(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b brain.Quota
+		want brain.Quota
+	}{
+		{"none", brain.Quota{}, brain.Quota{}, brain.Quota{}},
+		{"one", brain.Quota{Tuples: 10}, brain.Quota{}, brain.Quota{Tuples: 10}},
+		{"other", brain.Quota{}, brain.Quota{Bytes: 10}, brain.Quota{Bytes: 10}},
+		{"mixed", brain.Quota{Tuples: 10}, brain.Quota{Bytes: 20}, brain.Quota{Tuples: 10, Bytes: 20}},
+		{"least", brain.Quota{Tuples: 10, Bytes: 5}, brain.Quota{Tuples: 5, Bytes: 10}, brain.Quota{Tuples: 5, Bytes: 5}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := tighter(c.a, c.b); got != c.want {
+				t.Errorf("wrong quota: want %+v, got %+v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestSweepOnce(t *testing.T) {
+	ctx := context.Background()
+	br := membrain.New()
+	msgs := []struct {
+		tag, id, text string
+	}{
+		{"kessoku", "1", "bocchi plays guitar"},
+		{"kessoku", "2", "nijika plays drums"},
+		{"sickhack", "3", "kikuri plays bass"},
+	}
+	for i, m := range msgs {
+		if err := brain.Learn(ctx, br, m.tag, m.id, userhash.Hash{}, time.Unix(int64(i), 0), brain.Tokens(nil, m.text)); err != nil {
+			t.Fatalf("couldn't learn %q: %v", m.text, err)
+		}
+	}
+	robo := New(1)
+	robo.addQuota("kessoku", brain.Quota{Tuples: 6})
+	robo.addQuota("kessoku", brain.Quota{Tuples: 4})
+	robo.addQuota("sickhack", brain.Quota{})
+	if len(robo.quotas) != 1 {
+		t.Errorf("wrong quotas: %v", robo.quotas)
+	}
+	evicted := new(expvar.Map)
+	if cp, err := robo.sweepOnce(ctx, br, evicted, ""); cp != "" || err != nil {
+		t.Errorf("sweep didn't finish: checkpoint %q, err %v", cp, err)
+	}
+	if s, _ := br.Recall(ctx, "kessoku", "1"); s != "" {
+		t.Errorf("oldest message over quota still known: %q", s)
+	}
+	if s, _ := br.Recall(ctx, "kessoku", "2"); s == "" {
+		t.Errorf("newer message within quota evicted")
+	}
+	if s, _ := br.Recall(ctx, "sickhack", "3"); s == "" {
+		t.Errorf("message without quota evicted")
+	}
+	if v, ok := evicted.Get("kessoku").(*expvar.Int); !ok || v.Value() != 1 {
+		t.Errorf("wrong eviction count: %v", evicted.Get("kessoku"))
+	}
+}
+
+func TestSweepResume(t *testing.T) {
+	ctx := context.Background()
+	br := membrain.New()
+	for i, text := range []string{"bocchi plays guitar", "nijika plays drums"} {
+		if err := brain.Learn(ctx, br, "kessoku", fmt.Sprint(i), userhash.Hash{}, time.Unix(int64(i), 0), brain.Tokens(nil, text)); err != nil {
+			t.Fatalf("couldn't learn %q: %v", text, err)
+		}
+	}
+	robo := New(1)
+	robo.addQuota("kessoku", brain.Quota{Tuples: 4})
+	// Resuming after the tag skips it.
+	if cp, err := robo.sweepOnce(ctx, br, new(expvar.Map), "kessoku"); cp != "" || err != nil {
+		t.Errorf("sweep didn't finish: checkpoint %q, err %v", cp, err)
+	}
+	if s, _ := br.Recall(ctx, "kessoku", "0"); s == "" {
+		t.Errorf("message evicted from tag before checkpoint")
+	}
+}
+
+type undeleter struct {
+	before time.Time
+}
+
+func (u *undeleter) Undelete(ctx context.Context, op string) (int, error)  { return 0, nil }
+func (u *undeleter) Forgotten(ctx context.Context, op string) (int, error) { return 0, nil }
+
+func (u *undeleter) Purge(ctx context.Context, before time.Time) (int, error) {
+	u.before = before
+	return 1, nil
+}
+
+func TestPurgeOnce(t *testing.T) {
+	robo := New(1)
+	robo.SetUndelete(3600)
+	var u undeleter
+	now := time.Unix(10000, 0)
+	if err := robo.purgeOnce(context.Background(), &u, now); err != nil {
+		t.Errorf("couldn't purge: %v", err)
+	}
+	if want := time.Unix(6400, 0); !u.before.Equal(want) {
+		t.Errorf("wrong purge time: want %v, got %v", want, u.before)
+	}
+}
+
+func TestCheckQuotas(t *testing.T) {
+	robo := New(1)
+	// Hide the brain's capabilities.
+	robo.brain = struct{ brain.Brain }{membrain.New()}
+	if err := robo.checkQuotas(); err != nil {
+		t.Errorf("error without quotas: %v", err)
+	}
+	robo.addQuota("kessoku", brain.Quota{Tuples: 4})
+	if err := robo.checkQuotas(); err == nil {
+		t.Error("no error with quotas on a brain that can't evict")
+	}
+	robo.brain = membrain.New()
+	if err := robo.checkQuotas(); err != nil {
+		t.Errorf("error with quotas on a brain that can evict: %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/zephyrtronium/robot/brain"
+	"github.com/zephyrtronium/robot/journal"
+	"github.com/zephyrtronium/robot/spoken"
+)
+
+/*
+Tags are named by a namespace and a name separated by a slash, like
+twitch/bocchi. Channels learn into the namespace of their own platform, so
+that channels with the same name on different platforms never share knowledge
+by accident. Knowledge shared on purpose between platforms goes in the shared
+namespace instead, and views are in the view namespace.
+*/
+
+const (
+	// tagShared is the namespace of tags which channels on any platform may
+	// learn into.
+	tagShared = "shared"
+	// tagView is the namespace of views.
+	tagView = "view"
+)
+
+// tagNamespaces is the namespaces which tags may use.
+var tagNamespaces = []string{"twitch", "matrix", "telegram", "kick", "slack", "discord", "logs", tagShared, tagView}
+
+// checkTag checks that a tag is a namespace and name separated by a slash.
+func checkTag(tag string) error {
+	ns, name, ok := strings.Cut(tag, "/")
+	switch {
+	case !ok:
+		return fmt.Errorf("tag %q has no namespace", tag)
+	case !slices.Contains(tagNamespaces, ns):
+		return fmt.Errorf("tag %q has unknown namespace %q", tag, ns)
+	case name == "":
+		return fmt.Errorf("tag %q has no name", tag)
+	case strings.ContainsFunc(tag, unicode.IsSpace):
+		return fmt.Errorf("tag %q contains spaces", tag)
+	}
+	return nil
+}
+
+// checkLearnTag checks that a tag is one into which channels on a platform
+// may learn.
+func checkLearnTag(platform, tag string) error {
+	if !strings.Contains(tag, "/") {
+		return fmt.Errorf("tag %q has no namespace; move its knowledge with: robot retag --from %s --to %s/%s", tag, tag, platform, tag)
+	}
+	if err := checkTag(tag); err != nil {
+		return err
+	}
+	if ns, _, _ := strings.Cut(tag, "/"); ns != platform && ns != tagShared {
+		return fmt.Errorf("tag %q is not in the %s or %s namespace", tag, platform, tagShared)
+	}
+	return nil
+}
+
+// checkTags checks the tags named throughout a config.
+// Empty tags are left to the options which use them.
+func checkTags(cfg *Config) error {
+	var errs []error
+	check := func(where string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", where, err))
+		}
+	}
+	for _, g := range channelGroups(cfg) {
+		for _, nm := range slices.Sorted(maps.Keys(g.channels)) {
+			ch := g.channels[nm]
+			where := g.key + "." + nm
+			if ch.Learn != "" {
+				check(where+".learn", checkLearnTag(g.platform, ch.Learn))
+			}
+			if ch.Send != "" {
+				check(where+".send", checkTag(ch.Send))
+			}
+			for _, tag := range ch.Tags {
+				check(where+".tags", checkTag(tag))
+			}
+		}
+	}
+	for _, nm := range slices.Sorted(maps.Keys(cfg.View)) {
+		err := checkTag(nm)
+		if ns, _, _ := strings.Cut(nm, "/"); err == nil && ns != tagView {
+			err = fmt.Errorf("view %q is not in the %s namespace", nm, tagView)
+		}
+		check("view."+nm, err)
+		for _, tag := range cfg.View[nm].Tags {
+			check("view."+nm+".tags", checkTag(tag))
+		}
+	}
+	for _, nm := range slices.Sorted(maps.Keys(cfg.Poster)) {
+		if tag := cfg.Poster[nm].Tag; tag != "" {
+			check("poster."+nm+".tag", checkTag(tag))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func cliRetag(ctx context.Context, cmd *cli.Command) error {
+	from, to := cmd.String("from"), cmd.String("to")
+	if err := checkTag(to); err != nil {
+		return err
+	}
+	db, err := cliDBs(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	br, done, err := openBrain(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer done()
+	rt, ok := brain.As[brain.Retagger](br)
+	if !ok {
+		return errors.New("brain does not support retagging")
+	}
+	j, err := journal.Open(ctx, db.priv)
+	if err != nil {
+		return fmt.Errorf("couldn't open operation journal: %w", err)
+	}
+	n, err := rt.Retag(ctx, from, to)
+	if err != nil {
+		return err
+	}
+	e := journal.Entry{
+		Op:     brain.NewOp(),
+		Time:   time.Now(),
+		Actor:  "cli",
+		Tag:    from,
+		Action: "retag",
+		Count:  n,
+		Params: map[string]string{"to": to},
+	}
+	if err := j.Record(ctx, e); err != nil {
+		return fmt.Errorf("moved %d messages but couldn't record it: %w", n, err)
+	}
+	fmt.Printf("moved %d messages from %s to %s\n", n, from, to)
+	h, err := spoken.Open(ctx, db.spoke)
+	if err != nil {
+		return fmt.Errorf("couldn't open spoken history: %w", err)
+	}
+	s, err := h.Retag(ctx, from, to)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("moved %d spoken messages\n", s)
+	return nil
+}
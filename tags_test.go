@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCheckTags(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  Config
+		bad  []string
+	}{
+		{
+			name: "namespaced",
+			cfg: Config{
+				Twitch:   map[string]*ChannelCfg{"bocchi": {Learn: "twitch/bocchi", Send: "view/kessoku", Tags: []string{"kick/bocchi"}}},
+				Kick:     KickCfg{Channels: map[string]*ChannelCfg{"bocchi": {Learn: "shared/bocchi", Send: "shared/bocchi"}}},
+				Logs:     LogsCfg{Channels: map[string]*ChannelCfg{"bocchi": {Learn: "logs/bocchi"}}},
+				View:     map[string]*ViewCfg{"view/kessoku": {Tags: []string{"twitch/bocchi", "shared/bocchi"}}},
+				Poster:   map[string]*PosterCfg{"bocchi": {Tag: "twitch/bocchi"}},
+				Telegram: TelegramCfg{Groups: map[string]*ChannelCfg{"bocchi": {Learn: "telegram/ぼっち"}}},
+			},
+		},
+		{
+			name: "legacy",
+			cfg: Config{
+				Twitch: map[string]*ChannelCfg{"bocchi": {Learn: "bocchi", Send: "bocchi", Tags: []string{"kessoku"}}},
+				View:   map[string]*ViewCfg{"everyone": {Tags: []string{"bocchi"}}},
+				Poster: map[string]*PosterCfg{"bocchi": {Tag: "bocchi"}},
+			},
+			bad: []string{
+				"twitch.bocchi.learn: tag \"bocchi\" has no namespace; move its knowledge with: robot retag --from bocchi --to twitch/bocchi",
+				"twitch.bocchi.send",
+				"twitch.bocchi.tags",
+				"view.everyone:",
+				"view.everyone.tags",
+				"poster.bocchi.tag",
+			},
+		},
+		{
+			name: "other-platform",
+			cfg: Config{
+				Matrix: MatrixCfg{Rooms: map[string]*ChannelCfg{"kessoku": {Learn: "twitch/kessoku"}}},
+				Slack:  SlackCfg{Channels: map[string]*ChannelCfg{"kessoku": {Learn: "view/kessoku"}}},
+			},
+			bad: []string{"matrix.rooms.kessoku.learn", "slack.channels.kessoku.learn"},
+		},
+		{
+			name: "malformed",
+			cfg: Config{
+				Twitch: map[string]*ChannelCfg{"bocchi": {Learn: "twitch/", Send: "youtube/bocchi", Tags: []string{"twitch/bocchi the rock"}}},
+				View:   map[string]*ViewCfg{"shared/everyone": {}},
+			},
+			bad: []string{"twitch.bocchi.learn", "twitch.bocchi.send", "twitch.bocchi.tags", "view.shared/everyone"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := checkTags(&c.cfg)
+			if len(c.bad) == 0 {
+				if err != nil {
+					t.Errorf("valid tags were rejected: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("invalid tags were accepted")
+			}
+			lines := strings.Split(err.Error(), "\n")
+			if len(lines) != len(c.bad) {
+				t.Errorf("wrong number of errors: want %d, got %q", len(c.bad), lines)
+			}
+			for _, want := range c.bad {
+				found := false
+				for _, l := range lines {
+					found = found || strings.HasPrefix(l, want)
+				}
+				if !found {
+					t.Errorf("no error for %s in %q", want, lines)
+				}
+			}
+		})
+	}
+}
+
+func TestExampleTags(t *testing.T) {
+	f, err := os.Open("example.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	cfg, _, err := Load(context.Background(), f, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkTags(cfg); err != nil {
+		t.Errorf("example config has invalid tags: %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zephyrtronium/robot/message"
+	"github.com/zephyrtronium/robot/platform"
+	"github.com/zephyrtronium/robot/telegram"
+)
+
+// telegramClient is the bot's connection to the Telegram Bot API.
+type telegramClient struct {
+	cl *telegram.Client
+	// name is the bot's username, used to recognize commands.
+	name string
+	// owner is the user ID of the owner.
+	owner string
+	// id is the bot's user ID.
+	id string
+	// mu guards threads.
+	mu sync.Mutex
+	// threads is the forum topic of the latest message in each chat, or zero
+	// if it was outside any topic, so that messages which reply to nothing
+	// go where the conversation is.
+	threads map[string]int64
+}
+
+var _ platform.Client = (*telegramClient)(nil)
+
+func (tc *telegramClient) Platform() string { return "telegram" }
+
+func (tc *telegramClient) Bot() (id, name string) { return tc.id, tc.name }
+
+func (tc *telegramClient) Owner() string { return tc.owner }
+
+func (tc *telegramClient) Capabilities() platform.Capabilities {
+	return platform.Capabilities{Replies: true, Format: platform.Format{Newlines: true}}
+}
+
+// Send sends a message to a chat. Replies are IDs of the form chat/message,
+// or chat/message/topic for messages in forum topics, and go to the topic of
+// the message they reply to. Other messages go to the topic of the latest
+// message in the chat.
+func (tc *telegramClient) Send(ctx context.Context, channel, reply, text string) error {
+	if reply == "" {
+		tc.mu.Lock()
+		thread := tc.threads[channel]
+		tc.mu.Unlock()
+		return tc.cl.SendMessage(ctx, channel, 0, thread, text)
+	}
+	_, r, _ := strings.Cut(reply, "/")
+	r, t, _ := strings.Cut(r, "/")
+	id, _ := strconv.ParseInt(r, 10, 64)
+	thread, _ := strconv.ParseInt(t, 10, 64)
+	return tc.cl.SendMessage(ctx, channel, id, thread, text)
+}
+
+// telegramID formats the ID of a message in a chat. Messages in forum topics
+// carry the topic so that replies can go to it.
+func telegramID(chat string, msg *telegram.Message) string {
+	id := chat + "/" + strconv.FormatInt(msg.ID, 10)
+	if msg.IsTopic && msg.Thread != 0 {
+		id += "/" + strconv.FormatInt(msg.Thread, 10)
+	}
+	return id
+}
+
+// InitTelegram checks the Telegram bot token.
+func (robo *Robot) InitTelegram(ctx context.Context, cfg TelegramCfg) error {
+	cl := &telegram.Client{Token: cfg.Token}
+	me, err := cl.GetMe(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't get Telegram bot: %w", err)
+	}
+	slog.InfoContext(ctx, "Telegram bot", slog.Int64("id", me.ID), slog.String("username", me.Username))
+	robo.telegram = &telegramClient{
+		cl:      cl,
+		name:    me.Username,
+		owner:   cfg.Owner,
+		id:      strconv.FormatInt(me.ID, 10),
+		threads: make(map[string]int64),
+	}
+	return nil
+}
+
+// SetTelegramGroups initializes Telegram group configuration.
+// It must be called after InitTelegram.
+func (robo *Robot) SetTelegramGroups(ctx context.Context, global Global, groups map[string]*ChannelCfg) error {
+	return robo.setChannels(ctx, global, robo.telegram, global.Privileges.Telegram, groups)
+}
+
+func (tc *telegramClient) Run(ctx context.Context, h platform.Handler) error {
+	var offset int64
+	for {
+		u, err := tc.cl.GetUpdates(ctx, offset, 30*time.Second)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			slog.ErrorContext(ctx, "Telegram updates failed", slog.Any("err", err))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+		for _, up := range u {
+			offset = max(offset, up.ID+1)
+			if up.Message != nil {
+				tc.message(ctx, h, up.Message)
+			}
+		}
+	}
+}
+
+// message processes a message from Telegram.
+func (tc *telegramClient) message(ctx context.Context, h platform.Handler, msg *telegram.Message) {
+	if msg.From == nil || msg.From.IsBot || msg.Text == "" {
+		return
+	}
+	chat := strconv.FormatInt(msg.Chat.ID, 10)
+	name := msg.From.Username
+	if name == "" {
+		name = msg.From.FirstName
+	}
+	var thread int64
+	if msg.IsTopic {
+		thread = msg.Thread
+	}
+	tc.mu.Lock()
+	tc.threads[chat] = thread
+	tc.mu.Unlock()
+	m := message.Incoming{
+		// Message IDs are only unique within a chat.
+		ID:        telegramID(chat, msg),
+		To:        chat,
+		Sender:    strconv.FormatInt(msg.From.ID, 10),
+		Name:      name,
+		Text:      msg.Text,
+		Timestamp: msg.Time().UnixMilli(),
+		Raw:       msg,
+	}
+	if msg.ReplyTo != nil {
+		m.ReplyParent = telegramID(chat, msg.ReplyTo)
+	}
+	h.Message(ctx, tc, &m)
+}
@@ -0,0 +1,222 @@
+// Package telegram implements the parts of the Telegram Bot API that Robot
+// uses.
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxLength is the maximum length of a message text in UTF-16 code units.
+const MaxLength = 4096
+
+// Client holds the context for requests to the Bot API.
+type Client struct {
+	// HTTP is the HTTP client for performing requests.
+	// If nil, http.DefaultClient is used.
+	HTTP *http.Client
+	// Token is the bot token.
+	Token string
+	// API is the base URL of the Bot API. If it is empty,
+	// https://api.telegram.org is used.
+	API string
+}
+
+// call calls a Bot API method and decodes its result.
+func (c *Client) call(ctx context.Context, method string, params, u any) error {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("couldn't encode request: %w", err)
+	}
+	api := c.API
+	if api == "" {
+		api = "https://api.telegram.org"
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", api+"/bot"+c.Token+"/"+method, bytes.NewReader(b))
+	if err != nil {
+		// Don't wrap the error, because it would include the token.
+		return fmt.Errorf("couldn't make request for %s", method)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	hc := c.HTTP
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("couldn't call %s: %w", method, stripToken(err, c.Token))
+	}
+	b, err = io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("couldn't read response: %w", err)
+	}
+	r := struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+		Result      any    `json:"result"`
+	}{Result: u}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return fmt.Errorf("couldn't decode %s response (%s): %w", method, resp.Status, err)
+	}
+	if !r.OK {
+		return fmt.Errorf("%s failed: %s (%s)", method, r.Description, resp.Status)
+	}
+	return nil
+}
+
+// stripToken replaces the token in an error, since URL errors include the
+// full URL.
+func stripToken(err error, tok string) error {
+	if tok == "" || !strings.Contains(err.Error(), tok) {
+		return err
+	}
+	return fmt.Errorf("%s", strings.ReplaceAll(err.Error(), tok, "<token>"))
+}
+
+// User is a Telegram user or bot.
+type User struct {
+	ID        int64  `json:"id"`
+	IsBot     bool   `json:"is_bot"`
+	FirstName string `json:"first_name"`
+	Username  string `json:"username"`
+}
+
+// Chat is a Telegram chat.
+type Chat struct {
+	ID    int64  `json:"id"`
+	Type  string `json:"type"`
+	Title string `json:"title"`
+}
+
+// Message is a Telegram message.
+type Message struct {
+	ID       int64    `json:"message_id"`
+	Thread   int64    `json:"message_thread_id"`
+	From     *User    `json:"from"`
+	Chat     Chat     `json:"chat"`
+	Date     int64    `json:"date"`
+	Text     string   `json:"text"`
+	ReplyTo  *Message `json:"reply_to_message"`
+	IsTopic  bool     `json:"is_topic_message"`
+	Entities []struct {
+		Type   string `json:"type"`
+		Offset int    `json:"offset"`
+		Length int    `json:"length"`
+	} `json:"entities"`
+}
+
+// Time returns the time the message was sent.
+func (m *Message) Time() time.Time {
+	return time.Unix(m.Date, 0)
+}
+
+// Update is an incoming update.
+type Update struct {
+	ID      int64    `json:"update_id"`
+	Message *Message `json:"message"`
+}
+
+// GetMe returns the bot's own user.
+func (c *Client) GetMe(ctx context.Context) (*User, error) {
+	var u User
+	if err := c.call(ctx, "getMe", struct{}{}, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// GetUpdates waits up to timeout for updates with IDs at least offset.
+func (c *Client) GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]Update, error) {
+	params := map[string]any{
+		"offset":          offset,
+		"timeout":         int(timeout.Seconds()),
+		"allowed_updates": []string{"message"},
+	}
+	var u []Update
+	if err := c.call(ctx, "getUpdates", params, &u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// SendMessage sends a text message to a chat, optionally in reply to another
+// message or in a forum topic. The text is split into multiple messages if it
+// is too long.
+func (c *Client) SendMessage(ctx context.Context, chat string, reply, thread int64, text string) error {
+	for _, s := range Split(text, MaxLength) {
+		params := map[string]any{
+			"chat_id": chat,
+			"text":    s,
+		}
+		if thread != 0 {
+			params["message_thread_id"] = thread
+		}
+		if reply != 0 {
+			params["reply_parameters"] = map[string]any{
+				"message_id":                  reply,
+				"allow_sending_without_reply": true,
+			}
+			// Only the first part is a reply.
+			reply = 0
+		}
+		if err := c.call(ctx, "sendMessage", params, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Split divides text into parts of at most n UTF-16 code units, preferring
+// to break at spaces.
+func Split(text string, n int) []string {
+	var r []string
+	for utf16len(text) > n {
+		// Find the longest prefix that fits.
+		k, l := 0, 0
+		for i, c := range text {
+			w := utf16width(c)
+			if l+w > n {
+				break
+			}
+			l += w
+			k = i + utf8.RuneLen(c)
+		}
+		cut := k
+		if c, _ := utf8.DecodeRuneInString(text[k:]); !unicode.IsSpace(c) {
+			// Break at the last space instead of in the middle of a word.
+			if sp := strings.LastIndexFunc(text[:k], unicode.IsSpace); sp > 0 {
+				cut = sp
+			}
+		}
+		r = append(r, strings.TrimSpace(text[:cut]))
+		text = strings.TrimLeftFunc(text[cut:], unicode.IsSpace)
+	}
+	if text != "" || len(r) == 0 {
+		r = append(r, text)
+	}
+	return r
+}
+
+func utf16len(s string) int {
+	n := 0
+	for _, c := range s {
+		n += utf16width(c)
+	}
+	return n
+}
+
+func utf16width(c rune) int {
+	if c >= 0x10000 {
+		return 2
+	}
+	return 1
+}
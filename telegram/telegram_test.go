@@ -0,0 +1,119 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGetUpdates(t *testing.T) {
+	var path string
+	var params map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		b, _ := io.ReadAll(r.Body)
+		json.Unmarshal(b, &params)
+		http.ServeFile(w, r, "testdata/updates.json")
+	}))
+	defer srv.Close()
+	cl := Client{Token: "123:abc", API: srv.URL}
+	u, err := cl.GetUpdates(context.Background(), 10, 30*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/bot123:abc/getUpdates" {
+		t.Errorf("wrong path: %s", path)
+	}
+	if params["offset"] != 10.0 || params["timeout"] != 30.0 {
+		t.Errorf("wrong params: %v", params)
+	}
+	if len(u) != 2 {
+		t.Fatalf("wrong number of updates: want 2, got %d", len(u))
+	}
+	m := u[0].Message
+	if m.ID != 42 || m.From.Username != "bocchi" || m.Chat.ID != -1001234 || m.Text != "@robot say something" {
+		t.Errorf("wrong first message: %+v", m)
+	}
+	m = u[1].Message
+	if m.Thread != 7 || !m.IsTopic || m.ReplyTo == nil || m.ReplyTo.ID != 42 {
+		t.Errorf("wrong second message: %+v", m)
+	}
+}
+
+func TestCallError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, `{"ok":false,"error_code":401,"description":"Unauthorized"}`)
+	}))
+	defer srv.Close()
+	cl := Client{Token: "123:abc", API: srv.URL}
+	_, err := cl.GetMe(context.Background())
+	if err == nil {
+		t.Fatal("no error")
+	}
+	if !strings.Contains(err.Error(), "Unauthorized") || strings.Contains(err.Error(), "123:abc") {
+		t.Errorf("bad error: %v", err)
+	}
+}
+
+func TestSendMessage(t *testing.T) {
+	var sent []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		var p map[string]any
+		json.Unmarshal(b, &p)
+		sent = append(sent, p)
+		io.WriteString(w, `{"ok":true,"result":{}}`)
+	}))
+	defer srv.Close()
+	cl := Client{Token: "123:abc", API: srv.URL}
+	text := strings.Repeat("bocchi ", 1000)
+	if err := cl.SendMessage(context.Background(), "-1001234", 42, 7, text); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 2 {
+		t.Fatalf("wrong number of messages: want 2, got %d", len(sent))
+	}
+	want := map[string]any{"message_id": 42.0, "allow_sending_without_reply": true}
+	if diff := cmp.Diff(want, sent[0]["reply_parameters"]); diff != "" {
+		t.Errorf("wrong reply parameters:\n%s", diff)
+	}
+	if _, ok := sent[1]["reply_parameters"]; ok {
+		t.Errorf("second part is a reply")
+	}
+	for i, p := range sent {
+		if p["chat_id"] != "-1001234" || p["message_thread_id"] != 7.0 {
+			t.Errorf("wrong destination for part %d: %v", i, p)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	cases := []struct {
+		name string
+		text string
+		n    int
+		want []string
+	}{
+		{"short", "bocchi ryo", 20, []string{"bocchi ryo"}},
+		{"empty", "", 20, []string{""}},
+		{"spaces", "bocchi ryo nijika kita", 10, []string{"bocchi ryo", "nijika", "kita"}},
+		{"long", "bocchibocchi ryo", 6, []string{"bocchi", "bocchi", "ryo"}},
+		{"astral", "😀😀😀 😀", 4, []string{"😀😀", "😀", "😀"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := Split(c.text, c.n)
+			if diff := cmp.Diff(c.want, got); diff != "" {
+				t.Errorf("wrong split of %q:\n%s", c.text, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/zephyrtronium/robot/telegram"
+)
+
+func TestTelegramThreads(t *testing.T) {
+	var mu sync.Mutex
+	var reqs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text   string `json:"text"`
+			Thread int64  `json:"message_thread_id"`
+			Reply  struct {
+				ID int64 `json:"message_id"`
+			} `json:"reply_parameters"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		reqs = append(reqs, body.Text+" "+strconv.FormatInt(body.Reply.ID, 10)+" "+strconv.FormatInt(body.Thread, 10))
+		mu.Unlock()
+		w.Write([]byte(`{"ok":true,"result":{}}`))
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	tc := &telegramClient{
+		cl:      &telegram.Client{Token: "bocchi", API: srv.URL},
+		name:    "robot",
+		id:      "100",
+		threads: make(map[string]int64),
+	}
+	var h messageHandler
+	from := &telegram.User{ID: 400, Username: "bocchi"}
+	chat := telegram.Chat{ID: -200}
+	tc.message(ctx, &h, &telegram.Message{ID: 1, From: from, Chat: chat, Text: "general"})
+	tc.message(ctx, &h, &telegram.Message{ID: 2, From: from, Chat: chat, Text: "topic", Thread: 7, IsTopic: true})
+	// Replies in General carry the ID of the message they reply to, which
+	// Telegram reports as a thread without marking it a topic message.
+	reply := &telegram.Message{ID: 1, Chat: chat, Thread: 1}
+	tc.message(ctx, &h, &telegram.Message{ID: 3, From: from, Chat: chat, Text: "reply", Thread: 1, ReplyTo: reply})
+	wantIDs := [][2]string{{"-200/1", ""}, {"-200/2/7", ""}, {"-200/3", "-200/1"}}
+	var gotIDs [][2]string
+	for _, m := range h.msgs {
+		gotIDs = append(gotIDs, [2]string{m.ID, m.ReplyParent})
+	}
+	if !slices.Equal(gotIDs, wantIDs) {
+		t.Errorf("wrong IDs:\nwant %q\ngot  %q", wantIDs, gotIDs)
+	}
+	// Replies go to the topic of their parent.
+	if err := tc.Send(ctx, "-200", "-200/2/7", "in topic"); err != nil {
+		t.Error(err)
+	}
+	if err := tc.Send(ctx, "-200", "-200/1", "in general"); err != nil {
+		t.Error(err)
+	}
+	// Other messages go to the topic of the latest message.
+	if err := tc.Send(ctx, "-200", "", "latest general"); err != nil {
+		t.Error(err)
+	}
+	tc.message(ctx, &h, &telegram.Message{ID: 4, From: from, Chat: chat, Text: "topic", Thread: 7, IsTopic: true})
+	if err := tc.Send(ctx, "-200", "", "latest topic"); err != nil {
+		t.Error(err)
+	}
+	wantReqs := []string{
+		"in topic 2 7",
+		"in general 1 0",
+		"latest general 0 0",
+		"latest topic 0 7",
+	}
+	if !slices.Equal(reqs, wantReqs) {
+		t.Errorf("wrong requests:\nwant %q\ngot  %q", wantReqs, reqs)
+	}
+}
@@ -2,57 +2,182 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"gitlab.com/zephyrtronium/tmi"
+	"golang.org/x/oauth2"
 	"golang.org/x/sync/errgroup"
 
-	"github.com/zephyrtronium/robot/userhash"
+	"github.com/zephyrtronium/robot/jobs"
+	"github.com/zephyrtronium/robot/message"
+	"github.com/zephyrtronium/robot/platform"
+	"github.com/zephyrtronium/robot/twitch"
 )
 
-func (robo *Robot) tmiLoop(ctx context.Context, group *errgroup.Group, send chan<- *tmi.Message, recv <-chan *tmi.Message) {
+// twitchClient is the bot's connection to Twitch chat and the Twitch API.
+type twitchClient struct {
+	// tmi contains the bot's Twitch OAuth2 settings.
+	tmi *client[*tmi.Message, *tmi.Message]
+	// api is the Twitch API client.
+	api twitch.Client
+	// channels is the list of channels to join, including the leading #.
+	channels []string
+	// modes is the chat restrictions in each channel.
+	// Only the TMI loop uses it.
+	modes map[string]platform.Mode
+	// joined is the set of channels TMI has confirmed we are in, lowercased.
+	// Only the TMI loop uses it.
+	joined map[string]bool
+	// roles is the interval at which to sync channel moderators and VIPs.
+	// If it is not positive, they aren't synced.
+	roles time.Duration
+	// eventsub is whether to receive channel events from EventSub.
+	eventsub bool
+	// liveness is how long a TMI connection may go without receiving
+	// anything before the bot reconnects.
+	liveness time.Duration
+	// notify sends a notification to the owner. It may be nil.
+	notify func(ctx context.Context, text string)
+}
+
+var _ platform.Client = (*twitchClient)(nil)
+
+func (tc *twitchClient) Platform() string { return "twitch" }
+
+func (tc *twitchClient) Bot() (id, name string) { return tc.tmi.userID, tc.tmi.name }
+
+func (tc *twitchClient) Owner() string { return tc.tmi.owner }
+
+func (tc *twitchClient) Capabilities() platform.Capabilities {
+	return platform.Capabilities{Replies: true, Live: true, Format: platform.Format{MaxLength: 500}}
+}
+
+// Send sends a message to TMI after waiting for the global rate limit.
+// The caller should verify that it is safe to send the message.
+func (tc *twitchClient) Send(ctx context.Context, channel, reply, text string) error {
+	msg := message.Format(reply, channel, "%s", text)
+	if err := tc.tmi.rate.Wait(ctx); err != nil {
+		return err
+	}
+	resp := message.ToTMI(msg)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case tc.tmi.send <- resp:
+		return nil
+	}
+}
+
+func (tc *twitchClient) Run(ctx context.Context, h platform.Handler) error {
+	cfg := tmi.ConnectConfig{
+		Dial:         new(tls.Dialer).DialContext,
+		RetryWait:    tmi.RetryList(true, 0, time.Second, time.Minute, 5*time.Minute),
+		Nick:         strings.ToLower(tc.tmi.name),
+		Capabilities: []string{"twitch.tv/commands", "twitch.tv/tags"},
+		Timeout:      300 * time.Second,
+	}
+	group, ctx := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		tc.tmiLoop(ctx, h)
+		return nil
+	})
+	group.Go(func() error {
+		return tc.validateLoop(ctx)
+	})
+	group.Go(func() error {
+		return tc.streamsLoop(ctx, h)
+	})
+	group.Go(func() error {
+		return tc.connectTMI(ctx, cfg)
+	})
+	return group.Wait()
+}
+
+func (tc *twitchClient) tmiLoop(ctx context.Context, h platform.Handler) {
+	tc.joined = make(map[string]bool)
+	// reconcile fires after joining has had time to finish on each connection.
+	var reconcile <-chan time.Time
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case msg, ok := <-recv:
+		case <-reconcile:
+			reconcile = nil
+			tc.reconcile(ctx)
+		case msg, ok := <-tc.tmi.recv:
 			if !ok {
 				return
 			}
 			switch msg.Command {
 			case "PRIVMSG":
-				robo.tmiMessage(ctx, group, send, msg)
+				tc.privmsg(ctx, h, msg)
 			case "WHISPER":
 				// TODO(zeph): this
 			case "NOTICE":
 				// nothing yet
 			case "CLEARCHAT":
-				robo.clearchat(ctx, group, msg)
+				tc.clearchat(ctx, h, msg)
 			case "CLEARMSG":
-				robo.clearmsg(ctx, group, msg)
+				tc.clearmsg(ctx, h, msg)
 			case "HOSTTARGET":
 				// nothing yet
+			case "ROOMSTATE":
+				tc.roomstate(ctx, h, msg)
 			case "USERSTATE":
 				// We used to check our badges and update our hard rate limit
 				// per-channel, but per-channel rate limits only really make
 				// sense for verified bots which have a relaxed global limit.
 			case "GLOBALUSERSTATE":
 				slog.InfoContext(ctx, "connected to TMI", slog.String("GLOBALUSERSTATE", msg.Tags))
+			case "JOIN":
+				if strings.EqualFold(msg.Nick, tc.tmi.name) {
+					tc.joined[strings.ToLower(msg.To())] = true
+				}
+			case "PART":
+				if strings.EqualFold(msg.Nick, tc.tmi.name) {
+					delete(tc.joined, strings.ToLower(msg.To()))
+				}
 			case "376": // End MOTD
-				go robo.joinTwitch(ctx, send)
+				// This is a new connection, so we aren't in any channels.
+				clear(tc.joined)
+				go tc.join(ctx, tc.channels)
+				reconcile = time.After(joinTime(len(tc.channels)) + 30*time.Second)
 			}
 		}
 	}
 }
 
-func (robo *Robot) joinTwitch(ctx context.Context, send chan<- *tmi.Message) {
-	ls := make([]string, 0, robo.channels.Len())
-	for _, ch := range robo.channels.All() {
-		ls = append(ls, ch.Name)
+// privmsg processes a PRIVMSG from TMI.
+func (tc *twitchClient) privmsg(ctx context.Context, h platform.Handler, msg *tmi.Message) {
+	h.Message(ctx, tc, message.FromTMI(msg))
+}
+
+// joinBurst is the number of channels to join at once.
+const joinBurst = 20
+
+// joinWait is the time between bursts of joins.
+// Per https://dev.twitch.tv/docs/irc/#rate-limits we get 20 join attempts per
+// ten seconds. Use a slightly longer delay to ensure we don't get globaled by
+// clock drift.
+const joinWait = 11 * time.Second
+
+// joinTime is the time it takes to join n channels.
+func joinTime(n int) time.Duration {
+	if n <= 0 {
+		return 0
 	}
-	burst := 20
+	return time.Duration((n-1)/joinBurst) * joinWait
+}
+
+func (tc *twitchClient) join(ctx context.Context, ls []string) {
+	burst := joinBurst
 	for len(ls) > 0 {
 		l := ls[:min(burst, len(ls))]
 		ls = ls[len(l):]
@@ -63,141 +188,363 @@ func (robo *Robot) joinTwitch(ctx context.Context, send chan<- *tmi.Message) {
 		select {
 		case <-ctx.Done():
 			return
-		case send <- &msg:
+		case tc.tmi.send <- &msg:
 			// do nothing
 		}
 		if len(ls) > 0 {
-			// Per https://dev.twitch.tv/docs/irc/#rate-limits we get 20 join
-			// attempts per ten seconds. Use a slightly longer delay to ensure
-			// we don't get globaled by clock drift.
-			time.Sleep(11 * time.Second)
+			time.Sleep(joinWait)
 		}
 	}
 }
 
-func (robo *Robot) clearchat(ctx context.Context, group *errgroup.Group, msg *tmi.Message) {
-	if len(msg.Params) == 0 {
+// reconcile compares the channels TMI says we are in against the configured
+// ones, parting channels which aren't configured and joining ones we missed.
+// It must run in the TMI loop.
+func (tc *twitchClient) reconcile(ctx context.Context) {
+	join, part := reconcileChannels(tc.channels, tc.joined)
+	if len(join) == 0 && len(part) == 0 {
+		slog.InfoContext(ctx, "joined channels match config", slog.Int("count", len(tc.joined)))
 		return
 	}
-	ch, _ := robo.channels.Load(msg.To())
-	if ch == nil {
-		return
+	if len(join) != 0 {
+		slog.WarnContext(ctx, "configured channels not joined", slog.Any("channels", join))
+		go tc.join(ctx, join)
 	}
-	var work func(ctx context.Context)
-	t, _ := msg.Tag("target-user-id")
-	switch t {
-	case "":
-		// Delete all recent chat.
-		work = func(ctx context.Context) {
-			tag := ch.Learn
-			slog.InfoContext(ctx, "clear all chat", slog.String("channel", msg.To()), slog.String("tag", tag))
-			err := robo.brain.ForgetDuring(ctx, tag, msg.Time().Add(-15*time.Minute), msg.Time())
-			if err != nil {
-				slog.ErrorContext(ctx, "failed to forget from all chat", slog.Any("err", err), slog.String("channel", msg.To()))
-			}
+	if len(part) != 0 {
+		slog.WarnContext(ctx, "joined channels not configured", slog.Any("channels", part))
+		msg := tmi.Message{
+			Command: "PART",
+			Params:  []string{strings.Join(part, ",")},
 		}
-	case robo.tmi.userID:
-		work = func(ctx context.Context) {
-			// We use the send tag because we are forgetting something we sent.
-			tag := ch.Send
-			slog.InfoContext(ctx, "forget recent generated", slog.String("channel", msg.To()), slog.String("tag", tag))
-			for id, err := range robo.spoken.Since(ctx, tag, msg.Time().Add(-15*time.Minute)) {
-				if err != nil {
-					slog.ErrorContext(ctx, "failed to get recent traces",
-						slog.Any("err", err),
-						slog.String("channel", msg.To()),
-						slog.String("tag", tag),
-					)
-					continue
-				}
-				if err := robo.brain.ForgetMessage(ctx, tag, id); err != nil {
-					slog.ErrorContext(ctx, "failed to forget from recent trace",
-						slog.Any("err", err),
-						slog.String("channel", msg.To()),
-						slog.String("tag", tag),
-						slog.String("id", id),
-					)
-				}
+		// Send from another goroutine so that we don't block receiving.
+		go func() {
+			select {
+			case <-ctx.Done():
+			case tc.tmi.send <- &msg:
 			}
+		}()
+	}
+}
+
+// reconcileChannels finds the configured channels missing from joined and
+// the joined channels missing from the configuration. The results are
+// lowercased and sorted.
+func reconcileChannels(want []string, joined map[string]bool) (join, part []string) {
+	w := make(map[string]bool, len(want))
+	for _, ch := range want {
+		ch = strings.ToLower(ch)
+		w[ch] = true
+		if !joined[ch] {
+			join = append(join, ch)
 		}
-	default:
-		// Delete from user.
-		// We use the user's current and previous userhash, since userhashes
-		// are time-based.
-		work = func(ctx context.Context) {
-			hr := userhash.New(robo.secrets.userhash)
-			h := hr.Hash(new(userhash.Hash), t, msg.To(), msg.Time())
-			if err := robo.brain.ForgetUser(ctx, h); err != nil {
-				slog.ErrorContext(ctx, "failed to forget recent messages from user", slog.Any("err", err), slog.String("channel", msg.To()))
-				// Try the previous userhash anyway.
-			}
-			h = hr.Hash(h, t, msg.To(), msg.Time().Add(-userhash.TimeQuantum))
-			if err := robo.brain.ForgetUser(ctx, h); err != nil {
-				slog.ErrorContext(ctx, "failed to forget older messages from user", slog.Any("err", err), slog.String("channel", msg.To()))
-			}
+	}
+	for ch := range joined {
+		if !w[ch] {
+			part = append(part, ch)
 		}
 	}
-	robo.enqueue(ctx, group, work)
+	slices.Sort(join)
+	join = slices.Compact(join)
+	slices.Sort(part)
+	return join, part
 }
 
-func (robo *Robot) clearmsg(ctx context.Context, group *errgroup.Group, msg *tmi.Message) {
+// roomstate processes a ROOMSTATE from TMI.
+func (tc *twitchClient) roomstate(ctx context.Context, h platform.Handler, msg *tmi.Message) {
 	if len(msg.Params) == 0 {
 		return
 	}
-	ch, _ := robo.channels.Load(msg.To())
-	if ch == nil {
+	if tc.modes == nil {
+		tc.modes = make(map[string]platform.Mode)
+	}
+	ch := msg.To()
+	mode := roomMode(tc.modes[ch], msg)
+	tc.modes[ch] = mode
+	h.Mode(ctx, tc, ch, mode)
+}
+
+// roomMode updates chat restrictions with the tags of a ROOMSTATE.
+// Twitch sends all tags on join but only the changed ones afterward.
+func roomMode(mode platform.Mode, msg *tmi.Message) platform.Mode {
+	if v, ok := msg.Tag("emote-only"); ok {
+		mode.EmoteOnly = v == "1"
+	}
+	if v, ok := msg.Tag("subs-only"); ok {
+		mode.SubOnly = v == "1"
+	}
+	if v, ok := msg.Tag("slow"); ok {
+		n, _ := strconv.Atoi(v)
+		mode.Slow = time.Duration(n) * time.Second
+	}
+	return mode
+}
+
+func (tc *twitchClient) clearchat(ctx context.Context, h platform.Handler, msg *tmi.Message) {
+	if len(msg.Params) == 0 {
+		return
+	}
+	t, _ := msg.Tag("target-user-id")
+	h.Clear(ctx, tc, msg.To(), t, msg.Time())
+}
+
+func (tc *twitchClient) clearmsg(ctx context.Context, h platform.Handler, msg *tmi.Message) {
+	if len(msg.Params) == 0 {
 		return
 	}
 	t, _ := msg.Tag("target-msg-id")
 	u, _ := msg.Tag("login")
-	work := func(ctx context.Context) {
-		if u != robo.tmi.name {
-			// Forget a message from someone else.
-			slog.InfoContext(ctx, "forget message", slog.String("channel", msg.To()), slog.String("id", t))
-			err := robo.brain.ForgetMessage(ctx, ch.Learn, t)
-			if err != nil {
-				slog.ErrorContext(ctx, "failed to forget message",
-					slog.Any("err", err),
-					slog.String("channel", msg.To()),
-					slog.String("tag", ch.Learn),
-					slog.String("id", t),
-				)
+	h.Delete(ctx, tc, msg.To(), t, msg.Trailing, u == tc.tmi.name)
+}
+
+// addRolesJob adds a job to sync the moderators and VIPs of Twitch channels,
+// if it is configured.
+func (robo *Robot) addRolesJob() {
+	if robo.twitch == nil || robo.twitch.roles <= 0 {
+		return
+	}
+	robo.jobs.Add(jobs.Job{
+		Name:  "twitch-roles",
+		Every: robo.twitch.roles,
+		Fn: func(ctx context.Context, _ string) (string, error) {
+			return "", robo.syncRoles(ctx)
+		},
+	})
+}
+
+// syncRoles updates the moderators and VIPs of each Twitch channel from Helix.
+func (robo *Robot) syncRoles(ctx context.Context) error {
+	tc := robo.twitch
+	tok, err := tc.tmi.tokens.Token(ctx)
+	if err != nil {
+		return err
+	}
+	err = robo.syncRolesWith(ctx, tok)
+	if errors.Is(err, twitch.ErrNeedRefresh) {
+		tok, err = tc.tmi.tokens.Refresh(ctx, tok)
+		if err != nil {
+			return fmt.Errorf("couldn't refresh token: %w", err)
+		}
+		err = robo.syncRolesWith(ctx, tok)
+	}
+	return err
+}
+
+// syncRolesWith updates Twitch channel roles using a particular token.
+// Failures in some channels don't stop others from syncing, except that it
+// returns immediately if the token needs to be refreshed.
+func (robo *Robot) syncRolesWith(ctx context.Context, tok *oauth2.Token) error {
+	tc := robo.twitch
+	ids, err := robo.twitchIDs(ctx, tok)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, name := range tc.channels {
+		id := ids[name]
+		ch, _ := robo.channels.Load(name)
+		if id == "" || ch == nil {
+			continue
+		}
+		mods, err := twitch.Moderators(ctx, tc.api, tok, id)
+		if err != nil {
+			if errors.Is(err, twitch.ErrNeedRefresh) {
+				return err
 			}
-			return
+			errs = append(errs, fmt.Errorf("couldn't sync roles in %s: %w", name, err))
+			continue
 		}
-		// Forget a message from the robo.
-		// This may or may not be a generated message; it could be a command
-		// output or copypasta. Regardless, if it was deleted, we should try
-		// not to say it.
-		// Note that we use the send tag rather than the learn tag for this,
-		// because we are unlearning something that we sent.
-		trace, tm, err := robo.spoken.Trace(ctx, ch.Send, msg.Trailing)
+		vips, err := twitch.VIPs(ctx, tc.api, tok, id)
 		if err != nil {
-			slog.ErrorContext(ctx, "failed to get message trace",
-				slog.Any("err", err),
-				slog.String("channel", msg.To()),
-				slog.String("tag", ch.Send),
-				slog.String("id", t),
-			)
-			return
+			if errors.Is(err, twitch.ErrNeedRefresh) {
+				return err
+			}
+			errs = append(errs, fmt.Errorf("couldn't sync roles in %s: %w", name, err))
+			continue
+		}
+		ch.Roles.Set(roleIDs(mods), roleIDs(vips))
+		slog.InfoContext(ctx, "synced roles", slog.String("in", name), slog.Int("mods", len(mods)), slog.Int("vips", len(vips)))
+	}
+	return errors.Join(errs...)
+}
+
+// roleIDs gets the user IDs of users holding a role.
+func roleIDs(users []twitch.RoleUser) []string {
+	r := make([]string, len(users))
+	for i, u := range users {
+		r[i] = u.UserID
+	}
+	return r
+}
+
+func (tc *twitchClient) validateLoop(ctx context.Context) error {
+	tm := time.NewTicker(time.Hour)
+	defer tm.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tm.C: // continue below
 		}
-		slog.InfoContext(ctx, "forget trace",
-			slog.String("channel", msg.To()),
-			slog.String("tag", ch.Send),
-			slog.Any("learned", tm),
-			slog.Any("trace", trace),
-		)
-		for _, id := range trace {
-			err := robo.brain.ForgetMessage(ctx, ch.Send, id)
+		tok, err := tc.tmi.tokens.Token(ctx)
+		if err != nil {
+			return fmt.Errorf("validation loop failed to get user access token: %w", err)
+		}
+		val, err := twitch.Validate(ctx, tc.api.HTTP, tok)
+		switch {
+		case err == nil:
+			slog.InfoContext(ctx, "validation loop",
+				slog.String("clientid", val.ClientID),
+				slog.String("userid", val.UserID),
+				slog.String("login", val.Login),
+				slog.Int("expires", val.ExpiresIn),
+			)
+		case errors.Is(err, twitch.ErrNeedRefresh):
+			_, err := tc.tmi.tokens.Refresh(ctx, tok)
 			if err != nil {
-				slog.ErrorContext(ctx, "failed to forget from trace",
-					slog.Any("err", err),
-					slog.String("channel", msg.To()),
-					slog.String("tag", ch.Send),
-					slog.String("id", t),
+				return fmt.Errorf("validation loop failed to refresh user access token: %w", err)
+			}
+		default:
+			if val != nil {
+				slog.ErrorContext(ctx, "validation loop", slog.Int("status", val.Status), slog.String("reason", val.Message))
+			}
+			return fmt.Errorf("validation loop failed to validate user access token: %w", err)
+		}
+	}
+}
+
+func (tc *twitchClient) streamsLoop(ctx context.Context, h platform.Handler) error {
+	// TODO(zeph): one day we should switch to eventsub
+	// TODO(zeph): remove anything learned since the last check when offline
+	tok, err := tc.tmi.tokens.Token(ctx)
+	if err != nil {
+		return err
+	}
+	streams := make([]twitch.Stream, 0, len(tc.channels))
+	m := make(map[string]platform.Stream, len(tc.channels))
+	// Run once at the start so we start learning in online streams immediately.
+	streams = streams[:0]
+	for _, name := range tc.channels {
+		n := strings.ToLower(strings.TrimPrefix(name, "#"))
+		streams = append(streams, twitch.Stream{UserLogin: n})
+	}
+	for range 5 {
+		// TODO(zeph): limit to 100
+		streams, err = twitch.UserStreams(ctx, tc.api, tok, streams)
+		switch {
+		case err == nil:
+			slog.InfoContext(ctx, "stream infos", slog.Int("count", len(streams)))
+			// Mark online streams as enabled.
+			// First map names to online status.
+			for _, s := range streams {
+				slog.DebugContext(ctx, "stream",
+					slog.String("login", s.UserLogin),
+					slog.String("display", s.UserName),
+					slog.String("id", s.UserID),
+					slog.String("type", s.Type),
 				)
+				n := strings.ToLower(s.UserLogin)
+				m[n] = platform.Stream{Live: true, Game: s.GameName}
 			}
+			// Now loop all streams.
+			for _, name := range tc.channels {
+				n := strings.ToLower(strings.TrimPrefix(name, "#"))
+				h.Live(ctx, tc, name, m[n])
+			}
+		case errors.Is(err, twitch.ErrNeedRefresh):
+			tok, err = tc.tmi.tokens.Refresh(ctx, tok)
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to refresh token", slog.Any("err", err))
+				return fmt.Errorf("couldn't get valid access token: %w", err)
+			}
+			continue
+		default:
+			slog.ErrorContext(ctx, "failed to query online broadcasters", slog.Any("streams", streams), slog.Any("err", err))
+			// All streams are already offline.
 		}
+		break
+	}
+	streams = streams[:0]
+	clear(m)
+
+	tick := time.NewTicker(time.Minute)
+	go func() {
+		<-ctx.Done()
+		tick.Stop()
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+			for _, name := range tc.channels {
+				n := strings.TrimPrefix(name, "#")
+				streams = append(streams, twitch.Stream{UserLogin: n})
+			}
+			for range 5 {
+				// TODO(zeph): limit to 100
+				streams, err = twitch.UserStreams(ctx, tc.api, tok, streams)
+				switch {
+				case err == nil:
+					slog.InfoContext(ctx, "stream infos", slog.Int("count", len(streams)))
+					// Mark online streams as enabled.
+					// First map names to online status.
+					for _, s := range streams {
+						slog.DebugContext(ctx, "stream",
+							slog.String("login", s.UserLogin),
+							slog.String("display", s.UserName),
+							slog.String("id", s.UserID),
+							slog.String("type", s.Type),
+						)
+						n := strings.ToLower(s.UserLogin)
+						m[n] = platform.Stream{Live: true, Game: s.GameName}
+					}
+					// Now loop all streams.
+					for _, name := range tc.channels {
+						n := strings.ToLower(strings.TrimPrefix(name, "#"))
+						h.Live(ctx, tc, name, m[n])
+					}
+				case errors.Is(err, twitch.ErrNeedRefresh):
+					tok, err = tc.tmi.tokens.Refresh(ctx, tok)
+					if err != nil {
+						slog.ErrorContext(ctx, "failed to refresh token", slog.Any("err", err))
+						return fmt.Errorf("couldn't get valid access token: %w", err)
+					}
+					continue
+				default:
+					slog.ErrorContext(ctx, "failed to query online broadcasters", slog.Any("streams", streams), slog.Any("err", err))
+					// Set all streams as offline.
+					for _, name := range tc.channels {
+						h.Live(ctx, tc, name, platform.Stream{})
+					}
+				}
+				break
+			}
+			streams = streams[:0]
+			clear(m)
+		}
+	}
+}
+
+type tmiSlog struct {
+	l *slog.Logger
+	// alive records activity on the connection. It may be nil.
+	alive *tmiLiveness
+}
+
+func (l *tmiSlog) Error(err error) { l.l.Error("TMI error", slog.String("err", err.Error())) }
+func (l *tmiSlog) Status(s string) { l.l.Info("TMI status", slog.String("status", s)) }
+func (l *tmiSlog) Send(s string)   { l.l.Debug("TMI send", slog.String("message", s)) }
+func (l *tmiSlog) Recv(s string) {
+	l.seen()
+	l.l.Debug("TMI recv", slog.String("message", s))
+}
+func (l *tmiSlog) Ping(s string) {
+	l.seen()
+	l.l.Log(context.Background(), slog.LevelDebug-1, "TMI ping", slog.String("message", s))
+}
+
+func (l *tmiSlog) seen() {
+	if l.alive != nil {
+		l.alive.touch(time.Now())
 	}
-	robo.enqueue(ctx, group, work)
 }
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/zephyrtronium/tmi"
+
+	"github.com/zephyrtronium/robot/platform"
+)
+
+func TestRoomMode(t *testing.T) {
+	cases := []struct {
+		name string
+		old  platform.Mode
+		msg  string
+		want platform.Mode
+	}{
+		{
+			name: "join",
+			msg:  "@emote-only=0;followers-only=-1;r9k=0;room-id=1;slow=0;subs-only=0 :tmi.twitch.tv ROOMSTATE #bocchi",
+			want: platform.Mode{},
+		},
+		{
+			name: "join-restricted",
+			msg:  "@emote-only=1;followers-only=-1;r9k=0;room-id=1;slow=30;subs-only=1 :tmi.twitch.tv ROOMSTATE #bocchi",
+			want: platform.Mode{EmoteOnly: true, SubOnly: true, Slow: 30 * time.Second},
+		},
+		{
+			name: "partial",
+			old:  platform.Mode{SubOnly: true, Slow: 30 * time.Second},
+			msg:  "@emote-only=1;room-id=1 :tmi.twitch.tv ROOMSTATE #bocchi",
+			want: platform.Mode{EmoteOnly: true, SubOnly: true, Slow: 30 * time.Second},
+		},
+		{
+			name: "off",
+			old:  platform.Mode{EmoteOnly: true, Slow: 30 * time.Second},
+			msg:  "@room-id=1;slow=0 :tmi.twitch.tv ROOMSTATE #bocchi",
+			want: platform.Mode{EmoteOnly: true},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			msg, err := tmi.Parse(strings.NewReader(c.msg + "\r\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := roomMode(c.old, msg); got != c.want {
+				t.Errorf("wrong mode: want %+v, got %+v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestReconcileChannels(t *testing.T) {
+	cases := []struct {
+		name       string
+		want       []string
+		joined     map[string]bool
+		join, part []string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:   "match",
+			want:   []string{"#Bocchi", "#kita"},
+			joined: map[string]bool{"#bocchi": true, "#kita": true},
+		},
+		{
+			name:   "missing",
+			want:   []string{"#bocchi", "#kita", "#ryo"},
+			joined: map[string]bool{"#kita": true},
+			join:   []string{"#bocchi", "#ryo"},
+		},
+		{
+			name:   "extra",
+			want:   []string{"#kita"},
+			joined: map[string]bool{"#kita": true, "#nijika": true, "#bocchi": true},
+			part:   []string{"#bocchi", "#nijika"},
+		},
+		{
+			name:   "both",
+			want:   []string{"#bocchi", "#bocchi"},
+			joined: map[string]bool{"#kita": true},
+			join:   []string{"#bocchi"},
+			part:   []string{"#kita"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			join, part := reconcileChannels(c.want, c.joined)
+			if !slices.Equal(join, c.join) {
+				t.Errorf("wrong joins: want %q, got %q", c.join, join)
+			}
+			if !slices.Equal(part, c.part) {
+				t.Errorf("wrong parts: want %q, got %q", c.part, part)
+			}
+		})
+	}
+}
+
+func TestJoinTime(t *testing.T) {
+	cases := []struct {
+		n    int
+		want time.Duration
+	}{
+		{0, 0},
+		{1, 0},
+		{20, 0},
+		{21, joinWait},
+		{45, 2 * joinWait},
+	}
+	for _, c := range cases {
+		if got := joinTime(c.n); got != c.want {
+			t.Errorf("wrong time to join %d: want %v, got %v", c.n, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"gitlab.com/zephyrtronium/tmi"
+)
+
+// tmiLiveness tracks when data last arrived from TMI.
+type tmiLiveness struct {
+	// last is the time in nanoseconds from the UNIX epoch at which data last
+	// arrived.
+	last atomic.Int64
+}
+
+func (l *tmiLiveness) touch(now time.Time) { l.last.Store(now.UnixNano()) }
+
+func (l *tmiLiveness) idle(now time.Time) time.Duration {
+	return now.Sub(time.Unix(0, l.last.Load()))
+}
+
+// connectTMI connects to TMI until ctx is canceled, reconnecting whenever a
+// connection ends or goes quiet for longer than the liveness window.
+// When TMI rejects the login, the token is refreshed before reconnecting, and
+// the owner is notified if that doesn't fix it.
+// Server PINGs are answered by the connection's receiver directly rather than
+// through the send channel, so the send rate limit never delays them.
+func (tc *twitchClient) connectTMI(ctx context.Context, cfg tmi.ConnectConfig) error {
+	// tmiLoop ends when recv closes, as it did when TMI connected only once.
+	defer close(tc.tmi.recv)
+	wait := time.Second
+	// refreshed is whether the token has been refreshed for a login failure
+	// since the last healthy connection, and notified is whether the owner
+	// has been told that logins are failing.
+	refreshed, notified := false, false
+	for {
+		tok, err := tc.tmi.tokens.Token(ctx)
+		if err != nil {
+			return err
+		}
+		cfg.Pass = "oauth:" + tok.AccessToken
+		start := time.Now()
+		err = tc.connectOnce(ctx, cfg)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if time.Since(start) > 5*time.Minute {
+			// The connection was healthy for a while, so this isn't part of
+			// a run of failures.
+			wait = time.Second
+			refreshed, notified = false, false
+		}
+		if errors.Is(err, tmi.ErrAuthenticationFailed) {
+			if !refreshed {
+				refreshed = true
+				slog.WarnContext(ctx, "TMI rejected login; refreshing token")
+				_, err = tc.tmi.tokens.Refresh(ctx, tok)
+				if err == nil {
+					// Try the new token right away.
+					continue
+				}
+				err = fmt.Errorf("couldn't refresh token: %w", err)
+			}
+			if !notified && tc.notify != nil {
+				notified = true
+				text := fmt.Sprintf("Twitch chat rejected my login, and refreshing my token didn't help (%v). I'll keep trying, but you may need to authorize me again.", err)
+				// The notification may go to a Twitch channel, which can't
+				// send until we reconnect, so don't wait on it.
+				go func() {
+					ctx, cancel := context.WithTimeout(ctx, time.Minute)
+					defer cancel()
+					tc.notify(ctx, text)
+				}()
+			}
+		}
+		slog.WarnContext(ctx, "TMI connection ended", slog.Any("err", err), slog.String("reconnect", wait.String()))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		wait = min(2*wait, 5*time.Minute)
+	}
+}
+
+// connectOnce runs one TMI connection, forwarding received messages to the
+// client's receive channel, until the connection ends or the liveness
+// watchdog closes it.
+func (tc *twitchClient) connectOnce(ctx context.Context, cfg tmi.ConnectConfig) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	alive := new(tmiLiveness)
+	alive.touch(time.Now())
+	recv := make(chan *tmi.Message, cap(tc.tmi.recv))
+	done := make(chan error, 1)
+	go func() {
+		done <- tmi.Connect(ctx, cfg, &tmiSlog{l: slog.Default(), alive: alive}, tc.tmi.send, recv)
+	}()
+	go tc.watchTMI(ctx, cancel, alive)
+	for msg := range recv {
+		if tmiAuthFailed(msg) {
+			// TMI doesn't always close the connection when it rejects a
+			// login, and we can't do anything useful on it anyway.
+			cancel(tmi.ErrAuthenticationFailed)
+			continue
+		}
+		select {
+		case tc.tmi.recv <- msg:
+		case <-ctx.Done():
+			// Connect is shutting down. Keep draining until it closes recv.
+		}
+	}
+	err := <-done
+	if cause := context.Cause(ctx); cause != nil && !errors.Is(cause, context.Canceled) {
+		return cause
+	}
+	return err
+}
+
+// tmiAuthFailed reports whether a message is a NOTICE rejecting the login.
+func tmiAuthFailed(msg *tmi.Message) bool {
+	if msg.Command != "NOTICE" {
+		return false
+	}
+	switch msg.Trailing {
+	case "Login authentication failed", "Improperly formatted auth":
+		return true
+	}
+	return false
+}
+
+// watchTMI watches a TMI connection for liveness. Once the connection is idle
+// for half the liveness window, it pings the server to prompt a reply. If the
+// connection stays idle for the whole window, it cancels ctx with the reason.
+func (tc *twitchClient) watchTMI(ctx context.Context, cancel context.CancelCauseFunc, alive *tmiLiveness) {
+	window := tc.liveness
+	t := time.NewTicker(window / 4)
+	defer t.Stop()
+	for {
+		var now time.Time
+		select {
+		case <-ctx.Done():
+			return
+		case now = <-t.C:
+		}
+		idle := alive.idle(now)
+		switch {
+		case idle >= window:
+			cancel(fmt.Errorf("no data from TMI in %v", idle.Truncate(time.Second)))
+			return
+		case idle >= window/2:
+			// If the send channel is full, the message already waiting is
+			// as good as a ping once it goes through.
+			select {
+			case tc.tmi.send <- &tmi.Message{Command: "PING", Trailing: "tmi.twitch.tv"}:
+			default:
+			}
+		}
+	}
+}
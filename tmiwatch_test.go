@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gitlab.com/zephyrtronium/tmi"
+	"golang.org/x/oauth2"
+)
+
+// staticTokens is a token source whose refreshes do nothing.
+type staticTokens struct {
+	// refreshes counts calls to Refresh.
+	refreshes atomic.Int32
+	// err is the error Refresh returns.
+	err error
+}
+
+func (*staticTokens) Token(ctx context.Context) (*oauth2.Token, error) {
+	return &oauth2.Token{AccessToken: "bocchi"}, nil
+}
+
+func (s *staticTokens) Refresh(ctx context.Context, old *oauth2.Token) (*oauth2.Token, error) {
+	s.refreshes.Add(1)
+	return old, s.err
+}
+
+func TestTMILiveness(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var dials atomic.Int32
+	pings := make(chan string, 8)
+	// The server never says anything, so the bot should ping it and then
+	// give up on the connection.
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dials.Add(1)
+		c, s := net.Pipe()
+		go func() {
+			defer s.Close()
+			r := bufio.NewScanner(s)
+			for r.Scan() {
+				if strings.HasPrefix(r.Text(), "PING") {
+					select {
+					case pings <- r.Text():
+					default:
+					}
+				}
+			}
+		}()
+		return c, nil
+	}
+	tc := &twitchClient{
+		tmi: &client[*tmi.Message, *tmi.Message]{
+			send:   make(chan *tmi.Message, 1),
+			recv:   make(chan *tmi.Message, 8),
+			tokens: new(staticTokens),
+		},
+		liveness: 40 * time.Millisecond,
+	}
+	done := make(chan error, 1)
+	go func() { done <- tc.connectTMI(ctx, tmi.ConnectConfig{Dial: dial, Nick: "bocchi"}) }()
+	select {
+	case p := <-pings:
+		if p != "PING :tmi.twitch.tv" {
+			t.Errorf("wrong ping: %q", p)
+		}
+	case <-ctx.Done():
+		t.Fatal("never pinged")
+	}
+	for dials.Load() < 2 {
+		if ctx.Err() != nil {
+			t.Fatal("never reconnected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	if _, ok := <-tc.tmi.recv; ok {
+		t.Error("recv not closed")
+	}
+}
+
+func TestTMILivenessIdle(t *testing.T) {
+	var l tmiLiveness
+	now := time.Now()
+	l.touch(now)
+	if got := l.idle(now.Add(time.Minute)); got != time.Minute {
+		t.Errorf("wrong idle time: want %v, got %v", time.Minute, got)
+	}
+}
+
+func TestTMIAuthFailed(t *testing.T) {
+	cases := []struct {
+		name   string
+		notice string
+		err    error
+		notify bool
+	}{
+		{"login", "Login authentication failed", nil, false},
+		{"improper", "Improperly formatted auth", nil, false},
+		{"refresh-fails", "Improperly formatted auth", errors.New("invalid grant"), true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			dials := make(chan struct{}, 8)
+			// The server rejects every login. TMI doesn't always close the
+			// connection when it does, so neither does this.
+			dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+				dials <- struct{}{}
+				cc, s := net.Pipe()
+				go func() {
+					r := bufio.NewScanner(s)
+					for r.Scan() {
+						if strings.HasPrefix(r.Text(), "USER") {
+							go io.WriteString(s, ":tmi.twitch.tv NOTICE * :"+c.notice+"\r\n")
+						}
+					}
+				}()
+				return cc, nil
+			}
+			tokens := &staticTokens{err: c.err}
+			notified := make(chan string, 1)
+			tc := &twitchClient{
+				tmi: &client[*tmi.Message, *tmi.Message]{
+					send:   make(chan *tmi.Message, 1),
+					recv:   make(chan *tmi.Message, 8),
+					tokens: tokens,
+				},
+				liveness: time.Minute,
+				// Like sending to a Twitch channel, notifying blocks until
+				// the connection is up or the context ends.
+				notify: func(ctx context.Context, text string) {
+					select {
+					case notified <- text:
+					default:
+					}
+					<-ctx.Done()
+				},
+			}
+			done := make(chan error, 1)
+			go func() { done <- tc.connectTMI(ctx, tmi.ConnectConfig{Dial: dial, Nick: "bocchi"}) }()
+			<-dials
+			// A successful refresh reconnects immediately. A failed one waits
+			// for the backoff, which is a second the first time.
+			select {
+			case <-dials:
+			case <-ctx.Done():
+				t.Fatal("never reconnected")
+			}
+			if n := tokens.refreshes.Load(); n != 1 {
+				t.Errorf("wrong number of refreshes: want 1, got %d", n)
+			}
+			select {
+			case text := <-notified:
+				if !c.notify {
+					t.Errorf("notified after successful refresh: %q", text)
+				}
+			default:
+				if c.notify {
+					t.Error("not notified after failed refresh")
+				}
+			}
+			cancel()
+			<-done
+		})
+	}
+}
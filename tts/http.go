@@ -0,0 +1,34 @@
+package tts
+
+import (
+	"context"
+	_ "embed"
+	"net/http"
+	"time"
+)
+
+//go:embed page.html
+var page []byte
+
+// Page serves a page suitable for use as a browser source which plays each
+// clip from the feed served at the same path followed by /next.
+func Page(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
+
+// ServeHTTP waits for the next clip in the feed and serves it.
+// If no clip arrives within 30 seconds, it responds with No Content so that
+// the client can poll again.
+func (f *Feed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	c := f.Next(ctx)
+	if c == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", c.Type)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(c.Audio)
+}
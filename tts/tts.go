@@ -0,0 +1,149 @@
+// Package tts provides text-to-speech for generated messages.
+package tts
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Clip is a synthesized audio clip.
+type Clip struct {
+	// Text is the text from which the clip was synthesized.
+	Text string
+	// Type is the MIME type of the audio.
+	Type string
+	// Audio is the encoded audio.
+	Audio []byte
+}
+
+// Synthesizer converts text to speech.
+type Synthesizer interface {
+	Synthesize(ctx context.Context, text string) (*Clip, error)
+}
+
+// Command is a [Synthesizer] which runs a local program with the text on its
+// standard input and reads the audio from its standard output.
+type Command struct {
+	// Args is the program and its arguments.
+	Args []string
+	// Type is the MIME type of the program's output.
+	// If it is empty, the output is assumed to be audio/wav.
+	Type string
+}
+
+// Synthesize runs the command.
+func (c *Command) Synthesize(ctx context.Context, text string) (*Clip, error) {
+	cmd := exec.CommandContext(ctx, c.Args[0], c.Args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	b, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't run %s: %w (%s)", c.Args[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	typ := c.Type
+	if typ == "" {
+		typ = "audio/wav"
+	}
+	return &Clip{Text: text, Type: typ, Audio: b}, nil
+}
+
+// HTTP is a [Synthesizer] which POSTs the text as text/plain to a TTS API and
+// uses the response body as the audio.
+type HTTP struct {
+	// URL is the endpoint of the API.
+	URL string
+	// Client is the HTTP client to use. If it is nil, http.DefaultClient is
+	// used.
+	Client *http.Client
+}
+
+// Synthesize requests audio from the API.
+func (h *HTTP) Synthesize(ctx context.Context, text string) (*Clip, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", h.URL, strings.NewReader(text))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't make TTS request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	cl := h.Client
+	if cl == nil {
+		cl = http.DefaultClient
+	}
+	resp, err := cl.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get TTS audio: %w", err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read TTS audio: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("TTS API returned %s: %s", resp.Status, bytes.TrimSpace(b))
+	}
+	return &Clip{Text: text, Type: resp.Header.Get("Content-Type"), Audio: b}, nil
+}
+
+// Feed is a queue of clips waiting to be played for a channel.
+type Feed struct {
+	synth Synthesizer
+	size  int
+
+	mu    sync.Mutex
+	clips []*Clip
+	// ready is closed and replaced when a clip is added.
+	ready chan struct{}
+}
+
+// NewFeed creates a feed which synthesizes clips with s and holds at most
+// size clips waiting to be played. When the feed is full, the oldest clip is
+// dropped.
+func NewFeed(s Synthesizer, size int) *Feed {
+	return &Feed{synth: s, size: max(size, 1), ready: make(chan struct{})}
+}
+
+// Say synthesizes text and adds it to the feed.
+func (f *Feed) Say(ctx context.Context, text string) error {
+	c, err := f.synth.Synthesize(ctx, text)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.clips) >= f.size {
+		clear(f.clips[:1])
+		f.clips = f.clips[1:]
+	}
+	f.clips = append(f.clips, c)
+	close(f.ready)
+	f.ready = make(chan struct{})
+	return nil
+}
+
+// Next waits for a clip and removes it from the feed.
+// It returns nil if ctx is canceled first.
+func (f *Feed) Next(ctx context.Context) *Clip {
+	for {
+		f.mu.Lock()
+		if len(f.clips) > 0 {
+			c := f.clips[0]
+			f.clips[0] = nil
+			f.clips = f.clips[1:]
+			f.mu.Unlock()
+			return c
+		}
+		ready := f.ready
+		f.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ready:
+		}
+	}
+}
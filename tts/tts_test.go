@@ -0,0 +1,72 @@
+package tts_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zephyrtronium/robot/tts"
+)
+
+type echo struct{}
+
+func (echo) Synthesize(ctx context.Context, text string) (*tts.Clip, error) {
+	return &tts.Clip{Text: text, Type: "text/plain", Audio: []byte(text)}, nil
+}
+
+func TestFeed(t *testing.T) {
+	ctx := context.Background()
+	f := tts.NewFeed(echo{}, 2)
+	for _, s := range []string{"bocchi", "ryo", "nijika"} {
+		if err := f.Say(ctx, s); err != nil {
+			t.Fatalf("couldn't say %q: %v", s, err)
+		}
+	}
+	// The oldest clip is dropped when the feed is full.
+	for _, want := range []string{"ryo", "nijika"} {
+		c := f.Next(ctx)
+		if c == nil || c.Text != want {
+			t.Errorf("wrong clip: want %q, got %+v", want, c)
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if c := f.Next(ctx); c != nil {
+		t.Errorf("got clip from empty feed: %+v", c)
+	}
+}
+
+func TestFeedWait(t *testing.T) {
+	ctx := context.Background()
+	f := tts.NewFeed(echo{}, 2)
+	got := make(chan *tts.Clip)
+	go func() { got <- f.Next(ctx) }()
+	time.Sleep(time.Millisecond)
+	if err := f.Say(ctx, "kita"); err != nil {
+		t.Fatalf("couldn't say: %v", err)
+	}
+	if c := <-got; c == nil || c.Text != "kita" {
+		t.Errorf("wrong clip: want kita, got %+v", c)
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "audio/mpeg")
+		fmt.Fprintf(w, "audio:%s", b)
+	}))
+	defer srv.Close()
+	h := tts.HTTP{URL: srv.URL}
+	c, err := h.Synthesize(context.Background(), "bocchi")
+	if err != nil {
+		t.Fatalf("couldn't synthesize: %v", err)
+	}
+	if c.Type != "audio/mpeg" || string(c.Audio) != "audio:bocchi" {
+		t.Errorf("wrong clip: %+v", c)
+	}
+}
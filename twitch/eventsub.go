@@ -0,0 +1,34 @@
+package twitch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"golang.org/x/oauth2"
+)
+
+// Subscribe creates an EventSub subscription delivered to a websocket
+// session. The token must be a user access token with the scopes the
+// subscription type requires.
+// See https://dev.twitch.tv/docs/api/reference/#create-eventsub-subscription.
+func Subscribe(ctx context.Context, client Client, tok *oauth2.Token, typ, version string, condition map[string]string, session string) error {
+	body := map[string]any{
+		"type":      typ,
+		"version":   version,
+		"condition": condition,
+		"transport": map[string]string{
+			"method":     "websocket",
+			"session_id": session,
+		},
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("couldn't encode subscription: %w", err)
+	}
+	if _, err := reqbody(ctx, client, tok, "POST", apiurl("/helix/eventsub/subscriptions", nil), bytes.NewReader(b)); err != nil {
+		return fmt.Errorf("couldn't subscribe to %s: %w", typ, err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package twitch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+// status is a round tripper which responds with a fixed status.
+type status struct {
+	code int
+	got  *http.Request
+	body []byte
+}
+
+func (s *status) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.got = req
+	s.body, _ = io.ReadAll(req.Body)
+	return &http.Response{
+		StatusCode: s.code,
+		Status:     http.StatusText(s.code),
+		Body:       io.NopCloser(strings.NewReader(`{}`)),
+	}, nil
+}
+
+func TestSubscribe(t *testing.T) {
+	spy := &status{code: http.StatusAccepted}
+	cl := Client{HTTP: &http.Client{Transport: spy}, ID: "kessoku"}
+	tok := &oauth2.Token{AccessToken: "bocchi"}
+	err := Subscribe(context.Background(), cl, tok, "channel.raid", "1", map[string]string{"to_broadcaster_user_id": "1234"}, "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := spy.got
+	if r.Method != "POST" || r.URL.Path != "/helix/eventsub/subscriptions" || r.Header.Get("Content-Type") != "application/json" || r.Header.Get("Client-Id") != "kessoku" {
+		t.Errorf("wrong request: %s %s %v", r.Method, r.URL, r.Header)
+	}
+	var body struct {
+		Type      string            `json:"type"`
+		Version   string            `json:"version"`
+		Condition map[string]string `json:"condition"`
+		Transport map[string]string `json:"transport"`
+	}
+	if err := json.Unmarshal(spy.body, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Type != "channel.raid" || body.Version != "1" || body.Condition["to_broadcaster_user_id"] != "1234" {
+		t.Errorf("wrong subscription: %+v", body)
+	}
+	if body.Transport["method"] != "websocket" || body.Transport["session_id"] != "s1" {
+		t.Errorf("wrong transport: %v", body.Transport)
+	}
+	spy.code = http.StatusUnauthorized
+	err = Subscribe(context.Background(), cl, tok, "channel.raid", "1", nil, "s1")
+	if !errors.Is(err, ErrNeedRefresh) {
+		t.Errorf("wrong error for unauthorized: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package twitch
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"golang.org/x/oauth2"
+)
+
+// RoleUser is the response type from
+// https://dev.twitch.tv/docs/api/reference/#get-moderators and
+// https://dev.twitch.tv/docs/api/reference/#get-vips.
+type RoleUser struct {
+	UserID    string `json:"user_id"`
+	UserLogin string `json:"user_login"`
+	UserName  string `json:"user_name"`
+}
+
+// Moderators gets all moderators of a channel by the broadcaster's user ID.
+// The token must belong to the broadcaster or one of the channel's moderators
+// and include the moderator:read:moderators scope.
+func Moderators(ctx context.Context, client Client, tok *oauth2.Token, broadcaster string) ([]RoleUser, error) {
+	r, err := roleUsers(ctx, client, tok, "/helix/moderation/moderators", broadcaster)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get moderators: %w", err)
+	}
+	return r, nil
+}
+
+// VIPs gets all VIPs of a channel by the broadcaster's user ID.
+// The token must belong to the broadcaster or one of the channel's moderators
+// and include the moderator:read:vips scope.
+func VIPs(ctx context.Context, client Client, tok *oauth2.Token, broadcaster string) ([]RoleUser, error) {
+	r, err := roleUsers(ctx, client, tok, "/helix/channels/vips", broadcaster)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get VIPs: %w", err)
+	}
+	return r, nil
+}
+
+// roleUsers gets every page of users from a role list endpoint.
+func roleUsers(ctx context.Context, client Client, tok *oauth2.Token, ep, broadcaster string) ([]RoleUser, error) {
+	var r []RoleUser
+	v := url.Values{"broadcaster_id": {broadcaster}, "first": {"100"}}
+	for {
+		var page []RoleUser
+		cursor, err := reqpage(ctx, client, tok, apiurl(ep, v), &page)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, page...)
+		if cursor == "" {
+			return r, nil
+		}
+		v.Set("after", cursor)
+	}
+}
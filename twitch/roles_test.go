@@ -0,0 +1,82 @@
+package twitch
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/oauth2"
+)
+
+// pages is a round tripper which responds with pages by cursor.
+type pages struct {
+	// bodies is the response body for each value of the after parameter.
+	bodies map[string]string
+	// got is the requests received.
+	got []*http.Request
+}
+
+func (p *pages) RoundTrip(req *http.Request) (*http.Response, error) {
+	p.got = append(p.got, req)
+	return &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(p.bodies[req.URL.Query().Get("after")])),
+	}, nil
+}
+
+func TestModerators(t *testing.T) {
+	spy := &pages{
+		bodies: map[string]string{
+			"":     `{"data":[{"user_id":"1","user_login":"nijika","user_name":"Nijika"}],"pagination":{"cursor":"next"}}`,
+			"next": `{"data":[{"user_id":"2","user_login":"ryo","user_name":"Ryo"}],"pagination":{}}`,
+		},
+	}
+	cl := Client{HTTP: &http.Client{Transport: spy}}
+	tok := &oauth2.Token{AccessToken: "bocchi"}
+	u, err := Moderators(context.Background(), cl, tok, "1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []RoleUser{
+		{UserID: "1", UserLogin: "nijika", UserName: "Nijika"},
+		{UserID: "2", UserLogin: "ryo", UserName: "Ryo"},
+	}
+	if diff := cmp.Diff(want, u); diff != "" {
+		t.Errorf("wrong result (-want/+got):\n%s", diff)
+	}
+	if len(spy.got) != 2 {
+		t.Fatalf("wrong number of requests: want 2, got %d", len(spy.got))
+	}
+	for _, r := range spy.got {
+		if r.URL.Path != "/helix/moderation/moderators" {
+			t.Errorf("wrong path: %q", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("broadcaster_id"); got != "1234" {
+			t.Errorf("wrong broadcaster: %q", got)
+		}
+	}
+}
+
+func TestVIPs(t *testing.T) {
+	spy := &pages{
+		bodies: map[string]string{
+			"": `{"data":[{"user_id":"3","user_login":"kita","user_name":"Kita"}],"pagination":{}}`,
+		},
+	}
+	cl := Client{HTTP: &http.Client{Transport: spy}}
+	tok := &oauth2.Token{AccessToken: "bocchi"}
+	u, err := VIPs(context.Background(), cl, tok, "1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []RoleUser{{UserID: "3", UserLogin: "kita", UserName: "Kita"}}
+	if diff := cmp.Diff(want, u); diff != "" {
+		t.Errorf("wrong result (-want/+got):\n%s", diff)
+	}
+	if len(spy.got) != 1 || spy.got[0].URL.Path != "/helix/channels/vips" {
+		t.Errorf("wrong requests: %v", spy.got)
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"net/url"
 
 	"golang.org/x/oauth2"
+
+	"github.com/zephyrtronium/robot/fault"
 )
 
 // Client holds the context for requests to the Twitch API.
@@ -23,39 +25,76 @@ type Client struct {
 // reqjson performs an HTTP request and decodes the response as JSON.
 // The response body is truncated to 2 MB.
 func reqjson[Resp any](ctx context.Context, client Client, tok *oauth2.Token, method, url string, body io.Reader, u *Resp) error {
+	b, err := reqbody(ctx, client, tok, method, url, body)
+	if err != nil {
+		return err
+	}
+	r := struct {
+		Data *Resp `json:"data"`
+	}{u}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return fmt.Errorf("couldn't decode JSON response: %w", err)
+	}
+	return nil
+}
+
+// reqpage gets one page of a paginated endpoint and decodes the response as
+// JSON. It returns the cursor for the next page, or the empty string if there
+// are no more.
+func reqpage[Resp any](ctx context.Context, client Client, tok *oauth2.Token, url string, u *Resp) (string, error) {
+	b, err := reqbody(ctx, client, tok, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	r := struct {
+		Data       *Resp `json:"data"`
+		Pagination struct {
+			Cursor string `json:"cursor"`
+		} `json:"pagination"`
+	}{Data: u}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return "", fmt.Errorf("couldn't decode JSON response: %w", err)
+	}
+	return r.Pagination.Cursor, nil
+}
+
+// reqbody performs an HTTP request and returns the response body, truncated
+// to 2 MB.
+func reqbody(ctx context.Context, client Client, tok *oauth2.Token, method, url string, body io.Reader) ([]byte, error) {
 	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
-		return fmt.Errorf("couldn't make request: %w", err)
+		return nil, fmt.Errorf("couldn't make request: %w", err)
 	}
 	tok.SetAuthHeader(req)
 	req.Header.Set("Client-Id", client.ID)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 	hc := client.HTTP
 	if hc == nil {
 		hc = http.DefaultClient
 	}
 	resp, err := hc.Do(req)
 	if err != nil {
-		return fmt.Errorf("couldn't %s: %w", method, err)
+		return nil, fmt.Errorf("couldn't %s: %w", method, err)
 	}
 	b, err := io.ReadAll(io.LimitReader(resp.Body, 2<<20))
 	if err != nil {
-		return fmt.Errorf("couldn't read response: %w", err)
+		return nil, fmt.Errorf("couldn't read response: %w", err)
 	}
 	resp.Body.Close()
 	switch resp.StatusCode {
-	case http.StatusOK: // do nothing
+	case http.StatusOK, http.StatusAccepted: // do nothing
 	case http.StatusUnauthorized:
-		return fmt.Errorf("request failed: %s (%w)", b, ErrNeedRefresh)
+		return nil, fmt.Errorf("request failed: %s (%w)", b, ErrNeedRefresh)
+	case http.StatusForbidden:
+		return nil, fmt.Errorf("request failed: %s (%w)", b, fault.ErrNotPermitted)
+	case http.StatusTooManyRequests:
+		return nil, fmt.Errorf("request failed: %s (%w)", b, fault.ErrRateLimited)
 	default:
-		return fmt.Errorf("request failed: %s (%s)", b, resp.Status)
+		return nil, fmt.Errorf("request failed: %s (%s)", b, resp.Status)
 	}
-	r := struct {
-		Data *Resp `json:"data"`
-	}{u}
-	if err := json.Unmarshal(b, &r); err != nil {
-		return fmt.Errorf("couldn't decode JSON response: %w", err)
-	}
-	return nil
+	return b, nil
 }
 
 // apiurl creates an api.twitch.tv URL for the given endpoint and with the
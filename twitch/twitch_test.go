@@ -11,6 +11,8 @@ import (
 	"testing"
 
 	"golang.org/x/oauth2"
+
+	"github.com/zephyrtronium/robot/fault"
 )
 
 type reqspy struct {
@@ -98,4 +100,33 @@ func TestReqJSON(t *testing.T) {
 			t.Errorf("unauthorized request didn't return ErrNeedRefresh error")
 		}
 	})
+	t.Run("status", func(t *testing.T) {
+		cases := []struct {
+			code int
+			want error
+		}{
+			{403, fault.ErrNotPermitted},
+			{429, fault.ErrRateLimited},
+		}
+		for _, c := range cases {
+			spy := &reqspy{
+				respond: &http.Response{
+					StatusCode: c.code,
+					Body:       io.NopCloser(strings.NewReader(`{}`)),
+				},
+			}
+			cl := Client{
+				HTTP: &http.Client{
+					Transport: spy,
+				},
+				ID: "bocchi",
+			}
+			tok := &oauth2.Token{AccessToken: "ryo"}
+			var u int
+			err := reqjson(context.Background(), cl, tok, "GET", "https://bocchi.rocks/bocchi", nil, &u)
+			if !errors.Is(err, c.want) {
+				t.Errorf("status %d gave wrong error: want %v, got %v", c.code, c.want, err)
+			}
+		}
+	})
 }
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/zephyrtronium/robot/jobs"
+	"github.com/zephyrtronium/robot/release"
+)
+
+// buildVersion is the version of the running binary, e.g. v1.2.3.
+// It is (devel) for builds which aren't of a tagged module version.
+func buildVersion() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok || bi.Main.Version == "" {
+		return "(devel)"
+	}
+	return bi.Main.Version
+}
+
+// buildInfo describes the running binary.
+func buildInfo() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "robot (devel), no build info"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "robot %s\n", buildVersion())
+	fmt.Fprintf(&b, "go %s\n", strings.TrimPrefix(bi.GoVersion, "go"))
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision", "vcs.time", "vcs.modified", "GOOS", "GOARCH":
+			fmt.Fprintf(&b, "%s %s\n", s.Key, s.Value)
+		}
+	}
+	return b.String()
+}
+
+func cliVersion(ctx context.Context, cmd *cli.Command) error {
+	fmt.Print(buildInfo())
+	return nil
+}
+
+func cliUpgrade(ctx context.Context, cmd *cli.Command) error {
+	client := &http.Client{Timeout: 5 * time.Minute}
+	r, err := release.Latest(ctx, client, release.LatestURL)
+	if err != nil {
+		return fmt.Errorf("couldn't check for releases: %w", err)
+	}
+	cur := buildVersion()
+	if !release.Newer(r.Tag, cur) && !cmd.Bool("force") {
+		fmt.Printf("robot %s is up to date; latest is %s\n", cur, r.Tag)
+		return nil
+	}
+	if cmd.Bool("check") {
+		fmt.Printf("robot %s is available (running %s): %s\n", r.Tag, cur, r.URL)
+		return nil
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("couldn't find executable: %w", err)
+	}
+	if err := release.Install(ctx, client, r, exe); err != nil {
+		return fmt.Errorf("couldn't upgrade: %w", err)
+	}
+	fmt.Printf("upgraded from %s to %s; restart the bot to use it\n", cur, r.Tag)
+	return nil
+}
+
+// SetUpdateCheck sets the interval in seconds at which to check for new
+// releases while serving. If it is not positive, the bot doesn't check.
+func (robo *Robot) SetUpdateCheck(every float64) {
+	robo.updateEvery = fseconds(every)
+}
+
+// addUpdateJob adds a job to check for new releases periodically.
+// The checkpoint is the latest release the owner has been told about, so
+// restarts don't repeat notifications.
+func (robo *Robot) addUpdateJob() {
+	if robo.updateEvery <= 0 {
+		return
+	}
+	robo.jobs.Add(jobs.Job{
+		Name:  "update-check",
+		Every: robo.updateEvery,
+		Fn: func(ctx context.Context, told string) (string, error) {
+			return robo.checkUpdates(ctx, release.LatestURL, told)
+		},
+	})
+}
+
+// checkUpdates checks for a new release and notifies the owner if it is newer
+// than both the running version and told, the last release the owner heard
+// about. It returns the latest release the owner has heard about.
+func (robo *Robot) checkUpdates(ctx context.Context, url, told string) (string, error) {
+	if !release.Newer(told, buildVersion()) {
+		told = buildVersion()
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	r, err := release.Latest(ctx, client, url)
+	if err != nil {
+		return told, fmt.Errorf("couldn't check for new release: %w", err)
+	}
+	if release.Newer(r.Tag, told) {
+		robo.notifyOwner(ctx, fmt.Sprintf("robot %s is available (running %s): %s", r.Tag, buildVersion(), r.URL))
+		told = r.Tag
+	}
+	return told, nil
+}
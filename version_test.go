@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckUpdates(t *testing.T) {
+	rel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tag_name":"v999.0.0","html_url":"https://example.com/v999"}`))
+	}))
+	defer rel.Close()
+	got := make(chan string, 4)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m map[string]string
+		json.NewDecoder(r.Body).Decode(&m)
+		got <- m["content"]
+	}))
+	defer hook.Close()
+	robo := New(1)
+	robo.SetOwner("bocchi", "", hook.URL)
+	ctx := context.Background()
+	told, err := robo.checkUpdates(ctx, rel.URL, "")
+	if err != nil {
+		t.Fatalf("couldn't check updates: %v", err)
+	}
+	if told != "v999.0.0" {
+		t.Errorf("wrong checkpoint: want v999.0.0, got %q", told)
+	}
+	// Checking again with the checkpoint shouldn't repeat the notification.
+	if _, err := robo.checkUpdates(ctx, rel.URL, told); err != nil {
+		t.Fatalf("couldn't check updates again: %v", err)
+	}
+	close(got)
+	var msgs []string
+	for m := range got {
+		msgs = append(msgs, m)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("wrong number of notifications: want 1, got %q", msgs)
+	}
+	if !strings.Contains(msgs[0], "v999.0.0") {
+		t.Errorf("notification doesn't name the release: %q", msgs[0])
+	}
+}